@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bananazon/pstree/pkg/pstree"
+	"github.com/bananazon/pstree/util"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/spf13/cobra"
+)
+
+// completionFunc completes the value of a flag, see cobra.Command.RegisterFlagCompletionFunc.
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// passwdPath is the passwd(5) file the usernames are completed from.
+var passwdPath = "/etc/passwd"
+
+// validShells are the shells the completion command generates a script for.
+var validShells = []string{"bash", "fish", "powershell", "zsh"}
+
+// completionCmd prints the completion script of a shell. It is hidden, since the usage of pstree
+// only lists its flags.
+var completionCmd = &cobra.Command{
+	Use:   "completion <shell>",
+	Short: "Generate the autocompletion script for the specified shell",
+	Long: `Generate the autocompletion script of pstree for bash, fish, powershell, or zsh, e.g.,
+
+    source <(pstree completion bash)
+    pstree completion zsh > "${fpath[1]}/_pstree"
+    pstree completion fish > ~/.config/fish/completions/pstree.fish`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	Hidden:                true,
+	RunE:                  completionRunCmd,
+	SilenceUsage:          true,
+	ValidArgs:             validShells,
+}
+
+// completionRunCmd writes the completion script of the shell given as argument to the output of
+// the command.
+//
+// Parameters:
+//   - cmd: The completion command
+//   - args: The name of the shell
+//
+// Returns:
+//   - error: Any error encountered while writing the script
+func completionRunCmd(cmd *cobra.Command, args []string) error {
+	root := cmd.Root()
+	output := cmd.OutOrStdout()
+
+	switch args[0] {
+	case "bash":
+		return root.GenBashCompletionV2(output, true)
+	case "fish":
+		return root.GenFishCompletion(output, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(output)
+	case "zsh":
+		return root.GenZshCompletion(output)
+	}
+	return fmt.Errorf("valid shells for completion are: %s", strings.Join(validShells, ", "))
+}
+
+// registerCompletions registers the completion of the values of the flags: the users of the
+// system for --user and --not-user, the running processes for the flags taking a PID, and the
+// valid options of the flags validated against a fixed list, which are completed from the same
+// list so they never drift. The flags that are not defined on this platform are skipped.
+//
+// Parameters:
+//   - cmd: The root command, whose flags were added by GetPersistentFlags
+func registerCompletions(cmd *cobra.Command) {
+	completions := map[string]completionFunc{
+		"age-format":      completeValues(validAgeFormats),
+		"color":           completeValues(validColorModes),
+		"color-attr":      completeValues(validAttributes),
+		"color-scheme":    completeColorSchemes,
+		"command-format":  completeValues(validCommandFormats),
+		"cpu-mode":        completeValues(validCpuModes),
+		"fields":          completeValues(pstree.FieldNames()),
+		"highlight-pid":   completePIDs,
+		"mem-field":       completeValues(validMemFields),
+		"mem-format":      completeValues(validMemFormats),
+		"mem-mode":        completeValues(validMemModes),
+		"mem-unit":        completeValues(validMemUnits),
+		"not-user":        completeUsers,
+		"order-by":        completeValues(validOrderBy),
+		"order-dir":       completeValues(validOrderDir),
+		"output":          completeValues(validOutputs),
+		"page-faults":     completeValues(validPageFaults),
+		"parents-of":      completePIDs,
+		"pid":             completePIDs,
+		"session":         completePIDs,
+		"snapshot-repair": completeValues(validSnapshotRepairs),
+		"user":            completeUsers,
+	}
+
+	for name, complete := range completions {
+		if cmd.PersistentFlags().Lookup(name) == nil {
+			continue
+		}
+		_ = cmd.RegisterFlagCompletionFunc(name, complete)
+	}
+}
+
+// completeValues returns a completion function offering the given values. The flags taking a
+// comma-separated list, e.g., --fields, are completed after the last comma.
+//
+// Parameters:
+//   - values: The valid values of the flag
+//
+// Returns:
+//   - completionFunc: The completion function
+func completeValues(values []string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeList(toComplete, values), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeColorSchemes completes --color-scheme with the built-in schemes, falling back to the
+// files of the shell, since a scheme file can be given too.
+//
+// Parameters:
+//   - cmd: The command being completed
+//   - args: The arguments already given
+//   - toComplete: The value being completed
+//
+// Returns:
+//   - []string: The matching schemes
+//   - cobra.ShellCompDirective: The directive letting the shell complete files
+func completeColorSchemes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeList(toComplete, validColorSchemes), cobra.ShellCompDirectiveDefault
+}
+
+// completeUsers completes --user and --not-user with the users listed in the passwd file, or
+// with the owners of the running processes on the systems without one, e.g., Windows. The
+// exclamation mark of --user='!name' is kept.
+//
+// Parameters:
+//   - cmd: The command being completed
+//   - args: The arguments already given
+//   - toComplete: The value being completed
+//
+// Returns:
+//   - []string: The matching usernames
+//   - cobra.ShellCompDirective: The directive disabling the completion of files
+func completeUsers(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	usernames, err := util.ReadUsernames(passwdPath)
+	if err != nil || len(usernames) == 0 {
+		usernames = processOwners()
+	}
+
+	prefix, partial := splitList(toComplete)
+	if name, ok := strings.CutPrefix(partial, "!"); ok {
+		prefix, partial = prefix+"!", name
+	}
+
+	candidates := []string{}
+	for _, username := range usernames {
+		if strings.HasPrefix(username, partial) {
+			candidates = append(candidates, prefix+username)
+		}
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePIDs completes the flags taking a PID with the running processes, described by their
+// command, e.g., 1234 followed by bash.
+//
+// Parameters:
+//   - cmd: The command being completed
+//   - args: The arguments already given
+//   - toComplete: The value being completed
+//
+// Returns:
+//   - []string: The matching PIDs with their command as description, in PID order
+//   - cobra.ShellCompDirective: The directive disabling the completion of files
+func completePIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	slices.SortFunc(procs, func(a, b *process.Process) int {
+		return cmp.Compare(a.Pid, b.Pid)
+	})
+
+	prefix, partial := splitList(toComplete)
+	candidates := []string{}
+	for _, proc := range procs {
+		pid := strconv.Itoa(int(proc.Pid))
+		if !strings.HasPrefix(pid, partial) {
+			continue
+		}
+		if name, err := proc.Name(); err == nil && name != "" {
+			pid += "\t" + name
+		}
+		candidates = append(candidates, prefix+pid)
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeList returns the values starting with the value being completed, after the values
+// already listed before its last comma.
+//
+// Parameters:
+//   - toComplete: The value being completed, e.g., cpu,m
+//   - values: The valid values
+//
+// Returns:
+//   - []string: The matching values, each following the values already listed, e.g., cpu,mem
+func completeList(toComplete string, values []string) []string {
+	prefix, partial := splitList(toComplete)
+	candidates := []string{}
+	for _, value := range values {
+		if strings.HasPrefix(value, partial) {
+			candidates = append(candidates, prefix+value)
+		}
+	}
+	return candidates
+}
+
+// splitList splits the value of a flag taking a comma-separated list after its last comma.
+//
+// Parameters:
+//   - toComplete: The value being completed
+//
+// Returns:
+//   - string: The values already listed, up to and including the last comma
+//   - string: The value being completed after the last comma
+func splitList(toComplete string) (string, string) {
+	if index := strings.LastIndex(toComplete, ","); index >= 0 {
+		return toComplete[:index+1], toComplete[index+1:]
+	}
+	return "", toComplete
+}
+
+// processOwners returns the owners of the running processes.
+//
+// Returns:
+//   - []string: The usernames, sorted
+func processOwners() []string {
+	var (
+		usernames []string
+	)
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil
+	}
+	for _, proc := range procs {
+		if username, err := proc.Username(); err == nil && username != "" {
+			usernames = append(usernames, username)
+		}
+	}
+	slices.Sort(usernames)
+	return slices.Compact(usernames)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestCompletions runs the completion request of the shell scripts for the given arguments,
+// returning the candidates and the directive printed on the last line
+func requestCompletions(t *testing.T, args ...string) ([]string, string) {
+	var output bytes.Buffer
+
+	rootCmd.SetOut(&output)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, args...))
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	})
+	require.NoError(t, rootCmd.Execute())
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	return lines[:len(lines)-1], lines[len(lines)-1]
+}
+
+func TestCompleteValues(t *testing.T) {
+	// The values are completed from the lists they are validated against
+	candidates, directive := requestCompletions(t, "--order-by", "c")
+	assert.Equal(t, []string{"cpu", "cputime"}, candidates)
+	assert.Equal(t, ":"+strconv.Itoa(int(cobra.ShellCompDirectiveNoFileComp)), directive)
+
+	candidates, _ = requestCompletions(t, "--order-dir", "")
+	assert.Equal(t, validOrderDir, candidates)
+
+	// A list is completed after its last comma
+	candidates, _ = requestCompletions(t, "--fields", "cpu,m")
+	assert.Equal(t, []string{"cpu,mem"}, candidates)
+
+	// A scheme file can be given too, so the shell completes the files; --color-scheme is only defined on terminals with 256 colors
+	candidates, schemeDirective := completeColorSchemes(rootCmd, nil, "")
+	assert.Equal(t, validColorSchemes, candidates)
+	assert.Equal(t, cobra.ShellCompDirectiveDefault, schemeDirective)
+}
+
+func TestCompleteUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwd")
+	require.NoError(t, os.WriteFile(path, []byte("root:x:0:0::/root:/bin/sh\nmessagebus:x:100:101::/:/usr/sbin/nologin\nalice:x:1000:1000::/home/alice:/bin/sh\n"), 0o600))
+	saved := passwdPath
+	passwdPath = path
+	t.Cleanup(func() { passwdPath = saved })
+
+	candidates, _ := requestCompletions(t, "--user", "")
+	assert.Equal(t, []string{"alice", "messagebus", "root"}, candidates)
+
+	// The exclamation mark of an excluded user and the users already listed are kept
+	candidates, _ = requestCompletions(t, "--user", "alice,!r")
+	assert.Equal(t, []string{"alice,!root"}, candidates)
+
+	candidates, _ = requestCompletions(t, "--not-user", "m")
+	assert.Equal(t, []string{"messagebus"}, candidates)
+}
+
+func TestCompletePIDs(t *testing.T) {
+	// The running processes are completed with their command as description
+	pid := strconv.Itoa(os.Getpid())
+	candidates, directive := completePIDs(rootCmd, nil, pid)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+	require.NotEmpty(t, candidates)
+	assert.Regexp(t, "^"+pid+"\t.+", candidates[0])
+
+	candidates, _ = completePIDs(rootCmd, nil, "1,"+pid)
+	require.NotEmpty(t, candidates)
+	assert.True(t, strings.HasPrefix(candidates[0], "1,"+pid))
+}
+
+func TestCompletionCommand(t *testing.T) {
+	for _, shell := range validShells {
+		var output bytes.Buffer
+		completionCmd.SetOut(&output)
+		require.NoError(t, completionRunCmd(completionCmd, []string{shell}))
+		assert.Contains(t, output.String(), "pstree", shell)
+	}
+	completionCmd.SetOut(nil)
+
+	assert.Error(t, completionRunCmd(completionCmd, []string{"tcsh"}))
+}
@@ -23,6 +23,7 @@ import (
 //   - username: String containing the current user's username for privilege-based flags
 func GetPersistentFlags(cmd *cobra.Command, colorSupport bool, colorCount int, username string) {
 	// Drawing characters
+	cmd.PersistentFlags().BoolVarP(&flagASCII, "ascii", "", false, "use ASCII line drawing characters, even when the locale uses UTF-8")
 	if runtime.GOOS == "windows" || username == "bananazon" { // I put this here to show all output for the usage section of the README
 		cmd.PersistentFlags().BoolVarP(&flagIBM850, "ibm-850", "i", false, "use IBM-850 line drawing characters; only supported on DOS/Windows")
 	}
@@ -30,49 +31,158 @@ func GetPersistentFlags(cmd *cobra.Command, colorSupport bool, colorCount int, u
 	cmd.PersistentFlags().BoolVarP(&flagVT100, "vt-100", "v", false, "use VT-100 line drawing characters")
 
 	// Depth
+	cmd.PersistentFlags().BoolVarP(&flagCollapseChains, "collapse-chains", "", false, "print each run of processes with a single child on one line, e.g., sshd───bash───vim, showing the metrics of the deepest process; a change of owner or container shown with the other flags breaks the run; can only be used with --output=tree")
 	cmd.PersistentFlags().IntVarP(&flagLevel, "level", "l", 0, "print tree to <level> level deep")
 
 	// Width
+	cmd.PersistentFlags().BoolVarP(&flagAlign, "align", "", false, "pad the lines so the metrics of the processes line up in columns, unless they would not fit on the screen; can only be used with --output=tree")
 	cmd.PersistentFlags().BoolVarP(&flagWide, "wide", "w", false, "wide output, not truncated to window width")
+	cmd.PersistentFlags().BoolVarP(&flagWrap, "wrap", "", false, "wrap long lines onto indented continuation lines instead of truncating them; cannot be used with --wide")
+
+	// Highlighting
+	cmd.PersistentFlags().BoolVarP(&flagHighlightNice, "highlight-nice", "", false, "highlight the processes with a negative nice value, which can starve the others, in bold red, or with an exclamation mark without colors, e.g., (nice: -10)!; implies --nice")
+	cmd.PersistentFlags().IntVarP(&flagHighlightPid, "highlight-pid", "", 0, "highlight process <pid> and all of its ancestors; cannot be used with --highlight-self")
+	cmd.PersistentFlags().BoolVarP(&flagHighlightSelf, "highlight-self", "", false, "highlight the current process and all of its ancestors; cannot be used with --highlight-pid")
+
+	// Output format
+	cmd.PersistentFlags().StringVarP(&flagOutput, "output", "", "tree", fmt.Sprintf("the output format; csv and tsv print one row per process with a depth column instead of drawing the tree, dot prints a Graphviz digraph, markdown prints a nested list followed by a table of the metrics\nvalid options are: %s", strings.Join(validOutputs, ", ")))
+
+	// Snapshots
+	cmd.PersistentFlags().StringVarP(&flagDumpSnapshot, "dump-snapshot", "", "", "write the collected processes to <file> as a JSON snapshot instead of printing the tree, - for stdout; cannot be used with --watch")
+	cmd.PersistentFlags().StringVarP(&flagDiff, "diff", "", "", "compare the processes with those <seconds> earlier, or with a snapshot <file> written by --dump-snapshot, and mark the processes that started [new] or exited [gone]; the processes in both show the change of their CPU usage and memory, e.g., (Δc:+1.25%, Δm:-3.0 MiB); In compacted view, the changes will represent the sum of all process group members; cannot be used with --from-file, --dump-snapshot, --watch, or --kill")
+	cmd.PersistentFlags().StringVarP(&flagSnapshotRepair, "snapshot-repair", "", "off", fmt.Sprintf("repair the processes whose parent exited or spawned while the processes were collected, which would otherwise show up as separate trees: refetch collects each missing parent once more, reparent attaches the process to its nearest ancestor that was collected; cannot be used with --from-file\nvalid options are: %s", strings.Join(validSnapshotRepairs, ", ")))
+	cmd.PersistentFlags().BoolVarP(&flagKeepVanished, "keep-vanished", "", false, "keep the processes that exited while the processes were collected, shown as [PID n] with unknown attributes, instead of skipping them; cannot be used with --from-file")
+	cmd.PersistentFlags().StringVarP(&flagFromFile, "from-file", "", "", "read the processes from a snapshot <file> written by --dump-snapshot instead of the running system; cannot be used with --connections or --watch")
 
 	// Color options
 	if colorSupport {
 		if colorCount >= 8 && colorCount < 256 {
-			cmd.PersistentFlags().BoolVarP(&flagColor, "color", "C", false, fmt.Sprintf("add some beautiful %s to the pstree output; cannot be used with --color-attr", pstree.Print8ColorRainbow("color")))
-			cmd.PersistentFlags().StringVarP(&flagColorAttr, "color-attr", "k", "", fmt.Sprintf("color the process name by given attribute; implies --compact-not; valid options are: %s;\ncannot be used with --color", strings.Join(validAttributes, ", ")))
+			cmd.PersistentFlags().StringVarP(&flagColor, "color", "C", "auto", fmt.Sprintf("add some beautiful %s to the pstree output\n<when> is always, auto, or never, --color alone means always; auto only colors a terminal and honors NO_COLOR; with --color-attr or --rainbow, only decides when their colors are written", pstree.Print8ColorRainbow("color")))
+			cmd.PersistentFlags().StringVarP(&flagColorAttr, "color-attr", "k", "", fmt.Sprintf("color the process name by given attribute; compacted lines are colored by the value of the whole group; valid options are: %s", strings.Join(validAttributes, ", ")))
 		} else if colorCount >= 256 {
-			cmd.PersistentFlags().BoolVarP(&flagColor, "color", "C", false, gorainbow.Rainbow("add some beautiful color to the pstree output; cannot be used with --color-attr or --rainbow"))
-			cmd.PersistentFlags().BoolVarP(&flagRainbow, "rainbow", "r", false, "for the adventurous; cannot be used with --color, --color-attr, or --color-scheme")
-			cmd.PersistentFlags().StringVarP(&flagColorAttr, "color-attr", "k", "", fmt.Sprintf("color the process name by given attribute; implies --compact-not; cannot be used with --color, --color-scheme, or --rainbow\nvalid options are: %s", strings.Join(validAttributes, ", ")))
-			cmd.PersistentFlags().StringVarP(&flagColorScheme, "color-scheme", "q", "", fmt.Sprintf("override the default color scheme; implies --color; cannot be used with --color-attr or --rainbow\nvalid options are: %s", strings.Join(validColorSchemes, ", ")))
+			cmd.PersistentFlags().StringVarP(&flagColor, "color", "C", "auto", gorainbow.Rainbow("add some beautiful color to the pstree output")+"\n<when> is always, auto, or never, --color alone means always; auto only colors a terminal and honors NO_COLOR; with --color-attr or --rainbow, only decides when their colors are written")
+			cmd.PersistentFlags().BoolVarP(&flagRainbow, "rainbow", "r", false, "for the adventurous; cannot be used with --color-attr or --color-scheme")
+			cmd.PersistentFlags().StringVarP(&flagColorAttr, "color-attr", "k", "", fmt.Sprintf("color the process name by given attribute; compacted lines are colored by the value of the whole group; cannot be used with --rainbow or a built-in --color-scheme\nvalid options are: %s", strings.Join(validAttributes, ", ")))
+			cmd.PersistentFlags().StringVarP(&flagColorScheme, "color-scheme", "q", "", fmt.Sprintf("override the default color scheme with a built-in scheme or a scheme file, see pstree(1); implies --color; cannot be used with --rainbow\nvalid options are: %s, a path, or the name of a scheme in ~/.config/pstree/schemes", strings.Join(validColorSchemes, ", ")))
 		}
+		cmd.PersistentFlags().StringVarP(&flagAttrThresholds, "attr-thresholds", "", "", "comma-separated, increasing thresholds between the --color-attr colors, e.g., 50,80; requires --color-attr\nage takes three values in seconds, cpu and mem take two percentages, cputime takes two values in seconds, fds takes two counts")
+	} else {
+		// --color=always still works when the terminal capabilities can't be detected, e.g., when TERM is unset
+		cmd.PersistentFlags().StringVarP(&flagColor, "color", "C", "auto", "add some color to the pstree output\n<when> is always, auto, or never, --color alone means always; auto only colors a terminal and honors NO_COLOR; with --color-attr or --rainbow, only decides when their colors are written")
 	}
+	cmd.PersistentFlags().Lookup("color").NoOptDefVal = "always"
 
 	// Optional information
 	cmd.PersistentFlags().BoolVarP(&flagShowAll, "all", "A", false, "equivalent to -acDGmOpSt")
+	cmd.PersistentFlags().StringVarP(&flagCommandFormat, "command-format", "", "basename", fmt.Sprintf("show the command of each process as its basename, e.g., bash, or its full path, e.g., /usr/bin/bash\nvalid options are: %s", strings.Join(validCommandFormats, ", ")))
 	cmd.PersistentFlags().BoolVarP(&flagCompactNot, "compact-not", "n", false, "do not compact identical subtrees in output")
+	cmd.PersistentFlags().BoolVarP(&flagCompactNot, "no-compact", "", false, "do not compact identical subtrees in output; same as --compact-not")
+	cmd.PersistentFlags().BoolVarP(&flagConnections, "connections", "", false, "show a summary of the network connections of each process, e.g., (tcp: 3 est, 1 listen :8080); (conn: ?) is shown when they cannot be read")
+	cmd.PersistentFlags().BoolVarP(&flagContainers, "containers", "", false, "show the container each process runs in, e.g., (ctr: 3f4e5a6b7c8d), read from its cgroup for Docker, containerd, CRI-O, and Podman; Linux only")
 	cmd.PersistentFlags().BoolVarP(&flagCpu, "cpu", "c", false, "show CPU utilization percentage with each process, e.g., (c:0.00%); In compacted view, this value will represent the sum of all process group members")
+	cmd.PersistentFlags().StringVarP(&flagCpuMode, "cpu-mode", "", "irix", fmt.Sprintf("how the CPU usage percentages are shown: as a share of one CPU the way top does by default, which exceeds 100%% for a process busy on several CPUs (irix), or divided by the number of CPUs so the usage of the host adds up to at most 100%% (solaris); also used by the compact group sums, --min-cpu, and --color-attr=cpu; implies --cpu\nvalid options are: %s", strings.Join(validCpuModes, ", ")))
+	cmd.PersistentFlags().BoolVarP(&flagCpuTime, "cpu-time", "", false, "show the CPU time consumed by each process the way ps -o cputime does, e.g., (ct:00:01:23) or (ct:2-03:15:00); In compacted view, this value will represent the sum of all process group members")
+	cmd.PersistentFlags().BoolVarP(&flagCumulative, "cumulative", "", false, "show the CPU and memory usage of each process summed with all of its descendants next to its own values, e.g., (c:0.50% (2.00%)); implies --cpu and --memory unless one of them is given")
+	cmd.PersistentFlags().BoolVarP(&flagCwd, "cwd", "", false, "show the current working directory of each process, e.g., (cwd: /srv/app); (cwd: ?) is shown when it cannot be read; long directories are shortened in the middle unless --wide or --wrap is given")
+	cmd.PersistentFlags().BoolVarP(&flagFDs, "fds", "", false, "show the number of open file descriptors with each process, e.g., (fds: 12); (fds: -) is shown when they cannot be read; In compacted view, this value will represent the sum of all process group members")
+	cmd.PersistentFlags().StringSliceVarP(&flagFields, "fields", "", []string{}, "show exactly the comma-separated <fields> in this order, e.g., cpu,mem,user; the fields of the other flags are appended; valid fields are: "+strings.Join(pstree.FieldNames(), ", "))
+	cmd.PersistentFlags().BoolVarP(&flagIO, "io", "", false, "show the number of bytes read and written by each process, e.g., (io: r 1.2 MiB, w 64.0 KiB); (io: -) is shown when they cannot be read; In compacted view, this value will represent the sum of all process group members")
+	cmd.PersistentFlags().StringVarP(&flagPageFaults, "page-faults", "", "", fmt.Sprintf("show the number of major page faults of each process, e.g., (pf: 12), or the major and minor faults with --page-faults=all, e.g., (pf: 12 maj, 3400 min); (pf: -) is shown when they cannot be read; In compacted view, this value will represent the sum of all process group members\nvalid options are: %s", strings.Join(validPageFaults, ", ")))
+	cmd.PersistentFlags().Lookup("page-faults").NoOptDefVal = "major"
+	cmd.PersistentFlags().StringVarP(&flagMemField, "mem-field", "", "rss", fmt.Sprintf("the memory value shown with --memory: the resident set size (m:), the swapped out memory (sw:), or the virtual memory size (v:); also used by --order-by=mem; implies --memory\nvalid options are: %s", strings.Join(validMemFields, ", ")))
+	cmd.PersistentFlags().StringVarP(&flagMemFormat, "mem-format", "", "abs", fmt.Sprintf("how the memory values are shown: the absolute value (m:1.5 MiB), the percentage of the installed memory (m:0.3%%), or both (m:1.5 MiB, 0.3%%); implies --memory\nvalid options are: %s", strings.Join(validMemFormats, ", ")))
+	cmd.PersistentFlags().StringVarP(&flagMemMode, "mem-mode", "", "rss", fmt.Sprintf("the resident memory used for the memory values, the compact group sums, --order-by=mem, and --color-attr=mem: the resident set size (m:), the proportional set size (pss:), or the unique set size (uss:); pss and uss are read on Linux only and fall back to rss elsewhere; implies --memory\nvalid options are: %s", strings.Join(validMemModes, ", ")))
+	cmd.PersistentFlags().BoolVarP(&flagMemPercent, "mem-percent", "", false, "show the memory usage as a percentage of the installed memory, a shorthand for --mem-format=pct; implies --memory")
+	cmd.PersistentFlags().StringVarP(&flagMemUnit, "mem-unit", "", "auto", fmt.Sprintf("the unit of the memory values; auto picks the largest unit that keeps the value below 1024; implies --memory\nvalid options are: %s", strings.Join(validMemUnits, ", ")))
 	cmd.PersistentFlags().BoolVarP(&flagMemory, "memory", "m", false, "show the memory usage with each process, e.g., (m:x.y MiB); In compacted view, this value will represent the sum of all process group members")
+	cmd.PersistentFlags().BoolVarP(&flagNamespaces, "namespaces", "", false, "mark the processes living in other pid, mnt, net, or user namespaces than PID 1, e.g., [ns:pid,net]; ? is shown for the namespaces that cannot be read; Linux only")
+	cmd.PersistentFlags().BoolVarP(&flagNice, "nice", "", false, "show the nice value of each process, e.g., (nice: 5); on Windows, the priority class is shown as an approximate nice value; (nice: ?) is shown when it cannot be read; In compacted view, this value will represent the range of the group, e.g., (nice: 0..10)")
+	cmd.PersistentFlags().BoolVarP(&flagNSPids, "ns-pids", "", false, "show the PID of each process running in a nested PID namespace, e.g., a container, in its innermost namespace next to its host PID, e.g., (1234/7); implies --show-pids; Linux only")
+	cmd.PersistentFlags().StringVarP(&flagOrphanSymbol, "orphan-symbol", "", pstree.DefaultOrphanSymbol, "the symbol shown in front of orphaned processes with --show-orphans; implies --show-orphans")
+	cmd.PersistentFlags().BoolVarP(&flagNumeric, "numeric", "", false, "show user IDs instead of usernames with --show-owner and --user-transitions and sort --order-by=user numerically; implies --show-owner unless a transition flag is given")
+	cmd.PersistentFlags().BoolVarP(&flagGroupByContainer, "group-by-container", "", false, "move the processes of each container under a (container <id>) node of its own, shown after the processes of the host; Linux only; cannot be used with --pid")
+	cmd.PersistentFlags().BoolVarP(&flagByPgroup, "by-pgroup", "", false, "show each process group leader at the top of a tree of its own with the members of its group as its children, whatever their parent; cannot be used with --show-orphans or --group-by-container")
+	cmd.PersistentFlags().BoolVarP(&flagShowOrphans, "show-orphans", "", false, "attach processes whose parent is missing to an (orphans) node instead of showing them as separate trees")
 	cmd.PersistentFlags().BoolVarP(&flagShowOwner, "show-owner", "O", false, "show the owner of the process")
+	cmd.PersistentFlags().BoolVarP(&flagShowParent, "show-parent", "", false, "show the command of the parent of each process, e.g., (parent: sshd), and add a parent_command column to --output=csv and --output=tsv; nothing is shown when the parent was not collected")
 	cmd.PersistentFlags().BoolVarP(&flagShowPGIDs, "show-pgids", "g", false, "show process group IDs")
+	cmd.PersistentFlags().BoolVarP(&flagShowSIDs, "sids", "", false, "show session IDs and mark session leaders")
 	cmd.PersistentFlags().BoolVarP(&flagShowPIDs, "show-pids", "p", false, "show process IDs")
 	cmd.PersistentFlags().BoolVarP(&flagShowPPIDs, "show-ppids", "D", false, "show parent process IDs")
 	cmd.PersistentFlags().BoolVarP(&flagShowUIDTransitions, "uid-transitions", "I", false, "show processes where the user ID changes from the parent process, e.g., (uid→uid); cannot be used with --user-transitions")
 	cmd.PersistentFlags().BoolVarP(&flagShowUserTransitions, "user-transitions", "U", false, "show processes where the user changes from the parent process, e.g., (user→user); cannot be used with --uid-transitions")
+	cmd.PersistentFlags().BoolVarP(&flagSched, "sched", "", false, "show the scheduling policy of each process, e.g., (sched: FIFO); (sched: ?) is shown when it cannot be read; Linux only; In compacted view, this value will list the policies present in the group")
+	cmd.PersistentFlags().BoolVarP(&flagShowStatus, "status", "", false, "show the process state with each process the way ps does, e.g., (s:R); In compacted view, this value will list the states present in the group")
+	cmd.PersistentFlags().BoolVarP(&flagDepthStats, "depth-stats", "", false, "print the number of displayed processes at each level after the tree, e.g., level 1: 12 processes")
+	cmd.PersistentFlags().BoolVarP(&flagShowDepth, "show-depth", "", false, "prefix each line with the depth of the process in the tree")
+	cmd.PersistentFlags().BoolVarP(&flagStream, "stream", "", false, "print each line of the tree as soon as it is built, for very large trees; the process IDs are then not padded to a common width; cannot be used with --align")
+	cmd.PersistentFlags().BoolVarP(&flagSummary, "summary", "", false, "print a summary of the displayed processes after the tree, e.g., 87 processes, 3 users, 412 threads; the thread, memory, and CPU totals are included with --threads, --memory, and --cpu")
+	cmd.PersistentFlags().BoolVarP(&flagThreadsTree, "show-threads-tree", "", false, "show the threads of each process as {command} child nodes the way Linux pstree does; In compacted view, the threads of a process are shown as N*[{command}]")
+	cmd.PersistentFlags().BoolVarP(&flagPrivileged, "privileged", "", false, "mark processes that gained privileges with ⚑, i.e., running with an effective UID of 0 below a parent that isn't, or with differing real, effective, and saved UIDs, e.g., setuid binaries; not supported on Windows")
+	cmd.PersistentFlags().BoolVarP(&flagZombies, "zombies", "", false, "mark zombie processes with <defunct> and show them in red when colors are enabled; the summary counts the zombies")
 	cmd.PersistentFlags().BoolVarP(&flagThreads, "threads", "t", false, "show the number of threads with each process, e.g., (t:xx); In compacted view, this value will represent the sum of all process group members")
 
 	// Filtering and sorting
-	cmd.PersistentFlags().BoolVarP(&flagAge, "age", "G", false, "show the age of the process using the format (dd:hh:mm:ss); In compacted view, this value will represent the oldest process in the group")
+	cmd.PersistentFlags().BoolVarP(&flagAge, "age", "G", false, "show the age of the process using the format (dd:hh:mm:ss), or (?) when it cannot be read; In compacted view, this value will represent the oldest process in the group")
+	cmd.PersistentFlags().StringVarP(&flagAgeFormat, "age-format", "", "dhms", fmt.Sprintf("the format of the process age, e.g., dhms (02:04:13:07), hms (52:13:07), human (2d4h), or seconds (187987); implies --age\nvalid options are: %s", strings.Join(validAgeFormats, ", ")))
+	cmd.PersistentFlags().BoolVarP(&flagStartTime, "start-time", "", false, "show the time each process started, e.g., (start: 15:04:05), or (start: Jan 02 15:04) for a process started more than a day ago; (start: ?) is shown when it cannot be read; In compacted view, this value will represent the oldest process in the group")
+	cmd.PersistentFlags().BoolVarP(&flagUTC, "utc", "", false, "show the start times of --start-time in UTC instead of local time; requires --start-time")
 	cmd.PersistentFlags().BoolVarP(&flagArguments, "arguments", "a", false, "show command line arguments")
+	cmd.PersistentFlags().BoolVarP(&flagRawArgs, "raw-args", "", false, "show the command line arguments as collected, argv[0] included, and the executable as the command, instead of the name a process was started with, e.g., -bash; implies --arguments")
+	cmd.PersistentFlags().StringVarP(&flagArgsFilter, "args-filter", "", "", "show only the arguments matching the regular expression <regex>, e.g., --args-filter=^-Xmx; identical processes are still compacted by their full arguments; implies --arguments")
+	cmd.PersistentFlags().IntVarP(&flagMaxArgs, "max-args", "", 0, "show only the first <n> arguments of each process followed by … (+K more); applied after --args-filter; implies --arguments")
+	cmd.PersistentFlags().StringSliceVarP(&flagExclude, "exclude", "", []string{}, "hide processes with <pattern> in the command line along with their descendants; this option can be used more than once")
 	cmd.PersistentFlags().BoolVarP(&flagExcludeRoot, "exclude-root", "X", false, "don't show branches containing only root processes; cannot be used with --user")
-	cmd.PersistentFlags().Int32VarP(&flagPid, "pid", "P", 0, "show only branches containing process <pid>")
-	cmd.PersistentFlags().StringSliceVarP(&flagUsername, "user", "", []string{}, "show only branches containing processes of <user>; this option can be used more than and cannot be used with --exclude-root")
-	cmd.PersistentFlags().StringVarP(&flagContains, "contains", "s", "", "show only branches containing processes with <pattern> in the command line; implies --compact-not")
-	cmd.PersistentFlags().StringVarP(&flagOrderBy, "order-by", "o", "", fmt.Sprintf("sort the results by <field>; valid options are: %s", strings.Join(validOrderBy, ", ")))
+	cmd.PersistentFlags().BoolVarP(&flagOnlyForeignNS, "only-foreign-ns", "", false, "show only branches containing processes living in other namespaces than PID 1, e.g., containers and sandboxes; Linux only")
+	cmd.PersistentFlags().BoolVarP(&flagOnlyRealtime, "only-realtime", "", false, "show only branches containing processes with a realtime scheduling policy (FIFO, RR, or DEADLINE), e.g., to debug latency; Linux only")
+	cmd.PersistentFlags().BoolVarP(&flagOnlyDeleted, "only-deleted", "", false, "show only branches containing processes still running a deleted executable, e.g., daemons not restarted after an upgrade; Linux only")
+	cmd.PersistentFlags().StringVarP(&flagDeletedMarker, "deleted-marker", "", "[deleted]", "the marker shown after the command of processes running a deleted executable; Linux only")
+	cmd.PersistentFlags().BoolVarP(&flagOnlyPrivileged, "only-privileged", "", false, "show only branches containing processes that gained privileges, see --privileged; implies --privileged")
+	cmd.PersistentFlags().BoolVarP(&flagOnlyZombies, "only-zombies", "", false, "show only branches containing zombie processes, to find the parents that fail to reap them; implies --zombies")
+	cmd.PersistentFlags().BoolVarP(&flagNoKernelThreads, "no-kernel-threads", "", false, "hide Linux kernel threads, i.e., kthreadd and its descendants or processes with a [bracketed] name and no arguments; has no effect on other systems")
+	cmd.PersistentFlags().IntVarP(&flagParentsOf, "parents-of", "", 0, "show only process <pid> and its ancestors up to the root, the way pstree -s does; cannot be used with --pid, --contains, --user, --group, --tty, or --exclude-root")
+	cmd.PersistentFlags().BoolVarP(&flagWithChildren, "with-children", "", false, "with --parents-of, also show the direct children of the process")
+	cmd.PersistentFlags().IntSliceVarP(&flagPid, "pid", "P", []int{}, "show only the tree rooted at process <pid>; this option can be used more than once or with a comma-separated list, each tree is printed separately")
+	cmd.PersistentFlags().StringVarP(&flagRootCmd, "root-cmd", "", "", "show only the trees rooted at the processes whose command is <name>, e.g., --root-cmd=nginx, printed separately in PID order; the matches below another one are part of its tree; cannot be used with --pid")
+	cmd.PersistentFlags().StringVarP(&flagRootCmdRegex, "root-cmd-regex", "", "", "like --root-cmd, with the commands matching the regular expression <regex>, e.g., --root-cmd-regex='^php-fpm'")
+	cmd.PersistentFlags().BoolVarP(&flagNoRootLine, "no-root-line", "", false, "with --pid or --root-cmd, print the children of the process as trees of their own, without the line of the process itself; exits with status 1 when it has no displayed children")
+	cmd.PersistentFlags().StringSliceVarP(&flagGroup, "group", "", []string{}, "show only branches containing processes in the group <group>, given by name or GID; this option can be used more than once")
+	cmd.PersistentFlags().StringSliceVarP(&flagUsername, "user", "", []string{}, "show only branches containing processes of <user>, or hide the processes of <user> with --user='!<user>'; this option can be used more than once and cannot be used with --exclude-root")
+	cmd.PersistentFlags().StringSliceVarP(&flagNotUser, "not-user", "", []string{}, "hide the processes of <user> unless they are the ancestors of displayed processes, e.g., --not-user=root,messagebus; the users shown with --user take precedence; this option can be used more than once")
+	cmd.PersistentFlags().StringVarP(&flagTTY, "tty", "", "", "show only branches containing processes attached to the terminal <tty>, e.g., --tty=pts/3; --tty alone means the current terminal")
+	cmd.PersistentFlags().Lookup("tty").NoOptDefVal = "current"
+	cmd.PersistentFlags().StringVarP(&flagContains, "contains", "s", "", "show only branches containing processes with <pattern> in the command line; matching processes are only compacted with each other")
+	cmd.PersistentFlags().IntVarP(&flagSession, "session", "", 0, "show only branches containing processes in the session of process <pid>, e.g., everything started from a login")
+	cmd.PersistentFlags().StringVarP(&flagCwdUnder, "cwd-under", "", "", "show only branches containing processes whose working directory is <dir> or below it, e.g., to find what keeps a mount busy; matching processes are only compacted with each other")
+	cmd.PersistentFlags().StringVarP(&flagEnvContains, "env-contains", "", "", "show only branches containing processes with the environment variable <KEY=VALUE>, or <KEY> with any value; only the environments of the processes left by the other filters are read; matching processes are only compacted with each other")
+	cmd.PersistentFlags().BoolVarP(&flagEnvShow, "env-show", "", false, "with --env-contains, show the matching environment variable with each process, e.g., (env: FEATURE_X=on)")
+	cmd.PersistentFlags().BoolVarP(&flagMatchSubtree, "match-subtree", "", false, "with --contains, --cwd-under, --env-contains, --group, --min-cpu, --min-mem, or --tty, also show all descendants of the matching processes; --contains already does by default")
+	cmd.PersistentFlags().Float64VarP(&flagMinCPU, "min-cpu", "", 0, "show only branches containing processes using at least <percent> CPU; implies --cpu")
+	cmd.PersistentFlags().StringVarP(&flagMinMem, "min-mem", "", "", "show only branches containing processes using at least <size> of memory, e.g., 512M or 1.5G; implies --memory")
+	cmd.PersistentFlags().StringVarP(&flagYoungerThan, "younger-than", "", "", "show only branches containing processes started less than <duration> ago, e.g., 5m, 2h, or 1d, along with their ancestors")
+	cmd.PersistentFlags().StringVarP(&flagOlderThan, "older-than", "", "", "show only branches containing processes started more than <duration> ago; with --younger-than, the processes started in between, e.g., during an incident")
+	cmd.PersistentFlags().StringVarP(&flagOrderBy, "order-by", "o", "", fmt.Sprintf("sort the children of each process by <field>; valid options are: %s", strings.Join(validOrderBy, ", ")))
+	cmd.PersistentFlags().IntVarP(&flagTop, "top", "", 0, "show only the <n> processes that sort first by --order-by, and their ancestors; sorts in descending order unless --order-dir is given; each process counts toward <n>, identical ones are still compacted into one line; requires --order-by")
+	cmd.PersistentFlags().StringVarP(&flagOrderDir, "order-dir", "", "asc", fmt.Sprintf("the direction to sort in with --order-by; valid options are: %s", strings.Join(validOrderDir, ", ")))
+
+	// Watch mode
+	cmd.PersistentFlags().BoolVarP(&flagWatch, "watch", "W", false, "redraw the tree every <interval> seconds until interrupted")
+	cmd.PersistentFlags().IntVarP(&flagInterval, "interval", "", 2, "refresh interval in seconds for --watch and --serve; implies --watch unless --serve is given")
+
+	// Serve mode
+	cmd.PersistentFlags().StringVarP(&flagServe, "serve", "", "", "serve the tree over HTTP on <address>, e.g., :8080, collecting the processes every <interval> seconds: GET /tree returns the tree, /tree.json the processes as JSON, and /healthz the health; query parameters mirror the flags, e.g., /tree?contains=nginx&cpu=1; cannot be used with --watch, --dump-snapshot, --diff, --kill, or --output")
+
+	// Signaling
+	cmd.PersistentFlags().BoolVarP(&flagDryRun, "dry-run", "", false, "with --kill, only list the processes that would be signaled")
+	cmd.PersistentFlags().StringVarP(&flagKill, "kill", "", "", fmt.Sprintf("after printing the tree, send <signal> to the displayed processes, children before parents; requires --pid or --contains\nvalid options are: %s", strings.Join(pstree.SignalNames, ", ")))
+	cmd.PersistentFlags().BoolVarP(&flagYes, "yes", "y", false, "with --kill, send the signal without asking for confirmation")
 
 	// Miscellaneous
-	cmd.PersistentFlags().BoolVarP(&flagVersion, "version", "V", false, "display version information")
+	cmd.PersistentFlags().BoolVarP(&flagVersion, "version", "V", false, "display version information, the same as the version command; as JSON with --output=json")
 	cmd.PersistentFlags().BoolVarP(&flagShowPGLs, "show-pgls", "S", false, "show process group leader indicators")
+	cmd.PersistentFlags().DurationVarP(&flagLookupTimeout, "lookup-timeout", "", pstree.DefaultLookupTimeout, "give each lookup of a username <duration>, e.g., 1s, before showing the UID instead, so a hanging directory service doesn't hang pstree; each user is only looked up once")
+	cmd.PersistentFlags().BoolVarP(&flagQuiet, "quiet", "", false, "don't print the note about the attributes that could not be read for most of the processes without privileges, e.g., the IO counters of other users' processes")
+	cmd.PersistentFlags().StringVarP(&flagProfile, "profile", "", "", "write pprof CPU and heap profiles of the process collection to <prefix>.cpu.pprof and <prefix>.heap.pprof, e.g., to report a slow run; cannot be used with --watch or --serve")
 
 	// Debugging and experimental features
 	if username == "bananazon" {
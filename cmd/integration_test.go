@@ -1,15 +1,29 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/bananazon/pstree/pkg/logger"
+	"github.com/bananazon/pstree/pkg/pstree"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -126,7 +140,7 @@ func TestRealPstreeOutput(t *testing.T) {
 		},
 		{
 			name: "show_owner",
-			args: []string{"--show-owner"},
+			args: []string{"--show-owner", "--command-format=full"},
 			patterns: []string{
 				// Username should be shown before the command
 				`root /`,
@@ -228,7 +242,7 @@ func TestOrderByRealOutput(t *testing.T) {
 	for _, option := range orderOptions {
 		t.Run("order_by_"+option, func(t *testing.T) {
 			// Run the pstree command with --order-by flag
-			cmd := exec.Command(binaryPath, "--order-by="+option)
+			cmd := exec.Command(binaryPath, "--order-by="+option, "--ascii")
 			var stdout, stderr bytes.Buffer
 			cmd.Stdout = &stdout
 			cmd.Stderr = &stderr
@@ -283,6 +297,14 @@ func TestFlagCombinationsRealOutput(t *testing.T) {
 				`\(\d+,\d+\)`,
 			},
 		},
+		{
+			name: "ascii_graphics",
+			args: []string{"--ascii"},
+			patterns: []string{
+				// ASCII tree characters should be used regardless of the locale
+				`\|-|\\-`,
+			},
+		},
 		{
 			name: "utf8_graphics",
 			args: []string{"--utf-8"},
@@ -329,3 +351,351 @@ func TestFlagCombinationsRealOutput(t *testing.T) {
 		})
 	}
 }
+
+// TestColorModeRealOutput tests when --color writes ANSI escape sequences, since the output of
+// the test binary is not a terminal, auto mode must not add any colors
+func TestColorModeRealOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		noColor  string
+		colorful bool
+	}{
+		{name: "default", args: []string{}, colorful: false},
+		{name: "bare_color", args: []string{"--color"}, colorful: true},
+		{name: "always", args: []string{"--color=always"}, colorful: true},
+		{name: "always_overrides_no_color", args: []string{"--color=always"}, noColor: "1", colorful: true},
+		{name: "auto_not_a_terminal", args: []string{"--color=auto"}, colorful: false},
+		{name: "never", args: []string{"--color=never"}, colorful: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tc.args...)
+			cmd.Env = append(os.Environ(), "NO_COLOR="+tc.noColor)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			require.NoError(t, err, stderr.String())
+
+			assert.Equal(t, tc.colorful, bytes.Contains(stdout.Bytes(), []byte("\x1b[")))
+		})
+	}
+
+	// Invalid modes are rejected
+	err := exec.Command(binaryPath, "--color=sometimes").Run()
+	assert.Error(t, err)
+}
+
+// TestParseAttrThresholds tests the validation of --attr-thresholds
+func TestParseAttrThresholds(t *testing.T) {
+	thresholds, err := parseAttrThresholds("50,80", "cpu")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{50, 80}, thresholds)
+
+	thresholds, err = parseAttrThresholds("30, 600, 7200", "age")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{30, 600, 7200}, thresholds)
+
+	_, err = parseAttrThresholds("80,50", "mem")
+	assert.Error(t, err, "thresholds must be increasing")
+
+	_, err = parseAttrThresholds("50,50", "mem")
+	assert.Error(t, err, "thresholds must be strictly increasing")
+
+	_, err = parseAttrThresholds("50,high", "cpu")
+	assert.Error(t, err, "thresholds must be numbers")
+
+	_, err = parseAttrThresholds("50,80", "age")
+	assert.Error(t, err, "age requires three thresholds")
+}
+
+func TestNeedArgs(t *testing.T) {
+	savedCompactNot, savedExclude := flagCompactNot, flagExclude
+	t.Cleanup(func() {
+		flagCompactNot, flagExclude = savedCompactNot, savedExclude
+	})
+	flagCompactNot, flagExclude = false, nil
+
+	// argv[0] alone names the processes and tells them apart in compact mode, with or without it
+	assert.False(t, needArgs(pstree.DisplayOptions{}))
+	flagCompactNot = true
+	assert.False(t, needArgs(pstree.DisplayOptions{}))
+
+	// The full command lines are only read when they are shown or matched
+	assert.True(t, needArgs(pstree.DisplayOptions{ShowArguments: true}))
+	flagExclude = []string{"sleep"}
+	assert.True(t, needArgs(pstree.DisplayOptions{}))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(nil))
+	assert.Equal(t, ExitFailure, ExitCode(ErrNoMatch))
+	assert.Equal(t, ExitFailure, ExitCode(errors.New("failed to open snapshot")))
+	assert.Equal(t, ExitUsage, ExitCode(&UsageError{Err: errors.New("--level cannot be set to less than 1")}))
+	assert.Equal(t, ExitUsage, ExitCode(fmt.Errorf("wrapped: %w", &UsageError{Err: ErrNoMatch})))
+}
+
+func TestSignalProcesses(t *testing.T) {
+	var (
+		output      bytes.Buffer
+		errorOutput bytes.Buffer
+		signaled    []int32
+	)
+
+	sendSignal = func(pid int32, signal syscall.Signal) error {
+		if pid == 30 {
+			return errors.New("operation not permitted")
+		}
+		signaled = append(signaled, pid)
+		return nil
+	}
+	defer func() {
+		sendSignal = pstree.SendSignal
+		flagDryRun, flagKill, flagYes = false, "", false
+	}()
+
+	targets := []pstree.SignalTarget{{Command: "worker", PID: 21}, {Command: "cron", PID: 30}, {Command: "worker", PID: 20}}
+	flagKill, killSignal = "term", syscall.SIGTERM
+
+	// Anything but yes cancels
+	err := signalProcesses(targets, strings.NewReader("\n"), &output, &errorOutput)
+	require.NoError(t, err)
+	assert.Equal(t, "send SIGTERM to these 3 processes? [y/N] ", errorOutput.String())
+	assert.Empty(t, signaled)
+
+	// A failure is reported without stopping the others
+	errorOutput.Reset()
+	err = signalProcesses(targets, strings.NewReader("y\n"), &output, &errorOutput)
+	assert.EqualError(t, err, "failed to signal 1 of 3 processes")
+	assert.Equal(t, []int32{21, 20}, signaled)
+	assert.Contains(t, errorOutput.String(), "failed to send SIGTERM to 30 (cron): operation not permitted")
+
+	// --dry-run only lists the processes
+	signaled = nil
+	flagDryRun = true
+	err = signalProcesses(targets, strings.NewReader(""), &output, &errorOutput)
+	require.NoError(t, err)
+	assert.Empty(t, signaled)
+	assert.Equal(t, "would send SIGTERM to 21 (worker)\nwould send SIGTERM to 30 (cron)\nwould send SIGTERM to 20 (worker)\n", output.String())
+}
+
+// TestKillRealOutput signals a real process with --kill
+func TestKillRealOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	sleep := exec.Command("sleep", "60")
+	require.NoError(t, sleep.Start())
+	done := make(chan error, 1)
+	go func() { done <- sleep.Wait() }()
+	defer sleep.Process.Kill()
+
+	pid := strconv.Itoa(sleep.Process.Pid)
+
+	// Without confirmation nothing is signaled
+	cmd := exec.Command(binaryPath, "--pid", pid, "--kill", "TERM")
+	cmd.Stdin = strings.NewReader("n\n")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "send SIGTERM to this process? [y/N]")
+
+	cmd = exec.Command(binaryPath, "--pid", pid, "--kill", "TERM", "--yes")
+	output, err = cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the process was not signaled")
+	}
+}
+
+func TestServeOptions(t *testing.T) {
+	base := pstree.DisplayOptions{ColorSupport: true, ColorizeOutput: true, CompactMode: true, MaxDepth: 999}
+
+	options, err := serveOptions(base, url.Values{"contains": {"nginx"}, "cpu": {"1"}, "compact-not": {"true"}, "pid": {"1", "42"}, "user": {"root", "www-data"}, "level": {"2"}})
+	require.NoError(t, err)
+	assert.Equal(t, "nginx", options.Contains)
+	assert.True(t, options.ShowCpuPercent)
+	assert.False(t, options.CompactMode)
+	assert.Equal(t, []int32{1, 42}, options.RootPIDs)
+	assert.Equal(t, []string{"root", "www-data"}, options.Usernames)
+	assert.Equal(t, 2, options.MaxDepth)
+
+	// The served tree is never colored nor truncated
+	assert.False(t, options.ColorSupport)
+	assert.False(t, options.ColorizeOutput)
+	assert.True(t, options.WideDisplay)
+
+	// The options given on the command line are left alone
+	assert.True(t, base.CompactMode)
+	assert.Empty(t, base.Contains)
+
+	// order-by=start sorts by age in the opposite direction, whatever the order of the parameters
+	options, err = serveOptions(base, url.Values{"order-by": {"start"}, "order-dir": {"asc"}})
+	require.NoError(t, err)
+	assert.Equal(t, "age", options.OrderBy)
+	assert.Equal(t, "desc", options.OrderDir)
+	assert.True(t, options.ShowStartTime)
+
+	for _, query := range []url.Values{{"cpu": {"maybe"}}, {"level": {"0"}}, {"order-by": {"color"}}, {"pid": {"init"}}, {"bogus": {"1"}}} {
+		_, err = serveOptions(base, query)
+		assert.Error(t, err, query.Encode())
+	}
+}
+
+func TestServeHandler(t *testing.T) {
+	savedLogger, savedOptions := logger.Logger, displayOptions
+	logger.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	displayOptions = pstree.DisplayOptions{CompactMode: true, MaxDepth: 999, ScreenWidth: 80}
+	defer func() { logger.Logger, displayOptions = savedLogger, savedOptions }()
+
+	server := &snapshotServer{}
+	handler := server.handler()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	// Nothing is served before the first snapshot
+	assert.Equal(t, http.StatusServiceUnavailable, get("/healthz").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/tree").Code)
+
+	server.processes = []pstree.Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", HasCPU: true, CPUPercent: 0.5},
+		{PID: 100, PPID: 1, Command: "nginx", Username: "root", HasCPU: true, CPUPercent: 7.0},
+		{PID: 200, PPID: 1, Command: "cron", Username: "root"},
+	}
+	assert.Equal(t, "ok\n", get("/healthz").Body.String())
+
+	response := get("/tree?contains=nginx&cpu=1")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Equal(t, "-+- (c:0.50%) init \n \\--- (c:7.00%) nginx* \n", response.Body.String())
+
+	// The filters of a request don't change the shared snapshot
+	assert.Contains(t, get("/tree").Body.String(), "cron")
+
+	response = get("/tree.json?show-pids=1")
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[{"command":"init","pid":1,"ppid":0,"children":[{"command":"nginx","pid":100,"ppid":1},{"command":"cron","pid":200,"ppid":1}]}]`, response.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, get("/tree?level=none").Code)
+
+	// A failed collection is reported, the last snapshot is still served
+	server.err = errors.New("permission denied")
+	assert.Equal(t, http.StatusServiceUnavailable, get("/healthz").Code)
+	assert.Equal(t, http.StatusOK, get("/tree").Code)
+}
+
+// TestServeRealOutput serves the tree from a real process and shuts it down with SIGTERM
+func TestServeRealOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	serve := exec.Command(binaryPath, "--serve", address, "--interval", "1")
+	require.NoError(t, serve.Start())
+	done := make(chan error, 1)
+	go func() { done <- serve.Wait() }()
+
+	var response *http.Response
+	require.Eventually(t, func() bool {
+		response, err = http.Get("http://" + address + "/healthz")
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	}, 10*time.Second, 100*time.Millisecond)
+
+	response, err = http.Get("http://" + address + "/tree?show-pids=1")
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), fmt.Sprintf("(%d)", serve.Process.Pid))
+
+	require.NoError(t, serve.Process.Signal(syscall.SIGTERM))
+	select {
+	case err = <-done:
+		assert.NoError(t, err, "pstree should exit cleanly on SIGTERM")
+	case <-time.After(10 * time.Second):
+		serve.Process.Kill()
+		t.Fatal("pstree did not exit on SIGTERM")
+	}
+}
+
+func TestProfileCollection(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "pstree")
+
+	called := false
+	require.NoError(t, profileCollection(prefix, func() error {
+		called = true
+		return nil
+	}))
+	assert.True(t, called)
+	for _, name := range []string{prefix + ".cpu.pprof", prefix + ".heap.pprof"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.Positive(t, info.Size(), name)
+	}
+
+	// A failed collection is returned and no heap profile is written
+	prefix = filepath.Join(t.TempDir(), "failed")
+	collectErr := errors.New("failed to get processes")
+	assert.ErrorIs(t, profileCollection(prefix, func() error { return collectErr }), collectErr)
+	assert.NoFileExists(t, prefix+".heap.pprof")
+
+	assert.Error(t, profileCollection(filepath.Join(t.TempDir(), "missing", "pstree"), func() error { return nil }))
+}
+
+// TestBrokenPipeRealOutput pipes pstree into a reader that goes away after a few lines, like head -3
+func TestBrokenPipeRealOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// The tree has to be larger than the pipe buffer, so pstree is still writing when the reader goes away
+	processes := []pstree.Process{{PID: 1, PPID: 0, Command: "init"}}
+	for pid := int32(2); pid <= 10000; pid++ {
+		processes = append(processes, pstree.Process{PID: pid, PPID: 1, Command: fmt.Sprintf("worker-%d", pid)})
+	}
+	snapshotFile := filepath.Join(t.TempDir(), "snapshot.json")
+	file, err := os.Create(snapshotFile)
+	require.NoError(t, err)
+	require.NoError(t, pstree.WriteSnapshot(file, pstree.NewSnapshot(processes, 0)))
+	require.NoError(t, file.Close())
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	var stderr bytes.Buffer
+	pstreeCmd := exec.Command(binaryPath, "--from-file", snapshotFile, "--compact-not")
+	pstreeCmd.Stdout = writer
+	pstreeCmd.Stderr = &stderr
+	require.NoError(t, pstreeCmd.Start())
+	writer.Close()
+
+	scanner := bufio.NewScanner(reader)
+	for range 3 {
+		require.True(t, scanner.Scan())
+	}
+	reader.Close()
+
+	assert.NoError(t, pstreeCmd.Wait(), "pstree should exit 0 when its reader goes away")
+	assert.Empty(t, stderr.String())
+}
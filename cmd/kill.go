@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bananazon/pstree/pkg/pstree"
+)
+
+// sendSignal sends a signal to a process, tests replace it to record the signals instead.
+var sendSignal = pstree.SendSignal
+
+// signalProcesses sends the --kill signal to the displayed processes after the tree was printed.
+//
+// Unless --yes is given, the user is asked to confirm before anything is signaled, and anything
+// but y or yes, including the end of the input, cancels. With --dry-run, the processes are only
+// listed. A process that cannot be signaled is reported without stopping the others.
+//
+// Parameters:
+//   - targets: The processes to signal, children before parents, see ProcessTree.SignalTargets
+//   - input: Where the confirmation is read from
+//   - output: Where the --dry-run list is written to
+//   - errorOutput: Where the prompt and the failures are written to
+//
+// Returns:
+//   - error: An error if any of the processes could not be signaled
+func signalProcesses(targets []pstree.SignalTarget, input io.Reader, output io.Writer, errorOutput io.Writer) error {
+	var (
+		answer     string
+		failures   int
+		signalName string
+	)
+
+	signalName = "SIG" + strings.TrimPrefix(strings.ToUpper(flagKill), "SIG")
+
+	if len(targets) == 0 {
+		fmt.Fprintln(errorOutput, "no processes to signal")
+		return nil
+	}
+
+	if flagDryRun {
+		for _, target := range targets {
+			fmt.Fprintf(output, "would send %s to %d (%s)\n", signalName, target.PID, target.Command)
+		}
+		return nil
+	}
+
+	if !flagYes {
+		if len(targets) == 1 {
+			fmt.Fprintf(errorOutput, "send %s to this process? [y/N] ", signalName)
+		} else {
+			fmt.Fprintf(errorOutput, "send %s to these %d processes? [y/N] ", signalName, len(targets))
+		}
+		answer, _ = bufio.NewReader(input).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return nil
+		}
+	}
+
+	for _, target := range targets {
+		if err := sendSignal(target.PID, killSignal); err != nil {
+			fmt.Fprintf(errorOutput, "failed to send %s to %d (%s): %v\n", signalName, target.PID, target.Command, err)
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to signal %d of %d processes", failures, len(targets))
+	}
+	return nil
+}
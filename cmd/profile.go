@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// profileCollection runs the collection of the processes while writing pprof profiles of it.
+//
+// The CPU profile covers the whole collection and is written to <prefix>.cpu.pprof, the heap
+// profile is taken once the collection completed and is written to <prefix>.heap.pprof. Both
+// can be read with go tool pprof.
+//
+// Parameters:
+//   - prefix: Path the names of the profile files start with
+//   - collect: The function collecting the processes, e.g., collectProcesses
+//
+// Returns:
+//   - error: Any error returned by collect, or encountered while creating or writing the profiles
+func profileCollection(prefix string, collect func() error) error {
+	cpuFile, err := os.Create(prefix + ".cpu.pprof")
+	if err != nil {
+		return fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err = pprof.StartCPUProfile(cpuFile); err != nil {
+		cpuFile.Close()
+		return fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+
+	collectErr := collect()
+	pprof.StopCPUProfile()
+	if err = cpuFile.Close(); err != nil {
+		return fmt.Errorf("failed to write CPU profile: %w", err)
+	}
+	if collectErr != nil {
+		return collectErr
+	}
+
+	heapFile, err := os.Create(prefix + ".heap.pprof")
+	if err != nil {
+		return fmt.Errorf("failed to create heap profile: %w", err)
+	}
+	// The heap profile only reflects the allocations up to the last garbage collection
+	runtime.GC()
+	if err = pprof.WriteHeapProfile(heapFile); err != nil {
+		heapFile.Close()
+		return fmt.Errorf("failed to write heap profile: %w", err)
+	}
+	return heapFile.Close()
+}
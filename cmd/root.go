@@ -5,68 +5,196 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/bananazon/pstree/pkg/globals"
 	"github.com/bananazon/pstree/pkg/logger"
 	"github.com/bananazon/pstree/pkg/pstree"
 	"github.com/bananazon/pstree/util"
 	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/process"
 	"github.com/spf13/cobra"
 )
 
 var (
+	argsFilter              *regexp.Regexp
+	attrThresholds          []float64
 	colorCount              int
+	colorOutput             bool
+	colorScheme             string
 	colorSupport            bool
+	colorizeOutput          bool
+	collectionTimings       *pstree.CollectionTimings
+	customColors            map[string]string
 	debugLevel              int
+	deferredOptions         pstree.DisplayOptions
+	diffInterval            int
 	displayOptions          pstree.DisplayOptions
 	errorMessage            string
 	flagAge                 bool
+	flagAgeFormat           string
+	flagAlign               bool
+	flagASCII               bool
+	flagArgsFilter          string
 	flagArguments           bool
-	flagColor               bool
+	flagAttrThresholds      string
+	flagByPgroup            bool
+	flagCollapseChains      bool
+	flagColor               string
 	flagColorAttr           string
 	flagColorScheme         string
+	flagCommandFormat       string
 	flagCompactNot          bool
+	flagConnections         bool
+	flagContainers          bool
 	flagContains            string
 	flagCpu                 bool
+	flagCpuMode             string
+	flagCpuTime             bool
+	flagCumulative          bool
+	flagCwd                 bool
+	flagCwdUnder            string
+	flagDeletedMarker       string
+	flagDepthStats          bool
+	flagDiff                string
+	flagDryRun              bool
+	flagDumpSnapshot        string
+	flagEnvContains         string
+	flagEnvShow             bool
+	flagExclude             []string
 	flagExcludeRoot         bool
+	flagFDs                 bool
+	flagFields              []string
+	flagFromFile            string
+	flagGroup               []string
+	flagGroupByContainer    bool
+	flagHighlightNice       bool
+	flagHighlightPid        int
+	flagHighlightSelf       bool
 	flagIBM850              bool
+	flagInterval            int
+	flagIO                  bool
+	flagKeepVanished        bool
+	flagKill                string
 	flagLevel               int
+	flagLookupTimeout       time.Duration
 	flagMapBasedTree        bool // New flag for using the map-based tree structure
+	flagMatchSubtree        bool
+	flagMaxArgs             int
+	flagMemField            string
+	flagMemFormat           string
+	flagMemMode             string
+	flagMemPercent          bool
+	flagMemUnit             string
 	flagMemory              bool
+	flagMinCPU              float64
+	flagMinMem              string
+	flagNamespaces          bool
+	flagNice                bool
+	flagNSPids              bool
+	flagNoKernelThreads     bool
+	flagNoRootLine          bool
+	flagNotUser             []string
+	flagNumeric             bool
+	flagOlderThan           string
+	flagOnlyDeleted         bool
+	flagOnlyForeignNS       bool
+	flagOnlyPrivileged      bool
+	flagOnlyRealtime        bool
+	flagOnlyZombies         bool
 	flagOrderBy             string
-	flagPid                 int32
+	flagOrderDir            string
+	flagOrphanSymbol        string
+	flagOutput              string
+	flagPageFaults          string
+	flagParentsOf           int
+	flagPid                 []int
+	flagPrivileged          bool
+	flagProfile             string
+	flagQuiet               bool
 	flagRainbow             bool
+	flagRawArgs             bool
+	flagRootCmd             string
+	flagRootCmdRegex        string
+	flagSched               bool
+	flagServe               string
+	flagSession             int
 	flagShowAll             bool
+	flagShowDepth           bool
+	flagShowOrphans         bool
 	flagShowOwner           bool
+	flagShowParent          bool
 	flagShowPGIDs           bool
 	flagShowPGLs            bool
 	flagShowPIDs            bool
 	flagShowPPIDs           bool
+	flagShowSIDs            bool
+	flagShowStatus          bool
+	flagStartTime           bool
+	flagStream              bool
+	flagSummary             bool
 	flagShowUIDTransitions  bool
 	flagShowUserTransitions bool
+	flagSnapshotRepair      string
 	flagThreads             bool
+	flagThreadsTree         bool
+	flagTop                 int
+	flagTTY                 string
 	flagUsername            []string
+	flagUTC                 bool
 	flagUTF8                bool
 	flagVersion             bool
 	flagVT100               bool
+	flagWatch               bool
 	flagWide                bool
+	flagWithChildren        bool
+	flagWrap                bool
+	flagYes                 bool
+	flagYoungerThan         string
+	flagZombies             bool
+	cpuCount                int
+	excludedUsers           []string
+	groupIDs                []uint32
 	installedMemory         *mem.VirtualMemoryStat
+	killSignal              syscall.Signal
+	minMemory               uint64
+	miniOptions             pstree.DisplayOptions
+	olderThan               time.Duration
 	processes               []pstree.Process
 	processTree             *pstree.ProcessTree
-	screenWidth             int
-	sorted                  []pstree.Process
+	rootCmdPattern          *regexp.Regexp
+	rootPIDs                []int32
+	screen                  screenSize
+	terminal                string
 	usageTemplate           string
 	username                string
-	validAttributes         []string = []string{"age", "cpu", "mem"}
+	youngerThan             time.Duration
+	validAgeFormats         []string = []string{"dhms", "hms", "human", "seconds"}
+	validAttributes         []string = []string{"age", "cpu", "cputime", "fds", "mem", "user"}
+	validColorModes         []string = []string{"always", "auto", "never"}
 	validColorSchemes       []string = []string{"darwin", "linux", "powershell", "windows10", "xterm"}
-	validOrderBy            []string = []string{"age", "cpu", "mem", "pid", "threads", "user"}
-	version                 string   = "0.9.6"
-	versionString           string
-	rootCmd                 = &cobra.Command{
+	validCommandFormats     []string = []string{"basename", "full"}
+	validCpuModes           []string = []string{"irix", "solaris"}
+	validMemFields          []string = []string{"rss", "swap", "vms"}
+	validMemFormats         []string = []string{"abs", "pct", "both"}
+	validMemModes           []string = []string{"rss", "pss", "uss"}
+	validMemUnits           []string = []string{"auto", "K", "M", "G"}
+	validOrderBy            []string = []string{"age", "cpu", "cputime", "faults", "fds", "io", "mem", "nice", "pid", "start", "threads", "user"}
+	validOrderDir           []string = []string{"asc", "desc"}
+	validOutputs            []string = []string{"csv", "dot", "markdown", "tree", "tsv"}
+	validPageFaults         []string = []string{"all", "major"}
+	validSnapshotRepairs    []string = []string{"off", pstree.SnapshotRepairRefetch, pstree.SnapshotRepairReparent}
+	rootCmd                          = &cobra.Command{
 		Use:    "pstree",
 		Short:  "",
+		Args:   cobra.ArbitraryArgs, // The arguments were always ignored, they are not unknown subcommands
 		Long:   fmt.Sprintf("pstree $Revision: %s $ by Cursed Bananazon (C) 2025, 2026", version),
 		PreRun: pstreePreRunCmd,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
@@ -79,13 +207,61 @@ var (
 	}
 )
 
+// Exit statuses of the pstree command, see ExitCode
+const (
+	// The tree was printed
+	ExitOK = 0
+	// None of the processes matched the filters, or pstree failed while running
+	ExitFailure = 1
+	// The command line was invalid, e.g., an unknown flag or a violated flag conflict rule
+	ExitUsage = 2
+)
+
+// ErrNoMatch is returned when none of the processes match the filters.
+var ErrNoMatch = errors.New("no processes match the given filters")
+
+// UsageError wraps an error caused by an invalid command line.
+type UsageError struct {
+	// The validation error
+	Err error
+}
+
+// Error returns the message of the validation error.
+func (usageError *UsageError) Error() string {
+	return usageError.Err.Error()
+}
+
+// Unwrap returns the validation error.
+func (usageError *UsageError) Unwrap() error {
+	return usageError.Err
+}
+
 // Execute runs the root command of the pstree application.
 // It serves as the entry point for the CLI application.
-// Returns any error encountered during command execution.
+// Returns any error encountered during command execution, see ExitCode for the matching exit status.
 func Execute() error {
 	return rootCmd.Execute()
 }
 
+// ExitCode returns the exit status matching an error returned by Execute.
+//
+// Parameters:
+//   - err: The error returned by Execute, or nil
+//
+// Returns:
+//   - int: ExitOK without an error, ExitUsage for an invalid command line, and ExitFailure otherwise
+func ExitCode(err error) int {
+	var usageError *UsageError
+
+	if err == nil {
+		return ExitOK
+	}
+	if errors.As(err, &usageError) {
+		return ExitUsage
+	}
+	return ExitFailure
+}
+
 // init initializes the root command with appropriate flags and usage template.
 // It determines the current username and color support capabilities of the terminal,
 // then sets up the command-line interface with appropriate usage instructions.
@@ -94,6 +270,13 @@ func init() {
 	colorSupport, colorCount = util.HasColorSupport()
 
 	GetPersistentFlags(rootCmd, colorSupport, colorCount, username)
+	registerCompletions(rootCmd)
+	rootCmd.AddCommand(completionCmd, versionCmd)
+
+	// --version is handled by cobra before the flags are validated, printing the same as the version command
+	rootCmd.Version = version
+	rootCmd.SetVersionTemplate("{{versionText}}")
+	cobra.AddTemplateFunc("versionText", versionText)
 
 	usageTemplate = fmt.Sprintf(`Usage: pstree [OPTIONS]
 
@@ -102,9 +285,14 @@ Display a tree of processes.
 Application Options:
 {{.Flags.FlagUsages}}
 Process group leaders are marked with '%s' for ASCII, '%s' for IBM-850, '%s' for VT-100, and '%s' for UTF-8.
-`, pstree.TreeStyles["ascii"].PGL, pstree.TreeStyles["pc850"].PGL, pstree.TreeStyles["vt100"].PGL, pstree.TreeStyles["utf8"].PGL)
+Session leaders are marked with '%s' for ASCII, '%s' for IBM-850, '%s' for VT-100, and '%s' for UTF-8 with --sids.
+`, pstree.TreeStyles["ascii"].PGL, pstree.TreeStyles["pc850"].PGL, pstree.TreeStyles["vt100"].PGL, pstree.TreeStyles["utf8"].PGL,
+		pstree.TreeStyles["ascii"].SL, pstree.TreeStyles["pc850"].SL, pstree.TreeStyles["vt100"].SL, pstree.TreeStyles["utf8"].SL)
 
 	rootCmd.SetUsageTemplate(usageTemplate)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &UsageError{Err: err}
+	})
 }
 
 // pstreePreRunCmd is executed before the main run command.
@@ -127,8 +315,26 @@ func pstreePreRunCmd(cmd *cobra.Command, args []string) {
 //   - args: Command line arguments passed to the command
 //
 // Returns:
-//   - error: Any error encountered during execution
-func pstreeRunCmd(cmd *cobra.Command, args []string) error {
+//   - err: Any error encountered during execution; errors found while validating the flags are
+//     returned as a UsageError, and ErrNoMatch is returned when no processes match the filters
+func pstreeRunCmd(cmd *cobra.Command, args []string) (err error) {
+	// The usage is only shown for errors found while validating the flags, and those are usage errors
+	defer func() {
+		if err != nil && !cmd.SilenceUsage {
+			err = &UsageError{Err: err}
+		}
+	}()
+
+	// The reader of the output going away, e.g., pstree | head, is not an error
+	defer func() {
+		if pstree.IsBrokenPipe(err) {
+			err = nil
+		}
+	}()
+
+	// Writing to a closed pipe then fails with EPIPE instead of killing pstree with SIGPIPE
+	signal.Ignore(syscall.SIGPIPE)
+
 	if debugLevel > 0 {
 		logger.Init(slog.LevelDebug)
 	} else {
@@ -141,30 +347,96 @@ func pstreeRunCmd(cmd *cobra.Command, args []string) error {
 	// to show if a flag is set, use cmd.Flags().Changed("flag")
 	//
 	// 1. --user cannot be used with --exclude-root
-	// 2. only one of --color-attr, --colorize, and --rainbow can be used
-	// 3. only one of --ibm-850, --utf-8, and --vt-100 can be use
-	// 4. valid options for --color-attr are: age, cpu, mem
+	// 2. only one of --color-attr and --rainbow can be used, --color only decides when their colors are written
+	// 3. only one of --ascii, --ibm-850, --utf-8, and --vt-100 can be used
+	// 4. valid options for --color-attr are: age, cpu, cputime, fds, mem, user
 	// 5. only one of --uid-transitions and --user-transitions can be used
 	// 6. --level cannot be set to less than 1
-	// 7. valid options for --color-scheme are: darwin, linux, windows10, xterm
-	// 8. --color-scheme cannot be used with --color-attr or --rainbow
+	// 7. valid options for --color-scheme are: darwin, linux, powershell, windows10, xterm, or a scheme file
+	// 8. --color-scheme cannot be used with --rainbow, and only a scheme file can be used with --color-attr
+	// 9. --interval cannot be set to less than 1
+	// 10. --match-subtree requires --contains, --cwd-under, --env-contains, --group, --min-cpu, --min-mem, or --tty
+	// 11. --pid cannot be set to less than 1
+	// 12. only one of --highlight-pid and --highlight-self can be used
+	// 13. --highlight-pid cannot be set to less than 1
+	// 14. valid options for --order-dir are: asc, desc
+	// 15. valid options for --output are: csv, dot, markdown, tree, tsv
+	// 16. valid options for --color are: always, auto, never
+	// 17. --attr-thresholds requires --color-attr with age, cpu, fds, or mem
+	// 18. --attr-thresholds must be increasing numbers, three for age and two for the other attributes
+	// 19. valid options for --age-format are: dhms, hms, human, seconds
+	// 20. --min-cpu cannot be set to less than 0
+	// 21. --min-mem must be a size, e.g., 512M
+	// 22. --tty without a terminal name requires pstree to be attached to a terminal
+	// 23. --from-file cannot be used with --connections or --watch
+	// 24. --dump-snapshot cannot be used with --watch
+	// 25. --summary can only be used with --output=tree
+	// 26. --wrap cannot be used with --wide
+	// 27. valid options for --command-format are: basename, full
+	// 28. valid options for --mem-field are: rss, swap, vms
+	// 29. valid options for --mem-unit are: auto, K, M, G
+	// 30. valid options for --mem-format are: abs, pct, both
+	// 31. --mem-percent cannot be used with --mem-format=abs
+	// 32. --kill requires --pid or --contains
+	// 33. valid options for --kill are: TERM, KILL, HUP, INT, USR1, USR2
+	// 34. --yes and --dry-run require --kill
+	// 35. --kill cannot be used with --watch, --from-file, or --dump-snapshot
+	// 36. valid options for --mem-mode are: rss, pss, uss
+	// 37. --mem-mode=pss or uss cannot be used with --mem-field=swap or vms
+	// 38. --show-depth and --depth-stats can only be used with --output=tree
+	// 39. --parents-of cannot be set to less than 1
+	// 40. --parents-of cannot be used with --pid, --contains, --user, --group, --tty, or --exclude-root
+	// 41. --with-children requires --parents-of
+	// 42. valid options for --page-faults are: all, major
+	// 43. --env-contains requires a variable name
+	// 44. --env-show requires --env-contains
+	// 45. --env-contains cannot be used with --from-file
+	// 46. --cwd-under requires a directory
+	// 47. valid options for --snapshot-repair are: off, refetch, reparent
+	// 48. --snapshot-repair cannot be used with --from-file
+	// 49. --diff requires a number of seconds of at least 1 or a snapshot file
+	// 50. --diff cannot be used with --from-file, --dump-snapshot, --watch, or --kill
+	// 51. --top cannot be set to less than 1
+	// 52. --top requires --order-by
+	// 53. --max-args cannot be set to less than 1
+	// 54. --args-filter must be a valid regular expression
+	// 55. --no-root-line requires --pid, --root-cmd, or --root-cmd-regex
+	// 56. --group-by-container cannot be used with --pid
+	// 57. --serve cannot be used with --watch, --dump-snapshot, --diff, --kill, or --output
+	// 58. --profile cannot be used with --watch or --serve
+	// 59. --align can only be used with --output=tree
+	// 60. --keep-vanished cannot be used with --from-file
+	// 61. valid fields for --fields are listed by pstree.FieldNames
+	// 62. valid options for --cpu-mode are: irix, solaris
+	// 63. --utc requires --start-time
+	// 64. --lookup-timeout must be positive
+	// 65. only one of --root-cmd and --root-cmd-regex can be used
+	// 66. --root-cmd and --root-cmd-regex cannot be used with --pid, --parents-of, or --group-by-container
+	// 67. --root-cmd-regex must be a valid regular expression
+	// 68. --younger-than and --older-than must be positive durations, e.g., 5m, 2h, or 1d
+	// 69. --older-than must be shorter than --younger-than
+	// 70. --collapse-chains can only be used with --output=tree
+	// 71. a user cannot be both shown with --user and hidden with --not-user or --user='!name'
+	// 72. --by-pgroup cannot be used with --show-orphans or --group-by-container
+	// 73. --session cannot be set to less than 1
+	// 74. --stream can only be used with --output=tree and cannot be used with --align
 
 	// Rule 1: --user root cannot be used with --exclude-root
 	if cmd.Flags().Changed("user") && flagExcludeRoot {
 		return errors.New("--user and --exclude-root cannot be used together")
 	}
 
-	// Rule 2: only one of --color-attr, --color, and --rainbow can be used
-	if (util.BtoI(flagColor) + util.BtoI(flagRainbow) + util.StoI(flagColorAttr)) > 1 {
-		return errors.New("only one of --color-attr, --color, and --rainbow can be used")
+	// Rule 2: only one of --color-attr and --rainbow can be used, --color only decides when their colors are written
+	if (util.BtoI(flagRainbow) + util.StoI(flagColorAttr)) > 1 {
+		return errors.New("only one of --color-attr and --rainbow can be used")
 	}
 
-	// Rule 3: only one of --ibm-850, --utf-8, and --vt-100 can be used
-	if (util.BtoI(flagIBM850) + util.BtoI(flagUTF8) + util.BtoI(flagVT100)) > 1 {
-		return errors.New("only one of --ibm-850, --utf-8, and --vt-100 can be used")
+	// Rule 3: only one of --ascii, --ibm-850, --utf-8, and --vt-100 can be used
+	if (util.BtoI(flagASCII) + util.BtoI(flagIBM850) + util.BtoI(flagUTF8) + util.BtoI(flagVT100)) > 1 {
+		return errors.New("only one of --ascii, --ibm-850, --utf-8, and --vt-100 can be used")
 	}
 
-	// Rule 4: valid options for --color-attr are: age, cpu, mem
+	// Rule 4: valid options for --color-attr are: age, cpu, cputime, fds, mem, user
 	if flagColorAttr != "" && !slices.Contains(validAttributes, flagColorAttr) {
 		return fmt.Errorf("valid options for --color-attr are: %s", strings.Join(validAttributes, ", "))
 	}
@@ -179,44 +451,460 @@ func pstreeRunCmd(cmd *cobra.Command, args []string) error {
 		return errors.New("--level cannot be set to less than 1")
 	}
 
-	// Rule 7: valid options for --color-scheme are: darwin, linux, windows10, xterm
+	// Rule 7: valid options for --color-scheme are: darwin, linux, powershell, windows10, xterm, or a scheme file
+	colorScheme = flagColorScheme
+	customColors = nil
 	if flagColorScheme != "" && !slices.Contains(validColorSchemes, flagColorScheme) {
-		return fmt.Errorf("valid options for --color-scheme are: %s", strings.Join(validColorSchemes, ", "))
+		path, err := colorSchemePath(flagColorScheme)
+		if err != nil {
+			return err
+		}
+		customColors, err = pstree.LoadColorSchemeFile(path)
+		if err != nil {
+			return err
+		}
+		// The elements missing from the file keep the colors of the default scheme
+		colorScheme = ""
 	}
 
-	// Rule 8: --color-scheme cannot be used with --color-attr or --rainbow
-	if flagColorScheme != "" && (flagColorAttr != "" || flagRainbow) {
-		return errors.New("--color-scheme cannot be used with --color-attr or --rainbow")
+	// Rule 8: --color-scheme cannot be used with --rainbow, and only a scheme file can be used with --color-attr
+	if flagColorScheme != "" && flagRainbow {
+		return errors.New("--color-scheme cannot be used with --rainbow")
+	}
+	if flagColorScheme != "" && customColors == nil && flagColorAttr != "" {
+		return errors.New("only a color scheme file can be used with --color-attr")
 	}
 
-	if flagVersion {
-		versionString = fmt.Sprintf(`pstree %s
-Copyright (C) 2025, 2026 Cursed Bananazon
+	// Rule 9: --interval cannot be set to less than 1
+	if cmd.Flags().Changed("interval") && flagInterval < 1 {
+		return errors.New("--interval cannot be set to less than 1")
+	}
 
-pstree comes with ABSOLUTELY NO WARRANTY.
-This is free software, and you are welcome to redistribute it under
-the terms of the GNU General Public License.
-For more information about these matters, see the file named LICENSE.`,
-			version,
-		)
-		fmt.Fprintln(os.Stdout, versionString)
-		os.Exit(0)
+	// Rule 10: --match-subtree requires --contains, --cwd-under, --env-contains, --group, --min-cpu, --min-mem, or --tty
+	if flagMatchSubtree && flagContains == "" && flagCwdUnder == "" && flagEnvContains == "" && len(flagGroup) == 0 && flagMinCPU <= 0 && flagMinMem == "" && !cmd.Flags().Changed("tty") {
+		return errors.New("--match-subtree requires --contains, --cwd-under, --env-contains, --group, --min-cpu, --min-mem, or --tty")
 	}
 
-	for i, username := range flagUsername {
-		if !util.UserExists(username) {
-			excluded := []int{}
-			excluded = append(excluded, i)
-			logger.Logger.Warn(fmt.Sprintf("user '%s' does not exist, excluding", username))
-			if len(excluded) > 0 {
-				slices.Reverse(excluded)
-				for i := range excluded {
-					flagUsername = util.DeleteSliceElement(flagUsername, i)
-				}
+	// Rule 11: --pid cannot be set to less than 1
+	rootPIDs = []int32{}
+	for _, pid := range flagPid {
+		if pid < 1 {
+			return errors.New("--pid cannot be set to less than 1")
+		}
+		rootPIDs = append(rootPIDs, int32(pid))
+	}
+
+	// Rule 12: only one of --highlight-pid and --highlight-self can be used
+	if cmd.Flags().Changed("highlight-pid") && flagHighlightSelf {
+		return errors.New("only one of --highlight-pid and --highlight-self can be used")
+	}
+
+	// Rule 13: --highlight-pid cannot be set to less than 1
+	if cmd.Flags().Changed("highlight-pid") && flagHighlightPid < 1 {
+		return errors.New("--highlight-pid cannot be set to less than 1")
+	}
+
+	// Rule 14: valid options for --order-dir are: asc, desc
+	if !slices.Contains(validOrderDir, flagOrderDir) {
+		errorMessage = fmt.Sprintf("valid options for --order-dir are: %s", strings.Join(validOrderDir, ", "))
+		return errors.New(errorMessage)
+	}
+
+	// Rule 15: valid options for --output are: csv, dot, markdown, tree, tsv
+	if !slices.Contains(validOutputs, flagOutput) {
+		errorMessage = fmt.Sprintf("valid options for --output are: %s", strings.Join(validOutputs, ", "))
+		return errors.New(errorMessage)
+	}
+
+	// Rule 16: valid options for --color are: always, auto, never
+	if !slices.Contains(validColorModes, flagColor) {
+		errorMessage = fmt.Sprintf("valid options for --color are: %s", strings.Join(validColorModes, ", "))
+		return errors.New(errorMessage)
+	}
+
+	// Rule 17: --attr-thresholds requires --color-attr with age, cpu, fds, or mem
+	if flagAttrThresholds != "" && flagColorAttr == "" {
+		return errors.New("--attr-thresholds requires --color-attr")
+	}
+	if flagAttrThresholds != "" && flagColorAttr == "user" {
+		return errors.New("--attr-thresholds cannot be used with --color-attr=user")
+	}
+
+	// Rule 18: --attr-thresholds must be increasing numbers, three for age and two for the other attributes
+	attrThresholds = nil
+	if flagAttrThresholds != "" {
+		var err error
+		attrThresholds, err = parseAttrThresholds(flagAttrThresholds, flagColorAttr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rule 19: valid options for --age-format are: dhms, hms, human, seconds
+	if !slices.Contains(validAgeFormats, flagAgeFormat) {
+		errorMessage = fmt.Sprintf("valid options for --age-format are: %s", strings.Join(validAgeFormats, ", "))
+		return errors.New(errorMessage)
+	}
+
+	// Rule 20: --min-cpu cannot be set to less than 0
+	if flagMinCPU < 0 {
+		return errors.New("--min-cpu cannot be set to less than 0")
+	}
+
+	// Rule 21: --min-mem must be a size, e.g., 512M
+	minMemory = 0
+	if flagMinMem != "" {
+		var err error
+		minMemory, err = util.ParseByteSize(flagMinMem)
+		if err != nil {
+			return fmt.Errorf("--min-mem: %w", err)
+		}
+	}
+
+	// Rule 22: --tty without a terminal name requires pstree to be attached to a terminal
+	terminal = ""
+	if flagTTY != "" {
+		var err error
+		terminal, err = ttyFilter()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rule 23: --from-file cannot be used with --connections or --watch
+	if flagFromFile != "" && flagConnections {
+		return errors.New("--from-file cannot be used with --connections")
+	}
+	if flagFromFile != "" && (flagWatch || cmd.Flags().Changed("interval")) {
+		return errors.New("--from-file cannot be used with --watch")
+	}
+
+	// Rule 24: --dump-snapshot cannot be used with --watch
+	if flagDumpSnapshot != "" && (flagWatch || cmd.Flags().Changed("interval")) {
+		return errors.New("--dump-snapshot cannot be used with --watch")
+	}
+
+	// Rule 25: --summary can only be used with --output=tree
+	if flagSummary && flagOutput != "tree" {
+		return errors.New("--summary can only be used with --output=tree")
+	}
+
+	// Rule 26: --wrap cannot be used with --wide
+	if flagWrap && flagWide {
+		return errors.New("--wrap and --wide cannot be used together")
+	}
+
+	// Rule 27: valid options for --command-format are: basename, full
+	if !slices.Contains(validCommandFormats, flagCommandFormat) {
+		return fmt.Errorf("valid options for --command-format are: %s", strings.Join(validCommandFormats, ", "))
+	}
+
+	// Rule 28: valid options for --mem-field are: rss, swap, vms
+	if !slices.Contains(validMemFields, flagMemField) {
+		return fmt.Errorf("valid options for --mem-field are: %s", strings.Join(validMemFields, ", "))
+	}
+
+	// Rule 29: valid options for --mem-unit are: auto, K, M, G
+	if !slices.Contains(validMemUnits, flagMemUnit) {
+		return fmt.Errorf("valid options for --mem-unit are: %s", strings.Join(validMemUnits, ", "))
+	}
+
+	// Rule 30: valid options for --mem-format are: abs, pct, both
+	if !slices.Contains(validMemFormats, flagMemFormat) {
+		return fmt.Errorf("valid options for --mem-format are: %s", strings.Join(validMemFormats, ", "))
+	}
+
+	// Rule 31: --mem-percent cannot be used with --mem-format=abs
+	if flagMemPercent && cmd.Flags().Changed("mem-format") && flagMemFormat == "abs" {
+		return errors.New("--mem-percent cannot be used with --mem-format=abs")
+	}
+
+	// Rule 32: --kill requires --pid or --contains
+	if flagKill != "" && len(flagPid) == 0 && flagContains == "" {
+		return errors.New("--kill requires --pid or --contains")
+	}
+
+	// Rule 33: valid options for --kill are: TERM, KILL, HUP, INT, USR1, USR2
+	if flagKill != "" {
+		killSignal, err = pstree.ParseSignal(flagKill)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rule 34: --yes and --dry-run require --kill
+	if (flagYes || flagDryRun) && flagKill == "" {
+		return errors.New("--yes and --dry-run require --kill")
+	}
+
+	// Rule 35: --kill cannot be used with --watch, --from-file, or --dump-snapshot
+	if flagKill != "" && (flagWatch || flagFromFile != "" || flagDumpSnapshot != "") {
+		return errors.New("--kill cannot be used with --watch, --from-file, or --dump-snapshot")
+	}
+
+	// Rule 36: valid options for --mem-mode are: rss, pss, uss
+	if !slices.Contains(validMemModes, flagMemMode) {
+		return fmt.Errorf("valid options for --mem-mode are: %s", strings.Join(validMemModes, ", "))
+	}
+
+	// Rule 37: --mem-mode=pss or uss cannot be used with --mem-field=swap or vms
+	if flagMemMode != "rss" && flagMemField != "rss" {
+		return fmt.Errorf("--mem-mode=%s cannot be used with --mem-field=%s", flagMemMode, flagMemField)
+	}
+
+	// Rule 38: --show-depth and --depth-stats can only be used with --output=tree
+	if (flagShowDepth || flagDepthStats) && flagOutput != "tree" {
+		return errors.New("--show-depth and --depth-stats can only be used with --output=tree")
+	}
+
+	// Rule 39: --parents-of cannot be set to less than 1
+	if cmd.Flags().Changed("parents-of") && flagParentsOf < 1 {
+		return errors.New("--parents-of cannot be set to less than 1")
+	}
+
+	// Rule 40: --parents-of cannot be used with --pid, --contains, --user, --group, --tty, or --exclude-root
+	if cmd.Flags().Changed("parents-of") && (len(flagPid) > 0 || flagContains != "" || len(flagUsername) > 0 || len(flagGroup) > 0 || cmd.Flags().Changed("tty") || flagExcludeRoot) {
+		return errors.New("--parents-of cannot be used with --pid, --contains, --user, --group, --tty, or --exclude-root")
+	}
+
+	// Rule 41: --with-children requires --parents-of
+	if flagWithChildren && !cmd.Flags().Changed("parents-of") {
+		return errors.New("--with-children requires --parents-of")
+	}
+
+	// Rule 42: valid options for --page-faults are: all, major
+	if cmd.Flags().Changed("page-faults") && !slices.Contains(validPageFaults, flagPageFaults) {
+		return fmt.Errorf("valid options for --page-faults are: %s", strings.Join(validPageFaults, ", "))
+	}
+
+	// Rule 43: --env-contains requires a variable name
+	if cmd.Flags().Changed("env-contains") && (flagEnvContains == "" || strings.HasPrefix(flagEnvContains, "=")) {
+		return errors.New("--env-contains requires a variable name, e.g., FEATURE_X or FEATURE_X=on")
+	}
+
+	// Rule 44: --env-show requires --env-contains
+	if flagEnvShow && flagEnvContains == "" {
+		return errors.New("--env-show requires --env-contains")
+	}
+
+	// Rule 45: --env-contains cannot be used with --from-file
+	if flagEnvContains != "" && flagFromFile != "" {
+		return errors.New("--env-contains cannot be used with --from-file")
+	}
+
+	// Rule 46: --cwd-under requires a directory
+	if cmd.Flags().Changed("cwd-under") {
+		if flagCwdUnder == "" {
+			return errors.New("--cwd-under requires a directory")
+		}
+		// The working directories are absolute, so a relative directory is taken from the current one
+		cwdUnder, err := filepath.Abs(flagCwdUnder)
+		if err != nil {
+			return fmt.Errorf("--cwd-under: %w", err)
+		}
+		flagCwdUnder = cwdUnder
+	}
+
+	// Rule 47: valid options for --snapshot-repair are: off, refetch, reparent
+	if !slices.Contains(validSnapshotRepairs, flagSnapshotRepair) {
+		return fmt.Errorf("valid options for --snapshot-repair are: %s", strings.Join(validSnapshotRepairs, ", "))
+	}
+
+	// Rule 48: --snapshot-repair cannot be used with --from-file
+	if flagSnapshotRepair != "off" && flagFromFile != "" {
+		return errors.New("--snapshot-repair cannot be used with --from-file")
+	}
+
+	// Rule 49: --diff requires a number of seconds of at least 1 or a snapshot file
+	if cmd.Flags().Changed("diff") {
+		if seconds, err := strconv.Atoi(flagDiff); err == nil {
+			if seconds < 1 {
+				return errors.New("--diff cannot be set to less than 1 second")
 			}
+			diffInterval = seconds
+		} else if info, err := os.Stat(flagDiff); err != nil || info.IsDir() {
+			return fmt.Errorf("--diff requires a number of seconds or a snapshot file: %s", flagDiff)
 		}
 	}
 
+	// Rule 50: --diff cannot be used with --from-file, --dump-snapshot, --watch, or --kill
+	if flagDiff != "" && (flagFromFile != "" || flagDumpSnapshot != "" || flagWatch || cmd.Flags().Changed("interval") || flagKill != "") {
+		return errors.New("--diff cannot be used with --from-file, --dump-snapshot, --watch, or --kill")
+	}
+
+	// Rule 51: --top cannot be set to less than 1
+	if cmd.Flags().Changed("top") && flagTop < 1 {
+		return errors.New("--top cannot be set to less than 1")
+	}
+
+	// Rule 52: --top requires --order-by
+	if flagTop > 0 && flagOrderBy == "" {
+		return errors.New("--top requires --order-by")
+	}
+
+	// Rule 53: --max-args cannot be set to less than 1
+	if cmd.Flags().Changed("max-args") && flagMaxArgs < 1 {
+		return errors.New("--max-args cannot be set to less than 1")
+	}
+
+	// Rule 54: --args-filter must be a valid regular expression
+	argsFilter = nil
+	if flagArgsFilter != "" {
+		var err error
+		argsFilter, err = regexp.Compile(flagArgsFilter)
+		if err != nil {
+			return fmt.Errorf("--args-filter: %w", err)
+		}
+	}
+
+	// Rule 55: --no-root-line requires --pid, --root-cmd, or --root-cmd-regex
+	if flagNoRootLine && len(flagPid) == 0 && flagRootCmd == "" && flagRootCmdRegex == "" {
+		return errors.New("--no-root-line requires --pid, --root-cmd, or --root-cmd-regex")
+	}
+
+	// Rule 56: --group-by-container cannot be used with --pid
+	if flagGroupByContainer && len(flagPid) > 0 {
+		return errors.New("--group-by-container cannot be used with --pid")
+	}
+
+	// Rule 57: --serve cannot be used with --watch, --dump-snapshot, --diff, --kill, or --output
+	if flagServe != "" && (flagWatch || flagDumpSnapshot != "" || flagDiff != "" || flagKill != "" || cmd.Flags().Changed("output")) {
+		return errors.New("--serve cannot be used with --watch, --dump-snapshot, --diff, --kill, or --output")
+	}
+
+	// Rule 58: --profile cannot be used with --watch or --serve
+	if flagProfile != "" && (flagWatch || flagServe != "") {
+		return errors.New("--profile cannot be used with --watch or --serve")
+	}
+
+	// Rule 59: --align can only be used with --output=tree
+	if flagAlign && flagOutput != "tree" {
+		return errors.New("--align can only be used with --output=tree")
+	}
+
+	// Rule 60: --keep-vanished cannot be used with --from-file
+	if flagKeepVanished && flagFromFile != "" {
+		return errors.New("--keep-vanished cannot be used with --from-file")
+	}
+
+	// Rule 61: valid fields for --fields are listed by pstree.FieldNames
+	if err := pstree.ValidateFields(flagFields); err != nil {
+		return err
+	}
+
+	// Rule 62: valid options for --cpu-mode are: irix, solaris
+	if !slices.Contains(validCpuModes, flagCpuMode) {
+		return fmt.Errorf("valid options for --cpu-mode are: %s", strings.Join(validCpuModes, ", "))
+	}
+
+	// Rule 63: --utc requires --start-time
+	if flagUTC && !flagStartTime && flagOrderBy != "start" {
+		return errors.New("--utc requires --start-time")
+	}
+
+	// Rule 64: --lookup-timeout must be positive
+	if flagLookupTimeout <= 0 {
+		return errors.New("--lookup-timeout must be positive")
+	}
+
+	// Rule 65: only one of --root-cmd and --root-cmd-regex can be used
+	if flagRootCmd != "" && flagRootCmdRegex != "" {
+		return errors.New("only one of --root-cmd and --root-cmd-regex can be used")
+	}
+
+	// Rule 66: --root-cmd and --root-cmd-regex cannot be used with --pid, --parents-of, or --group-by-container
+	if (flagRootCmd != "" || flagRootCmdRegex != "") && (len(flagPid) > 0 || cmd.Flags().Changed("parents-of") || flagGroupByContainer) {
+		return errors.New("--root-cmd and --root-cmd-regex cannot be used with --pid, --parents-of, or --group-by-container")
+	}
+
+	// Rule 67: --root-cmd-regex must be a valid regular expression
+	rootCmdPattern = nil
+	if flagRootCmdRegex != "" {
+		var err error
+		rootCmdPattern, err = regexp.Compile(flagRootCmdRegex)
+		if err != nil {
+			return fmt.Errorf("--root-cmd-regex: %w", err)
+		}
+	}
+
+	// Rule 68: --younger-than and --older-than must be positive durations, e.g., 5m, 2h, or 1d
+	youngerThan, err = parseAge(flagYoungerThan, "--younger-than")
+	if err != nil {
+		return err
+	}
+	olderThan, err = parseAge(flagOlderThan, "--older-than")
+	if err != nil {
+		return err
+	}
+
+	// Rule 69: --older-than must be shorter than --younger-than
+	if youngerThan > 0 && olderThan >= youngerThan {
+		return errors.New("--older-than must be shorter than --younger-than, no process can be both")
+	}
+
+	// Rule 70: --collapse-chains can only be used with --output=tree
+	if flagCollapseChains && flagOutput != "tree" {
+		return errors.New("--collapse-chains can only be used with --output=tree")
+	}
+
+	// Rule 71: a user cannot be both shown with --user and hidden with --not-user or --user='!name'
+	flagUsername, excludedUsers = util.SplitUsernames(flagUsername)
+	excludedUsers = append(excludedUsers, flagNotUser...)
+	for _, username := range flagUsername {
+		if slices.Contains(excludedUsers, username) {
+			return fmt.Errorf("user '%s' cannot be both shown with --user and hidden with --not-user or --user='!%s'", username, username)
+		}
+	}
+
+	// Rule 72: --by-pgroup cannot be used with --show-orphans or --group-by-container
+	if flagByPgroup && (flagShowOrphans || flagGroupByContainer) {
+		return errors.New("--by-pgroup cannot be used with --show-orphans or --group-by-container, the processes are placed under their process group leader")
+	}
+
+	// Rule 73: --session cannot be set to less than 1
+	if cmd.Flags().Changed("session") && flagSession < 1 {
+		return errors.New("--session cannot be set to less than 1")
+	}
+
+	// Rule 74: --stream can only be used with --output=tree and cannot be used with --align
+	if flagStream && (flagOutput != "tree" || flagAlign) {
+		return errors.New("--stream can only be used with --output=tree and cannot be used with --align, which buffers the lines of the tree")
+	}
+
+	// The top processes are usually the largest ones, so --top sorts in descending order unless a direction is given
+	if flagTop > 0 && !cmd.Flags().Changed("order-dir") {
+		flagOrderDir = "desc"
+	}
+
+	// The users that don't exist are left out, so an unknown user doesn't hide the processes of the others
+	if len(flagUsername) > 0 {
+		var unknown []string
+		flagUsername, unknown = util.KnownUsernames(flagUsername, util.UserExists)
+		for _, username := range unknown {
+			logger.Logger.Warn(fmt.Sprintf("user '%s' does not exist, excluding", username))
+		}
+		if len(flagUsername) == 0 {
+			return fmt.Errorf("none of the users given with --user exist: %s", strings.Join(unknown, ", "))
+		}
+	}
+	if len(excludedUsers) > 0 {
+		var unknown []string
+		excludedUsers, unknown = util.KnownUsernames(excludedUsers, util.UserExists)
+		for _, username := range unknown {
+			logger.Logger.Warn(fmt.Sprintf("user '%s' does not exist, not hiding it", username))
+		}
+	}
+
+	groupIDs = []uint32{}
+	for _, group := range flagGroup {
+		gid, err := util.LookupGroupID(group)
+		if err != nil {
+			logger.Logger.Warn(fmt.Sprintf("group '%s' does not exist, excluding", group))
+			continue
+		}
+		groupIDs = append(groupIDs, gid)
+	}
+
 	if flagShowAll {
 		flagAge = true
 		flagArguments = true
@@ -229,119 +917,534 @@ For more information about these matters, see the file named LICENSE.`,
 		flagThreads = true
 	}
 
-	screenWidth = util.GetScreenWidth()
+	// Trimming the arguments, or showing them raw, implies showing them
+	if flagMaxArgs > 0 || argsFilter != nil || flagRawArgs {
+		flagArguments = true
+	}
 
-	miniOptions := pstree.DisplayOptions{
-		ColorAttr:           flagColorAttr,
-		OrderBy:             flagOrderBy,
-		ShowArguments:       flagArguments,
-		ShowCpuPercent:      flagCpu,
-		ShowMemoryUsage:     flagMemory,
-		ShowNumThreads:      flagThreads,
-		ShowOwner:           flagShowOwner,
-		ShowPGIDs:           flagShowPGIDs,
-		ShowPGLs:            flagShowPGLs,
-		ShowProcessAge:      flagAge,
-		ShowUIDTransitions:  flagShowUIDTransitions,
-		ShowUserTransitions: flagShowUserTransitions,
-		Usernames:           flagUsername,
+	// Highlighting the negative nice values implies showing them
+	if flagHighlightNice {
+		flagNice = true
+	}
+
+	// The namespace PIDs are shown next to the PIDs
+	if flagNSPids {
+		flagShowPIDs = true
+	}
+
+	// Choosing an age format implies showing the age
+	if cmd.Flags().Changed("age-format") {
+		flagAge = true
+	}
+
+	// --mem-percent is a shorthand for --mem-format=pct
+	if flagMemPercent && !cmd.Flags().Changed("mem-format") {
+		flagMemFormat = "pct"
+	}
+
+	// Choosing a CPU mode implies showing the CPU usage, and the usage is divided by the number of CPUs in solaris mode
+	if cmd.Flags().Changed("cpu-mode") {
+		flagCpu = true
+	}
+	if flagCpuMode == "solaris" {
+		cpuCount = util.GetCPUCount()
 	}
 
-	pstree.GetProcesses(&processes, miniOptions)
+	// Choosing a memory field, mode, unit, or format implies showing the memory usage
+	if cmd.Flags().Changed("mem-field") || cmd.Flags().Changed("mem-mode") || cmd.Flags().Changed("mem-unit") || cmd.Flags().Changed("mem-format") || flagMemPercent {
+		flagMemory = true
+	}
+
+	// Showing the UIDs implies showing the owner, unless they are shown with the transitions
+	if flagNumeric && !flagShowUIDTransitions && !flagShowUserTransitions {
+		flagShowOwner = true
+	}
+
+	// Choosing an orphan symbol implies showing the orphans
+	if cmd.Flags().Changed("orphan-symbol") {
+		flagShowOrphans = true
+	}
+
+	// Filtering by zombies implies marking them
+	if flagOnlyZombies {
+		flagZombies = true
+	}
+
+	// Likewise for the privileged processes
+	if flagOnlyPrivileged {
+		flagPrivileged = true
+	}
 
 	if flagOrderBy != "" {
 		if !slices.Contains(validOrderBy, flagOrderBy) {
 			errorMessage = fmt.Sprintf("valid options for --order-by are: %s", strings.Join(validOrderBy, ", "))
 			return errors.New(errorMessage)
 		}
-		proc, err := pstree.GetProcessByPid(&processes, 1)
-		if err != nil {
-			panic(err)
-		}
-		sorted = []pstree.Process{proc}
+		// Make sure the attribute we sort by is also displayed
 		switch flagOrderBy {
 		case "age":
 			flagAge = true
-			pstree.SortProcsByAge(&processes)
 		case "cpu":
 			flagCpu = true
-			pstree.SortProcsByCpu(&processes)
+		case "cputime":
+			flagCpuTime = true
+		case "faults":
+			if flagPageFaults == "" {
+				flagPageFaults = "major"
+			}
+		case "fds":
+			flagFDs = true
+		case "io":
+			flagIO = true
 		case "mem":
 			flagMemory = true
-			pstree.SortProcsByMemory(&processes)
+		case "nice":
+			flagNice = true
 		case "pid":
 			flagShowPIDs = true
-			pstree.SortProcsByPid(&processes)
+		case "start":
+			// The processes that started first are the oldest ones, so start sorts by age in the opposite direction
+			flagStartTime = true
+			flagOrderBy = "age"
+			if flagOrderDir == "desc" {
+				flagOrderDir = "asc"
+			} else {
+				flagOrderDir = "desc"
+			}
 		case "threads":
 			flagThreads = true
-			pstree.SortProcsByNumThreads(&processes)
 		case "user":
 			flagShowOwner = true
-			pstree.SortProcsByUsername(&processes)
-		default:
-			sorted = processes
 		}
+	}
 
-		for _, proc := range processes {
-			if proc.PID != 1 {
-				sorted = append(sorted, proc)
-			}
-		}
-		processes = sorted
+	// The flags are valid, errors from here on are not caused by the command line
+	cmd.SilenceUsage = true
+
+	// The scheduling policies are only read on Linux, elsewhere no process has one
+	if (flagSched || flagOnlyRealtime) && !pstree.SchedSupported {
+		logger.Logger.Warn("--sched and --only-realtime are not supported on this platform")
 	}
 
-	if flagColorScheme != "" {
-		flagColor = true
+	// Likewise, the containers are only read on Linux
+	if (flagContainers || flagGroupByContainer) && !pstree.ContainersSupported {
+		logger.Logger.Warn("--containers and --group-by-container are not supported on this platform")
+	}
+	if (flagNamespaces || flagOnlyForeignNS) && !pstree.NamespacesSupported {
+		logger.Logger.Warn("--namespaces and --only-foreign-ns are not supported on this platform")
+	}
+	if flagNSPids && !pstree.NamespacesSupported {
+		logger.Logger.Warn("--ns-pids is not supported on this platform")
 	}
 
-	if flagLevel == 0 {
-		flagLevel = 999
+	// Without process groups, --by-pgroup leaves each process under its parent
+	if flagByPgroup && !pstree.PGIDSupported {
+		logger.Logger.Warn("--by-pgroup is not supported on this platform")
+	}
+	if (flagShowSIDs || flagSession > 0) && !pstree.SIDSupported {
+		logger.Logger.Warn("--sids and --session are not supported on this platform")
+	}
+
+	// Make sure the attributes we filter by are also collected and displayed
+	if flagMinCPU > 0 {
+		flagCpu = true
+	}
+	if minMemory > 0 {
+		flagMemory = true
+	}
+
+	// Cumulative values are shown next to the CPU and memory usage, so at least one has to be displayed
+	if flagCumulative && !flagCpu && !flagMemory {
+		flagCpu = true
+		flagMemory = true
+	}
+
+	// With --serve, the interval is the one of the snapshot loop
+	if cmd.Flags().Changed("interval") && flagServe == "" {
+		flagWatch = true
+	}
+
+	screen = newScreenSize()
+
+	// --snapshot-repair=off leaves the processes as they are collected
+	snapshotRepair := flagSnapshotRepair
+	if snapshotRepair == "off" {
+		snapshotRepair = ""
+	}
+
+	miniOptions = pstree.DisplayOptions{
+		ByProcessGroup:      flagByPgroup,
+		ColorAttr:           flagColorAttr,
+		CwdUnder:            flagCwdUnder,
+		GroupByContainer:    flagGroupByContainer,
+		KeepVanished:        flagKeepVanished,
+		LookupTimeout:       flagLookupTimeout,
+		MemoryMode:          flagMemMode,
+		MinCPU:              flagMinCPU,
+		MinMemory:           minMemory,
+		Numeric:             flagNumeric,
+		OlderThan:           olderThan,
+		OnlyForeignNS:       flagOnlyForeignNS,
+		OnlyRealtime:        flagOnlyRealtime,
+		OrderBy:             flagOrderBy,
+		PageFaults:          flagPageFaults,
+		SessionOf:           int32(flagSession),
+		ShowArguments:       flagArguments,
+		ShowContainers:      flagContainers,
+		ShowCpuPercent:      flagCpu,
+		ShowCpuTime:         flagCpuTime,
+		ShowCumulative:      flagCumulative,
+		ShowCwd:             flagCwd,
+		ShowIO:              flagIO,
+		ShowMemoryUsage:     flagMemory,
+		ShowNamespaces:      flagNamespaces,
+		ShowNice:            flagNice,
+		ShowNSPids:          flagNSPids,
+		ShowNumFDs:          flagFDs,
+		ShowNumThreads:      flagThreads,
+		ShowOwner:           flagShowOwner,
+		ShowPGIDs:           flagShowPGIDs,
+		ShowPGLs:            flagShowPGLs,
+		ShowPrivileged:      flagPrivileged,
+		ShowProcessAge:      flagAge,
+		ShowSched:           flagSched,
+		ShowSIDs:            flagShowSIDs,
+		ShowStartTime:       flagStartTime,
+		ShowStatus:          flagShowStatus,
+		ShowThreadsTree:     flagThreadsTree,
+		ShowUIDTransitions:  flagShowUIDTransitions,
+		ShowUserTransitions: flagShowUserTransitions,
+		ShowZombies:         flagZombies,
+		SnapshotRepair:      snapshotRepair,
+		Terminal:            terminal,
+		Usernames:           flagUsername,
+		WatchInterval:       watchInterval(),
+		YoungerThan:         youngerThan,
+	}
+	if err := pstree.ApplyFields(&miniOptions, flagFields); err != nil {
+		return err
+	}
+
+	// The processes are matched by their create time, and the changes of the CPU usage and memory are shown for the processes in both snapshots
+	if flagDiff != "" {
+		miniOptions.ShowCpuPercent = true
+		miniOptions.ShowDiff = true
+		miniOptions.ShowMemoryUsage = true
 	}
 
-	// If any of the following flags are set, then compact mode should be disabled
-	if flagColorAttr != "" || flagContains != "" {
-		flagCompactNot = true
+	// A snapshot may be rendered with any display options later, so collect everything that can be displayed
+	if flagDumpSnapshot != "" {
+		miniOptions.MemoryMode = "pss"
+		miniOptions.ShowContainers = true
+		miniOptions.ShowCpuPercent = true
+		miniOptions.ShowCpuTime = true
+		miniOptions.ShowCwd = true
+		miniOptions.PageFaults = "all"
+		miniOptions.ShowIO = true
+		miniOptions.ShowMemoryUsage = true
+		miniOptions.ShowNamespaces = true
+		miniOptions.ShowNice = true
+		miniOptions.ShowNSPids = true
+		miniOptions.ShowNumFDs = true
+		miniOptions.ShowNumThreads = true
+		miniOptions.ShowOwner = true
+		miniOptions.ShowPGIDs = true
+		miniOptions.ShowProcessAge = true
+		miniOptions.ShowSched = true
+		miniOptions.ShowSIDs = true
+		miniOptions.ShowStatus = true
+		miniOptions.ShowUIDTransitions = true
+	}
+
+	// The query parameters of --serve can show these, and the CPU percentage is measured over the interval
+	if flagServe != "" {
+		miniOptions.ShowCpuPercent = true
+		miniOptions.ShowMemoryUsage = true
+		miniOptions.ShowNumThreads = true
+		miniOptions.ShowProcessAge = true
+		miniOptions.WatchInterval = flagInterval
+	}
+
+	// The command lines are one of the costlier reads, so only argv[0] is read unless something uses them
+	miniOptions.SkipArgs = !needArgs(miniOptions)
+
+	// The usage that is only displayed is collected after filtering, for the displayed processes only
+	if flagFromFile == "" && flagDumpSnapshot == "" {
+		miniOptions, deferredOptions = pstree.SplitCollection(miniOptions)
+	}
+
+	// --color-scheme implies --color, but whether any color is written is decided by the color mode:
+	// an explicit --color=always or --color=never wins, then NO_COLOR, then whether stdout is a terminal
+	// With --color-attr or --rainbow, --color=always forces their colors instead of the predefined ones
+	colorizeOutput = (flagColorScheme != "" || colorRequested(cmd)) && flagColorAttr == "" && !flagRainbow
+	colorOutput = util.ShouldColorize(flagColor, os.Getenv("NO_COLOR"), util.IsTerminal(os.Stdout), colorSupport)
+	if colorOutput && colorCount < 8 {
+		// The terminal capabilities are unknown, fall back to the basic colors
+		colorCount = 8
+	}
+
+	if flagLevel == 0 {
+		flagLevel = 999
 	}
 
 	displayOptions = pstree.DisplayOptions{
+		AgeFormat:           flagAgeFormat,
+		AlignColumns:        flagAlign,
+		ArgsFilter:          argsFilter,
+		ASCIIGraphics:       flagASCII,
+		AttrThresholds:      attrThresholds,
+		ByProcessGroup:      flagByPgroup,
+		CollapseChains:      flagCollapseChains,
 		ColorAttr:           flagColorAttr,
 		ColorCount:          colorCount,
-		ColorizeOutput:      flagColor,
-		ColorScheme:         flagColorScheme,
-		ColorSupport:        colorSupport,
+		ColorizeOutput:      colorizeOutput,
+		ColorScheme:         colorScheme,
+		ColorSupport:        colorOutput,
+		CommandFormat:       flagCommandFormat,
 		CompactMode:         !flagCompactNot,
+		CustomColors:        customColors,
 		Contains:            flagContains,
+		CPUMode:             flagCpuMode,
+		CwdUnder:            flagCwdUnder,
+		DeletedMarker:       flagDeletedMarker,
+		EnvContains:         flagEnvContains,
+		ExcludePatterns:     flagExclude,
 		ExcludeRoot:         flagExcludeRoot,
+		ExcludeUsernames:    excludedUsers,
+		GroupByContainer:    flagGroupByContainer,
+		Groups:              groupIDs,
+		HideKernelThreads:   flagNoKernelThreads,
+		HighlightNice:       flagHighlightNice,
+		HighlightPID:        highlightPID(),
 		IBM850Graphics:      flagIBM850,
 		InstalledMemory:     installedMemory.Total,
+		MatchSubtree:        flagMatchSubtree,
+		MaxArgs:             flagMaxArgs,
 		MaxDepth:            flagLevel,
+		MemoryField:         flagMemField,
+		MemoryFormat:        flagMemFormat,
+		MemoryMode:          flagMemMode,
+		MemoryUnit:          flagMemUnit,
+		MinCPU:              flagMinCPU,
+		MinMemory:           minMemory,
+		NoRootLine:          flagNoRootLine,
+		NumCPU:              cpuCount,
+		Numeric:             flagNumeric,
+		OlderThan:           olderThan,
+		OnlyDeleted:         flagOnlyDeleted,
+		OnlyForeignNS:       flagOnlyForeignNS,
+		OnlyPrivileged:      flagOnlyPrivileged,
+		OnlyRealtime:        flagOnlyRealtime,
+		OnlyZombies:         flagOnlyZombies,
 		OrderBy:             flagOrderBy,
-		RainbowOutput:       flagRainbow,
-		RootPID:             flagPid,
-		ScreenWidth:         screenWidth,
+		OrderDir:            flagOrderDir,
+		OrphanSymbol:        flagOrphanSymbol,
+		PageFaults:          flagPageFaults,
+		ParentsOf:           int32(flagParentsOf),
+		RainbowOutput:       flagRainbow && colorOutput,
+		RawArgs:             flagRawArgs,
+		RootCommand:         flagRootCmd,
+		RootCommandPattern:  rootCmdPattern,
+		RootPIDs:            rootPIDs,
+		ScreenWidth:         screen.Width(),
+		SessionOf:           int32(flagSession),
 		ShowArguments:       flagArguments,
+		ShowConnections:     flagConnections,
+		ShowContainers:      flagContainers,
 		ShowCpuPercent:      flagCpu,
+		ShowCpuTime:         flagCpuTime,
+		ShowCumulative:      flagCumulative,
+		ShowCwd:             flagCwd,
+		ShowDepth:           flagShowDepth,
+		ShowDepthStats:      flagDepthStats,
+		ShowDiff:            flagDiff != "",
+		ShowEnv:             flagEnvShow,
+		ShowIO:              flagIO,
 		ShowMemoryUsage:     flagMemory,
+		ShowNamespaces:      flagNamespaces,
+		ShowNice:            flagNice,
+		ShowNSPids:          flagNSPids,
+		ShowNumFDs:          flagFDs,
 		ShowNumThreads:      flagThreads,
+		ShowOrphans:         flagShowOrphans,
 		ShowOwner:           flagShowOwner,
+		ShowParent:          flagShowParent,
 		ShowPGIDs:           flagShowPGIDs,
 		ShowPGLs:            flagShowPGLs,
 		ShowPIDs:            flagShowPIDs,
+		ShowPrivileged:      flagPrivileged,
 		ShowPPIDs:           flagShowPPIDs,
 		ShowProcessAge:      flagAge,
+		ShowSched:           flagSched,
+		ShowSIDs:            flagShowSIDs,
+		ShowStartTime:       flagStartTime,
+		ShowStatus:          flagShowStatus,
+		ShowSummary:         flagSummary,
+		ShowThreadsTree:     flagThreadsTree,
 		ShowUIDTransitions:  flagShowUIDTransitions,
 		ShowUserTransitions: flagShowUserTransitions,
+		ShowZombies:         flagZombies,
+		Stream:              flagStream,
+		Terminal:            terminal,
+		Top:                 flagTop,
+		UTC:                 flagUTC,
 		Usernames:           flagUsername,
 		UTF8Graphics:        flagUTF8,
 		VT100Graphics:       flagVT100,
+		WatchInterval:       watchInterval(),
 		WideDisplay:         flagWide,
+		WithChildren:        flagWithChildren,
+		WrapLines:           flagWrap,
+		YoungerThan:         youngerThan,
+	}
+	if err := pstree.ApplyFields(&displayOptions, flagFields); err != nil {
+		return err
+	}
+
+	// Without a graphics flag, the tree is drawn with UTF-8 characters when the locale uses UTF-8
+	if pstree.ResolveTreeStyle(displayOptions, localeEnvironment()) == "utf8" {
+		displayOptions.UTF8Graphics = true
 	}
 
+	if flagServe != "" {
+		return serveProcessTree()
+	}
+
+	if flagWatch {
+		return watchProcessTree()
+	}
+
+	if flagProfile != "" {
+		err = profileCollection(flagProfile, collectProcesses)
+	} else {
+		err = collectProcesses()
+	}
+	if err != nil {
+		return err
+	}
+
+	if flagDumpSnapshot != "" {
+		return dumpSnapshot()
+	}
+
+	err = displayProcessTree()
+
+	// The unknown values would look like a bug, explain them once the tree is printed
+	if !flagQuiet && processTree != nil {
+		if notice := processTree.DegradedNotice(); notice != "" {
+			fmt.Fprintln(os.Stderr, notice)
+		}
+	}
+	return err
+}
+
+// collectProcesses gathers a fresh snapshot of the system processes into the processes slice.
+//
+// The snapshot is collected using the previously parsed miniOptions, so only the attributes
+// required for display, sorting and coloring are fetched. With --from-file, the processes are
+// read from the snapshot file instead, and the installed memory of the host the snapshot was
+// taken on is used for the memory percentages. The time spent collecting the processes is
+// recorded in collectionTimings, nil when they were read from a file.
+//
+// Returns:
+//   - error: An error if the list of processes could not be retrieved or the snapshot could not be read
+func collectProcesses() (err error) {
+	collectionTimings = nil
+	if flagFromFile != "" {
+		source := &pstree.FileSource{Path: flagFromFile}
+		processes, err = source.Processes(miniOptions, nil)
+		if err != nil {
+			return err
+		}
+		if source.InstalledMemory > 0 {
+			displayOptions.InstalledMemory = source.InstalledMemory
+		}
+		return nil
+	}
+
+	collectionTimings = &pstree.CollectionTimings{}
+	if flagDiff != "" {
+		return collectDiff()
+	}
+
+	processes, err = pstree.GetProcessesWithTimings(miniOptions, collectionTimings)
+	return err
+}
+
+// collectDiff gathers the processes compared with --diff into the processes slice.
+//
+// The first snapshot is either collected from the running system, followed by a pause of
+// --diff seconds, or read from the --diff snapshot file. The second snapshot is always collected
+// from the running system, and both are combined with pstree.MergeSnapshots.
+//
+// Returns:
+//   - error: An error if the list of processes could not be retrieved or the snapshot could not be read
+func collectDiff() error {
+	var before pstree.ProcessSource = &pstree.FileSource{Path: flagDiff}
+
+	if diffInterval > 0 {
+		before = pstree.SystemSource{}
+	}
+	first, err := before.Processes(miniOptions, collectionTimings)
+	if err != nil {
+		return err
+	}
+	if diffInterval > 0 {
+		time.Sleep(time.Duration(diffInterval) * time.Second)
+	}
+
+	after, err := pstree.SystemSource{}.Processes(miniOptions, collectionTimings)
+	if err != nil {
+		return err
+	}
+	processes = pstree.MergeSnapshots(first, after)
+	return nil
+}
+
+// dumpSnapshot writes the collected processes to the --dump-snapshot file, or to stdout for -.
+//
+// Returns:
+//   - error: Any error encountered while creating or writing the file
+func dumpSnapshot() error {
+	snapshot := pstree.NewSnapshot(processes, displayOptions.InstalledMemory)
+	if flagDumpSnapshot == "-" {
+		return pstree.WriteSnapshot(os.Stdout, snapshot)
+	}
+
+	file, err := os.Create(flagDumpSnapshot)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	if err = pstree.WriteSnapshot(file, snapshot); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return file.Close()
+}
+
+// displayProcessTree builds the process tree from the current snapshot and prints it.
+//
+// The tree is built using the previously parsed displayOptions, the processes to be
+// displayed are marked, unmarked processes are dropped and the result is printed to stdout.
+// When --pid is given, each requested PID is printed as its own tree in PID order. With
+// --output=csv or --output=tsv the displayed processes are written as rows instead, and
+// with --output=dot as a Graphviz digraph. With --kill, the displayed processes are signaled
+// after they were printed, see signalProcesses. With --debug, the durations of the stages are
+// logged after the output, see pstree.ProcessTree.LogTimings.
+//
+// Returns:
+//   - error: ErrNoMatch if no processes match the filters, an error if none of the requested --pid
+//     processes exist, the rows could not be written, or any of the --kill signals could not be sent
+func displayProcessTree() error {
 	// Use the traditional array-based tree structure
 	logger.Logger.Debug("Using traditional array-based tree structure")
 
+	// The terminal may have been resized since the previous render in watch mode
+	displayOptions.ScreenWidth = screen.Width()
+
 	// Generate the process tree
 	processTree = pstree.NewProcessTree(debugLevel, logger.Logger, processes, displayOptions)
+	processTree.Timings.Collection = collectionTimings
 	// pretty.Println(processTree.Nodes)
 	// os.Exit(0)
 
@@ -351,14 +1454,224 @@ For more information about these matters, see the file named LICENSE.`,
 	// Drop unmarked processes
 	processTree.DropUnmarked()
 
+	// Mark the ancestry of the process to highlight
+	processTree.MarkCurrentAndAncestors()
+
+	// Gather the network connections of the remaining processes
+	processTree.CollectConnections()
+
+	// Gather the usage of the remaining processes deferred by pstree.SplitCollection
+	processTree.CollectDeferredUsage(deferredOptions, collectionTimings)
+
 	// Show processes that will be displayed
 	if processTree.DebugLevel > 2 {
 		processTree.ShowPrintable()
 		os.Exit(0)
 	}
 
-	// Print the tree
-	processTree.PrintTree(0, "")
+	rootIndices, err := processTree.RootIndices()
+	if err != nil {
+		return err
+	}
+
+	// Scripts can tell an empty result from a successful run by the exit status, watch mode keeps waiting for matches
+	if !flagWatch && !processTree.HasPrintable() {
+		return ErrNoMatch
+	}
+
+	printStart := time.Now()
+	switch flagOutput {
+	case "csv":
+		err = processTree.WriteFlat(processTree.Output, ',', rootIndices)
+	case "dot":
+		err = processTree.WriteDot(processTree.Output, rootIndices)
+	case "markdown":
+		err = processTree.WriteMarkdown(processTree.Output, rootIndices)
+	case "tsv":
+		err = processTree.WriteFlat(processTree.Output, '\t', rootIndices)
+	default:
+		// Print the tree, once for each root
+		for _, rootIndex := range rootIndices {
+			if err = processTree.PrintTree(rootIndex, ""); err != nil {
+				break
+			}
+		}
+
+		if err == nil && displayOptions.ShowDepthStats {
+			err = processTree.PrintDepthStats()
+		}
+		if err == nil && displayOptions.ShowSummary {
+			err = processTree.PrintSummary()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	processTree.Timings.Print = time.Since(printStart)
+	processTree.LogTimings()
+
+	if flagKill != "" {
+		return signalProcesses(processTree.SignalTargets(rootIndices), os.Stdin, os.Stdout, os.Stderr)
+	}
 
 	return nil
 }
+
+// parseAttrThresholds parses the value of --attr-thresholds for the given --color-attr attribute.
+//
+// Parameters:
+//   - value: Comma-separated list of thresholds, e.g., "50,80"
+//   - attribute: The --color-attr attribute the thresholds apply to
+//
+// Returns:
+//   - []float64: The thresholds in increasing order
+//   - error: An error if the thresholds are not numbers, not increasing, or not as many as the attribute requires
+func parseAttrThresholds(value string, attribute string) ([]float64, error) {
+	var (
+		count      int
+		thresholds []float64
+	)
+
+	count = len(pstree.DefaultAttributeThresholds[attribute])
+	for _, field := range strings.Split(value, ",") {
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("--attr-thresholds must be a comma-separated list of numbers, got %q", field)
+		}
+		if len(thresholds) > 0 && threshold <= thresholds[len(thresholds)-1] {
+			return nil, errors.New("--attr-thresholds must be in increasing order")
+		}
+		thresholds = append(thresholds, threshold)
+	}
+
+	if len(thresholds) != count {
+		return nil, fmt.Errorf("--attr-thresholds for %s requires %d values, got %d", attribute, count, len(thresholds))
+	}
+
+	return thresholds, nil
+}
+
+// parseAge parses the value of --younger-than or --older-than.
+//
+// Parameters:
+//   - value: The duration, e.g., 5m, 2h, or 1d, see util.ParseDuration
+//   - flag: The name of the flag, used in the error
+//
+// Returns:
+//   - time.Duration: The duration, 0 if the value is empty
+//   - error: An error if the value is not a positive duration
+func parseAge(value string, flag string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := util.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", flag, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, e.g., 5m, 2h, or 1d", flag)
+	}
+	return duration, nil
+}
+
+// colorSchemePath returns the path of the color scheme file given with --color-scheme.
+//
+// A value containing a path separator or a file extension is used as a path. Any other value
+// names a scheme in ~/.config/pstree/schemes, e.g., "solarized" is read from
+// ~/.config/pstree/schemes/solarized.yaml.
+//
+// Parameters:
+//   - name: The value of --color-scheme, which is not one of the built-in schemes
+//
+// Returns:
+//   - string: The path of the scheme file
+//   - error: An error if the value names a scheme that doesn't exist
+func colorSchemePath(name string) (string, error) {
+	if strings.ContainsRune(name, os.PathSeparator) || strings.Contains(name, "/") || filepath.Ext(name) != "" {
+		return name, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		path := filepath.Join(home, ".config", "pstree", "schemes", name+".yaml")
+		if _, err = os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("valid options for --color-scheme are: %s, or the path of a scheme file", strings.Join(validColorSchemes, ", "))
+}
+
+// colorRequested determines if the predefined colors were requested with --color.
+//
+// Parameters:
+//   - cmd: The command being executed
+//
+// Returns:
+//   - bool: true if --color was given with any mode other than never, false otherwise
+func colorRequested(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("color") && flagColor != "never"
+}
+
+// localeEnvironment returns the locale variables of the environment, as used by pstree.ResolveTreeStyle.
+func localeEnvironment() map[string]string {
+	env := make(map[string]string)
+	for _, name := range []string{"LANG", "LC_ALL", "LC_CTYPE"} {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// highlightPID returns the PID of the process to highlight, or 0 if highlighting is disabled.
+func highlightPID() int32 {
+	if flagHighlightSelf {
+		return int32(os.Getpid())
+	}
+	return int32(flagHighlightPid)
+}
+
+// needArgs reports whether the full command lines of the processes have to be read: to show the
+// arguments, to match --exclude and --no-kernel-threads, and for --dump-snapshot and --serve,
+// whose later renderings may need them. Otherwise only argv[0] is read, which is all that names
+// the processes, e.g., -bash for a login shell, and tells them apart in compact mode.
+//
+// Parameters:
+//   - miniOptions: The collection options, with the fields of --fields applied
+//
+// Returns:
+//   - bool: true if the arguments are collected
+func needArgs(miniOptions pstree.DisplayOptions) bool {
+	return miniOptions.ShowArguments || flagRawArgs || len(flagExclude) > 0 || flagNoKernelThreads || flagDumpSnapshot != "" || flagServe != ""
+}
+
+// ttyFilter returns the terminal requested with --tty.
+//
+// Returns:
+//   - string: The terminal name, e.g., pts/3; for --tty without a name, the terminal of pstree itself
+//   - error: An error if --tty was given without a name and pstree has no controlling terminal
+func ttyFilter() (string, error) {
+	if flagTTY != "current" {
+		return pstree.NormalizeTerminal(flagTTY), nil
+	}
+
+	self, err := process.NewProcess(int32(os.Getpid()))
+	if err == nil {
+		var current string
+		current, err = pstree.ProcessTerminal(self)
+		if err == nil && current != "" {
+			return current, nil
+		}
+	}
+	return "", errors.New("--tty: pstree is not attached to a terminal, give a terminal name, e.g., --tty=pts/3")
+}
+
+// watchInterval returns the watch mode refresh interval in seconds, or 0 if watch mode is disabled.
+func watchInterval() int {
+	if flagWatch {
+		return flagInterval
+	}
+	return 0
+}
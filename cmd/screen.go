@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// screenSize provides the width of the screen the tree is rendered for, measured before each
+// render so that watch mode follows the resizes of the terminal, see notifyResize.
+type screenSize interface {
+	// Width returns the width of the screen in characters
+	Width() int
+}
+
+// fixedScreen is the screen of an output that is not a terminal, e.g., a pipe or a file, whose
+// width is measured once at startup.
+type fixedScreen struct {
+	// Width of the screen in characters
+	width int
+}
+
+// Width returns the width measured at startup.
+//
+// Returns:
+//   - int: Width of the screen in characters
+func (screen fixedScreen) Width() int {
+	return screen.width
+}
+
+// terminalScreen is the screen of a terminal, whose width is queried again on each render.
+type terminalScreen struct {
+	// The terminal the tree is written to
+	file *os.File
+	// Width of the screen used when the terminal cannot be queried
+	fallback int
+}
+
+// Width queries the current width of the terminal.
+//
+// Returns:
+//   - int: Width of the terminal in characters, or the fallback width if it cannot be queried
+func (screen terminalScreen) Width() int {
+	if width, err := terminalWidth(screen.file); err == nil && width > 0 {
+		return width
+	}
+	return screen.fallback
+}
+
+// newScreenSize returns the screen the tree is written to on the standard output: a terminal
+// queried on each render, or a fixed width for the other outputs, measured the way pstree always
+// did, so pstree | less still fits the terminal it runs in.
+//
+// Returns:
+//   - screenSize: The screen of the standard output
+func newScreenSize() screenSize {
+	width := util.GetScreenWidth()
+	if util.IsTerminal(os.Stdout) {
+		return terminalScreen{file: os.Stdout, fallback: width}
+	}
+	return fixedScreen{width: width}
+}
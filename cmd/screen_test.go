@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/logger"
+	"github.com/bananazon/pstree/pkg/pstree"
+	"github.com/bananazon/pstree/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScreen is a screen whose width is set by the test
+type fakeScreen struct {
+	width int
+}
+
+func (screen *fakeScreen) Width() int {
+	return screen.width
+}
+
+// renderWithScreen renders a small tree with displayProcessTree, returning what it wrote to the standard output
+func renderWithScreen(t *testing.T) string {
+	processes = []pstree.Process{
+		{PID: 1, PPID: 0, Command: "init", Args: []string{"--system"}},
+		{PID: 100, PPID: 1, Command: "sshd", Args: []string{"-D", "-o", "LogLevel=VERBOSE", "-o", "PermitRootLogin=no"}},
+	}
+
+	path := t.TempDir() + "/stdout"
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = file
+	err = displayProcessTree()
+	os.Stdout = stdout
+	require.NoError(t, file.Close())
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(output)
+}
+
+func TestDisplayProcessTreeScreenWidth(t *testing.T) {
+	savedLogger, savedOptions, savedScreen := logger.Logger, displayOptions, screen
+	logger.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	displayOptions = pstree.DisplayOptions{MaxDepth: 999, ScreenWidth: 132, ShowArguments: true}
+	defer func() { logger.Logger, displayOptions, screen = savedLogger, savedOptions, savedScreen }()
+
+	// The width is measured on each render rather than once at startup
+	fake := &fakeScreen{width: 30}
+	screen = fake
+	lines := strings.Split(strings.TrimSuffix(renderWithScreen(t), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, 30, util.VisibleWidth(lines[1]))
+
+	// A resize in watch mode is followed by the next render
+	fake.width = 200
+	assert.Equal(t, " \\--- sshd -D -o LogLevel=VERBOSE -o PermitRootLogin=no\n", strings.SplitAfter(renderWithScreen(t), "\n")[1])
+}
+
+func TestScreenSize(t *testing.T) {
+	assert.Equal(t, 80, fixedScreen{width: 80}.Width())
+
+	// A file is no terminal, so the width measured at startup is kept
+	file, err := os.CreateTemp(t.TempDir(), "pstree")
+	require.NoError(t, err)
+	defer file.Close()
+	assert.Equal(t, 77, terminalScreen{file: file, fallback: 77}.Width())
+
+	if !util.IsTerminal(os.Stdout) {
+		assert.IsType(t, fixedScreen{}, newScreenSize())
+	}
+}
@@ -0,0 +1,58 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalWidth queries the width of a terminal with the TIOCGWINSZ ioctl, which is cheap enough
+// to be made on each render, unlike util.GetScreenWidth, which runs stty.
+//
+// Parameters:
+//   - file: The terminal to query
+//
+// Returns:
+//   - int: Width of the terminal in characters
+//   - error: Any error encountered while querying the terminal, e.g., ENOTTY
+func terminalWidth(file *os.File) (int, error) {
+	size, err := unix.IoctlGetWinsize(int(file.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, err
+	}
+	return int(size.Col), nil
+}
+
+// notifyResize notifies the resizes of the terminal, which the kernel signals with SIGWINCH.
+//
+// Parameters:
+//   - screen: The screen of the output, unused since the signal is sent for any resize
+//   - done: Closed to stop the notifications
+//
+// Returns:
+//   - <-chan struct{}: Receives a value after each resize, coalescing those not received yet
+func notifyResize(screen screenSize, done <-chan struct{}) <-chan struct{} {
+	signals := make(chan os.Signal, 1)
+	resized := make(chan struct{}, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return resized
+}
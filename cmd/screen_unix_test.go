@@ -0,0 +1,27 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyResize(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	resized := notifyResize(fixedScreen{width: 80}, done)
+
+	// Several resizes not received yet are coalesced into one notification
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGWINCH))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGWINCH))
+	select {
+	case <-resized:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the resize was not notified")
+	}
+}
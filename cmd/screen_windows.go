@@ -0,0 +1,65 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// resizePollInterval is how often the size of the console is polled in watch mode, since Windows
+// has no SIGWINCH.
+const resizePollInterval = 500 * time.Millisecond
+
+// terminalWidth queries the width of the visible window of a console.
+//
+// Parameters:
+//   - file: The console to query
+//
+// Returns:
+//   - int: Width of the console window in characters
+//   - error: Any error encountered while querying the console, e.g., when it is redirected
+func terminalWidth(file *os.File) (int, error) {
+	var info windows.ConsoleScreenBufferInfo
+
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(file.Fd()), &info); err != nil {
+		return 0, err
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, nil
+}
+
+// notifyResize notifies the resizes of the console, polling its width every resizePollInterval.
+//
+// Parameters:
+//   - screen: The screen of the output, whose width is polled
+//   - done: Closed to stop the notifications
+//
+// Returns:
+//   - <-chan struct{}: Receives a value after each resize, coalescing those not received yet
+func notifyResize(screen screenSize, done <-chan struct{}) <-chan struct{} {
+	resized := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+
+		width := screen.Width()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if current := screen.Width(); current != width {
+					width = current
+					select {
+					case resized <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return resized
+}
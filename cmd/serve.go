@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bananazon/pstree/pkg/logger"
+	"github.com/bananazon/pstree/pkg/pstree"
+)
+
+// serveShutdownTimeout bounds how long the requests in flight are waited for on shutdown.
+const serveShutdownTimeout = 5 * time.Second
+
+// serveBoolParams maps the boolean query parameters of the served tree to the display option
+// each of them sets, named like the flags they mirror.
+var serveBoolParams = map[string]func(options *pstree.DisplayOptions, value bool){
+	"age":         func(options *pstree.DisplayOptions, value bool) { options.ShowProcessAge = value },
+	"arguments":   func(options *pstree.DisplayOptions, value bool) { options.ShowArguments = value },
+	"compact-not": func(options *pstree.DisplayOptions, value bool) { options.CompactMode = !value },
+	"cpu":         func(options *pstree.DisplayOptions, value bool) { options.ShowCpuPercent = value },
+	"memory":      func(options *pstree.DisplayOptions, value bool) { options.ShowMemoryUsage = value },
+	"show-owner":  func(options *pstree.DisplayOptions, value bool) { options.ShowOwner = value },
+	"show-parent": func(options *pstree.DisplayOptions, value bool) { options.ShowParent = value },
+	"show-pids":   func(options *pstree.DisplayOptions, value bool) { options.ShowPIDs = value },
+	"show-ppids":  func(options *pstree.DisplayOptions, value bool) { options.ShowPPIDs = value },
+	"start-time":  func(options *pstree.DisplayOptions, value bool) { options.ShowStartTime = value },
+	"threads":     func(options *pstree.DisplayOptions, value bool) { options.ShowNumThreads = value },
+}
+
+// snapshotServer serves the process tree over HTTP from a snapshot refreshed in the background.
+//
+// The processes are only ever collected by refresh, which takes collectMutex, so concurrent
+// requests never trigger concurrent full scans: each request builds its own tree from the shared
+// snapshot, with the display options changed by its query parameters. The trees are rendered one
+// at a time, compact mode keeps the processes it skips in package state.
+type snapshotServer struct {
+	// Time the snapshot was collected
+	collectedAt time.Time
+	// Serializes the collections
+	collectMutex sync.Mutex
+	// Error of the last collection, nil if it succeeded
+	err error
+	// Guards collectedAt, err, and processes
+	mutex sync.RWMutex
+	// CPU times of the previous snapshot, see pstree.CPUTimesByPID
+	previous map[int32]float64
+	// The processes of the snapshot, never modified once published
+	processes []pstree.Process
+	// Serializes the rendering of the trees
+	renderMutex sync.Mutex
+}
+
+// serveProcessTree serves the process tree on the --serve address until SIGINT or SIGTERM.
+//
+// The processes are collected every --interval seconds. GET /tree returns the rendered tree as
+// text, GET /tree.json the displayed processes as JSON, and GET /healthz whether the last
+// collection succeeded. On SIGINT or SIGTERM, the server stops accepting connections and waits
+// for the requests in flight before returning.
+//
+// Returns:
+//   - error: Any error encountered while listening on the address
+func serveProcessTree() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &snapshotServer{}
+	server.refresh()
+
+	listener, err := net.Listen("tcp", flagServe)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", flagServe, err)
+	}
+	httpServer := &http.Server{Handler: server.handler(), ReadHeaderTimeout: 10 * time.Second}
+	logger.Logger.Info(fmt.Sprintf("Serving the process tree on http://%s", listener.Addr()))
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(flagInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				server.refresh()
+			}
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Logger.Warn(fmt.Sprintf("Failed to shut down the server: %v", err))
+		}
+	}()
+
+	if err = httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// refresh collects a new snapshot of the processes and publishes it. The CPU percentage of each
+// process is measured over the time since the previous snapshot, like in watch mode. When the
+// collection fails, the previous snapshot is kept and the error is reported by /healthz.
+func (server *snapshotServer) refresh() {
+	server.collectMutex.Lock()
+	defer server.collectMutex.Unlock()
+
+	now := time.Now()
+	err := collectProcesses()
+	if err == nil {
+		if server.previous != nil {
+			pstree.ApplyIntervalCPUPercent(processes, server.previous, now.Sub(server.collectedAt))
+		}
+		server.previous = pstree.CPUTimesByPID(processes)
+	} else {
+		logger.Logger.Warn(fmt.Sprintf("Failed to collect the processes: %v", err))
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.err = err
+	if err == nil {
+		server.collectedAt = now
+		server.processes = processes
+	}
+}
+
+// snapshot returns the processes of the current snapshot.
+//
+// Returns:
+//   - []pstree.Process: A copy of the processes, which the caller may build a tree from, nil before the first snapshot
+//   - error: The error of the last collection
+func (server *snapshotServer) snapshot() ([]pstree.Process, error) {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+	return slices.Clone(server.processes), server.err
+}
+
+// handler returns the HTTP handler serving the endpoints.
+//
+// Returns:
+//   - http.Handler: The handler for /tree, /tree.json, and /healthz
+func (server *snapshotServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", server.serveHealth)
+	mux.HandleFunc("GET /tree", func(writer http.ResponseWriter, request *http.Request) {
+		server.serveTree(writer, request, "text/plain; charset=utf-8", func(processTree *pstree.ProcessTree, output io.Writer, rootIndices []int) error {
+			for _, rootIndex := range rootIndices {
+				if err := processTree.PrintTree(rootIndex, ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	mux.HandleFunc("GET /tree.json", func(writer http.ResponseWriter, request *http.Request) {
+		server.serveTree(writer, request, "application/json", func(processTree *pstree.ProcessTree, output io.Writer, rootIndices []int) error {
+			return processTree.WriteJSON(output, rootIndices)
+		})
+	})
+	return mux
+}
+
+// serveHealth reports whether the last collection succeeded.
+//
+// Parameters:
+//   - writer: Destination of the response
+//   - request: The request
+func (server *snapshotServer) serveHealth(writer http.ResponseWriter, request *http.Request) {
+	server.mutex.RLock()
+	collected, err := server.processes != nil, server.err
+	server.mutex.RUnlock()
+
+	switch {
+	case err != nil:
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+	case !collected:
+		http.Error(writer, "no snapshot collected yet", http.StatusServiceUnavailable)
+	default:
+		fmt.Fprintln(writer, "ok")
+	}
+}
+
+// serveTree builds the tree from the current snapshot with the display options of the request
+// and writes it with render.
+//
+// Parameters:
+//   - writer: Destination of the response
+//   - request: The request, whose query parameters change the display options, see serveOptions
+//   - contentType: Content type of the response
+//   - render: Writes the tree to the output, the roots are returned by RootIndices
+func (server *snapshotServer) serveTree(writer http.ResponseWriter, request *http.Request, contentType string, render func(processTree *pstree.ProcessTree, output io.Writer, rootIndices []int) error) {
+	var output bytes.Buffer
+
+	options, err := serveOptions(displayOptions, request.URL.Query())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snapshot, _ := server.snapshot()
+	if snapshot == nil {
+		http.Error(writer, "no snapshot collected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	server.renderMutex.Lock()
+	processTree := pstree.NewProcessTreeWithOutput(debugLevel, logger.Logger, snapshot, options, &output)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	processTree.MarkCurrentAndAncestors()
+	processTree.CollectConnections()
+	rootIndices, err := processTree.RootIndices()
+	if err == nil {
+		err = render(processTree, &output, rootIndices)
+	}
+	server.renderMutex.Unlock()
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writer.Header().Set("Content-Type", contentType)
+	writer.Write(output.Bytes())
+}
+
+// serveOptions returns the display options of a request to the served tree.
+//
+// The query parameters mirror the flags: contains, order-by, and order-dir take a value, level
+// a number, pid and user can be given more than once, and the serveBoolParams take a boolean,
+// e.g., ?contains=nginx&cpu=1. The tree is always drawn without colors and not truncated.
+//
+// Parameters:
+//   - base: The display options given on the command line
+//   - query: The query parameters of the request
+//
+// Returns:
+//   - pstree.DisplayOptions: The display options of the request
+//   - error: An error if a parameter is unknown or its value is not valid
+func serveOptions(base pstree.DisplayOptions, query url.Values) (pstree.DisplayOptions, error) {
+	options := base
+	options.ColorAttr = ""
+	options.ColorizeOutput = false
+	options.ColorSupport = false
+	options.RainbowOutput = false
+	options.WideDisplay = true
+
+	for name, values := range query {
+		value := values[len(values)-1]
+		if setter, ok := serveBoolParams[name]; ok {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return options, fmt.Errorf("%s: %q is not a boolean", name, value)
+			}
+			setter(&options, enabled)
+			continue
+		}
+
+		switch name {
+		case "contains":
+			options.Contains = value
+		case "level":
+			level, err := strconv.Atoi(value)
+			if err != nil || level < 1 {
+				return options, fmt.Errorf("level: %q is not a number of at least 1", value)
+			}
+			options.MaxDepth = level
+		case "order-by":
+			if !slices.Contains(validOrderBy, value) {
+				return options, fmt.Errorf("valid options for order-by are: %s", strings.Join(validOrderBy, ", "))
+			}
+			options.OrderBy = value
+		case "order-dir":
+			if !slices.Contains(validOrderDir, value) {
+				return options, fmt.Errorf("valid options for order-dir are: %s", strings.Join(validOrderDir, ", "))
+			}
+			options.OrderDir = value
+		case "pid":
+			options.RootPIDs = nil
+			for _, pidValue := range values {
+				pid, err := strconv.ParseInt(pidValue, 10, 32)
+				if err != nil || pid < 1 {
+					return options, fmt.Errorf("pid: %q is not a process ID", pidValue)
+				}
+				options.RootPIDs = append(options.RootPIDs, int32(pid))
+			}
+		case "user":
+			options.Usernames = slices.Clone(values)
+		default:
+			return options, fmt.Errorf("unknown query parameter %q", name)
+		}
+	}
+
+	// Like --order-by=start, sort by age in the opposite direction once order-dir is known
+	if options.OrderBy == "start" {
+		options.OrderBy = "age"
+		options.ShowStartTime = true
+		if options.OrderDir == "desc" {
+			options.OrderDir = "asc"
+		} else {
+			options.OrderDir = "desc"
+		}
+	}
+	return options, nil
+}
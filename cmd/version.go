@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// The build metadata, set at build time with -ldflags, e.g.,
+//
+//	go build -ldflags "-X github.com/bananazon/pstree/cmd.commit=$(git rev-parse --short HEAD)"
+//
+// see the build target of the Makefile. A plain go build keeps the defaults.
+var (
+	commit  = "dev"
+	date    = "dev"
+	version = "0.9.6"
+)
+
+// versionCmd prints the version of pstree and the build metadata, like --version.
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version of pstree",
+	Long: `Print the version of pstree, the commit it was built from, the build date, and the Go version,
+or, with --output=json, the same as a JSON object for tooling, e.g.,
+
+    pstree version --output=json`,
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE:                  versionRunCmd,
+	SilenceUsage:          true,
+}
+
+// versionInfo is the version of pstree and the build metadata printed with --output=json.
+type versionInfo struct {
+	// The commit pstree was built from
+	Commit string `json:"commit"`
+	// The date pstree was built
+	Date string `json:"date"`
+	// The version of Go pstree was built with
+	GoVersion string `json:"go_version"`
+	// The version of pstree
+	Version string `json:"version"`
+}
+
+// versionRunCmd writes the version of pstree to the output of the command.
+//
+// Parameters:
+//   - cmd: The version command
+//   - args: No arguments
+//
+// Returns:
+//   - error: An error if --output is neither tree nor json, or any error encountered while writing
+func versionRunCmd(cmd *cobra.Command, args []string) error {
+	text, err := versionText()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(cmd.OutOrStdout(), text)
+	return err
+}
+
+// versionText formats the version of pstree and the build metadata, as text by default or as a
+// JSON object with --output=json. It is also the template of --version, see cobra.Command.Version.
+//
+// Returns:
+//   - string: The version, followed by a newline
+//   - error: An error if --output is neither tree nor json
+func versionText() (string, error) {
+	info := versionInfo{
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Version:   version,
+	}
+
+	switch flagOutput {
+	case "json":
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	case "tree":
+		return fmt.Sprintf(`pstree %s (commit %s, built %s, %s)
+Copyright (C) 2025, 2026 Cursed Bananazon
+
+pstree comes with ABSOLUTELY NO WARRANTY.
+This is free software, and you are welcome to redistribute it under
+the terms of the GNU General Public License.
+For more information about these matters, see the file named LICENSE.
+`, info.Version, info.Commit, info.Date, info.GoVersion), nil
+	}
+	return "", fmt.Errorf("valid options for --output with the version are: json, tree")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeRoot runs the root command with the given arguments, returning its output
+func executeRoot(t *testing.T, args ...string) (string, error) {
+	var output bytes.Buffer
+
+	rootCmd.SetOut(&output)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(args)
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		flagOutput = "tree"
+		flagVersion = false
+	})
+	err := rootCmd.Execute()
+	return output.String(), err
+}
+
+func TestVersionCommand(t *testing.T) {
+	output, err := executeRoot(t, "version")
+	require.NoError(t, err)
+	assert.Regexp(t, `^pstree 0\.9\.6 \(commit dev, built dev, go\S+\)\n`, output)
+	assert.Contains(t, output, "GNU General Public License")
+
+	output, err = executeRoot(t, "version", "--output=json")
+	require.NoError(t, err)
+	var info map[string]string
+	require.NoError(t, json.Unmarshal([]byte(output), &info))
+	assert.Equal(t, map[string]string{"commit": "dev", "date": "dev", "go_version": runtime.Version(), "version": version}, info)
+
+	_, err = executeRoot(t, "version", "--output=csv")
+	assert.EqualError(t, err, "valid options for --output with the version are: json, tree")
+}
+
+func TestVersionFlag(t *testing.T) {
+	// --version prints the same as the version command, without validating the other flags
+	expected, err := executeRoot(t, "version")
+	require.NoError(t, err)
+	output, err := executeRoot(t, "--version")
+	require.NoError(t, err)
+	assert.Equal(t, expected, output)
+
+	output, err = executeRoot(t, "-V", "--output", "json")
+	require.NoError(t, err)
+	var info versionInfo
+	require.NoError(t, json.Unmarshal([]byte(output), &info))
+	assert.Equal(t, versionInfo{Commit: commit, Date: date, GoVersion: runtime.Version(), Version: version}, info)
+}
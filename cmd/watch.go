@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bananazon/pstree/pkg/pstree"
+)
+
+const (
+	// ANSI escape sequences used to redraw the screen in watch mode
+	clearScreen = "\033[H\033[2J"
+	hideCursor  = "\033[?25l"
+	showCursor  = "\033[?25h"
+)
+
+// watchProcessTree repeatedly collects and displays the process tree until interrupted.
+//
+// Every --interval seconds the screen is cleared and a fresh snapshot is rendered using the
+// already parsed display options. The CPU times of each snapshot are kept so that the CPU
+// percentage of the next snapshot reflects the usage during the interval rather than the
+// lifetime average of the process. When the terminal is resized, a snapshot is rendered right
+// away for the new width, see notifyResize. The cursor is hidden while watching and restored
+// when SIGINT or SIGTERM is received.
+//
+// Returns:
+//   - error: Any error encountered while collecting or displaying the processes
+func watchProcessTree() error {
+	var (
+		err          error
+		lastSnapshot time.Time
+		previous     map[int32]float64
+	)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	done := make(chan struct{})
+	defer close(done)
+	resized := notifyResize(screen, done)
+
+	fmt.Fprint(os.Stdout, hideCursor)
+	defer fmt.Fprint(os.Stdout, showCursor)
+
+	ticker := time.NewTicker(time.Duration(flagInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		err = collectProcesses()
+		if err != nil {
+			return err
+		}
+
+		// The interval CPU percentage has to be in place before the tree is sorted by CPU
+		if previous != nil {
+			pstree.ApplyIntervalCPUPercent(processes, previous, now.Sub(lastSnapshot))
+		}
+		previous = pstree.CPUTimesByPID(processes)
+		lastSnapshot = now
+
+		fmt.Fprint(os.Stdout, clearScreen)
+		err = displayProcessTree()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C:
+		case <-resized:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package fixtures provides canned process trees for testing the code built on pstree without
+// reading the running system. The trees only depend on the standard library, so the tests of the
+// pstree package itself can use them, converting each Process into a pstree.Process for a
+// pstree.MockSource.
+package fixtures
+
+import (
+	"fmt"
+)
+
+// Process describes a process of a canned tree.
+type Process struct {
+	// Command line arguments, without the command
+	Args []string
+	// Command name
+	Command string
+	// Process ID
+	PID int32
+	// Process group ID
+	PGID int32
+	// Parent process ID
+	PPID int32
+	// Session ID (0 if the tree doesn't set one)
+	SID int32
+	// Controlling terminal, e.g., pts/0 (empty for none)
+	Terminal string
+	// Owner of the process
+	Username string
+}
+
+// DeepChain returns a chain of processes, each the only child of the one before it: init, and
+// nested bash shells below it with the PIDs 2, 3, and so on.
+//
+// Parameters:
+//   - depth: Number of processes in the chain, including init
+//
+// Returns:
+//   - []Process: The processes, sorted by PID
+func DeepChain(depth int) []Process {
+	processes := []Process{{PID: 1, PPID: 0, PGID: 1, Command: "init", Username: "root"}}
+	for pid := int32(2); pid <= int32(depth); pid++ {
+		processes = append(processes, Process{PID: pid, PPID: pid - 1, PGID: pid, Command: "bash", Terminal: "pts/0", Username: "alice"})
+	}
+	return processes
+}
+
+// WideFanOut returns init with an sshd daemon (PID 100) serving many sessions, each a sshd
+// process with its own arguments and a bash child. The sessions use the PIDs 1000, 1002, and so
+// on, and their shells the odd PIDs after them. Each process leads its own process group.
+//
+// Parameters:
+//   - width: Number of sessions of the daemon
+//
+// Returns:
+//   - []Process: The processes, sorted by PID
+func WideFanOut(width int) []Process {
+	processes := []Process{
+		{PID: 1, PPID: 0, PGID: 1, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, PGID: 100, Command: "sshd", Username: "root"},
+	}
+	for i := range width {
+		session := int32(1000 + 2*i)
+		username := fmt.Sprintf("user%d", i)
+		processes = append(processes,
+			Process{PID: session, PPID: 100, PGID: session, Command: "sshd", Args: []string{fmt.Sprintf("%s@pts/%d", username, i)}, Username: "root"},
+			Process{PID: session + 1, PPID: session, PGID: session + 1, Command: "bash", Terminal: fmt.Sprintf("pts/%d", i), Username: username},
+		)
+	}
+	return processes
+}
+
+// OrphanedChild returns init with a cron daemon, and a worker (PID 300) and its child whose
+// parent (PID 299) exited, as when the parent exits while the processes are collected.
+//
+// Returns:
+//   - []Process: The processes, sorted by PID
+func OrphanedChild() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, PGID: 1, Command: "init", Username: "root"},
+		{PID: 200, PPID: 1, PGID: 200, Command: "cron", Username: "root"},
+		{PID: 300, PPID: 299, PGID: 300, Command: "worker", Args: []string{"--queue", "default"}, Username: "app"},
+		{PID: 301, PPID: 300, PGID: 300, Command: "sleep", Args: []string{"60"}, Username: "app"},
+	}
+}
+
+// IdenticalSiblings returns init with an nginx master (PID 100) running four identical workers
+// (PIDs 101 to 104) and a cache manager in its process group, and a cron daemon (PID 200).
+//
+// Returns:
+//   - []Process: The processes, sorted by PID
+func IdenticalSiblings() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, PGID: 1, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, PGID: 100, Command: "nginx", Args: []string{"master", "process"}, Username: "root"},
+		{PID: 101, PPID: 100, PGID: 100, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+		{PID: 102, PPID: 100, PGID: 100, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+		{PID: 103, PPID: 100, PGID: 100, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+		{PID: 104, PPID: 100, PGID: 100, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+		{PID: 105, PPID: 100, PGID: 100, Command: "nginx", Args: []string{"cache", "manager", "process"}, Username: "www-data"},
+		{PID: 200, PPID: 1, PGID: 200, Command: "cron", Username: "root"},
+	}
+}
+
+// TwoMasters returns init with two nginx masters (PIDs 100 and 300), each running two workers,
+// e.g., a production and a staging instance, and a cron daemon (PID 200).
+//
+// Returns:
+//   - []Process: The processes, sorted by PID
+func TwoMasters() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, PGID: 1, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, PGID: 100, Command: "nginx", Args: []string{"-c", "/etc/nginx/prod.conf"}, Username: "root"},
+		{PID: 101, PPID: 100, PGID: 100, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+		{PID: 102, PPID: 100, PGID: 100, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+		{PID: 200, PPID: 1, PGID: 200, Command: "cron", Username: "root"},
+		{PID: 300, PPID: 1, PGID: 300, Command: "nginx", Args: []string{"-c", "/etc/nginx/staging.conf"}, Username: "root"},
+		{PID: 301, PPID: 300, PGID: 300, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+		{PID: 302, PPID: 300, PGID: 300, Command: "nginx", Args: []string{"worker", "process"}, Username: "www-data"},
+	}
+}
+
+// SSHSession returns init with a cron daemon (PID 200) and an sshd daemon (PID 100) serving a
+// session of alice: the privileged sshd of the session (PID 1000) owned by root, its unprivileged
+// sshd (PID 1001), and her shell (PID 1002) running vim and a make building with cc1. Each
+// daemon leads a session of its own, and so do the privileged sshd and the shell of alice.
+//
+// Returns:
+//   - []Process: The processes, sorted by PID
+func SSHSession() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, PGID: 1, SID: 1, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, PGID: 100, SID: 100, Command: "sshd", Username: "root"},
+		{PID: 200, PPID: 1, PGID: 200, SID: 200, Command: "cron", Username: "root"},
+		{PID: 1000, PPID: 100, PGID: 1000, SID: 1000, Command: "sshd", Args: []string{"alice", "[priv]"}, Username: "root"},
+		{PID: 1001, PPID: 1000, PGID: 1000, SID: 1000, Command: "sshd", Args: []string{"alice@pts/0"}, Username: "alice"},
+		{PID: 1002, PPID: 1001, PGID: 1002, SID: 1002, Command: "bash", Terminal: "pts/0", Username: "alice"},
+		{PID: 1003, PPID: 1002, PGID: 1003, SID: 1002, Command: "vim", Args: []string{"notes.txt"}, Terminal: "pts/0", Username: "alice"},
+		{PID: 1004, PPID: 1002, PGID: 1004, SID: 1002, Command: "make", Terminal: "pts/0", Username: "alice"},
+		{PID: 1005, PPID: 1004, PGID: 1004, SID: 1002, Command: "cc1", Terminal: "pts/0", Username: "alice"},
+	}
+}
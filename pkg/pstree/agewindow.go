@@ -0,0 +1,51 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the age filters (--younger-than and --older-than), e.g., to see what
+// started during an incident window with --younger-than=2h --older-than=1h. Like the resource
+// usage filters, they narrow the processes marked by the other filters down, so they compose with
+// --contains and --user, and the ancestors of the processes kept stay marked so the tree remains
+// connected. The create time of every process is collected when they are given, and a process
+// whose age is not known never matches.
+package pstree
+
+import (
+	"time"
+)
+
+// hasAgeWindow reports whether the processes are filtered by their age.
+//
+// Returns:
+//   - bool: true with --younger-than or --older-than
+func (processTree *ProcessTree) hasAgeWindow() bool {
+	return processTree.DisplayOptions.YoungerThan > 0 || processTree.DisplayOptions.OlderThan > 0
+}
+
+// markAgeWindow unmarks the processes outside the --younger-than and --older-than window.
+func (processTree *ProcessTree) markAgeWindow() {
+	processTree.Logger.Debug("Entering processTree.markAgeWindow()")
+	processTree.narrowMarked(processTree.inAgeWindow, "is in the age window")
+}
+
+// inAgeWindow determines whether a process is younger than --younger-than and older than
+// --older-than. When both are given, the process has to be both.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the age of the process is known and within the window
+func (processTree *ProcessTree) inAgeWindow(node *Process) bool {
+	if node.IsThread || node.Age < 0 {
+		// Thread nodes share the age of their process, which is matched on the process itself
+		return false
+	}
+
+	age := time.Duration(node.Age) * time.Second
+	if processTree.DisplayOptions.YoungerThan > 0 && age >= processTree.DisplayOptions.YoungerThan {
+		return false
+	}
+	if processTree.DisplayOptions.OlderThan > 0 && age <= processTree.DisplayOptions.OlderThan {
+		return false
+	}
+	return true
+}
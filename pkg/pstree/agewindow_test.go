@@ -0,0 +1,47 @@
+package pstree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ageWindowTestProcesses returns a small tree of processes started at different times:
+//
+//	init(1, 30d) -+- sshd(100, 2d) --- bash(101, 90m, alice) --- vim(102, 10m, alice)
+//	              \- cron(200, unknown) --- backup(201, 30m, root)
+func ageWindowTestProcesses() []Process {
+	const minute = 60
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Age: 30 * 24 * 60 * minute, Username: "root"},
+		{PID: 100, PPID: 1, Command: "sshd", Age: 2 * 24 * 60 * minute, Username: "root"},
+		{PID: 101, PPID: 100, Command: "bash", Age: 90 * minute, Username: "alice"},
+		{PID: 102, PPID: 101, Command: "vim", Age: 10 * minute, Username: "alice"},
+		{PID: 200, PPID: 1, Command: "cron", Age: -1, Username: "root"},
+		{PID: 201, PPID: 200, Command: "backup", Age: 30 * minute, Username: "root"},
+	}
+}
+
+func TestMarkAgeWindow(t *testing.T) {
+	mark := func(displayOptions DisplayOptions) []int32 {
+		processTree := NewProcessTree(0, setupTestLogger(), ageWindowTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		return markedPIDs(processTree)
+	}
+
+	// The processes younger than the duration are marked along with their ancestors
+	assert.Equal(t, []int32{1, 100, 101, 102, 200, 201}, mark(DisplayOptions{YoungerThan: 2 * time.Hour}))
+	assert.Equal(t, []int32{1, 100, 101, 102}, mark(DisplayOptions{YoungerThan: 15 * time.Minute}))
+
+	// The older ones the other way around, the age of cron is not known so it never matches
+	assert.Equal(t, []int32{1, 100}, mark(DisplayOptions{OlderThan: 24 * time.Hour}))
+
+	// Both define a window
+	assert.Equal(t, []int32{1, 100, 101, 200, 201}, mark(DisplayOptions{OlderThan: 20 * time.Minute, YoungerThan: 2 * time.Hour}))
+
+	// The window narrows down the other filters
+	assert.Equal(t, []int32{1, 100, 101}, mark(DisplayOptions{Usernames: []string{"alice"}, OlderThan: 20 * time.Minute}))
+	assert.Equal(t, []int32{1, 200, 201}, mark(DisplayOptions{Contains: "backup", YoungerThan: time.Hour}))
+	assert.Equal(t, []int32{}, mark(DisplayOptions{Contains: "vim", YoungerThan: 5 * time.Minute}))
+}
@@ -0,0 +1,174 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the column alignment of --align. When several metrics are shown, they
+// start at a different offset on each line, following the depth of the process in the tree,
+// which makes them hard to compare. PrintTree then buffers the lines of each tree instead of
+// printing them, and printAligned pads the tree prefix and the identity of each process to a
+// common width, and each metric to the widest value of its column, so the metrics and the
+// commands line up vertically. The widths are measured without the ANSI escape sequences, so
+// colored lines are aligned the same way. When the aligned metrics would not fit on the
+// screen, the lines are printed unaligned.
+package pstree
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// alignedLine holds a line of the tree buffered by PrintTree with --align.
+type alignedLine struct {
+	// The accumulated prefix string from parent levels
+	head string
+	// The formatted items of the line by name, nil for the orphans and container nodes, see buildLineItems
+	items map[string]string
+	// The prefix of the children of the process, see buildNewHead
+	newHead string
+	// Index of the process in the Nodes array
+	pidIndex int
+	// The tree prefix followed by a space, or the whole line for the orphans and container nodes
+	start string
+}
+
+// identityKeys are the line items identifying a process, aligned along with the tree prefix.
+var identityKeys = []string{"pidPgid", "owner"}
+
+// trailingKeys are the line items left unpadded at the end of the line.
+var trailingKeys = []string{"command", "args"}
+
+// alignedColumns returns the line items padded to the width of their column with --align: the
+// metrics and states shown between the identity of a process and its command, in display order.
+//
+// Parameters:
+//   - keys: The names of the items of a line in display order, see lineItemOrder
+//
+// Returns:
+//   - []string: The names of the items, see lineItemKeys
+func alignedColumns(keys []string) []string {
+	return slices.DeleteFunc(slices.Clone(keys), func(key string) bool {
+		return slices.Contains(identityKeys, key) || slices.Contains(trailingKeys, key)
+	})
+}
+
+// selectLineItems returns the items of a line with the given names.
+//
+// Parameters:
+//   - lineItemMap: The formatted items of the line by name
+//   - keys: The names of the items to keep
+//
+// Returns:
+//   - map[string]string: The items of the line that are present among keys
+func selectLineItems(lineItemMap map[string]string, keys []string) map[string]string {
+	selected := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, ok := lineItemMap[key]; ok {
+			selected[key] = value
+		}
+	}
+	return selected
+}
+
+// padVisible pads a string with spaces to the given visible width.
+//
+// Parameters:
+//   - value: The string to pad, which may contain ANSI escape sequences
+//   - width: The visible width to pad to
+//
+// Returns:
+//   - string: The padded string, or value itself if it is already as wide
+func padVisible(value string, width int) string {
+	return value + strings.Repeat(" ", max(width-util.VisibleWidth(value), 0))
+}
+
+// columnWidths returns the visible width of the widest value of each column among the rows. It
+// is the first pass of the padding of the metrics with --align and of the identity fields.
+//
+// Parameters:
+//   - rows: The formatted values of each row by column name
+//   - keys: The names of the columns to measure
+//
+// Returns:
+//   - map[string]int: The width of each column present in at least one row
+func columnWidths(rows []map[string]string, keys []string) map[string]int {
+	widths := make(map[string]int, len(keys))
+	for _, row := range rows {
+		for _, key := range keys {
+			if value, ok := row[key]; ok {
+				widths[key] = max(widths[key], util.VisibleWidth(value))
+			}
+		}
+	}
+	return widths
+}
+
+// printAligned pads the lines buffered by PrintTree to common column widths and prints them.
+//
+// The tree prefix and the identity of each process are padded to the widest of them, and each
+// of the columns returned by alignedColumns to its widest value, with blanks on the lines
+// without it. If the commands would start beyond DisplayOptions.ScreenWidth, and the output is
+// not wide, the lines are printed as they were built instead.
+//
+// Returns:
+//   - error: ErrBrokenPipe if the reader of the output went away, or any other error encountered while writing
+func (processTree *ProcessTree) printAligned() error {
+	var (
+		builder    strings.Builder
+		columns    []string
+		identities []string
+		keys       []string
+		leftWidth  int
+		lineStart  int
+		rows       []map[string]string
+		widths     map[string]int
+	)
+
+	processTree.Logger.Debug("Entering processTree.printAligned()")
+	keys = processTree.lineItemOrder()
+	columns = alignedColumns(keys)
+	identities = make([]string, len(processTree.alignedLines))
+
+	for i, line := range processTree.alignedLines {
+		if line.items == nil {
+			continue
+		}
+		identities[i] = line.start + joinLineItems(selectLineItems(line.items, identityKeys), keys)
+		leftWidth = max(leftWidth, util.VisibleWidth(processTree.depthLabel(line.pidIndex)+identities[i]))
+		rows = append(rows, line.items)
+	}
+
+	// Each column is followed by the space separating it from the next one
+	widths = columnWidths(rows, columns)
+	lineStart = leftWidth
+	for key := range widths {
+		widths[key]++
+		lineStart += widths[key]
+	}
+	aligned := processTree.DisplayOptions.WideDisplay || lineStart < processTree.DisplayOptions.ScreenWidth
+	if !aligned {
+		processTree.Logger.Debug("The aligned columns are wider than the screen, printing the lines unaligned")
+	}
+
+	for i, line := range processTree.alignedLines {
+		builder.Reset()
+		switch {
+		case line.items == nil:
+			builder.WriteString(line.start)
+		case !aligned:
+			builder.WriteString(line.start + joinLineItems(line.items, keys))
+		default:
+			// The depth label is added by printLine, in front of the padded prefix
+			builder.WriteString(padVisible(identities[i], leftWidth-util.VisibleWidth(processTree.depthLabel(line.pidIndex))))
+			for _, key := range columns {
+				if width, ok := widths[key]; ok {
+					builder.WriteString(padVisible(line.items[key], width))
+				}
+			}
+			builder.WriteString(joinLineItems(selectLineItems(line.items, trailingKeys), keys))
+		}
+		if err := processTree.printLine(builder.String(), line.head, line.newHead, line.pidIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/util"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// alignTestProcesses returns a tree whose metrics differ in width from line to line
+func alignTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 0.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1024}},
+		{PID: 100, PPID: 1, Command: "sshd", HasCPU: true, CPUPercent: 12.25, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 3 * 1024 * 1024}},
+		{PID: 1000, PPID: 100, Command: "bash", HasCPU: true, CPUPercent: 1, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 512}},
+	}
+}
+
+// renderAligned renders the test tree with the given options, with the CPU and memory usage shown
+func renderAligned(t *testing.T, displayOptions DisplayOptions) []string {
+	displayOptions.MaxDepth = 10
+	displayOptions.ShowCpuPercent = true
+	displayOptions.ShowMemoryUsage = true
+	displayOptions.ShowPIDs = true
+	processTree := NewProcessTree(0, setupTestLogger(), alignTestProcesses(), displayOptions)
+	output := renderProcessTree(t, processTree)
+	assert.Empty(t, processTree.alignedLines)
+	return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+}
+
+func TestPrintAligned(t *testing.T) {
+	// Without --align, the metrics follow the depth of each process
+	assert.Equal(t, []string{
+		"-+- (1) (c:0.50%) (m:1.0 KiB) init ",
+		" \\-+- (100) (c:12.25%) (m:3.0 MiB) sshd ",
+		"   \\--- (1000) (c:1.00%) (m:512.0 B) bash ",
+	}, renderAligned(t, DisplayOptions{ScreenWidth: 80}))
+
+	// Each column is padded to its widest value
+	assert.Equal(t, []string{
+		"-+- (1)        (c:0.50%)  (m:1.0 KiB) init ",
+		" \\-+- (100)    (c:12.25%) (m:3.0 MiB) sshd ",
+		"   \\--- (1000) (c:1.00%)  (m:512.0 B) bash ",
+	}, renderAligned(t, DisplayOptions{AlignColumns: true, ScreenWidth: 80}))
+
+	// The lines stay unaligned when the commands would start beyond the screen, unless the output is wide
+	assert.Equal(t, renderAligned(t, DisplayOptions{ScreenWidth: 30}), renderAligned(t, DisplayOptions{AlignColumns: true, ScreenWidth: 30}))
+	assert.Equal(t, "-+- (1)        (c:0.50%)  (m:1.0 KiB) init ", renderAligned(t, DisplayOptions{AlignColumns: true, ScreenWidth: 30, WideDisplay: true})[0])
+}
+
+func TestPrintAlignedColors(t *testing.T) {
+	// The escape sequences don't count toward the widths of the columns
+	lines := renderAligned(t, DisplayOptions{AlignColumns: true, ColorCount: 256, ColorizeOutput: true, ColorSupport: true, ScreenWidth: 80})
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.Contains(t, line, "\x1b[")
+	}
+	plain := renderAligned(t, DisplayOptions{AlignColumns: true, ScreenWidth: 80})
+	for i, command := range []string{"init", "sshd", "bash"} {
+		assert.Equal(t, util.VisibleWidth(plain[i]), util.VisibleWidth(lines[i]))
+		// The commands start at the same column as without colors
+		assert.Equal(t, strings.Index(plain[i], command), util.VisibleWidth(lines[i][:strings.Index(lines[i], command)]))
+	}
+}
+
+func TestAlignedColumns(t *testing.T) {
+	columns := alignedColumns(lineItemKeys)
+	assert.Equal(t, "age", columns[0])
+	assert.Equal(t, "privileged", columns[len(columns)-1])
+	assert.NotContains(t, columns, "owner")
+	assert.NotContains(t, columns, "command")
+	assert.Len(t, columns, len(lineItemKeys)-len(identityKeys)-len(trailingKeys))
+}
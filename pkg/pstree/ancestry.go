@@ -0,0 +1,42 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the reverse tree (--parents-of), which shows the chain from a process up
+// to its root the way pstree -s does, optionally with the direct children of the process
+// (--with-children). When the parent of a process in the chain was not collected, e.g., because
+// it exited while the processes were read, the chain stops at the break and the topmost process
+// found is printed as the root.
+package pstree
+
+import (
+	"fmt"
+)
+
+// markAncestry marks the DisplayOptions.ParentsOf process and its ancestors as printable,
+// along with its direct children with DisplayOptions.WithChildren.
+//
+// Nothing is marked when the process does not exist; RootIndices reports it instead.
+func (processTree *ProcessTree) markAncestry() {
+	processTree.Logger.Debug("Entering processTree.markAncestry()")
+	var (
+		childPidIndex int
+		ok            bool
+		pidIndex      int
+	)
+
+	pidIndex, ok = processTree.PidToIndexMap[processTree.DisplayOptions.ParentsOf]
+	if !ok {
+		return
+	}
+
+	processTree.Logger.Debug(fmt.Sprintf("Marking PID %d and its ancestors", processTree.DisplayOptions.ParentsOf))
+	processTree.markParents(pidIndex)
+	processTree.Nodes[pidIndex].Print = true
+
+	if processTree.DisplayOptions.WithChildren {
+		childPidIndex = processTree.Nodes[pidIndex].Child
+		for childPidIndex != -1 {
+			processTree.Nodes[childPidIndex].Print = true
+			childPidIndex = processTree.Nodes[childPidIndex].Sister
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ancestryTestProcesses returns a tree with a long chain and a process whose parent is missing:
+//
+//	init(1) -+- sshd(100) --- bash(101) -+- vim(102) --- lsp(103)
+//	         |                           \- make(104)
+//	         \- cron(200)
+//	worker(301), whose parent 300 exited --- job(302)
+func ancestryTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+		{PID: 101, PPID: 100, Command: "bash"},
+		{PID: 102, PPID: 101, Command: "vim"},
+		{PID: 103, PPID: 102, Command: "lsp"},
+		{PID: 104, PPID: 101, Command: "make"},
+		{PID: 200, PPID: 1, Command: "cron"},
+		{PID: 301, PPID: 300, Command: "worker"},
+		{PID: 302, PPID: 301, Command: "job"},
+	}
+}
+
+func TestMarkAncestry(t *testing.T) {
+	mark := func(displayOptions DisplayOptions) []int32 {
+		processTree := NewProcessTree(0, setupTestLogger(), ancestryTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		return markedPIDs(processTree)
+	}
+
+	// Only the chain up to the root is marked, not the siblings or children
+	assert.Equal(t, []int32{1, 100, 101, 102}, mark(DisplayOptions{ParentsOf: 102}))
+
+	// The direct children are added, but not their descendants
+	assert.Equal(t, []int32{1, 100, 101, 102, 104}, mark(DisplayOptions{ParentsOf: 101, WithChildren: true}))
+
+	// The chain stops where the parent is missing
+	assert.Equal(t, []int32{301, 302}, mark(DisplayOptions{ParentsOf: 302}))
+
+	// A missing process marks nothing
+	assert.Equal(t, []int32{}, mark(DisplayOptions{ParentsOf: 999}))
+}
+
+func TestParentsOfRender(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), ancestryTestProcesses(), DisplayOptions{MaxDepth: 10, ParentsOf: 103, ScreenWidth: 80})
+	output := renderProcessTree(t, processTree)
+	assert.Equal(t, "-+- init \n \\-+- sshd \n   \\-+- bash \n     \\-+- vim \n       \\--- lsp \n", output)
+
+	// A PID that doesn't exist is an error
+	processTree = NewProcessTree(0, setupTestLogger(), ancestryTestProcesses(), DisplayOptions{MaxDepth: 10, ParentsOf: 999, ScreenWidth: 80})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	_, err := processTree.RenderString()
+	assert.ErrorContains(t, err, "999")
+}
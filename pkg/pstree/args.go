@@ -0,0 +1,53 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the trimming of long argument lists shown with --arguments, e.g., the
+// hundreds of flags of a java or chrome process. --args-filter shows only the arguments matching
+// a regular expression, e.g., -Xmx to find the heap size of a JVM, and --max-args shows only the
+// first arguments followed by the number of arguments left out. Both only change how the
+// arguments are rendered: identical processes are still compacted by their full arguments, and
+// the arguments are trimmed before the line is truncated to the screen width.
+package pstree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ArgsEllipsis is shown after the arguments kept by --max-args, followed by the number of
+// arguments left out, e.g., … (+12 more).
+const ArgsEllipsis = "…"
+
+// formatArgs joins the arguments of a process for display, keeping only those matching
+// DisplayOptions.ArgsFilter, and only the first DisplayOptions.MaxArgs of them.
+//
+// Parameters:
+//   - node: The process whose arguments are formatted
+//
+// Returns:
+//   - string: The arguments separated by spaces, e.g., -jar app.jar … (+3 more), or an empty string if none are shown
+func (processTree *ProcessTree) formatArgs(node *Process) string {
+	var (
+		args    []string
+		omitted int
+	)
+
+	args = processTree.displayArgs(node)
+	if processTree.DisplayOptions.ArgsFilter != nil {
+		args = make([]string, 0, len(args))
+		for _, arg := range processTree.displayArgs(node) {
+			if processTree.DisplayOptions.ArgsFilter.MatchString(arg) {
+				args = append(args, arg)
+			}
+		}
+	}
+
+	if processTree.DisplayOptions.MaxArgs > 0 && len(args) > processTree.DisplayOptions.MaxArgs {
+		omitted = len(args) - processTree.DisplayOptions.MaxArgs
+		args = args[:processTree.DisplayOptions.MaxArgs]
+	}
+
+	if omitted > 0 {
+		return fmt.Sprintf("%s %s (+%d more)", strings.Join(args, " "), ArgsEllipsis, omitted)
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,96 @@
+package pstree
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// argsTestProcesses returns a JVM with a long argument list, two workers that only differ in
+// their last argument, and a process with multi-byte arguments:
+//
+//	init(1) -+- java(100)
+//	         |- worker(200, --queue=a)
+//	         |- worker(201, --queue=b)
+//	         \- convert(300, 写真/日本語.png)
+func argsTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "java", Args: []string{"-server", "-Xms512m", "-Xmx4g", "-XX:+UseG1GC", "-jar", "app.jar"}},
+		{PID: 200, PPID: 1, Command: "worker", Args: []string{"--verbose", "--queue=a"}},
+		{PID: 201, PPID: 1, Command: "worker", Args: []string{"--verbose", "--queue=b"}},
+		{PID: 300, PPID: 1, Command: "convert", Args: []string{"写真/日本語.png", "-resize", "50%", "出力/サムネイル.png"}},
+	}
+}
+
+func TestFormatArgs(t *testing.T) {
+	processes := argsTestProcesses()
+	format := func(displayOptions DisplayOptions, node *Process) string {
+		processTree := &ProcessTree{DisplayOptions: displayOptions}
+		return processTree.formatArgs(node)
+	}
+
+	assert.Equal(t, "-server -Xms512m -Xmx4g -XX:+UseG1GC -jar app.jar", format(DisplayOptions{}, &processes[1]))
+	assert.Equal(t, "-server -Xms512m … (+4 more)", format(DisplayOptions{MaxArgs: 2}, &processes[1]))
+	assert.Equal(t, "-server -Xms512m -Xmx4g -XX:+UseG1GC -jar app.jar", format(DisplayOptions{MaxArgs: 6}, &processes[1]))
+	assert.Equal(t, "-Xmx4g", format(DisplayOptions{ArgsFilter: regexp.MustCompile(`^-Xmx`)}, &processes[1]))
+
+	// The limit applies to the arguments matching the filter
+	assert.Equal(t, "-Xms512m … (+2 more)", format(DisplayOptions{ArgsFilter: regexp.MustCompile(`^-X`), MaxArgs: 1}, &processes[1]))
+
+	// No argument is shown when none matches
+	assert.Equal(t, "", format(DisplayOptions{ArgsFilter: regexp.MustCompile(`^-Xss`)}, &processes[1]))
+	assert.Equal(t, "", format(DisplayOptions{MaxArgs: 1}, &processes[0]))
+
+	// Multi-byte arguments are kept whole
+	assert.Equal(t, "写真/日本語.png … (+3 more)", format(DisplayOptions{MaxArgs: 1}, &processes[4]))
+	assert.Equal(t, "写真/日本語.png 出力/サムネイル.png", format(DisplayOptions{ArgsFilter: regexp.MustCompile(`\.png$`)}, &processes[4]))
+}
+
+func TestShowTrimmedArgs(t *testing.T) {
+	render := func(displayOptions DisplayOptions) []string {
+		displayOptions.ShowArguments = true
+		output := renderTree(t, argsTestProcesses(), displayOptions)
+		return strings.Split(strings.TrimRight(output, "\n"), "\n")
+	}
+
+	output := strings.Join(render(DisplayOptions{MaxArgs: 1, ScreenWidth: 80}), "\n")
+	assert.Contains(t, output, "java -server … (+5 more)")
+	assert.Contains(t, output, "convert 写真/日本語.png … (+3 more)")
+
+	// The workers are not compacted, since they are grouped by their full arguments
+	lines := render(DisplayOptions{CompactMode: true, MaxArgs: 1, ScreenWidth: 80})
+	assert.NotContains(t, strings.Join(lines, "\n"), "2*[worker")
+	count := 0
+	for _, line := range lines {
+		if strings.Contains(line, "worker --verbose … (+1 more)") {
+			count++
+		}
+	}
+	assert.Equal(t, 2, count)
+
+	// The trimmed arguments are truncated to the screen width like the rest of the line
+	for _, line := range render(DisplayOptions{MaxArgs: 3, ScreenWidth: 30}) {
+		assert.LessOrEqual(t, util.VisibleWidth(line), 30)
+		assert.Regexp(t, `^ ?[|\\-]`, line, "the tree branches stay aligned")
+	}
+}
+
+func TestWriteFlatTrimmedArgs(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), argsTestProcesses(), DisplayOptions{
+		ArgsFilter:    regexp.MustCompile(`^-Xm`),
+		MaxArgs:       1,
+		ShowArguments: true,
+	})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	require.NoError(t, processTree.WriteFlat(&output, ',', []int{0}))
+	assert.Contains(t, output.String(), "java,-Xms512m … (+1 more)")
+}
@@ -0,0 +1,120 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the handling of argv[0], the name a process was started with. It is
+// usually the path or the name of the executable, and it is left out of the arguments since the
+// command already shows it. Some programs are started with another name, e.g., login shells
+// shown as -bash, or change it to describe themselves, e.g., postgres: checkpointer. Like ps,
+// such a process is shown with its argv[0] in place of the name of its executable, and it is not
+// compacted with the processes started under another name. With --raw-args, the arguments are
+// shown as they were collected, argv[0] included, after the name of the executable. When the
+// command lines are not collected, see SkipArgs, argv[0] alone is still read so the processes
+// are named the same way.
+package pstree
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// argv0Limit is the number of bytes of the command line read for argv[0], a page being more than
+// any program name; a longer argv[0] is cut.
+const argv0Limit = 4096
+
+// splitArgv0 splits argv[0] off the command line of a process.
+//
+// Parameters:
+//   - args: The command line of the process, argv[0] first
+//
+// Returns:
+//   - string: argv[0], empty if the command line is empty
+//   - []string: The arguments of the process
+func splitArgv0(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", args
+	}
+	return args[0], args[1:]
+}
+
+// readArgv0 reads argv[0] of a process, the first NUL-terminated field of /proc/<pid>/cmdline,
+// without reading the rest of the command line.
+//
+// Parameters:
+//   - procPath: Mount point of the proc filesystem
+//   - pid: The process ID
+//
+// Returns:
+//   - string: argv[0], empty for the kernel threads, whose command line is empty
+//   - error: Any error encountered while reading the file
+func readArgv0(procPath string, pid int32) (string, error) {
+	file, err := os.Open(filepath.Join(procPath, strconv.Itoa(int(pid)), "cmdline"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, argv0Limit)
+	count, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	argv0, _, _ := bytes.Cut(buffer[:count], []byte{0})
+	return string(argv0), nil
+}
+
+// programName returns the name of a program without its directory and extension, e.g., bash for
+// /usr/bin/bash or python3 for python3.11, so the names an executable is usually started with
+// compare equal to it.
+//
+// Parameters:
+//   - name: The path or name of the program
+//
+// Returns:
+//   - string: The name of the program
+func programName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// hasOwnArgv0 determines whether a process was started with another name than its executable.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if argv[0] names another program than the executable, false otherwise
+func hasOwnArgv0(node *Process) bool {
+	return node.Argv0 != "" && programName(node.Argv0) != programName(node.Command)
+}
+
+// commandName returns the command shown for a process: its argv[0] when it was started with
+// another name than its executable, unless --raw-args is used, and the executable otherwise.
+//
+// Parameters:
+//   - node: The process to name
+//
+// Returns:
+//   - string: The command, to be formatted with FormatCommand
+func (processTree *ProcessTree) commandName(node *Process) string {
+	if !processTree.DisplayOptions.RawArgs && hasOwnArgv0(node) {
+		return node.Argv0
+	}
+	return node.Command
+}
+
+// displayArgs returns the arguments shown for a process, preceded by argv[0] with --raw-args.
+//
+// Parameters:
+//   - node: The process whose arguments are shown
+//
+// Returns:
+//   - []string: The arguments
+func (processTree *ProcessTree) displayArgs(node *Process) []string {
+	if processTree.DisplayOptions.RawArgs && node.Argv0 != "" {
+		return append([]string{node.Argv0}, node.Args...)
+	}
+	return node.Args
+}
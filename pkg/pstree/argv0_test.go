@@ -0,0 +1,123 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// argv0Processes returns a login shell, a plain shell, and nginx workers naming themselves
+func argv0Processes() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "/sbin/init", Argv0: "/sbin/init"},
+		{PID: 100, PPID: 1, Command: "/usr/bin/bash", Argv0: "-bash"},
+		{PID: 101, PPID: 1, Command: "/usr/bin/bash", Argv0: "bash", Args: []string{"-c", "sleep 1"}},
+		{PID: 200, PPID: 1, Command: "/usr/sbin/nginx", Argv0: "nginx: master process"},
+		{PID: 201, PPID: 200, Command: "/usr/sbin/nginx", Argv0: "nginx: worker process"},
+		{PID: 202, PPID: 200, Command: "/usr/sbin/nginx", Argv0: "nginx: worker process"},
+		{PID: 203, PPID: 200, Command: "/usr/sbin/nginx", Argv0: "nginx: cache manager process"},
+	}
+}
+
+func TestSplitArgv0(t *testing.T) {
+	argv0, args := splitArgv0([]string{"-bash", "-l"})
+	assert.Equal(t, "-bash", argv0)
+	assert.Equal(t, []string{"-l"}, args)
+
+	argv0, args = splitArgv0([]string{})
+	assert.Empty(t, argv0)
+	assert.Empty(t, args)
+}
+
+func TestReadArgv0(t *testing.T) {
+	procPath := t.TempDir()
+	writeCmdline := func(pid string, cmdline string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(procPath, pid), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(procPath, pid, "cmdline"), []byte(cmdline), 0o644))
+	}
+	writeCmdline("100", "-bash\x00-l\x00")
+	writeCmdline("200", "nginx: worker process\x00\x00\x00")
+	writeCmdline("300", "")
+	writeCmdline("400", strings.Repeat("x", argv0Limit+10))
+
+	for _, test := range []struct {
+		pid      int32
+		expected string
+	}{
+		{100, "-bash"},
+		{200, "nginx: worker process"},
+		{300, ""},
+		{400, strings.Repeat("x", argv0Limit)},
+	} {
+		argv0, err := readArgv0(procPath, test.pid)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, argv0, "PID %d", test.pid)
+	}
+
+	_, err := readArgv0(procPath, 500)
+	assert.Error(t, err)
+
+	// Without compact mode, e.g., with -n, argv[0] alone still names a login shell
+	argv0, err := readArgv0(procPath, 100)
+	require.NoError(t, err)
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "/sbin/init"},
+		{PID: 100, PPID: 1, Command: "/usr/bin/bash", Argv0: argv0},
+	}
+	output := renderTree(t, processes, DisplayOptions{})
+	assert.Contains(t, output, "--- -bash \n")
+}
+
+func TestHasOwnArgv0(t *testing.T) {
+	for _, test := range []struct {
+		command  string
+		argv0    string
+		expected bool
+	}{
+		{"/usr/bin/bash", "bash", false},
+		{"/usr/bin/bash", "/bin/bash", false},
+		{"/usr/bin/python3.11", "python3", false},
+		{`C:\Program Files\Git\bin\bash.exe`, "bash", false},
+		{"[kworker/0:1]", "", false},
+		{"/usr/bin/bash", "-bash", true},
+		{"/usr/sbin/nginx", "nginx: worker process", true},
+	} {
+		assert.Equal(t, test.expected, hasOwnArgv0(&Process{Command: test.command, Argv0: test.argv0}), "%s started as %q", test.command, test.argv0)
+	}
+}
+
+func TestArgv0Output(t *testing.T) {
+	displayOptions := DisplayOptions{CompactMode: true, MaxDepth: 10, ScreenWidth: 80, ShowArguments: true}
+	processTree := NewProcessTree(0, setupTestLogger(), argv0Processes(), displayOptions)
+	processTree.MarkProcesses()
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	assert.Contains(t, output, "--- -bash \n")
+	assert.Contains(t, output, "--- bash -c sleep 1\n")
+	assert.Contains(t, output, "-+- nginx: master process \n")
+	assert.Contains(t, output, "--- nginx: cache manager process \n")
+
+	// The workers started under the same name are still compacted, not with the cache manager
+	processTree.InitCompactMode()
+	count, _, _, _, _, _ := processTree.GetProcessCount(processTree.PidToIndexMap[201])
+	assert.Equal(t, 2, count)
+
+	// With --raw-args, the executable is shown, followed by argv[0] and the arguments
+	displayOptions.RawArgs = true
+	processTree = NewProcessTree(0, setupTestLogger(), argv0Processes(), displayOptions)
+	processTree.MarkProcesses()
+	output, err = processTree.RenderString()
+	require.NoError(t, err)
+	assert.Contains(t, output, "--- bash -bash\n")
+	assert.Contains(t, output, "--- bash bash -c sleep 1\n")
+	assert.Equal(t, []string{"bash", "-c", "sleep 1"}, processTree.displayArgs(processTree.Nodes[processTree.PidToIndexMap[101]]))
+}
+
+func TestArgv0KernelThread(t *testing.T) {
+	assert.True(t, IsKernelThread(Process{PID: 50, Command: "[kworker/0:1]"}))
+	assert.False(t, IsKernelThread(Process{PID: 51, Command: "[fake]", Argv0: "[fake]"}), "a process named like a kernel thread has a command line")
+}
@@ -1,8 +1,16 @@
 package pstree
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/process"
 )
 
 // BenchmarkBuildTree benchmarks the BuildTree function with different numbers of processes
@@ -55,6 +63,266 @@ func BenchmarkBuildTree(b *testing.B) {
 	}
 }
 
+// BenchmarkGenerateProcesses compares collecting processes serially with the worker pool
+func BenchmarkGenerateProcesses(b *testing.B) {
+	// Create a synthetic list of processes that exist for the whole benchmark
+	procs := make([]*process.Process, 0, 200)
+	for i := 0; i < 100; i++ {
+		procs = append(procs, &process.Process{Pid: 1}, &process.Process{Pid: int32(os.Getpid())})
+	}
+	miniOptions := DisplayOptions{ShowCpuPercent: true, ShowMemoryUsage: true, ShowNumThreads: true}
+
+	b.Run("Serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			processes := make([]Process, 0, len(procs))
+			for _, proc := range procs {
+				generated, _ := GenerateProcess(proc, miniOptions)
+				processes = append(processes, generated)
+			}
+		}
+	})
+
+	b.Run("WorkerPool", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			generateProcesses(procs, miniOptions, nil)
+		}
+	})
+}
+
+// BenchmarkSkipArgs compares collecting the processes of a large host with their full command
+// lines and with only argv[0], which is all that is read when no arguments are shown or matched,
+// reporting the time spent reading the command lines of each collection
+func BenchmarkSkipArgs(b *testing.B) {
+	procs := make([]*process.Process, 0, 2000)
+	for i := 0; i < 1000; i++ {
+		procs = append(procs, &process.Process{Pid: 1}, &process.Process{Pid: int32(os.Getpid())})
+	}
+
+	for _, test := range []struct {
+		name     string
+		skipArgs bool
+	}{
+		{"Args", false},
+		{"SkipArgs", true},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			timings := &CollectionTimings{}
+			for i := 0; i < b.N; i++ {
+				generateProcesses(procs, DisplayOptions{SkipArgs: test.skipArgs}, timings)
+			}
+			b.ReportMetric(float64(timings.Attributes["args"].Microseconds())/float64(b.N), "args-us/op")
+		})
+	}
+}
+
+// firstWriteWriter discards the writes made to it, recording when the first one was made.
+type firstWriteWriter struct {
+	first time.Time
+}
+
+func (w *firstWriteWriter) Write(p []byte) (int, error) {
+	if w.first.IsZero() {
+		w.first = time.Now()
+	}
+	return len(p), nil
+}
+
+// BenchmarkStreamPrint compares printing a large tree with --align, which buffers every line
+// until the whole tree is formatted, with --stream, which writes each line as it is built,
+// reporting the time until the first line is written along with the memory allocated
+func BenchmarkStreamPrint(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	displayOptions := DisplayOptions{MaxDepth: 999, ShowCpuPercent: true, ShowMemoryUsage: true, ShowPIDs: true, ShowPPIDs: true, WideDisplay: true}
+
+	for _, test := range []struct {
+		name   string
+		align  bool
+		stream bool
+	}{
+		{"Align", true, false},
+		{"Default", false, false},
+		{"Stream", false, true},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			options := displayOptions
+			options.AlignColumns = test.align
+			options.Stream = test.stream
+			output := &firstWriteWriter{}
+			processTree := NewProcessTreeWithOutput(0, logger, syntheticProcesses(50000), options, output)
+			processTree.MarkProcesses()
+
+			var firstLine time.Duration
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				output.first = time.Time{}
+				start := time.Now()
+				if err := processTree.PrintTree(0, ""); err != nil {
+					b.Fatal(err)
+				}
+				firstLine += output.first.Sub(start)
+			}
+			b.ReportMetric(float64(firstLine.Microseconds())/float64(b.N), "first-line-us/op")
+		})
+	}
+}
+
+// BenchmarkUsernameLookups compares looking the username of every process up with the lookups of the
+// usernameCache shared by the workers, reporting the number of lookups of each collection
+func BenchmarkUsernameLookups(b *testing.B) {
+	procs := make([]*process.Process, 0, 1000)
+	for i := 0; i < 500; i++ {
+		procs = append(procs, &process.Process{Pid: 1}, &process.Process{Pid: int32(os.Getpid())})
+	}
+
+	b.Run("PerProcess", func(b *testing.B) {
+		resolver := &slowResolver{}
+		miniOptions := DisplayOptions{ShowOwner: true, UserResolver: resolver}
+		for i := 0; i < b.N; i++ {
+			for _, proc := range procs {
+				GenerateProcess(proc, miniOptions)
+			}
+		}
+		b.ReportMetric(float64(resolver.lookups.Load())/float64(b.N), "lookups/op")
+	})
+
+	b.Run("Cached", func(b *testing.B) {
+		resolver := &slowResolver{}
+		miniOptions := DisplayOptions{ShowOwner: true, UserResolver: resolver}
+		for i := 0; i < b.N; i++ {
+			generateProcesses(procs, miniOptions, nil)
+		}
+		b.ReportMetric(float64(resolver.lookups.Load())/float64(b.N), "lookups/op")
+	})
+}
+
+// BenchmarkLazyCollection compares collecting the usage of every process with deferring it until
+// a narrow filter, here the test binary itself, left only a few processes to display
+func BenchmarkLazyCollection(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	displayOptions := DisplayOptions{Contains: filepath.Base(os.Args[0]), ShowCpuPercent: true, ShowMemoryUsage: true, ShowNumThreads: true, ShowProcessAge: true}
+	miniOptions := displayOptions
+
+	b.Run("Eager", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			processes, err := GetProcesses(miniOptions)
+			if err != nil {
+				b.Fatal(err)
+			}
+			processTree := NewProcessTreeWithOutput(0, logger, processes, displayOptions, io.Discard)
+			processTree.MarkProcesses()
+			processTree.DropUnmarked()
+		}
+	})
+
+	b.Run("Deferred", func(b *testing.B) {
+		first, deferred := SplitCollection(miniOptions)
+		for i := 0; i < b.N; i++ {
+			processes, err := GetProcesses(first)
+			if err != nil {
+				b.Fatal(err)
+			}
+			processTree := NewProcessTreeWithOutput(0, logger, processes, displayOptions, io.Discard)
+			processTree.MarkProcesses()
+			processTree.DropUnmarked()
+			processTree.CollectDeferredUsage(deferred, nil)
+		}
+	})
+}
+
+// syntheticTreeSizes are the tree sizes of the benchmarks covering the whole pipeline.
+var syntheticTreeSizes = []struct {
+	name     string
+	numProcs int
+}{
+	{"1k", 1000},
+	{"10k", 10000},
+	{"50k", 50000},
+}
+
+// syntheticProcesses creates a tree of processes where each parent has eight children, the
+// way a few supervisors run pools of identical workers. Commands and owners repeat, so compact
+// mode finds groups to merge, and every fourth process matches the "worker1" pattern.
+func syntheticProcesses(numProcs int) []Process {
+	const branching = 8
+	users := []string{"root", "www-data", "postgres"}
+
+	processes := make([]Process, numProcs)
+	processes[0] = Process{PID: 1, PPID: 0, Command: "init", Username: "root", HasCPU: true, CPUPercent: 0.1, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4096}}
+	for i := 1; i < numProcs; i++ {
+		processes[i] = Process{
+			PID:        int32(i + 1),
+			PPID:       int32((i-1)/branching + 1),
+			Command:    fmt.Sprintf("worker%d", i%4),
+			Args:       []string{"--pool", fmt.Sprintf("%d", i%3)},
+			Username:   users[i%len(users)],
+			HasCPU:     true,
+			CPUPercent: float64(i%100) / 10,
+			HasMemory:  true,
+			MemoryInfo: &process.MemoryInfoStat{RSS: uint64(i%64) * 1024 * 1024},
+			NumThreads: int32(i%16 + 1),
+			NumFDs:     int32(i % 32),
+			Age:        int64(i),
+			Status:     []string{"S"},
+			CPUTimes:   &cpu.TimesStat{User: float64(i % 60)},
+			ExeDeleted: i%1000 == 0,
+		}
+	}
+	return processes
+}
+
+// copyProcesses returns a fresh copy of the processes, since building a tree links the nodes
+// given to it.
+func copyProcesses(processes []Process) []Process {
+	return append([]Process(nil), processes...)
+}
+
+// BenchmarkNewProcessTree benchmarks building a process tree, including the subtree signatures
+func BenchmarkNewProcessTree(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, bc := range syntheticTreeSizes {
+		b.Run(bc.name, func(b *testing.B) {
+			processes := syntheticProcesses(bc.numProcs)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				NewProcessTree(0, logger, copyProcesses(processes), DisplayOptions{ShowArguments: true})
+			}
+		})
+	}
+}
+
+// BenchmarkInitCompactMode benchmarks grouping the identical processes of a tree
+func BenchmarkInitCompactMode(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	displayOptions := DisplayOptions{ShowCpuPercent: true, ShowMemoryUsage: true, ShowNumThreads: true}
+	for _, bc := range syntheticTreeSizes {
+		b.Run(bc.name, func(b *testing.B) {
+			processTree := NewProcessTree(0, logger, syntheticProcesses(bc.numProcs), displayOptions)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				processTree.InitCompactMode()
+			}
+		})
+	}
+}
+
+// BenchmarkMarkProcesses benchmarks marking the processes to display with a pattern filter
+func BenchmarkMarkProcesses(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, bc := range syntheticTreeSizes {
+		b.Run(bc.name, func(b *testing.B) {
+			processTree := NewProcessTree(0, logger, syntheticProcesses(bc.numProcs), DisplayOptions{Contains: "worker1"})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				processTree.MarkProcesses()
+			}
+		})
+	}
+}
+
 // generateTestProcesses creates a slice of test processes with a realistic hierarchy
 func generateTestProcesses(numProcs, maxDepth, branching int) []*Process {
 	processes := make([]*Process, 0, numProcs)
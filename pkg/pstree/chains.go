@@ -0,0 +1,163 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the collapsing of the chains of --collapse-chains. A deep chain of
+// processes with a single child each, e.g., sshd, bash, and vim below a session, takes a line per
+// process and pushes the rest of the tree to the right. With --collapse-chains, the run of
+// single children is printed on the line of its first process, the commands separated by the
+// horizontal connector of the tree style, e.g., sshd───bash───vim, and the children of the
+// deepest process continue below it. The identity and the metrics shown are the ones of the
+// deepest process, which already include its descendants with --cumulative. The tree itself is
+// left as it is, so the depths of --level and --show-depth still count each process of a chain.
+//
+// A chain is broken where collapsing it would hide what was asked to be shown for each process:
+// a change of owner with --show-owner, --uid-transitions, or --user-transitions, a change of
+// container with --containers, and an orphan or privileged marker. The threads, the orphans and
+// container nodes, and the groups of identical processes of compact mode are never collapsed.
+package pstree
+
+import (
+	"strings"
+)
+
+// chainEnd returns the deepest process of the chain starting at a process with
+// --collapse-chains, following the single children that can be collapsed into its line and
+// stopping at the maximum depth.
+//
+// Parameters:
+//   - pidIndex: Index of the first process of the chain in the Nodes array
+//
+// Returns:
+//   - int: Index of the deepest process of the chain, pidIndex itself if nothing is collapsed
+//   - int: Number of processes collapsed below the first one
+func (processTree *ProcessTree) chainEnd(pidIndex int) (int, int) {
+	var (
+		child  int
+		length int
+		last   = pidIndex
+	)
+
+	if !processTree.DisplayOptions.CollapseChains {
+		return last, 0
+	}
+
+	for {
+		child = processTree.Nodes[last].Child
+		if child == -1 || processTree.Nodes[child].Sister != -1 || processTree.AtDepth+length+1 > processTree.DisplayOptions.MaxDepth {
+			return last, length
+		}
+		if processTree.breaksChain(last, child) {
+			return last, length
+		}
+		last = child
+		length++
+	}
+}
+
+// breaksChain reports whether the only child of a process has to be printed on a line of its
+// own rather than collapsed into the line of its parent.
+//
+// Parameters:
+//   - parentIndex: Index of the parent in the Nodes array
+//   - childIndex: Index of its only child in the Nodes array
+//
+// Returns:
+//   - bool: true if the chain stops at the parent
+func (processTree *ProcessTree) breaksChain(parentIndex int, childIndex int) bool {
+	parent := processTree.Nodes[parentIndex]
+	child := processTree.Nodes[childIndex]
+
+	switch {
+	case parent.IsThread || child.IsThread || isSyntheticNode(parent) || isSyntheticNode(child):
+		return true
+	case parent.IsOrphan || parent.IsPrivileged:
+		// The markers of the parent would be lost, only the items of the deepest process are shown
+		return true
+	case processTree.DisplayOptions.ShowOwner && processTree.ownerName(parent) != processTree.ownerName(child):
+		return true
+	case (processTree.DisplayOptions.ShowUIDTransitions || processTree.DisplayOptions.ShowUserTransitions) && child.HasUIDTransition:
+		return true
+	case processTree.DisplayOptions.ShowContainers && parent.ContainerID != child.ContainerID:
+		return true
+	}
+
+	if processTree.DisplayOptions.CompactMode {
+		// A line standing for several identical processes keeps its own line
+		if group, ok := processTree.getProcessGroup(parentIndex); ok && group.Count > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// buildChainItems builds the parts of the line of a process like buildLineItems, collapsing the
+// chain starting at the process with --collapse-chains. The tree prefix is the one of the first
+// process, with the branch to the children of the deepest one, and the commands of the chain
+// lead the command of the deepest process, whose other items are shown.
+//
+// Parameters:
+//   - head: The accumulated prefix string from parent levels
+//   - pidIndex: Index of the first process of the chain in the Nodes array
+//
+// Returns:
+//   - string: The tree prefix followed by a space, or the whole line for the orphans and container nodes
+//   - map[string]string: The formatted items of the line by name, see joinLineItems, nil for the orphans and container nodes
+func (processTree *ProcessTree) buildChainItems(head string, pidIndex int) (string, map[string]string) {
+	var (
+		builder   strings.Builder
+		connector string
+		current   int
+	)
+
+	last, length := processTree.chainEnd(pidIndex)
+	if length == 0 {
+		return processTree.buildLineItems(head, pidIndex)
+	}
+
+	_, lineItemMap := processTree.buildLineItems(head, last)
+
+	linePrefix := processTree.buildLinePrefix(head, pidIndex)
+	processTree.colorizeField("prefix", &linePrefix, pidIndex)
+
+	connector = processTree.chainConnector()
+	processTree.colorizeField("connector", &connector, last)
+
+	for current = pidIndex; current != last; current = processTree.Nodes[current].Child {
+		builder.WriteString(processTree.chainCommand(current))
+		builder.WriteString(connector)
+	}
+	builder.WriteString(lineItemMap["command"])
+	lineItemMap["command"] = builder.String()
+
+	return linePrefix + " ", lineItemMap
+}
+
+// chainConnector returns the horizontal connector separating the commands of a collapsed chain,
+// drawn with the characters of the tree style, e.g., ─── or ---.
+//
+// Returns:
+//   - string: The connector
+func (processTree *ProcessTree) chainConnector() string {
+	return processTree.TreeChars.SG + processTree.TreeChars.S2 + processTree.TreeChars.NPGL + processTree.TreeChars.EG
+}
+
+// chainCommand returns the command of a process collapsed into the line of a chain, formatted,
+// marked, and colored like the command of the line of the process would be.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - string: The command of the process
+func (processTree *ProcessTree) chainCommand(pidIndex int) string {
+	command := FormatCommand(processTree.commandName(processTree.Nodes[pidIndex]), processTree.DisplayOptions.CommandFormat)
+	if processTree.Nodes[pidIndex].IsCurrentOrAncestor {
+		processTree.highlightField(&command)
+	}
+
+	processTree.flagZombie(&command, pidIndex)
+	processTree.flagDeleted(&command, pidIndex)
+	processTree.flagDiff(&command, pidIndex)
+	processTree.highlightMatch(&command, pidIndex)
+	processTree.colorizeField("command", &command, pidIndex)
+	return command
+}
@@ -0,0 +1,55 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+// renderChains renders a canned tree with --collapse-chains and the given options
+func renderChains(t *testing.T, tree []fixtures.Process, displayOptions DisplayOptions) string {
+	displayOptions.CollapseChains = true
+	return renderFixtureTree(t, tree, displayOptions)
+}
+
+func TestCollapseChains(t *testing.T) {
+	// The runs of single children are printed on one line, and their children continue below the deepest process
+	assert.Equal(t, "-+- init \n |-+- sshd---sshd---sshd---bash \n | |--- vim \n | \\--- make---cc1 \n \\--- cron \n", renderChains(t, fixtures.SSHSession(), DisplayOptions{}))
+
+	// The connector is the horizontal line of the tree style, and the identity is the one of the deepest process
+	assert.Equal(t, "─┬─ (1) init \n ├─┬─ (1002) sshd───sshd───sshd───bash \n │ ├─── (1003) vim \n │ └─── (1005) make───cc1 \n └─── (200) cron \n", renderChains(t, fixtures.SSHSession(), DisplayOptions{ShowPIDs: true, UTF8Graphics: true}))
+
+	// A change of owner shown with --show-owner breaks the chain
+	assert.Equal(t, "-+- root init \n |-+- root sshd---sshd \n | \\-+- alice sshd---bash \n |   |--- alice vim \n |   \\--- alice make---cc1 \n \\--- root cron \n", renderChains(t, fixtures.SSHSession(), DisplayOptions{ShowOwner: true}))
+
+	// The chain stops at the maximum depth, which still counts each process
+	assert.Equal(t, "-+- init---bash---bash---bash \n", renderChains(t, fixtures.DeepChain(6), DisplayOptions{MaxDepth: 3}))
+}
+
+func TestChainEnd(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{MaxDepth: 10})
+	sshd := processTree.PidToIndexMap[100]
+
+	// Nothing is collapsed without --collapse-chains
+	last, length := processTree.chainEnd(sshd)
+	assert.Equal(t, sshd, last)
+	assert.Equal(t, 0, length)
+
+	processTree.DisplayOptions.CollapseChains = true
+	last, length = processTree.chainEnd(sshd)
+	assert.Equal(t, processTree.PidToIndexMap[1002], last)
+	assert.Equal(t, 3, length)
+
+	// A user transition shown with --user-transitions breaks the chain
+	processTree.Nodes[processTree.PidToIndexMap[1001]].HasUIDTransition = true
+	processTree.DisplayOptions.ShowUserTransitions = true
+	last, _ = processTree.chainEnd(sshd)
+	assert.Equal(t, processTree.PidToIndexMap[1000], last)
+
+	// So do the markers of the processes of the chain
+	processTree.DisplayOptions.ShowUserTransitions = false
+	processTree.Nodes[processTree.PidToIndexMap[1001]].IsPrivileged = true
+	last, _ = processTree.chainEnd(sshd)
+	assert.Equal(t, processTree.PidToIndexMap[1001], last)
+}
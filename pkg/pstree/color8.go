@@ -9,6 +9,10 @@ func color8(cm ColorMap, text *string) {
 	*text = fmt.Sprintf("%s%s%s", cm.Ansi, *text, AnsiReset)
 }
 
+// ColorDefault leaves the text in the default color of the terminal, as the tree branches are by
+// default.
+func ColorDefault(cs ColorScheme, text *string) {}
+
 func Color8Black(cs ColorScheme, text *string) {
 	color8(cs.Black, text)
 }
@@ -6,9 +6,12 @@
 package pstree
 
 import (
+	"cmp"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
 //------------------------------------------------------------------------------
@@ -40,16 +43,15 @@ var skipProcesses map[int]bool
 //   - processGroups: Map to store process groups
 func (processTree *ProcessTree) InitCompactMode() {
 	var (
-		cmd          string
-		compositeKey string
-		exists       bool
-		group        ProcessGroup
-		parentPID    int32
-		processOwner string
+		cmd    string
+		exists bool
+		group  ProcessGroup
+		key    ProcessGroupKey
 	)
+	defer addSince(&processTree.Timings.Compact, time.Now())
 
-	// Initialize the maps
-	// processGroups = make(map[int32]map[string]map[string]ProcessGroup)
+	// Initialize the maps, PrintTree calls this once per root so the groups must not accumulate
+	processTree.ProcessGroups = make(map[ProcessGroupKey]ProcessGroup)
 	skipProcesses = make(map[int]bool)
 
 	// Group processes with identical commands under the same parent
@@ -59,34 +61,28 @@ func (processTree *ProcessTree) InitCompactMode() {
 			continue
 		}
 
-		// Get parent PID
-		parentPID = processTree.Nodes[pidIndex].PPID
-
-		// Get the process owner
-		processOwner = processTree.Nodes[pidIndex].Username
-		compositeKey = processTree.Nodes[pidIndex].Signature
-
-		// Initialize map for this parent if needed
-		if _, exists = processTree.ProcessGroups[parentPID]; !exists {
-			processTree.ProcessGroups[parentPID] = make(map[string]map[string]ProcessGroup)
-		}
-
-		if _, exists = processTree.ProcessGroups[parentPID][compositeKey]; !exists {
-			processTree.ProcessGroups[parentPID][compositeKey] = make(map[string]ProcessGroup)
+		// With --top, only the selected processes are grouped, so a group is never represented by a hidden process
+		if processTree.DisplayOptions.Top > 0 && !processTree.Nodes[pidIndex].Print {
+			continue
 		}
 
-		// Use the composite key for grouping
-		// This ensures that processes are only grouped if both signature matches exactly
-		group, exists = processTree.ProcessGroups[parentPID][compositeKey][processOwner]
+		// Processes are only grouped if their parent, signature, owner, and match state are the same
+		key = processGroupKey(processTree.Nodes[pidIndex])
+		group, exists = processTree.ProcessGroups[key]
 
 		if !exists {
 			// Create a new group
 			group = ProcessGroup{
+				Age:        -1,
 				Count:      1,
+				CreateTime: -1,
+				CPUPercent: -1,
+				CPUTime:    -1,
 				FirstIndex: pidIndex,
 				FullPath:   cmd,
 				Indices:    []int{pidIndex},
-				Owner:      processOwner,
+				NumFDs:     -1,
+				Owner:      key.Owner,
 			}
 		} else {
 			// Add to existing group
@@ -100,19 +96,100 @@ func (processTree *ProcessTree) InitCompactMode() {
 		if processTree.DisplayOptions.ShowProcessAge {
 			group.Age = max(group.Age, processTree.Nodes[pidIndex].Age)
 		}
-		if processTree.DisplayOptions.ShowCpuPercent {
-			group.CPUPercent += processTree.Nodes[pidIndex].CPUPercent
+		if processTree.DisplayOptions.ShowStartTime && processTree.Nodes[pidIndex].CreateTime > 0 && (group.CreateTime < 0 || processTree.Nodes[pidIndex].CreateTime < group.CreateTime) {
+			group.CreateTime = processTree.Nodes[pidIndex].CreateTime
+		}
+		// Members whose usage was not collected are left out of the sums rather than counted as 0
+		if processTree.DisplayOptions.ShowCpuPercent && processTree.Nodes[pidIndex].HasCPU {
+			group.CPUPercent = max(group.CPUPercent, 0) + processTree.cpuPercentOf(processTree.Nodes[pidIndex])
 		}
-		if processTree.DisplayOptions.ShowMemoryUsage {
-			group.MemoryUsage += processTree.Nodes[pidIndex].MemoryInfo.RSS
+		if processTree.DisplayOptions.ShowCpuTime && CPUTime(processTree.Nodes[pidIndex]) >= 0 {
+			group.CPUTime = max(group.CPUTime, 0) + CPUTime(processTree.Nodes[pidIndex])
+		}
+		if processTree.DisplayOptions.ShowMemoryUsage && processTree.Nodes[pidIndex].HasMemory {
+			group.HasMemory = true
+			group.MemoryUsage += processTree.memoryValue(processTree.Nodes[pidIndex])
+			if percent := processTree.memoryPercent(processTree.Nodes[pidIndex]); percent >= 0 && group.MemoryPercent >= 0 {
+				group.MemoryPercent += percent
+			} else {
+				group.MemoryPercent = -1
+			}
+		}
+		if processTree.DisplayOptions.ShowCumulative {
+			// Each member's cumulative value already covers its own subtree, and the
+			// subtrees of grouped members are disjoint, so nothing is counted twice
+			group.CumulativeCPU += processTree.Nodes[pidIndex].CumulativeCPU
+			group.CumulativeRSS += processTree.Nodes[pidIndex].CumulativeRSS
+		}
+		if processTree.DisplayOptions.ShowDiff {
+			// The members share their diff state, see computeSignature
+			group.DiffCPUPercent += processTree.Nodes[pidIndex].DiffCPUPercent
+			group.DiffRSS += processTree.Nodes[pidIndex].DiffRSS
 		}
 		if processTree.DisplayOptions.ShowNumThreads {
 			group.NumThreads += processTree.Nodes[pidIndex].NumThreads
 		}
+		if processTree.DisplayOptions.ShowNice {
+			group.Nice = addNice(group.Nice, processTree.Nodes[pidIndex].Nice)
+		}
+		if processTree.DisplayOptions.ShowNumFDs && processTree.Nodes[pidIndex].NumFDs >= 0 {
+			group.NumFDs = max(group.NumFDs, 0) + processTree.Nodes[pidIndex].NumFDs
+		}
+		if processTree.DisplayOptions.ShowIO {
+			group.IOCounters = addIOCounters(group.IOCounters, processTree.Nodes[pidIndex].IOCounters)
+		}
+		if processTree.DisplayOptions.PageFaults != "" {
+			group.PageFaults = addPageFaults(group.PageFaults, processTree.Nodes[pidIndex].PageFaults)
+		}
+		if processTree.DisplayOptions.ShowSched && processTree.Nodes[pidIndex].SchedPolicy != "" {
+			if !slices.Contains(group.SchedPolicies, processTree.Nodes[pidIndex].SchedPolicy) {
+				group.SchedPolicies = append(group.SchedPolicies, processTree.Nodes[pidIndex].SchedPolicy)
+				slices.Sort(group.SchedPolicies)
+			}
+		}
+		if processTree.DisplayOptions.ShowStatus {
+			state := StatusLetter(processTree.Nodes[pidIndex].Status)
+			if !slices.Contains(group.States, state) {
+				group.States = append(group.States, state)
+				slices.Sort(group.States)
+			}
+		}
 
 		// Update the group in the map
-		processTree.ProcessGroups[parentPID][compositeKey][processOwner] = group
+		processTree.ProcessGroups[key] = group
 	}
+
+	processTree.orderProcessGroups()
+}
+
+// orderProcessGroups makes the process groups independent of the order of the nodes.
+//
+// The members of each group are ordered by PID, so the PIDs shown with --show-pids are in
+// ascending order, and the member with the lowest PID represents the group instead of the one
+// encountered first, which changes with the sorting options.
+func (processTree *ProcessTree) orderProcessGroups() {
+	for key, group := range processTree.ProcessGroups {
+		slices.SortFunc(group.Indices, func(i, j int) int {
+			return cmp.Compare(processTree.Nodes[i].PID, processTree.Nodes[j].PID)
+		})
+		if group.Indices[0] != group.FirstIndex {
+			skipProcesses[group.FirstIndex] = true
+			group.FirstIndex = group.Indices[0]
+			delete(skipProcesses, group.FirstIndex)
+		}
+		processTree.ProcessGroups[key] = group
+	}
+}
+
+// processGroupKey returns the key of the group a process belongs to in compact mode.
+//
+// Parameters:
+//   - node: The process, whose signature was computed with computeSignatures
+//
+// Returns:
+//   - ProcessGroupKey: The parent PID, subtree signature, owner, and match state of the process
+func processGroupKey(node *Process) ProcessGroupKey {
+	return ProcessGroupKey{Match: node.PrintReason == ReasonMatch, PPID: node.PPID, Signature: node.Signature, Owner: node.Username}
 }
 
 //------------------------------------------------------------------------------
@@ -152,32 +229,19 @@ func ShouldSkipProcess(processIndex int) bool {
 //   - count: Number of identical processes in the group
 //   - isThread: Whether the process group represents threads
 //   - age: Oldest process age of the group
-//   - cpuPercent: Summed CPU percent of the group
+//   - cpuPercent: Summed CPU percent of the group, -1 if none of the members was collected
 //   - memoryUsage: Summed RSS memory usage of the group
 //   - numThreads: Summed thread count of the group
 func (processTree *ProcessTree) GetProcessCount(pidIndex int) (int, []int32, int64, float64, uint64, int32) {
-	var (
-		groupPIDs    []int32
-		compositeKey string
-		parentPID    int32
-		processOwner string
-	)
+	var groupPIDs []int32
 
-	// Get parent PID and command
-	parentPID = processTree.Nodes[pidIndex].PPID
-	processOwner = processTree.Nodes[pidIndex].Username
-	compositeKey = processTree.Nodes[pidIndex].Signature
-
-	// Check if we have a group for this process
-	if groups, exists := processTree.ProcessGroups[parentPID]; exists {
-		// Look up by owner and composite key (command + args)
-		if group, exists := groups[compositeKey][processOwner]; exists && group.FirstIndex == pidIndex {
-			// Find PIDs for each member of the group
-			for i := range group.Indices {
-				groupPIDs = append(groupPIDs, processTree.Nodes[group.Indices[i]].PID)
-			}
-			return group.Count, groupPIDs, group.Age, group.CPUPercent, group.MemoryUsage, group.NumThreads
+	// Check if this process represents a group
+	if group, exists := processTree.ProcessGroups[processGroupKey(processTree.Nodes[pidIndex])]; exists && group.FirstIndex == pidIndex {
+		// Find PIDs for each member of the group
+		for i := range group.Indices {
+			groupPIDs = append(groupPIDs, processTree.Nodes[group.Indices[i]].PID)
 		}
+		return group.Count, groupPIDs, group.Age, group.CPUPercent, group.MemoryUsage, group.NumThreads
 	}
 
 	// No group or not the first process in the group
@@ -188,27 +252,54 @@ func (processTree *ProcessTree) GetProcessCount(pidIndex int) (int, []int32, int
 // OUTPUT FORMATTING
 //------------------------------------------------------------------------------
 
+// FormatCommand formats a command name for display according to --command-format.
+//
+// With the default basename format, a command given as a path, e.g., "/usr/bin/bash" or
+// "./server", is shortened to its last element like Linux pstree does. Names in brackets or
+// braces such as "[kthreadd]", the "[PID n]" fallback, and thread names like "{java}" are never
+// altered, and neither are names like "kworker/0:1" that contain a slash without being a path.
+//
+// Parameters:
+//   - command: The command name, as collected
+//   - commandFormat: "basename" or "full"; an empty string means "basename"
+//
+// Returns:
+//   - string: The command name to display
+func FormatCommand(command string, commandFormat string) string {
+	if commandFormat == "full" || strings.HasPrefix(command, "[") || strings.HasPrefix(command, "{") {
+		return command
+	}
+	if filepath.IsAbs(command) || strings.HasPrefix(command, "./") || strings.HasPrefix(command, "../") {
+		return filepath.Base(command)
+	}
+	return command
+}
+
 // FormatCompactOutput formats the command with count for compact mode.
 //
 // This function creates a formatted string representation of a process group
 // in the style of Linux pstree. For regular processes, the format is "N*[command]",
 // and for threads, the format is "N*[{command}]", where N is the count.
+// The command is formatted with FormatCommand.
 //
 // Parameters:
 //   - command: The command name to format
 //   - count: Number of identical processes/threads
+//   - groupPIDs: PIDs of the processes in the group, shown when showPIDs is set
+//   - showPIDs: Whether to append the PIDs of the group
+//   - commandFormat: "basename" or "full", see FormatCommand
 //
 // Returns:
 //   - Formatted string for display, or empty string if threads should be hidden
-func FormatCompactOutput(command string, count int, groupPIDs []int32, showPIDs bool) string {
+func FormatCompactOutput(command string, count int, groupPIDs []int32, showPIDs bool, commandFormat string) string {
 	if count <= 1 {
 		return command
 	}
 
 	if showPIDs {
-		return fmt.Sprintf("%d*[%s] (%s)", count, filepath.Base(command), strings.Join(PIDsToString(groupPIDs), ","))
+		return fmt.Sprintf("%d*[%s] (%s)", count, FormatCommand(command, commandFormat), strings.Join(PIDsToString(groupPIDs), ","))
 	} else {
-		return fmt.Sprintf("%d*[%s]", count, filepath.Base(command))
+		return fmt.Sprintf("%d*[%s]", count, FormatCommand(command, commandFormat))
 	}
 }
 
@@ -3,7 +3,10 @@ package pstree
 import (
 	"testing"
 
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/shirou/gopsutil/v4/process"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInitCompactMode(t *testing.T) {
@@ -40,50 +43,147 @@ func TestInitCompactMode(t *testing.T) {
 }
 
 func TestShouldSkipProcess(t *testing.T) {
-	// Create test processes
-	proc1 := Process{PID: 1, PPID: 0, Command: "init"}
-	proc2 := Process{PID: 100, PPID: 1, Command: "bash"}
-	proc3 := Process{PID: 200, PPID: 1, Command: "bash"}
-
-	processes := []Process{proc1, proc2, proc3}
-	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{})
+	// Four identical nginx workers and a cache manager under the same master, told apart by their arguments
+	processTree := newFixtureTree(t, fixtures.IdenticalSiblings(), DisplayOptions{ShowArguments: true})
 
 	// Initialize compact mode
 	processTree.InitCompactMode()
 
 	// Verify that ShouldSkipProcess returns the correct values
-	assert.False(t, ShouldSkipProcess(0))
-	assert.False(t, ShouldSkipProcess(1))
-	assert.True(t, ShouldSkipProcess(2))
+	assert.False(t, ShouldSkipProcess(processTree.PidToIndexMap[1]))
+	assert.False(t, ShouldSkipProcess(processTree.PidToIndexMap[100]))
+	assert.False(t, ShouldSkipProcess(processTree.PidToIndexMap[101])) // First worker of the group
+	for _, pid := range []int32{102, 103, 104} {
+		assert.True(t, ShouldSkipProcess(processTree.PidToIndexMap[pid]), "PID %d should be skipped", pid)
+	}
+	assert.False(t, ShouldSkipProcess(processTree.PidToIndexMap[105])) // The cache manager has other arguments
+	assert.False(t, ShouldSkipProcess(processTree.PidToIndexMap[200]))
 
 	// Test with an index that doesn't exist in the skipProcesses map
 	assert.False(t, ShouldSkipProcess(999))
 }
 
-func TestGetProcessCount(t *testing.T) {
-	// Create test processes with identical commands
-	proc1 := Process{PID: 1, PPID: 0, Command: "init"}
-	proc2 := Process{PID: 100, PPID: 1, Command: "bash"}
-	proc3 := Process{PID: 200, PPID: 1, Command: "bash"}
-	proc4 := Process{PID: 300, PPID: 1, Command: "bash"}
+func TestInitCompactModeStates(t *testing.T) {
+	// Create a group of identical processes in different states
+	proc1 := Process{PID: 1, PPID: 0, Command: "init", Status: []string{"sleep"}}
+	proc2 := Process{PID: 100, PPID: 1, Command: "nginx", Status: []string{"sleep"}}
+	proc3 := Process{PID: 200, PPID: 1, Command: "nginx", Status: []string{"running"}}
+	proc4 := Process{PID: 300, PPID: 1, Command: "nginx", Status: []string{"sleep"}}
 
 	processes := []Process{proc1, proc2, proc3, proc4}
-	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{})
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{ShowStatus: true})
+
+	// Initialize compact mode
+	processTree.InitCompactMode()
+
+	// The group lists each state present once
+	group, ok := processTree.getProcessGroup(1)
+	assert.True(t, ok)
+	assert.Equal(t, 3, group.Count)
+	assert.Equal(t, []string{"R", "S"}, group.States)
+}
+
+func TestInitCompactModeCumulative(t *testing.T) {
+	// Create a group of identical processes, each with a child of its own
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 0.5},
+		{PID: 100, PPID: 1, Command: "nginx", HasCPU: true, CPUPercent: 1.0},
+		{PID: 101, PPID: 100, Command: "worker", HasCPU: true, CPUPercent: 2.0},
+		{PID: 200, PPID: 1, Command: "nginx", HasCPU: true, CPUPercent: 1.0},
+		{PID: 201, PPID: 200, Command: "worker", HasCPU: true, CPUPercent: 4.0},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{ShowCpuPercent: true, ShowCumulative: true})
+
+	// Initialize compact mode
+	processTree.InitCompactMode()
+
+	// The group sums the subtrees of its members, each counted once
+	group, ok := processTree.getProcessGroup(processTree.PidToIndexMap[100])
+	assert.True(t, ok)
+	assert.Equal(t, 2, group.Count)
+	assert.Equal(t, 2.0, group.CPUPercent)
+	assert.Equal(t, 8.0, group.CumulativeCPU)
+
+	// The parent of the group is not affected by the grouping
+	assert.Equal(t, 8.5, processTree.Nodes[0].CumulativeCPU)
+}
+
+func TestInitCompactModeNumFDs(t *testing.T) {
+	// Create a group of identical processes, one of which can't be read
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", NumFDs: 40},
+		{PID: 100, PPID: 1, Command: "nginx", NumFDs: 12},
+		{PID: 200, PPID: 1, Command: "nginx", NumFDs: -1},
+		{PID: 300, PPID: 1, Command: "nginx", NumFDs: 8},
+		{PID: 400, PPID: 1, Command: "sshd", NumFDs: -1},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{ShowNumFDs: true})
+
+	// Initialize compact mode
+	processTree.InitCompactMode()
+
+	// Only the readable counts are summed
+	group, ok := processTree.getProcessGroup(processTree.PidToIndexMap[100])
+	assert.True(t, ok)
+	assert.Equal(t, int32(20), group.NumFDs)
+
+	// A group without any readable count stays unknown
+	group, ok = processTree.getProcessGroup(processTree.PidToIndexMap[400])
+	assert.True(t, ok)
+	assert.Equal(t, int32(-1), group.NumFDs)
+	assert.Equal(t, "(fds: -)", formatNumFDs(group.NumFDs))
+}
+
+func TestInitCompactModeUncollected(t *testing.T) {
+	// Create a group of identical processes, one of which has no usage collected
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "nginx", HasCPU: true, CPUPercent: 1.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1024}},
+		{PID: 200, PPID: 1, Command: "nginx", CPUPercent: -1, MemoryInfo: &process.MemoryInfoStat{}},
+		{PID: 300, PPID: 1, Command: "nginx", HasCPU: true, CPUPercent: 2, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 2048}},
+		{PID: 400, PPID: 1, Command: "sshd"},
+		{PID: 500, PPID: 1, Command: "sshd"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{ShowCpuPercent: true, ShowMemoryUsage: true})
+
+	// Initialize compact mode
+	processTree.InitCompactMode()
+
+	// Only the collected usage is summed
+	group, ok := processTree.getProcessGroup(processTree.PidToIndexMap[100])
+	require.True(t, ok)
+	assert.Equal(t, 3.5, group.CPUPercent)
+	assert.True(t, group.HasMemory)
+	assert.Equal(t, uint64(3072), group.MemoryUsage)
+
+	// A group without any collected usage stays unknown
+	group, ok = processTree.getProcessGroup(processTree.PidToIndexMap[400])
+	require.True(t, ok)
+	assert.Equal(t, -1.0, group.CPUPercent)
+	assert.False(t, group.HasMemory)
+	assert.Equal(t, "(c:-)", processTree.formatCPUPercent(group.CPUPercent, 0))
+	assert.Equal(t, "(m:-)", processTree.formatMemory(group.MemoryUsage, group.HasMemory, group.MemoryPercent, 0))
+}
+
+func TestGetProcessCount(t *testing.T) {
+	// Four identical nginx workers and a cache manager under the same master, told apart by their arguments
+	processTree := newFixtureTree(t, fixtures.IdenticalSiblings(), DisplayOptions{ShowArguments: true})
 
 	// Initialize compact mode
 	processTree.InitCompactMode()
 
 	// Test GetProcessCount for the first process in a group
-	count, _, _, _, _, _ := processTree.GetProcessCount(1)
-	assert.Equal(t, 3, count) // proc2 has two duplicates (proc3 and proc4)
+	count, _, _, _, _, _ := processTree.GetProcessCount(processTree.PidToIndexMap[101])
+	assert.Equal(t, 4, count) // The first worker has three duplicates
 	// assert.False(t, isThread) // proc2 is not a thread
 
 	// Test GetProcessCount for a process that should be skipped
-	count, _, _, _, _, _ = processTree.GetProcessCount(2)
+	count, _, _, _, _, _ = processTree.GetProcessCount(processTree.PidToIndexMap[102])
 	assert.Equal(t, 1, count) // Not the first in group, so count is 1
 	// assert.False(t, isThread)
 
 	// Test with a process that has threads
+	proc1 := Process{PID: 1, PPID: 0, Command: "init"}
 	proc5 := Process{PID: 500, PPID: 1, Command: "chrome", NumThreads: 5}
 	proc6 := Process{PID: 600, PPID: 1, Command: "chrome", NumThreads: 5}
 
@@ -101,22 +201,99 @@ func TestGetProcessCount(t *testing.T) {
 
 func TestFormatCompactOutput(t *testing.T) {
 	// Test formatting for regular processes
-	output := FormatCompactOutput("bash", 3, []int32{}, false)
+	output := FormatCompactOutput("bash", 3, []int32{}, false, "")
 	assert.Equal(t, "3*[bash]", output)
 
 	// Test formatting for a single process (no compaction)
-	output = FormatCompactOutput("bash", 1, []int32{}, false)
+	output = FormatCompactOutput("bash", 1, []int32{}, false, "")
 	assert.Equal(t, "bash", output)
 
 	// Test formatting for threads
-	output = FormatCompactOutput("chrome", 4, []int32{}, false)
+	output = FormatCompactOutput("chrome", 4, []int32{}, false, "")
 	assert.Equal(t, "4*[{chrome}]", output)
 
 	// Test formatting for threads when threads are hidden
-	output = FormatCompactOutput("chrome", 4, []int32{}, false)
+	output = FormatCompactOutput("chrome", 4, []int32{}, false, "")
 	assert.Equal(t, "", output)
 
 	// Test formatting for processes with full paths
-	output = FormatCompactOutput("/usr/bin/bash", 2, []int32{}, false)
+	output = FormatCompactOutput("/usr/bin/bash", 2, []int32{}, false, "")
 	assert.Equal(t, "2*[bash]", output)
+
+	// Test formatting for processes with full paths when the full path is requested
+	output = FormatCompactOutput("/usr/bin/bash", 2, []int32{}, false, "full")
+	assert.Equal(t, "2*[/usr/bin/bash]", output)
+}
+
+func TestFormatCommand(t *testing.T) {
+	// Paths are shortened to their basename by default
+	assert.Equal(t, "bash", FormatCommand("/usr/bin/bash", ""))
+	assert.Equal(t, "bash", FormatCommand("/usr/bin/bash", "basename"))
+	assert.Equal(t, "server", FormatCommand("./bin/server", "basename"))
+	assert.Equal(t, "/usr/bin/bash", FormatCommand("/usr/bin/bash", "full"))
+
+	// Names that are not paths are never altered
+	assert.Equal(t, "bash", FormatCommand("bash", "basename"))
+	assert.Equal(t, "[kthreadd]", FormatCommand("[kthreadd]", "basename"))
+	assert.Equal(t, "[PID 42]", FormatCommand("[PID 42]", "basename"))
+	assert.Equal(t, "{worker}", FormatCommand("{worker}", "basename"))
+	assert.Equal(t, "kworker/0:1H-events", FormatCommand("kworker/0:1H-events", "basename"))
+	assert.Equal(t, "[kworker/u8:2]", FormatCommand("[kworker/u8:2]", "basename"))
+}
+
+func TestInitCompactModeSortedPIDs(t *testing.T) {
+	// Create a group of identical processes that are not in PID order
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 300, PPID: 1, Command: "worker"},
+		{PID: 100, PPID: 1, Command: "worker"},
+		{PID: 200, PPID: 1, Command: "worker"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{CompactMode: true, MaxDepth: 10, ScreenWidth: 80, ShowPIDs: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	// The member with the lowest PID represents the group
+	processTree.InitCompactMode()
+	group, ok := processTree.getProcessGroup(processTree.PidToIndexMap[300])
+	assert.True(t, ok)
+	assert.Equal(t, processTree.PidToIndexMap[100], group.FirstIndex)
+	assert.False(t, ShouldSkipProcess(processTree.PidToIndexMap[100]))
+	assert.True(t, ShouldSkipProcess(processTree.PidToIndexMap[300]))
+
+	// The PIDs are listed in ascending order
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	assert.Contains(t, output, "3*[worker] (100,200,300)")
+}
+
+func TestInitCompactModeMatches(t *testing.T) {
+	// Identical processes, one shown for context and two matching the filters
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "worker"},
+		{PID: 200, PPID: 1, Command: "worker"},
+		{PID: 300, PPID: 1, Command: "worker"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{})
+	for _, pid := range []int32{1, 100, 200, 300} {
+		processTree.Nodes[processTree.PidToIndexMap[pid]].Print = true
+	}
+	processTree.Nodes[processTree.PidToIndexMap[100]].PrintReason = ReasonAncestor
+	processTree.Nodes[processTree.PidToIndexMap[200]].PrintReason = ReasonMatch
+	processTree.Nodes[processTree.PidToIndexMap[300]].PrintReason = ReasonMatch
+
+	processTree.InitCompactMode()
+
+	// The matches are only grouped with each other
+	group, ok := processTree.getProcessGroup(processTree.PidToIndexMap[200])
+	require.True(t, ok)
+	assert.Equal(t, 2, group.Count)
+	assert.Equal(t, processTree.PidToIndexMap[200], group.FirstIndex)
+
+	group, ok = processTree.getProcessGroup(processTree.PidToIndexMap[100])
+	require.True(t, ok)
+	assert.Equal(t, 1, group.Count)
+	assert.False(t, ShouldSkipProcess(processTree.PidToIndexMap[100]))
+	assert.True(t, ShouldSkipProcess(processTree.PidToIndexMap[300]))
 }
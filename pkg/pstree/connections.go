@@ -0,0 +1,125 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the --connections enrichment pass. Reading the network connections
+// of a process is expensive, so instead of gathering them for every process during the
+// initial snapshot they are only collected for the processes that are still displayed
+// after MarkProcesses and DropUnmarked have filtered the tree.
+package pstree
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// CollectConnections gathers the network connections of every process marked for display.
+// It should be called after DropUnmarked. Processes whose connections cannot be read, e.g.,
+// because they belong to another user, are flagged with ConnectionsUnavailable so they are
+// displayed with "?" instead of failing the whole tree.
+func (processTree *ProcessTree) CollectConnections() {
+	var (
+		connections []net.ConnectionStat
+		err         error
+		node        *Process
+		proc        *process.Process
+	)
+
+	if !processTree.DisplayOptions.ShowConnections {
+		return
+	}
+
+	processTree.Logger.Debug("Entering processTree.CollectConnections()")
+	for _, node = range processTree.Nodes {
+		// Threads share the connections of their process
+		if !node.Print || node.IsThread {
+			continue
+		}
+
+		proc, err = process.NewProcess(node.PID)
+		if err == nil {
+			connections, err = ProcessConnections(proc)
+		}
+		if err != nil {
+			processTree.Logger.Debug(fmt.Sprintf("Unable to read the connections of PID %d: %v", node.PID, err))
+			node.Connections = []net.ConnectionStat{}
+			node.ConnectionsUnavailable = true
+			continue
+		}
+		node.Connections = connections
+	}
+}
+
+// FormatConnections summarizes network connections for display, e.g., "tcp: 3 est, 1 listen :8080".
+//
+// TCP connections are counted by state, with the local ports of listening sockets listed
+// after the listen count. Connections in any other TCP state are counted together as "other".
+// UDP sockets are only counted. Unix domain sockets are ignored.
+//
+// Parameters:
+//   - connections: Slice of network connections of a process
+//
+// Returns:
+//   - string: The summary, or an empty string if the process has no TCP or UDP connections
+func FormatConnections(connections []net.ConnectionStat) string {
+	var (
+		connection  net.ConnectionStat
+		established int
+		listen      int
+		listenPorts []uint32
+		other       int
+		port        uint32
+		ports       []string
+		summary     []string
+		tcpCounts   []string
+		udp         int
+	)
+
+	for _, connection = range connections {
+		if connection.Family == syscall.AF_UNIX {
+			continue
+		}
+		switch connection.Type {
+		case syscall.SOCK_STREAM:
+			switch connection.Status {
+			case "ESTABLISHED":
+				established++
+			case "LISTEN":
+				listen++
+				if !slices.Contains(listenPorts, connection.Laddr.Port) {
+					listenPorts = append(listenPorts, connection.Laddr.Port)
+				}
+			default:
+				other++
+			}
+		case syscall.SOCK_DGRAM:
+			udp++
+		}
+	}
+
+	if established > 0 {
+		tcpCounts = append(tcpCounts, fmt.Sprintf("%d est", established))
+	}
+	if listen > 0 {
+		slices.Sort(listenPorts)
+		for _, port = range listenPorts {
+			ports = append(ports, fmt.Sprintf(":%d", port))
+		}
+		tcpCounts = append(tcpCounts, fmt.Sprintf("%d listen %s", listen, strings.Join(ports, ",")))
+	}
+	if other > 0 {
+		tcpCounts = append(tcpCounts, fmt.Sprintf("%d other", other))
+	}
+
+	if len(tcpCounts) > 0 {
+		summary = append(summary, "tcp: "+strings.Join(tcpCounts, ", "))
+	}
+	if udp > 0 {
+		summary = append(summary, fmt.Sprintf("udp: %d", udp))
+	}
+
+	return strings.Join(summary, "; ")
+}
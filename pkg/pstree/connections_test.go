@@ -0,0 +1,53 @@
+package pstree
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatConnections(t *testing.T) {
+	tcp := func(status string, port uint32) net.ConnectionStat {
+		return net.ConnectionStat{Family: syscall.AF_INET, Type: syscall.SOCK_STREAM, Status: status, Laddr: net.Addr{Port: port}}
+	}
+
+	connections := []net.ConnectionStat{
+		tcp("ESTABLISHED", 40000),
+		tcp("ESTABLISHED", 40001),
+		tcp("ESTABLISHED", 40002),
+		tcp("LISTEN", 8080),
+		tcp("LISTEN", 443),
+		tcp("LISTEN", 8080), // IPv4 and IPv6 listeners on the same port
+		tcp("TIME_WAIT", 40003),
+		{Family: syscall.AF_INET, Type: syscall.SOCK_DGRAM},
+		{Family: syscall.AF_UNIX, Type: syscall.SOCK_STREAM},
+	}
+
+	assert.Equal(t, "tcp: 3 est, 3 listen :443,:8080, 1 other; udp: 1", FormatConnections(connections))
+	assert.Equal(t, "tcp: 1 est", FormatConnections(connections[:1]))
+
+	// Unix domain sockets alone don't produce a summary
+	assert.Equal(t, "", FormatConnections(connections[8:]))
+	assert.Equal(t, "", FormatConnections([]net.ConnectionStat{}))
+}
+
+func TestCollectConnections(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 999999, PPID: 1, Command: "gone"}, // no longer exists, so its connections can't be read
+		{PID: 1000000, PPID: 1, Command: "hidden"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{Contains: "gone", ShowConnections: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	processTree.CollectConnections()
+
+	assert.True(t, processTree.Nodes[1].ConnectionsUnavailable)
+	assert.Empty(t, processTree.Nodes[1].Connections)
+
+	// Processes that are not displayed are skipped
+	assert.False(t, processTree.Nodes[2].ConnectionsUnavailable)
+	assert.Nil(t, processTree.Nodes[2].Connections)
+}
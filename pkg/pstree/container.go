@@ -0,0 +1,258 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the container awareness (--containers). On Linux, the container a process
+// belongs to is found in /proc/<pid>/cgroup, whose paths end in a directory named after the
+// container by Docker, containerd, CRI-O, and Podman, e.g., docker-<id>.scope with the systemd
+// cgroup driver or /docker/<id> with the cgroupfs driver, in both the cgroup v1 and v2 layouts.
+// The ID is shortened to 12 characters like docker ps does. With --group-by-container, the
+// processes of each container are moved under a synthetic "(container <id>)" node of their own,
+// printed after the host trees, so the workloads stand apart from their runtime shims. Other
+// platforms don't report containers, so no process belongs to one there.
+package pstree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// ContainerIDLength is the number of characters of a container ID shown, like docker ps.
+const ContainerIDLength = 12
+
+// ContainerPIDBase is the PID of the synthetic node of the first container with
+// --group-by-container, the other containers count down from it.
+const ContainerPIDBase int32 = -2
+
+// ErrContainerIDUnavailable is returned when the container of a process can't be read on this platform.
+var ErrContainerIDUnavailable = errors.New("the container of a process is not available on this platform")
+
+// containerIDPattern matches the full ID of a container, 64 hexadecimal characters.
+var containerIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// containerPrefixes are the prefixes container runtimes put in front of the container ID in the
+// name of its cgroup with the systemd cgroup driver.
+var containerPrefixes = []string{"cri-containerd-", "containerd-", "crio-", "docker-", "libpod-"}
+
+// parseContainerID parses the ID of the container of a process from the contents of a Linux
+// /proc/<pid>/cgroup file.
+//
+// Each line holds a hierarchy ID, the controllers, and a cgroup path, e.g.,
+// 0::/system.slice/docker-<id>.scope with cgroup v2, or a line per controller such as
+// 4:memory:/docker/<id> with cgroup v1. The path is searched from its last directory, so the
+// innermost container is found when containers are nested, and a directory names a container
+// when it is a full container ID once the runtime prefix and the .scope suffix are removed.
+//
+// Parameters:
+//   - cgroup: The contents of the file
+//
+// Returns:
+//   - string: The full container ID, or an empty string if the process doesn't run in a container
+func parseContainerID(cgroup string) string {
+	scanner := bufio.NewScanner(strings.NewReader(cgroup))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		dirs := strings.Split(parts[2], "/")
+		for i := len(dirs) - 1; i >= 0; i-- {
+			// containerd names the cgroup <slice>:cri-containerd:<id> with the systemd cgroup driver
+			name := dirs[i][strings.LastIndex(dirs[i], ":")+1:]
+			name = strings.TrimSuffix(name, ".scope")
+			for _, prefix := range containerPrefixes {
+				if trimmed, ok := strings.CutPrefix(name, prefix); ok {
+					name = trimmed
+					break
+				}
+			}
+			if containerIDPattern.MatchString(name) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// readContainerID reads the ID of the container of a process from a proc filesystem.
+//
+// Parameters:
+//   - procPath: Mount point of the proc filesystem
+//   - pid: The process ID
+//
+// Returns:
+//   - string: The full container ID, or an empty string if the process doesn't run in a container
+//   - error: Any error encountered while reading the file
+func readContainerID(procPath string, pid int32) (string, error) {
+	cgroup, err := os.ReadFile(filepath.Join(procPath, strconv.Itoa(int(pid)), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	return parseContainerID(string(cgroup)), nil
+}
+
+// ShortContainerID shortens a container ID to ContainerIDLength characters.
+//
+// Parameters:
+//   - containerID: The full container ID
+//
+// Returns:
+//   - string: The short container ID, e.g., 3f4e5a6b7c8d
+func ShortContainerID(containerID string) string {
+	if len(containerID) > ContainerIDLength {
+		return containerID[:ContainerIDLength]
+	}
+	return containerID
+}
+
+// formatContainerField formats the container field of a line, e.g., (ctr: 3f4e5a6b7c8d).
+//
+// Parameters:
+//   - containerID: The full container ID
+//
+// Returns:
+//   - string: The formatted field
+func formatContainerField(containerID string) string {
+	return fmt.Sprintf("(ctr: %s)", ShortContainerID(containerID))
+}
+
+// rootRank ranks a root of the tree for sorting: the trees of the host first, then the container
+// nodes, then the orphans node.
+//
+// Parameters:
+//   - node: The root process
+//
+// Returns:
+//   - int: 0 for a process, 1 for a container node, 2 for the orphans node
+func rootRank(node *Process) int {
+	switch {
+	case node.PID == OrphansPID:
+		return 2
+	case isSyntheticNode(node):
+		return 1
+	}
+	return 0
+}
+
+// isContainerRoot reports whether a process is the topmost process of its container, i.e., it
+// runs in a container and its parent doesn't run in the same one.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - bool: true if the process is moved under the node of its container by AttachContainers
+func (processTree *ProcessTree) isContainerRoot(pidIndex int) bool {
+	node := processTree.Nodes[pidIndex]
+	if node.ContainerID == "" || node.IsThread || isSyntheticNode(node) {
+		return false
+	}
+	return node.Parent == -1 || processTree.Nodes[node.Parent].ContainerID != node.ContainerID
+}
+
+// AttachContainers moves the processes of each container under a synthetic "(container <id>)" node.
+//
+// The topmost processes of each container are detached from their parent, usually the shim of
+// the container runtime, and attached to the node of their container, which is appended to Nodes
+// and becomes a root of its own. The nodes get the PIDs from ContainerPIDBase down, in the order
+// of the container IDs. The processes keep their PPID, so --show-ppids still shows the real
+// parent. Nothing is added when no process runs in a container. It must be called after
+// AttachOrphans and before the children are sorted.
+func (processTree *ProcessTree) AttachContainers() {
+	var (
+		containerIDs     []string
+		containerIndices []int
+		roots            map[string][]int
+	)
+
+	roots = make(map[string][]int)
+	for pidIndex, node := range processTree.Nodes {
+		if processTree.isContainerRoot(pidIndex) {
+			if _, ok := roots[node.ContainerID]; !ok {
+				containerIDs = append(containerIDs, node.ContainerID)
+			}
+			roots[node.ContainerID] = append(roots[node.ContainerID], pidIndex)
+		}
+	}
+	if len(containerIDs) == 0 {
+		return
+	}
+	slices.Sort(containerIDs)
+
+	for i, containerID := range containerIDs {
+		containerPID := ContainerPIDBase - int32(i)
+		containerIndex := len(processTree.Nodes)
+		processTree.Nodes = append(processTree.Nodes, &Process{
+			Age:         -1,
+			Child:       -1,
+			Command:     fmt.Sprintf("(container %s)", ShortContainerID(containerID)),
+			ContainerID: containerID,
+			MemoryInfo:  &process.MemoryInfoStat{},
+			NumFDs:      -1,
+			Parent:      -1,
+			PID:         containerPID,
+			Sister:      -1,
+		})
+		processTree.PidToIndexMap[containerPID] = containerIndex
+		processTree.IndexToPidMap[containerIndex] = containerPID
+		containerIndices = append(containerIndices, containerIndex)
+
+		lastIndex := -1
+		for _, pidIndex := range roots[containerID] {
+			processTree.detachNode(pidIndex)
+
+			node := processTree.Nodes[pidIndex]
+			node.Parent = containerIndex
+			node.Sister = -1
+			if lastIndex == -1 {
+				processTree.Nodes[containerIndex].Child = pidIndex
+			} else {
+				processTree.Nodes[lastIndex].Sister = pidIndex
+			}
+			processTree.Nodes[containerIndex].Children = append(processTree.Nodes[containerIndex].Children, node)
+			lastIndex = pidIndex
+		}
+	}
+
+	// The runtime shims lost their containers, so their subtrees are no longer the same
+	processTree.computeSignatures()
+	for _, containerIndex := range containerIndices {
+		computeSignature(processTree.Nodes[containerIndex], processTree.DisplayOptions.ShowArguments, processTree.DisplayOptions.ShowZombies)
+	}
+}
+
+// detachNode removes a process from the children of its parent, keeping its own children.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+func (processTree *ProcessTree) detachNode(pidIndex int) {
+	node := processTree.Nodes[pidIndex]
+	if node.Parent == -1 {
+		return
+	}
+
+	parent := processTree.Nodes[node.Parent]
+	if parent.Child == pidIndex {
+		parent.Child = node.Sister
+	} else {
+		for sisterIndex := parent.Child; sisterIndex != -1; sisterIndex = processTree.Nodes[sisterIndex].Sister {
+			if processTree.Nodes[sisterIndex].Sister == pidIndex {
+				processTree.Nodes[sisterIndex].Sister = node.Sister
+				break
+			}
+		}
+	}
+	parent.Children = slices.DeleteFunc(parent.Children, func(child *Process) bool {
+		return child == node
+	})
+	node.Parent = -1
+	node.Sister = -1
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package pstree
+
+// ContainersSupported reports whether the containers of the processes can be read on this platform.
+const ContainersSupported = true
+
+// ProcessContainerID retrieves the ID of the container a process runs in from /proc/<pid>/cgroup.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - string: The full container ID, or an empty string if the process doesn't run in a container
+//   - error: Any error encountered while reading the file
+func ProcessContainerID(pid int32) (string, error) {
+	return readContainerID("/proc", pid)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package pstree
+
+// ContainersSupported reports whether the containers of the processes can be read on this platform.
+const ContainersSupported = false
+
+// ProcessContainerID retrieves the ID of the container a process runs in.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - string: Always an empty string
+//   - error: Always ErrContainerIDUnavailable
+func ProcessContainerID(pid int32) (string, error) {
+	return "", ErrContainerIDUnavailable
+}
@@ -0,0 +1,145 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testContainerA = strings.Repeat("3f4e5a6b", 8)
+	testContainerB = strings.Repeat("a1b2c3d4", 8)
+)
+
+func TestParseContainerID(t *testing.T) {
+	tests := []struct {
+		name   string
+		cgroup string
+		want   string
+	}{
+		{"docker cgroup v1", "12:memory:/docker/" + testContainerA + "\n11:cpu,cpuacct:/docker/" + testContainerA + "\n1:name=systemd:/docker/" + testContainerA + "\n", testContainerA},
+		{"docker cgroup v2", "0::/system.slice/docker-" + testContainerA + ".scope\n", testContainerA},
+		{"docker cgroup v1 systemd driver", "5:pids:/system.slice/docker-" + testContainerA + ".scope\n0::/system.slice/docker-" + testContainerA + ".scope\n", testContainerA},
+		{"crio", "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod6c1d.slice/crio-" + testContainerA + ".scope\n", testContainerA},
+		{"cri-containerd", "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod6c1d.slice/cri-containerd-" + testContainerA + ".scope\n", testContainerA},
+		{"containerd systemd driver", "0::/system.slice/containerd.service/kubepods-besteffort-pod6c1d.slice:cri-containerd:" + testContainerA + "\n", testContainerA},
+		{"kubernetes cgroupfs driver", "3:cpuset:/kubepods/besteffort/pod6c1d/" + testContainerA + "\n", testContainerA},
+		{"podman", "0::/machine.slice/libpod-" + testContainerA + ".scope/container\n", testContainerA},
+		{"nested containers", "0::/docker/" + testContainerA + "/docker/" + testContainerB + "\n", testContainerB},
+		{"podman conmon", "0::/machine.slice/libpod-conmon-" + testContainerA + ".scope\n", ""},
+		{"crio conmon", "0::/kubepods.slice/crio-conmon-" + testContainerA + ".scope\n", ""},
+		{"host session", "0::/user.slice/user-1000.slice/session-2.scope\n", ""},
+		{"host service", "0::/system.slice/docker.service\n", ""},
+		{"short id", "0::/docker/3f4e5a6b7c8d\n", ""},
+		{"malformed", "garbage\n", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseContainerID(tt.cgroup))
+		})
+	}
+}
+
+func TestReadContainerID(t *testing.T) {
+	procPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procPath, "42"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(procPath, "42", "cgroup"), []byte("0::/system.slice/docker-"+testContainerA+".scope\n"), 0o644))
+
+	containerID, err := readContainerID(procPath, 42)
+	require.NoError(t, err)
+	assert.Equal(t, testContainerA, containerID)
+
+	_, err = readContainerID(procPath, 43)
+	assert.Error(t, err)
+}
+
+func TestShortContainerID(t *testing.T) {
+	assert.Equal(t, "3f4e5a6b3f4e", ShortContainerID(testContainerA))
+	assert.Equal(t, "3f4e", ShortContainerID("3f4e"))
+	assert.Equal(t, "(ctr: 3f4e5a6b3f4e)", formatContainerField(testContainerA))
+}
+
+// containerProcesses returns a process list where two shims run the processes of two containers
+func containerProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "systemd", Username: "root"},
+		{PID: 100, PPID: 1, Command: "containerd-shim", Username: "root"},
+		{PID: 101, PPID: 100, Command: "postgres", Username: "root", ContainerID: testContainerB},
+		{PID: 102, PPID: 101, Command: "postgres", Username: "root", ContainerID: testContainerB},
+		{PID: 200, PPID: 1, Command: "containerd-shim", Username: "root"},
+		{PID: 201, PPID: 200, Command: "nginx", Username: "root", ContainerID: testContainerA},
+		{PID: 202, PPID: 201, Command: "nginx", Username: "root", ContainerID: testContainerA},
+		{PID: 300, PPID: 1, Command: "sshd", Username: "root"},
+		{PID: 400, PPID: 399, Command: "backup", Username: "root"},
+	}
+}
+
+func TestAttachContainers(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), containerProcesses(), DisplayOptions{
+		CompactMode:      true,
+		GroupByContainer: true,
+		MaxDepth:         10,
+		OrphanSymbol:     DefaultOrphanSymbol,
+		ScreenWidth:      80,
+		ShowOrphans:      true,
+		ShowPIDs:         true,
+	})
+
+	// The topmost process of each container is moved under its node, but keeps its PPID
+	containerIndex, ok := processTree.PidToIndexMap[ContainerPIDBase]
+	require.True(t, ok)
+	assert.Equal(t, testContainerA, processTree.Nodes[containerIndex].ContainerID)
+	nginx := processTree.Nodes[processTree.PidToIndexMap[201]]
+	assert.Equal(t, containerIndex, nginx.Parent)
+	assert.Equal(t, int32(200), nginx.PPID)
+	assert.Equal(t, -1, processTree.Nodes[processTree.PidToIndexMap[200]].Child)
+	assert.Empty(t, processTree.Nodes[processTree.PidToIndexMap[200]].Children)
+
+	output := renderProcessTree(t, processTree)
+	// Without their containers, the shims are identical and compacted
+	assert.Equal(t, "-+- (1) systemd \n"+
+		" |--- (100) containerd-shim───2*[containerd-shim] (100,200) \n"+
+		" \\--- (300) sshd \n"+
+		"-+- (container 3f4e5a6b3f4e)\n"+
+		" \\-+- (201) nginx \n"+
+		"   \\--- (202) nginx \n"+
+		"-+- (container a1b2c3d4a1b2)\n"+
+		" \\-+- (101) postgres \n"+
+		"   \\--- (102) postgres \n"+
+		"-+- (orphans)\n"+
+		" \\--- (400) ? backup \n", output)
+
+	// The container nodes are not counted as processes
+	assert.Equal(t, 9, processTree.Summarize().Processes)
+}
+
+func TestAttachContainersWithoutContainers(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), orphanProcesses(), DisplayOptions{
+		GroupByContainer: true,
+		MaxDepth:         10,
+		ScreenWidth:      80,
+	})
+
+	_, ok := processTree.PidToIndexMap[ContainerPIDBase]
+	assert.False(t, ok)
+	assert.Len(t, processTree.Nodes, len(orphanProcesses()))
+}
+
+func TestContainerField(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), containerProcesses(), DisplayOptions{
+		MaxDepth:       10,
+		ScreenWidth:    120,
+		ShowContainers: true,
+	})
+
+	output := renderProcessTree(t, processTree)
+	assert.Contains(t, output, "(ctr: a1b2c3d4a1b2) postgres")
+	assert.Contains(t, output, "(ctr: 3f4e5a6b3f4e) nginx")
+	assert.NotContains(t, output, "(ctr: 3f4e5a6b3f4e) containerd-shim")
+}
@@ -0,0 +1,39 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the CPU modes of --cpu-mode. gopsutil reports the CPU usage of a process
+// the way top does in its default irix mode, as a percentage of one CPU, so a multithreaded
+// process can use more than 100%. In solaris mode, the usage is divided by the number of CPUs,
+// DisplayOptions.NumCPU, so the usage of all the processes of the host adds up to at most 100%.
+// The processes keep the usage they were collected with, and the mode is applied wherever it is
+// read: the displayed values, the compact group sums, --cumulative, --min-cpu, the --color-attr
+// thresholds, the summary, and the flat and JSON outputs. Sorting by cpu is not affected, since
+// dividing by the same number keeps the order.
+package pstree
+
+// normalizeCPU converts a CPU usage percentage of one CPU to the --cpu-mode.
+//
+// Parameters:
+//   - percent: The CPU usage percentage as collected, in irix mode
+//
+// Returns:
+//   - float64: The percentage divided by DisplayOptions.NumCPU in solaris mode, otherwise percent
+func (processTree *ProcessTree) normalizeCPU(percent float64) float64 {
+	if processTree.DisplayOptions.CPUMode == "solaris" && processTree.DisplayOptions.NumCPU > 1 {
+		return percent / float64(processTree.DisplayOptions.NumCPU)
+	}
+	return percent
+}
+
+// cpuPercentOf returns the CPU usage percentage of a process for display, in the --cpu-mode.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - float64: The CPU usage percentage, or -1 if it was not collected, see HasCPU
+func (processTree *ProcessTree) cpuPercentOf(process *Process) float64 {
+	if !process.HasCPU {
+		return -1
+	}
+	return processTree.normalizeCPU(process.CPUPercent)
+}
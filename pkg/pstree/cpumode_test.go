@@ -0,0 +1,79 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cpuModeProcesses returns a process busy on three CPUs with two identical workers
+func cpuModeProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 2},
+		{PID: 100, PPID: 1, Command: "java", HasCPU: true, CPUPercent: 300},
+		{PID: 101, PPID: 1, Command: "worker", HasCPU: true, CPUPercent: 20},
+		{PID: 102, PPID: 1, Command: "worker", HasCPU: true, CPUPercent: 40},
+		{PID: 103, PPID: 1, Command: "cron"},
+	}
+}
+
+func TestNormalizeCPU(t *testing.T) {
+	processTree := &ProcessTree{DisplayOptions: DisplayOptions{NumCPU: 4}}
+	assert.Equal(t, 300.0, processTree.normalizeCPU(300))
+
+	processTree.DisplayOptions.CPUMode = "irix"
+	assert.Equal(t, 300.0, processTree.normalizeCPU(300))
+
+	processTree.DisplayOptions.CPUMode = "solaris"
+	assert.Equal(t, 75.0, processTree.normalizeCPU(300))
+	assert.Equal(t, 75.0, processTree.cpuPercentOf(&Process{HasCPU: true, CPUPercent: 300}))
+	assert.Equal(t, -1.0, processTree.cpuPercentOf(&Process{CPUPercent: 300}), "not collected")
+
+	// Without the number of CPUs, the usage is left as it is
+	processTree.DisplayOptions.NumCPU = 0
+	assert.Equal(t, 300.0, processTree.normalizeCPU(300))
+}
+
+func TestCPUModeOutput(t *testing.T) {
+	for _, test := range []struct {
+		mode     string
+		expected string
+		groupCPU float64
+	}{
+		{"irix", "-+- (c:2.00%) init \n |--- (c:300.00%) java \n |--- (c:20.00%) worker \n |--- (c:40.00%) worker \n \\--- (c:-) cron \n", 60},
+		{"solaris", "-+- (c:0.50%) init \n |--- (c:75.00%) java \n |--- (c:5.00%) worker \n |--- (c:10.00%) worker \n \\--- (c:-) cron \n", 15},
+	} {
+		t.Run(test.mode, func(t *testing.T) {
+			displayOptions := DisplayOptions{CPUMode: test.mode, MaxDepth: 10, NumCPU: 4, ScreenWidth: 80, ShowCpuPercent: true}
+			processTree := NewProcessTree(0, setupTestLogger(), cpuModeProcesses(), displayOptions)
+			processTree.MarkProcesses()
+			output, err := processTree.RenderString()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, output)
+
+			// The identical workers are summed in the same mode
+			processTree.DisplayOptions.CompactMode = true
+			processTree.InitCompactMode()
+			count, _, _, groupCPU, _, _ := processTree.GetProcessCount(processTree.PidToIndexMap[101])
+			assert.Equal(t, 2, count)
+			assert.InDelta(t, test.groupCPU, groupCPU, 0.001)
+		})
+	}
+}
+
+func TestCPUModeThresholds(t *testing.T) {
+	java := &Process{PID: 100, HasCPU: true, CPUPercent: 40}
+
+	// 40% of one CPU is above the default thresholds of 5 and 15, but 10% of the host is between them
+	processTree := &ProcessTree{DisplayOptions: DisplayOptions{ColorAttr: "cpu", CPUMode: "irix", NumCPU: 4}}
+	assert.Equal(t, 2, processTree.attributeLevel(java))
+	processTree.DisplayOptions.CPUMode = "solaris"
+	assert.Equal(t, 1, processTree.attributeLevel(java))
+
+	// --min-cpu is interpreted in the same mode
+	processTree.DisplayOptions.MinCPU = 20
+	assert.False(t, processTree.meetsThresholds(java))
+	processTree.DisplayOptions.CPUMode = "irix"
+	assert.True(t, processTree.meetsThresholds(java))
+}
@@ -0,0 +1,68 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the CPU time shown with --cpu-time: the user and system time consumed by a
+// process over its lifetime, as shown by ps -o cputime. Unlike the CPU percentage, it doesn't
+// depend on when the processes are read. The CPU times are only collected when they are shown,
+// sorted by (--order-by=cputime), or colored by (--color-attr=cputime).
+package pstree
+
+import (
+	"fmt"
+)
+
+// CPUTime returns the CPU time consumed by a process, the sum of its user and system time.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - float64: The CPU time in seconds, or -1 if the CPU times were not collected
+func CPUTime(process *Process) float64 {
+	if process.CPUTimes == nil {
+		return -1
+	}
+	return process.CPUTimes.User + process.CPUTimes.System
+}
+
+// FormatCPUTime formats a CPU time the way ps formats cputime, as HH:MM:SS, with the number
+// of days in front once it exceeds a day, e.g., 03:15:00 or 2-03:15:00.
+//
+// Parameters:
+//   - seconds: The CPU time in seconds, or a negative value if it is not known
+//
+// Returns:
+//   - string: The formatted CPU time, or "?" if it is not known
+func FormatCPUTime(seconds float64) string {
+	var (
+		days    int64
+		hours   int64
+		minutes int64
+		total   int64
+	)
+
+	if seconds < 0 {
+		return "?"
+	}
+
+	total = int64(seconds)
+	days = total / 86400
+	hours = (total % 86400) / 3600
+	minutes = (total % 3600) / 60
+	total %= 60
+
+	if days > 0 {
+		return fmt.Sprintf("%d-%02d:%02d:%02d", days, hours, minutes, total)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, total)
+}
+
+// formatCPUTimeField formats the CPU time field of a line, e.g., (ct:00:01:23).
+//
+// Parameters:
+//   - seconds: The CPU time in seconds, or a negative value if it is not known
+//
+// Returns:
+//   - string: The formatted field
+func formatCPUTimeField(seconds float64) string {
+	return fmt.Sprintf("(ct:%s)", FormatCPUTime(seconds))
+}
@@ -0,0 +1,82 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+// cpuTimeTestProcesses returns processes whose order by CPU time and by CPU percentage differ.
+func cpuTimeTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", CPUTimes: &cpu.TimesStat{User: 40, System: 20}},
+		{PID: 100, PPID: 1, Command: "db", HasCPU: true, CPUPercent: 1, CPUTimes: &cpu.TimesStat{User: 2 * 86400, System: 3*3600 + 15*60}},
+		{PID: 200, PPID: 1, Command: "worker", HasCPU: true, CPUPercent: 50, CPUTimes: &cpu.TimesStat{User: 30, System: 5.5}},
+		{PID: 300, PPID: 1, Command: "worker", HasCPU: true, CPUPercent: 50, CPUTimes: &cpu.TimesStat{User: 50, System: 1}},
+		{PID: 400, PPID: 1, Command: "sshd"},
+	}
+}
+
+func TestFormatCPUTime(t *testing.T) {
+	tests := []struct {
+		seconds  float64
+		expected string
+	}{
+		{0, "00:00:00"},
+		{5.9, "00:00:05"},
+		{83, "00:01:23"},
+		{3*3600 + 15*60, "03:15:00"},
+		{23*3600 + 59*60 + 59, "23:59:59"},
+		{86400, "1-00:00:00"},
+		{2*86400 + 3*3600 + 15*60, "2-03:15:00"},
+		{400 * 86400, "400-00:00:00"},
+		{-1, "?"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, FormatCPUTime(test.seconds), "%v seconds", test.seconds)
+	}
+}
+
+func TestCPUTime(t *testing.T) {
+	processes := cpuTimeTestProcesses()
+	assert.Equal(t, 60.0, CPUTime(&processes[0]))
+	assert.Equal(t, 35.5, CPUTime(&processes[2]))
+	assert.Equal(t, -1.0, CPUTime(&processes[4]))
+}
+
+func TestShowCpuTime(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		displayOptions.ShowCpuTime = true
+		return renderTree(t, cpuTimeTestProcesses(), displayOptions)
+	}
+
+	output := render(DisplayOptions{})
+	assert.Contains(t, output, "(ct:00:01:00) init")
+	assert.Contains(t, output, "(ct:2-03:15:00) db")
+	assert.Contains(t, output, "(ct:?) sshd")
+
+	// The compact group sums the CPU times of its members
+	output = render(DisplayOptions{CompactMode: true})
+	assert.Contains(t, output, "(ct:00:01:26) worker───2*[worker]")
+
+	// --order-by=cputime sorts by the consumed time, the processes that could not be read last
+	output = render(DisplayOptions{OrderBy: "cputime", OrderDir: "desc"})
+	assert.Regexp(t, `(?s)db.*00:00:51\) worker.*00:00:35\) worker.*sshd`, output)
+	output = render(DisplayOptions{OrderBy: "cputime", OrderDir: "asc"})
+	assert.Regexp(t, `(?s)00:00:35\) worker.*00:00:51\) worker.*db.*sshd`, output)
+}
+
+func TestAttributeLevelCpuTime(t *testing.T) {
+	processes := cpuTimeTestProcesses()
+	processTree := &ProcessTree{DisplayOptions: DisplayOptions{ColorAttr: "cputime"}}
+
+	assert.Equal(t, 1, processTree.attributeLevel(&processes[0]))
+	assert.Equal(t, 2, processTree.attributeLevel(&processes[1]))
+	assert.Equal(t, 0, processTree.attributeLevel(&processes[2]))
+	assert.Equal(t, -1, processTree.attributeLevel(&processes[4]))
+
+	processTree.DisplayOptions.AttrThresholds = []float64{10, 36}
+	assert.Equal(t, 1, processTree.attributeLevel(&processes[2]))
+}
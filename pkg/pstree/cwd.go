@@ -0,0 +1,113 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the working directory shown with --cwd and the --cwd-under filter, which
+// finds the processes working inside a directory, e.g., the ones keeping a mount busy. Working
+// directories of another user's process can only be read with elevated privileges; they are shown
+// as "?" and never match --cwd-under. Unless --wide or --wrap is given, a working directory too
+// long for the line is shortened in the middle, keeping its start and its last elements visible.
+package pstree
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+	"github.com/mattn/go-runewidth"
+)
+
+// minCwdWidth is the width a working directory is never shortened below.
+const minCwdWidth = 12
+
+// markCwdUnder narrows the marked processes down to those whose working directory is in
+// DisplayOptions.CwdUnder, keeping their ancestors marked so the tree remains connected.
+func (processTree *ProcessTree) markCwdUnder() {
+	processTree.Logger.Debug("Entering processTree.markCwdUnder()")
+	processTree.narrowMarked(processTree.underCwd, fmt.Sprintf("works in %s", processTree.DisplayOptions.CwdUnder))
+}
+
+// underCwd determines whether the working directory of a process is DisplayOptions.CwdUnder or
+// one of its subdirectories. A directory merely starting with the same characters, e.g., /mnt/data2
+// for /mnt/data, doesn't match.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the process works in the directory, false otherwise or if its working directory could not be read
+func (processTree *ProcessTree) underCwd(node *Process) bool {
+	var (
+		dir string
+		rel string
+		err error
+	)
+
+	if node.IsThread || node.Cwd == "" {
+		return false
+	}
+
+	dir = filepath.Clean(processTree.DisplayOptions.CwdUnder)
+	rel, err = filepath.Rel(dir, filepath.Clean(node.Cwd))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// formatCwdField formats the working directory field of a line, e.g., (cwd: /srv/app).
+//
+// Parameters:
+//   - cwd: The working directory, empty if it could not be read
+//   - width: The maximum width of the directory, or 0 to show it in full
+//
+// Returns:
+//   - string: The formatted field, or (cwd: ?) if the working directory could not be read
+func formatCwdField(cwd string, width int) string {
+	if cwd == "" {
+		return "(cwd: ?)"
+	}
+	if width > 0 {
+		cwd = abbreviateMiddle(cwd, width)
+	}
+	return fmt.Sprintf("(cwd: %s)", cwd)
+}
+
+// abbreviateMiddle shortens a string to a width by replacing its middle with "...", e.g.,
+// /home/alice/.../build/output. The end is kept slightly longer than the start, since the last
+// elements of a path tell the most about it.
+//
+// Parameters:
+//   - input: The string to shorten
+//   - width: The maximum width of the result, which is never less than minCwdWidth
+//
+// Returns:
+//   - string: The input if it fits, the shortened string otherwise
+func abbreviateMiddle(input string, width int) string {
+	var (
+		dots     string = "..."
+		endWidth int
+		runes    []rune
+		start    int
+		end      int
+		used     int
+	)
+
+	width = max(width, minCwdWidth)
+	if util.VisibleWidth(input) <= width {
+		return input
+	}
+
+	runes = []rune(input)
+	endWidth = (width - len(dots) + 1) / 2
+	end = len(runes)
+	for end > 0 && used+runewidth.RuneWidth(runes[end-1]) <= endWidth {
+		end--
+		used += runewidth.RuneWidth(runes[end])
+	}
+	for start < end && used+runewidth.RuneWidth(runes[start]) <= width-len(dots) {
+		used += runewidth.RuneWidth(runes[start])
+		start++
+	}
+
+	return string(runes[:start]) + dots + string(runes[end:])
+}
@@ -0,0 +1,111 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// cwdTestProcesses returns processes working in and around a mount, one of which cannot be read:
+//
+//	init(1) -+- sshd(100) --- bash(101, /mnt/data/projects)
+//	         |- backup(200, /mnt/data2)
+//	         \- cron(300, unreadable)
+func cwdTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Cwd: "/"},
+		{PID: 100, PPID: 1, Command: "sshd", Cwd: "/"},
+		{PID: 101, PPID: 100, Command: "bash", Cwd: "/mnt/data/projects"},
+		{PID: 200, PPID: 1, Command: "backup", Cwd: "/mnt/data2"},
+		{PID: 300, PPID: 1, Command: "cron"},
+	}
+}
+
+func TestAbbreviateMiddle(t *testing.T) {
+	assert.Equal(t, "/srv/app", abbreviateMiddle("/srv/app", 20))
+	assert.Equal(t, "/home/alice/src/pstree", abbreviateMiddle("/home/alice/src/pstree", 22))
+
+	abbreviated := abbreviateMiddle("/home/alice/src/github.com/bananazon/pstree/build", 30)
+	assert.Equal(t, "/home/alice/s...n/pstree/build", abbreviated)
+	assert.Equal(t, 30, util.VisibleWidth(abbreviated))
+
+	// The directory is never shortened below the minimum width
+	assert.Equal(t, minCwdWidth, util.VisibleWidth(abbreviateMiddle("/home/alice/src/pstree", 1)))
+
+	// Wide characters count with their display width
+	abbreviated = abbreviateMiddle("/home/用户/文档/项目/构建/输出", 20)
+	assert.LessOrEqual(t, util.VisibleWidth(abbreviated), 20)
+	assert.True(t, strings.HasPrefix(abbreviated, "/home/"))
+	assert.True(t, strings.HasSuffix(abbreviated, "输出"))
+}
+
+func TestUnderCwd(t *testing.T) {
+	processes := cwdTestProcesses()
+	processTree := &ProcessTree{DisplayOptions: DisplayOptions{CwdUnder: "/mnt/data/"}}
+
+	assert.False(t, processTree.underCwd(&processes[0]))
+	assert.True(t, processTree.underCwd(&processes[2]))
+	assert.False(t, processTree.underCwd(&processes[3]), "a sibling directory with the same prefix doesn't match")
+	assert.False(t, processTree.underCwd(&processes[4]), "an unreadable directory never matches")
+
+	processTree.DisplayOptions.CwdUnder = "/mnt/data/projects"
+	assert.True(t, processTree.underCwd(&processes[2]), "the directory itself matches")
+
+	processTree.DisplayOptions.CwdUnder = "/"
+	assert.True(t, processTree.underCwd(&processes[0]))
+	assert.True(t, processTree.underCwd(&processes[3]))
+}
+
+func TestMarkCwdUnder(t *testing.T) {
+	mark := func(displayOptions DisplayOptions) []int32 {
+		processTree := NewProcessTree(0, setupTestLogger(), cwdTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		return markedPIDs(processTree)
+	}
+
+	assert.Equal(t, []int32{1, 100, 101}, mark(DisplayOptions{CwdUnder: "/mnt/data"}))
+	assert.Equal(t, []int32{1, 200}, mark(DisplayOptions{CwdUnder: "/mnt/data2"}))
+	assert.Equal(t, []int32{}, mark(DisplayOptions{CwdUnder: "/mnt/data", Contains: "backup"}))
+}
+
+func TestCwdUnderCompactMode(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Cwd: "/"},
+		{PID: 100, PPID: 1, Command: "bash", Cwd: "/mnt/data/projects"},
+		{PID: 101, PPID: 100, Command: "make", Cwd: "/mnt/data/projects"},
+		{PID: 102, PPID: 100, Command: "make", Cwd: "/mnt/data/projects"},
+		{PID: 103, PPID: 100, Command: "make", Cwd: "/tmp"},
+	}
+
+	// The matches are compacted with each other, not with the descendant shown with --match-subtree
+	output := renderTree(t, processes, DisplayOptions{CompactMode: true, CwdUnder: "/mnt/data", MatchSubtree: true})
+	assert.Equal(t, "-+- init \n \\-+- bash \n   |--- make───2*[make] \n   \\--- make \n", output)
+}
+
+func TestShowCwd(t *testing.T) {
+	render := func(processes []Process, displayOptions DisplayOptions) string {
+		displayOptions.ShowCwd = true
+		return renderTree(t, processes, displayOptions)
+	}
+
+	output := render(cwdTestProcesses(), DisplayOptions{ScreenWidth: 80})
+	assert.Contains(t, output, "(cwd: /mnt/data/projects) bash")
+	assert.Contains(t, output, "(cwd: ?) cron")
+
+	// A long directory is shortened in the middle so the command stays visible
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Cwd: "/"},
+		{PID: 100, PPID: 1, Command: "make", Cwd: "/home/alice/src/github.com/bananazon/pstree/build/output"},
+	}
+	output = render(processes, DisplayOptions{ScreenWidth: 40})
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		assert.LessOrEqual(t, util.VisibleWidth(line), 40)
+	}
+	assert.Regexp(t, `\(cwd: /home/.*\.\.\..*/output\) make`, output)
+
+	// With --wide, the directory is shown in full
+	output = render(processes, DisplayOptions{ScreenWidth: 40, WideDisplay: true})
+	assert.Contains(t, output, "(cwd: /home/alice/src/github.com/bananazon/pstree/build/output) make")
+}
@@ -6,7 +6,10 @@
 package pstree
 
 import (
+	"io"
 	"log/slog"
+	"regexp"
+	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/net"
@@ -36,6 +39,15 @@ const (
 	AnsiMagentaBold = "\033[1;35m"
 	AnsiCyanBold    = "\033[1;36m"
 	AnsiWhiteBold   = "\033[1;37m"
+
+	// Text attributes
+	AnsiBoldInverse      = "\033[1;7m"
+	AnsiDim              = "\033[2m"
+	AnsiInverse          = "\033[7m"
+	AnsiInverseOff       = "\033[27m"
+	AnsiNormalIntensity  = "\033[22m"
+	AnsiStrikethrough    = "\033[9m"
+	AnsiStrikethroughOff = "\033[29m"
 )
 
 //------------------------------------------------------------------------------
@@ -45,21 +57,28 @@ const (
 // Process represents a system process with all its attributes and relationships.
 // It combines information gathered from both gopsutil and direct ps command calls
 // to provide comprehensive details about the process.
+// Fields that are derived while building the tree are left out of snapshots.
 type Process struct {
-	// Process age in seconds since creation
+	// Process age in seconds since creation, or -1 if unknown
 	Age int64
-	// Command line arguments
+	// Command line arguments, without argv[0]
 	Args []string
+	// Name the process was started with, i.e., argv[0] (empty if unknown), see commandName
+	Argv0 string
 	// Background status of the process
 	Background bool
 	// Index of the first child process in the process tree
-	Child int
+	Child int `json:"-"`
 	// Pointer to a slice of child processes
-	Children []*Process
+	Children []*Process `json:"-"`
 	// Command name (executable name)
 	Command string
 	// Network connections associated with this process
 	Connections []net.ConnectionStat
+	// Indicates if the network connections could not be read, e.g., due to permissions
+	ConnectionsUnavailable bool `json:"-"`
+	// Full ID of the container the process runs in, empty for the processes of the host, see ProcessContainerID
+	ContainerID string
 	// CPU Affinity
 	CPUAffinity []int32
 	// CPU usage percentage
@@ -68,20 +87,46 @@ type Process struct {
 	CPUTimes *cpu.TimesStat
 	// Process creation time as Unix timestamp
 	CreateTime int64
+	// CPU usage percentage of the process and all of its descendants
+	CumulativeCPU float64 `json:"-"`
+	// Memory usage in the --mem-field, RSS by default, of the process and all of its descendants
+	CumulativeRSS uint64 `json:"-"`
+	// Current working directory, empty if it could not be read
+	Cwd string
+	// Depth of the process below the root it is printed under, see AssignDepths
+	Depth int `json:"-"`
+	// Change of the CPU usage percentage between the snapshots compared with --diff
+	DiffCPUPercent float64 `json:"-"`
+	// Change of the resident set size in bytes between the snapshots compared with --diff
+	DiffRSS int64 `json:"-"`
+	// How the process changed between the snapshots compared with --diff, see MergeSnapshots
+	DiffState DiffState `json:"-"`
 	// Environment variables
 	Environment []string
+	// Indicates if the executable of this process was deleted, see isDeletedExe
+	ExeDeleted bool
 	// Foreground status of the process
 	Foreground bool
 	// Group IDs associated with this process
 	GIDs []uint32
 	// Groups associated with this process
 	Groups []uint32
+	// Indicates if the CPU usage percentage was collected, see collectUsage
+	HasCPU bool
+	// Indicates if the memory usage was collected, see collectUsage
+	HasMemory bool
 	// Indicates if this process has a different UID from its parent
-	HasUIDTransition bool
+	HasUIDTransition bool `json:"-"`
 	// Process hierarchy
 	Hierarchy map[int32][]string
 	// Indicates if this process is the current process or an ancestor
-	IsCurrentOrAncestor bool
+	IsCurrentOrAncestor bool `json:"-"`
+	// Indicates if the parent of this process was not collected, see AttachOrphans
+	IsOrphan bool `json:"-"`
+	// Indicates if this process gained privileges with --privileged, see isPrivileged
+	IsPrivileged bool `json:"-"`
+	// Indicates if this is a synthetic node representing a thread of its parent process
+	IsThread bool
 	// IO counters associated with this process
 	IOCounters *process.IOCountersStat
 	// Memory usage information
@@ -90,6 +135,12 @@ type Process struct {
 	MemoryInfoEx *process.MemoryInfoExStat
 	// Memory usage as percentage of total system memory
 	MemoryPercent float32
+	// Inode of each of the NamespaceTypes namespaces by type, 0 if it could not be read, nil if they were not collected, see ProcessNamespaces
+	Namespaces map[string]uint64
+	// Nice value of the process, nil if it was not collected or could not be read, see ProcessNice
+	Nice *int32
+	// PIDs of the process in each of its PID namespaces, from the host to the innermost one, nil if they were not collected or could not be read, see ProcessNSPids
+	NSPids []int32
 	// Number of file descriptors
 	NumFDs int32
 	// Number of context switches
@@ -101,33 +152,45 @@ type Process struct {
 	// Page faults associated with this process
 	PageFaults *process.PageFaultsStat
 	// Index of the parent process in the process tree
-	Parent int
+	Parent int `json:"-"`
+	// Command of the parent process, resolved by BuildTree, empty if the parent was not collected, see commandName
+	ParentCommand string `json:"-"`
 	// Pointer to the parent process
-	ParentProcess *Process
+	ParentProcess *Process `json:"-"`
 	// UID of the parent process
-	ParentUID uint32
+	ParentUID uint32 `json:"-"`
 	// Username of the parent process
-	ParentUsername string
+	ParentUsername string `json:"-"`
 	// Process group ID
 	PGID int32
 	// Process ID
 	PID int32
 	// The PID index
-	PidIndex int
+	PidIndex int `json:"-"`
 	// Parent process ID
 	PPID int32
 	// Whether or not we plan to display this process
-	Print bool
+	Print bool `json:"-"`
+	// Why the process is displayed, see PrintReason
+	PrintReason PrintReason `json:"-"`
 	// Resource limits associated with this process
 	ResourceLimit []process.RlimitStat
 	// Resource limits associated with this process
 	ResourceLimitUsage []process.RlimitStat
+	// Scheduling policy, e.g., FIFO, empty if it was not collected or could not be read, see ProcessSchedPolicy
+	SchedPolicy string
+	// Memory usage with the shared pages accounted for, nil unless --mem-mode=pss or uss could read it
+	SharedMemory *SharedMemoryStat
+	// Session ID, i.e., the PID of the session leader
+	SID int32
 	// Cached subtree signature
-	Signature string // cached subtree signature
+	Signature string `json:"-"` // cached subtree signature
 	// Index of the next sibling process in the process tree
-	Sister int
+	Sister int `json:"-"`
 	// Process status information
 	Status []string
+	// Name of the controlling terminal, e.g., pts/3, or empty if the process has none
+	Terminal string
 	// Threads associated with this process
 	Threads map[int32]*cpu.TimesStat
 	// User IDs associated with this process
@@ -136,6 +199,37 @@ type Process struct {
 	Username string
 }
 
+// SharedMemoryStat holds the memory usage of a process with its shared pages accounted for.
+type SharedMemoryStat struct {
+	// Proportional set size in bytes: the resident memory, with each shared page divided among the processes sharing it
+	PSS uint64 `json:"pss"`
+	// Unique set size in bytes: the resident memory not shared with any other process
+	USS uint64 `json:"uss"`
+}
+
+// DefaultAttributeThresholds holds the thresholds between the levels of each --color-attr attribute.
+// A value at or above a threshold belongs to the next level. Age and cputime are in seconds, cpu
+// and mem are percentages, and fds is a number of open file descriptors.
+var DefaultAttributeThresholds = map[string][]float64{
+	"age":     {60, 3600, 86400},
+	"cpu":     {5, 15},
+	"cputime": {60, 3600},
+	"fds":     {100, 1000},
+	"mem":     {10, 20},
+}
+
+// StatusLetters maps the process status reported by gopsutil to the single-letter state shown by ps.
+var StatusLetters = map[string]string{
+	process.Blocked: "D",
+	process.Idle:    "I",
+	process.Lock:    "L",
+	process.Running: "R",
+	process.Sleep:   "S",
+	process.Stop:    "T",
+	process.Wait:    "W",
+	process.Zombie:  "Z",
+}
+
 //------------------------------------------------------------------------------
 // DISPLAY CONFIGURATION
 //------------------------------------------------------------------------------
@@ -143,7 +237,21 @@ type Process struct {
 // DisplayOptions controls how the process tree is displayed, including formatting,
 // coloring, and which information is shown for each process.
 type DisplayOptions struct {
-	// Attribute to color by ("age", "cpu", or "mem")
+	// Format of the process age ("dhms", "hms", "human", or "seconds")
+	AgeFormat string
+	// Whether to pad the lines of the tree so the metrics line up in columns, see printAligned
+	AlignColumns bool
+	// Regular expression the arguments shown with ShowArguments must match (nil for all), see formatArgs
+	ArgsFilter *regexp.Regexp
+	// Whether to use ASCII characters for tree lines even when the locale uses UTF-8
+	ASCIIGraphics bool
+	// Thresholds between the levels of the --color-attr attribute, or nil to use DefaultAttributeThresholds
+	AttrThresholds []float64
+	// Whether to show the processes under the leader of their process group instead of their parent, see BuildGroupTree
+	ByProcessGroup bool
+	// Whether to print the runs of single children on the line of their first process, see buildChainItems
+	CollapseChains bool
+	// Attribute to color by ("age", "cpu", "fds", "mem", or "user")
 	ColorAttr string
 	// Number of colors to use in rainbow mode
 	ColorCount int
@@ -151,62 +259,220 @@ type DisplayOptions struct {
 	ColorizeOutput bool
 	// The system color scheme to use
 	ColorScheme string
+	// ANSI escape sequences of the elements listed in a color scheme file, applied over the default colors
+	CustomColors map[string]string
 	// Whether the terminal supports color output
 	ColorSupport bool
+	// How to display the command of each process ("basename" or "full"), see FormatCommand
+	CommandFormat string
 	// Whether to compact identical processes in the tree
 	CompactMode bool
 	// String to search for in process names
 	Contains string
+	// How the CPU usage percentages are shown ("irix" or "solaris", "" for irix), see normalizeCPU
+	CPUMode string
+	// Directory whose processes to show, those with their working directory in it, see underCwd
+	CwdUnder string
+	// Marker shown after the command of processes running a deleted executable ("" for none)
+	DeletedMarker string
+	// Environment variable to search for, as KEY=VALUE or KEY, see markEnvironment
+	EnvContains string
+	// Patterns matched against the command line of processes to hide along with their descendants
+	ExcludePatterns []string
 	// Whether to exclude processes owned by root
 	ExcludeRoot bool
+	// Usernames whose processes to hide unless they are the ancestors of displayed processes, see markExcludedUsers
+	ExcludeUsernames []string
+	// Names of the fields selected with --fields in display order, see ApplyFields (nil for the default order)
+	Fields []string
+	// Whether to move the processes of each container under a node of their own, see AttachContainers
+	GroupByContainer bool
+	// List of group IDs to filter by, see ProcessTree.inGroups
+	Groups []uint32
+	// Whether to hide Linux kernel threads along with their descendants, see IsKernelThread
+	HideKernelThreads bool
 	// Whether to hide threads in the output
 	HideThreads bool
+	// Whether to highlight the processes with a negative nice value, see niceField
+	HighlightNice bool
+	// PID of the process to highlight along with its ancestors (0 for none)
+	HighlightPID int32
 	// Whether to use IBM850 graphics characters for tree lines
 	IBM850Graphics bool
 	// Total installed system memory in bytes
 	InstalledMemory uint64
+	// Whether to keep the processes that exited while they were collected instead of skipping them, see readParent
+	KeepVanished bool
+	// Time each lookup of a username is given before falling back to the UID (0 for DefaultLookupTimeout), see usernameCache
+	LookupTimeout time.Duration
+	// Whether to also show all descendants of processes matching CwdUnder, EnvContains, Groups, MinCPU, MinMemory or Terminal; the descendants of the processes matching Contains are always shown
+	MatchSubtree bool
+	// Maximum number of arguments shown with ShowArguments (0 for all), see formatArgs
+	MaxArgs int
 	// Maximum depth of the tree to display (0 for unlimited)
 	MaxDepth int
+	// Memory value shown with ShowMemoryUsage ("rss", "swap", or "vms"), see MemoryValue
+	MemoryField string
+	// How the memory values are shown ("abs", "pct", or "both"), see formatMemory
+	MemoryFormat string
+	// Resident memory used for the memory values, sums, sorting, and coloring ("rss", "pss", or "uss"), see memoryField
+	MemoryMode string
+	// Unit of the memory values ("auto", "K", "M", or "G"), see util.FormatByteSize
+	MemoryUnit string
+	// Minimum CPU usage percentage of the processes to display (0 for no minimum)
+	MinCPU float64
+	// Minimum resident memory in bytes of the processes to display (0 for no minimum)
+	MinMemory uint64
+	// Whether to print the children of each RootPIDs process as trees of their own, without its line
+	NoRootLine bool
+	// Number of logical CPUs the CPU usage is divided by with CPUMode solaris (0 if unknown)
+	NumCPU int
+	// Whether to show UIDs instead of usernames, see ProcessTree.ownerName
+	Numeric bool
+	// Minimum age of the processes to display (0 for no minimum), see inAgeWindow
+	OlderThan time.Duration
+	// Whether to show only the processes running a deleted executable and their ancestors
+	OnlyDeleted bool
+	// Whether to show only the processes living in other namespaces than the host and their ancestors, see markForeignNamespaces
+	OnlyForeignNS bool
+	// Whether to show only the privileged processes and their ancestors, see isPrivileged
+	OnlyPrivileged bool
+	// Whether to show only the processes with a realtime scheduling policy and their ancestors, see IsRealtime
+	OnlyRealtime bool
+	// Whether to show only the zombie processes and their ancestors, see IsZombie
+	OnlyZombies bool
 	// Sort the results by a number of fields
 	OrderBy string
+	// Direction of the sort ("asc" or "desc")
+	OrderDir string
+	// Symbol shown in front of the command of orphaned processes with ShowOrphans
+	OrphanSymbol string
+	// Page faults to show ("" for none, "major", or "all"), see formatPageFaultsField
+	PageFaults string
+	// PID of the process to show along with its ancestors only (0 for none), see markAncestry
+	ParentsOf int32
 	// Whether to use rainbow colors for output
 	RainbowOutput bool
-	// Root process PID
-	RootPID int32
+	// Whether to show argv[0] in the arguments and the executable as the command, see displayArgs
+	RawArgs bool
+	// Basename of the command of the processes to use as roots (empty for none), see matchesRootCommand
+	RootCommand string
+	// Regular expression the basename of the command of the processes to use as roots must match (nil for none)
+	RootCommandPattern *regexp.Regexp
+	// PIDs of the processes to use as tree roots, each rendered as its own tree
+	RootPIDs []int32
 	// Width of the terminal screen in characters
 	ScreenWidth int
+	// PID of the process whose session the processes must be in (0 for all), see markSession
+	SessionOf int32
 	// Whether to show command line arguments
 	ShowArguments bool
+	// Whether to show a summary of the network connections
+	ShowConnections bool
+	// Whether to show the container each process runs in
+	ShowContainers bool
 	// Whether to show CPU usage percentage
 	ShowCpuPercent bool
+	// Whether to show the consumed CPU time
+	ShowCpuTime bool
+	// Whether to show the cumulative CPU and memory usage of each subtree
+	ShowCumulative bool
+	// Whether to show the current working directory
+	ShowCwd bool
+	// Whether to prefix each line with the depth of the process
+	ShowDepth bool
+	// Whether to print the number of displayed processes at each level after the tree
+	ShowDepthStats bool
+	// Whether to show the changes of the processes compared with --diff, see MergeSnapshots
+	ShowDiff bool
+	// Whether to show the environment variable matching EnvContains
+	ShowEnv bool
+	// Whether to show the number of bytes read and written
+	ShowIO bool
 	// Whether to show memory usage
 	ShowMemoryUsage bool
+	// Whether to mark the processes living in other namespaces than the host
+	ShowNamespaces bool
+	// Whether to show the nice value of each process
+	ShowNice bool
+	// Whether to show the PID of each process in its innermost PID namespace next to its PID, see formatPID
+	ShowNSPids bool
+	// Whether to show the number of open file descriptors
+	ShowNumFDs bool
 	// Whether to show thread count
 	ShowNumThreads bool
+	// Whether to attach processes whose parent was not collected to a synthetic "(orphans)" node
+	ShowOrphans bool
 	// Whether to show process owner
 	ShowOwner bool
+	// Whether to show the command of the parent of each process, see formatParentField
+	ShowParent bool
 	// Whether to highlight process group leaders
 	ShowPGLs bool
 	// Whether to show process group IDs
 	ShowPGIDs bool
 	// Whether to show process IDs
 	ShowPIDs bool
+	// Whether to mark the processes that gained privileges, see isPrivileged
+	ShowPrivileged bool
 	// Whether to show parent process IDs
 	ShowPPIDs bool
 	// Whether to show process age
 	ShowProcessAge bool
+	// Whether to show the scheduling policy of each process
+	ShowSched bool
+	// Whether to show session IDs and mark the session leaders, see formatIdentity and leaderMarker
+	ShowSIDs bool
+	// Whether to show the start time of each process, see formatStartTime
+	ShowStartTime bool
+	// Whether to show the single-letter process state
+	ShowStatus bool
+	// Whether to print a summary of the displayed processes after the tree
+	ShowSummary bool
+	// Whether to show the threads of each process as {command} child nodes
+	ShowThreadsTree bool
 	// Whether to show UID transitions
 	ShowUIDTransitions bool
 	// Whether to show username transitions
 	ShowUserTransitions bool
+	// Whether to mark zombie processes with DefunctSuffix and count them in the summary
+	ShowZombies bool
+	// Whether to read only argv[0] of the processes instead of their command lines, which are only
+	// needed to show them, to group the identical processes of compact mode by their arguments, and
+	// to match --exclude; argv[0] still names each process, e.g., -bash for a login shell
+	SkipArgs bool
+	// How processes whose parent is missing from the snapshot are repaired: SnapshotRepairRefetch,
+	// SnapshotRepairReparent, or empty to leave them as they are
+	SnapshotRepair string
+	// Whether to print each line of the tree as soon as it is built, skipping the passes over the
+	// whole tree of AlignColumns and of the padding of the identity fields, see PrintTree
+	Stream bool
+	// Name of the controlling terminal to filter by, e.g., pts/3 (empty for none)
+	Terminal string
+	// Number of processes to show, ranked by OrderBy in the OrderDir direction (0 for all)
+	Top int
+	// Whether to show the start times in UTC instead of local time
+	UTC bool
 	// Whether to use UTF-8 graphics characters for tree lines
 	UTF8Graphics bool
+	// Resolver of the usernames of the processes (nil for SystemUserResolver), see usernameCache
+	UserResolver UserResolver
 	// List of usernames to filter by
 	Usernames []string
 	// Whether to use VT100 graphics characters for tree lines
 	VT100Graphics bool
+	// Refresh interval in seconds for watch mode (0 disables watch mode)
+	WatchInterval int
 	// Whether to display wide output (not truncated to screen width)
 	WideDisplay bool
+	// Whether to also show the direct children of the ParentsOf process
+	WithChildren bool
+	// Whether to wrap lines wider than the screen onto continuation lines instead of truncating them
+	WrapLines bool
+	// Number of workers collecting process information (0 for GOMAXPROCS)
+	Workers int
+	// Maximum age of the processes to display (0 for no maximum), see inAgeWindow
+	YoungerThan time.Duration
 }
 
 //------------------------------------------------------------------------------
@@ -217,6 +483,8 @@ type DisplayOptions struct {
 // It maintains the tree structure and provides methods for building,
 // manipulating, and displaying the process hierarchy.
 type ProcessTree struct {
+	// Lines of the tree being printed, buffered with DisplayOptions.AlignColumns, see printAligned
+	alignedLines []alignedLine
 	// Current depth in the tree during traversal
 	AtDepth int
 	// Color scheme for applying colors to text
@@ -227,20 +495,32 @@ type ProcessTree struct {
 	DebugLevel int
 	// Display options controlling how the tree is rendered
 	DisplayOptions DisplayOptions
+	// Inodes of the namespaces of the host the processes are compared with, see hostNamespaces
+	HostNamespaces map[string]uint64
+	// Width of each identity field among the printed processes, see computeIdentityWidths
+	identityWidths map[string]int
 	// Map from index in the Nodes array to PID
 	IndexToPidMap map[int]int32
 	// Logger for debug and informational messages
 	Logger *slog.Logger
 	// Array of process nodes in the tree
 	Nodes []*Process
+	// Destination of the rendered tree
+	Output io.Writer
 	// Map from PID to index in the Nodes array for quick lookups
 	PidToIndexMap map[int32]int
 	// Process groups for grouping identical processes
-	ProcessGroups map[int32]map[string]map[string]ProcessGroup
-	// PID of the root process for the tree
-	RootPID int32
+	ProcessGroups map[ProcessGroupKey]ProcessGroup
+	// PIDs of the root processes for the tree
+	RootPIDs []int32
+	// Durations of the pipeline stages, see LogTimings
+	Timings Timings
+	// Number of processes ranked by --top, see markTop
+	TopCandidates int
 	// Tree characters for drawing the tree
 	TreeChars TreeChars
+	// Palette slot of each user for --color-attr=user, see assignUserColors
+	UserColors map[string]int
 }
 
 // ------------------------------------------------------------------------------
@@ -249,22 +529,64 @@ type ProcessTree struct {
 type ProcessGroup struct {
 	// Oldest process age of the group
 	Age int64
+	// Create time of the oldest process of the group as a Unix timestamp, -1 if none of the members could be read
+	CreateTime int64
 	// Number of identical processes
 	Count int
-	// Summed CPU percent of the group
+	// Summed CPU percent of the group, -1 if none of the members was collected
 	CPUPercent float64
+	// Summed CPU time of the group in seconds, -1 if none of the members could be read
+	CPUTime float64
+	// Summed cumulative CPU percent of the group members and their descendants
+	CumulativeCPU float64
+	// Summed cumulative memory usage in the --mem-field of the group members and their descendants
+	CumulativeRSS uint64
+	// Summed change of the CPU percent of the group members in both snapshots, see DisplayOptions.ShowDiff
+	DiffCPUPercent float64
+	// Summed change of the resident memory of the group members in both snapshots, in bytes
+	DiffRSS int64
 	// Index of the first process in the group
 	FirstIndex int
 	// Full path of the command
 	FullPath string
+	// Indicates if the memory usage of any of the members was collected
+	HasMemory bool
 	// Indices of all processes in the group
 	Indices []int
-	// Summed RSS memory usage of the group
+	// Summed IO counters of the group, nil if none of the members could be read
+	IOCounters *process.IOCountersStat
+	// Summed memory usage in the --mem-field of the group as a percentage of the installed memory
+	MemoryPercent float64
+	// Summed memory usage in the --mem-field of the members that were collected
 	MemoryUsage uint64
+	// Range of the nice values of the group, nil if none of the members could be read
+	Nice *NiceRange
+	// Summed file descriptor count of the group, -1 if none of the members could be read
+	NumFDs int32
 	// Summed thread count of the group
 	NumThreads int32
 	// The process owner
 	Owner string
+	// Summed page faults of the group, nil if none of the members could be read
+	PageFaults *process.PageFaultsStat
+	// Distinct scheduling policies of the group members that could be read
+	SchedPolicies []string
+	// Distinct single-letter states of the group members
+	States []string
+}
+
+// ProcessGroupKey identifies a group of identical processes: the processes with the same parent,
+// subtree signature, and owner are shown as a single line in compact mode. Processes matching the
+// filters are never grouped with processes only shown for context, e.g., with --contains.
+type ProcessGroupKey struct {
+	// Whether the processes match the filters themselves
+	Match bool
+	// PID of the parent process
+	PPID int32
+	// Subtree signature of the processes, see computeSignature
+	Signature string
+	// Username of the process owner
+	Owner string
 }
 
 //------------------------------------------------------------------------------
@@ -282,6 +604,8 @@ type TreeChars struct {
 	BarL string
 	// EG represents the End Graphics character sequence for terminating graphic mode
 	EG string
+	// Ellipsis represents the single-column character (…) appended to lines truncated to the screen width
+	Ellipsis string
 	// Init represents the initialization sequence for the terminal
 	Init string
 	// NPGL represents the character sequence to initialize the graphic set for non-process group leaders
@@ -294,6 +618,8 @@ type TreeChars struct {
 	S2 string
 	// SG represents the Start Graphics character sequence for entering graphic mode
 	SG string
+	// SL represents the character sequence used to highlight session leaders
+	SL string
 }
 
 // TreeStyles defines different graphical styles for tree visualization.
@@ -302,52 +628,60 @@ type TreeChars struct {
 var TreeStyles = map[string]TreeChars{
 	// https://github.com/FredHucht/pstree/blob/main/pstree.c#L192-L207
 	"ascii": {
-		Bar:  "|",  // B
-		BarC: "|",  // C
-		BarL: "\\", // L
-		EG:   "",   // eg
-		Init: "",   // init
-		NPGL: "-",  // N
-		P:    "-+", // PP
-		PGL:  "=",  // G
-		S2:   "--", // ss
-		SG:   "",   // sg
+		Bar:      "|",  // B
+		BarC:     "|",  // C
+		BarL:     "\\", // L
+		EG:       "",   // eg
+		Ellipsis: "+",  // (not in pstree.c)
+		Init:     "",   // init
+		NPGL:     "-",  // N
+		P:        "-+", // PP
+		PGL:      "=",  // G
+		S2:       "--", // ss
+		SG:       "",   // sg
+		SL:       "@",  // (not in pstree.c)
 	},
 	"pc850": {
-		Bar:  string([]byte{0xB3}),       // B
-		BarC: string([]byte{0xC3}),       // C
-		BarL: string([]byte{0xB4}),       // L
-		EG:   string([]byte{}),           // eg
-		Init: string([]byte{}),           // init
-		NPGL: string([]byte{0xDA}),       // N
-		P:    string([]byte{0xDA, 0xC2}), // PP
-		PGL:  "¤",                        // G
-		S2:   string([]byte{0xDA, 0xDA}), // ss
-		SG:   string([]byte{}),           // sg
+		Bar:      string([]byte{0xB3}),       // B
+		BarC:     string([]byte{0xC3}),       // C
+		BarL:     string([]byte{0xB4}),       // L
+		EG:       string([]byte{}),           // eg
+		Ellipsis: "+",                        // (not in pstree.c)
+		Init:     string([]byte{}),           // init
+		NPGL:     string([]byte{0xDA}),       // N
+		P:        string([]byte{0xDA, 0xC2}), // PP
+		PGL:      "¤",                        // G
+		S2:       string([]byte{0xDA, 0xDA}), // ss
+		SG:       string([]byte{}),           // sg
+		SL:       "§",                        // (not in pstree.c)
 	},
 	"vt100": {
-		Bar:  "\x0Ex\x0F",    // B
-		BarC: "\x0Et\x0F",    // C
-		BarL: "\x0Em\x0F",    // L
-		EG:   "\x0F",         // eg
-		Init: "\033(B\033)0", // init
-		NPGL: "\x0Eq\x0F",    // N
-		P:    "\x0Eqw\x0F",   // PP
-		PGL:  "◆",            // G
-		S2:   "\x0Eqq\x0F",   // ss
-		SG:   "\x0E",         // sg
+		Bar:      "\x0Ex\x0F",    // B
+		BarC:     "\x0Et\x0F",    // C
+		BarL:     "\x0Em\x0F",    // L
+		EG:       "\x0F",         // eg
+		Ellipsis: "+",            // (not in pstree.c)
+		Init:     "\033(B\033)0", // init
+		NPGL:     "\x0Eq\x0F",    // N
+		P:        "\x0Eqw\x0F",   // PP
+		PGL:      "◆",            // G
+		S2:       "\x0Eqq\x0F",   // ss
+		SG:       "\x0E",         // sg
+		SL:       "◈",            // (not in pstree.c)
 	},
 	"utf8": {
-		Bar:  "\342\224\202",             // B
-		BarC: "\342\224\234",             // C
-		BarL: "\342\224\224",             // L
-		EG:   "",                         // eg
-		Init: "",                         // init
-		NPGL: "\342\224\200",             // N
-		P:    "\342\224\200\342\224\254", // PP
-		PGL:  "●",                        // G
-		S2:   "\342\224\200\342\224\200", // ss
-		SG:   "",                         // sg
+		Bar:      "\342\224\202",             // B
+		BarC:     "\342\224\234",             // C
+		BarL:     "\342\224\224",             // L
+		EG:       "",                         // eg
+		Ellipsis: "…",                        // (not in pstree.c)
+		Init:     "",                         // init
+		NPGL:     "\342\224\200",             // N
+		P:        "\342\224\200\342\224\254", // PP
+		PGL:      "●",                        // G
+		S2:       "\342\224\200\342\224\200", // ss
+		SG:       "",                         // sg
+		SL:       "◉",                        // (not in pstree.c)
 	},
 }
 
@@ -360,12 +694,15 @@ type Colorizer struct {
 	CompactStr         ColorFunc
 	Connector          ColorFunc
 	CPU                ColorFunc
+	FDs                ColorFunc
 	Memory             ColorFunc
 	NumThreads         ColorFunc
 	Owner              ColorFunc
 	OwnerTransition    ColorFunc
 	PIDPGID            ColorFunc
 	Prefix             ColorFunc
+	Status             ColorFunc
+	StatusZombie       ColorFunc
 	ProcessAgeLow      ColorFunc
 	ProcessAgeMedium   ColorFunc
 	ProcessAgeHigh     ColorFunc
@@ -376,6 +713,9 @@ type Colorizer struct {
 	MemoryLow          ColorFunc
 	MemoryMedium       ColorFunc
 	MemoryHigh         ColorFunc
+	FDsLow             ColorFunc
+	FDsMedium          ColorFunc
+	FDsHigh            ColorFunc
 	Default            ColorFunc
 }
 
@@ -387,12 +727,15 @@ var Colorizers = map[string]Colorizer{
 		CompactStr:         Color8BlackBold,
 		Connector:          Color8BlackBold,
 		CPU:                Color8YellowBold,
+		FDs:                Color8Blue,
 		Memory:             Color8RedBold,
 		NumThreads:         Color8WhiteBold,
 		Owner:              Color8CyanBold,
 		OwnerTransition:    Color8BlackBold,
 		PIDPGID:            Color8MagentaBold,
-		Prefix:             Color8Green,
+		Prefix:             ColorDefault,
+		Status:             Color8Cyan,
+		StatusZombie:       Color8RedBold,
 		ProcessAgeLow:      Color8Red,
 		ProcessAgeMedium:   Color8Yellow,
 		ProcessAgeHigh:     Color8Cyan,
@@ -403,6 +746,9 @@ var Colorizers = map[string]Colorizer{
 		MemoryLow:          Color8Green,
 		MemoryMedium:       Color8Yellow,
 		MemoryHigh:         Color8Red,
+		FDsLow:             Color8Green,
+		FDsMedium:          Color8Yellow,
+		FDsHigh:            Color8Red,
 		Default:            Color8Green,
 	},
 	"256color": {
@@ -412,12 +758,15 @@ var Colorizers = map[string]Colorizer{
 		CompactStr:         Color256BlackBold,
 		Connector:          Color256BlackBold,
 		CPU:                Color256Yellow,
+		FDs:                Color256BlueBold,
 		Memory:             Color256Orange,
 		NumThreads:         Color256White,
 		Owner:              Color256Cyan,
 		OwnerTransition:    Color256BlackBold,
 		PIDPGID:            Color256Magenta,
-		Prefix:             Color256Green,
+		Prefix:             ColorDefault,
+		Status:             Color256CyanBold,
+		StatusZombie:       Color256RedBold,
 		ProcessAgeLow:      Color256Red,
 		ProcessAgeMedium:   Color256Yellow,
 		ProcessAgeHigh:     Color256Cyan,
@@ -428,6 +777,9 @@ var Colorizers = map[string]Colorizer{
 		MemoryLow:          Color256Green,
 		MemoryMedium:       Color256Yellow,
 		MemoryHigh:         Color256Red,
+		FDsLow:             Color256Green,
+		FDsMedium:          Color256Yellow,
+		FDsHigh:            Color256Red,
 		Default:            Color256Green,
 	},
 }
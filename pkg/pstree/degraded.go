@@ -0,0 +1,187 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the detection of the attributes that cannot be read without privileges.
+// Run as an unprivileged user, reading some attributes of the processes of the other users
+// fails, e.g., their IO counters, file descriptors, and working directories on Linux, or their
+// CPU and memory usage on macOS, and each failure quietly falls back to an unknown value. A tree
+// full of unknown values looks like a bug, so the collection counts how each read failed, telling
+// a permission error (EPERM or EACCES) apart from a process that exited meanwhile (ESRCH), and
+// DegradedNotice explains the attributes denied for most of the processes in a single line,
+// which is printed after the tree unless --quiet is given.
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// degradedRatio is the share of the reads of an attribute that must be denied for the notice
+// to mention it, so the few processes of other users on a desktop don't trigger it.
+const degradedRatio = 0.5
+
+// attributeDescriptions name the attributes in the notice, the others are named as recorded.
+var attributeDescriptions = map[string]string{
+	"age":      "start times",
+	"cpu":      "CPU usage",
+	"cputimes": "CPU time",
+	"cwd":      "working directories",
+	"faults":   "page faults",
+	"fds":      "file descriptors",
+	"io":       "IO counters",
+	"memory":   "memory usage",
+	"nice":     "nice values",
+	"sched":    "scheduling policies",
+	"smaps":    "shared memory",
+	"threads":  "thread counts",
+}
+
+// failureKind is the category of a failed read of an attribute, see classifyFailure.
+type failureKind int
+
+const (
+	// The read succeeded
+	failureNone failureKind = iota
+	// The read was denied, i.e., EPERM or EACCES
+	failureDenied
+	// The process exited before the attribute was read, i.e., ESRCH or a missing /proc entry
+	failureVanished
+	// Any other error, e.g., an attribute the platform doesn't report
+	failureOther
+)
+
+// AttributeFailures counts the reads of an attribute and how they failed.
+type AttributeFailures struct {
+	// Number of reads denied for lack of privileges
+	Denied int
+	// Number of reads that failed for another reason
+	Other int
+	// Number of reads
+	Reads int
+	// Number of reads of processes that exited meanwhile
+	Vanished int
+}
+
+// classifyFailure categorizes the error returned while reading an attribute of a process.
+//
+// Parameters:
+//   - err: The error, nil if the read succeeded
+//
+// Returns:
+//   - failureKind: The category of the error
+func classifyFailure(err error) failureKind {
+	switch {
+	case err == nil:
+		return failureNone
+	case errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES):
+		return failureDenied
+	case isNotFound(err):
+		return failureVanished
+	}
+	return failureOther
+}
+
+// addResult counts a read of an attribute and, if it failed, how it failed.
+//
+// Parameters:
+//   - name: Name of the attribute, e.g., cpu
+//   - err: The error returned by the read, nil if it succeeded
+func (timings *CollectionTimings) addResult(name string, err error) {
+	if timings == nil {
+		return
+	}
+
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+	if timings.Failures == nil {
+		timings.Failures = make(map[string]AttributeFailures)
+	}
+	failures := timings.Failures[name]
+	failures.Reads++
+	switch classifyFailure(err) {
+	case failureDenied:
+		failures.Denied++
+	case failureVanished:
+		failures.Vanished++
+	case failureOther:
+		failures.Other++
+	}
+	timings.Failures[name] = failures
+}
+
+// DeniedAttributes returns the attributes whose reads were denied for more than half of the
+// processes, the processes that exited meanwhile left out.
+//
+// Returns:
+//   - []string: The names of the attributes, sorted, nil if none or if nothing was collected
+func (timings *CollectionTimings) DeniedAttributes() []string {
+	var (
+		names []string
+	)
+
+	if timings == nil {
+		return nil
+	}
+
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+	for name, failures := range timings.Failures {
+		if reads := failures.Reads - failures.Vanished; failures.Denied > 0 && float64(failures.Denied) > degradedRatio*float64(reads) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DegradedNotice returns the notice explaining the attributes that could not be read for most of
+// the processes for lack of privileges, e.g., note: run as root for the IO counters and file
+// descriptors of other users' processes.
+//
+// Returns:
+//   - string: The notice, empty if the processes were not collected here or nothing was denied
+func (processTree *ProcessTree) DegradedNotice() string {
+	names := processTree.Timings.Collection.DeniedAttributes()
+	if len(names) == 0 {
+		return ""
+	}
+
+	descriptions := make([]string, 0, len(names))
+	for _, name := range names {
+		if description, ok := attributeDescriptions[name]; ok {
+			name = description
+		}
+		if !slices.Contains(descriptions, name) {
+			descriptions = append(descriptions, name)
+		}
+	}
+
+	privileged := "root"
+	if runtime.GOOS == "windows" {
+		privileged = "administrator"
+	}
+	return fmt.Sprintf("note: run as %s for the %s of other users' processes", privileged, joinWords(descriptions))
+}
+
+// joinWords joins words into an English list, e.g., a, b, and c.
+//
+// Parameters:
+//   - words: The words to join
+//
+// Returns:
+//   - string: The list
+func joinWords(words []string) string {
+	switch len(words) {
+	case 0:
+		return ""
+	case 1:
+		return words[0]
+	case 2:
+		return words[0] + " and " + words[1]
+	}
+	return strings.Join(words[:len(words)-1], ", ") + ", and " + words[len(words)-1]
+}
@@ -0,0 +1,76 @@
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	assert.Equal(t, failureNone, classifyFailure(nil))
+
+	// A permission error is told apart from a process that exited meanwhile
+	assert.Equal(t, failureDenied, classifyFailure(&fs.PathError{Op: "open", Path: "/proc/1/io", Err: syscall.EACCES}))
+	assert.Equal(t, failureDenied, classifyFailure(fmt.Errorf("could not read: %w", syscall.EPERM)))
+	assert.Equal(t, failureVanished, classifyFailure(syscall.ESRCH))
+	assert.Equal(t, failureVanished, classifyFailure(process.ErrorProcessNotRunning))
+	assert.Equal(t, failureOther, classifyFailure(errors.New("not implemented yet")))
+}
+
+func TestDeniedAttributes(t *testing.T) {
+	var timings *CollectionTimings
+	timings.addResult("io", syscall.EACCES)
+	assert.Nil(t, timings.DeniedAttributes())
+
+	timings = &CollectionTimings{}
+	for range 3 {
+		timings.addResult("io", syscall.EACCES)
+		timings.addResult("cpu", nil)
+		timings.addResult("fds", nil)
+	}
+	timings.addResult("io", nil)
+	timings.addResult("fds", syscall.EPERM)
+	assert.Equal(t, AttributeFailures{Denied: 3, Reads: 4}, timings.Failures["io"])
+
+	// Only the attributes denied for most of the processes are reported
+	assert.Equal(t, []string{"io"}, timings.DeniedAttributes())
+
+	// The processes that exited meanwhile don't count
+	timings.addResult("fds", syscall.EPERM)
+	timings.addResult("fds", syscall.ESRCH)
+	timings.addResult("fds", syscall.ESRCH)
+	timings.addResult("fds", syscall.EPERM)
+	timings.addResult("fds", syscall.EPERM)
+	assert.Equal(t, []string{"fds", "io"}, timings.DeniedAttributes())
+}
+
+func TestDegradedNotice(t *testing.T) {
+	processTree := &ProcessTree{}
+	assert.Empty(t, processTree.DegradedNotice())
+
+	processTree.Timings.Collection = &CollectionTimings{}
+	processTree.Timings.Collection.addResult("cpu", nil)
+	assert.Empty(t, processTree.DegradedNotice())
+
+	processTree.Timings.Collection.addResult("memory", syscall.EACCES)
+	processTree.Timings.Collection.addResult("cwd", syscall.EACCES)
+	processTree.Timings.Collection.addResult("ctxswitches", syscall.EACCES)
+	privileged := "root"
+	if runtime.GOOS == "windows" {
+		privileged = "administrator"
+	}
+	assert.Equal(t, "note: run as "+privileged+" for the ctxswitches, working directories, and memory usage of other users' processes", processTree.DegradedNotice())
+}
+
+func TestJoinWords(t *testing.T) {
+	assert.Empty(t, joinWords(nil))
+	assert.Equal(t, "cpu", joinWords([]string{"cpu"}))
+	assert.Equal(t, "cpu and memory", joinWords([]string{"cpu", "memory"}))
+	assert.Equal(t, "cpu, memory, and io", joinWords([]string{"cpu", "memory", "io"}))
+}
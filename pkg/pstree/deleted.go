@@ -0,0 +1,64 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the detection of processes running a deleted executable, e.g., daemons that
+// were not restarted after a package upgrade replaced their binary. On Linux, the kernel appends
+// " (deleted)" to the /proc/<pid>/exe link once the executable is unlinked. The suffix is removed
+// from the command, the process is flagged with ExeDeleted, and the --deleted-marker is shown after
+// its command. --only-deleted narrows the tree down to those processes and their ancestors. Other
+// platforms don't report it, so no process is flagged there.
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DeletedExeSuffix is appended by the Linux kernel to the executable link of a process whose
+// executable was deleted.
+const DeletedExeSuffix = " (deleted)"
+
+// isDeletedExe determines whether the executable link of a process points to a deleted file.
+//
+// The suffix alone is not enough, since an executable may really be named like that. The link is
+// only taken as deleted when no file exists under its literal name, or when that file is not the
+// executable of the process. A binary installed under the name without the suffix, e.g., by the
+// package upgrade that deleted the old one, doesn't matter.
+//
+// Parameters:
+//   - link: The raw target of the executable link, e.g., "/usr/sbin/sshd (deleted)"
+//   - exe: The file the executable link resolves to, nil if it could not be read
+//
+// Returns:
+//   - bool: true if the executable was deleted, false otherwise
+func isDeletedExe(link string, exe os.FileInfo) bool {
+	if !strings.HasSuffix(link, DeletedExeSuffix) {
+		return false
+	}
+	info, err := os.Stat(link)
+	if err != nil {
+		return true
+	}
+	return exe != nil && !os.SameFile(info, exe)
+}
+
+// markDeleted unmarks the processes that don't run a deleted executable, keeping the ancestors
+// of those that do.
+func (processTree *ProcessTree) markDeleted() {
+	processTree.Logger.Debug("Entering processTree.markDeleted()")
+	processTree.narrowMarked(func(node *Process) bool {
+		return !node.IsThread && node.ExeDeleted
+	}, "runs a deleted executable")
+}
+
+// flagDeleted appends DisplayOptions.DeletedMarker to the command of a process running a deleted
+// executable.
+//
+// Parameters:
+//   - value: Pointer to the command to be flagged (modified in place)
+//   - pidIndex: Index of the process in the Nodes array
+func (processTree *ProcessTree) flagDeleted(value *string, pidIndex int) {
+	if processTree.DisplayOptions.DeletedMarker != "" && processTree.Nodes[pidIndex].ExeDeleted && !processTree.Nodes[pidIndex].IsThread {
+		*value = fmt.Sprintf("%s %s", *value, processTree.DisplayOptions.DeletedMarker)
+	}
+}
@@ -0,0 +1,72 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDeletedExe(t *testing.T) {
+	dir := t.TempDir()
+	literal := filepath.Join(dir, "tool (deleted)")
+	other := filepath.Join(dir, "tool")
+	require.NoError(t, os.WriteFile(literal, []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.WriteFile(other, []byte("#!/bin/sh\n"), 0o755))
+	literalInfo, err := os.Stat(literal)
+	require.NoError(t, err)
+	otherInfo, err := os.Stat(other)
+	require.NoError(t, err)
+
+	assert.False(t, isDeletedExe("/usr/sbin/sshd", nil))
+	assert.False(t, isDeletedExe(other, otherInfo))
+
+	// The binary was deleted, and a new one may have been installed under its name
+	assert.True(t, isDeletedExe(filepath.Join(dir, "gone"+DeletedExeSuffix), nil))
+	assert.True(t, isDeletedExe(other+DeletedExeSuffix, otherInfo))
+
+	// An executable really named "tool (deleted)" is still there
+	assert.False(t, isDeletedExe(literal, literalInfo))
+	assert.False(t, isDeletedExe(literal, nil))
+
+	// A file of that name exists, but the process runs another one that was deleted
+	assert.True(t, isDeletedExe(literal, otherInfo))
+}
+
+// deletedTestProcesses returns daemons of which one still runs the binary replaced by an upgrade.
+func deletedTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "/sbin/init"},
+		{PID: 100, PPID: 1, Command: "/usr/sbin/sshd", ExeDeleted: true},
+		{PID: 101, PPID: 100, Command: "/usr/sbin/sshd"},
+		{PID: 102, PPID: 100, Command: "/usr/sbin/sshd"},
+		{PID: 103, PPID: 100, Command: "/usr/sbin/sshd", ExeDeleted: true},
+		{PID: 200, PPID: 1, Command: "/usr/sbin/cron"},
+	}
+}
+
+func TestMarkDeleted(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), deletedTestProcesses(), DisplayOptions{OnlyDeleted: true})
+	processTree.MarkProcesses()
+	assert.Equal(t, []int32{1, 100, 103}, markedPIDs(processTree))
+}
+
+func TestShowDeleted(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		return renderTree(t, deletedTestProcesses(), displayOptions)
+	}
+
+	output := render(DisplayOptions{DeletedMarker: "[deleted]"})
+	assert.Contains(t, output, "- sshd [deleted] \n")
+	assert.NotContains(t, output, "cron [deleted]")
+
+	// The processes running the deleted binary are not grouped with the others
+	output = render(DisplayOptions{CompactMode: true, DeletedMarker: "[deleted]"})
+	assert.Contains(t, output, "2*[sshd]")
+	assert.Contains(t, output, "sshd [deleted]")
+
+	output = render(DisplayOptions{})
+	assert.NotContains(t, output, "[deleted]")
+}
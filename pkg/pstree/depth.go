@@ -0,0 +1,119 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the depth of each process in the tree. The depth is assigned once the tree
+// is built, counting from 0 at the root the process is printed under, i.e., the --pid process
+// when one is given. --show-depth prefixes each line with it, and --depth-stats prints the number
+// of displayed processes at each level after the tree. Like --level, levels are counted from the
+// printed roots, so the processes below the --level limit are not counted.
+package pstree
+
+import (
+	"fmt"
+	"slices"
+)
+
+// AssignDepths sets the Depth of every process.
+//
+// The roots of the tree are at depth 0. The subtree of each requested root PID is assigned again
+// starting from 0, since it is printed as a tree of its own. It must be called after BuildTree and
+// AttachOrphans.
+func (processTree *ProcessTree) AssignDepths() {
+	var (
+		pid       int32
+		pidIndex  int
+		rootDepth int
+		rootPIDs  []int32
+	)
+
+	for pidIndex = range processTree.Nodes {
+		if processTree.Nodes[pidIndex].Parent == -1 {
+			processTree.assignDepth(pidIndex, 0)
+		}
+	}
+
+	rootPIDs = slices.Clone(processTree.RootPIDs)
+	slices.Sort(rootPIDs)
+	// The children of a hidden root are printed as the roots, at depth 0
+	rootDepth = 0
+	if processTree.DisplayOptions.NoRootLine {
+		rootDepth = -1
+	}
+	for _, pid = range rootPIDs {
+		if rootIndex, ok := processTree.PidToIndexMap[pid]; ok {
+			processTree.assignDepth(rootIndex, rootDepth)
+		}
+	}
+}
+
+// assignDepth sets the Depth of a process and all of its descendants.
+//
+// Parameters:
+//   - pidIndex: Index of the process
+//   - depth: The depth of the process
+func (processTree *ProcessTree) assignDepth(pidIndex int, depth int) {
+	var (
+		childPidIndex int
+	)
+
+	processTree.Nodes[pidIndex].Depth = depth
+	childPidIndex = processTree.Nodes[pidIndex].Child
+	for childPidIndex != -1 {
+		processTree.assignDepth(childPidIndex, depth+1)
+		childPidIndex = processTree.Nodes[childPidIndex].Sister
+	}
+}
+
+// depthLabel returns the depth shown in front of the line of a process with --show-depth.
+//
+// Parameters:
+//   - pidIndex: Index of the process
+//
+// Returns:
+//   - string: The depth followed by a space, e.g., " 2 ", or an empty string without --show-depth
+func (processTree *ProcessTree) depthLabel(pidIndex int) string {
+	if !processTree.DisplayOptions.ShowDepth {
+		return ""
+	}
+	return fmt.Sprintf("%2d ", processTree.Nodes[pidIndex].Depth)
+}
+
+// DepthStats counts the displayed processes at each level of the tree.
+//
+// Like the summary, every member of a compacted group is counted, while the thread nodes of
+// --show-threads-tree and the orphans node are not. Processes below DisplayOptions.MaxDepth are
+// not displayed and not counted. It should be called after MarkProcesses and DropUnmarked.
+//
+// Returns:
+//   - []int: The number of processes at each depth, starting at depth 0
+func (processTree *ProcessTree) DepthStats() []int {
+	var (
+		counts []int
+		node   *Process
+	)
+
+	for _, node = range processTree.Nodes {
+		if !node.Print || node.IsThread || isSyntheticNode(node) || node.Depth > processTree.DisplayOptions.MaxDepth {
+			continue
+		}
+		for len(counts) <= node.Depth {
+			counts = append(counts, 0)
+		}
+		counts[node.Depth]++
+	}
+
+	return counts
+}
+
+// PrintDepthStats writes the number of displayed processes at each level to the output of the
+// tree, one line per level, e.g., "level 1: 12 processes".
+//
+// Returns:
+//   - error: ErrBrokenPipe if the reader of the output went away, or any other error encountered while writing a line
+func (processTree *ProcessTree) PrintDepthStats() error {
+	for depth, count := range processTree.DepthStats() {
+		if err := processTree.writeLine(fmt.Sprintf("level %d: %s", depth, pluralize(count, "process", "processes"))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// depthTestProcesses returns a tree three levels deep:
+//
+//	init(1) -+- sshd(100) --- bash(101) --- vim(102)
+//	         |- cron(200)
+//	         \- cron(300)
+func depthTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+		{PID: 101, PPID: 100, Command: "bash"},
+		{PID: 102, PPID: 101, Command: "vim"},
+		{PID: 200, PPID: 1, Command: "cron"},
+		{PID: 300, PPID: 1, Command: "cron"},
+	}
+}
+
+func TestAssignDepths(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), depthTestProcesses(), DisplayOptions{})
+	depths := map[int32]int{}
+	for _, node := range processTree.Nodes {
+		depths[node.PID] = node.Depth
+	}
+	assert.Equal(t, map[int32]int{1: 0, 100: 1, 101: 2, 102: 3, 200: 1, 300: 1}, depths)
+
+	// The subtree of a --pid root is counted from the requested process
+	processTree = NewProcessTree(0, setupTestLogger(), depthTestProcesses(), DisplayOptions{RootPIDs: []int32{100}})
+	assert.Equal(t, 0, processTree.Nodes[processTree.PidToIndexMap[100]].Depth)
+	assert.Equal(t, 2, processTree.Nodes[processTree.PidToIndexMap[102]].Depth)
+}
+
+func TestShowDepth(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		displayOptions.ShowDepth = true
+		return renderTree(t, depthTestProcesses(), displayOptions)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(render(DisplayOptions{}), "\n"), "\n")
+	require.Len(t, lines, 6)
+	assert.Regexp(t, `^ 0 -\+- init`, lines[0])
+	assert.Regexp(t, `^ 1 .*sshd`, lines[1])
+	assert.Regexp(t, `^ 3 .*vim`, lines[3])
+
+	// Compacted groups show the depth of their members
+	output := render(DisplayOptions{CompactMode: true})
+	assert.Regexp(t, `(?m)^ 1 .*2\*\[cron\]`, output)
+
+	// Continuation lines are indented past the depth
+	processes := depthTestProcesses()
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 10, ScreenWidth: 30, ShowArguments: true, ShowDepth: true, WrapLines: true})
+	processTree.Nodes[processTree.PidToIndexMap[102]].Args = []string{strings.Repeat("x", 40)}
+	output = renderProcessTree(t, processTree)
+	lines = strings.Split(output, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "vim") {
+			assert.True(t, strings.HasPrefix(lines[i+1], "   "), "the continuation is indented past the depth: %q", lines[i+1])
+			assert.LessOrEqual(t, len(line), 30)
+		}
+	}
+}
+
+func TestDepthStats(t *testing.T) {
+	stats := func(displayOptions DisplayOptions) []int {
+		processTree := NewProcessTree(0, setupTestLogger(), depthTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		processTree.DropUnmarked()
+		return processTree.DepthStats()
+	}
+
+	assert.Equal(t, []int{1, 3, 1, 1}, stats(DisplayOptions{MaxDepth: 10}))
+
+	// The processes below --level are not displayed, so they are not counted
+	assert.Equal(t, []int{1, 3}, stats(DisplayOptions{MaxDepth: 1}))
+
+	// Only the displayed processes are counted
+	assert.Equal(t, []int{1, 1, 1, 1}, stats(DisplayOptions{Contains: "vim", MaxDepth: 10}))
+	assert.Equal(t, []int{1, 1, 1}, stats(DisplayOptions{MaxDepth: 10, RootPIDs: []int32{100}}))
+
+	output := renderTree(t, depthTestProcesses(), DisplayOptions{CompactMode: true, ShowDepthStats: true})
+	assert.True(t, strings.HasSuffix(output, "level 0: 1 process\nlevel 1: 3 processes\nlevel 2: 1 process\nlevel 3: 1 process\n"), output)
+}
@@ -0,0 +1,185 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the delta mode (--diff), which compares two snapshots of the processes,
+// e.g., taken before and after a deploy. MergeSnapshots combines them into a single process
+// list: processes only in the second snapshot are marked [new], processes only in the first are
+// kept and marked [gone], and the processes in both show how much their CPU usage and resident
+// memory changed. Processes are matched by their PID and creation time, so a PID reused by
+// another process is reported as one process gone and another new.
+package pstree
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// DiffState records how a process changed between the snapshots compared with --diff.
+type DiffState int
+
+const (
+	// The process list was not compared
+	DiffNone DiffState = iota
+	// The process is in both snapshots
+	DiffSurvivor
+	// The process is only in the second snapshot
+	DiffNew
+	// The process is only in the first snapshot
+	DiffGone
+)
+
+// DiffNewMarker is shown after the command of a process that is only in the second snapshot.
+const DiffNewMarker = "[new]"
+
+// DiffGoneMarker is shown after the command of a process that is only in the first snapshot.
+const DiffGoneMarker = "[gone]"
+
+// diffKey identifies a process across snapshots, the PID alone may have been reused.
+type diffKey struct {
+	PID        int32
+	CreateTime int64
+}
+
+// MergeSnapshots combines two snapshots of the processes into a single process list.
+//
+// The processes of the second snapshot are returned with their DiffState set, followed by the
+// processes that are only in the first snapshot, all sorted by PID. When a gone process shares
+// its PID with a new one, the gone process is placed first, so the children are attached to the
+// process that is still running.
+//
+// Parameters:
+//   - before: The processes of the first snapshot
+//   - after: The processes of the second snapshot
+//
+// Returns:
+//   - []Process: The combined processes
+func MergeSnapshots(before []Process, after []Process) []Process {
+	var (
+		beforeByKey map[diffKey]int
+		merged      []Process
+		seen        map[diffKey]bool
+	)
+
+	beforeByKey = make(map[diffKey]int, len(before))
+	for i := range before {
+		beforeByKey[diffKey{PID: before[i].PID, CreateTime: before[i].CreateTime}] = i
+	}
+
+	merged = make([]Process, 0, len(after)+len(before))
+	seen = make(map[diffKey]bool, len(after))
+	for _, proc := range after {
+		key := diffKey{PID: proc.PID, CreateTime: proc.CreateTime}
+		seen[key] = true
+		if i, ok := beforeByKey[key]; ok {
+			proc.DiffState = DiffSurvivor
+			// A value missing from either snapshot leaves its change at 0 rather than the whole value
+			if proc.HasCPU && before[i].HasCPU {
+				proc.DiffCPUPercent = proc.CPUPercent - before[i].CPUPercent
+			}
+			if proc.HasMemory && before[i].HasMemory {
+				proc.DiffRSS = int64(residentSize(&proc)) - int64(residentSize(&before[i]))
+			}
+		} else {
+			proc.DiffState = DiffNew
+		}
+		merged = append(merged, proc)
+	}
+
+	for _, proc := range before {
+		if !seen[diffKey{PID: proc.PID, CreateTime: proc.CreateTime}] {
+			proc.DiffState = DiffGone
+			merged = append(merged, proc)
+		}
+	}
+
+	slices.SortStableFunc(merged, func(a, b Process) int {
+		if c := cmp.Compare(a.PID, b.PID); c != 0 {
+			return c
+		}
+		// A gone process goes before the new process that reused its PID
+		return cmp.Compare(util.BtoI(b.DiffState == DiffGone), util.BtoI(a.DiffState == DiffGone))
+	})
+
+	return merged
+}
+
+// residentSize returns the resident set size of a process, 0 if it could not be read.
+func residentSize(node *Process) uint64 {
+	return MemoryValue(node, "rss")
+}
+
+// diffStateName returns the name of a DiffState used in the flat output.
+//
+// Parameters:
+//   - state: The state to name
+//
+// Returns:
+//   - string: new, gone, or same, or an empty string if the process list was not compared
+func diffStateName(state DiffState) string {
+	switch state {
+	case DiffSurvivor:
+		return "same"
+	case DiffNew:
+		return "new"
+	case DiffGone:
+		return "gone"
+	}
+	return ""
+}
+
+// formatDiffField formats the change of the CPU usage and resident memory of a process that is
+// in both snapshots, e.g., (Δc:+1.25%, Δm:-3.0 MiB), with the change of the CPU usage in the --cpu-mode.
+//
+// Parameters:
+//   - node: The process
+//
+// Returns:
+//   - string: The formatted field, or an empty string if nothing changed or the process is not in both snapshots
+func (processTree *ProcessTree) formatDiffField(node *Process) string {
+	return processTree.formatDiffChange(node.DiffState, node.DiffCPUPercent, node.DiffRSS)
+}
+
+// formatDiffChange formats a change of the CPU usage and resident memory, of a process or of the
+// summed members of a compacted group, see formatDiffField.
+//
+// Parameters:
+//   - state: The diff state of the process, or of the members of the group
+//   - cpuDelta: The change of the CPU percent
+//   - rssDelta: The change of the resident memory in bytes
+//
+// Returns:
+//   - string: The formatted field, or an empty string if nothing changed or the state is not DiffSurvivor
+func (processTree *ProcessTree) formatDiffChange(state DiffState, cpuDelta float64, rssDelta int64) string {
+	var sign string
+
+	if state != DiffSurvivor || (cpuDelta == 0 && rssDelta == 0) {
+		return ""
+	}
+
+	sign = "+"
+	if rssDelta < 0 {
+		sign = "-"
+		rssDelta = -rssDelta
+	}
+	return fmt.Sprintf("(Δc:%+.2f%%, Δm:%s%s)", processTree.normalizeCPU(cpuDelta), sign, util.FormatByteSize(uint64(rssDelta), "auto"))
+}
+
+// flagDiff appends DiffNewMarker or DiffGoneMarker to the command of a process that is only in
+// one of the snapshots. With colors, a gone process is also dimmed and struck through.
+//
+// Parameters:
+//   - value: Pointer to the command to be flagged (modified in place)
+//   - pidIndex: Index of the process in the Nodes array
+func (processTree *ProcessTree) flagDiff(value *string, pidIndex int) {
+	switch processTree.Nodes[pidIndex].DiffState {
+	case DiffNew:
+		*value = fmt.Sprintf("%s %s", *value, DiffNewMarker)
+	case DiffGone:
+		*value = fmt.Sprintf("%s %s", *value, DiffGoneMarker)
+		if processTree.colorEnabled() {
+			*value = AnsiDim + AnsiStrikethrough + *value + AnsiStrikethroughOff + AnsiNormalIntensity
+		}
+	}
+}
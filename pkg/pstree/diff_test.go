@@ -0,0 +1,123 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// diffTestSnapshots returns the processes before and after a deploy, where the old worker
+// exited and its PID was reused by a new one:
+//
+//	before: init(1) -+- sshd(100) --- worker(200)
+//	after:  init(1) -+- sshd(100) -+- worker(200, started later)
+//	                               \- migrate(300)
+func diffTestSnapshots() ([]Process, []Process) {
+	mib := uint64(1024 * 1024)
+	before := []Process{
+		{PID: 1, PPID: 0, Command: "init", CreateTime: 10, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4 * mib}},
+		{PID: 100, PPID: 1, Command: "sshd", CreateTime: 20, HasCPU: true, CPUPercent: 1, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 10 * mib}},
+		{PID: 200, PPID: 100, Command: "worker", CreateTime: 30, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 64 * mib}},
+	}
+	after := []Process{
+		{PID: 1, PPID: 0, Command: "init", CreateTime: 10, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4 * mib}},
+		{PID: 100, PPID: 1, Command: "sshd", CreateTime: 20, HasCPU: true, CPUPercent: 3.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 7 * mib}},
+		{PID: 200, PPID: 100, Command: "worker", CreateTime: 90, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 32 * mib}},
+		{PID: 300, PPID: 100, Command: "migrate", CreateTime: 95},
+	}
+	return before, after
+}
+
+func TestMergeSnapshots(t *testing.T) {
+	merged := MergeSnapshots(diffTestSnapshots())
+
+	require.Len(t, merged, 5)
+	states := []DiffState{}
+	for _, proc := range merged {
+		states = append(states, proc.DiffState)
+	}
+	// The reused PID is one process gone and another new, the gone one first
+	assert.Equal(t, []DiffState{DiffSurvivor, DiffSurvivor, DiffGone, DiffNew, DiffNew}, states)
+	assert.Equal(t, []int64{30, 90}, []int64{merged[2].CreateTime, merged[3].CreateTime})
+
+	assert.Equal(t, 2.5, merged[1].DiffCPUPercent)
+	assert.Equal(t, int64(-3*1024*1024), merged[1].DiffRSS)
+	assert.Zero(t, merged[0].DiffRSS)
+}
+
+func TestFormatDiffField(t *testing.T) {
+	processTree := &ProcessTree{}
+	assert.Equal(t, "(Δc:+2.50%, Δm:-3.0 MiB)", processTree.formatDiffField(&Process{DiffState: DiffSurvivor, DiffCPUPercent: 2.5, DiffRSS: -3 * 1024 * 1024}))
+	assert.Equal(t, "(Δc:-0.25%, Δm:+0.0 B)", processTree.formatDiffField(&Process{DiffState: DiffSurvivor, DiffCPUPercent: -0.25}))
+	assert.Equal(t, "", processTree.formatDiffField(&Process{DiffState: DiffSurvivor}), "nothing changed")
+	assert.Equal(t, "", processTree.formatDiffField(&Process{DiffState: DiffNew, DiffRSS: 1024}))
+}
+
+func TestShowDiff(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		displayOptions.ScreenWidth = 120
+		displayOptions.ShowDiff = true
+		return renderTree(t, MergeSnapshots(diffTestSnapshots()), displayOptions)
+	}
+
+	output := render(DisplayOptions{ShowPIDs: true})
+	assert.Contains(t, output, "(1) init \n")
+	assert.Contains(t, output, "(100) (Δc:+2.50%, Δm:-3.0 MiB) sshd")
+	assert.Contains(t, output, "(200) worker [gone]")
+	assert.Contains(t, output, "(200) worker [new]")
+	assert.Contains(t, output, "(300) migrate [new]")
+
+	// With colors, the gone processes are dimmed and struck through
+	output = render(DisplayOptions{ColorSupport: true, ColorizeOutput: true, ColorCount: 256})
+	assert.Contains(t, output, AnsiDim+AnsiStrikethrough+"worker [gone]"+AnsiStrikethroughOff+AnsiNormalIntensity)
+
+	// New and gone processes are never grouped with each other in compact mode
+	output = render(DisplayOptions{CompactMode: true})
+	assert.Contains(t, output, "worker [gone]")
+	assert.Contains(t, output, "worker [new]")
+}
+
+func TestShowDiffCompactMode(t *testing.T) {
+	mib := uint64(1024 * 1024)
+	before := []Process{
+		{PID: 1, PPID: 0, Command: "init", CreateTime: 10},
+		{PID: 100, PPID: 1, Command: "worker", CreateTime: 20, HasCPU: true, CPUPercent: 1, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 10 * mib}},
+		{PID: 101, PPID: 1, Command: "worker", CreateTime: 20, HasCPU: true, CPUPercent: 1, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 10 * mib}},
+		{PID: 200, PPID: 1, Command: "cron", CreateTime: 30, HasCPU: true, CPUPercent: 1, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4 * mib}},
+		{PID: 201, PPID: 1, Command: "cron", CreateTime: 30, HasCPU: true, CPUPercent: 2, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4 * mib}},
+	}
+	after := []Process{
+		{PID: 1, PPID: 0, Command: "init", CreateTime: 10},
+		{PID: 100, PPID: 1, Command: "worker", CreateTime: 20, HasCPU: true, CPUPercent: 2, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 12 * mib}},
+		{PID: 101, PPID: 1, Command: "worker", CreateTime: 20, HasCPU: true, CPUPercent: 1.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 9 * mib}},
+		{PID: 102, PPID: 1, Command: "worker", CreateTime: 90},
+		{PID: 200, PPID: 1, Command: "cron", CreateTime: 30, HasCPU: true, CPUPercent: 2, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 5 * mib}},
+		{PID: 201, PPID: 1, Command: "cron", CreateTime: 30, HasCPU: true, CPUPercent: 1, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 3 * mib}},
+	}
+	output := renderTree(t, MergeSnapshots(before, after), DisplayOptions{CompactMode: true, ScreenWidth: 120, ShowDiff: true})
+
+	// The changes of the remaining members are summed, the new worker is not grouped with them
+	assert.Contains(t, output, "- (Δc:+1.50%, Δm:+1.0 MiB) worker───2*[worker] \n")
+	assert.Contains(t, output, "- worker [new] \n")
+
+	// Changes that cancel each other out are not shown
+	assert.Contains(t, output, "- cron───2*[cron] \n")
+}
+
+func TestWriteFlatDiff(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), MergeSnapshots(diffTestSnapshots()), DisplayOptions{ShowPIDs: true, ShowDiff: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	require.NoError(t, processTree.WriteFlat(&output, ',', []int{0}))
+	assert.Equal(t, "pid,depth,command,diff,cpu%_delta,rss_delta\n"+
+		"1,0,init,same,0.00,0\n"+
+		"100,1,sshd,same,2.50,-3145728\n"+
+		"200,2,worker,gone,,\n"+
+		"200,2,worker,new,,\n"+
+		"300,2,migrate,new,,\n", output.String())
+}
@@ -0,0 +1,170 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the Graphviz output mode (--output=dot). The displayed processes are
+// written as a directed graph with an edge from each parent to its children, so the output
+// can be rendered with e.g. `pstree --output=dot | dot -Tsvg > pstree.svg`.
+package pstree
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// dotAttributeColors maps each --color-attr attribute to the Graphviz fill color of each level
+// returned by attributeLevel, mirroring the colors used in the terminal.
+var dotAttributeColors = map[string][]string{
+	"age": {"red", "yellow", "cyan", "green"},
+	"cpu": {"green", "yellow", "red"},
+	"fds": {"green", "yellow", "red"},
+	"mem": {"green", "yellow", "red"},
+}
+
+// WriteDot writes the displayed processes as a Graphviz digraph.
+//
+// Each node is labeled with the command and PID of the process, followed by the owner, CPU
+// and memory usage when those display options are enabled. With --color-attr the nodes are
+// filled with the color matching the attribute thresholds used in the terminal. In compact
+// mode, identical processes collapse into a single node labeled "N*[command]".
+//
+// Parameters:
+//   - writer: Destination of the output
+//   - rootIndices: Indices of the processes at the top of each tree, as returned by RootIndices
+//
+// Returns:
+//   - error: Any error encountered while writing the graph
+func (processTree *ProcessTree) WriteDot(writer io.Writer, rootIndices []int) error {
+	var (
+		builder   strings.Builder
+		err       error
+		rootIndex int
+	)
+
+	if processTree.DisplayOptions.CompactMode {
+		processTree.InitCompactMode()
+	}
+
+	builder.WriteString("digraph pstree {\n")
+	builder.WriteString("\tnode [shape=box];\n")
+	for _, rootIndex = range rootIndices {
+		processTree.writeDotNodes(&builder, rootIndex, 0)
+	}
+	builder.WriteString("}\n")
+
+	_, err = io.WriteString(writer, builder.String())
+	return err
+}
+
+// writeDotNodes recursively writes the node of a process, the edges to its children and their nodes.
+//
+// Parameters:
+//   - builder: Builder collecting the graph
+//   - pidIndex: Index of the process to write
+//   - depth: Depth of the process relative to the root of its tree
+func (processTree *ProcessTree) writeDotNodes(builder *strings.Builder, pidIndex int, depth int) {
+	var (
+		childPidIndex int
+		node          *Process
+	)
+
+	node = processTree.Nodes[pidIndex]
+	if !node.Print {
+		return
+	}
+
+	fmt.Fprintf(builder, "\t%d [%s];\n", node.PID, processTree.dotNodeAttributes(pidIndex))
+
+	if processTree.DisplayOptions.MaxDepth > 0 && depth >= processTree.DisplayOptions.MaxDepth {
+		return
+	}
+
+	childPidIndex = node.Child
+	for childPidIndex != -1 {
+		if !(processTree.DisplayOptions.CompactMode && ShouldSkipProcess(childPidIndex)) && processTree.Nodes[childPidIndex].Print {
+			fmt.Fprintf(builder, "\t%d -> %d;\n", node.PID, processTree.Nodes[childPidIndex].PID)
+			processTree.writeDotNodes(builder, childPidIndex, depth+1)
+		}
+		childPidIndex = processTree.Nodes[childPidIndex].Sister
+	}
+}
+
+// dotNodeAttributes builds the Graphviz attribute list of a process node.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - string: The attributes, e.g., label="bash\n100", style=filled, fillcolor=green
+func (processTree *ProcessTree) dotNodeAttributes(pidIndex int) string {
+	var (
+		attributes  []string
+		colors      []string
+		cpuPercent  float64
+		hasMemory   bool
+		level       int
+		lines       []string
+		memoryUsage uint64
+		node        *Process
+	)
+
+	node = processTree.Nodes[pidIndex]
+	cpuPercent = processTree.cpuPercentOf(node)
+	hasMemory = node.HasMemory
+	memoryUsage = processTree.memoryValue(node)
+
+	if processTree.DisplayOptions.CompactMode {
+		count, groupPIDs, _, groupCPUPercent, groupMemoryUsage, _ := processTree.GetProcessCount(pidIndex)
+		if count > 1 {
+			// The group collapses into this node, labeled like the compacted tree
+			cpuPercent = groupCPUPercent
+			memoryUsage = groupMemoryUsage
+			if group, ok := processTree.getProcessGroup(pidIndex); ok {
+				hasMemory = group.HasMemory
+			}
+			lines = append(lines, FormatCompactOutput(node.Command, count, groupPIDs, false, processTree.DisplayOptions.CommandFormat), strings.Join(PIDsToString(groupPIDs), ","))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, FormatCommand(processTree.commandName(node), processTree.DisplayOptions.CommandFormat), util.Int32toStr(node.PID))
+	}
+
+	if processTree.DisplayOptions.ShowOwner {
+		lines = append(lines, processTree.ownerName(node))
+	}
+	if processTree.DisplayOptions.ShowCpuPercent && cpuPercent < 0 {
+		lines = append(lines, "c:-")
+	} else if processTree.DisplayOptions.ShowCpuPercent {
+		lines = append(lines, fmt.Sprintf("c:%.2f%%", cpuPercent))
+	}
+	if processTree.DisplayOptions.ShowMemoryUsage && !hasMemory {
+		lines = append(lines, processTree.memoryLabel()+":-")
+	} else if processTree.DisplayOptions.ShowMemoryUsage {
+		lines = append(lines, fmt.Sprintf("%s:%s", processTree.memoryLabel(), util.FormatByteSize(memoryUsage, processTree.DisplayOptions.MemoryUnit)))
+	}
+
+	for i := range lines {
+		lines[i] = dotEscape(lines[i])
+	}
+	attributes = append(attributes, fmt.Sprintf("label=\"%s\"", strings.Join(lines, "\\n")))
+
+	colors = dotAttributeColors[processTree.DisplayOptions.ColorAttr]
+	level = processTree.attributeLevel(node)
+	if level >= 0 && level < len(colors) {
+		attributes = append(attributes, "style=filled", fmt.Sprintf("fillcolor=%s", colors[level]))
+	}
+
+	return strings.Join(attributes, ", ")
+}
+
+// dotEscape escapes a string for use inside a double-quoted Graphviz string.
+//
+// Parameters:
+//   - value: The string to escape
+//
+// Returns:
+//   - string: The escaped string
+func dotEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
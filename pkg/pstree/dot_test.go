@@ -0,0 +1,60 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriteDot tests that nodes and edges follow the tree, with attribute colors applied
+func TestWriteDot(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", HasCPU: true, CPUPercent: 0.5},
+		{PID: 100, PPID: 1, Command: "sshd", Username: "root", HasCPU: true, CPUPercent: 7.0},
+		{PID: 101, PPID: 100, Command: "say \"hi\"", Username: "alice", HasCPU: true, CPUPercent: 20.0},
+	}
+	displayOptions := DisplayOptions{ColorAttr: "cpu", ShowCpuPercent: true, ShowOwner: true}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	err := processTree.WriteDot(&output, []int{0})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "digraph pstree {\n"+
+		"\tnode [shape=box];\n"+
+		"\t1 [label=\"init\\n1\\nroot\\nc:0.50%\", style=filled, fillcolor=green];\n"+
+		"\t1 -> 100;\n"+
+		"\t100 [label=\"sshd\\n100\\nroot\\nc:7.00%\", style=filled, fillcolor=yellow];\n"+
+		"\t100 -> 101;\n"+
+		"\t101 [label=\"say \\\"hi\\\"\\n101\\nalice\\nc:20.00%\", style=filled, fillcolor=red];\n"+
+		"}\n", output.String())
+}
+
+// TestWriteDotCompact tests that identical processes collapse into one node in compact mode
+func TestWriteDotCompact(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "/usr/sbin/nginx"},
+		{PID: 200, PPID: 1, Command: "/usr/sbin/nginx"},
+		{PID: 300, PPID: 1, Command: "cron"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{CompactMode: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	err := processTree.WriteDot(&output, []int{0})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "digraph pstree {\n"+
+		"\tnode [shape=box];\n"+
+		"\t1 [label=\"init\\n1\"];\n"+
+		"\t1 -> 100;\n"+
+		"\t100 [label=\"2*[nginx]\\n100,200\"];\n"+
+		"\t1 -> 300;\n"+
+		"\t300 [label=\"cron\\n300\"];\n"+
+		"}\n", output.String())
+}
@@ -0,0 +1,88 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the environment filter (--env-contains), which finds the processes started
+// with a given environment variable, e.g., the workers running with FEATURE_X=on. Reading the
+// environment of a process is expensive and requires permissions, so it is not gathered in the
+// initial snapshot. Instead, like the resource usage filters, the filter is applied after the
+// cheaper filters have marked the processes to display, and only the environments of those
+// processes are read. A process whose environment cannot be read never matches.
+package pstree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// markEnvironment narrows the marked processes down to those whose environment matches
+// DisplayOptions.EnvContains, keeping their ancestors marked so the tree remains connected.
+func (processTree *ProcessTree) markEnvironment() {
+	processTree.Logger.Debug("Entering processTree.markEnvironment()")
+	processTree.narrowMarked(func(node *Process) bool {
+		if node.IsThread {
+			// Thread nodes share the environment of their process
+			return false
+		}
+		processTree.loadEnvironment(node)
+		_, ok := processTree.envMatch(node)
+		return ok
+	}, fmt.Sprintf("has %s in its environment", processTree.DisplayOptions.EnvContains))
+}
+
+// loadEnvironment reads the environment of a process unless it was already read, e.g., from a
+// snapshot or by a previous call. An environment that cannot be read is stored as empty.
+//
+// Parameters:
+//   - node: The process whose environment is read
+func (processTree *ProcessTree) loadEnvironment(node *Process) {
+	var (
+		environment []string
+		err         error
+		proc        *process.Process
+	)
+
+	if node.Environment != nil {
+		return
+	}
+
+	proc, err = process.NewProcess(node.PID)
+	if err == nil {
+		environment, err = ProcessEnvironment(proc)
+	}
+	if err != nil {
+		processTree.Logger.Debug(fmt.Sprintf("Unable to read the environment of PID %d: %v", node.PID, err))
+		environment = []string{}
+	}
+	node.Environment = environment
+}
+
+// envMatch finds the environment variable of a process matching DisplayOptions.EnvContains.
+//
+// A KEY=VALUE pattern matches the variable with exactly that value, while a KEY pattern matches
+// the variable with any value, including an empty one.
+//
+// Parameters:
+//   - node: The process to check, whose environment was read with loadEnvironment
+//
+// Returns:
+//   - string: The matching variable, e.g., FEATURE_X=on
+//   - bool: true if a variable matches, false otherwise or if the environment was not read
+func (processTree *ProcessTree) envMatch(node *Process) (string, bool) {
+	var (
+		pattern  string
+		variable string
+	)
+
+	pattern = processTree.DisplayOptions.EnvContains
+	for _, variable = range node.Environment {
+		if strings.Contains(pattern, "=") {
+			if variable == pattern {
+				return variable, true
+			}
+		} else if strings.HasPrefix(variable, pattern+"=") {
+			return variable, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,73 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// envTestProcesses returns workers started with different feature flags, and one whose
+// environment cannot be read:
+//
+//	init(1) -+- supervisor(100) -+- worker(101, FEATURE_X=on)
+//	         |                   |- worker(102, FEATURE_X=off)
+//	         |                   \- worker(103, unreadable)
+//	         \- cron(200)
+func envTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Environment: []string{"PATH=/usr/bin"}},
+		{PID: 100, PPID: 1, Command: "supervisor", Environment: []string{"PATH=/usr/bin", "FEATURE_XY=on"}},
+		{PID: 101, PPID: 100, Command: "worker", Environment: []string{"PATH=/usr/bin", "FEATURE_X=on"}},
+		{PID: 102, PPID: 100, Command: "worker", Environment: []string{"FEATURE_X=off", "PATH=/usr/bin"}},
+		{PID: 103, PPID: 100, Command: "worker", Environment: []string{}},
+		{PID: 200, PPID: 1, Command: "cron", Environment: []string{"FEATURE_X="}},
+	}
+}
+
+func TestMarkEnvironment(t *testing.T) {
+	mark := func(displayOptions DisplayOptions) []int32 {
+		processTree := NewProcessTree(0, setupTestLogger(), envTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		return markedPIDs(processTree)
+	}
+
+	// KEY=VALUE matches the exact value, along with the ancestors of each match
+	assert.Equal(t, []int32{1, 100, 101}, mark(DisplayOptions{EnvContains: "FEATURE_X=on"}))
+
+	// KEY matches any value, even an empty one, but not a longer name
+	assert.Equal(t, []int32{1, 100, 101, 102, 200}, mark(DisplayOptions{EnvContains: "FEATURE_X"}))
+	assert.Equal(t, []int32{1, 100}, mark(DisplayOptions{EnvContains: "FEATURE_XY"}))
+
+	assert.Equal(t, []int32{}, mark(DisplayOptions{EnvContains: "MISSING"}))
+
+	// Only the processes selected by the other filters are considered
+	assert.Equal(t, []int32{1, 200}, mark(DisplayOptions{Contains: "cron", EnvContains: "FEATURE_X"}))
+
+	// With --match-subtree, the descendants of the matches are shown as well
+	assert.Equal(t, []int32{1, 100, 101, 102, 103}, mark(DisplayOptions{EnvContains: "FEATURE_XY", MatchSubtree: true}))
+}
+
+func TestMarkEnvironmentReadsOnlyCandidates(t *testing.T) {
+	processes := envTestProcesses()
+	// The environment of a process left out by the other filters is never read
+	processes[5].Environment = nil
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{Contains: "worker", EnvContains: "FEATURE_X"})
+	processTree.MarkProcesses()
+
+	assert.Equal(t, []int32{1, 100, 101, 102}, markedPIDs(processTree))
+	assert.Nil(t, processTree.Nodes[processTree.PidToIndexMap[200]].Environment)
+}
+
+func TestShowEnv(t *testing.T) {
+	output := renderTree(t, envTestProcesses(), DisplayOptions{EnvContains: "FEATURE_X", ShowEnv: true})
+
+	assert.Contains(t, output, "(env: FEATURE_X=on) worker")
+	assert.Contains(t, output, "(env: FEATURE_X=off) worker")
+	assert.Contains(t, output, "(env: FEATURE_X=) cron")
+	assert.Contains(t, output, "- supervisor \n")
+	assert.NotContains(t, output, "FEATURE_XY")
+
+	// The matching workers are compacted with each other
+	output = renderTree(t, envTestProcesses(), DisplayOptions{CompactMode: true, EnvContains: "FEATURE_X"})
+	assert.Contains(t, output, "- worker───2*[worker] \n")
+}
@@ -0,0 +1,153 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the --exclude filter. Exclusions are applied after all other
+// filters have marked the processes to display, so an excluded process is always hidden.
+// Its descendants are hidden with it, unless a descendant independently matches one of
+// the include filters (--contains or --user), in which case it is moved under the
+// nearest ancestor that is still displayed.
+package pstree
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// markExcluded unmarks processes matching one of the --exclude patterns along with their descendants.
+//
+// Descendants that independently match an include filter stay marked and are re-linked under
+// the nearest displayed ancestor, so they are not lost when DropUnmarked removes the excluded
+// processes and don't end up at the root level of the tree.
+func (processTree *ProcessTree) markExcluded() {
+	processTree.Logger.Debug("Entering processTree.markExcluded()")
+	var (
+		ancestorIndex int
+		kept          []int
+		pidIndex      int
+		visited       map[int]bool
+	)
+
+	visited = make(map[int]bool)
+	for pidIndex = range processTree.Nodes {
+		if !visited[pidIndex] && processTree.isExcluded(pidIndex) {
+			processTree.unmarkExcluded(pidIndex, visited, &kept)
+		}
+	}
+
+	for _, pidIndex = range kept {
+		ancestorIndex = processTree.Nodes[pidIndex].Parent
+		for ancestorIndex != -1 && !processTree.Nodes[ancestorIndex].Print {
+			ancestorIndex = processTree.Nodes[ancestorIndex].Parent
+		}
+		if ancestorIndex == -1 {
+			continue
+		}
+		processTree.Logger.Debug(fmt.Sprintf("Moving PID %d under PID %d", processTree.Nodes[pidIndex].PID, processTree.Nodes[ancestorIndex].PID))
+		processTree.moveUnder(pidIndex, ancestorIndex)
+	}
+}
+
+// unmarkExcluded unmarks an excluded process and recursively all of its descendants.
+//
+// The recursion stops at descendants that match an include filter without being excluded
+// themselves; these are collected so they can be re-linked by markExcluded.
+//
+// Parameters:
+//   - pidIndex: Index of the process to unmark
+//   - visited: Indices of the processes already unmarked
+//   - kept: Indices of the descendants that should remain displayed
+func (processTree *ProcessTree) unmarkExcluded(pidIndex int, visited map[int]bool, kept *[]int) {
+	var (
+		childPidIndex int
+	)
+
+	visited[pidIndex] = true
+	processTree.Nodes[pidIndex].Print = false
+
+	childPidIndex = processTree.Nodes[pidIndex].Child
+	for childPidIndex != -1 {
+		if processTree.Nodes[childPidIndex].Print && processTree.matchesInclude(childPidIndex) && !processTree.isExcluded(childPidIndex) {
+			*kept = append(*kept, childPidIndex)
+		} else {
+			processTree.unmarkExcluded(childPidIndex, visited, kept)
+		}
+		childPidIndex = processTree.Nodes[childPidIndex].Sister
+	}
+}
+
+// isExcluded determines if the command line of a process matches one of the --exclude patterns.
+//
+// Parameters:
+//   - pidIndex: Index of the process to check
+//
+// Returns:
+//   - bool: true if the process should be excluded, false otherwise
+func (processTree *ProcessTree) isExcluded(pidIndex int) bool {
+	var (
+		commandLine string
+		pattern     string
+	)
+
+	commandLine = processTree.Nodes[pidIndex].Command
+	if len(processTree.Nodes[pidIndex].Args) > 0 {
+		commandLine += " " + strings.Join(processTree.Nodes[pidIndex].Args, " ")
+	}
+
+	for _, pattern = range processTree.DisplayOptions.ExcludePatterns {
+		if pattern != "" && strings.Contains(commandLine, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesInclude determines if a process matches the --contains or --user filters on its own.
+//
+// Parameters:
+//   - pidIndex: Index of the process to check
+//
+// Returns:
+//   - bool: true if the process matches an include filter, false otherwise
+func (processTree *ProcessTree) matchesInclude(pidIndex int) bool {
+	if processTree.DisplayOptions.Contains != "" && strings.Contains(processTree.Nodes[pidIndex].Command, processTree.DisplayOptions.Contains) {
+		return true
+	}
+	return slices.Contains(processTree.DisplayOptions.Usernames, processTree.Nodes[pidIndex].Username)
+}
+
+// moveUnder re-links a process, along with its subtree, as the last child of another process.
+//
+// Parameters:
+//   - pidIndex: Index of the process to move
+//   - ppidIndex: Index of the new parent process
+func (processTree *ProcessTree) moveUnder(pidIndex int, ppidIndex int) {
+	var (
+		oldParentIndex int
+		sisterIndex    int
+	)
+
+	// Unlink from the current parent
+	oldParentIndex = processTree.Nodes[pidIndex].Parent
+	if processTree.Nodes[oldParentIndex].Child == pidIndex {
+		processTree.Nodes[oldParentIndex].Child = processTree.Nodes[pidIndex].Sister
+	} else {
+		sisterIndex = processTree.Nodes[oldParentIndex].Child
+		for processTree.Nodes[sisterIndex].Sister != pidIndex {
+			sisterIndex = processTree.Nodes[sisterIndex].Sister
+		}
+		processTree.Nodes[sisterIndex].Sister = processTree.Nodes[pidIndex].Sister
+	}
+	processTree.Nodes[pidIndex].Sister = -1
+
+	// Link as the last child of the new parent
+	processTree.Nodes[pidIndex].Parent = ppidIndex
+	if processTree.Nodes[ppidIndex].Child == -1 {
+		processTree.Nodes[ppidIndex].Child = pidIndex
+	} else {
+		sisterIndex = processTree.Nodes[ppidIndex].Child
+		for processTree.Nodes[sisterIndex].Sister != -1 {
+			sisterIndex = processTree.Nodes[sisterIndex].Sister
+		}
+		processTree.Nodes[sisterIndex].Sister = pidIndex
+	}
+}
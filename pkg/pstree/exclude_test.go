@@ -0,0 +1,82 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// excludeTestProcesses returns a small tree with an excluded process in the middle:
+//
+//	init(1) -+- chrome(100) -+- helper(101)
+//	         |               \- sshd(102) --- bash(103)
+//	         \- cron(200)
+func excludeTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "chrome"},
+		{PID: 101, PPID: 100, Command: "helper", Args: []string{"--type=renderer"}},
+		{PID: 102, PPID: 100, Command: "sshd"},
+		{PID: 103, PPID: 102, Command: "bash"},
+		{PID: 200, PPID: 1, Command: "cron"},
+	}
+}
+
+// TestMarkExcluded tests that an excluded process is hidden along with its descendants
+func TestMarkExcluded(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), excludeTestProcesses(), DisplayOptions{ExcludePatterns: []string{"chrome"}})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	for _, pid := range []int32{1, 200} {
+		assert.True(t, processTree.Nodes[processTree.PidToIndexMap[pid]].Print, "PID %d should be marked", pid)
+	}
+	for _, pid := range []int32{100, 101, 102, 103} {
+		assert.False(t, processTree.Nodes[processTree.PidToIndexMap[pid]].Print, "PID %d should not be marked", pid)
+	}
+
+	// The children of the excluded process are not moved to the root level
+	initIndex := processTree.PidToIndexMap[1]
+	cronIndex := processTree.PidToIndexMap[200]
+	assert.Equal(t, cronIndex, processTree.Nodes[initIndex].Child)
+	assert.Equal(t, -1, processTree.Nodes[cronIndex].Sister)
+}
+
+// TestMarkExcludedArguments tests that the patterns are matched against the full command line
+func TestMarkExcludedArguments(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), excludeTestProcesses(), DisplayOptions{ExcludePatterns: []string{"--type=renderer"}})
+	processTree.MarkProcesses()
+
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[101]].Print) // helper has a matching argument
+	assert.True(t, processTree.Nodes[processTree.PidToIndexMap[102]].Print)  // sshd is a sibling of the excluded process
+}
+
+// TestMarkExcludedWithContains tests that a descendant matching --contains survives the exclusion
+func TestMarkExcludedWithContains(t *testing.T) {
+	displayOptions := DisplayOptions{
+		Contains:        "h",
+		ExcludePatterns: []string{"chrome", "helper"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), excludeTestProcesses(), displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	initIndex := processTree.PidToIndexMap[1]
+	sshdIndex := processTree.PidToIndexMap[102]
+	bashIndex := processTree.PidToIndexMap[103]
+
+	// Exclude wins over --contains for chrome and helper
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[100]].Print)
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[101]].Print)
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[200]].Print) // cron doesn't match --contains
+
+	// sshd matches on its own and is moved under init instead of the root level
+	assert.True(t, processTree.Nodes[sshdIndex].Print)
+	assert.Equal(t, initIndex, processTree.Nodes[sshdIndex].Parent)
+	assert.Equal(t, sshdIndex, processTree.Nodes[initIndex].Child)
+	assert.Equal(t, -1, processTree.Nodes[sshdIndex].Sister)
+
+	// The subtree of sshd moves along with it
+	assert.True(t, processTree.Nodes[bashIndex].Print)
+	assert.Equal(t, bashIndex, processTree.Nodes[sshdIndex].Child)
+}
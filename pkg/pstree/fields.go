@@ -0,0 +1,312 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the registry of the fields that can be selected with --fields, e.g.,
+// --fields=cpu,mem,user. ApplyFields enables the collection and display of each field, like its
+// boolean flag does, and records the order of the fields in DisplayOptions.Fields, which the
+// tree, the flat outputs, and the JSON rendering follow. The fields enabled by the boolean flags
+// are appended after the listed ones, so the flags keep working along with --fields. The
+// identity of each process, i.e., its PID, PPID, PGID, SID, and owner, always leads the line of the
+// tree, and its command and arguments always end it.
+package pstree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// field is a field of a process that can be selected with --fields.
+type field struct {
+	// Names of the columns of the flat output showing the field, see flatColumns
+	columns []string
+	// Function enabling the collection and display of the field
+	enable func(options *DisplayOptions)
+	// Function reporting whether the field is enabled
+	enabled func(options DisplayOptions) bool
+	// Name of the member of a TreeNode showing the field (empty for none)
+	jsonKey string
+	// Name of the line item showing the field, see lineItemKeys
+	lineItem string
+	// Name of the field with --fields
+	name string
+}
+
+// flagField returns a field enabled by a boolean display option.
+//
+// Parameters:
+//   - name: Name of the field with --fields
+//   - option: Function returning the option enabling the field
+//   - lineItem: Name of the line item showing the field
+//   - jsonKey: Name of the member of a TreeNode showing the field (empty for none)
+//   - columns: Names of the columns of the flat output showing the field
+//
+// Returns:
+//   - field: The field
+func flagField(name string, option func(options *DisplayOptions) *bool, lineItem string, jsonKey string, columns ...string) field {
+	return field{
+		columns:  columns,
+		enable:   func(options *DisplayOptions) { *option(options) = true },
+		enabled:  func(options DisplayOptions) bool { return *option(&options) },
+		jsonKey:  jsonKey,
+		lineItem: lineItem,
+		name:     name,
+	}
+}
+
+// fields are the fields that can be selected with --fields, in their default order.
+var fields = []field{
+	flagField("pid", func(options *DisplayOptions) *bool { return &options.ShowPIDs }, "pidPgid", "", "pid"),
+	flagField("ppid", func(options *DisplayOptions) *bool { return &options.ShowPPIDs }, "pidPgid", "", "ppid"),
+	flagField("pgid", func(options *DisplayOptions) *bool { return &options.ShowPGIDs }, "pidPgid", ""),
+	flagField("sid", func(options *DisplayOptions) *bool { return &options.ShowSIDs }, "pidPgid", ""),
+	flagField("user", func(options *DisplayOptions) *bool { return &options.ShowOwner }, "owner", "username", "username"),
+	flagField("age", func(options *DisplayOptions) *bool { return &options.ShowProcessAge }, "age", "age", "age"),
+	flagField("start", func(options *DisplayOptions) *bool { return &options.ShowStartTime }, "start", "start_time", "start_time"),
+	flagField("cpu", func(options *DisplayOptions) *bool { return &options.ShowCpuPercent }, "cpu", "cpu_percent", "cpu%"),
+	flagField("cputime", func(options *DisplayOptions) *bool { return &options.ShowCpuTime }, "cputime", ""),
+	flagField("mem", func(options *DisplayOptions) *bool { return &options.ShowMemoryUsage }, "memory", "memory", "rss"),
+	flagField("threads", func(options *DisplayOptions) *bool { return &options.ShowNumThreads }, "threads", "threads", "threads"),
+	flagField("nice", func(options *DisplayOptions) *bool { return &options.ShowNice }, "nice", "", "nice"),
+	flagField("fds", func(options *DisplayOptions) *bool { return &options.ShowNumFDs }, "fds", ""),
+	flagField("io", func(options *DisplayOptions) *bool { return &options.ShowIO }, "io", "", "read_bytes", "write_bytes"),
+	{
+		columns: []string{"major_faults", "minor_faults"},
+		enable: func(options *DisplayOptions) {
+			if options.PageFaults == "" {
+				options.PageFaults = "major"
+			}
+		},
+		enabled:  func(options DisplayOptions) bool { return options.PageFaults != "" },
+		lineItem: "faults",
+		name:     "faults",
+	},
+	flagField("status", func(options *DisplayOptions) *bool { return &options.ShowStatus }, "status", ""),
+	flagField("sched", func(options *DisplayOptions) *bool { return &options.ShowSched }, "sched", "", "sched"),
+	flagField("connections", func(options *DisplayOptions) *bool { return &options.ShowConnections }, "connections", ""),
+	flagField("cwd", func(options *DisplayOptions) *bool { return &options.ShowCwd }, "cwd", ""),
+	flagField("container", func(options *DisplayOptions) *bool { return &options.ShowContainers }, "container", "", "container"),
+	flagField("ns", func(options *DisplayOptions) *bool { return &options.ShowNamespaces }, "ns", "", "namespaces"),
+	flagField("parent", func(options *DisplayOptions) *bool { return &options.ShowParent }, "parent", "parent_command", "parent_command"),
+	flagField("args", func(options *DisplayOptions) *bool { return &options.ShowArguments }, "args", "args", "args"),
+}
+
+// FieldNames returns the names of the fields that can be selected with --fields.
+//
+// Returns:
+//   - []string: The names of the fields, in their default order
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.name)
+	}
+	return names
+}
+
+// lookupField returns the field with the given name.
+//
+// Parameters:
+//   - name: Name of the field with --fields
+//
+// Returns:
+//   - field: The field
+//   - bool: false if there is no such field
+func lookupField(name string) (field, bool) {
+	for _, field := range fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	return field{}, false
+}
+
+// ValidateFields checks that each name is the name of a field, see FieldNames.
+//
+// Parameters:
+//   - names: The names given with --fields
+//
+// Returns:
+//   - error: An error listing the valid names if a name is not the name of a field
+func ValidateFields(names []string) error {
+	for _, name := range names {
+		if _, ok := lookupField(name); !ok {
+			return fmt.Errorf("unknown field %q, valid fields for --fields are: %s", name, strings.Join(FieldNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// ApplyFields enables the collection and display of the given fields, and sets
+// DisplayOptions.Fields to their order, followed by the fields already enabled by the other
+// display options in their default order. A field listed more than once keeps its first
+// position. Without any name, the options are left as they are.
+//
+// Parameters:
+//   - options: The display options to update
+//   - names: The names given with --fields, in display order
+//
+// Returns:
+//   - error: An error listing the valid names if a name is not the name of a field
+func ApplyFields(options *DisplayOptions, names []string) error {
+	var (
+		ordered []string
+	)
+
+	if len(names) == 0 {
+		return nil
+	}
+	if err := ValidateFields(names); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if !slices.Contains(ordered, name) {
+			field, _ := lookupField(name)
+			field.enable(options)
+			ordered = append(ordered, name)
+		}
+	}
+	for _, field := range fields {
+		if field.enabled(*options) && !slices.Contains(ordered, field.name) {
+			ordered = append(ordered, field.name)
+		}
+	}
+	options.Fields = ordered
+	return nil
+}
+
+// lineItemOrder returns the names of the items of a line in display order: the identity of the
+// process, the items of the fields in the order of DisplayOptions.Fields, the other items in
+// their default order, and the command and its arguments last. Without DisplayOptions.Fields,
+// it is the default order.
+//
+// Returns:
+//   - []string: The names of the items, see lineItemKeys
+func (processTree *ProcessTree) lineItemOrder() []string {
+	if len(processTree.DisplayOptions.Fields) == 0 {
+		return lineItemKeys
+	}
+
+	order := slices.Clone(identityKeys)
+	for _, name := range processTree.DisplayOptions.Fields {
+		field, _ := lookupField(name)
+		if !slices.Contains(order, field.lineItem) && !slices.Contains(trailingKeys, field.lineItem) {
+			order = append(order, field.lineItem)
+		}
+	}
+	for _, key := range lineItemKeys {
+		if !slices.Contains(order, key) && !slices.Contains(trailingKeys, key) {
+			order = append(order, key)
+		}
+	}
+	return append(order, trailingKeys...)
+}
+
+// orderFlatColumns orders the columns of the flat output like the fields of
+// DisplayOptions.Fields: the depth first, the columns of the fields in their order, then the
+// command and its arguments, and the other columns in their default order.
+//
+// Parameters:
+//   - columns: The columns in their default order
+//
+// Returns:
+//   - []flatColumn: The ordered columns, the same as columns without DisplayOptions.Fields
+func (processTree *ProcessTree) orderFlatColumns(columns []flatColumn) []flatColumn {
+	var (
+		names   []string
+		ordered []flatColumn
+	)
+
+	if len(processTree.DisplayOptions.Fields) == 0 {
+		return columns
+	}
+
+	names = []string{"depth"}
+	for _, name := range processTree.DisplayOptions.Fields {
+		if name != "args" {
+			field, _ := lookupField(name)
+			names = append(names, field.columns...)
+		}
+	}
+	names = append(names, "command", "args")
+	for _, name := range names {
+		if index := slices.IndexFunc(columns, func(column flatColumn) bool { return column.Name == name }); index >= 0 {
+			ordered = append(ordered, columns[index])
+		}
+	}
+	for _, column := range columns {
+		if !slices.Contains(names, column.Name) {
+			ordered = append(ordered, column)
+		}
+	}
+	return ordered
+}
+
+// jsonFieldOrder returns the members of a TreeNode showing the fields of DisplayOptions.Fields,
+// in their order.
+//
+// Returns:
+//   - []string: The names of the members, nil without DisplayOptions.Fields
+func (processTree *ProcessTree) jsonFieldOrder() []string {
+	var (
+		keys []string
+	)
+
+	for _, name := range processTree.DisplayOptions.Fields {
+		if field, _ := lookupField(name); field.jsonKey != "" {
+			keys = append(keys, field.jsonKey)
+		}
+	}
+	return keys
+}
+
+// MarshalJSON encodes a TreeNode with its PID, PPID, and command first, then the members of the
+// fields in the order of DisplayOptions.Fields, and its children last. Without
+// DisplayOptions.Fields, the members are encoded in their default order.
+//
+// Returns:
+//   - []byte: The encoded node
+//   - error: Any error encountered while encoding the node
+func (node TreeNode) MarshalJSON() ([]byte, error) {
+	type plainNode TreeNode
+	var (
+		buffer  bytes.Buffer
+		keys    []string
+		members map[string]json.RawMessage
+	)
+
+	data, err := json.Marshal(plainNode(node))
+	if err != nil || len(node.fieldOrder) == 0 {
+		return data, err
+	}
+	if err = json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+
+	keys = append([]string{"pid", "ppid", "command"}, node.fieldOrder...)
+	for _, key := range slices.Sorted(maps.Keys(members)) {
+		if key != "children" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	keys = append(keys, "children")
+
+	buffer.WriteByte('{')
+	for _, key := range keys {
+		value, ok := members[key]
+		if !ok {
+			continue
+		}
+		if buffer.Len() > 1 {
+			buffer.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,66 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyFields(t *testing.T) {
+	// The listed fields are enabled in their order, the duplicates are dropped
+	options := DisplayOptions{ShowNumThreads: true}
+	require.NoError(t, ApplyFields(&options, []string{"mem", "cpu", "faults", "mem"}))
+	assert.True(t, options.ShowMemoryUsage)
+	assert.True(t, options.ShowCpuPercent)
+	assert.Equal(t, "major", options.PageFaults)
+	assert.Equal(t, []string{"mem", "cpu", "faults", "threads"}, options.Fields)
+
+	// An explicit page fault mode is kept
+	options = DisplayOptions{PageFaults: "all"}
+	require.NoError(t, ApplyFields(&options, []string{"faults"}))
+	assert.Equal(t, "all", options.PageFaults)
+
+	// Without any field, the default order is kept
+	options = DisplayOptions{ShowCpuPercent: true}
+	require.NoError(t, ApplyFields(&options, nil))
+	assert.Nil(t, options.Fields)
+
+	err := ApplyFields(&options, []string{"cpu", "rss"})
+	assert.ErrorContains(t, err, `unknown field "rss", valid fields for --fields are: pid, ppid, pgid, sid, user`)
+	assert.NoError(t, ValidateFields(FieldNames()))
+}
+
+func TestLineItemOrder(t *testing.T) {
+	processTree := &ProcessTree{}
+	assert.Equal(t, lineItemKeys, processTree.lineItemOrder())
+
+	processTree.DisplayOptions.Fields = []string{"threads", "args", "mem", "user", "cpu"}
+	order := processTree.lineItemOrder()
+	assert.Equal(t, []string{"pidPgid", "owner", "threads", "memory", "cpu", "age"}, order[:6])
+	assert.Equal(t, []string{"command", "args"}, order[len(order)-2:])
+	assert.Len(t, order, len(lineItemKeys))
+}
+
+func TestFieldsOutput(t *testing.T) {
+	options := DisplayOptions{MaxDepth: 10, ScreenWidth: 80, ShowPIDs: true}
+	require.NoError(t, ApplyFields(&options, []string{"threads", "user"}))
+	processTree := newFixtureTree(t, fixtures.DeepChain(2), options)
+	processTree.Nodes[0].NumThreads = 1
+	processTree.Nodes[1].NumThreads = 3
+	processTree.MarkProcesses()
+
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	assert.Equal(t, "-+- (1) root (t:1) init \n \\--- (2) alice (t:3) bash \n", output)
+
+	var flat bytes.Buffer
+	require.NoError(t, processTree.WriteFlat(&flat, ',', []int{0}))
+	assert.Equal(t, "depth,threads,username,pid,command\n0,1,root,1,init\n1,3,alice,2,bash\n", flat.String())
+
+	var tree bytes.Buffer
+	require.NoError(t, processTree.WriteJSON(&tree, []int{0}))
+	assert.Equal(t, `[{"pid":1,"ppid":0,"command":"init","threads":1,"username":"root","children":[{"pid":2,"ppid":1,"command":"bash","threads":3,"username":"alice"}]}]`+"\n", tree.String())
+}
@@ -0,0 +1,64 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+// flatTestProcesses returns a small tree for the flat output tests
+func flatTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", HasCPU: true, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4096}},
+		{PID: 100, PPID: 1, Command: "sshd", Username: "root", Args: []string{"-D", "-o", "Banner=\"hello, world\""}, HasCPU: true, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 8192}},
+		{PID: 101, PPID: 100, Command: "bash", Username: "alice", HasCPU: true, CPUPercent: 1.5},
+		{PID: 200, PPID: 1, Command: "cron", Username: "root"},
+	}
+}
+
+// TestWriteFlat tests that only the enabled columns are written, in tree order, with the tree depth
+func TestWriteFlat(t *testing.T) {
+	displayOptions := DisplayOptions{ShowPIDs: true, ShowOwner: true, ShowMemoryUsage: true}
+	processTree := NewProcessTree(0, setupTestLogger(), flatTestProcesses(), displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	err := processTree.WriteFlat(&output, ',', []int{0})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pid,depth,username,command,rss\n"+
+		"1,0,root,init,4096\n"+
+		"100,1,root,sshd,8192\n"+
+		"101,2,alice,bash,\n"+
+		"200,1,root,cron,\n", output.String())
+}
+
+// TestWriteFlatEscaping tests that fields are quoted per RFC 4180 and that filters still apply
+func TestWriteFlatEscaping(t *testing.T) {
+	displayOptions := DisplayOptions{Contains: "sshd", ShowArguments: true, ShowCpuPercent: true, MaxDepth: 999}
+	processTree := NewProcessTree(0, setupTestLogger(), flatTestProcesses(), displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	err := processTree.WriteFlat(&output, ',', []int{0})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "depth,command,args,cpu%\n"+
+		"0,init,,0.00\n"+
+		"1,sshd,\"-D -o Banner=\"\"hello, world\"\"\",0.00\n"+
+		"2,bash,,1.50\n", output.String())
+
+	// The same fields only need quoting for the quotes with TSV
+	output.Reset()
+	err = processTree.WriteFlat(&output, '\t', []int{0})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "depth\tcommand\targs\tcpu%\n"+
+		"0\tinit\t\t0.00\n"+
+		"1\tsshd\t\"-D -o Banner=\"\"hello, world\"\"\"\t0.00\n"+
+		"2\tbash\t\t1.50\n", output.String())
+}
@@ -0,0 +1,29 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the filtering by group (--group). A process is in a group when it is its
+// real, effective, or saved group ID, see ProcessGIDs, or one of its supplementary groups, see
+// ProcessGroups. Both are collected for every process, so the filter doesn't collect anything more.
+package pstree
+
+import (
+	"slices"
+)
+
+// inGroups determines whether a process is in one of the groups requested with --group.
+//
+// Parameters:
+//   - process: The process to check
+//
+// Returns:
+//   - bool: true if no groups were requested or the process is in one of them, false otherwise
+func (processTree *ProcessTree) inGroups(process *Process) bool {
+	if len(processTree.DisplayOptions.Groups) == 0 {
+		return true
+	}
+	for _, gid := range processTree.DisplayOptions.Groups {
+		if slices.Contains(process.GIDs, gid) || slices.Contains(process.Groups, gid) {
+			return true
+		}
+	}
+	return false
+}
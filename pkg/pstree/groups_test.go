@@ -0,0 +1,63 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// groupTestProcesses returns services running under shared groups:
+//
+//	init(1) -+- nginx(100) --- worker(101)
+//	         |- app(200)
+//	         \- cron(300)
+func groupTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", GIDs: []uint32{0, 0, 0, 0}},
+		{PID: 100, PPID: 1, Command: "nginx", Username: "www", GIDs: []uint32{33, 33, 33, 33}, Groups: []uint32{33, 1500}},
+		{PID: 101, PPID: 100, Command: "worker", Username: "www", GIDs: []uint32{33, 33, 33, 33}},
+		{PID: 200, PPID: 1, Command: "app", Username: "app", GIDs: []uint32{1000, 1000, 1000, 1000}, Groups: []uint32{1000, 1500, 2000}},
+		{PID: 300, PPID: 1, Command: "cron", Username: "root", GIDs: []uint32{0, 0, 0, 0}},
+	}
+}
+
+func TestMarkProcessesGroups(t *testing.T) {
+	mark := func(displayOptions DisplayOptions) []int32 {
+		processTree := NewProcessTree(0, setupTestLogger(), groupTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		return markedPIDs(processTree)
+	}
+
+	// The group IDs of the process match, along with its ancestors
+	assert.Equal(t, []int32{1, 100, 101}, mark(DisplayOptions{Groups: []uint32{33}}))
+
+	// So do the supplementary groups
+	assert.Equal(t, []int32{1, 100, 200}, mark(DisplayOptions{Groups: []uint32{1500}}))
+	assert.Equal(t, []int32{1, 200}, mark(DisplayOptions{Groups: []uint32{2000}}))
+
+	// Any of the requested groups matches
+	assert.Equal(t, []int32{1, 100, 101, 200}, mark(DisplayOptions{Groups: []uint32{33, 2000}}))
+	assert.Equal(t, []int32{}, mark(DisplayOptions{Groups: []uint32{4242}}))
+
+	// The descendants are shown with --match-subtree
+	assert.Equal(t, []int32{1, 100, 101, 200}, mark(DisplayOptions{Groups: []uint32{1500}, MatchSubtree: true}))
+
+	// Combined with the other filters, processes have to match both
+	assert.Equal(t, []int32{1, 200}, mark(DisplayOptions{Groups: []uint32{1500}, Usernames: []string{"app"}}))
+	assert.Equal(t, []int32{1, 100, 101}, mark(DisplayOptions{Contains: "nginx", Groups: []uint32{1500}}))
+	assert.Equal(t, []int32{}, mark(DisplayOptions{Contains: "cron", Groups: []uint32{1500}}))
+	assert.Equal(t, []int32{1, 100, 101}, mark(DisplayOptions{ExcludeRoot: true, Groups: []uint32{0, 33}}))
+}
+
+func TestInGroups(t *testing.T) {
+	process := groupTestProcesses()[3]
+
+	processTree := &ProcessTree{}
+	assert.True(t, processTree.inGroups(&process), "without groups, every process matches")
+
+	processTree.DisplayOptions.Groups = []uint32{2000}
+	assert.True(t, processTree.inGroups(&process))
+	processTree.DisplayOptions.Groups = []uint32{33}
+	assert.False(t, processTree.inGroups(&process))
+	assert.False(t, processTree.inGroups(&Process{}), "processes whose groups could not be read never match")
+}
@@ -0,0 +1,105 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the identity of each process shown in parentheses in front of its
+// metrics: its PID with --show-pids, its PPID with --show-ppids, its PGID with --show-pgids, and
+// its SID with --sids, always in this order, e.g., (1234,1,1234). A value that is not known is shown as -, so a field
+// never takes the place of another. When more than one field is shown, each of them is
+// right-aligned to its widest value among the processes printed, which PrintTree computes once
+// before the first line, the way printAligned computes the widths of the metrics, so the fields
+// of the different lines are comparable. A single field keeps the look of pstree -p, e.g., (1).
+package pstree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// identityFieldKeys are the fields of the identity of a process, in display order.
+var identityFieldKeys = []string{"pid", "ppid", "pgid", "sid"}
+
+// identityFields returns the identity fields of a process enabled by the display options. The
+// PPID of a process whose parent could not be read, and the PGID and SID of a process whose group
+// and session are not known, see hasPGID and hasSID, are left out.
+//
+// Parameters:
+//   - node: The process to identify
+//
+// Returns:
+//   - map[string]string: The known fields by name, see identityFieldKeys
+func (processTree *ProcessTree) identityFields(node *Process) map[string]string {
+	fields := make(map[string]string, len(identityFieldKeys))
+
+	if processTree.DisplayOptions.ShowPIDs {
+		fields["pid"] = processTree.formatPID(node)
+	}
+	if processTree.DisplayOptions.ShowPPIDs && node.PPID >= 0 {
+		fields["ppid"] = util.Int32toStr(node.PPID)
+	}
+	if processTree.DisplayOptions.ShowPGIDs && hasPGID(node) {
+		fields["pgid"] = util.Int32toStr(node.PGID)
+	}
+	if processTree.DisplayOptions.ShowSIDs && hasSID(node) {
+		fields["sid"] = util.Int32toStr(node.SID)
+	}
+	return fields
+}
+
+// computeIdentityWidths computes the width of each identity field, the widest value among the
+// processes that are printed. The orphans and container nodes have no identity, and neither
+// have the processes grouped with another one in compact mode. A field without any known value,
+// e.g., the PGIDs on a platform without process groups, has a width of 0 and is not shown.
+func (processTree *ProcessTree) computeIdentityWidths() {
+	var rows []map[string]string
+
+	for pidIndex, node := range processTree.Nodes {
+		if isSyntheticNode(node) || (processTree.DisplayOptions.CompactMode && ShouldSkipProcess(pidIndex)) {
+			continue
+		}
+		rows = append(rows, processTree.identityFields(node))
+	}
+	processTree.identityWidths = columnWidths(rows, identityFieldKeys)
+}
+
+// formatIdentity formats the identity of a process, e.g., (1234,   1,1234), with each field
+// right-aligned to its width when more than one is shown, and - in place of the values that are
+// not known.
+//
+// Parameters:
+//   - node: The process to identify
+//
+// Returns:
+//   - string: The identity in parentheses, or an empty string if no field is shown
+func (processTree *ProcessTree) formatIdentity(node *Process) string {
+	var (
+		keys   []string
+		values []string
+	)
+
+	if processTree.identityWidths == nil {
+		processTree.computeIdentityWidths()
+	}
+	for _, key := range identityFieldKeys {
+		if processTree.identityWidths[key] > 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	fields := processTree.identityFields(node)
+	for _, key := range keys {
+		value, ok := fields[key]
+		if !ok {
+			value = "-"
+		}
+		if len(keys) > 1 {
+			value = fmt.Sprintf("%*s", processTree.identityWidths[key], value)
+		}
+		values = append(values, value)
+	}
+
+	if len(values) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(values, ",") + ")"
+}
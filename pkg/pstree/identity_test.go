@@ -0,0 +1,85 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+// renderFixture renders a canned tree with the given options
+func renderFixture(t *testing.T, tree []fixtures.Process, displayOptions DisplayOptions) string {
+	displayOptions.MaxDepth = 10
+	displayOptions.ScreenWidth = 200
+	processTree := newFixtureTree(t, tree, displayOptions)
+	for _, node := range processTree.Nodes {
+		node.Age = 90
+		node.HasCPU = true
+		node.HasMemory = true
+		node.MemoryInfo = &process.MemoryInfoStat{RSS: 2048}
+		node.NumThreads = 1
+	}
+	return renderProcessTree(t, processTree)
+}
+
+func TestFormatIdentityGolden(t *testing.T) {
+	// Every field is right-aligned to its widest value, in the same order on every line
+	assert.Equal(t, ""+
+		"-+- (   1,   0,   1) root (00:00:01:30) (c:0.00%) (m:2.0 KiB) (t:1) init \n"+
+		" \\-+- ( 100,   1, 100) root (00:00:01:30) (c:0.00%) (m:2.0 KiB) (t:1) sshd \n"+
+		"   |-+- (1000, 100,1000) root (00:00:01:30) (c:0.00%) (m:2.0 KiB) (t:1) sshd user0@pts/0\n"+
+		"   | \\--- (1001,1000,1001) user0 (00:00:01:30) (c:0.00%) (m:2.0 KiB) (t:1) bash \n"+
+		"   \\-+- (1002, 100,1002) root (00:00:01:30) (c:0.00%) (m:2.0 KiB) (t:1) sshd user1@pts/1\n"+
+		"     \\--- (1003,1002,1003) user1 (00:00:01:30) (c:0.00%) (m:2.0 KiB) (t:1) bash \n",
+		renderFixture(t, fixtures.WideFanOut(2), DisplayOptions{
+			ShowArguments:   true,
+			ShowCpuPercent:  true,
+			ShowMemoryUsage: true,
+			ShowNumThreads:  true,
+			ShowOwner:       true,
+			ShowPGIDs:       true,
+			ShowPIDs:        true,
+			ShowPPIDs:       true,
+			ShowProcessAge:  true,
+		}))
+
+	// The PPID and PGID columns keep their place without the PIDs
+	assert.Equal(t, ""+
+		"-+- (   0,   1) init \n"+
+		" \\-+- (   1, 100) sshd \n"+
+		"   \\-+- ( 100,1000) sshd \n"+
+		"     \\--- (1000,1001) bash \n",
+		renderFixture(t, fixtures.WideFanOut(1), DisplayOptions{ShowPGIDs: true, ShowPPIDs: true}))
+
+	// A single field is not padded
+	assert.Equal(t, "-+- (1) init \n \\-+- (100) sshd \n   \\-+- (1000) sshd \n     \\--- (1001) bash \n",
+		renderFixture(t, fixtures.WideFanOut(1), DisplayOptions{ShowPIDs: true}))
+}
+
+func TestFormatIdentityUnknown(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), []Process{
+		{PID: 1, PPID: 0, PGID: 1, Command: "init"},
+		{PID: 4242, PPID: -1, PGID: -1, Command: "worker"},
+	}, DisplayOptions{ShowPGIDs: true, ShowPIDs: true, ShowPPIDs: true})
+
+	// The values that are not known are shown as -, so the other fields stay in their columns
+	if PGIDSupported {
+		assert.Equal(t, "(   1,0, 1)", processTree.formatIdentity(processTree.Nodes[0]))
+		assert.Equal(t, "(4242,-,-1)", processTree.formatIdentity(processTree.Nodes[1]))
+	} else {
+		assert.Equal(t, "(   1,0,1)", processTree.formatIdentity(processTree.Nodes[0]))
+		assert.Equal(t, "(4242,-,-)", processTree.formatIdentity(processTree.Nodes[1]))
+	}
+
+	// Without any known value, a field is not shown at all
+	processTree = NewProcessTree(0, setupTestLogger(), []Process{{PID: 1, PPID: -1, Command: "init"}}, DisplayOptions{ShowPIDs: true, ShowPPIDs: true})
+	assert.Equal(t, "(1)", processTree.formatIdentity(processTree.Nodes[0]))
+	processTree = NewProcessTree(0, setupTestLogger(), []Process{{PID: 1, Command: "init"}}, DisplayOptions{})
+	assert.Equal(t, "", processTree.formatIdentity(processTree.Nodes[0]))
+}
+
+func TestColumnWidths(t *testing.T) {
+	widths := columnWidths([]map[string]string{{"pid": "1", "cpu": "(c:1.00%)"}, {"pid": "1234", "mem": "\x1b[31m(m:1 B)\x1b[0m"}}, []string{"pid", "cpu", "mem", "age"})
+	assert.Equal(t, map[string]int{"pid": 4, "cpu": 9, "mem": 7}, widths)
+}
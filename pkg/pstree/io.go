@@ -0,0 +1,66 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the IO counters shown with --io: the number of bytes each process has read
+// from and written to storage over its lifetime, to find the processes that keep the disks busy.
+// The counters are only collected when they are shown or sorted by (--order-by=io). On Linux,
+// reading the counters of another user's process requires elevated permissions, so processes
+// whose counters cannot be read are shown with "-" instead of failing the whole tree.
+package pstree
+
+import (
+	"fmt"
+
+	"github.com/bananazon/pstree/util"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// IOBytes returns the number of bytes a process has read and written, which is what --order-by=io
+// sorts by.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - int64: The sum of the read and written bytes, or -1 if the IO counters could not be read
+func IOBytes(process *Process) int64 {
+	if process.IOCounters == nil {
+		return -1
+	}
+	return int64(process.IOCounters.ReadBytes + process.IOCounters.WriteBytes)
+}
+
+// addIOCounters adds the IO counters of a process to the summed counters of a process group.
+//
+// Parameters:
+//   - sum: The summed counters, nil if none of the members could be read so far
+//   - ioCounters: The counters of the process, nil if they could not be read
+//
+// Returns:
+//   - *process.IOCountersStat: The new sum, nil if neither could be read
+func addIOCounters(sum *process.IOCountersStat, ioCounters *process.IOCountersStat) *process.IOCountersStat {
+	if ioCounters == nil {
+		return sum
+	}
+	if sum == nil {
+		sum = &process.IOCountersStat{}
+	}
+	sum.ReadBytes += ioCounters.ReadBytes
+	sum.ReadCount += ioCounters.ReadCount
+	sum.WriteBytes += ioCounters.WriteBytes
+	sum.WriteCount += ioCounters.WriteCount
+	return sum
+}
+
+// formatIOField formats the IO field of a line, e.g., (io: r 1.2 MiB, w 64.0 KiB).
+//
+// Parameters:
+//   - ioCounters: The IO counters to display, nil if they could not be read
+//
+// Returns:
+//   - string: The formatted field, or (io: -) if the counters could not be read
+func formatIOField(ioCounters *process.IOCountersStat) string {
+	if ioCounters == nil {
+		return "(io: -)"
+	}
+	return fmt.Sprintf("(io: r %s, w %s)", util.FormatByteSize(ioCounters.ReadBytes, "auto"), util.FormatByteSize(ioCounters.WriteBytes, "auto"))
+}
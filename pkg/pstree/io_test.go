@@ -0,0 +1,67 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+// ioTestProcesses returns processes of which one belongs to another user whose IO counters cannot be read.
+func ioTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", IOCounters: &process.IOCountersStat{ReadBytes: 512, WriteBytes: 0}},
+		{PID: 100, PPID: 1, Command: "db", IOCounters: &process.IOCountersStat{ReadBytes: 3 * 1024 * 1024 * 1024, WriteBytes: 1536 * 1024 * 1024}},
+		{PID: 200, PPID: 1, Command: "worker", IOCounters: &process.IOCountersStat{ReadBytes: 1024 * 1024, WriteBytes: 64 * 1024}},
+		{PID: 300, PPID: 1, Command: "worker", IOCounters: &process.IOCountersStat{ReadBytes: 1024 * 1024, WriteBytes: 0}},
+		{PID: 400, PPID: 1, Command: "sshd"},
+	}
+}
+
+func TestIOBytes(t *testing.T) {
+	processes := ioTestProcesses()
+	assert.Equal(t, int64(512), IOBytes(&processes[0]))
+	assert.Equal(t, int64(1024*1024+64*1024), IOBytes(&processes[2]))
+	assert.Equal(t, int64(-1), IOBytes(&processes[4]))
+}
+
+func TestShowIO(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		displayOptions.ScreenWidth = 120
+		displayOptions.ShowIO = true
+		return renderTree(t, ioTestProcesses(), displayOptions)
+	}
+
+	output := render(DisplayOptions{})
+	assert.Contains(t, output, "(io: r 512.0 B, w 0.0 B) init")
+	assert.Contains(t, output, "(io: r 3.0 GiB, w 1.5 GiB) db")
+	assert.Contains(t, output, "(io: -) sshd")
+
+	// The compact group sums the counters of its members
+	output = render(DisplayOptions{CompactMode: true})
+	assert.Contains(t, output, "(io: r 2.0 MiB, w 64.0 KiB) worker───2*[worker]")
+
+	// --order-by=io sorts by the bytes read and written, the processes that could not be read last
+	output = render(DisplayOptions{OrderBy: "io", OrderDir: "desc"})
+	assert.Regexp(t, `(?s)db.*w 64.0 KiB\) worker.*w 0.0 B\) worker.*sshd`, output)
+	output = render(DisplayOptions{OrderBy: "io", OrderDir: "asc"})
+	assert.Regexp(t, `(?s)w 0.0 B\) worker.*w 64.0 KiB\) worker.*db.*sshd`, output)
+}
+
+func TestWriteFlatIO(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), ioTestProcesses(), DisplayOptions{ShowIO: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	err := processTree.WriteFlat(&output, ',', []int{0})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "depth,command,read_bytes,write_bytes\n"+
+		"0,init,512,0\n"+
+		"1,db,3221225472,1610612736\n"+
+		"1,worker,1048576,65536\n"+
+		"1,worker,1048576,0\n"+
+		"1,sshd,,\n", output.String())
+}
@@ -0,0 +1,144 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the JSON rendering of the tree served on /tree.json by --serve. Unlike a
+// snapshot, which holds every collected attribute of every process, the rendered tree only holds
+// the displayed processes, nested under their parents, with the attributes enabled by the
+// display options. Like the flat output, compact mode does not apply, every process gets its
+// own node.
+package pstree
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// TreeNode is a displayed process in the JSON rendering of the tree.
+type TreeNode struct {
+	// Age of the process in seconds, with ShowProcessAge
+	Age *int64 `json:"age,omitempty"`
+	// Command line arguments, with ShowArguments
+	Args []string `json:"args,omitempty"`
+	// The displayed children of the process
+	Children []TreeNode `json:"children,omitempty"`
+	// Command of the process, formatted with CommandFormat
+	Command string `json:"command"`
+	// CPU usage percentage, with ShowCpuPercent, left out when it was not collected
+	CPUPercent *float64 `json:"cpu_percent,omitempty"`
+	// Memory usage in bytes in the --mem-field, with ShowMemoryUsage, left out when it was not collected
+	Memory *uint64 `json:"memory,omitempty"`
+	// Process ID, negative for the orphans and container nodes
+	PID int32 `json:"pid"`
+	// Command of the parent process, with ShowParent, left out when the parent was not collected
+	ParentCommand string `json:"parent_command,omitempty"`
+	// Parent process ID
+	PPID int32 `json:"ppid"`
+	// Start time of the process as a Unix timestamp, with ShowStartTime
+	StartTime *int64 `json:"start_time,omitempty"`
+	// Number of threads, with ShowNumThreads
+	Threads *int32 `json:"threads,omitempty"`
+	// Owner of the process, with ShowOwner
+	Username string `json:"username,omitempty"`
+	// Members of the fields of DisplayOptions.Fields in their order, see MarshalJSON
+	fieldOrder []string
+}
+
+// TreeNodes returns the displayed processes below each root as nested TreeNode values.
+//
+// Parameters:
+//   - rootIndices: Indices of the processes at the top of each tree, as returned by RootIndices
+//
+// Returns:
+//   - []TreeNode: A node for each displayed root, in the order of rootIndices
+func (processTree *ProcessTree) TreeNodes(rootIndices []int) []TreeNode {
+	nodes := make([]TreeNode, 0, len(rootIndices))
+	for _, rootIndex := range rootIndices {
+		if node, ok := processTree.treeNode(rootIndex, 0); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// treeNode recursively builds the TreeNode of a process and its displayed descendants.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+//   - depth: Depth of the process relative to the root of its tree
+//
+// Returns:
+//   - TreeNode: The node of the process
+//   - bool: false if the process is not displayed
+func (processTree *ProcessTree) treeNode(pidIndex int, depth int) (TreeNode, bool) {
+	var (
+		node     *Process
+		treeNode TreeNode
+	)
+
+	if processTree.DisplayOptions.MaxDepth > 0 && depth > processTree.DisplayOptions.MaxDepth {
+		return TreeNode{}, false
+	}
+
+	node = processTree.Nodes[pidIndex]
+	if !node.Print {
+		return TreeNode{}, false
+	}
+
+	treeNode = TreeNode{
+		Command:    FormatCommand(processTree.commandName(node), processTree.DisplayOptions.CommandFormat),
+		PID:        node.PID,
+		PPID:       node.PPID,
+		fieldOrder: processTree.jsonFieldOrder(),
+	}
+
+	// The orphans and container nodes are not processes, so there is nothing to show but their name
+	if !isSyntheticNode(node) {
+		if processTree.DisplayOptions.ShowArguments {
+			treeNode.Args = processTree.displayArgs(node)
+		}
+		if processTree.DisplayOptions.ShowParent {
+			treeNode.ParentCommand = processTree.parentCommand(node)
+		}
+		if processTree.DisplayOptions.ShowOwner {
+			treeNode.Username = processTree.ownerName(node)
+		}
+		if processTree.DisplayOptions.ShowProcessAge && node.Age >= 0 {
+			age := node.Age
+			treeNode.Age = &age
+		}
+		if processTree.DisplayOptions.ShowStartTime && node.CreateTime > 0 {
+			startTime := node.CreateTime
+			treeNode.StartTime = &startTime
+		}
+		if processTree.DisplayOptions.ShowCpuPercent && node.HasCPU {
+			cpuPercent := processTree.cpuPercentOf(node)
+			treeNode.CPUPercent = &cpuPercent
+		}
+		if processTree.DisplayOptions.ShowMemoryUsage && node.HasMemory {
+			memory := processTree.memoryValue(node)
+			treeNode.Memory = &memory
+		}
+		if processTree.DisplayOptions.ShowNumThreads {
+			threads := node.NumThreads
+			treeNode.Threads = &threads
+		}
+	}
+
+	for childPidIndex := node.Child; childPidIndex != -1; childPidIndex = processTree.Nodes[childPidIndex].Sister {
+		if child, ok := processTree.treeNode(childPidIndex, depth+1); ok {
+			treeNode.Children = append(treeNode.Children, child)
+		}
+	}
+	return treeNode, true
+}
+
+// WriteJSON writes the displayed processes as a JSON array of nested TreeNode values.
+//
+// Parameters:
+//   - writer: Destination of the output
+//   - rootIndices: Indices of the processes at the top of each tree, as returned by RootIndices
+//
+// Returns:
+//   - error: Any error encountered while encoding or writing the tree
+func (processTree *ProcessTree) WriteJSON(writer io.Writer, rootIndices []int) error {
+	return json.NewEncoder(writer).Encode(processTree.TreeNodes(rootIndices))
+}
@@ -0,0 +1,48 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteJSON tests that the displayed processes are nested under their parents with the enabled attributes
+func TestWriteJSON(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", HasCPU: true, CPUPercent: 0.5, NumThreads: 1},
+		{PID: 100, PPID: 1, Command: "sshd", Username: "root", HasCPU: true, CPUPercent: 7.0, NumThreads: 2},
+		{PID: 101, PPID: 100, Command: "bash", Args: []string{"-l"}, Username: "alice", HasCPU: true, NumThreads: 1},
+		{PID: 200, PPID: 1, Command: "cron", Username: "root", NumThreads: 1},
+	}
+	displayOptions := DisplayOptions{CompactMode: true, Contains: "bash", ShowArguments: true, ShowCpuPercent: true, ShowOwner: true}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	require.NoError(t, processTree.WriteJSON(&output, []int{0}))
+	assert.JSONEq(t, `[{"command":"init","pid":1,"ppid":0,"cpu_percent":0.5,"username":"root","children":[
+		{"command":"sshd","pid":100,"ppid":1,"cpu_percent":7,"username":"root","children":[
+			{"command":"bash","pid":101,"ppid":100,"args":["-l"],"cpu_percent":0,"username":"alice"}]}]}]`, output.String())
+}
+
+// TestTreeNodesMaxDepth tests that the processes below --level are left out
+func TestTreeNodesMaxDepth(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", NumThreads: 1},
+		{PID: 100, PPID: 1, Command: "sshd", NumThreads: 3},
+		{PID: 101, PPID: 100, Command: "bash", NumThreads: 1},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 1, ShowNumThreads: true})
+	processTree.MarkProcesses()
+
+	nodes := processTree.TreeNodes([]int{0})
+	require.Len(t, nodes, 1)
+	require.Len(t, nodes[0].Children, 1)
+	assert.Empty(t, nodes[0].Children[0].Children)
+	require.NotNil(t, nodes[0].Children[0].Threads)
+	assert.Equal(t, int32(3), *nodes[0].Children[0].Threads)
+	assert.Nil(t, nodes[0].Age)
+}
@@ -0,0 +1,86 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the hiding of Linux kernel threads (--no-kernel-threads). Kernel threads
+// such as kworker and ksoftirqd are started by kthreadd (PID 2), so hiding kthreadd along with
+// its descendants removes them from the tree. Processes from other tools or snapshots may instead
+// show kernel threads by their bracketed name without a command line, e.g., "[kworker/0:1]".
+// Neither heuristic matches the processes of macOS or Windows, so the option has no effect there.
+package pstree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KthreaddPID is the PID of kthreadd, the parent of all Linux kernel threads.
+const KthreaddPID int32 = 2
+
+// IsKernelThread determines whether a process is a Linux kernel thread.
+//
+// A process is a kernel thread when it is kthreadd itself, or when its name is in brackets and
+// it has no command line arguments. The "[PID n]" fallback used for processes whose name could
+// not be read is not a kernel thread. The children of kthreadd are hidden along with it by
+// markKernelThreads. Since PID 2 may be an ordinary process in a PID namespace, e.g., in a
+// container, neither PID 2 nor its children count as kernel threads unless it is named kthreadd.
+//
+// Parameters:
+//   - p: The process to check
+//
+// Returns:
+//   - bool: true if the process is a kernel thread, false otherwise
+func IsKernelThread(p Process) bool {
+	if isKthreadd(p) {
+		return true
+	}
+	if strings.HasPrefix(p.Command, "[") && strings.HasSuffix(p.Command, "]") && !strings.HasPrefix(p.Command, "[PID ") && len(p.Args) == 0 && p.Argv0 == "" {
+		return true
+	}
+	return false
+}
+
+// isKthreadd determines whether a process is kthreadd.
+//
+// Parameters:
+//   - p: The process to check
+//
+// Returns:
+//   - bool: true if the process is kthreadd, false otherwise
+func isKthreadd(p Process) bool {
+	return p.PID == KthreaddPID && p.PPID == 0 && (p.Command == "kthreadd" || p.Command == "[kthreadd]")
+}
+
+// markKernelThreads unmarks the kernel threads along with their descendants.
+//
+// Descendants are hidden even when they don't look like kernel threads themselves, so every
+// child of kthreadd is hidden with it. Like the exclusions, this is applied after the other
+// filters so the summary only counts the processes that remain.
+func (processTree *ProcessTree) markKernelThreads() {
+	processTree.Logger.Debug("Entering processTree.markKernelThreads()")
+	var (
+		pidIndex int
+	)
+
+	for pidIndex = range processTree.Nodes {
+		if processTree.Nodes[pidIndex].Print && IsKernelThread(*processTree.Nodes[pidIndex]) {
+			processTree.Logger.Debug(fmt.Sprintf("PID %d is a kernel thread", processTree.Nodes[pidIndex].PID))
+			processTree.unmarkSubtree(pidIndex)
+		}
+	}
+}
+
+// unmarkSubtree unmarks a process and all of its descendants.
+//
+// Parameters:
+//   - pidIndex: Index of the process to unmark
+func (processTree *ProcessTree) unmarkSubtree(pidIndex int) {
+	var (
+		childPidIndex int
+	)
+
+	processTree.Nodes[pidIndex].Print = false
+	childPidIndex = processTree.Nodes[pidIndex].Child
+	for childPidIndex != -1 {
+		processTree.unmarkSubtree(childPidIndex)
+		childPidIndex = processTree.Nodes[childPidIndex].Sister
+	}
+}
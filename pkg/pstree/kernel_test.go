@@ -0,0 +1,77 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// kernelTestProcesses returns a Linux-like process list with kernel threads:
+//
+//	systemd(1) --- sshd(500) --- bash(501)
+//	kthreadd(2) -+- kworker/0:1(10)
+//	             \- ksoftirqd/0(11)
+//	[rcu_sched](20)
+func kernelTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "/usr/lib/systemd/systemd", Args: []string{"--system"}},
+		{PID: 2, PPID: 0, Command: "kthreadd"},
+		{PID: 10, PPID: 2, Command: "kworker/0:1"},
+		{PID: 11, PPID: 2, Command: "ksoftirqd/0"},
+		{PID: 20, PPID: 0, Command: "[rcu_sched]"},
+		{PID: 500, PPID: 1, Command: "/usr/sbin/sshd", Args: []string{"-D"}},
+		{PID: 501, PPID: 500, Command: "bash"},
+	}
+}
+
+func TestIsKernelThread(t *testing.T) {
+	// kthreadd itself
+	assert.True(t, IsKernelThread(Process{PID: 2, PPID: 0, Command: "kthreadd"}))
+	assert.True(t, IsKernelThread(Process{PID: 2, PPID: 0, Command: "[kthreadd]"}))
+
+	// Bracketed names without a command line
+	assert.True(t, IsKernelThread(Process{PID: 20, PPID: 2, Command: "[kworker/u8:2]"}))
+	assert.False(t, IsKernelThread(Process{PID: 20, PPID: 1, Command: "[bracketed]", Args: []string{"--flag"}}))
+
+	// The fallback for processes whose name could not be read
+	assert.False(t, IsKernelThread(Process{PID: 42, PPID: 1, Command: "[PID 42]"}))
+
+	// PID 2 in a PID namespace is an ordinary process
+	assert.False(t, IsKernelThread(Process{PID: 2, PPID: 0, Command: "bash"}))
+	assert.False(t, IsKernelThread(Process{PID: 1, PPID: 0, Command: "/sbin/init"}))
+	assert.False(t, IsKernelThread(Process{PID: 10, PPID: 2, Command: "kworker/0:1"}), "children are hidden through kthreadd")
+}
+
+func TestMarkKernelThreads(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), kernelTestProcesses(), DisplayOptions{HideKernelThreads: true})
+	processTree.MarkProcesses()
+
+	// kthreadd is hidden along with its children
+	assert.Equal(t, []int32{1, 500, 501}, markedPIDs(processTree))
+
+	// The summary only counts the remaining processes
+	processTree.DropUnmarked()
+	assert.Equal(t, 3, processTree.Summarize().Processes)
+
+	// The other filters still apply
+	processTree = NewProcessTree(0, setupTestLogger(), kernelTestProcesses(), DisplayOptions{Contains: "k", HideKernelThreads: true})
+	processTree.MarkProcesses()
+	assert.Equal(t, []int32{}, markedPIDs(processTree))
+
+	// Without the option, nothing is hidden
+	processTree = NewProcessTree(0, setupTestLogger(), kernelTestProcesses(), DisplayOptions{})
+	processTree.MarkProcesses()
+	assert.Len(t, markedPIDs(processTree), 7)
+}
+
+func TestMarkKernelThreadsNamespace(t *testing.T) {
+	// In a container, PID 2 and its children are ordinary processes
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "tini"},
+		{PID: 2, PPID: 0, Command: "bash"},
+		{PID: 3, PPID: 2, Command: "vim"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{HideKernelThreads: true})
+	processTree.MarkProcesses()
+	assert.Equal(t, []int32{1, 2, 3}, markedPIDs(processTree))
+}
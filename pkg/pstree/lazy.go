@@ -0,0 +1,131 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the lazy collection of the resource usage. Filters such as --contains or
+// --user usually discard most of the processes, so reading the CPU, memory, thread, and age
+// values of every process is wasted work. SplitCollection moves these attributes out of the
+// first pass, which then only collects what is needed to build and filter the tree, and
+// CollectDeferredUsage reads them once MarkProcesses and DropUnmarked have filtered the tree,
+// only for the processes still displayed. An attribute the tree is sorted by, filtered by,
+// or summed over with --cumulative is still collected for every process in the first pass.
+package pstree
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// SplitCollection splits the attributes collected for the tree into those collected for every
+// process, and the resource usage only displayed, which can be deferred to CollectDeferredUsage.
+//
+// Nothing is deferred when the usage of every process is needed anyway: with
+// DisplayOptions.WatchInterval, the CPU usage is measured over the interval from the CPU times of
+// each snapshot, with DisplayOptions.ShowDiff the processes of both snapshots are compared, and
+// with DisplayOptions.ShowThreadsTree the thread nodes are created from their process.
+//
+// Parameters:
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//
+// Returns:
+//   - DisplayOptions: The options of the first pass, collecting every process
+//   - DisplayOptions: The options of the deferred pass, see CollectDeferredUsage
+func SplitCollection(miniOptions DisplayOptions) (DisplayOptions, DisplayOptions) {
+	var deferred DisplayOptions
+
+	first := miniOptions
+	if miniOptions.WatchInterval > 0 || miniOptions.ShowDiff || miniOptions.ShowThreadsTree {
+		return first, deferred
+	}
+
+	deferred.MemoryMode = miniOptions.MemoryMode
+	deferred.Workers = miniOptions.Workers
+
+	// The thresholds and the cumulative usage need the CPU and memory usage of every process
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowCpuPercent }, "cpu", miniOptions.MinCPU > 0 || miniOptions.ShowCumulative)
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowCpuTime }, "cputime", false)
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowIO }, "io", false)
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowMemoryUsage }, "mem", miniOptions.MinMemory > 0 || miniOptions.ShowCumulative)
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowNice }, "nice", false)
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowNumFDs }, "fds", false)
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowNumThreads }, "threads", false)
+	// The age filters need the create time of every process
+	ageWindow := miniOptions.YoungerThan > 0 || miniOptions.OlderThan > 0
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowProcessAge }, "age", ageWindow)
+	deferAttribute(&first, &deferred, func(options *DisplayOptions) *bool { return &options.ShowStartTime }, "start", miniOptions.OrderBy == "age" || miniOptions.ColorAttr == "age" || ageWindow)
+
+	if miniOptions.PageFaults != "" && miniOptions.OrderBy != "faults" {
+		first.PageFaults = ""
+		deferred.PageFaults = miniOptions.PageFaults
+	}
+
+	return first, deferred
+}
+
+// deferAttribute moves an attribute displayed with its option or --color-attr from the first
+// pass to the deferred pass, unless the tree is sorted by it or it is needed for every process.
+//
+// Parameters:
+//   - first: The options of the first pass (modified in place)
+//   - deferred: The options of the deferred pass (modified in place)
+//   - option: Returns the option displaying the attribute in the given options
+//   - attribute: Name of the attribute for --order-by and --color-attr
+//   - needed: Whether the attribute is needed for every process
+func deferAttribute(first *DisplayOptions, deferred *DisplayOptions, option func(options *DisplayOptions) *bool, attribute string, needed bool) {
+	if needed || first.OrderBy == attribute || (!*option(first) && first.ColorAttr != attribute) {
+		return
+	}
+
+	*option(first) = false
+	if first.ColorAttr == attribute {
+		first.ColorAttr = ""
+	}
+	*option(deferred) = true
+}
+
+// defersUsage reports whether any attribute was deferred by SplitCollection.
+//
+// Parameters:
+//   - deferred: The options of the deferred pass
+//
+// Returns:
+//   - bool: true if CollectDeferredUsage has anything to collect
+func defersUsage(deferred DisplayOptions) bool {
+	return deferred.ShowCpuPercent || deferred.ShowCpuTime || deferred.ShowIO || deferred.ShowMemoryUsage || deferred.ShowNice || deferred.ShowNumFDs || deferred.ShowNumThreads || deferred.ShowProcessAge || deferred.ShowStartTime || deferred.PageFaults != ""
+}
+
+// CollectDeferredUsage reads the resource usage deferred by SplitCollection for every process
+// marked for display. It should be called after DropUnmarked, and before the tree is printed.
+// Processes that exited since the first pass keep their usage unknown.
+//
+// Parameters:
+//   - deferred: The options of the deferred pass returned by SplitCollection
+//   - timings: The timings the attributes and the duration of the pass are added to, nil to not record them
+func (processTree *ProcessTree) CollectDeferredUsage(deferred DisplayOptions, timings *CollectionTimings) {
+	var nodes []*Process
+
+	if !defersUsage(deferred) {
+		return
+	}
+
+	processTree.Logger.Debug("Entering processTree.CollectDeferredUsage()")
+	if timings != nil {
+		defer addSince(&timings.Deferred, time.Now())
+	}
+
+	// Threads share the usage of their process, and the orphans and container nodes are not processes
+	for _, node := range processTree.Nodes {
+		if node.Print && !node.IsThread && !isSyntheticNode(node) {
+			nodes = append(nodes, node)
+		}
+	}
+
+	runWorkers(len(nodes), deferred.Workers, func(i int) {
+		proc, err := process.NewProcess(nodes[i].PID)
+		if err != nil {
+			processTree.Logger.Debug(fmt.Sprintf("Unable to read the usage of PID %d: %v", nodes[i].PID, err))
+			return
+		}
+		collectUsage(proc, deferred, timings, nodes[i])
+	})
+}
@@ -0,0 +1,101 @@
+package pstree
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSplitCollection tests that only the usage that is displayed is deferred
+func TestSplitCollection(t *testing.T) {
+	first, deferred := SplitCollection(DisplayOptions{ShowArguments: true, ShowCpuPercent: true, ShowMemoryUsage: true, MemoryMode: "pss", ShowNumThreads: true, ShowProcessAge: true, PageFaults: "all", Workers: 3})
+	assert.True(t, first.ShowArguments)
+	assert.False(t, first.ShowCpuPercent)
+	assert.False(t, first.ShowMemoryUsage)
+	assert.False(t, first.ShowNumThreads)
+	assert.False(t, first.ShowProcessAge)
+	assert.Empty(t, first.PageFaults)
+	assert.True(t, deferred.ShowCpuPercent)
+	assert.True(t, deferred.ShowMemoryUsage)
+	assert.True(t, deferred.ShowNumThreads)
+	assert.True(t, deferred.ShowProcessAge)
+	assert.Equal(t, "all", deferred.PageFaults)
+	assert.Equal(t, "pss", deferred.MemoryMode)
+	assert.Equal(t, 3, deferred.Workers)
+	assert.False(t, deferred.ShowArguments)
+
+	// The attribute the tree is sorted by is collected before the sort
+	first, deferred = SplitCollection(DisplayOptions{ShowCpuPercent: true, ShowNumThreads: true, OrderBy: "cpu"})
+	assert.True(t, first.ShowCpuPercent)
+	assert.False(t, deferred.ShowCpuPercent)
+	assert.True(t, deferred.ShowNumThreads)
+
+	first, deferred = SplitCollection(DisplayOptions{OrderBy: "age"})
+	assert.Equal(t, "age", first.OrderBy)
+	assert.False(t, defersUsage(deferred))
+
+	// The age filters need the age of every process
+	first, _ = SplitCollection(DisplayOptions{ShowProcessAge: true, ShowStartTime: true, YoungerThan: time.Hour})
+	assert.True(t, first.ShowProcessAge)
+	assert.True(t, first.ShowStartTime)
+
+	// The thresholds and the cumulative usage need every process
+	first, _ = SplitCollection(DisplayOptions{ShowCpuPercent: true, MinCPU: 5})
+	assert.True(t, first.ShowCpuPercent)
+	first, _ = SplitCollection(DisplayOptions{ShowCpuPercent: true, ShowMemoryUsage: true, ShowCumulative: true})
+	assert.True(t, first.ShowCpuPercent)
+	assert.True(t, first.ShowMemoryUsage)
+
+	// The colors only need the displayed processes
+	first, deferred = SplitCollection(DisplayOptions{ColorAttr: "mem"})
+	assert.Empty(t, first.ColorAttr)
+	assert.True(t, deferred.ShowMemoryUsage)
+	first, _ = SplitCollection(DisplayOptions{ColorAttr: "user", ShowNumThreads: true})
+	assert.Equal(t, "user", first.ColorAttr)
+
+	for _, options := range []DisplayOptions{
+		{ShowCpuPercent: true, WatchInterval: 2},
+		{ShowCpuPercent: true, ShowDiff: true},
+		{ShowCpuPercent: true, ShowThreadsTree: true},
+	} {
+		first, deferred = SplitCollection(options)
+		assert.True(t, first.ShowCpuPercent)
+		assert.False(t, defersUsage(deferred))
+	}
+}
+
+// TestCollectDeferredUsage tests that the deferred usage is only read for the displayed processes
+func TestCollectDeferredUsage(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Age: -1, NumFDs: -1},
+		{PID: int32(os.Getpid()), PPID: 1, Command: "pstree.test", Age: -1, NumFDs: -1},
+		{PID: 999999999, PPID: 1, Command: "pstree.test-gone", Age: -1, NumFDs: -1},
+		{PID: 999999998, PPID: 1, Command: "other", Age: -1, NumFDs: -1},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{Contains: "pstree.test"})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	timings := &CollectionTimings{}
+	_, deferred := SplitCollection(DisplayOptions{ShowNumThreads: true, ShowProcessAge: true})
+	processTree.CollectDeferredUsage(deferred, timings)
+
+	self := processTree.Nodes[processTree.PidToIndexMap[int32(os.Getpid())]]
+	assert.Positive(t, self.NumThreads)
+	assert.GreaterOrEqual(t, self.Age, int64(0))
+	assert.Positive(t, timings.Deferred)
+	assert.Contains(t, timings.Attributes, "threads")
+
+	// The usage of a process that exited stays unknown, the processes not displayed are left alone
+	for _, pid := range []int32{999999999, 999999998} {
+		node := processTree.Nodes[processTree.PidToIndexMap[pid]]
+		assert.Zero(t, node.NumThreads)
+		assert.Equal(t, int64(-1), node.Age)
+	}
+
+	// Nothing is read when nothing was deferred
+	require.NotPanics(t, func() { processTree.CollectDeferredUsage(DisplayOptions{}, nil) })
+}
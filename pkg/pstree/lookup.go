@@ -0,0 +1,182 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the lookups of the usernames of the processes. A username is looked up in
+// the user database of the system, which may ask a directory service, e.g., LDAP through SSSD,
+// and hang for seconds when it misbehaves. So each lookup is given DisplayOptions.LookupTimeout,
+// DefaultLookupTimeout by default, and falls back to the UID, e.g., "uid=1000", when it takes
+// longer. Each UID is only looked up once per collection, whatever the outcome, so thousands of
+// processes trigger a handful of lookups, and a hanging directory service only delays the first
+// process of each user. The lookups go through a UserResolver, so the tests can simulate slow
+// ones.
+package pstree
+
+import (
+	"errors"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// DefaultLookupTimeout is the time a lookup is given without DisplayOptions.LookupTimeout.
+const DefaultLookupTimeout = 250 * time.Millisecond
+
+// errLookupTimeout is returned by lookupWithTimeout when the lookup takes too long.
+var errLookupTimeout = errors.New("lookup timed out")
+
+// UserResolver resolves the UIDs of the processes to usernames.
+type UserResolver interface {
+	// LookupUsername returns the username of the UID, or an error if it has none or cannot be looked up
+	LookupUsername(uid uint32) (string, error)
+}
+
+// SystemUserResolver is the UserResolver of the user database of the system, see os/user.
+type SystemUserResolver struct{}
+
+// LookupUsername looks the UID up in the user database of the system.
+//
+// Parameters:
+//   - uid: The UID to look up
+//
+// Returns:
+//   - string: The username
+//   - error: Any error encountered while looking the UID up
+func (resolver SystemUserResolver) LookupUsername(uid uint32) (string, error) {
+	account, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return "", err
+	}
+	return account.Username, nil
+}
+
+// usernameEntry is the outcome of the lookup of a UID, shared by the processes of the user.
+type usernameEntry struct {
+	// Closed once the lookup is done
+	done chan struct{}
+	// The username, empty if the lookup failed or timed out
+	username string
+}
+
+// usernameCache looks the usernames up for a collection, at most once per UID, even when the
+// processes are collected by concurrent workers.
+type usernameCache struct {
+	// The lookups by UID
+	entries map[uint32]*usernameEntry
+	mutex   sync.Mutex
+	// The resolver the UIDs are looked up with
+	resolver UserResolver
+	// Time each lookup is given
+	timeout time.Duration
+}
+
+// newUsernameCache returns an empty cache for the lookups of a collection.
+//
+// Parameters:
+//   - miniOptions: DisplayOptions struct with the resolver and the timeout of the lookups
+//
+// Returns:
+//   - *usernameCache: The cache, using SystemUserResolver and DefaultLookupTimeout unless set
+func newUsernameCache(miniOptions DisplayOptions) *usernameCache {
+	cache := &usernameCache{
+		entries:  make(map[uint32]*usernameEntry),
+		resolver: miniOptions.UserResolver,
+		timeout:  miniOptions.LookupTimeout,
+	}
+	if cache.resolver == nil {
+		cache.resolver = SystemUserResolver{}
+	}
+	if cache.timeout <= 0 {
+		cache.timeout = DefaultLookupTimeout
+	}
+	return cache
+}
+
+// username returns the username of a UID, looking it up unless another process of the user
+// already did, or waiting for the lookup of another worker to be done.
+//
+// Parameters:
+//   - uid: The UID to look up
+//
+// Returns:
+//   - string: The username
+//   - bool: false if the lookup failed or timed out
+func (cache *usernameCache) username(uid uint32) (string, bool) {
+	cache.mutex.Lock()
+	entry, found := cache.entries[uid]
+	if !found {
+		entry = &usernameEntry{done: make(chan struct{})}
+		cache.entries[uid] = entry
+	}
+	cache.mutex.Unlock()
+
+	if found {
+		<-entry.done
+	} else {
+		entry.username, _ = lookupWithTimeout(cache.timeout, func() (string, error) {
+			return cache.resolver.LookupUsername(uid)
+		})
+		close(entry.done)
+	}
+	return entry.username, entry.username != ""
+}
+
+// processUsername returns the username of the owner of a process, the username of its real UID,
+// or the UID itself if it cannot be looked up in time. The platforms without UIDs, i.e., Windows,
+// ask the process for its owner instead, with the same timeout.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//   - uids: The user IDs of the process, see ProcessUIDs
+//
+// Returns:
+//   - string: The username, see UnresolvedUsername for the fallback
+func (cache *usernameCache) processUsername(proc *process.Process, uids []uint32) string {
+	if len(uids) > 0 {
+		if username, ok := cache.username(uids[0]); ok {
+			return username
+		}
+		return UnresolvedUsername(uids)
+	}
+
+	username, err := lookupWithTimeout(cache.timeout, func() (string, error) {
+		return ProcessUsername(proc)
+	})
+	if err != nil || username == "" {
+		return "?"
+	}
+	return username
+}
+
+// lookupWithTimeout runs a lookup that may block, giving up after the timeout. A lookup that
+// times out is left to finish in the background and its result is dropped.
+//
+// Parameters:
+//   - timeout: Time the lookup is given
+//   - lookup: The lookup to run
+//
+// Returns:
+//   - string: The result of the lookup
+//   - error: The error of the lookup, or errLookupTimeout if it took longer than the timeout
+func lookupWithTimeout(timeout time.Duration, lookup func() (string, error)) (string, error) {
+	type result struct {
+		err   error
+		value string
+	}
+	results := make(chan result, 1)
+
+	go func() {
+		value, err := lookup()
+		results <- result{err: err, value: value}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result := <-results:
+		return result.value, result.err
+	case <-timer.C:
+		return "", errLookupTimeout
+	}
+}
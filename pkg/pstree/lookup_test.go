@@ -0,0 +1,94 @@
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowResolver is a UserResolver taking delay to answer each lookup, and counting them
+type slowResolver struct {
+	delay   time.Duration
+	lookups atomic.Int32
+}
+
+func (resolver *slowResolver) LookupUsername(uid uint32) (string, error) {
+	resolver.lookups.Add(1)
+	time.Sleep(resolver.delay)
+	if uid == 404 {
+		return "", errors.New("unknown user")
+	}
+	return fmt.Sprintf("user%d", uid), nil
+}
+
+func TestUsernameCache(t *testing.T) {
+	resolver := &slowResolver{delay: 10 * time.Millisecond}
+	cache := newUsernameCache(DisplayOptions{UserResolver: resolver})
+	assert.Equal(t, DefaultLookupTimeout, cache.timeout)
+
+	// Concurrent workers looking up the same UIDs only look each of them up once
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			username, ok := cache.username(uint32(1000 + i%2))
+			assert.True(t, ok)
+			assert.Equal(t, fmt.Sprintf("user%d", 1000+i%2), username)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), resolver.lookups.Load())
+
+	// A UID without a username falls back to the UID, and is not looked up again
+	for range 3 {
+		assert.Equal(t, "uid=404", cache.processUsername(&process.Process{Pid: 1}, []uint32{404, 404, 404, 404}))
+	}
+	assert.Equal(t, int32(3), resolver.lookups.Load())
+}
+
+func TestUsernameCacheTimeout(t *testing.T) {
+	resolver := &slowResolver{delay: time.Second}
+	cache := newUsernameCache(DisplayOptions{LookupTimeout: 20 * time.Millisecond, UserResolver: resolver})
+
+	// A hanging lookup falls back to the UID after the timeout, and only delays the first process of the user
+	started := time.Now()
+	for range 10 {
+		assert.Equal(t, "uid=1000", cache.processUsername(&process.Process{Pid: 1}, []uint32{1000}))
+	}
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+	assert.Equal(t, int32(1), resolver.lookups.Load())
+}
+
+func TestLookupWithTimeout(t *testing.T) {
+	value, err := lookupWithTimeout(time.Second, func() (string, error) { return "root", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "root", value)
+
+	_, err = lookupWithTimeout(10*time.Millisecond, func() (string, error) {
+		time.Sleep(time.Second)
+		return "root", nil
+	})
+	assert.ErrorIs(t, err, errLookupTimeout)
+}
+
+func TestGenerateProcessesSlowLookups(t *testing.T) {
+	procs := []*process.Process{{Pid: 1}, {Pid: int32(os.Getpid())}}
+	resolver := &slowResolver{delay: time.Second}
+
+	// The collection doesn't wait for a directory service that hangs
+	started := time.Now()
+	results, _ := generateProcesses(procs, DisplayOptions{LookupTimeout: 20 * time.Millisecond, UserResolver: resolver, Workers: 2}, nil)
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+	require.Len(t, results, 2)
+	assert.Equal(t, fmt.Sprintf("uid=%d", os.Getuid()), results[1].Username)
+	assert.LessOrEqual(t, resolver.lookups.Load(), int32(2))
+}
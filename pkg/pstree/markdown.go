@@ -0,0 +1,225 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the Markdown output mode (--output=markdown), for pasting a process tree
+// into a wiki page or an issue. The displayed processes are written as a nested bullet list,
+// indented by their depth, with the commands quoted in backticks, followed by a table of the
+// enabled metrics of each PID, the same columns as --output=csv. Characters that are significant
+// to Markdown, e.g., the pipes, asterisks, and brackets of a process named *[weird]*, are escaped,
+// and no ANSI escape sequence is ever written, whatever the color flags. The filters applied by
+// MarkProcesses and DropUnmarked are honored, and compact mode applies to the list.
+package pstree
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// markdownEscaper escapes the characters that are significant to Markdown outside of code spans.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "|", `\|`, "~", `\~`, "#", `\#`,
+)
+
+// WriteMarkdown writes the displayed processes as a Markdown bullet list followed by a table of
+// their metrics.
+//
+// Each item is the command of a process in backticks followed by its PID and, with
+// --arguments, its arguments. In compact mode, identical processes collapse into a single item
+// labeled "N*[command]" with the PIDs of the group. The table has a row for each listed PID and
+// is left out when no metric is enabled.
+//
+// Parameters:
+//   - writer: Destination of the output
+//   - rootIndices: Indices of the processes at the top of each tree, as returned by RootIndices
+//
+// Returns:
+//   - error: Any error encountered while writing the output
+func (processTree *ProcessTree) WriteMarkdown(writer io.Writer, rootIndices []int) error {
+	var (
+		builder   strings.Builder
+		columns   []flatColumn
+		err       error
+		header    []string
+		rows      []int
+		rootIndex int
+	)
+
+	if processTree.DisplayOptions.CompactMode {
+		processTree.InitCompactMode()
+	}
+
+	for _, rootIndex = range rootIndices {
+		rows = processTree.writeMarkdownItems(&builder, rootIndex, 0, rows)
+	}
+
+	// The PID, command, and arguments are in the list already
+	header = []string{"PID"}
+	for _, column := range processTree.flatColumns() {
+		if column.Enabled && !slices.Contains([]string{"pid", "depth", "command", "args"}, column.Name) {
+			columns = append(columns, column)
+			header = append(header, markdownEscape(column.Name))
+		}
+	}
+
+	if len(columns) > 0 && len(rows) > 0 {
+		builder.WriteString("\n")
+		writeMarkdownRow(&builder, header)
+		writeMarkdownRow(&builder, slices.Repeat([]string{"---"}, len(header)))
+		for _, pidIndex := range rows {
+			row := []string{fmt.Sprintf("%d", processTree.Nodes[pidIndex].PID)}
+			for _, column := range columns {
+				row = append(row, markdownEscape(column.Value(processTree.Nodes[pidIndex], processTree.Nodes[pidIndex].Depth)))
+			}
+			writeMarkdownRow(&builder, row)
+		}
+	}
+
+	_, err = io.WriteString(writer, builder.String())
+	return err
+}
+
+// writeMarkdownItems recursively writes the list item of a process followed by the items of its
+// descendants.
+//
+// Parameters:
+//   - builder: Builder collecting the output
+//   - pidIndex: Index of the process to write
+//   - depth: Depth of the process relative to the root of its tree
+//   - rows: Indices of the processes listed so far, which get a row in the table
+//
+// Returns:
+//   - []int: The rows with the processes of this item and its descendants appended
+func (processTree *ProcessTree) writeMarkdownItems(builder *strings.Builder, pidIndex int, depth int, rows []int) []int {
+	var (
+		childPidIndex int
+		label         string
+		node          *Process
+		pids          []int32
+	)
+
+	if processTree.DisplayOptions.MaxDepth > 0 && depth > processTree.DisplayOptions.MaxDepth {
+		return rows
+	}
+
+	node = processTree.Nodes[pidIndex]
+	if !node.Print {
+		return rows
+	}
+
+	builder.WriteString(strings.Repeat("  ", depth))
+	builder.WriteString("- ")
+
+	// The orphans and container nodes are not processes, so there is nothing to show but their name
+	if isSyntheticNode(node) {
+		builder.WriteString(markdownEscape(node.Command))
+	} else {
+		label = FormatCommand(processTree.commandName(node), processTree.DisplayOptions.CommandFormat)
+		pids = []int32{node.PID}
+		if processTree.DisplayOptions.CompactMode {
+			if group, ok := processTree.getProcessGroup(pidIndex); ok && group.Count > 1 {
+				label = FormatCompactOutput(node.Command, group.Count, nil, false, processTree.DisplayOptions.CommandFormat)
+				pids = pids[:0]
+				for _, memberIndex := range group.Indices {
+					pids = append(pids, processTree.Nodes[memberIndex].PID)
+					rows = append(rows, memberIndex)
+				}
+			}
+		}
+		if len(pids) == 1 {
+			rows = append(rows, pidIndex)
+		}
+
+		fmt.Fprintf(builder, "%s (%s)", markdownCode(label), strings.Join(PIDsToString(pids), ","))
+		if processTree.DisplayOptions.ShowArguments {
+			if args := processTree.formatArgs(node); args != "" {
+				builder.WriteString(" ")
+				builder.WriteString(markdownEscape(args))
+			}
+		}
+	}
+	builder.WriteString("\n")
+
+	childPidIndex = node.Child
+	for childPidIndex != -1 {
+		if !(processTree.DisplayOptions.CompactMode && ShouldSkipProcess(childPidIndex)) {
+			rows = processTree.writeMarkdownItems(builder, childPidIndex, depth+1, rows)
+		}
+		childPidIndex = processTree.Nodes[childPidIndex].Sister
+	}
+	return rows
+}
+
+// writeMarkdownRow writes a row of a Markdown table.
+//
+// Parameters:
+//   - builder: Builder collecting the output
+//   - cells: The escaped cells of the row
+func writeMarkdownRow(builder *strings.Builder, cells []string) {
+	fmt.Fprintf(builder, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// stripControl removes the ANSI escape sequences and other control characters a process may
+// have put in its command line.
+//
+// Parameters:
+//   - value: The text to clean
+//
+// Returns:
+//   - string: The text without escape sequences and control characters
+func stripControl(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, util.ANSIEscape.ReplaceAllString(value, ""))
+}
+
+// markdownEscape escapes a string for use as Markdown text, e.g., in a list item or a table cell.
+//
+// Parameters:
+//   - value: The string to escape
+//
+// Returns:
+//   - string: The escaped string, e.g., \*\[weird\]\* for *[weird]*
+func markdownEscape(value string) string {
+	return markdownEscaper.Replace(stripControl(value))
+}
+
+// markdownCode quotes a string as a Markdown code span. The span is delimited by more backticks
+// than the longest run of backticks in the string, so a command containing backticks is quoted
+// as a whole. Nothing else needs escaping, Markdown is not interpreted inside a code span.
+//
+// Parameters:
+//   - value: The string to quote
+//
+// Returns:
+//   - string: The code span, e.g., `nginx`, or a`b between two backticks on each side
+func markdownCode(value string) string {
+	var (
+		fence   string
+		longest int
+		run     int
+	)
+
+	value = stripControl(value)
+	for _, r := range value {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+
+	fence = strings.Repeat("`", longest+1)
+	if longest > 0 || strings.HasPrefix(value, " ") || strings.HasSuffix(value, " ") {
+		return fence + " " + value + " " + fence
+	}
+	return fence + value + fence
+}
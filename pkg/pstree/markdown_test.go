@@ -0,0 +1,93 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteMarkdown tests that the list follows the tree and the table lists the enabled metrics
+func TestWriteMarkdown(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", HasCPU: true, CPUPercent: 0.5},
+		{PID: 100, PPID: 1, Command: "sshd", Username: "root", HasCPU: true, CPUPercent: 7.0},
+		{PID: 101, PPID: 100, Command: "*[weird]*", Args: []string{"a|b", "_x_"}, Username: "alice", HasCPU: true, CPUPercent: 20.0},
+		{PID: 200, PPID: 1, Command: "cron", Username: "root", HasCPU: true},
+	}
+	displayOptions := DisplayOptions{ColorAttr: "cpu", ColorSupport: true, ColorizeOutput: true, ShowArguments: true, ShowCpuPercent: true, ShowOwner: true}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	require.NoError(t, processTree.WriteMarkdown(&output, []int{0}))
+
+	// Colors are never written
+	assert.NotContains(t, output.String(), "\x1b")
+	assert.Equal(t, "- `init` (1)\n"+
+		"  - `sshd` (100)\n"+
+		"    - `*[weird]*` (101) a\\|b \\_x\\_\n"+
+		"  - `cron` (200)\n"+
+		"\n"+
+		"| PID | username | cpu% |\n"+
+		"| --- | --- | --- |\n"+
+		"| 1 | root | 0.50 |\n"+
+		"| 100 | root | 7.00 |\n"+
+		"| 101 | alice | 20.00 |\n"+
+		"| 200 | root | 0.00 |\n", output.String())
+}
+
+// TestWriteMarkdownCompact tests that identical processes collapse into one item with a row for each PID
+func TestWriteMarkdownCompact(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "/usr/sbin/nginx"},
+		{PID: 200, PPID: 1, Command: "/usr/sbin/nginx"},
+		{PID: 300, PPID: 1, Command: "cron"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{CompactMode: true, ShowPIDs: true, ShowPPIDs: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	require.NoError(t, processTree.WriteMarkdown(&output, []int{0}))
+
+	assert.Equal(t, "- `init` (1)\n"+
+		"  - `2*[nginx]` (100,200)\n"+
+		"  - `cron` (300)\n"+
+		"\n"+
+		"| PID | ppid |\n"+
+		"| --- | --- |\n"+
+		"| 1 | 0 |\n"+
+		"| 100 | 1 |\n"+
+		"| 200 | 1 |\n"+
+		"| 300 | 1 |\n", output.String())
+}
+
+// TestWriteMarkdownWithoutMetrics tests that the table is left out when no metric is enabled
+func TestWriteMarkdownWithoutMetrics(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), []Process{{PID: 1, PPID: 0, Command: "init"}}, DisplayOptions{})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	require.NoError(t, processTree.WriteMarkdown(&output, []int{0}))
+	assert.Equal(t, "- `init` (1)\n", output.String())
+}
+
+func TestMarkdownEscape(t *testing.T) {
+	assert.Equal(t, `\*\[weird\]\*`, markdownEscape("*[weird]*"))
+	assert.Equal(t, `a\|b \<c\> \# \~ \\`, markdownEscape(`a|b <c> # ~ \`))
+	assert.Equal(t, "red", markdownEscape("\x1b[31mred\x1b[0m"))
+	assert.Equal(t, "ab", markdownEscape("a\x07b"))
+}
+
+func TestMarkdownCode(t *testing.T) {
+	assert.Equal(t, "`nginx`", markdownCode("nginx"))
+	assert.Equal(t, "`*[weird]*`", markdownCode("*[weird]*"))
+	assert.Equal(t, "`` a`b ``", markdownCode("a`b"))
+	assert.Equal(t, "``` a``b ```", markdownCode("a``b"))
+	assert.Equal(t, "`red`", markdownCode("\x1b[31mred"))
+}
@@ -0,0 +1,108 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the highlighting of --contains matches. While the processes are marked,
+// each one records why it is displayed: because it matches the filter itself, because it is
+// part of a matching subtree, or only because it is an ancestor of a match.
+// The printer highlights the matches and dims the ancestors shown for context, so the processes
+// that were searched for stand out from the rest of the tree.
+package pstree
+
+import (
+	"strings"
+)
+
+// PrintReason records why a process is marked for display.
+// The reasons are ordered by precedence, a process marked for several reasons keeps the highest.
+type PrintReason int
+
+const (
+	// The process is displayed because no filter is active, or it was marked by a filter that does not record reasons
+	ReasonNone PrintReason = iota
+	// The process is only displayed as an ancestor of a matching process
+	ReasonAncestor
+	// The process is displayed as a descendant of a matching process
+	ReasonSubtree
+	// The process matches the filter itself
+	ReasonMatch
+)
+
+// recordMatch records the print reasons of a matching process, its ancestors, and, when its
+// subtree is displayed, its descendants.
+//
+// Parameters:
+//   - pidIndex: Index of the matching process
+//   - subtree: Whether the descendants of the matching process are displayed
+func (processTree *ProcessTree) recordMatch(pidIndex int, subtree bool) {
+	var (
+		ppidIndex int
+	)
+
+	processTree.setPrintReason(pidIndex, ReasonMatch)
+
+	ppidIndex = processTree.Nodes[pidIndex].Parent
+	for ppidIndex != -1 {
+		processTree.setPrintReason(ppidIndex, ReasonAncestor)
+		ppidIndex = processTree.Nodes[ppidIndex].Parent
+	}
+
+	if subtree {
+		processTree.recordSubtree(processTree.Nodes[pidIndex].Child)
+	}
+}
+
+// recordSubtree records ReasonSubtree for a process, its sisters, and all of their descendants.
+//
+// Parameters:
+//   - pidIndex: Index of the first process, or -1
+func (processTree *ProcessTree) recordSubtree(pidIndex int) {
+	for pidIndex != -1 {
+		processTree.setPrintReason(pidIndex, ReasonSubtree)
+		processTree.recordSubtree(processTree.Nodes[pidIndex].Child)
+		pidIndex = processTree.Nodes[pidIndex].Sister
+	}
+}
+
+// setPrintReason raises the print reason of a process, a lower reason never replaces a higher one.
+//
+// Parameters:
+//   - pidIndex: Index of the process
+//   - reason: The reason the process is displayed
+func (processTree *ProcessTree) setPrintReason(pidIndex int, reason PrintReason) {
+	processTree.Nodes[pidIndex].PrintReason = max(processTree.Nodes[pidIndex].PrintReason, reason)
+}
+
+// highlightMatch highlights the command of a process according to why it is displayed.
+//
+// With --contains, the matching substring of a matching process is shown in inverse video, or
+// the whole command when the process was matched by another filter, and the command of an
+// ancestor shown only for context is dimmed. Without colors, a matching process is marked with
+// an asterisk after its command instead.
+//
+// Parameters:
+//   - value: Pointer to the command to be highlighted (modified in place)
+//   - pidIndex: Index of the process in the Nodes array
+func (processTree *ProcessTree) highlightMatch(value *string, pidIndex int) {
+	var (
+		contains string
+	)
+
+	contains = processTree.DisplayOptions.Contains
+	if contains == "" {
+		return
+	}
+
+	switch processTree.Nodes[pidIndex].PrintReason {
+	case ReasonMatch:
+		if !processTree.colorEnabled() {
+			*value += "*"
+		} else if strings.Contains(*value, contains) {
+			*value = strings.ReplaceAll(*value, contains, AnsiInverse+contains+AnsiInverseOff)
+		} else {
+			*value = AnsiInverse + *value + AnsiInverseOff
+		}
+	case ReasonAncestor:
+		if processTree.colorEnabled() {
+			*value = AnsiDim + *value + AnsiNormalIntensity
+		}
+	}
+}
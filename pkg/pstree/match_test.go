@@ -0,0 +1,71 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// matchTestProcesses returns a small tree with a single postgres subtree:
+//
+//	init(1) -+- postgres(100) --- worker(101)
+//	         \- cron(200)
+func matchTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "postgres"},
+		{PID: 101, PPID: 100, Command: "worker"},
+		{PID: 200, PPID: 1, Command: "cron"},
+	}
+}
+
+func TestRecordMatch(t *testing.T) {
+	reasons := func(displayOptions DisplayOptions) []PrintReason {
+		processTree := NewProcessTree(0, setupTestLogger(), matchTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		result := []PrintReason{}
+		for _, node := range processTree.Nodes {
+			result = append(result, node.PrintReason)
+		}
+		return result
+	}
+
+	assert.Equal(t, []PrintReason{ReasonAncestor, ReasonMatch, ReasonSubtree, ReasonNone}, reasons(DisplayOptions{Contains: "postgres"}))
+
+	// A match keeps its reason when it is also the ancestor of another match
+	assert.Equal(t, []PrintReason{ReasonAncestor, ReasonMatch, ReasonMatch, ReasonMatch}, reasons(DisplayOptions{Contains: "o"}))
+
+	// Without a filter, nothing is recorded
+	assert.Equal(t, []PrintReason{ReasonNone, ReasonNone, ReasonNone, ReasonNone}, reasons(DisplayOptions{}))
+}
+
+func TestHighlightMatch(t *testing.T) {
+	render := func(displayOptions DisplayOptions) []string {
+		output := renderTree(t, matchTestProcesses(), displayOptions)
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+		return lines
+	}
+
+	// Without colors, the matching process is marked with an asterisk
+	lines := render(DisplayOptions{Contains: "gres"})
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasSuffix(lines[0], " init"))
+	assert.True(t, strings.HasSuffix(lines[1], " postgres*"))
+	assert.True(t, strings.HasSuffix(lines[2], " worker"))
+
+	// With colors, the matching substring is inverted and the ancestors are dimmed
+	lines = render(DisplayOptions{Contains: "gres", ColorAttr: "cpu", ColorCount: 8, ColorSupport: true})
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], AnsiDim+"init"+AnsiNormalIntensity)
+	assert.Contains(t, lines[1], "post"+AnsiInverse+"gres"+AnsiInverseOff)
+	assert.NotContains(t, lines[1], "*")
+
+	// Without --contains, nothing is highlighted
+	lines = render(DisplayOptions{MinCPU: 0.5, ColorAttr: "cpu", ColorCount: 8, ColorSupport: true})
+	assert.NotContains(t, strings.Join(lines, "\n"), AnsiInverse)
+}
@@ -0,0 +1,191 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the memory value shown with --memory. By default the resident set size
+// is shown, --mem-field selects the virtual memory size or the swapped out memory instead, and
+// --mem-unit selects the unit it is shown in. All of them are read from the MemoryInfoStat
+// collected for each process, so choosing another field doesn't collect anything more. The
+// chosen field is also used by the compact group sums, --cumulative, and --order-by=mem.
+// --mem-format shows the value as a percentage of the installed memory instead of, or next to,
+// the absolute value. On Linux, --mem-mode replaces the resident set size with the proportional
+// or unique set size, so the sums of forked workers don't count their shared pages repeatedly.
+// Those are read from smaps_rollup, elsewhere the resident set size is used instead.
+package pstree
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// memoryLabels maps each --mem-field to the label shown in front of its value, e.g., (v:1.2 GiB).
+var memoryLabels = map[string]string{
+	"pss":  "pss",
+	"rss":  "m",
+	"swap": "sw",
+	"uss":  "uss",
+	"vms":  "v",
+}
+
+// MemoryValue returns the memory usage of a process in the given --mem-field or --mem-mode.
+//
+// Parameters:
+//   - process: The process to read
+//   - memoryField: One of "pss", "rss", "swap", "uss", or "vms"; an empty or unknown field means
+//     "rss", and so do "pss" and "uss" when the shared memory could not be read
+//
+// Returns:
+//   - uint64: The memory usage in bytes, or 0 if the memory usage was not collected, see HasMemory
+func MemoryValue(process *Process, memoryField string) uint64 {
+	if !process.HasMemory || process.MemoryInfo == nil {
+		return 0
+	}
+
+	switch memoryField {
+	case "pss":
+		if process.SharedMemory != nil {
+			return process.SharedMemory.PSS
+		}
+		return process.MemoryInfo.RSS
+	case "uss":
+		if process.SharedMemory != nil {
+			return process.SharedMemory.USS
+		}
+		return process.MemoryInfo.RSS
+	case "swap":
+		return process.MemoryInfo.Swap
+	case "vms":
+		return process.MemoryInfo.VMS
+	default:
+		return process.MemoryInfo.RSS
+	}
+}
+
+// memoryField returns the field of the memory values: DisplayOptions.MemoryField, with the
+// resident set size replaced by the proportional or unique set size of DisplayOptions.MemoryMode.
+//
+// Returns:
+//   - string: The field, see MemoryValue
+func (processTree *ProcessTree) memoryField() string {
+	var (
+		field string
+		mode  string
+	)
+
+	field = processTree.DisplayOptions.MemoryField
+	mode = processTree.DisplayOptions.MemoryMode
+	if (field == "" || field == "rss") && (mode == "pss" || mode == "uss") {
+		return mode
+	}
+	return field
+}
+
+// memoryValue returns the memory usage of a process in the field returned by memoryField.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - uint64: The memory usage in bytes, or 0 if the memory usage was not collected
+func (processTree *ProcessTree) memoryValue(process *Process) uint64 {
+	return MemoryValue(process, processTree.memoryField())
+}
+
+// memoryLabel returns the label shown in front of the memory values of the field returned by memoryField.
+//
+// Returns:
+//   - string: The label, e.g., "m" for rss
+func (processTree *ProcessTree) memoryLabel() string {
+	if label, ok := memoryLabels[processTree.memoryField()]; ok {
+		return label
+	}
+	return memoryLabels["rss"]
+}
+
+// memoryPercent returns the memory usage of a process in the field returned by memoryField as a
+// percentage of the installed memory.
+//
+// The MemoryPercent collected for the process is used for the resident set size when it is
+// available, otherwise the value is divided by DisplayOptions.InstalledMemory.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - float64: The percentage, or -1 if it cannot be determined
+func (processTree *ProcessTree) memoryPercent(process *Process) float64 {
+	field := processTree.memoryField()
+	if (field == "" || field == "rss") && process.MemoryPercent > 0 {
+		return float64(process.MemoryPercent)
+	}
+	return processTree.bytesPercent(processTree.memoryValue(process))
+}
+
+// bytesPercent returns a number of bytes as a percentage of DisplayOptions.InstalledMemory.
+//
+// Parameters:
+//   - usage: The memory usage in bytes
+//
+// Returns:
+//   - float64: The percentage, or -1 if the installed memory is not known
+func (processTree *ProcessTree) bytesPercent(usage uint64) float64 {
+	if processTree.DisplayOptions.InstalledMemory == 0 {
+		return -1
+	}
+	return float64(usage) / float64(processTree.DisplayOptions.InstalledMemory) * 100
+}
+
+// formatPercent formats a memory percentage with one decimal, capped at 100% since the sums of
+// compact groups and subtrees may exceed the installed memory through shared pages.
+//
+// Parameters:
+//   - percent: The percentage, or a negative value if it is not known
+//
+// Returns:
+//   - string: The formatted percentage, e.g., "0.3%", or "?%" if it is not known
+func formatPercent(percent float64) string {
+	if percent < 0 {
+		return "?%"
+	}
+	return fmt.Sprintf("%.1f%%", math.Min(percent, 100))
+}
+
+// formatMemory formats a memory usage for display using DisplayOptions.MemoryUnit and
+// DisplayOptions.MemoryFormat, e.g., (m:1.5 MiB), (m:0.3%), or (m:1.5 MiB, 0.3%).
+// With --cumulative, the usage of the subtree follows in parentheses, e.g., (m:1.5 MiB (12.0 MiB)).
+// A usage that was not collected is shown as (m:-) rather than as 0 bytes.
+//
+// Parameters:
+//   - usage: The memory usage in bytes
+//   - collected: Whether the memory usage was collected, see HasMemory
+//   - percent: The memory usage as a percentage of the installed memory, see memoryPercent
+//   - cumulative: The memory usage of the subtree in bytes, shown when --cumulative is used
+//
+// Returns:
+//   - string: The formatted memory usage
+func (processTree *ProcessTree) formatMemory(usage uint64, collected bool, percent float64, cumulative uint64) string {
+	var (
+		format func(usage uint64, percent float64) string
+		value  string
+	)
+
+	format = func(usage uint64, percent float64) string {
+		switch processTree.DisplayOptions.MemoryFormat {
+		case "pct":
+			return formatPercent(percent)
+		case "both":
+			return fmt.Sprintf("%s, %s", util.FormatByteSize(usage, processTree.DisplayOptions.MemoryUnit), formatPercent(percent))
+		default:
+			return util.FormatByteSize(usage, processTree.DisplayOptions.MemoryUnit)
+		}
+	}
+
+	value = "-"
+	if collected {
+		value = format(usage, percent)
+	}
+	if processTree.DisplayOptions.ShowCumulative {
+		value = fmt.Sprintf("%s (%s)", value, format(cumulative, processTree.bytesPercent(cumulative)))
+	}
+	return fmt.Sprintf("(%s:%s)", processTree.memoryLabel(), value)
+}
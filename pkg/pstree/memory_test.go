@@ -0,0 +1,137 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryTestProcesses returns processes where the order by RSS and by virtual size differ.
+func memoryTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1024, VMS: 4096, Swap: 0}},
+		{PID: 100, PPID: 1, Command: "java", HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 512 * 1024 * 1024, VMS: 1024 * 1024 * 1024, Swap: 1536}},
+		{PID: 200, PPID: 1, Command: "worker", HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 2048, VMS: 8 * 1024 * 1024 * 1024}},
+		{PID: 300, PPID: 1, Command: "worker", HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 2048, VMS: 1024 * 1024 * 1024}},
+	}
+}
+
+func TestMemoryValue(t *testing.T) {
+	process := memoryTestProcesses()[1]
+
+	assert.Equal(t, uint64(512*1024*1024), MemoryValue(&process, ""))
+	assert.Equal(t, uint64(512*1024*1024), MemoryValue(&process, "rss"))
+	assert.Equal(t, uint64(1024*1024*1024), MemoryValue(&process, "vms"))
+	assert.Equal(t, uint64(1536), MemoryValue(&process, "swap"))
+	assert.Zero(t, MemoryValue(&Process{}, "vms"))
+
+	// The memory usage only counts once HasMemory marks it as collected
+	assert.Zero(t, MemoryValue(&Process{MemoryInfo: memoryTestProcesses()[1].MemoryInfo}, "rss"))
+}
+
+func TestMemoryField(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		displayOptions.ShowMemoryUsage = true
+		return renderTree(t, memoryTestProcesses(), displayOptions)
+	}
+
+	output := render(DisplayOptions{})
+	assert.Contains(t, output, "(m:512.0 MiB) java")
+
+	output = render(DisplayOptions{MemoryField: "vms", MemoryUnit: "M"})
+	assert.Contains(t, output, "(v:1024.0 MiB) java")
+
+	output = render(DisplayOptions{MemoryField: "swap"})
+	assert.Contains(t, output, "(sw:1.5 KiB) java")
+
+	// The compact group sums the chosen field
+	output = render(DisplayOptions{CompactMode: true, MemoryField: "vms"})
+	assert.Contains(t, output, "(v:9.0 GiB) worker")
+
+	// --order-by=mem sorts by the chosen field
+	output = render(DisplayOptions{MemoryField: "vms", OrderBy: "mem", OrderDir: "desc"})
+	assert.Regexp(t, `(?s)\(v:8\.0 GiB\) worker.*\(v:1\.0 GiB\) java`, output)
+	output = render(DisplayOptions{OrderBy: "mem", OrderDir: "desc"})
+	assert.Regexp(t, `(?s)java.*worker`, output)
+}
+
+func TestMemoryFormat(t *testing.T) {
+	render := func(displayOptions DisplayOptions, processes []Process) string {
+		displayOptions.InstalledMemory = 1024 * 1024 * 1024
+		displayOptions.ShowMemoryUsage = true
+		return renderTree(t, processes, displayOptions)
+	}
+
+	// Without a collected MemoryPercent, the percentage is computed from the installed memory
+	output := render(DisplayOptions{MemoryFormat: "pct"}, memoryTestProcesses())
+	assert.Contains(t, output, "(m:50.0%) java")
+
+	output = render(DisplayOptions{MemoryFormat: "both"}, memoryTestProcesses())
+	assert.Contains(t, output, "(m:512.0 MiB, 50.0%) java")
+
+	// The collected MemoryPercent is preferred
+	processes := memoryTestProcesses()
+	processes[1].MemoryPercent = 12.34
+	output = render(DisplayOptions{MemoryFormat: "pct"}, processes)
+	assert.Contains(t, output, "(m:12.3%) java")
+
+	// The other fields are always computed
+	output = render(DisplayOptions{MemoryField: "vms", MemoryFormat: "pct"}, processes)
+	assert.Contains(t, output, "(v:100.0%) java")
+
+	// Compact groups show the summed percentage, capped at 100%
+	output = render(DisplayOptions{CompactMode: true, MemoryField: "vms", MemoryFormat: "pct"}, memoryTestProcesses())
+	assert.Contains(t, output, "(v:100.0%) worker")
+	processes = memoryTestProcesses()
+	processes[2].MemoryPercent = 0.25
+	processes[3].MemoryPercent = 0.5
+	output = render(DisplayOptions{CompactMode: true, MemoryFormat: "pct"}, processes)
+	assert.Contains(t, output, "(m:0.8%) worker")
+}
+
+func TestFormatPercent(t *testing.T) {
+	assert.Equal(t, "0.3%", formatPercent(0.26))
+	assert.Equal(t, "100.0%", formatPercent(250))
+	assert.Equal(t, "?%", formatPercent(-1))
+
+	processTree := &ProcessTree{}
+	assert.Equal(t, -1.0, processTree.memoryPercent(&Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1024}}))
+}
+
+func TestMemoryMode(t *testing.T) {
+	// Two forked workers whose resident memory is mostly shared, and one whose PSS could not be read
+	processes := func() []Process {
+		processes := memoryTestProcesses()
+		processes[2].MemoryInfo.RSS = 100 * 1024 * 1024
+		processes[2].SharedMemory = &SharedMemoryStat{PSS: 30 * 1024 * 1024, USS: 10 * 1024 * 1024}
+		processes[3].MemoryInfo.RSS = 100 * 1024 * 1024
+		processes[3].SharedMemory = &SharedMemoryStat{PSS: 30 * 1024 * 1024, USS: 10 * 1024 * 1024}
+		return processes
+	}
+	render := func(displayOptions DisplayOptions) string {
+		displayOptions.ShowMemoryUsage = true
+		return renderTree(t, processes(), displayOptions)
+	}
+
+	// The compact group sums the chosen mode instead of the shared pages of every member
+	output := render(DisplayOptions{CompactMode: true})
+	assert.Contains(t, output, "(m:200.0 MiB) worker")
+	output = render(DisplayOptions{CompactMode: true, MemoryMode: "pss"})
+	assert.Contains(t, output, "(pss:60.0 MiB) worker")
+	output = render(DisplayOptions{CompactMode: true, MemoryMode: "uss"})
+	assert.Contains(t, output, "(uss:20.0 MiB) worker")
+
+	// Processes without a PSS fall back to their RSS
+	output = render(DisplayOptions{MemoryMode: "pss"})
+	assert.Contains(t, output, "(pss:512.0 MiB) java")
+
+	// --order-by=mem sorts by the chosen mode
+	output = render(DisplayOptions{MemoryMode: "pss", OrderBy: "mem", OrderDir: "asc"})
+	assert.Regexp(t, `(?s)worker.*worker.*java`, output)
+
+	process := processes()[2]
+	assert.Equal(t, uint64(30*1024*1024), MemoryValue(&process, "pss"))
+	assert.Equal(t, uint64(10*1024*1024), MemoryValue(&process, "uss"))
+	assert.Equal(t, uint64(100*1024*1024), MemoryValue(&process, "rss"))
+}
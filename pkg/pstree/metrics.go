@@ -1,8 +1,12 @@
 package pstree
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
-	"syscall"
+	"io"
+	"strconv"
+	"strings"
 
 	"github.com/bananazon/pstree/pkg/globals"
 	"github.com/shirou/gopsutil/v4/cpu"
@@ -10,410 +14,554 @@ import (
 	"github.com/shirou/gopsutil/v4/process"
 )
 
-// ProcessArgs sends a function to the provided channel that retrieves command line arguments for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessArgs retrieves command line arguments for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessArgs(c chan func(proc *process.Process) (args []string, err error)) {
-	c <- (func(proc *process.Process) (args []string, err error) {
-		args, err = proc.CmdlineSlice()
-		return args, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - args: Command line arguments for a process
+//   - err: Any error encountered while retrieving it
+func ProcessArgs(proc *process.Process) (args []string, err error) {
+	args, err = proc.CmdlineSlice()
+	return args, err
 }
 
-// ProcessBackground sends a function to the provided channel that retrieves true if the process is in the background.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessBackground retrieves whether a process is in the background.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessBackground(c chan func(proc *process.Process) (background bool, err error)) {
-	c <- (func(proc *process.Process) (background bool, err error) {
-		background, err = proc.Background()
-		return background, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - background: True if the process is in the background
+//   - err: Any error encountered while retrieving it
+func ProcessBackground(proc *process.Process) (background bool, err error) {
+	background, err = proc.Background()
+	return background, err
 }
 
-// ProcessCommandName sends a function to the provided channel that retrieves the executable path of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessCommandName retrieves the executable path of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessCommandName(c chan func(proc *process.Process) (string, error)) {
-	c <- (func(proc *process.Process) (command string, err error) {
-		// First check for exe, which should be the full path to the
-		exe, err := proc.Exe()
-		if err == nil && exe != "" {
-			// Return the full path
-			if globals.GetDebugLevel() > 1 {
-				globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (ExeWithContext): %s", proc.Pid, exe))
-			}
-			return exe, nil
-		}
-
-		// Either there was en error or exe was empty so let's try to get the command slice
-		cmdLine, err := proc.CmdlineSlice()
-		if err == nil && len(cmdLine) > 0 {
-			// Return the first element of the command line slice, which is the executable
-			if globals.GetDebugLevel() > 1 {
-				globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (CmdlineSliceWithContext): %s", proc.Pid, cmdLine[0]))
-			}
-			return cmdLine[0], nil
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - command: The executable path of a process
+//   - err: Any error encountered while retrieving it
+func ProcessCommandName(proc *process.Process) (command string, err error) {
+	// First check for exe, which should be the full path to the
+	exe, err := proc.Exe()
+	if err == nil && exe != "" {
+		// Return the full path
+		if globals.GetDebugLevel() > 1 {
+			globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (ExeWithContext): %s", proc.Pid, exe))
 		}
+		return exe, nil
+	}
 
-		// Crud, we don't have a command name so let's try to get the command basename
-		name, err := proc.Name()
-		if err == nil && name != "" {
-			// Return name, which is the basename of the command
-			if globals.GetDebugLevel() > 1 {
-				globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (NameWithContext): %s", proc.Pid, name))
-			}
-			return name, nil
+	// Either there was en error or exe was empty so let's try to get the command slice
+	cmdLine, err := proc.CmdlineSlice()
+	if err == nil && len(cmdLine) > 0 {
+		// Return the first element of the command line slice, which is the executable
+		if globals.GetDebugLevel() > 1 {
+			globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (CmdlineSliceWithContext): %s", proc.Pid, cmdLine[0]))
 		}
+		return cmdLine[0], nil
+	}
 
-		// Well crap, I give up, let's return the PID
+	// Crud, we don't have a command name so let's try to get the command basename
+	name, err := proc.Name()
+	if err == nil && name != "" {
+		// Return name, which is the basename of the command
 		if globals.GetDebugLevel() > 1 {
-			globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (PID): %d", proc.Pid, proc.Pid))
+			globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (NameWithContext): %s", proc.Pid, name))
 		}
-		return fmt.Sprintf("[PID %d]", proc.Pid), nil
-	})
+		return name, nil
+	}
+
+	// Well crap, I give up, let's return the PID
+	if globals.GetDebugLevel() > 1 {
+		globals.GetLogger().Debug(fmt.Sprintf("ProcessCommandName, PID %d (PID): %d", proc.Pid, proc.Pid))
+	}
+	return fmt.Sprintf("[PID %d]", proc.Pid), nil
 }
 
-// ProcessChildren sends a function to the provided channel that retrieves a slice of child processes for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessChildren retrieves a slice of child processes for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessChildren(c chan func(proc *process.Process) (children []*process.Process, err error)) {
-	c <- (func(proc *process.Process) (children []*process.Process, err error) {
-		children, err = proc.Children()
-		return children, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - children: A slice of child processes for a process
+//   - err: Any error encountered while retrieving it
+func ProcessChildren(proc *process.Process) (children []*process.Process, err error) {
+	children, err = proc.Children()
+	return children, err
 }
 
-// ProcessConnections sends a function to the provided channel that retrieves network connections for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessConnections retrieves network connections for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessConnections(c chan func(proc *process.Process) (connections []net.ConnectionStat, err error)) {
-	c <- (func(proc *process.Process) (connections []net.ConnectionStat, err error) {
-		connections, err = proc.Connections()
-		return connections, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - connections: Network connections for a process
+//   - err: Any error encountered while retrieving it
+func ProcessConnections(proc *process.Process) (connections []net.ConnectionStat, err error) {
+	connections, err = proc.Connections()
+	return connections, err
 }
 
-// ProcessCpuAffinity sends a function to the provided channel that retrieves CPU affinty for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessCpuAffinity retrieves CPU affinity for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessCpuAffinity(c chan func(proc *process.Process) (cpuAffinity []int32, err error)) {
-	c <- (func(proc *process.Process) (cpuAffinity []int32, err error) {
-		cpuAffinity, err = proc.CPUAffinity()
-		return cpuAffinity, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - cpuAffinity: CPU affinity for a process
+//   - err: Any error encountered while retrieving it
+func ProcessCpuAffinity(proc *process.Process) (cpuAffinity []int32, err error) {
+	cpuAffinity, err = proc.CPUAffinity()
+	return cpuAffinity, err
 }
 
-// ProcessCpuPercent sends a function to the provided channel that retrieves CPU usage percentage for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessCpuPercent retrieves CPU usage percentage for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessCpuPercent(c chan func(proc *process.Process) (cpuPercent float64, err error)) {
-	c <- (func(proc *process.Process) (cpuPercent float64, err error) {
-		cpuPercent, err = proc.CPUPercent()
-		return cpuPercent, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - cpuPercent: CPU usage percentage for a process
+//   - err: Any error encountered while retrieving it
+func ProcessCpuPercent(proc *process.Process) (cpuPercent float64, err error) {
+	cpuPercent, err = proc.CPUPercent()
+	return cpuPercent, err
 }
 
-// ProcessCpuTimes sends a function to the provided channel that retrieves CPU times for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessCpuTimes retrieves CPU times for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessCpuTimes(c chan func(proc *process.Process) (cpuTimes *cpu.TimesStat, err error)) {
-	c <- (func(proc *process.Process) (cpuTimes *cpu.TimesStat, err error) {
-		cpuTimes, err = proc.Times()
-		return cpuTimes, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - cpuTimes: CPU times for a process
+//   - err: Any error encountered while retrieving it
+func ProcessCpuTimes(proc *process.Process) (cpuTimes *cpu.TimesStat, err error) {
+	cpuTimes, err = proc.Times()
+	return cpuTimes, err
 }
 
-// ProcessCreateTime sends a function to the provided channel that retrieves the creation time of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessCreateTime retrieves the creation time of a process.
 // The creation time is converted from milliseconds to seconds before being returned.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessCreateTime(c chan func(proc *process.Process) (createTime int64, err error)) {
-	c <- (func(proc *process.Process) (createTime int64, err error) {
-		createTime, err = proc.CreateTime()
-		return createTime / 1000, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - createTime: The creation time of a process
+//   - err: Any error encountered while retrieving it
+func ProcessCreateTime(proc *process.Process) (createTime int64, err error) {
+	createTime, err = proc.CreateTime()
+	return createTime / 1000, err
+}
+
+// ProcessCwd retrieves the current working directory of a process.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - cwd: The current working directory of a process
+//   - err: Any error encountered while retrieving it
+func ProcessCwd(proc *process.Process) (cwd string, err error) {
+	cwd, err = proc.Cwd()
+	return cwd, err
 }
 
-// ProcessEnvironment sends a function to the provided channel that retrieves environment variables for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessEnvironment retrieves environment variables for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessEnvironment(c chan func(proc *process.Process) (environment []string, err error)) {
-	c <- (func(proc *process.Process) (environment []string, err error) {
-		environment, err = proc.Environ()
-		return environment, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - environment: Environment variables for a process
+//   - err: Any error encountered while retrieving it
+func ProcessEnvironment(proc *process.Process) (environment []string, err error) {
+	environment, err = proc.Environ()
+	return environment, err
 }
 
-// ProcessForeground sends a function to the provided channel that retrieves true if the process is in the foreground.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessForeground retrieves whether a process is in the foreground.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessForeground(c chan func(proc *process.Process) (foreground bool, err error)) {
-	c <- (func(proc *process.Process) (foreground bool, err error) {
-		foreground, err = proc.Foreground()
-		return foreground, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - foreground: True if the process is in the foreground
+//   - err: Any error encountered while retrieving it
+func ProcessForeground(proc *process.Process) (foreground bool, err error) {
+	foreground, err = proc.Foreground()
+	return foreground, err
 }
 
-// ProcessGIDs sends a function to the provided channel that retrieves group IDs for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessGIDs retrieves group IDs for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessGIDs(c chan func(proc *process.Process) (gids []uint32, err error)) {
-	c <- (func(proc *process.Process) (gids []uint32, err error) {
-		gids, err = proc.Gids()
-		return gids, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - gids: Group IDs for a process
+//   - err: Any error encountered while retrieving it
+func ProcessGIDs(proc *process.Process) (gids []uint32, err error) {
+	gids, err = proc.Gids()
+	return gids, err
 }
 
-// ProcessGroups sends a function to the provided channel that retrieves supplementary group IDs for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessGroups retrieves supplementary group IDs for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessGroups(c chan func(proc *process.Process) (groups []uint32, err error)) {
-	c <- (func(proc *process.Process) (groups []uint32, err error) {
-		groups, err = proc.Groups()
-		return groups, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - groups: Supplementary group IDs for a process
+//   - err: Any error encountered while retrieving it
+func ProcessGroups(proc *process.Process) (groups []uint32, err error) {
+	groups, err = proc.Groups()
+	return groups, err
 }
 
-// ProcessIOCounters sends a function to the provided channel that retrieves IO counters for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessIOCounters retrieves IO counters for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessIOCounters(c chan func(proc *process.Process) (ioCounters *process.IOCountersStat, err error)) {
-	c <- (func(proc *process.Process) (ioCounters *process.IOCountersStat, err error) {
-		ioCounters, err = proc.IOCounters()
-		return ioCounters, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - ioCounters: IO counters for a process
+//   - err: Any error encountered while retrieving it
+func ProcessIOCounters(proc *process.Process) (ioCounters *process.IOCountersStat, err error) {
+	ioCounters, err = proc.IOCounters()
+	return ioCounters, err
 }
 
-// ProcessMemoryInfo sends a function to the provided channel that retrieves memory usage statistics for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessMemoryInfo retrieves memory usage statistics for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessMemoryInfo(c chan func(proc *process.Process) (memoryInfo *process.MemoryInfoStat, err error)) {
-	c <- (func(proc *process.Process) (memoryInfo *process.MemoryInfoStat, err error) {
-		memoryInfo, err = proc.MemoryInfo()
-		return memoryInfo, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - memoryInfo: Memory usage statistics for a process
+//   - err: Any error encountered while retrieving it
+func ProcessMemoryInfo(proc *process.Process) (memoryInfo *process.MemoryInfoStat, err error) {
+	memoryInfo, err = proc.MemoryInfo()
+	return memoryInfo, err
+}
+
+// ProcessMemoryInfoEx retrieves platform-specific memory usage statistics for a process.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - memoryInfoEx: Platform-specific memory usage statistics for a process
+//   - err: Any error encountered while retrieving it
+func ProcessMemoryInfoEx(proc *process.Process) (memoryInfoEx *process.MemoryInfoExStat, err error) {
+	memoryInfoEx, err = proc.MemoryInfoEx()
+	return memoryInfoEx, err
+}
+
+// ProcessMemoryPercent retrieves memory usage percentage for a process.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - memoryPercent: Memory usage percentage for a process
+//   - err: Any error encountered while retrieving it
+func ProcessMemoryPercent(proc *process.Process) (memoryPercent float32, err error) {
+	memoryPercent, err = proc.MemoryPercent()
+	return memoryPercent, err
 }
 
-// ProcessMemoryInfoEx sends a function to the provided channel that retrieves platform-spememory usage statistics for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// SharedMemoryReader reads the memory usage of a process with its shared pages accounted for.
+// The reader of the platform is returned by newSharedMemoryReader in metrics_linux.go and
+// metrics_other.go, tests replace sharedMemoryReader to exercise the fallback to the RSS.
+type SharedMemoryReader interface {
+	// SharedMemory returns the PSS and USS of the process with the given PID, or
+	// ErrSharedMemoryUnavailable if the platform doesn't report them
+	SharedMemory(pid int32) (*SharedMemoryStat, error)
+}
+
+// ErrSharedMemoryUnavailable is returned by a SharedMemoryReader when the PSS and USS cannot be read.
+var ErrSharedMemoryUnavailable = errors.New("the proportional and unique set sizes are not available")
+
+// sharedMemoryReader is used by ProcessSharedMemory.
+var sharedMemoryReader SharedMemoryReader = newSharedMemoryReader()
+
+// ProcessSharedMemory retrieves the proportional and unique set sizes of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessMemoryInfoEx(c chan func(proc *process.Process) (memoryInfoEx *process.MemoryInfoExStat, err error)) {
-	c <- (func(proc *process.Process) (memoryInfoEx *process.MemoryInfoExStat, err error) {
-		memoryInfoEx, err = proc.MemoryInfoEx()
-		return memoryInfoEx, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - sharedMemory: The PSS and USS of the process
+//   - err: Any error encountered while retrieving them, ErrSharedMemoryUnavailable on platforms without them
+func ProcessSharedMemory(proc *process.Process) (sharedMemory *SharedMemoryStat, err error) {
+	sharedMemory, err = sharedMemoryReader.SharedMemory(proc.Pid)
+	return sharedMemory, err
 }
 
-// ProcessMemoryPercent sends a function to the provided channel that retrieves memory usage percentage for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// parseSmapsRollup parses the contents of a Linux /proc/<pid>/smaps_rollup file.
+//
+// The PSS is read from the Pss line, and the USS is the sum of the Private_Clean and the
+// Private_Dirty lines. The values in the file are in kB.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessMemoryPercent(c chan func(proc *process.Process) (memoryPercent float32, err error)) {
-	c <- (func(proc *process.Process) (memoryPercent float32, err error) {
-		memoryPercent, err = proc.MemoryPercent()
-		return memoryPercent, err
-	})
+//   - reader: The contents of the file
+//
+// Returns:
+//   - *SharedMemoryStat: The PSS and USS in bytes
+//   - error: An error if the file could not be read or has no Pss line
+func parseSmapsRollup(reader io.Reader) (*SharedMemoryStat, error) {
+	var (
+		found        bool
+		sharedMemory SharedMemoryStat
+	)
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "Pss:":
+			sharedMemory.PSS = value * 1024
+			found = true
+		case "Private_Clean:", "Private_Dirty:":
+			sharedMemory.USS += value * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrSharedMemoryUnavailable
+	}
+	return &sharedMemory, nil
 }
 
-// ProcessNumCtxSwitches sends a function to the provided channel that retrieves the number of context switches for process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessNice retrieves the nice value of a process. On Windows the priority class of the
+// process is mapped to an approximate nice value, see niceFromPriority.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessNumCtxSwitches(c chan func(proc *process.Process) (numContextSwitches *process.NumCtxSwitchesStat, err error)) {
-	c <- (func(proc *process.Process) (numContextSwitches *process.NumCtxSwitchesStat, err error) {
-		numContextSwitches, err = proc.NumCtxSwitches()
-		return numContextSwitches, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - nice: The nice value of the process, from -20 to 19
+//   - err: Any error encountered while retrieving it
+func ProcessNice(proc *process.Process) (nice int32, err error) {
+	nice, err = proc.Nice()
+	if err != nil {
+		return 0, err
+	}
+	return niceFromPriority(nice), nil
 }
 
-// ProcessNumFDs sends a function to the provided channel that retrieves the number of file descriptors used by a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessNumCtxSwitches retrieves the number of context switches for process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessNumFDs(c chan func(proc *process.Process) (numFDs int32, err error)) {
-	c <- (func(proc *process.Process) (numFDs int32, err error) {
-		numFDs, err = proc.NumFDs()
-		return numFDs, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - numContextSwitches: The number of context switches for process
+//   - err: Any error encountered while retrieving it
+func ProcessNumCtxSwitches(proc *process.Process) (numContextSwitches *process.NumCtxSwitchesStat, err error) {
+	numContextSwitches, err = proc.NumCtxSwitches()
+	return numContextSwitches, err
 }
 
-// ProcessNumThreads sends a function to the provided channel that retrieves the number of threads used by a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessNumFDs retrieves the number of file descriptors used by a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessNumThreads(c chan func(proc *process.Process) (numThreads int32, err error)) {
-	c <- (func(proc *process.Process) (numThreads int32, err error) {
-		numThreads, err = proc.NumThreads()
-		return numThreads, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - numFDs: The number of file descriptors used by a process
+//   - err: Any error encountered while retrieving it
+func ProcessNumFDs(proc *process.Process) (numFDs int32, err error) {
+	numFDs, err = proc.NumFDs()
+	return numFDs, err
 }
 
-// ProcessOpenFiles sends a function to the provided channel that retrieves a slice of OpenFiles used by a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessNumThreads retrieves the number of threads used by a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessOpenFiles(c chan func(proc *process.Process) (openFilesStat []process.OpenFilesStat, err error)) {
-	c <- (func(proc *process.Process) (openFilesStat []process.OpenFilesStat, err error) {
-		openFilesStat, err = proc.OpenFiles()
-		return openFilesStat, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - numThreads: The number of threads used by a process
+//   - err: Any error encountered while retrieving it
+func ProcessNumThreads(proc *process.Process) (numThreads int32, err error) {
+	numThreads, err = proc.NumThreads()
+	return numThreads, err
 }
 
-// ProcessPageFaults sends a function to the provided channel that retrieves pagefaults for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessOpenFiles retrieves a slice of OpenFiles used by a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessPageFaults(c chan func(proc *process.Process) (pageFaults *process.PageFaultsStat, err error)) {
-	c <- (func(proc *process.Process) (pageFaults *process.PageFaultsStat, err error) {
-		pageFaults, err = proc.PageFaults()
-		return pageFaults, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - openFilesStat: A slice of OpenFiles used by a process
+//   - err: Any error encountered while retrieving it
+func ProcessOpenFiles(proc *process.Process) (openFilesStat []process.OpenFilesStat, err error) {
+	openFilesStat, err = proc.OpenFiles()
+	return openFilesStat, err
 }
 
-// ProcessParent sends a function to the provided channel that retrieves the parent process of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessPageFaults retrieves pagefaults for a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessParent(c chan func(proc *process.Process) (parent *process.Process, err error)) {
-	c <- (func(proc *process.Process) (parent *process.Process, err error) {
-		parent, err = proc.Parent()
-		return parent, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - pageFaults: Pagefaults for a process
+//   - err: Any error encountered while retrieving it
+func ProcessPageFaults(proc *process.Process) (pageFaults *process.PageFaultsStat, err error) {
+	pageFaults, err = proc.PageFaults()
+	return pageFaults, err
 }
 
-// ProcessPGID sends a function to the provided channel that retrieves the process group ID of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
-// Unlike other functions, this one uses syscall.Getpgid directly instead of a context-aware method.
+// ProcessParent retrieves the parent process of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessPGID(c chan func(proc *process.Process) (pgid int, err error)) {
-	c <- (func(proc *process.Process) (pgid int, err error) {
-		pgid, err = syscall.Getpgid(int(proc.Pid))
-		return pgid, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - parent: The parent process of a process
+//   - err: Any error encountered while retrieving it
+func ProcessParent(proc *process.Process) (parent *process.Process, err error) {
+	parent, err = proc.Parent()
+	return parent, err
 }
 
-// ProcessPPID sends a function to the provided channel that retrieves the parent process ID of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessPPID retrieves the parent process ID of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessPPID(c chan func(proc *process.Process) (ppid int32, err error)) {
-	c <- (func(proc *process.Process) (ppid int32, err error) {
-		ppid, err = proc.Ppid()
-		return ppid, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - ppid: The parent process ID of a process
+//   - err: Any error encountered while retrieving it
+func ProcessPPID(proc *process.Process) (ppid int32, err error) {
+	ppid, err = proc.Ppid()
+	return ppid, err
 }
 
-// ProcessResourceLimit sends a function to the provided channel that retrieves resource limits of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessResourceLimit retrieves resource limits of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessResourceLimit(c chan func(proc *process.Process) (resourceLimit []process.RlimitStat, err error)) {
-	c <- (func(proc *process.Process) (resourceLimit []process.RlimitStat, err error) {
-		resourceLimit, err = proc.Rlimit()
-		return resourceLimit, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - resourceLimit: Resource limits of a process
+//   - err: Any error encountered while retrieving it
+func ProcessResourceLimit(proc *process.Process) (resourceLimit []process.RlimitStat, err error) {
+	resourceLimit, err = proc.Rlimit()
+	return resourceLimit, err
 }
 
-// ProcessResourceLimitUsage sends a function to the provided channel that retrieves resource limits of a process.
+// ProcessResourceLimitUsage retrieves resource limits of a process.
 // If gatherUsed is true, the currently used value will be gathered and added to the resulting RlimitStat.
-// This function is designed to be used with goroutines to gather process information concurrently.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessResourceLimitUsage(c chan func(proc *process.Process) (resourceLimitUsage []process.RlimitStat, err error)) {
-	c <- (func(proc *process.Process) (resourceLimitUsage []process.RlimitStat, err error) {
-		resourceLimitUsage, err = proc.RlimitUsage(true)
-		return resourceLimitUsage, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - resourceLimitUsage: Resource limits of a process
+//   - err: Any error encountered while retrieving it
+func ProcessResourceLimitUsage(proc *process.Process) (resourceLimitUsage []process.RlimitStat, err error) {
+	resourceLimitUsage, err = proc.RlimitUsage(true)
+	return resourceLimitUsage, err
 }
 
-// ProcessStatus sends a function to the provided channel that retrieves the status of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessStatus retrieves the status of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessStatus(c chan func(proc *process.Process) (status []string, err error)) {
-	c <- (func(proc *process.Process) (status []string, err error) {
-		status, err = proc.Status()
-		return status, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - status: The status of a process
+//   - err: Any error encountered while retrieving it
+func ProcessStatus(proc *process.Process) (status []string, err error) {
+	status, err = proc.Status()
+	return status, err
 }
 
-// ProcessThreads sends a function to the provided channel that retrieves the threads of a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessTerminal retrieves the name of the controlling terminal of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessThreads(c chan func(proc *process.Process) (threads map[int32]*cpu.TimesStat, err error)) {
-	c <- (func(proc *process.Process) (threads map[int32]*cpu.TimesStat, err error) {
-		threads, err = proc.Threads()
-		return threads, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - terminal: The controlling terminal of a process, e.g., pts/3, or empty if it has none
+//   - err: Any error encountered while retrieving it
+func ProcessTerminal(proc *process.Process) (terminal string, err error) {
+	terminal, err = proc.Terminal()
+	return NormalizeTerminal(terminal), err
 }
 
-// ProcessUsername sends a function to the provided channel that retrieves the username of the process owner.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// NormalizeTerminal converts a terminal name into the form used to compare terminals.
+// The names /dev/pts/3, /pts/3, and pts/3 all refer to the same terminal, pts/3.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessUsername(c chan func(proc *process.Process) (username string, err error)) {
-	c <- (func(proc *process.Process) (username string, err error) {
-		username, err = proc.Username()
-		return username, err
-	})
+//   - terminal: The terminal name to normalize
+//
+// Returns:
+//   - string: The terminal name without the /dev/ prefix
+func NormalizeTerminal(terminal string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(terminal, "/dev"), "/")
 }
 
-// ProcessUIDs sends a function to the provided channel that retrieves user IDs for a process.
-// This function is designed to be used with goroutines to gather process information concurrently.
+// ProcessThreads retrieves the threads of a process.
 //
 // Parameters:
-//   - c: Channel to send the function through
-func ProcessUIDs(c chan func(proc *process.Process) (uids []uint32, err error)) {
-	c <- (func(proc *process.Process) (uids []uint32, err error) {
-		uids, err = proc.Uids()
-		return uids, err
-	})
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - threads: The threads of a process
+//   - err: Any error encountered while retrieving it
+func ProcessThreads(proc *process.Process) (threads map[int32]*cpu.TimesStat, err error) {
+	threads, err = proc.Threads()
+	return threads, err
+}
+
+// ProcessUsername retrieves the username of the process owner.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - username: The username of the process owner
+//   - err: Any error encountered while retrieving it
+func ProcessUsername(proc *process.Process) (username string, err error) {
+	username, err = proc.Username()
+	return username, err
+}
+
+// ProcessUIDs retrieves user IDs for a process.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - uids: User IDs for a process
+//   - err: Any error encountered while retrieving it
+func ProcessUIDs(proc *process.Process) (uids []uint32, err error) {
+	uids, err = proc.Uids()
+	return uids, err
 }
@@ -0,0 +1,71 @@
+//go:build linux
+
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// smapsRollupReader reads the PSS and USS from /proc/<pid>/smaps_rollup, available since Linux 4.14.
+type smapsRollupReader struct {
+	// Mount point of the proc filesystem
+	procPath string
+}
+
+// newSharedMemoryReader returns the SharedMemoryReader of this platform.
+//
+// Returns:
+//   - SharedMemoryReader: A reader of /proc/<pid>/smaps_rollup
+func newSharedMemoryReader() SharedMemoryReader {
+	return smapsRollupReader{procPath: "/proc"}
+}
+
+// SharedMemory returns the PSS and USS of the process with the given PID.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - *SharedMemoryStat: The PSS and USS in bytes
+//   - error: An error if the file cannot be read, e.g., for the processes of other users
+func (reader smapsRollupReader) SharedMemory(pid int32) (*SharedMemoryStat, error) {
+	file, err := os.Open(filepath.Join(reader.procPath, strconv.Itoa(int(pid)), "smaps_rollup"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseSmapsRollup(file)
+}
+
+// ProcessArgv0 retrieves argv[0] of a process alone, cheaper than its full command line, see readArgv0.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - string: argv[0], empty if the command line is empty
+//   - error: Any error encountered while reading it
+func ProcessArgv0(proc *process.Process) (string, error) {
+	return readArgv0("/proc", proc.Pid)
+}
+
+// ProcessExeDeleted determines whether the executable of a process was deleted, see isDeletedExe.
+//
+// Parameters:
+//   - pid: The process ID
+//   - link: The raw target of the executable link, as returned by ProcessCommandName
+//
+// Returns:
+//   - bool: true if the executable was deleted, false otherwise
+func ProcessExeDeleted(pid int32, link string) bool {
+	exe, err := os.Stat(filepath.Join("/proc", strconv.Itoa(int(pid)), "exe"))
+	if err != nil {
+		return isDeletedExe(link, nil)
+	}
+	return isDeletedExe(link, exe)
+}
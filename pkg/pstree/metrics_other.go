@@ -0,0 +1,59 @@
+//go:build !linux
+
+package pstree
+
+import (
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// unavailableSharedMemoryReader is the SharedMemoryReader of platforms that don't report the PSS and USS.
+type unavailableSharedMemoryReader struct{}
+
+// newSharedMemoryReader returns the SharedMemoryReader of this platform.
+//
+// Returns:
+//   - SharedMemoryReader: A reader that always returns ErrSharedMemoryUnavailable
+func newSharedMemoryReader() SharedMemoryReader {
+	return unavailableSharedMemoryReader{}
+}
+
+// SharedMemory returns ErrSharedMemoryUnavailable, the memory values fall back to the RSS.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - *SharedMemoryStat: Always nil
+//   - error: Always ErrSharedMemoryUnavailable
+func (reader unavailableSharedMemoryReader) SharedMemory(pid int32) (*SharedMemoryStat, error) {
+	return nil, ErrSharedMemoryUnavailable
+}
+
+// ProcessArgv0 retrieves argv[0] of a process from its command line, the other platforms don't
+// read it on its own.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - string: argv[0], empty if the command line is empty
+//   - error: Any error encountered while reading it
+func ProcessArgv0(proc *process.Process) (string, error) {
+	args, err := ProcessArgs(proc)
+	if err != nil || len(args) == 0 {
+		return "", err
+	}
+	return args[0], nil
+}
+
+// ProcessExeDeleted always returns false, only Linux reports deleted executables.
+//
+// Parameters:
+//   - pid: The process ID
+//   - link: The executable of the process, as returned by ProcessCommandName
+//
+// Returns:
+//   - bool: Always false
+func ProcessExeDeleted(pid int32, link string) bool {
+	return false
+}
@@ -0,0 +1,178 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the namespace indicators (--namespaces). On Linux, each process lives in a
+// set of namespaces, and /proc/<pid>/ns/<type> links to the inode of each of them, e.g.,
+// net:[4026531840]. The pid, mnt, net, and user namespaces of each process are compared with those
+// of PID 1, and a process living in other namespaces than the host is marked, e.g., [ns:pid,net],
+// which makes containers and sandboxed browser processes stand out. The links of another user's
+// process can only be read with elevated privileges; a namespace that can't be read is shown as
+// ?. When the links of PID 1 can't be read, those of pstree itself stand for the host.
+// --only-foreign-ns narrows the tree down to the processes in other namespaces and their
+// ancestors. Other platforms have no namespaces, see NamespacesSupported.
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// NamespaceUnknown is shown in place of the namespaces of a process that could not be read.
+const NamespaceUnknown = "?"
+
+// NamespaceTypes are the namespaces compared with those of the host, in display order.
+var NamespaceTypes = []string{"pid", "mnt", "net", "user"}
+
+// ErrNamespacesUnavailable is returned when the namespaces of a process can't be read on this platform.
+var ErrNamespacesUnavailable = errors.New("the namespaces of a process are not available on this platform")
+
+// namespaceFS reads the namespace links of the processes, so the tests don't need a proc filesystem.
+type namespaceFS interface {
+	// Readlink returns the destination of the named symbolic link
+	Readlink(name string) (string, error)
+}
+
+// osNamespaceFS reads the namespace links from the file system of the running system.
+type osNamespaceFS struct{}
+
+// Readlink returns the destination of the named symbolic link, see os.Readlink.
+func (osNamespaceFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+// parseNamespaceLink parses the inode of a namespace from the destination of its link.
+//
+// Parameters:
+//   - nsType: The type of the namespace, e.g., net
+//   - link: The destination of /proc/<pid>/ns/<type>, e.g., net:[4026531840]
+//
+// Returns:
+//   - uint64: The inode of the namespace
+//   - error: An error if the link doesn't name a namespace of the type
+func parseNamespaceLink(nsType string, link string) (uint64, error) {
+	inode, ok := strings.CutPrefix(link, nsType+":[")
+	if !ok || !strings.HasSuffix(inode, "]") {
+		return 0, fmt.Errorf("unexpected %s namespace link %q", nsType, link)
+	}
+	return strconv.ParseUint(strings.TrimSuffix(inode, "]"), 10, 64)
+}
+
+// readNamespaces reads the inodes of the NamespaceTypes namespaces of a process.
+//
+// Parameters:
+//   - fsys: The file system the links are read from
+//   - procPath: Mount point of the proc filesystem
+//   - pid: The process ID
+//
+// Returns:
+//   - map[string]uint64: The inode of each namespace by type, 0 for the namespaces that could not be read, e.g., due to permissions
+func readNamespaces(fsys namespaceFS, procPath string, pid int32) map[string]uint64 {
+	namespaces := make(map[string]uint64, len(NamespaceTypes))
+	for _, nsType := range NamespaceTypes {
+		namespaces[nsType] = 0
+		link, err := fsys.Readlink(filepath.Join(procPath, strconv.Itoa(int(pid)), "ns", nsType))
+		if err != nil {
+			continue
+		}
+		if inode, err := parseNamespaceLink(nsType, link); err == nil {
+			namespaces[nsType] = inode
+		}
+	}
+	return namespaces
+}
+
+// hostNamespaces returns the namespaces the processes are compared with: those of PID 1, with
+// the namespaces that could not be read taken from pstree itself.
+//
+// Returns:
+//   - map[string]uint64: The inode of each host namespace by type, 0 for the namespaces that are not known
+func (processTree *ProcessTree) hostNamespaces() map[string]uint64 {
+	host := make(map[string]uint64, len(NamespaceTypes))
+	for _, pid := range []int32{1, int32(os.Getpid())} {
+		pidIndex, ok := processTree.PidToIndexMap[pid]
+		if !ok {
+			continue
+		}
+		for _, nsType := range NamespaceTypes {
+			if host[nsType] == 0 {
+				host[nsType] = processTree.Nodes[pidIndex].Namespaces[nsType]
+			}
+		}
+	}
+	return host
+}
+
+// foreignNamespaces compares the namespaces of a process with HostNamespaces.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - []string: The types of the namespaces that differ from the host, in the order of NamespaceTypes
+//   - bool: true if some of the namespaces of the process or the host could not be read
+func (processTree *ProcessTree) foreignNamespaces(node *Process) ([]string, bool) {
+	var (
+		foreign []string
+		unknown bool
+	)
+
+	for _, nsType := range NamespaceTypes {
+		inode, host := node.Namespaces[nsType], processTree.HostNamespaces[nsType]
+		switch {
+		case inode == 0 || host == 0:
+			unknown = true
+		case inode != host:
+			foreign = append(foreign, nsType)
+		}
+	}
+	return foreign, unknown
+}
+
+// namespaceMarkers lists the namespaces a process is marked with: those that differ from the
+// host, followed by NamespaceUnknown when some of them could not be read.
+//
+// Parameters:
+//   - node: The process
+//
+// Returns:
+//   - []string: The markers, e.g., [pid net ?], empty if the process lives in the namespaces of the host
+func (processTree *ProcessTree) namespaceMarkers(node *Process) []string {
+	if isSyntheticNode(node) {
+		return nil
+	}
+	foreign, unknown := processTree.foreignNamespaces(node)
+	if unknown {
+		foreign = append(foreign, NamespaceUnknown)
+	}
+	return foreign
+}
+
+// formatNamespaceField formats the namespace marker of a line, e.g., [ns:pid,net], or [ns:net,?]
+// when some of the namespaces could not be read.
+//
+// Parameters:
+//   - node: The process
+//
+// Returns:
+//   - string: The formatted marker, or an empty string if the process lives in the namespaces of the host
+func (processTree *ProcessTree) formatNamespaceField(node *Process) string {
+	markers := processTree.namespaceMarkers(node)
+	if len(markers) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[ns:%s]", strings.Join(markers, ","))
+}
+
+// markForeignNamespaces unmarks the processes living in the namespaces of the host, keeping the
+// ancestors of those living in other namespaces. A namespace that could not be read doesn't make
+// a process foreign.
+func (processTree *ProcessTree) markForeignNamespaces() {
+	processTree.Logger.Debug("Entering processTree.markForeignNamespaces()")
+	processTree.narrowMarked(func(node *Process) bool {
+		foreign, _ := processTree.foreignNamespaces(node)
+		return !node.IsThread && len(foreign) > 0
+	}, "lives in other namespaces than the host")
+}
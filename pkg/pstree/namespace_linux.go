@@ -0,0 +1,18 @@
+//go:build linux
+
+package pstree
+
+// NamespacesSupported reports whether the namespaces of the processes can be read on this platform.
+const NamespacesSupported = true
+
+// ProcessNamespaces retrieves the inodes of the namespaces of a process from /proc/<pid>/ns.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - map[string]uint64: The inode of each of the NamespaceTypes by type, 0 for those that could not be read
+//   - error: Always nil, the namespaces that can't be read are left at 0
+func ProcessNamespaces(pid int32) (map[string]uint64, error) {
+	return readNamespaces(osNamespaceFS{}, "/proc", pid), nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package pstree
+
+// NamespacesSupported reports whether the namespaces of the processes can be read on this platform.
+const NamespacesSupported = false
+
+// ProcessNamespaces retrieves the inodes of the namespaces of a process.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - map[string]uint64: Always nil
+//   - error: Always ErrNamespacesUnavailable
+func ProcessNamespaces(pid int32) (map[string]uint64, error) {
+	return nil, ErrNamespacesUnavailable
+}
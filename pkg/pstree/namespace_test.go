@@ -0,0 +1,108 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNamespaceFS serves namespace links from a map, the links that are missing can't be read
+type fakeNamespaceFS map[string]string
+
+func (fsys fakeNamespaceFS) Readlink(name string) (string, error) {
+	if link, ok := fsys[name]; ok {
+		return link, nil
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrPermission}
+}
+
+func TestParseNamespaceLink(t *testing.T) {
+	inode, err := parseNamespaceLink("net", "net:[4026531840]")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4026531840), inode)
+
+	for _, link := range []string{"pid:[4026531836]", "net:4026531840", "net:[abc]", ""} {
+		_, err = parseNamespaceLink("net", link)
+		assert.Error(t, err, link)
+	}
+}
+
+func TestReadNamespaces(t *testing.T) {
+	fsys := fakeNamespaceFS{
+		"/proc/42/ns/pid":  "pid:[4026532201]",
+		"/proc/42/ns/mnt":  "mnt:[4026532199]",
+		"/proc/42/ns/net":  "net:[4026532203]",
+		"/proc/42/ns/user": "garbage",
+	}
+
+	assert.Equal(t, map[string]uint64{"pid": 4026532201, "mnt": 4026532199, "net": 4026532203, "user": 0}, readNamespaces(fsys, "/proc", 42))
+
+	// The links of another user's process can't be read
+	assert.Equal(t, map[string]uint64{"pid": 0, "mnt": 0, "net": 0, "user": 0}, readNamespaces(fsys, "/proc", 43))
+}
+
+// namespaceProcesses returns a process list with a container in its own pid and net namespaces,
+// and a process whose namespaces could not be read
+func namespaceProcesses() []Process {
+	host := map[string]uint64{"pid": 4026531836, "mnt": 4026531841, "net": 4026531840, "user": 4026531837}
+	container := map[string]uint64{"pid": 4026532201, "mnt": 4026531841, "net": 4026532203, "user": 4026531837}
+	return []Process{
+		{PID: 1, PPID: 0, Command: "systemd", Username: "root", Namespaces: host},
+		{PID: 100, PPID: 1, Command: "sshd", Username: "root", Namespaces: host},
+		{PID: 200, PPID: 1, Command: "containerd-shim", Username: "root", Namespaces: host},
+		{PID: 201, PPID: 200, Command: "nginx", Username: "root", Namespaces: container},
+		{PID: 300, PPID: 1, Command: "chrome", Username: "alice", Namespaces: map[string]uint64{"pid": 0, "mnt": 0, "net": 0, "user": 0}},
+	}
+}
+
+func TestNamespaceField(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), namespaceProcesses(), DisplayOptions{
+		MaxDepth:       10,
+		ScreenWidth:    80,
+		ShowNamespaces: true,
+	})
+
+	assert.Equal(t, map[string]uint64{"pid": 4026531836, "mnt": 4026531841, "net": 4026531840, "user": 4026531837}, processTree.HostNamespaces)
+
+	output := renderProcessTree(t, processTree)
+	assert.Equal(t, "-+- systemd \n"+
+		" |--- sshd \n"+
+		" |-+- containerd-shim \n"+
+		" | \\--- [ns:pid,net] nginx \n"+
+		" \\--- [ns:?] chrome \n", output)
+}
+
+func TestHostNamespacesFallback(t *testing.T) {
+	// PID 1 can't be read, so the namespaces of pstree itself stand for the host
+	self := int32(os.Getpid())
+	processTree := NewProcessTree(0, setupTestLogger(), []Process{
+		{PID: 1, PPID: 0, Command: "systemd", Username: "root", Namespaces: map[string]uint64{"pid": 0, "mnt": 0, "net": 4026531840, "user": 0}},
+		{PID: self, PPID: 1, Command: "pstree", Username: "alice", Namespaces: map[string]uint64{"pid": 4026531836, "mnt": 4026531841, "net": 4026531840, "user": 4026531837}},
+	}, DisplayOptions{
+		MaxDepth:       10,
+		ScreenWidth:    80,
+		ShowNamespaces: true,
+	})
+
+	assert.Equal(t, map[string]uint64{"pid": 4026531836, "mnt": 4026531841, "net": 4026531840, "user": 4026531837}, processTree.HostNamespaces)
+	assert.Equal(t, "[ns:?]", processTree.formatNamespaceField(processTree.Nodes[0]))
+	assert.Empty(t, processTree.formatNamespaceField(processTree.Nodes[1]), fmt.Sprintf("PID %d", self))
+}
+
+func TestOnlyForeignNS(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), namespaceProcesses(), DisplayOptions{
+		MaxDepth:      10,
+		OnlyForeignNS: true,
+		ScreenWidth:   80,
+	})
+
+	output := renderProcessTree(t, processTree)
+
+	// A process whose namespaces could not be read is not foreign
+	assert.Equal(t, "-+- systemd \n"+
+		" \\-+- containerd-shim \n"+
+		"   \\--- nginx \n", output)
+}
@@ -0,0 +1,79 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the folding of the tree prefix on narrow screens. A line wider than the
+// screen is truncated from the right, so its branch characters are kept and only the metrics and
+// the command of the process are shortened. When the prefix of a deep process leaves less than
+// minEntryWidth columns for its entry, the deepest levels of the prefix are replaced with a
+// single TreeChars.Ellipsis indent marker, e.g., " | … \-+- bash", so every line still shows
+// where it hangs in the tree along with the start of its command. Only the line of the process
+// is folded, its children fold their own prefix. Nothing is folded with --wide.
+package pstree
+
+import (
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// minEntryWidth is the narrowest process entry kept after the tree prefix; a deeper prefix is folded.
+const minEntryWidth = 10
+
+// headLevels splits a head built by buildNewHead into its leading column and its levels, each
+// the vertical bar of an ancestor with a sibling below it, or a blank, followed by a space.
+//
+// Parameters:
+//   - head: The accumulated prefix string from parent levels
+//
+// Returns:
+//   - string: The leading column of the head
+//   - []string: The levels of the head, from the root down
+func (processTree *ProcessTree) headLevels(head string) (string, []string) {
+	var (
+		levels []string
+		size   int
+	)
+
+	rest := head[1:]
+	for rest != "" {
+		size = 2
+		if strings.HasPrefix(rest, processTree.TreeChars.Bar) {
+			size = len(processTree.TreeChars.Bar) + 1
+		}
+		size = min(size, len(rest))
+		levels = append(levels, rest[:size])
+		rest = rest[size:]
+	}
+	return head[:1], levels
+}
+
+// foldHead folds the head of a process whose tree prefix leaves less than minEntryWidth columns
+// of the screen for its entry, replacing the deepest levels with an indent marker, see the
+// description of the file. The shallowest levels are kept, as many as fit.
+//
+// Parameters:
+//   - head: The accumulated prefix string from parent levels
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - string: The head to draw the line of the process with, head itself if it fits
+func (processTree *ProcessTree) foldHead(head string, pidIndex int) string {
+	if head == "" || processTree.DisplayOptions.WideDisplay || processTree.DisplayOptions.ScreenWidth <= 0 {
+		return head
+	}
+
+	// The entry starts one column after the depth label and the branch characters
+	headWidth := util.VisibleWidth(head)
+	connectorWidth := util.VisibleWidth(processTree.buildLinePrefix(head, pidIndex)) - headWidth
+	available := processTree.DisplayOptions.ScreenWidth - util.VisibleWidth(processTree.depthLabel(pidIndex)) - connectorWidth - 1 - minEntryWidth
+	if headWidth <= available {
+		return head
+	}
+
+	// The marker takes the two columns of a level, after the leading column, so a single level is kept
+	lead, levels := processTree.headLevels(head)
+	if len(levels) < 2 {
+		return head
+	}
+	keep := min(max((available-util.VisibleWidth(lead)-2)/2, 0), len(levels)-1)
+	return lead + strings.Join(levels[:keep], "") + processTree.TreeChars.Ellipsis + " "
+}
@@ -0,0 +1,79 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/bananazon/pstree/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renderNarrow renders a 12-level-deep chain with the PIDs and owners
+func renderNarrow(t *testing.T, displayOptions DisplayOptions) []string {
+	displayOptions.MaxDepth = 20
+	displayOptions.ShowOwner = true
+	displayOptions.ShowPIDs = true
+	output := renderFixtureTree(t, fixtures.DeepChain(12), displayOptions)
+	return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+}
+
+// treePrefix returns the branch characters of a line, up to the identity of the process
+func treePrefix(line string) string {
+	return line[:strings.Index(line, "(")]
+}
+
+func TestFoldHead(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.DeepChain(12), DisplayOptions{MaxDepth: 20, ScreenWidth: 20})
+	processTree.TreeChars = TreeStyles["utf8"]
+	bar := processTree.TreeChars.Bar
+	deepest := processTree.PidToIndexMap[12]
+
+	// A prefix leaving room for the entry is kept
+	assert.Equal(t, "   ", processTree.foldHead("   ", deepest))
+	assert.Equal(t, "", processTree.foldHead("", deepest))
+
+	// The deepest levels are replaced with the marker, the shallowest ones are kept
+	head := " " + bar + " " + strings.Repeat("  ", 10)
+	assert.Equal(t, " "+bar+" … ", processTree.foldHead(head, deepest))
+
+	// With --wide, nothing is folded
+	processTree.DisplayOptions.WideDisplay = true
+	assert.Equal(t, head, processTree.foldHead(head, deepest))
+}
+
+func TestNarrowOutput(t *testing.T) {
+	wide := renderNarrow(t, DisplayOptions{WideDisplay: true})
+	require.Len(t, wide, 12)
+
+	// At 40 columns, the entries are shortened and the branch characters are kept
+	lines := renderNarrow(t, DisplayOptions{ScreenWidth: 40})
+	require.Len(t, lines, 12)
+	for i, line := range lines {
+		assert.LessOrEqual(t, util.VisibleWidth(line), 40, line)
+		assert.Equal(t, treePrefix(wide[i]), treePrefix(line), line)
+	}
+	assert.Equal(t, "                     \\--- (12) alice ba+", lines[11])
+
+	// At 20 columns, the deepest levels are folded so the identity of each process is still shown
+	lines = renderNarrow(t, DisplayOptions{ScreenWidth: 20})
+	require.Len(t, lines, 12)
+	for i, line := range lines {
+		assert.LessOrEqual(t, util.VisibleWidth(line), 20, line)
+		if i < 4 {
+			assert.Equal(t, treePrefix(wide[i]), treePrefix(line), line)
+		} else {
+			assert.Equal(t, "   + ", line[:5], line)
+		}
+	}
+	assert.Equal(t, "   + \\--- (12) alic+", lines[11])
+	assert.Equal(t, "   + \\-+- (5) alice+", lines[4])
+
+	// The continuation lines of --wrap line up under the folded entry
+	lines = renderNarrow(t, DisplayOptions{ScreenWidth: 20, WrapLines: true})
+	for _, line := range lines {
+		assert.LessOrEqual(t, util.VisibleWidth(line), 20, line)
+	}
+	assert.Equal(t, "   +       bash ", lines[len(lines)-1])
+}
@@ -0,0 +1,101 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the nice value shown with --nice: the scheduling priority given to a process
+// with nice(1) or renice(1), from -20 for the highest priority to 19 for the lowest. Processes with
+// a negative nice value are the ones that can starve the others, so --highlight-nice makes them
+// stand out. Windows has no nice values, so the priority class of each process is mapped to an
+// approximate nice value instead, see niceFromPriority. The nice values are only collected when
+// they are shown or sorted by (--order-by=nice).
+package pstree
+
+import (
+	"fmt"
+)
+
+// NiceRange is the range of the nice values of a group of identical processes in compact mode.
+type NiceRange struct {
+	// Highest nice value of the group members
+	Max int32
+	// Lowest nice value of the group members
+	Min int32
+}
+
+// niceOf returns the nice value of a process for sorting.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - int32: The nice value, or 0 if it was not collected
+func niceOf(process *Process) int32 {
+	if process.Nice == nil {
+		return 0
+	}
+	return *process.Nice
+}
+
+// addNice widens the nice range of a group to include the nice value of another member.
+//
+// Parameters:
+//   - niceRange: The range of the group so far, nil if none of the members could be read
+//   - nice: The nice value of the member, nil if it could not be read
+//
+// Returns:
+//   - *NiceRange: The widened range, nil if none of the members could be read
+func addNice(niceRange *NiceRange, nice *int32) *NiceRange {
+	if nice == nil {
+		return niceRange
+	}
+	if niceRange == nil {
+		return &NiceRange{Max: *nice, Min: *nice}
+	}
+	widened := *niceRange
+	if *nice > widened.Max {
+		widened.Max = *nice
+	}
+	if *nice < widened.Min {
+		widened.Min = *nice
+	}
+	return &widened
+}
+
+// formatNiceField formats the nice field of a line, e.g., (nice: 5), or the range of a group,
+// e.g., (nice: 0..10).
+//
+// Parameters:
+//   - niceRange: The nice values to format, nil if they could not be read
+//
+// Returns:
+//   - string: The formatted field, (nice: ?) if the nice values are not known
+func formatNiceField(niceRange *NiceRange) string {
+	switch {
+	case niceRange == nil:
+		return "(nice: ?)"
+	case niceRange.Min == niceRange.Max:
+		return fmt.Sprintf("(nice: %d)", niceRange.Min)
+	}
+	return fmt.Sprintf("(nice: %d..%d)", niceRange.Min, niceRange.Max)
+}
+
+// niceField formats and colors the nice field of a line. With DisplayOptions.HighlightNice, the
+// field of a process with a negative nice value is shown in bold red, or followed by an
+// exclamation mark without colors; a group is highlighted when any of its members is.
+//
+// Parameters:
+//   - niceRange: The nice values to show, nil if they could not be read
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - string: The field to add to the line
+func (processTree *ProcessTree) niceField(niceRange *NiceRange, pidIndex int) string {
+	field := formatNiceField(niceRange)
+
+	if !processTree.DisplayOptions.HighlightNice || niceRange == nil || niceRange.Min >= 0 {
+		processTree.colorizeField("nice", &field, pidIndex)
+		return field
+	}
+	if processTree.colorEnabled() {
+		return AnsiRedBold + field + AnsiReset
+	}
+	return field + "!"
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package pstree
+
+// niceFromPriority converts the value read by gopsutil to a nice value. On Linux, gopsutil
+// returns the result of the getpriority system call, which is offset to stay positive: 20 minus
+// the nice value, e.g., 20 for a nice value of 0 and 40 for -20.
+//
+// Parameters:
+//   - priority: The value returned by process.Nice
+//
+// Returns:
+//   - int32: The nice value, from -20 to 19
+func niceFromPriority(priority int32) int32 {
+	return 20 - priority
+}
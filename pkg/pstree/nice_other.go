@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package pstree
+
+// niceFromPriority returns the nice value of a process as read by gopsutil, which is already a
+// nice value on this platform.
+//
+// Parameters:
+//   - priority: The value returned by process.Nice
+//
+// Returns:
+//   - int32: The nice value
+func niceFromPriority(priority int32) int32 {
+	return priority
+}
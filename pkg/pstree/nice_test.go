@@ -0,0 +1,118 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// niceValue returns a pointer to a nice value of a test process.
+func niceValue(nice int32) *int32 {
+	return &nice
+}
+
+func TestAddNice(t *testing.T) {
+	var niceRange *NiceRange
+
+	// Members whose nice value could not be read are left out
+	niceRange = addNice(niceRange, nil)
+	assert.Nil(t, niceRange)
+
+	niceRange = addNice(niceRange, niceValue(5))
+	assert.Equal(t, &NiceRange{Max: 5, Min: 5}, niceRange)
+
+	niceRange = addNice(niceRange, niceValue(-5))
+	niceRange = addNice(niceRange, niceValue(10))
+	niceRange = addNice(niceRange, nil)
+	assert.Equal(t, &NiceRange{Max: 10, Min: -5}, niceRange)
+}
+
+func TestFormatNiceField(t *testing.T) {
+	assert.Equal(t, "(nice: ?)", formatNiceField(nil))
+	assert.Equal(t, "(nice: 0)", formatNiceField(&NiceRange{}))
+	assert.Equal(t, "(nice: -5)", formatNiceField(&NiceRange{Max: -5, Min: -5}))
+	assert.Equal(t, "(nice: 0..10)", formatNiceField(&NiceRange{Max: 10, Min: 0}))
+}
+
+func TestShowNice(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Nice: niceValue(0)},
+		{PID: 100, PPID: 1, Command: "worker", Nice: niceValue(0)},
+		{PID: 200, PPID: 1, Command: "worker", Nice: niceValue(10)},
+		{PID: 300, PPID: 1, Command: "audio", Nice: niceValue(-10)},
+		{PID: 400, PPID: 1, Command: "sshd"},
+	}
+
+	render := func(displayOptions DisplayOptions) []string {
+		displayOptions.ScreenWidth = 200
+		displayOptions.ShowNice = true
+		output := renderTree(t, processes, displayOptions)
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+		return lines
+	}
+
+	lines := render(DisplayOptions{})
+	require.Len(t, lines, 5)
+	assert.Equal(t, "-+- (nice: 0) init", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], "(nice: 0) worker"))
+	assert.True(t, strings.HasSuffix(lines[2], "(nice: 10) worker"))
+	assert.True(t, strings.HasSuffix(lines[3], "(nice: -10) audio"))
+	assert.True(t, strings.HasSuffix(lines[4], "(nice: ?) sshd"))
+
+	// Compact groups show the range of their nice values
+	lines = render(DisplayOptions{CompactMode: true})
+	require.Len(t, lines, 4)
+	assert.True(t, strings.HasSuffix(lines[1], "(nice: 0..10) worker───2*[worker]"))
+
+	// Without colors, the negative nice values are marked with an exclamation mark
+	lines = render(DisplayOptions{HighlightNice: true})
+	assert.True(t, strings.HasSuffix(lines[3], "(nice: -10)! audio"))
+	assert.True(t, strings.HasSuffix(lines[2], "(nice: 10) worker"))
+
+	// With colors, they are shown in bold red
+	lines = render(DisplayOptions{ColorizeOutput: true, ColorCount: 256, ColorSupport: true, HighlightNice: true})
+	assert.Contains(t, lines[3], AnsiRedBold+"(nice: -10)"+AnsiReset)
+	assert.NotContains(t, lines[2], AnsiRedBold)
+}
+
+func TestSortProcsByNice(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Nice: niceValue(0)},
+		{PID: 100, PPID: 1, Command: "unknown"},
+		{PID: 200, PPID: 1, Command: "low", Nice: niceValue(19)},
+		{PID: 300, PPID: 1, Command: "high", Nice: niceValue(-20)},
+	}
+
+	order := func(desc bool) []int32 {
+		sorted := append([]Process(nil), processes...)
+		require.NoError(t, SortProcsBy(&sorted, "nice", desc))
+		pids := []int32{}
+		for _, proc := range sorted {
+			pids = append(pids, proc.PID)
+		}
+		return pids
+	}
+
+	// Processes whose nice value could not be read are sorted last in both directions
+	assert.Equal(t, []int32{1, 300, 200, 100}, order(false))
+	assert.Equal(t, []int32{1, 200, 300, 100}, order(true))
+}
+
+func TestWriteFlatNice(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Nice: niceValue(-5)},
+		{PID: 100, PPID: 1, Command: "sshd"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 10, ShowNice: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var builder strings.Builder
+	require.NoError(t, processTree.WriteFlat(&builder, ',', []int{0}))
+	assert.Equal(t, "depth,command,nice\n0,init,-5\n1,sshd,\n", builder.String())
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package pstree
+
+// niceFromPriority maps the base priority of the priority class of a process, as returned by
+// gopsutil, to an approximate nice value, so the priority classes sort and highlight like the
+// nice values on other platforms.
+//
+// Parameters:
+//   - priority: The value returned by process.Nice, e.g., 8 for NORMAL_PRIORITY_CLASS
+//
+// Returns:
+//   - int32: The approximate nice value, e.g., 0 for the normal class and -10 for the high class
+func niceFromPriority(priority int32) int32 {
+	switch {
+	case priority >= 24:
+		// REALTIME_PRIORITY_CLASS
+		return -20
+	case priority >= 13:
+		// HIGH_PRIORITY_CLASS
+		return -10
+	case priority >= 10:
+		// ABOVE_NORMAL_PRIORITY_CLASS
+		return -5
+	case priority >= 8:
+		// NORMAL_PRIORITY_CLASS
+		return 0
+	case priority >= 6:
+		// BELOW_NORMAL_PRIORITY_CLASS
+		return 10
+	}
+	// IDLE_PRIORITY_CLASS
+	return 19
+}
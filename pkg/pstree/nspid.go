@@ -0,0 +1,88 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the namespace-translated PIDs shown with --ns-pids. A process running in a
+// container has a PID in each PID namespace it is nested in: pstree, running on the host, shows
+// the PID of the host namespace, while the logs of the containerized application reference the
+// PID of its own namespace. On Linux, the NSpid line of /proc/<pid>/status lists the PID in each
+// namespace from the outermost to the innermost, and the innermost one is shown next to the host
+// PID, e.g., (1234/7). Kernels older than 4.1 don't have the line, and other platforms don't have
+// PID namespaces, so the processes are shown with their host PID only there. The PIDs are only
+// collected when they are shown.
+package pstree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrNSPidsUnavailable is returned when the namespace-translated PIDs of a process can't be read
+// on this platform.
+var ErrNSPidsUnavailable = errors.New("the namespace PIDs are not available on this platform")
+
+// parseNSPids parses the PIDs of a process in each of its PID namespaces from the contents of a
+// Linux /proc/<pid>/status file.
+//
+// Parameters:
+//   - status: The contents of the file
+//
+// Returns:
+//   - []int32: The PIDs from the outermost namespace to the innermost one, nil if the file has no NSpid line
+//   - error: An error if a PID of the NSpid line is not a number
+func parseNSPids(status string) ([]int32, error) {
+	var nsPids []int32
+
+	scanner := bufio.NewScanner(strings.NewReader(status))
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "NSpid:")
+		if !found {
+			continue
+		}
+		for _, field := range strings.Fields(value) {
+			nsPid, err := strconv.ParseInt(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespace PID %q: %w", field, err)
+			}
+			nsPids = append(nsPids, int32(nsPid))
+		}
+		return nsPids, nil
+	}
+	return nil, nil
+}
+
+// readNSPids reads the PIDs of a process in each of its PID namespaces from a proc filesystem.
+//
+// Parameters:
+//   - procPath: Mount point of the proc filesystem
+//   - pid: The process ID
+//
+// Returns:
+//   - []int32: The PIDs from the outermost namespace to the innermost one, nil if the kernel doesn't report them
+//   - error: Any error encountered while reading or parsing the file
+func readNSPids(procPath string, pid int32) ([]int32, error) {
+	status, err := os.ReadFile(filepath.Join(procPath, strconv.Itoa(int(pid)), "status"))
+	if err != nil {
+		return nil, err
+	}
+	return parseNSPids(string(status))
+}
+
+// formatPID formats the PID of a process for its line, followed with --ns-pids by its PID in
+// its innermost PID namespace when it runs in a nested one, e.g., 1234/7.
+//
+// Parameters:
+//   - node: The process to format
+//
+// Returns:
+//   - string: The formatted PID
+func (processTree *ProcessTree) formatPID(node *Process) string {
+	pid := strconv.Itoa(int(node.PID))
+	if processTree.DisplayOptions.ShowNSPids && len(node.NSPids) > 1 {
+		return fmt.Sprintf("%s/%d", pid, node.NSPids[len(node.NSPids)-1])
+	}
+	return pid
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package pstree
+
+// ProcessNSPids retrieves the PIDs of a process in each of its PID namespaces from the NSpid
+// line of /proc/<pid>/status.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - []int32: The PIDs from the outermost namespace to the innermost one, nil if the kernel doesn't report them
+//   - error: Any error encountered while reading or parsing the file
+func ProcessNSPids(pid int32) ([]int32, error) {
+	return readNSPids("/proc", pid)
+}
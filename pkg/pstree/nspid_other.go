@@ -0,0 +1,15 @@
+//go:build !linux
+
+package pstree
+
+// ProcessNSPids retrieves the PIDs of a process in each of its PID namespaces.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - []int32: Always nil
+//   - error: Always ErrNSPidsUnavailable
+func ProcessNSPids(pid int32) ([]int32, error) {
+	return nil, ErrNSPidsUnavailable
+}
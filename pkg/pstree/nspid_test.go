@@ -0,0 +1,87 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nsPidStatusFixture returns the contents of a /proc/<pid>/status file, taken from a real
+// process, with the given NSpid line, or without one if it is empty.
+func nsPidStatusFixture(nsPidLine string) string {
+	status := "Name:\tnginx\nUmask:\t0022\nState:\tS (sleeping)\nTgid:\t1234\nNgid:\t0\nPid:\t1234\nPPid:\t1201\nTracerPid:\t0\n"
+	if nsPidLine != "" {
+		status += nsPidLine + "\n"
+	}
+	return status + "NStgid:\t1234\t7\nNSpgid:\t1234\t7\nNSsid:\t1201\t1\nVmPeak:\t   12168 kB\nThreads:\t1\n"
+}
+
+func TestParseNSPids(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		expected []int32
+	}{
+		{"Host", nsPidStatusFixture("NSpid:\t1234"), []int32{1234}},
+		{"Container", nsPidStatusFixture("NSpid:\t1234\t7"), []int32{1234, 7}},
+		{"NestedContainer", nsPidStatusFixture("NSpid:\t1234\t56\t1"), []int32{1234, 56, 1}},
+		// Kernels older than 4.1 have no NSpid line
+		{"NoNSpidLine", nsPidStatusFixture(""), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nsPids, err := parseNSPids(test.status)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, nsPids)
+		})
+	}
+
+	_, err := parseNSPids(nsPidStatusFixture("NSpid:\t1234\tx"))
+	assert.Error(t, err)
+}
+
+func TestReadNSPids(t *testing.T) {
+	procPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procPath, "1234"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(procPath, "1234", "status"), []byte(nsPidStatusFixture("NSpid:\t1234\t7")), 0o644))
+
+	nsPids, err := readNSPids(procPath, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1234, 7}, nsPids)
+
+	// A process that exited has no status file
+	_, err = readNSPids(procPath, 5678)
+	assert.Error(t, err)
+
+	// The PIDs of the test binary itself are read on Linux, nothing is reported elsewhere
+	nsPids, err = ProcessNSPids(int32(os.Getpid()))
+	if runtime.GOOS == "linux" {
+		require.NoError(t, err)
+		require.NotEmpty(t, nsPids)
+		assert.Equal(t, int32(os.Getpid()), nsPids[len(nsPids)-1])
+	} else {
+		assert.ErrorIs(t, err, ErrNSPidsUnavailable)
+	}
+}
+
+func TestNSPidsRendering(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", NSPids: []int32{1}},
+		{PID: 1201, PPID: 1, Command: "containerd-shim"},
+		{PID: 1234, PPID: 1201, Command: "nginx", NSPids: []int32{1234, 7}},
+		{PID: 1235, PPID: 1234, Command: "nginx", NSPids: []int32{1235, 56, 8}},
+	}
+	render := func(displayOptions DisplayOptions) string {
+		displayOptions.ShowPIDs = true
+		return renderTree(t, processes, displayOptions)
+	}
+
+	// Only the processes in a nested namespace show their innermost PID, those without NSpid line their host PID
+	assert.Equal(t, "-+- (1) init \n \\-+- (1201) containerd-shim \n   \\-+- (1234/7) nginx \n     \\--- (1235/8) nginx \n", render(DisplayOptions{ShowNSPids: true}))
+	assert.Equal(t, "-+- (1) init \n \\-+- (1201) containerd-shim \n   \\-+- (1234) nginx \n     \\--- (1235) nginx \n", render(DisplayOptions{}))
+}
@@ -0,0 +1,95 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the orphan detection (--show-orphans). A process whose parent is not part
+// of the collected processes, e.g., because the parent exited and the process was reparented
+// or because the parent is hidden from this user, would otherwise become a tree of its own.
+// With ShowOrphans, these processes are attached under a single synthetic "(orphans)" node
+// instead, and marked with DisplayOptions.OrphanSymbol, so they stand out during an incident review.
+package pstree
+
+import (
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// OrphansPID is the PID of the synthetic node the orphaned processes are attached to.
+const OrphansPID int32 = -1
+
+// OrphansCommand is the command shown for the synthetic node the orphaned processes are attached to.
+const OrphansCommand = "(orphans)"
+
+// DefaultOrphanSymbol is the symbol shown in front of the command of orphaned processes.
+const DefaultOrphanSymbol = "?"
+
+// isSyntheticNode reports whether a process is a synthetic node added to group other processes,
+// i.e., the "(orphans)" node or a "(container <id>)" node, which have a negative PID.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the process is not a real process
+func isSyntheticNode(node *Process) bool {
+	return node.PID < 0
+}
+
+// isOrphan reports whether a process is an orphan, i.e., it has a parent that was not collected.
+// Processes without a parent (PPID 0), processes that are their own parent, and thread nodes
+// are never orphans.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the parent of the process is missing
+func (processTree *ProcessTree) isOrphan(node *Process) bool {
+	if node.PPID == 0 || node.PPID == node.PID || node.IsThread || isSyntheticNode(node) {
+		return false
+	}
+	_, ok := processTree.PidToIndexMap[node.PPID]
+	return !ok
+}
+
+// AttachOrphans attaches the orphaned processes to a synthetic "(orphans)" node.
+//
+// The node is appended to Nodes with OrphansPID and becomes a root of its own, printed after
+// the other trees. The orphans keep their PPID, so --show-ppids still shows the missing parent,
+// and are marked with IsOrphan. Nothing is added when there are no orphans. It must be called
+// after BuildTree and before the children are sorted.
+func (processTree *ProcessTree) AttachOrphans() {
+	var (
+		lastIndex    int
+		node         *Process
+		orphansIndex int
+		pidIndex     int
+	)
+
+	orphansIndex = -1
+	for pidIndex, node = range processTree.Nodes {
+		if !processTree.isOrphan(node) {
+			continue
+		}
+
+		if orphansIndex == -1 {
+			orphansIndex = len(processTree.Nodes)
+			processTree.Nodes = append(processTree.Nodes, &Process{
+				Age:        -1,
+				Child:      pidIndex,
+				Command:    OrphansCommand,
+				MemoryInfo: &process.MemoryInfoStat{},
+				NumFDs:     -1,
+				Parent:     -1,
+				PID:        OrphansPID,
+				Sister:     -1,
+			})
+			processTree.PidToIndexMap[OrphansPID] = orphansIndex
+			processTree.IndexToPidMap[orphansIndex] = OrphansPID
+		} else {
+			processTree.Nodes[lastIndex].Sister = pidIndex
+		}
+
+		node.IsOrphan = true
+		node.Parent = orphansIndex
+		processTree.Nodes[orphansIndex].Children = append(processTree.Nodes[orphansIndex].Children, node)
+		lastIndex = pidIndex
+	}
+}
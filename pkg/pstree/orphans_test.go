@@ -0,0 +1,94 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orphanProcesses returns a process list where the parents of 300 and 400 are missing
+func orphanProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, Command: "sshd", Username: "root"},
+		{PID: 300, PPID: 250, Command: "worker", Username: "alice"},
+		{PID: 301, PPID: 300, Command: "child", Username: "alice"},
+		{PID: 400, PPID: 399, Command: "backup", Username: "root"},
+	}
+}
+
+func TestAttachOrphans(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), orphanProcesses(), DisplayOptions{
+		MaxDepth:     10,
+		OrphanSymbol: DefaultOrphanSymbol,
+		ScreenWidth:  80,
+		ShowOrphans:  true,
+		ShowPPIDs:    true,
+	})
+
+	orphansIndex, ok := processTree.PidToIndexMap[OrphansPID]
+	require.True(t, ok)
+
+	// The orphans are children of the synthetic node, but keep their PPID
+	worker := processTree.Nodes[processTree.PidToIndexMap[300]]
+	assert.True(t, worker.IsOrphan)
+	assert.Equal(t, orphansIndex, worker.Parent)
+	assert.Equal(t, int32(250), worker.PPID)
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[301]].IsOrphan)
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[1]].IsOrphan)
+
+	// The orphans node is printed after the regular tree
+	rootIndices, err := processTree.RootIndices()
+	require.NoError(t, err)
+	assert.Equal(t, []int{processTree.PidToIndexMap[1], orphansIndex}, rootIndices)
+
+	output := renderProcessTree(t, processTree)
+	assert.Equal(t, "-+- (0) init \n"+
+		" \\--- (1) sshd \n"+
+		"-+- (orphans)\n"+
+		" |-+- (250) ? worker \n"+
+		" | \\--- (300) child \n"+
+		" \\--- (399) ? backup \n", output)
+
+	// The orphans node is not counted as a process
+	assert.Equal(t, 5, processTree.Summarize().Processes)
+}
+
+func TestAttachOrphansDisabled(t *testing.T) {
+	// Without ShowOrphans each orphan is a tree of its own
+	processTree := NewProcessTree(0, setupTestLogger(), orphanProcesses(), DisplayOptions{MaxDepth: 10, ScreenWidth: 80})
+	_, ok := processTree.PidToIndexMap[OrphansPID]
+	assert.False(t, ok)
+
+	rootIndices, err := processTree.RootIndices()
+	require.NoError(t, err)
+	assert.Equal(t, []int{processTree.PidToIndexMap[1], processTree.PidToIndexMap[300], processTree.PidToIndexMap[400]}, rootIndices)
+
+	// Nothing is added when no parent is missing
+	processTree = NewProcessTree(0, setupTestLogger(), orphanProcesses()[:2], DisplayOptions{MaxDepth: 10, ShowOrphans: true})
+	_, ok = processTree.PidToIndexMap[OrphansPID]
+	assert.False(t, ok)
+}
+
+func TestAttachOrphansFiltered(t *testing.T) {
+	// The orphans node is only shown when one of the orphans matches
+	processTree := NewProcessTree(0, setupTestLogger(), orphanProcesses(), DisplayOptions{
+		ExcludeRoot:  true,
+		MaxDepth:     10,
+		OrphanSymbol: "!",
+		ScreenWidth:  80,
+		ShowOrphans:  true,
+	})
+	output := renderProcessTree(t, processTree)
+	assert.Equal(t, "-+- (orphans)\n \\-+- ! worker \n   \\--- child \n", output)
+
+	processTree = NewProcessTree(0, setupTestLogger(), orphanProcesses(), DisplayOptions{
+		Contains:    "sshd",
+		MaxDepth:    10,
+		ScreenWidth: 80,
+		ShowOrphans: true,
+	})
+	output = renderProcessTree(t, processTree)
+	assert.NotContains(t, output, OrphansCommand)
+}
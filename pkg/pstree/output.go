@@ -0,0 +1,43 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the handling of write errors on the output of the tree. When the tree is
+// piped into a program that exits early, e.g., pstree | head -20, the writes fail with EPIPE.
+// The printers then return ErrBrokenPipe up the recursion, so printing stops at the first line
+// that cannot be written instead of formatting the rest of the tree, and the caller can exit
+// quietly the way other Unix tools do.
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// ErrBrokenPipe is returned by the printers when the reader of the output went away.
+var ErrBrokenPipe = errors.New("broken pipe")
+
+// IsBrokenPipe reports whether an error was caused by the reader of the output going away.
+//
+// Parameters:
+//   - err: The error returned by a write to the output
+//
+// Returns:
+//   - bool: true if err is ErrBrokenPipe or wraps EPIPE
+func IsBrokenPipe(err error) bool {
+	return errors.Is(err, ErrBrokenPipe) || errors.Is(err, syscall.EPIPE)
+}
+
+// writeLine writes a line followed by a newline to the output of the tree.
+//
+// Parameters:
+//   - line: The line to write
+//
+// Returns:
+//   - error: ErrBrokenPipe if the reader of the output went away, or any other error encountered while writing
+func (processTree *ProcessTree) writeLine(line string) error {
+	_, err := fmt.Fprintln(processTree.Output, line)
+	if IsBrokenPipe(err) {
+		return ErrBrokenPipe
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package pstree
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWriter counts the writes made to it before passing them on.
+type countingWriter struct {
+	writer io.Writer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.writer.Write(p)
+}
+
+// TestPrintTreeBrokenPipe tests that printing stops once the read end of the output is closed
+func TestPrintTreeBrokenPipe(t *testing.T) {
+	processes := []Process{{PID: 1, PPID: 0, Command: "init"}}
+	for pid := int32(2); pid <= 10000; pid++ {
+		processes = append(processes, Process{PID: pid, PPID: 1, Command: fmt.Sprintf("worker-%d", pid)})
+	}
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	defer writer.Close()
+
+	// The reader goes away after a few lines, like head -3, while the tree doesn't fit in the pipe buffer
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for range 3 {
+			scanner.Scan()
+		}
+		reader.Close()
+	}()
+
+	output := &countingWriter{writer: writer}
+	processTree := NewProcessTreeWithOutput(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 999, WideDisplay: true}, output)
+	processTree.MarkProcesses()
+
+	err = processTree.PrintTree(0, "")
+	assert.ErrorIs(t, err, ErrBrokenPipe)
+	assert.True(t, IsBrokenPipe(err))
+	assert.Less(t, output.writes, len(processes), "printing should stop at the first failed write")
+}
+
+// TestIsBrokenPipe tests that only the errors caused by the reader going away are broken pipes
+func TestIsBrokenPipe(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	reader.Close()
+	_, err = writer.Write([]byte("init\n"))
+	writer.Close()
+
+	assert.True(t, IsBrokenPipe(err))
+	assert.True(t, IsBrokenPipe(fmt.Errorf("failed to write: %w", ErrBrokenPipe)))
+	assert.False(t, IsBrokenPipe(errors.New("disk full")))
+	assert.False(t, IsBrokenPipe(nil))
+}
+
+// TestPrintTreeWriteError tests that other write errors are returned as they are
+func TestPrintTreeWriteError(t *testing.T) {
+	processes := []Process{{PID: 1, PPID: 0, Command: "init"}, {PID: 2, PPID: 1, Command: "sshd"}}
+	processTree := NewProcessTreeWithOutput(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 999, WideDisplay: true}, failingWriter{})
+	processTree.MarkProcesses()
+
+	err := processTree.PrintTree(0, "")
+	assert.EqualError(t, err, "disk full")
+	assert.False(t, IsBrokenPipe(err))
+
+	var output bytes.Buffer
+	processTree.Output = &output
+	assert.NoError(t, processTree.PrintTree(0, ""))
+	assert.Equal(t, "-+- init \n \\--- sshd \n", output.String())
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
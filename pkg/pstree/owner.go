@@ -0,0 +1,66 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the owner shown with --show-owner and --user-transitions. Usernames often
+// don't resolve in containers, so a username that cannot be looked up falls back to the UID, e.g.,
+// "uid=1000", and --numeric shows the UIDs instead of the usernames altogether. Sorting by user
+// then sorts by UID, numerically.
+package pstree
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// UnresolvedUsername returns the name shown for a process whose username could not be looked up.
+//
+// Parameters:
+//   - uids: The user IDs of the process, see ProcessUIDs
+//
+// Returns:
+//   - string: The effective UID, e.g., "uid=1000", or "?" if the UIDs could not be read either
+func UnresolvedUsername(uids []uint32) string {
+	if len(uids) == 0 {
+		return "?"
+	}
+	return fmt.Sprintf("uid=%d", uids[0])
+}
+
+// processUID returns the effective UID of a process.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - int64: The UID, or -1 if the UIDs were not collected
+func processUID(process *Process) int64 {
+	if len(process.UIDs) == 0 {
+		return -1
+	}
+	return int64(process.UIDs[0])
+}
+
+// ownerName returns the owner of a process as it is displayed: the username, or the UID with --numeric.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - string: The owner, the username if the UIDs were not collected
+func (processTree *ProcessTree) ownerName(process *Process) string {
+	if processTree.DisplayOptions.Numeric && len(process.UIDs) > 0 {
+		return strconv.FormatUint(uint64(process.UIDs[0]), 10)
+	}
+	return process.Username
+}
+
+// orderBy returns the attribute the children are sorted by, which is the UID instead of the
+// username when sorting by user with --numeric.
+//
+// Returns:
+//   - string: The attribute, see CompareProcesses
+func (processTree *ProcessTree) orderBy() string {
+	if processTree.DisplayOptions.Numeric && processTree.DisplayOptions.OrderBy == "user" {
+		return "uid"
+	}
+	return processTree.DisplayOptions.OrderBy
+}
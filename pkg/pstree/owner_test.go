@@ -0,0 +1,62 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ownerTestProcesses returns processes of a container whose users partly don't resolve.
+func ownerTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", UIDs: []uint32{0}},
+		{PID: 100, PPID: 1, Command: "app", Username: "uid=1000", UIDs: []uint32{1000}},
+		{PID: 200, PPID: 1, Command: "worker", Username: "uid=999", UIDs: []uint32{999}},
+		{PID: 300, PPID: 1, Command: "cron", Username: "root", UIDs: []uint32{0}},
+	}
+}
+
+func TestUnresolvedUsername(t *testing.T) {
+	assert.Equal(t, "uid=1000", UnresolvedUsername([]uint32{1000, 1000, 1000, 1000}))
+	assert.Equal(t, "?", UnresolvedUsername([]uint32{}))
+	assert.Equal(t, "?", UnresolvedUsername(nil))
+}
+
+func TestNumeric(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		return renderTree(t, ownerTestProcesses(), displayOptions)
+	}
+
+	output := render(DisplayOptions{ShowOwner: true})
+	assert.Contains(t, output, "root init")
+	assert.Contains(t, output, "uid=1000 app")
+
+	output = render(DisplayOptions{Numeric: true, ShowOwner: true})
+	assert.Contains(t, output, "0 init")
+	assert.Contains(t, output, "1000 app")
+
+	// The transitions are detected by UID, even between users that don't resolve
+	output = render(DisplayOptions{ShowUserTransitions: true})
+	assert.Contains(t, output, "(root→uid=1000) app")
+	assert.NotContains(t, output, "cron (")
+	output = render(DisplayOptions{Numeric: true, ShowUserTransitions: true})
+	assert.Contains(t, output, "(0→1000) app")
+
+	// Sorting by user compares the UIDs numerically instead of the names
+	output = render(DisplayOptions{OrderBy: "user", ShowOwner: true})
+	assert.Regexp(t, `(?s)root cron.*uid=1000 app.*uid=999 worker`, output)
+	output = render(DisplayOptions{Numeric: true, OrderBy: "user", ShowOwner: true})
+	assert.Regexp(t, `(?s)0 cron.*999 worker.*1000 app`, output)
+}
+
+func TestNumericWithoutUIDs(t *testing.T) {
+	// Without the UIDs, the usernames are shown and compared instead
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, Command: "app", Username: "www"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 10, Numeric: true, ScreenWidth: 80, ShowOwner: true, ShowUserTransitions: true})
+	assert.True(t, processTree.Nodes[1].HasUIDTransition)
+	output := renderProcessTree(t, processTree)
+	assert.Contains(t, output, "www (root→www) app")
+}
@@ -0,0 +1,69 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the page faults shown with --page-faults. The major faults, which had to
+// read the page from disk, are the ones that point to a process thrashing, so they are shown by
+// default; --page-faults=all adds the minor faults, which were resolved without any IO. The
+// counters are only collected when they are shown or sorted by (--order-by=faults). Platforms
+// that don't report page faults, e.g., macOS, show "-" instead.
+package pstree
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// MajorFaults returns the number of major page faults of a process, which is what
+// --order-by=faults sorts by.
+//
+// Parameters:
+//   - process: The process to read
+//
+// Returns:
+//   - int64: The number of major faults, or -1 if the page faults could not be read
+func MajorFaults(process *Process) int64 {
+	if process.PageFaults == nil {
+		return -1
+	}
+	return int64(process.PageFaults.MajorFaults)
+}
+
+// addPageFaults adds the page faults of a process to the summed page faults of a process group.
+//
+// Parameters:
+//   - sum: The summed page faults, nil if none of the members could be read so far
+//   - pageFaults: The page faults of the process, nil if they could not be read
+//
+// Returns:
+//   - *process.PageFaultsStat: The new sum, nil if neither could be read
+func addPageFaults(sum *process.PageFaultsStat, pageFaults *process.PageFaultsStat) *process.PageFaultsStat {
+	if pageFaults == nil {
+		return sum
+	}
+	if sum == nil {
+		sum = &process.PageFaultsStat{}
+	}
+	sum.ChildMajorFaults += pageFaults.ChildMajorFaults
+	sum.ChildMinorFaults += pageFaults.ChildMinorFaults
+	sum.MajorFaults += pageFaults.MajorFaults
+	sum.MinorFaults += pageFaults.MinorFaults
+	return sum
+}
+
+// formatPageFaultsField formats the page faults field of a line, e.g., (pf: 12), or
+// (pf: 12 maj, 3400 min) with DisplayOptions.PageFaults set to "all".
+//
+// Parameters:
+//   - pageFaults: The page faults to display, nil if they could not be read
+//
+// Returns:
+//   - string: The formatted field, or (pf: -) if the page faults could not be read
+func (processTree *ProcessTree) formatPageFaultsField(pageFaults *process.PageFaultsStat) string {
+	if pageFaults == nil {
+		return "(pf: -)"
+	}
+	if processTree.DisplayOptions.PageFaults == "all" {
+		return fmt.Sprintf("(pf: %d maj, %d min)", pageFaults.MajorFaults, pageFaults.MinorFaults)
+	}
+	return fmt.Sprintf("(pf: %d)", pageFaults.MajorFaults)
+}
@@ -0,0 +1,75 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+// pageFaultsTestProcesses returns processes of which one runs on a platform that doesn't report page faults.
+func pageFaultsTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", PageFaults: &process.PageFaultsStat{MajorFaults: 30, MinorFaults: 5000}},
+		{PID: 100, PPID: 1, Command: "db", PageFaults: &process.PageFaultsStat{MajorFaults: 9000, MinorFaults: 100}},
+		{PID: 200, PPID: 1, Command: "worker", PageFaults: &process.PageFaultsStat{MajorFaults: 12, MinorFaults: 3400}},
+		{PID: 300, PPID: 1, Command: "worker", PageFaults: &process.PageFaultsStat{MajorFaults: 3, MinorFaults: 600}},
+		{PID: 400, PPID: 1, Command: "sshd"},
+	}
+}
+
+func TestMajorFaults(t *testing.T) {
+	processes := pageFaultsTestProcesses()
+	assert.Equal(t, int64(30), MajorFaults(&processes[0]))
+	assert.Equal(t, int64(-1), MajorFaults(&processes[4]))
+}
+
+func TestShowPageFaults(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		return renderTree(t, pageFaultsTestProcesses(), displayOptions)
+	}
+
+	output := render(DisplayOptions{PageFaults: "major"})
+	assert.Contains(t, output, "(pf: 30) init")
+	assert.Contains(t, output, "(pf: 9000) db")
+	assert.Contains(t, output, "(pf: -) sshd")
+
+	output = render(DisplayOptions{PageFaults: "all"})
+	assert.Contains(t, output, "(pf: 30 maj, 5000 min) init")
+	assert.Contains(t, output, "(pf: -) sshd")
+
+	// The compact group sums the page faults of its members
+	output = render(DisplayOptions{CompactMode: true, PageFaults: "all"})
+	assert.Contains(t, output, "(pf: 15 maj, 4000 min) worker───2*[worker]")
+
+	// --order-by=faults sorts by the major faults, the processes that could not be read last
+	output = render(DisplayOptions{OrderBy: "faults", OrderDir: "desc", PageFaults: "major"})
+	assert.Regexp(t, `(?s)db.*\(pf: 12\) worker.*\(pf: 3\) worker.*sshd`, output)
+	output = render(DisplayOptions{OrderBy: "faults", OrderDir: "asc", PageFaults: "major"})
+	assert.Regexp(t, `(?s)\(pf: 3\) worker.*\(pf: 12\) worker.*db.*sshd`, output)
+}
+
+func TestWriteFlatPageFaults(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), pageFaultsTestProcesses(), DisplayOptions{PageFaults: "major"})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var output bytes.Buffer
+	err := processTree.WriteFlat(&output, ',', []int{0})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "depth,command,major_faults\n"+
+		"0,init,30\n"+
+		"1,db,9000\n"+
+		"1,worker,12\n"+
+		"1,worker,3\n"+
+		"1,sshd,\n", output.String())
+
+	processTree.DisplayOptions.PageFaults = "all"
+	output.Reset()
+	err = processTree.WriteFlat(&output, ',', []int{0})
+
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "depth,command,major_faults,minor_faults\n0,init,30,5000\n")
+}
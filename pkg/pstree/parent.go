@@ -0,0 +1,43 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the command of the parent shown with --show-parent. The PPID alone leaves
+// the consumers of the flat and JSON outputs to join the rows against the whole set to know what
+// the parent was, and the parent may not even be displayed, e.g., with --contains. The command
+// of the parent is resolved by BuildTree from the collected processes rather than looked up on
+// the live system, so it is consistent with the rest of the output, including a snapshot
+// rendered with --from-file. It is empty when the parent was not collected, e.g., for the roots
+// and the orphans.
+package pstree
+
+import (
+	"fmt"
+)
+
+// parentCommand returns the command of the parent of a process, formatted with CommandFormat.
+//
+// Parameters:
+//   - node: The process whose parent is shown
+//
+// Returns:
+//   - string: The command of the parent, empty if the parent was not collected
+func (processTree *ProcessTree) parentCommand(node *Process) string {
+	if node.ParentCommand == "" {
+		return ""
+	}
+	return FormatCommand(node.ParentCommand, processTree.DisplayOptions.CommandFormat)
+}
+
+// formatParentField formats the parent field of a line, e.g., (parent: sshd).
+//
+// Parameters:
+//   - node: The process whose parent is shown
+//
+// Returns:
+//   - string: The formatted field, empty if the parent was not collected
+func (processTree *ProcessTree) formatParentField(node *Process) string {
+	command := processTree.parentCommand(node)
+	if command == "" {
+		return ""
+	}
+	return fmt.Sprintf("(parent: %s)", command)
+}
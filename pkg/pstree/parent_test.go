@@ -0,0 +1,42 @@
+package pstree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParentCommand(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.OrphanedChild(), DisplayOptions{MaxDepth: 10})
+
+	// The command of the parent is resolved from the collected processes
+	assert.Equal(t, "init", processTree.Nodes[processTree.PidToIndexMap[200]].ParentCommand)
+	assert.Equal(t, "worker", processTree.Nodes[processTree.PidToIndexMap[301]].ParentCommand)
+
+	// It is empty for the roots and for the processes whose parent exited
+	assert.Empty(t, processTree.Nodes[processTree.PidToIndexMap[1]].ParentCommand)
+	assert.Empty(t, processTree.Nodes[processTree.PidToIndexMap[300]].ParentCommand)
+}
+
+func TestShowParent(t *testing.T) {
+	displayOptions := DisplayOptions{Contains: "bash", MaxDepth: 10, ScreenWidth: 80, ShowParent: true}
+	processTree := newFixtureTree(t, fixtures.SSHSession(), displayOptions)
+	output := renderProcessTree(t, processTree)
+	assert.Equal(t, "-+- init \n \\-+- (parent: init) sshd \n   \\-+- (parent: sshd) sshd \n     \\-+- (parent: sshd) sshd \n       \\-+- (parent: sshd) bash* \n         |--- (parent: bash) vim \n         \\-+- (parent: bash) make \n           \\--- (parent: make) cc1 \n", output)
+
+	// The flat output gets a column of its own, empty for the roots
+	processTree.DisplayOptions.ShowPIDs = true
+	processTree.DisplayOptions.ShowPPIDs = true
+	var flat bytes.Buffer
+	require.NoError(t, processTree.WriteFlat(&flat, ',', []int{0}))
+	assert.Equal(t, "pid,ppid,parent_command,depth,command\n1,0,,0,init\n100,1,init,1,sshd\n1000,100,sshd,2,sshd\n1001,1000,sshd,3,sshd\n1002,1001,sshd,4,bash\n1003,1002,bash,5,vim\n1004,1002,bash,5,make\n1005,1004,make,6,cc1\n", flat.String())
+
+	// So does the JSON rendering, which leaves it out for the roots
+	nodes := processTree.TreeNodes([]int{0})
+	require.Len(t, nodes, 1)
+	assert.Empty(t, nodes[0].ParentCommand)
+	assert.Equal(t, "init", nodes[0].Children[0].ParentCommand)
+}
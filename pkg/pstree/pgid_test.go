@@ -0,0 +1,49 @@
+//go:build !windows
+
+package pstree
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessPGID(t *testing.T) {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	require.NoError(t, err)
+
+	pgid, err := ProcessPGID(proc)
+	require.NoError(t, err)
+	assert.Equal(t, syscall.Getpgrp(), pgid)
+	assert.True(t, PGIDSupported)
+}
+
+func TestProcessPGIDBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping cross-compilation in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("Skipping cross-compilation without a go toolchain")
+	}
+
+	// Both variants of ProcessPGID, including the tests, have to compile
+	for _, goos := range []string{"darwin", "linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			cmd := exec.Command("go", "vet", ".")
+			cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH=amd64", "CGO_ENABLED=0")
+			output, err := cmd.CombinedOutput()
+			assert.NoError(t, err, string(output))
+		})
+	}
+}
+
+func TestHasPGID(t *testing.T) {
+	assert.True(t, hasPGID(&Process{PGID: 100}))
+	// The PGID could not be read, which is shown as -1 where process groups exist
+	assert.True(t, hasPGID(&Process{PGID: -1}))
+}
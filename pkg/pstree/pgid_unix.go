@@ -0,0 +1,26 @@
+//go:build !windows
+
+package pstree
+
+import (
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// PGIDSupported reports whether the process group IDs can be read on this platform.
+const PGIDSupported = true
+
+// ProcessPGID retrieves the process group ID of a process.
+// Unlike other functions, this one uses syscall.Getpgid directly instead of a context-aware method.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - pgid: The process group ID of a process
+//   - err: Any error encountered while retrieving it
+func ProcessPGID(proc *process.Process) (pgid int, err error) {
+	pgid, err = syscall.Getpgid(int(proc.Pid))
+	return pgid, err
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package pstree
+
+import (
+	"errors"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// PGIDSupported reports whether the process group IDs can be read on this platform.
+// Windows has no process groups, so the PGIDs and the process group leader markers are hidden.
+const PGIDSupported = false
+
+// ProcessPGID retrieves the process group ID of a process.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - pgid: Always -1
+//   - err: Always errors.ErrUnsupported
+func ProcessPGID(proc *process.Process) (pgid int, err error) {
+	return -1, errors.ErrUnsupported
+}
@@ -0,0 +1,96 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the construction of the tree by process group of --by-pgroup. The tree of
+// parents tells who started a process, but a shell job, e.g., make and the compilers it runs, is
+// a process group, and the signals of job control go to the group. With --by-pgroup, each process
+// group leader, i.e., a process whose PID is its PGID, is at the top of a tree of its own, with
+// the members of its group as its children whatever their parent. A member leading a nested group
+// of its own, e.g., a job started by an interactive shell, stays at the top of its own group
+// rather than under the leader of the group of its parent, so each process shows up once.
+//
+// The members of a group whose leader was not collected, e.g., because it exited, are placed
+// under the first of them, and the processes whose PGID is unknown, e.g., on Windows, stay under
+// their parent. The threads stay under their process. The parents are still resolved as by
+// BuildTree, so the PPIDs and --show-parent show who started each member.
+package pstree
+
+// BuildGroupTree constructs the relationships between the processes like BuildTree, linking each
+// process under the leader of its process group instead of its parent.
+func (processTree *ProcessTree) BuildGroupTree() {
+	processTree.Logger.Debug("Entering processTree.BuildGroupTree()")
+
+	// Resolve the parents first, they are still shown
+	processTree.BuildTree()
+
+	anchors := processTree.groupAnchors()
+	for _, node := range processTree.Nodes {
+		node.Child = -1
+		node.Parent = -1
+		node.Sister = -1
+		node.Children = []*Process{}
+	}
+
+	lastChild := make(map[int]int)
+	for pidIndex, anchorIndex := range anchors {
+		if anchorIndex == -1 {
+			continue
+		}
+
+		processTree.Nodes[pidIndex].Parent = anchorIndex
+		if sisterIndex, ok := lastChild[anchorIndex]; ok {
+			processTree.Nodes[sisterIndex].Sister = pidIndex
+		} else {
+			processTree.Nodes[anchorIndex].Child = pidIndex
+		}
+		lastChild[anchorIndex] = pidIndex
+		processTree.Nodes[anchorIndex].Children = append(processTree.Nodes[anchorIndex].Children, processTree.Nodes[pidIndex])
+	}
+
+	// The subtrees changed, so do their signatures
+	for _, node := range processTree.Nodes {
+		node.Signature = ""
+	}
+	for _, node := range processTree.Nodes {
+		computeSignature(node, processTree.DisplayOptions.ShowArguments, processTree.DisplayOptions.ShowZombies)
+	}
+}
+
+// groupAnchors finds the process each process is shown under with --by-pgroup: the leader of
+// its process group, or the first member collected if the leader was not. The threads and the
+// processes whose PGID is unknown are shown under their parent, as linked by BuildTree.
+//
+// Returns:
+//   - []int: Index of the process each process is shown under by index in the Nodes array, -1 for the roots
+func (processTree *ProcessTree) groupAnchors() []int {
+	var (
+		anchors      = make([]int, len(processTree.Nodes))
+		firstMembers = make(map[int32]int)
+	)
+
+	// The first member of a group is the one with the lowest PID, whatever the order of the nodes
+	for pidIndex, node := range processTree.Nodes {
+		if node.IsThread || node.PGID < 0 {
+			continue
+		}
+		if firstIndex, ok := firstMembers[node.PGID]; !ok || node.PID < processTree.Nodes[firstIndex].PID {
+			firstMembers[node.PGID] = pidIndex
+		}
+	}
+
+	for pidIndex, node := range processTree.Nodes {
+		anchors[pidIndex] = -1
+		switch {
+		case node.IsThread || node.PGID < 0:
+			anchors[pidIndex] = node.Parent
+		case node.PID == node.PGID:
+			// A leader is the root of its group
+		default:
+			if leaderIndex, ok := processTree.PidToIndexMap[node.PGID]; ok && !processTree.Nodes[leaderIndex].IsThread {
+				anchors[pidIndex] = leaderIndex
+			} else if firstIndex := firstMembers[node.PGID]; firstIndex != pidIndex {
+				anchors[pidIndex] = firstIndex
+			}
+		}
+	}
+	return anchors
+}
@@ -0,0 +1,45 @@
+package pstree
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+// renderGroups renders a canned tree with the given options, by process group with --by-pgroup
+func renderGroups(t *testing.T, tree []fixtures.Process, displayOptions DisplayOptions) string {
+	return renderFixtureTree(t, tree, displayOptions)
+}
+
+func TestBuildGroupTree(t *testing.T) {
+	// The same session by parent, and by process group: the jobs of the shell lead groups of their own
+	assert.Equal(t, "-+- (1) init \n |-+- (100) sshd \n | \\-+- (1000) sshd \n |   \\-+- (1001) sshd \n |     \\-+- (1002) bash \n |       |--- (1003) vim \n |       \\-+- (1004) make \n |         \\--- (1005) cc1 \n \\--- (200) cron \n", renderGroups(t, fixtures.SSHSession(), DisplayOptions{ShowPIDs: true}))
+	assert.Equal(t, "-+- (1) init \n-+- (100) sshd \n-+- (200) cron \n-+- (1000) sshd \n \\--- (1001) sshd \n-+- (1002) bash \n-+- (1003) vim \n-+- (1004) make \n \\--- (1005) cc1 \n", renderGroups(t, fixtures.SSHSession(), DisplayOptions{ByProcessGroup: true, ShowPIDs: true}))
+
+	// The PPIDs still show who started each member
+	assert.Equal(t, "-+- (   1,   0) init \n-+- ( 100,   1) sshd \n-+- ( 200,   1) cron \n-+- (1000, 100) sshd \n \\--- (1001,1000) sshd \n-+- (1002,1001) bash \n-+- (1003,1002) vim \n-+- (1004,1002) make \n \\--- (1005,1004) cc1 \n", renderGroups(t, fixtures.SSHSession(), DisplayOptions{ByProcessGroup: true, ShowPIDs: true, ShowPPIDs: true}))
+
+	// The members of a group whose leader was not collected are placed under the first of them
+	workers := slices.DeleteFunc(fixtures.IdenticalSiblings(), func(process fixtures.Process) bool { return process.PID == 100 })
+	assert.Equal(t, "-+- (1) init \n \\--- (200) cron \n-+- (101) nginx \n-+- (102) nginx \n-+- (103) nginx \n-+- (104) nginx \n-+- (105) nginx \n", renderGroups(t, workers, DisplayOptions{ShowPIDs: true}))
+	assert.Equal(t, "-+- (1) init \n-+- (101) nginx \n |--- (102) nginx \n |--- (103) nginx \n |--- (104) nginx \n \\--- (105) nginx \n-+- (200) cron \n", renderGroups(t, workers, DisplayOptions{ByProcessGroup: true, ShowPIDs: true}))
+}
+
+func TestGroupAnchors(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{ByProcessGroup: true})
+	index := processTree.PidToIndexMap
+
+	anchors := processTree.groupAnchors()
+	assert.Equal(t, -1, anchors[index[1002]])
+	assert.Equal(t, index[1000], anchors[index[1001]])
+	assert.Equal(t, index[1004], anchors[index[1005]])
+
+	// A process whose PGID is unknown stays under its parent
+	processTree.Nodes[index[1005]].PGID = -1
+	anchors = processTree.groupAnchors()
+	assert.Equal(t, index[1004], anchors[index[1005]])
+	processTree.Nodes[index[1003]].PGID = -1
+	assert.Equal(t, -1, processTree.groupAnchors()[index[1003]])
+}
@@ -0,0 +1,107 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the detection of processes that gained privileges (--privileged and
+// --only-privileged), e.g., a setuid binary such as sudo or passwd started by a regular user.
+// A process is privileged when its effective UID is 0 while the effective UID of its parent is
+// not, or when its real, effective, and saved UIDs differ, which only a setuid or setgid
+// executable or a call to setuid(2) leaves behind. Such processes are marked with
+// PrivilegedMarker in front of their command, and --only-privileged narrows the tree down to them
+// and their ancestors. The UIDs are only collected with these options, and the platforms that
+// don't report them, e.g., Windows, have no privileged process.
+package pstree
+
+import (
+	"fmt"
+)
+
+// PrivilegedMarker is shown in front of the command of a privileged process with --privileged.
+const PrivilegedMarker = "⚑"
+
+// effectiveUID returns the effective UID of a process.
+//
+// gopsutil returns the real, effective, and saved UIDs, followed by the filesystem UID on Linux,
+// so the effective UID is the second one. A single UID is taken as the effective one.
+//
+// Parameters:
+//   - uids: The user IDs of the process, see ProcessUIDs
+//
+// Returns:
+//   - uint32: The effective UID
+//   - bool: false if the UIDs were not collected
+func effectiveUID(uids []uint32) (uint32, bool) {
+	switch len(uids) {
+	case 0:
+		return 0, false
+	case 1:
+		return uids[0], true
+	default:
+		return uids[1], true
+	}
+}
+
+// hasMixedUIDs determines whether the real, effective, and saved UIDs of a process differ. The
+// filesystem UID of Linux follows the effective UID, so it is not compared.
+//
+// Parameters:
+//   - uids: The user IDs of the process, see ProcessUIDs
+//
+// Returns:
+//   - bool: true if the UIDs differ, false otherwise or if fewer than two UIDs are known
+func hasMixedUIDs(uids []uint32) bool {
+	for _, uid := range uids[min(len(uids), 1):min(len(uids), 3)] {
+		if uid != uids[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrivileged determines whether a process gained privileges, see the description of the file.
+//
+// Parameters:
+//   - node: The process to check
+//   - parent: The parent of the process, nil if it was not collected
+//
+// Returns:
+//   - bool: true if the process is privileged, false otherwise
+func isPrivileged(node *Process, parent *Process) bool {
+	if node.IsThread {
+		return false
+	}
+	if hasMixedUIDs(node.UIDs) {
+		return true
+	}
+
+	uid, ok := effectiveUID(node.UIDs)
+	if !ok || uid != 0 || parent == nil {
+		return false
+	}
+	parentUID, ok := effectiveUID(parent.UIDs)
+	return ok && parentUID != 0
+}
+
+// markPrivileged sets IsPrivileged on the processes that gained privileges with --privileged.
+// It runs before the signatures are computed, so the privileged processes are not compacted
+// with the others. The flags left over from a previous tree built on the same processes are
+// cleared.
+func (processTree *ProcessTree) markPrivileged() {
+	for _, node := range processTree.Nodes {
+		var parent *Process
+		if parentIndex, ok := processTree.PidToIndexMap[node.PPID]; ok && node.PPID != node.PID {
+			parent = processTree.Nodes[parentIndex]
+		}
+		node.IsPrivileged = processTree.DisplayOptions.ShowPrivileged && isPrivileged(node, parent)
+		if node.IsPrivileged && processTree.DebugLevel > 1 {
+			processTree.Logger.Debug(fmt.Sprintf("PID %d is privileged (UIDs %v)", node.PID, node.UIDs))
+		}
+	}
+}
+
+// markOnlyPrivileged unmarks the processes that are not privileged, keeping the ancestors of
+// those that are.
+func (processTree *ProcessTree) markOnlyPrivileged() {
+	processTree.Logger.Debug("Entering processTree.markOnlyPrivileged()")
+	processTree.narrowMarked(func(node *Process) bool {
+		return node.IsPrivileged
+	}, "is privileged")
+}
@@ -0,0 +1,109 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// privilegedProcesses returns a login shell running sudo and a setuid binary, with the UIDs in
+// the 4-element form of Linux and the 3-element form of macOS and the BSDs
+func privilegedProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", UIDs: []uint32{0, 0, 0, 0}},
+		{PID: 100, PPID: 1, Command: "sshd", UIDs: []uint32{0, 0, 0, 0}},
+		{PID: 200, PPID: 100, Command: "bash", UIDs: []uint32{1000, 1000, 1000, 1000}},
+		{PID: 201, PPID: 200, Command: "sudo", UIDs: []uint32{1000, 0, 0, 0}},
+		{PID: 202, PPID: 201, Command: "vim", UIDs: []uint32{0, 0, 0, 0}},
+		{PID: 203, PPID: 200, Command: "passwd", UIDs: []uint32{1000, 0, 0}},
+		{PID: 204, PPID: 200, Command: "su", UIDs: []uint32{0, 0, 0}},
+		{PID: 205, PPID: 200, Command: "less", UIDs: []uint32{1000, 1000, 1000}},
+	}
+}
+
+func TestEffectiveUID(t *testing.T) {
+	for _, test := range []struct {
+		uids []uint32
+		uid  uint32
+		ok   bool
+	}{
+		{nil, 0, false},
+		{[]uint32{1000}, 1000, true},
+		{[]uint32{1000, 0, 0}, 0, true},
+		{[]uint32{1000, 0, 0, 0}, 0, true},
+	} {
+		uid, ok := effectiveUID(test.uids)
+		assert.Equal(t, test.uid, uid, "%v", test.uids)
+		assert.Equal(t, test.ok, ok, "%v", test.uids)
+	}
+}
+
+func TestHasMixedUIDs(t *testing.T) {
+	assert.False(t, hasMixedUIDs(nil))
+	assert.False(t, hasMixedUIDs([]uint32{1000}))
+	assert.False(t, hasMixedUIDs([]uint32{1000, 1000, 1000}))
+	assert.False(t, hasMixedUIDs([]uint32{1000, 1000, 1000, 0}), "the filesystem UID is not compared")
+	assert.True(t, hasMixedUIDs([]uint32{1000, 0, 0}))
+	assert.True(t, hasMixedUIDs([]uint32{0, 0, 1000, 0}), "the saved UID differs")
+}
+
+func TestIsPrivileged(t *testing.T) {
+	user := &Process{UIDs: []uint32{1000, 1000, 1000, 1000}}
+	root := &Process{UIDs: []uint32{0, 0, 0, 0}}
+
+	assert.True(t, isPrivileged(&Process{UIDs: []uint32{0, 0, 0, 0}}, user), "root below a regular user")
+	assert.False(t, isPrivileged(&Process{UIDs: []uint32{0, 0, 0, 0}}, root), "root below root")
+	assert.False(t, isPrivileged(&Process{UIDs: []uint32{0, 0, 0, 0}}, nil), "root without a parent")
+	assert.False(t, isPrivileged(&Process{UIDs: []uint32{0, 0, 0}}, &Process{}), "the UIDs of the parent are unknown")
+	assert.True(t, isPrivileged(&Process{UIDs: []uint32{1000, 0, 0}}, nil), "setuid root")
+	assert.False(t, isPrivileged(&Process{}, user), "the UIDs are unknown")
+	assert.False(t, isPrivileged(&Process{UIDs: []uint32{1000, 0, 0}, IsThread: true}, user), "threads are left out")
+}
+
+func TestPrivilegedOutput(t *testing.T) {
+	displayOptions := DisplayOptions{MaxDepth: 10, ScreenWidth: 80, ShowPrivileged: true}
+	processTree := NewProcessTree(0, setupTestLogger(), privilegedProcesses(), displayOptions)
+	processTree.MarkProcesses()
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	assert.Equal(t, "-+- init \n \\-+- sshd \n   \\-+- bash \n     |-+- ⚑ sudo \n     | \\--- vim \n     |--- ⚑ passwd \n     |--- ⚑ su \n     \\--- less \n", output)
+
+	// --only-privileged keeps the privileged processes and their ancestors
+	displayOptions.OnlyPrivileged = true
+	processTree = NewProcessTree(0, setupTestLogger(), privilegedProcesses(), displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	output, err = processTree.RenderString()
+	require.NoError(t, err)
+	assert.Equal(t, "-+- init \n \\-+- sshd \n   \\-+- bash \n     |--- ⚑ sudo \n     |--- ⚑ passwd \n     \\--- ⚑ su \n", output)
+
+	// Without --privileged, nothing is marked, even on processes flagged by a previous tree
+	processes := privilegedProcesses()
+	NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	processTree = NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 10, ScreenWidth: 80})
+	for _, node := range processTree.Nodes {
+		assert.False(t, node.IsPrivileged, node.Command)
+	}
+}
+
+func TestPrivilegedCompact(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", UIDs: []uint32{0, 0, 0, 0}},
+		{PID: 200, PPID: 1, Command: "bash", UIDs: []uint32{1000, 1000, 1000, 1000}, Username: "alice"},
+		{PID: 201, PPID: 200, Command: "ping", UIDs: []uint32{1000, 0, 0, 0}, Username: "alice"},
+		{PID: 202, PPID: 200, Command: "ping", UIDs: []uint32{1000, 1000, 1000, 1000}, Username: "alice"},
+	}
+
+	// The setuid ping is not grouped with the one that didn't gain privileges
+	for _, test := range []struct {
+		privileged bool
+		count      int
+	}{{false, 2}, {true, 1}} {
+		processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{CompactMode: true, MaxDepth: 10, ShowPrivileged: test.privileged})
+		processTree.MarkProcesses()
+		processTree.InitCompactMode()
+		count, _, _, _, _, _ := processTree.GetProcessCount(processTree.PidToIndexMap[201])
+		assert.Equal(t, test.count, count, "--privileged=%t", test.privileged)
+	}
+}
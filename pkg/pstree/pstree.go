@@ -6,10 +6,16 @@
 package pstree
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
-	"log"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bananazon/pstree/util"
 	"github.com/shirou/gopsutil/v4/cpu"
@@ -36,6 +42,102 @@ func SortByPid(procs []*process.Process) []*process.Process {
 	return procs
 }
 
+// CompareProcesses compares two processes by the given --order-by attribute.
+//
+// Parameters:
+//   - a: The first process to compare
+//   - b: The second process to compare
+//   - orderBy: The attribute to compare by (age, cpu, cputime, faults, fds, io, mem, nice, pid, threads, user)
+//
+// Returns:
+//   - A negative number if a sorts before b, a positive number if a sorts after b, and 0 if
+//     they are equal or the attribute is unknown
+func CompareProcesses(a *Process, b *Process, orderBy string) int {
+	switch orderBy {
+	case "age":
+		return cmp.Compare(a.Age, b.Age)
+	case "cpu":
+		return cmp.Compare(a.CPUPercent, b.CPUPercent)
+	case "cputime":
+		return cmp.Compare(CPUTime(a), CPUTime(b))
+	case "faults":
+		return cmp.Compare(MajorFaults(a), MajorFaults(b))
+	case "fds":
+		return cmp.Compare(a.NumFDs, b.NumFDs)
+	case "io":
+		return cmp.Compare(IOBytes(a), IOBytes(b))
+	case "mem":
+		return cmp.Compare(MemoryValue(a, "rss"), MemoryValue(b, "rss"))
+	case "nice":
+		return cmp.Compare(niceOf(a), niceOf(b))
+	case "pid":
+		return cmp.Compare(a.PID, b.PID)
+	case "threads":
+		return cmp.Compare(a.NumThreads, b.NumThreads)
+	case "uid":
+		return cmp.Compare(processUID(a), processUID(b))
+	case "user":
+		return strings.Compare(a.Username, b.Username)
+	}
+	return 0
+}
+
+// compareOrdered compares two processes by the given --order-by attribute in the given direction.
+// Processes whose value could not be read always sort after the others, regardless of the direction.
+//
+// Parameters:
+//   - a: The first process to compare
+//   - b: The second process to compare
+//   - orderBy: The attribute to compare by
+//   - memoryField: The --mem-field or --mem-mode compared by mem, see MemoryValue
+//   - desc: Whether to compare in descending order
+//
+// Returns:
+//   - A negative number if a sorts before b, a positive number if a sorts after b, and 0 if they are equal
+func compareOrdered(a *Process, b *Process, orderBy string, memoryField string, desc bool) int {
+	var (
+		result int
+	)
+
+	if orderBy == "fds" && (a.NumFDs < 0 || b.NumFDs < 0) {
+		return cmp.Compare(b.NumFDs, a.NumFDs)
+	}
+	if orderBy == "age" && (a.Age < 0 || b.Age < 0) {
+		return cmp.Compare(b.Age, a.Age)
+	}
+	if orderBy == "cputime" && (CPUTime(a) < 0 || CPUTime(b) < 0) {
+		return cmp.Compare(CPUTime(b), CPUTime(a))
+	}
+	if orderBy == "faults" && (MajorFaults(a) < 0 || MajorFaults(b) < 0) {
+		return cmp.Compare(MajorFaults(b), MajorFaults(a))
+	}
+	if orderBy == "io" && (IOBytes(a) < 0 || IOBytes(b) < 0) {
+		return cmp.Compare(IOBytes(b), IOBytes(a))
+	}
+	if orderBy == "uid" && (processUID(a) < 0 || processUID(b) < 0) {
+		return cmp.Compare(processUID(b), processUID(a))
+	}
+	if orderBy == "cpu" && (!a.HasCPU || !b.HasCPU) {
+		return cmp.Compare(util.BtoI(!a.HasCPU), util.BtoI(!b.HasCPU))
+	}
+	if orderBy == "mem" && (!a.HasMemory || !b.HasMemory) {
+		return cmp.Compare(util.BtoI(!a.HasMemory), util.BtoI(!b.HasMemory))
+	}
+	if orderBy == "nice" && (a.Nice == nil || b.Nice == nil) {
+		return cmp.Compare(util.BtoI(a.Nice == nil), util.BtoI(b.Nice == nil))
+	}
+
+	if orderBy == "mem" {
+		result = cmp.Compare(MemoryValue(a, memoryField), MemoryValue(b, memoryField))
+	} else {
+		result = CompareProcesses(a, b, orderBy)
+	}
+	if desc {
+		result = -result
+	}
+	return result
+}
+
 //------------------------------------------------------------------------------
 // PROCESS LOOKUP FUNCTIONS
 //------------------------------------------------------------------------------
@@ -60,63 +162,191 @@ func GetProcessByPid(processes *[]Process, pid int32) (proc Process, err error)
 	return Process{}, errors.New(errorMessage)
 }
 
-// SortProcsByAge sorts the processes slice by process age in ascending order.
+// SortProcsBy sorts the processes slice by the given --order-by attribute, keeping the root processes first.
+//
+// The root processes are the ones whose parent is not in the slice, see isRootProcess. They are
+// moved to the front in both directions, in sorted order, so the tree still starts at the actual
+// roots when PID 1 is not collected, e.g., in a container or a snapshot taken from one.
 //
 // Parameters:
 //   - processes: Pointer to a slice of Process structs to be sorted
-func SortProcsByAge(processes *[]Process) {
-	sort.Slice(*processes, func(i, j int) bool {
-		return (*processes)[i].Age < (*processes)[j].Age
+//   - orderBy: The attribute to sort by (age, cpu, cputime, faults, fds, io, mem, nice, pid, threads, user)
+//   - desc: Whether to sort in descending order
+//
+// Returns:
+//   - An error if orderBy is not a valid attribute
+func SortProcsBy(processes *[]Process, orderBy string, desc bool) error {
+	switch orderBy {
+	case "age":
+		SortProcsByAge(processes, desc)
+	case "cpu":
+		SortProcsByCpu(processes, desc)
+	case "cputime":
+		SortProcsByCpuTime(processes, desc)
+	case "faults":
+		SortProcsByPageFaults(processes, desc)
+	case "fds":
+		SortProcsByNumFDs(processes, desc)
+	case "io":
+		SortProcsByIO(processes, desc)
+	case "mem":
+		SortProcsByMemory(processes, desc)
+	case "nice":
+		SortProcsByNice(processes, desc)
+	case "pid":
+		SortProcsByPid(processes, desc)
+	case "threads":
+		SortProcsByNumThreads(processes, desc)
+	case "user":
+		SortProcsByUsername(processes, desc)
+	default:
+		return fmt.Errorf("unable to sort by %q", orderBy)
+	}
+
+	// Move the roots back to the front, keeping the order of the other processes
+	pidToIndex := make(map[int32]int, len(*processes))
+	for i := range *processes {
+		pidToIndex[(*processes)[i].PID] = i
+	}
+	slices.SortStableFunc(*processes, func(a, b Process) int {
+		aRoot, bRoot := isRootProcess(&a, pidToIndex), isRootProcess(&b, pidToIndex)
+		switch {
+		case aRoot && !bRoot:
+			return -1
+		case !aRoot && bRoot:
+			return 1
+		}
+		return 0
 	})
+
+	return nil
+}
+
+// isRootProcess reports whether a process is at the top of a tree, because its parent
+// was not collected or it is its own parent.
+//
+// Parameters:
+//   - process: The process to check
+//   - pidToIndex: Map of the PIDs of the collected processes
+//
+// Returns:
+//   - bool: true if the process is a root
+func isRootProcess(process *Process, pidToIndex map[int32]int) bool {
+	_, ok := pidToIndex[process.PPID]
+	return !ok || process.PPID == process.PID
 }
 
-// SortProcsByCpu sorts the processes slice by CPU usage percentage in ascending order.
+// SortProcsByAge sorts the processes slice by process age.
 //
 // Parameters:
 //   - processes: Pointer to a slice of Process structs to be sorted
-func SortProcsByCpu(processes *[]Process) {
-	sort.Slice(*processes, func(i, j int) bool {
-		return (*processes)[i].CPUPercent < (*processes)[j].CPUPercent
-	})
+//   - desc: Whether to sort in descending order
+func SortProcsByAge(processes *[]Process, desc bool) {
+	sortProcs(processes, "age", desc)
 }
 
-// SortProcsByMemory sorts the processes slice by memory usage (RSS) in ascending order.
+// SortProcsByCpu sorts the processes slice by CPU usage percentage.
 //
 // Parameters:
 //   - processes: Pointer to a slice of Process structs to be sorted
-func SortProcsByMemory(processes *[]Process) {
-	sort.Slice(*processes, func(i, j int) bool {
-		return float64((*processes)[i].MemoryInfo.RSS) < float64((*processes)[j].MemoryInfo.RSS)
-	})
+//   - desc: Whether to sort in descending order
+func SortProcsByCpu(processes *[]Process, desc bool) {
+	sortProcs(processes, "cpu", desc)
 }
 
-// SortProcsByUsername sorts the processes slice by username in ascending alphabetical order.
+// SortProcsByCpuTime sorts the processes slice by consumed CPU time.
 //
 // Parameters:
 //   - processes: Pointer to a slice of Process structs to be sorted
-func SortProcsByUsername(processes *[]Process) {
-	sort.Slice(*processes, func(i, j int) bool {
-		return (*processes)[i].Username < (*processes)[j].Username
-	})
+//   - desc: Whether to sort in descending order
+func SortProcsByCpuTime(processes *[]Process, desc bool) {
+	sortProcs(processes, "cputime", desc)
 }
 
-// SortProcsByPid sorts the processes slice by PID in ascending order.
+// SortProcsByIO sorts the processes slice by the number of bytes read and written.
 //
 // Parameters:
 //   - processes: Pointer to a slice of Process structs to be sorted
-func SortProcsByPid(processes *[]Process) {
-	sort.Slice(*processes, func(i, j int) bool {
-		return (*processes)[i].PID < (*processes)[j].PID
-	})
+//   - desc: Whether to sort in descending order
+func SortProcsByIO(processes *[]Process, desc bool) {
+	sortProcs(processes, "io", desc)
+}
+
+// SortProcsByMemory sorts the processes slice by memory usage (RSS).
+//
+// Parameters:
+//   - processes: Pointer to a slice of Process structs to be sorted
+//   - desc: Whether to sort in descending order
+func SortProcsByMemory(processes *[]Process, desc bool) {
+	sortProcs(processes, "mem", desc)
 }
 
-// SortProcsByNumThreads sorts the processes slice by the number of threads in ascending order.
+// SortProcsByUsername sorts the processes slice by username in alphabetical order.
 //
 // Parameters:
 //   - processes: Pointer to a slice of Process structs to be sorted
-func SortProcsByNumThreads(processes *[]Process) {
-	sort.Slice(*processes, func(i, j int) bool {
-		return (*processes)[i].NumThreads < (*processes)[j].NumThreads
+//   - desc: Whether to sort in descending order
+func SortProcsByUsername(processes *[]Process, desc bool) {
+	sortProcs(processes, "user", desc)
+}
+
+// SortProcsByPageFaults sorts the processes slice by the number of major page faults.
+//
+// Parameters:
+//   - processes: Pointer to a slice of Process structs to be sorted
+//   - desc: Whether to sort in descending order
+func SortProcsByPageFaults(processes *[]Process, desc bool) {
+	sortProcs(processes, "faults", desc)
+}
+
+// SortProcsByPid sorts the processes slice by PID.
+//
+// Parameters:
+//   - processes: Pointer to a slice of Process structs to be sorted
+//   - desc: Whether to sort in descending order
+func SortProcsByPid(processes *[]Process, desc bool) {
+	sortProcs(processes, "pid", desc)
+}
+
+// SortProcsByNumThreads sorts the processes slice by the number of threads.
+//
+// Parameters:
+//   - processes: Pointer to a slice of Process structs to be sorted
+//   - desc: Whether to sort in descending order
+func SortProcsByNumThreads(processes *[]Process, desc bool) {
+	sortProcs(processes, "threads", desc)
+}
+
+// SortProcsByNice sorts the processes slice by their nice value.
+// Processes whose nice value could not be read are sorted last.
+//
+// Parameters:
+//   - processes: Pointer to a slice of Process structs to be sorted
+//   - desc: Whether to sort in descending order
+func SortProcsByNice(processes *[]Process, desc bool) {
+	sortProcs(processes, "nice", desc)
+}
+
+// SortProcsByNumFDs sorts the processes slice by the number of open file descriptors.
+// Processes whose file descriptors could not be read are sorted last.
+//
+// Parameters:
+//   - processes: Pointer to a slice of Process structs to be sorted
+//   - desc: Whether to sort in descending order
+func SortProcsByNumFDs(processes *[]Process, desc bool) {
+	sortProcs(processes, "fds", desc)
+}
+
+// sortProcs sorts the processes slice by the given attribute using CompareProcesses.
+// The sort is stable, so processes with equal values keep their relative order.
+//
+// Parameters:
+//   - processes: Pointer to a slice of Process structs to be sorted
+//   - orderBy: The attribute to sort by
+//   - desc: Whether to sort in descending order
+func sortProcs(processes *[]Process, orderBy string, desc bool) {
+	sort.SliceStable(*processes, func(i, j int) bool {
+		return compareOrdered(&(*processes)[i], &(*processes)[j], orderBy, "", desc) < 0
 	})
 }
 
@@ -127,46 +357,65 @@ func SortProcsByNumThreads(processes *[]Process) {
 
 // GenerateProcess creates a Process struct from a process.Process pointer.
 // It collects various process attributes using goroutines and channels for concurrent execution
-// to improve performance when gathering process information.
+// to improve performance when gathering process information. A process that exited since it was
+// listed is reported as not ok, unless miniOptions.KeepVanished is set, see readParent.
+//
+// Parameters:
+//   - proc: Pointer to a process.Process struct from which to generate the Process
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//
+// Returns:
+//   - A new Process struct populated with information from the input process
+//   - false if the process vanished and was not collected
+func GenerateProcess(proc *process.Process, miniOptions DisplayOptions) (Process, bool) {
+	return generateProcess(proc, miniOptions, newUsernameCache(miniOptions), nil)
+}
+
+// generateProcess creates a Process struct like GenerateProcess, adding the time spent reading
+// each attribute to the timings.
 //
 // Parameters:
 //   - proc: Pointer to a process.Process struct from which to generate the Process
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//   - users: The usernames already looked up during the collection, see usernameCache
+//   - timings: The timings the attributes are added to, nil to not record them
 //
 // Returns:
 //   - A new Process struct populated with information from the input process
-func GenerateProcess(proc *process.Process, miniOptions DisplayOptions) Process {
+//   - false if the process vanished and was not collected
+func generateProcess(proc *process.Process, miniOptions DisplayOptions, users *usernameCache, timings *CollectionTimings) (Process, bool) {
 	var (
 		args               []string
 		background         bool
 		command            string
 		connections        []net.ConnectionStat
+		containerID        string
 		cpuAffinity        []int32
-		cpuPercent         float64
-		cpuTimes           *cpu.TimesStat
-		createTime         int64
+		cwd                string
 		environment        []string
+		exeDeleted         bool
 		err                error
 		foreground         bool
 		gids               []uint32
 		groups             []uint32
-		ioCounters         *process.IOCountersStat
-		pageFaults         *process.PageFaultsStat
 		pgid               int
 		pid                int32
 		ppid               int32
-		memoryInfo         *process.MemoryInfoStat
-		memoryInfoEx       *process.MemoryInfoExStat
-		memoryPercent      float32
+		namespaces         map[string]uint64
+		nsPids             []int32
 		numContextSwitches *process.NumCtxSwitchesStat
-		numFDs             int32
-		numThreads         int32
 		openFiles          []process.OpenFilesStat
 		resourceLimit      []process.RlimitStat
 		resourceLimitUsage []process.RlimitStat
+		schedPolicy        string
+		sid                int
+		start              time.Time
 		status             []string
+		terminal           string
 		threads            map[int32]*cpu.TimesStat
 		uids               []uint32
 		username           string
+		vanished           bool
 	)
 
 	/*
@@ -174,50 +423,70 @@ func GenerateProcess(proc *process.Process, miniOptions DisplayOptions) Process
 	 */
 	pid = proc.Pid
 
-	// We need to get the arguments so identical processes are grouped, even if arguments are not displayed
-	argsChannel := make(chan func(proc *process.Process) (args []string, err error))
-	go ProcessArgs(argsChannel)
-	argsOut, err := (<-argsChannel)(proc)
-	if err != nil {
-		args = []string{}
+	// The PPID is read first, a process that exited since it was listed fails here instead of falling back on every attribute
+	start = time.Now()
+	ppid, vanished, err = readParent(proc)
+	timings.addAttribute("ppid", start)
+	timings.addResult("ppid", err)
+	if vanished && !miniOptions.KeepVanished {
+		return Process{}, false
+	}
+
+	// We need to get the arguments so identical processes are grouped, even if arguments are not displayed,
+	// but the command line is one of the costlier reads, so only argv[0] is read when nothing else uses it, see SkipArgs
+	args = []string{}
+	start = time.Now()
+	if miniOptions.SkipArgs {
+		argv0Out, err := ProcessArgv0(proc)
+		timings.addResult("args", err)
+		if err == nil && argv0Out != "" {
+			args = []string{argv0Out}
+		}
 	} else {
-		args = argsOut
+		argsOut, err := ProcessArgs(proc)
+		timings.addResult("args", err)
+		if err == nil {
+			args = argsOut
+		}
 	}
+	timings.addAttribute("args", start)
 
-	commandNameChannel := make(chan func(proc *process.Process) (string, error))
-	go ProcessCommandName(commandNameChannel)
-	commandOut, err := (<-commandNameChannel)(proc)
+	start = time.Now()
+	commandOut, err := ProcessCommandName(proc)
+	timings.addAttribute("command", start)
+	timings.addResult("command", err)
 	if err != nil {
 		command = "?"
 	} else {
 		command = commandOut
 	}
 
-	ppidChannel := make(chan func(proc *process.Process) (ppid int32, err error))
-	go ProcessPPID(ppidChannel)
-	ppidOut, err := (<-ppidChannel)(proc)
-	if err != nil {
-		ppid = -1
-	} else {
-		ppid = ppidOut
+	// The kernel marks the executable of a process whose binary was deleted, e.g., by a package upgrade
+	if strings.HasSuffix(command, DeletedExeSuffix) && ProcessExeDeleted(proc.Pid, command) {
+		command = strings.TrimSuffix(command, DeletedExeSuffix)
+		exeDeleted = true
 	}
 
-	usernameChannel := make(chan func(proc *process.Process) (username string, err error))
-	go ProcessUsername(usernameChannel)
-	usernameOut, err := (<-usernameChannel)(proc)
+	// The UIDs are also the fallback for usernames that cannot be looked up in time
+	start = time.Now()
+	uidsOut, err := ProcessUIDs(proc)
+	timings.addAttribute("uids", start)
+	timings.addResult("uids", err)
 	if err != nil {
-		username = "?"
+		uids = []uint32{}
 	} else {
-		username = usernameOut
+		uids = uidsOut
 	}
 
+	start = time.Now()
+	username = users.processUsername(proc, uids)
+	timings.addAttribute("username", start)
+
 	/*
 	 * Only gather these if they're requested
 	 */
 	// This is very expensive so we'll ignore it for now
-	// backgroundChannel := make(chan func(proc *process.Process) (background bool, err error))
-	// go ProcessBackground(backgroundChannel)
-	// backgroundOut, err := (<-backgroundChannel)(proc)
+	// backgroundOut, err := ProcessBackground(proc)
 	// if err != nil {
 	// 	background = false
 	// } else {
@@ -225,71 +494,53 @@ func GenerateProcess(proc *process.Process, miniOptions DisplayOptions) Process
 	// }
 
 	// This is very expensive so we'll ignore it for now
-	// childrenChannel := make(chan func(proc *process.Process) (children []*process.Process, err error))
-	// go ProcessChildren(childrenChannel)
-	// childrenOut, err := (<-childrenChannel)(proc)
+	// childrenOut, err := ProcessChildren(proc)
 	// if err != nil {
 	// 	children = []*process.Process{}
 	// } else {
 	// 	children = childrenOut
 	// }
 
-	// This is very expensive so we'll ignore it for now
-	// connectionsChannel := make(chan func(proc *process.Process) (connections []net.ConnectionStat, err error))
-	// go ProcessConnections(connectionsChannel)
-	// connectionsOut, err := (<-connectionsChannel)(proc)
+	// This is very expensive, CollectConnections gathers it later for the displayed processes only
+	// connectionsOut, err := ProcessConnections(proc)
 	// if err != nil {
 	// 	connections = []net.ConnectionStat{}
 	// } else {
 	// 	connections = connectionsOut
 	// }
 
-	// Not in use
-	// cpuAffintyChannel := make(chan func(proc *process.Process) (affinity []int32, err error))
-	// go ProcessCpuAffinity(cpuAffintyChannel)
-	// cpuAffinityOut, err := (<-cpuAffintyChannel)(proc)
-	// if err != nil {
-	// 	cpuAffinity = []int32{}
-	// } else {
-	// 	cpuAffinity = cpuAffinityOut
-	// }
-
-	if miniOptions.ShowCpuPercent || miniOptions.OrderBy == "cpu" || miniOptions.ColorAttr == "cpu" {
-		cpuPercentChannel := make(chan func(proc *process.Process) (cpuPercent float64, err error))
-		go ProcessCpuPercent(cpuPercentChannel)
-		cpuPercentOut, err := (<-cpuPercentChannel)(proc)
-		if err != nil {
-			cpuPercent = -1
-		} else {
-			cpuPercent = cpuPercentOut
+	// Like the scheduling policy, the container is read by PID, gopsutil doesn't report it
+	if miniOptions.ShowContainers || miniOptions.GroupByContainer {
+		start = time.Now()
+		containerIDOut, err := ProcessContainerID(pid)
+		timings.addAttribute("container", start)
+		timings.addResult("container", err)
+		if err == nil {
+			containerID = containerIDOut
 		}
 	}
 
 	// Not in use
-	// cpuTimesChannel := make(chan func(proc *process.Process) (cpuTimes *cpu.TimesStat, err error))
-	// go ProcessCpuTimes(cpuTimesChannel)
-	// cpuTimesOut, err := (<-cpuTimesChannel)(proc)
+	// cpuAffinityOut, err := ProcessCpuAffinity(proc)
 	// if err != nil {
-	// 	cpuTimes = &cpu.TimesStat{}
+	// 	cpuAffinity = []int32{}
 	// } else {
-	// 	cpuTimes = cpuTimesOut
+	// 	cpuAffinity = cpuAffinityOut
 	// }
 
-	if miniOptions.ShowProcessAge || miniOptions.OrderBy == "age" || miniOptions.ColorAttr == "age" {
-		createTimeChannel := make(chan func(proc *process.Process) (createTime int64, err error))
-		go ProcessCreateTime(createTimeChannel)
-		createTimeOut, err := (<-createTimeChannel)(proc)
-		if err != nil {
-			createTime = -1
-		} else {
-			createTime = createTimeOut
+	// Reading the working directory of another user's process fails without privileges, it is left empty then
+	if miniOptions.ShowCwd || miniOptions.CwdUnder != "" {
+		start = time.Now()
+		cwdOut, err := ProcessCwd(proc)
+		timings.addAttribute("cwd", start)
+		timings.addResult("cwd", err)
+		if err == nil {
+			cwd = cwdOut
 		}
 	}
 
 	// Not in use
-	// environmentChannel := make(chan func(proc *process.Process) (environment []string, err error))
-	// go ProcessEnvironment(environmentChannel)
-	// environmentOut, err := (<-environmentChannel)(proc)
+	// environmentOut, err := ProcessEnvironment(proc)
 	// if err != nil {
 	// 	environment = []string{}
 	// } else {
@@ -297,126 +548,101 @@ func GenerateProcess(proc *process.Process, miniOptions DisplayOptions) Process
 	// }
 
 	// This is very expensive so we'll ignore it for now
-	// foregroundChannel := make(chan func(proc *process.Process) (foreground bool, err error))
-	// go ProcessForeground(foregroundChannel)
-	// foregroundOut, err := (<-foregroundChannel)(proc)
+	// foregroundOut, err := ProcessForeground(proc)
 	// if err != nil {
 	// 	foreground = false
 	// } else {
 	// 	foreground = foregroundOut
 	// }
 
-	gidsChannel := make(chan func(proc *process.Process) (gids []uint32, err error))
-	go ProcessGIDs(gidsChannel)
-	gidsOut, err := (<-gidsChannel)(proc)
+	start = time.Now()
+	gidsOut, err := ProcessGIDs(proc)
+	timings.addAttribute("gids", start)
+	timings.addResult("gids", err)
 	if err != nil {
 		gids = []uint32{}
 	} else {
 		gids = gidsOut
 	}
 
-	groupsChannel := make(chan func(proc *process.Process) (groups []uint32, err error))
-	go ProcessGroups(groupsChannel)
-	groupsOut, err := (<-groupsChannel)(proc)
+	start = time.Now()
+	groupsOut, err := ProcessGroups(proc)
+	timings.addAttribute("groups", start)
+	timings.addResult("groups", err)
 	if err != nil {
 		groups = []uint32{}
 	} else {
 		groups = groupsOut
 	}
 
-	// Not in use
-	// ioCountersChannel := make(chan func(proc *process.Process) (ioCounters *process.IOCountersStat, err error))
-	// go ProcessIOCounters(ioCountersChannel)
-	// ioCountersOut, err := (<-ioCountersChannel)(proc)
-	// if err != nil {
-	// 	ioCounters = &process.IOCountersStat{}
-	// } else {
-	// 	ioCounters = ioCountersOut
-	// }
-
-	if miniOptions.ShowMemoryUsage || miniOptions.OrderBy == "mem" || miniOptions.ColorAttr == "mem" {
-		memoryInfoChannel := make(chan func(proc *process.Process) (memoryInfo *process.MemoryInfoStat, err error))
-		go ProcessMemoryInfo(memoryInfoChannel)
-		memoryInfoOut, err := (<-memoryInfoChannel)(proc)
-		if err != nil {
-			memoryInfo = &process.MemoryInfoStat{}
-		} else {
-			memoryInfo = memoryInfoOut
-		}
+	start = time.Now()
+	numContextSwitchesOut, err := ProcessNumCtxSwitches(proc)
+	timings.addAttribute("ctxswitches", start)
+	timings.addResult("ctxswitches", err)
+	if err != nil {
+		numContextSwitches = &process.NumCtxSwitchesStat{}
+	} else {
+		numContextSwitches = numContextSwitchesOut
+	}
 
-		memoryInfoExChannel := make(chan func(proc *process.Process) (memoryInfoEx *process.MemoryInfoExStat, err error))
-		go ProcessMemoryInfoEx(memoryInfoExChannel)
-		memoryInfoExOut, err := (<-memoryInfoExChannel)(proc)
-		if err != nil {
-			memoryInfoEx = &process.MemoryInfoExStat{}
-		} else {
-			memoryInfoEx = memoryInfoExOut
+	// Like the scheduling policy, the namespaces are read by PID
+	if miniOptions.ShowNamespaces || miniOptions.OnlyForeignNS {
+		start = time.Now()
+		namespacesOut, err := ProcessNamespaces(pid)
+		timings.addAttribute("namespaces", start)
+		timings.addResult("namespaces", err)
+		if err == nil {
+			namespaces = namespacesOut
 		}
+	}
 
-		memoryPercentChannel := make(chan func(proc *process.Process) (memoryPercent float32, err error))
-		go ProcessMemoryPercent(memoryPercentChannel)
-		memoryPercentOut, err := (<-memoryPercentChannel)(proc)
-		if err != nil {
-			memoryPercent = -1.0
-		} else {
-			memoryPercent = memoryPercentOut
+	// gopsutil only reports the PID in the namespace pstree runs in, the others are read by PID
+	if miniOptions.ShowNSPids {
+		start = time.Now()
+		nsPidsOut, err := ProcessNSPids(pid)
+		timings.addAttribute("nspids", start)
+		timings.addResult("nspids", err)
+		if err == nil {
+			nsPids = nsPidsOut
 		}
 	}
 
-	numCtxSwitchesChannel := make(chan func(proc *process.Process) (numContextSwitches *process.NumCtxSwitchesStat, err error))
-	go ProcessNumCtxSwitches(numCtxSwitchesChannel)
-	numContextSwitchesOut, err := (<-numCtxSwitchesChannel)(proc)
-	if err != nil {
-		numContextSwitches = &process.NumCtxSwitchesStat{}
-	} else {
-		numContextSwitches = numContextSwitchesOut
+	// Unlike the other attributes, the policy is read by PID, gopsutil doesn't report it
+	if miniOptions.ShowSched || miniOptions.OnlyRealtime {
+		start = time.Now()
+		schedPolicyOut, err := ProcessSchedPolicy(pid)
+		timings.addAttribute("sched", start)
+		timings.addResult("sched", err)
+		if err == nil {
+			schedPolicy = schedPolicyOut
+		}
 	}
 
-	// Not in use
-	// numFDsChannel := make(chan func(proc *process.Process) (numFDs int32, err error))
-	// go ProcessNumFDs(numFDsChannel)
-	// numFDsOut, err := (<-numFDsChannel)(proc)
-	// if err != nil {
-	// 	numFDs = -1
-	// } else {
-	// 	numFDs = numFDsOut
-	// }
-
-	if miniOptions.ShowNumThreads || miniOptions.OrderBy == "threads" {
-		numThreadsChannel := make(chan func(proc *process.Process) (numThreads int32, err error))
-		go ProcessNumThreads(numThreadsChannel)
-		numThreadsOut, err := (<-numThreadsChannel)(proc)
+	if miniOptions.ShowSIDs || miniOptions.SessionOf != 0 {
+		start = time.Now()
+		sidOut, err := ProcessSID(proc)
+		timings.addAttribute("sid", start)
+		timings.addResult("sid", err)
 		if err != nil {
-			numThreads = -1
+			sid = -1
 		} else {
-			numThreads = numThreadsOut
+			sid = sidOut
 		}
 	}
 
 	// Not in use
-	// openFilesChannel := make(chan func(proc *process.Process) (openFiles []process.OpenFilesStat, err error))
-	// go ProcessOpenFiles(openFilesChannel)
-	// openFilesOut, err := (<-openFilesChannel)(proc)
+	// openFilesOut, err := ProcessOpenFiles(proc)
 	// if err != nil {
 	// 	openFiles = []process.OpenFilesStat{}
 	// } else {
 	// 	openFiles = openFilesOut
 	// }
 
-	// Not in use
-	// pageFaultsChannel := make(chan func(proc *process.Process) (pageFaults *process.PageFaultsStat, err error))
-	// go ProcessPageFaults(pageFaultsChannel)
-	// pageFaultsOut, err := (<-pageFaultsChannel)(proc)
-	// if err != nil {
-	// 	pageFaults = &process.PageFaultsStat{}
-	// } else {
-	// 	pageFaults = pageFaultsOut
-	// }
-
-	if miniOptions.ShowPGIDs || miniOptions.ShowPGLs {
-		pgidChannel := make(chan func(proc *process.Process) (pgid int, err error))
-		go ProcessPGID(pgidChannel)
-		pgidOut, err := (<-pgidChannel)(proc)
+	if miniOptions.ShowPGIDs || miniOptions.ShowPGLs || miniOptions.ByProcessGroup {
+		start = time.Now()
+		pgidOut, err := ProcessPGID(proc)
+		timings.addAttribute("pgid", start)
+		timings.addResult("pgid", err)
 		if err != nil {
 			pgid = -1
 		} else {
@@ -425,9 +651,7 @@ func GenerateProcess(proc *process.Process, miniOptions DisplayOptions) Process
 	}
 
 	// Not in use
-	// resourceLimitChannel := make(chan func(proc *process.Process) (resourceLimit []process.RlimitStat, err error))
-	// go ProcessResourceLimit(resourceLimitChannel)
-	// resourceLimitOut, err := (<-resourceLimitChannel)(proc)
+	// resourceLimitOut, err := ProcessResourceLimit(proc)
 	// if err != nil {
 	// 	resourceLimit = []process.RlimitStat{}
 	// } else {
@@ -435,129 +659,433 @@ func GenerateProcess(proc *process.Process, miniOptions DisplayOptions) Process
 	// }
 
 	// Not in use
-	// resourceLimitUsageChannel := make(chan func(proc *process.Process) (resourceLimitUsage []process.RlimitStat, err error))
-	// go ProcessResourceLimitUsage(resourceLimitUsageChannel)
-	// resourceLimitUsageOut, err := (<-resourceLimitUsageChannel)(proc)
+	// resourceLimitUsageOut, err := ProcessResourceLimitUsage(proc)
 	// if err != nil {
 	// 	resourceLimitUsage = []process.RlimitStat{}
 	// } else {
 	// 	resourceLimitUsage = resourceLimitUsageOut
 	// }
 
-	// This is very expensive so we'll ignore it for now
-	// statusChannel := make(chan func(proc *process.Process) (status []string, err error))
-	// go ProcessStatus(statusChannel)
-	// statusOut, err := (<-statusChannel)(proc)
-	// if err != nil {
-	// 	status = []string{}
-	// } else {
-	// 	status = statusOut
-	// }
-
-	// Not in use
-	// threadsChannel := make(chan func(proc *process.Process) (threads map[int32]*cpu.TimesStat, err error))
-	// go ProcessThreads(threadsChannel)
-	// threadsOut, err := (<-threadsChannel)(proc)
-	// if err != nil {
-	// 	threads = map[int32]*cpu.TimesStat{}
-	// } else {
-	// 	threads = threadsOut
-	// }
-
-	if miniOptions.ShowOwner || miniOptions.ShowUserTransitions || miniOptions.OrderBy == "user" {
-		usernameChannel := make(chan func(proc *process.Process) (username string, err error))
-		go ProcessUsername(usernameChannel)
-		usernameOut, err := (<-usernameChannel)(proc)
+	// This is very expensive so only collect it when it's displayed
+	if miniOptions.ShowStatus || miniOptions.ShowZombies {
+		start = time.Now()
+		statusOut, err := ProcessStatus(proc)
+		timings.addAttribute("status", start)
+		timings.addResult("status", err)
 		if err != nil {
-			username = "?"
+			status = []string{}
 		} else {
-			username = usernameOut
+			status = statusOut
 		}
 	}
 
-	if miniOptions.ShowUIDTransitions {
-		uidsChannel := make(chan func(proc *process.Process) (uids []uint32, err error))
-		go ProcessUIDs(uidsChannel)
-		uidsOut, err := (<-uidsChannel)(proc)
+	// Only needed to filter by terminal
+	if miniOptions.Terminal != "" {
+		start = time.Now()
+		terminalOut, err := ProcessTerminal(proc)
+		timings.addAttribute("terminal", start)
+		timings.addResult("terminal", err)
 		if err != nil {
-			uids = []uint32{}
+			terminal = ""
 		} else {
-			uids = uidsOut
+			terminal = terminalOut
 		}
 	}
 
-	if len(args) > 0 {
-		if args[0] == command {
-			if len(args) == 1 {
-				args = []string{}
-			} else if len(args) > 1 {
-				args = args[1:]
-			}
+	if miniOptions.ShowThreadsTree {
+		start = time.Now()
+		threadsOut, err := ProcessThreads(proc)
+		timings.addAttribute("threadstree", start)
+		timings.addResult("threadstree", err)
+		if err != nil {
+			threads = map[int32]*cpu.TimesStat{}
+		} else {
+			threads = threadsOut
 		}
 	}
 
-	return Process{
-		Age:                util.GetUnixTimestamp() - createTime,
+	// argv[0] is shown as the command when it names another program, so it is never repeated in the arguments
+	argv0, args := splitArgv0(args)
+
+	generated := Process{
+		// -1 marks the age as unknown until the create time is read
+		Age:                -1,
 		Args:               args,
+		Argv0:              argv0,
 		Background:         background,
 		Child:              -1,
 		Command:            command,
 		Connections:        connections,
+		ContainerID:        containerID,
 		CPUAffinity:        cpuAffinity,
-		CPUPercent:         util.RoundFloat(cpuPercent, 2),
-		CPUTimes:           cpuTimes,
-		CreateTime:         createTime,
+		Cwd:                cwd,
 		Environment:        environment,
+		ExeDeleted:         exeDeleted,
 		Foreground:         foreground,
 		GIDs:               gids,
 		Groups:             groups,
-		IOCounters:         ioCounters,
-		MemoryInfo:         memoryInfo,
-		MemoryInfoEx:       memoryInfoEx,
-		MemoryPercent:      memoryPercent,
+		Namespaces:         namespaces,
+		NSPids:             nsPids,
 		NumContextSwitches: numContextSwitches,
-		NumFDs:             numFDs,
-		NumThreads:         numThreads,
+		// -1 marks the file descriptors as unknown until they are read
+		NumFDs:             -1,
 		OpenFiles:          openFiles,
-		PageFaults:         pageFaults,
 		Parent:             -1,
 		PGID:               int32(pgid),
 		PID:                pid,
 		PPID:               ppid,
 		ResourceLimit:      resourceLimit,
 		ResourceLimitUsage: resourceLimitUsage,
+		SchedPolicy:        schedPolicy,
+		SID:                int32(sid),
 		Sister:             -1,
 		Status:             status,
+		Terminal:           terminal,
 		Threads:            threads,
 		UIDs:               uids,
 		Username:           username,
 	}
+	collectUsage(proc, miniOptions, timings, &generated)
+
+	return generated, true
+}
+
+// collectUsage reads the resource usage of a process into its Process struct: the CPU usage and
+// times, the age, and the memory, IO, file descriptor, nice, thread, and page fault values.
+// Only the attributes enabled by miniOptions are read and the others are left as they are, so
+// the usage deferred by SplitCollection can be added to a process collected without it.
+//
+// Parameters:
+//   - proc: Pointer to the process.Process struct the usage is read from
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//   - timings: The timings the attributes are added to, nil to not record them
+//   - target: The Process the usage is written to
+func collectUsage(proc *process.Process, miniOptions DisplayOptions, timings *CollectionTimings, target *Process) {
+	var start time.Time
+
+	if miniOptions.ShowCpuPercent || miniOptions.OrderBy == "cpu" || miniOptions.ColorAttr == "cpu" {
+		start = time.Now()
+		cpuPercentOut, err := ProcessCpuPercent(proc)
+		timings.addAttribute("cpu", start)
+		timings.addResult("cpu", err)
+		if err != nil {
+			target.CPUPercent = -1
+		} else {
+			target.CPUPercent = util.RoundFloat(cpuPercentOut, 2)
+			target.HasCPU = true
+		}
+	}
+
+	// Watch mode needs the raw CPU times so the percentage can be computed over the refresh interval
+	if (miniOptions.WatchInterval > 0 && (miniOptions.ShowCpuPercent || miniOptions.OrderBy == "cpu" || miniOptions.ColorAttr == "cpu")) || miniOptions.ShowCpuTime || miniOptions.OrderBy == "cputime" || miniOptions.ColorAttr == "cputime" {
+		start = time.Now()
+		cpuTimesOut, err := ProcessCpuTimes(proc)
+		timings.addAttribute("cputimes", start)
+		timings.addResult("cputimes", err)
+		if err != nil {
+			target.CPUTimes = nil
+		} else {
+			target.CPUTimes = cpuTimesOut
+		}
+	}
+
+	// The age stays unknown when the create time can't be read, --diff matches the processes by their create time
+	if miniOptions.ShowProcessAge || miniOptions.ShowStartTime || miniOptions.OrderBy == "age" || miniOptions.ColorAttr == "age" || miniOptions.ShowDiff || miniOptions.YoungerThan > 0 || miniOptions.OlderThan > 0 {
+		start = time.Now()
+		createTimeOut, err := ProcessCreateTime(proc)
+		timings.addAttribute("age", start)
+		timings.addResult("age", err)
+		if err != nil {
+			target.CreateTime = -1
+		} else {
+			target.CreateTime = createTimeOut
+			// A create time in the future, e.g., due to clock skew in a container, counts as just started
+			target.Age = max(util.GetUnixTimestamp()-createTimeOut, 0)
+		}
+	}
+
+	// Reading the IO counters of another user's process fails without privileges, nil marks them as unknown
+	if miniOptions.ShowIO || miniOptions.OrderBy == "io" {
+		start = time.Now()
+		ioCountersOut, err := ProcessIOCounters(proc)
+		timings.addAttribute("io", start)
+		timings.addResult("io", err)
+		if err == nil {
+			target.IOCounters = ioCountersOut
+		}
+	}
+
+	if miniOptions.ShowMemoryUsage || miniOptions.OrderBy == "mem" || miniOptions.ColorAttr == "mem" {
+		start = time.Now()
+		memoryInfoOut, err := ProcessMemoryInfo(proc)
+		timings.addAttribute("memory", start)
+		timings.addResult("memory", err)
+		// A zero RSS is real data for kernel threads, HasMemory tells it apart from a failed read
+		if err != nil {
+			target.MemoryInfo = &process.MemoryInfoStat{}
+		} else {
+			target.MemoryInfo = memoryInfoOut
+			target.HasMemory = true
+		}
+
+		start = time.Now()
+		memoryInfoExOut, err := ProcessMemoryInfoEx(proc)
+		timings.addAttribute("memory", start)
+		timings.addResult("memory", err)
+		if err != nil {
+			target.MemoryInfoEx = &process.MemoryInfoExStat{}
+		} else {
+			target.MemoryInfoEx = memoryInfoExOut
+		}
+
+		start = time.Now()
+		memoryPercentOut, err := ProcessMemoryPercent(proc)
+		timings.addAttribute("memory", start)
+		timings.addResult("memory", err)
+		if err != nil {
+			target.MemoryPercent = -1.0
+		} else {
+			target.MemoryPercent = memoryPercentOut
+		}
+
+		// Reading smaps_rollup walks the page tables, so the PSS and USS are only read when selected
+		if miniOptions.MemoryMode == "pss" || miniOptions.MemoryMode == "uss" {
+			start = time.Now()
+			sharedMemoryOut, err := ProcessSharedMemory(proc)
+			timings.addAttribute("smaps", start)
+			timings.addResult("smaps", err)
+			if err == nil {
+				target.SharedMemory = sharedMemoryOut
+			}
+		}
+	}
+
+	// Reading the file descriptors of another user's process fails without privileges, they stay unknown then
+	if miniOptions.ShowNumFDs || miniOptions.OrderBy == "fds" || miniOptions.ColorAttr == "fds" {
+		start = time.Now()
+		numFDsOut, err := ProcessNumFDs(proc)
+		timings.addAttribute("fds", start)
+		timings.addResult("fds", err)
+		if err == nil {
+			target.NumFDs = numFDsOut
+		}
+	}
+
+	if miniOptions.ShowNice || miniOptions.OrderBy == "nice" {
+		start = time.Now()
+		niceOut, err := ProcessNice(proc)
+		timings.addAttribute("nice", start)
+		timings.addResult("nice", err)
+		if err == nil {
+			target.Nice = &niceOut
+		}
+	}
+
+	if miniOptions.ShowNumThreads || miniOptions.OrderBy == "threads" {
+		start = time.Now()
+		numThreadsOut, err := ProcessNumThreads(proc)
+		timings.addAttribute("threads", start)
+		timings.addResult("threads", err)
+		if err != nil {
+			target.NumThreads = -1
+		} else {
+			target.NumThreads = numThreadsOut
+		}
+	}
+
+	// Some platforms don't report page faults, nil marks them as unknown
+	if miniOptions.PageFaults != "" || miniOptions.OrderBy == "faults" {
+		start = time.Now()
+		pageFaultsOut, err := ProcessPageFaults(proc)
+		timings.addAttribute("faults", start)
+		timings.addResult("faults", err)
+		if err == nil {
+			target.PageFaults = pageFaultsOut
+		}
+	}
 }
 
-// GetProcesses retrieves all system processes and populates the provided processes slice.
+// GetProcesses retrieves all system processes.
 //
 // This function uses the gopsutil library to get a list of all processes running on the system,
-// sorts them by PID, and then generates detailed Process structs for each one using the
-// generateProcess function.
+// sorts them by PID, and then generates detailed Process structs for each one using a pool of
+// workers running GenerateProcess, see generateProcesses. With miniOptions.SnapshotRepair, the
+// processes whose parent went missing while they were collected are repaired, see repairSnapshot.
+// Errors are returned to the caller rather than terminating the program, so the package can be
+// embedded in other programs.
+//
+// Parameters:
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//
+// Returns:
+//   - Slice of Process structs sorted by PID
+//   - An error if the list of processes could not be retrieved
+func GetProcesses(miniOptions DisplayOptions) ([]Process, error) {
+	return GetProcessesWithTimings(miniOptions, nil)
+}
+
+// GetProcessesWithTimings retrieves all system processes like GetProcesses, adding the time spent
+// enumerating and collecting them to the timings. Calling it more than once with the same
+// timings, e.g., for both snapshots of --diff, adds up the durations.
 //
 // Parameters:
-//   - processes: A pointer to a slice that will be populated with Process structs
-//   - flagOrderBy: A string indicating the order by which to sort the processes
-//   - miniOptions: A pointer to a MiniOptions struct containing options for the process tree
-func GetProcesses(processes *[]Process, miniOptions DisplayOptions) {
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//   - timings: The timings the durations are added to, nil to not record them
+//
+// Returns:
+//   - Slice of Process structs sorted by PID
+//   - An error if the list of processes could not be retrieved
+func GetProcessesWithTimings(miniOptions DisplayOptions, timings *CollectionTimings) ([]Process, error) {
 	var (
 		err      error
 		sorted   []*process.Process
+		start    time.Time
 		unsorted []*process.Process
 	)
+	start = time.Now()
+	if timings != nil {
+		defer addSince(&timings.Collect, start)
+	}
+
 	unsorted, err = process.Processes()
 	if err != nil {
-		log.Fatalf("Failed to get processes: %v", err)
+		return nil, fmt.Errorf("failed to get processes: %w", err)
 	}
 
 	sorted = SortByPid(unsorted)
+	if timings != nil {
+		timings.Enumerate += time.Since(start)
+	}
+	processes, vanished := generateProcesses(sorted, miniOptions, timings)
+	logVanished(vanished)
+
+	if miniOptions.SnapshotRepair != "" {
+		processes = repairSnapshot(processes, miniOptions.SnapshotRepair, liveSnapshotSource(miniOptions), repairLogger())
+	}
+
+	if miniOptions.ShowThreadsTree {
+		processes = appendThreadNodes(processes)
+	}
+
+	return processes, nil
+}
+
+// appendThreadNodes adds a synthetic child node for each thread of every process.
+//
+// The thread nodes are named "{command}" after their process and use the thread ID as
+// their PID, so identical threads under the same process are grouped into "N*[{command}]"
+// in compact mode. The main thread shares the PID of its process and is not added. Thread
+// nodes carry no CPU, memory, thread or file descriptor values since those are already
+// accounted for by their process.
+//
+// Parameters:
+//   - processes: Slice of Process structs with their Threads populated
+//
+// Returns:
+//   - The processes followed by the thread nodes, ordered by process and thread ID
+func appendThreadNodes(processes []Process) []Process {
+	var (
+		threadIDs []int32
+	)
+
+	for i := range processes {
+		threadIDs = threadIDs[:0]
+		for tid := range processes[i].Threads {
+			if tid != processes[i].PID {
+				threadIDs = append(threadIDs, tid)
+			}
+		}
+		slices.Sort(threadIDs)
+
+		for _, tid := range threadIDs {
+			processes = append(processes, Process{
+				Age:        processes[i].Age,
+				Args:       []string{},
+				Command:    fmt.Sprintf("{%s}", filepath.Base(processes[i].Command)),
+				CreateTime: processes[i].CreateTime,
+				GIDs:       processes[i].GIDs,
+				IsThread:   true,
+				MemoryInfo: &process.MemoryInfoStat{},
+				NumFDs:     -1,
+				PGID:       processes[i].PGID,
+				PID:        tid,
+				PPID:       processes[i].PID,
+				SID:        processes[i].SID,
+				Terminal:   processes[i].Terminal,
+				UIDs:       processes[i].UIDs,
+				Username:   processes[i].Username,
+			})
+		}
+	}
+
+	return processes
+}
+
+// generateProcesses runs GenerateProcess for each process using a bounded pool of workers.
+//
+// The number of workers is taken from miniOptions.Workers, defaulting to GOMAXPROCS. Each
+// result is stored at the index of its input, so the returned slice keeps the order of procs.
+// A process that disappears while being inspected is left out, unless miniOptions.KeepVanished
+// is set, in which case it produces a Process with the default values set by GenerateProcess.
+// The workers share a usernameCache, so the username of each UID is only looked up once.
+//
+// Parameters:
+//   - procs: Slice of process pointers to generate Process structs for
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//   - timings: The timings the attributes are added to, nil to not record them
+//
+// Returns:
+//   - Slice of Process structs in the same order as procs
+//   - Number of processes left out because they vanished
+func generateProcesses(procs []*process.Process, miniOptions DisplayOptions, timings *CollectionTimings) ([]Process, int) {
+	results := make([]Process, len(procs))
+	collected := make([]bool, len(procs))
+	users := newUsernameCache(miniOptions)
+	runWorkers(len(procs), miniOptions.Workers, func(i int) {
+		results[i], collected[i] = generateProcess(procs[i], miniOptions, users, timings)
+	})
+
+	processes := results[:0]
+	for i := range results {
+		if collected[i] {
+			processes = append(processes, results[i])
+		}
+	}
+	return processes, len(results) - len(processes)
+}
+
+// runWorkers calls work for each index from 0 to count-1 using a bounded pool of workers, and
+// returns once all of them are done.
+//
+// Parameters:
+//   - count: Number of indices to work on
+//   - workers: Number of workers (0 for GOMAXPROCS)
+//   - work: The function called with each index, concurrently with the others
+func runWorkers(count int, workers int, work func(i int)) {
+	var (
+		jobs chan int
+		wg   sync.WaitGroup
+	)
+
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, count)
+
+	jobs = make(chan int)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
 
-	for _, p := range sorted {
-		*processes = append(*processes, GenerateProcess(p, miniOptions))
+	for i := range count {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 }
@@ -1,10 +1,13 @@
 package pstree
 
 import (
+	"os"
 	"testing"
 
+	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/process"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSortByPid(t *testing.T) {
@@ -44,6 +47,24 @@ func TestGetProcessByPid(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCompareProcesses(t *testing.T) {
+	proc1 := Process{PID: 100, Age: 300, HasCPU: true, CPUPercent: 1.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 2048}, NumFDs: 8, NumThreads: 4, Username: "bob"}
+	proc2 := Process{PID: 200, Age: 100, HasCPU: true, CPUPercent: 2.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1024}, NumFDs: 16, NumThreads: 4, Username: "alice"}
+
+	assert.Positive(t, CompareProcesses(&proc1, &proc2, "age"))
+	assert.Negative(t, CompareProcesses(&proc1, &proc2, "cpu"))
+	assert.Negative(t, CompareProcesses(&proc1, &proc2, "fds"))
+	assert.Positive(t, CompareProcesses(&proc1, &proc2, "mem"))
+	assert.Negative(t, CompareProcesses(&proc1, &proc2, "pid"))
+	assert.Zero(t, CompareProcesses(&proc1, &proc2, "threads"))
+	assert.Positive(t, CompareProcesses(&proc1, &proc2, "user"))
+	assert.Zero(t, CompareProcesses(&proc1, &proc2, "unknown"))
+
+	// Memory that wasn't collected compares as zero
+	proc2.MemoryInfo = nil
+	assert.Positive(t, CompareProcesses(&proc1, &proc2, "mem"))
+}
+
 func TestSortProcsByAge(t *testing.T) {
 	// Create test processes with different ages
 	proc1 := Process{PID: 100, Age: 300}
@@ -54,7 +75,7 @@ func TestSortProcsByAge(t *testing.T) {
 	processes := []Process{proc1, proc2, proc3}
 
 	// Sort the processes by age
-	SortProcsByAge(&processes)
+	SortProcsByAge(&processes, false)
 
 	// Verify that the processes are sorted by age in ascending order
 	assert.Equal(t, int64(100), processes[0].Age)
@@ -64,15 +85,15 @@ func TestSortProcsByAge(t *testing.T) {
 
 func TestSortProcsByCpu(t *testing.T) {
 	// Create test processes with different CPU percentages
-	proc1 := Process{PID: 100, CPUPercent: 5.0}
-	proc2 := Process{PID: 200, CPUPercent: 1.0}
-	proc3 := Process{PID: 300, CPUPercent: 10.0}
+	proc1 := Process{PID: 100, HasCPU: true, CPUPercent: 5.0}
+	proc2 := Process{PID: 200, HasCPU: true, CPUPercent: 1.0}
+	proc3 := Process{PID: 300, HasCPU: true, CPUPercent: 10.0}
 
 	// Create a slice with the processes
 	processes := []Process{proc1, proc2, proc3}
 
 	// Sort the processes by CPU percentage
-	SortProcsByCpu(&processes)
+	SortProcsByCpu(&processes, false)
 
 	// Verify that the processes are sorted by CPU percentage in ascending order
 	assert.Equal(t, float64(1.0), processes[0].CPUPercent)
@@ -82,15 +103,15 @@ func TestSortProcsByCpu(t *testing.T) {
 
 func TestSortProcsByMemory(t *testing.T) {
 	// Create test processes with different memory usage
-	proc1 := Process{PID: 100, MemoryInfo: &process.MemoryInfoStat{RSS: 5000}}
-	proc2 := Process{PID: 200, MemoryInfo: &process.MemoryInfoStat{RSS: 1000}}
-	proc3 := Process{PID: 300, MemoryInfo: &process.MemoryInfoStat{RSS: 10000}}
+	proc1 := Process{PID: 100, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 5000}}
+	proc2 := Process{PID: 200, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1000}}
+	proc3 := Process{PID: 300, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 10000}}
 
 	// Create a slice with the processes
 	processes := []Process{proc1, proc2, proc3}
 
 	// Sort the processes by memory usage
-	SortProcsByMemory(&processes)
+	SortProcsByMemory(&processes, false)
 
 	// Verify that the processes are sorted by memory usage in ascending order
 	assert.Equal(t, uint64(1000), processes[0].MemoryInfo.RSS)
@@ -108,7 +129,7 @@ func TestSortProcsByUsername(t *testing.T) {
 	processes := []Process{proc1, proc2, proc3}
 
 	// Sort the processes by username
-	SortProcsByUsername(&processes)
+	SortProcsByUsername(&processes, false)
 
 	// Verify that the processes are sorted by username in ascending alphabetical order
 	assert.Equal(t, "alice", processes[0].Username)
@@ -126,7 +147,7 @@ func TestSortProcsByPid(t *testing.T) {
 	processes := []Process{proc1, proc2, proc3}
 
 	// Sort the processes by PID
-	SortProcsByPid(&processes)
+	SortProcsByPid(&processes, false)
 
 	// Verify that the processes are sorted by PID in ascending order
 	assert.Equal(t, int32(100), processes[0].PID)
@@ -144,7 +165,7 @@ func TestSortProcsByNumThreads(t *testing.T) {
 	processes := []Process{proc1, proc2, proc3}
 
 	// Sort the processes by thread count
-	SortProcsByNumThreads(&processes)
+	SortProcsByNumThreads(&processes, false)
 
 	// Verify that the processes are sorted by thread count in ascending order
 	assert.Equal(t, int32(2), processes[0].NumThreads)
@@ -152,6 +173,129 @@ func TestSortProcsByNumThreads(t *testing.T) {
 	assert.Equal(t, int32(10), processes[2].NumThreads)
 }
 
+func TestSortProcsDescending(t *testing.T) {
+	// Create test processes whose attributes are all in a different order than their PIDs
+	proc1 := Process{PID: 100, Age: 300, HasCPU: true, CPUPercent: 2.0, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1024}, NumThreads: 5, Username: "bob"}
+	proc2 := Process{PID: 200, Age: 100, HasCPU: true, CPUPercent: 9.0, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4096}, NumThreads: 2, Username: "alice"}
+	proc3 := Process{PID: 300, Age: 200, HasCPU: true, CPUPercent: 0.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 2048}, NumThreads: 10, Username: "carol"}
+
+	tests := []struct {
+		name     string
+		sortFunc func(processes *[]Process, desc bool)
+		asc      []int32
+	}{
+		{"age", SortProcsByAge, []int32{200, 300, 100}},
+		{"cpu", SortProcsByCpu, []int32{300, 100, 200}},
+		{"mem", SortProcsByMemory, []int32{100, 300, 200}},
+		{"pid", SortProcsByPid, []int32{100, 200, 300}},
+		{"threads", SortProcsByNumThreads, []int32{200, 100, 300}},
+		{"user", SortProcsByUsername, []int32{200, 100, 300}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc := []int32{tt.asc[2], tt.asc[1], tt.asc[0]}
+
+			processes := []Process{proc1, proc2, proc3}
+			tt.sortFunc(&processes, false)
+			assert.Equal(t, tt.asc, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+			processes = []Process{proc1, proc2, proc3}
+			tt.sortFunc(&processes, true)
+			assert.Equal(t, desc, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+		})
+	}
+}
+
+func TestSortProcsBy(t *testing.T) {
+	// Create test processes where PID 1 would not be first when sorted by CPU
+	proc1 := Process{PID: 1, HasCPU: true, CPUPercent: 1.0}
+	proc2 := Process{PID: 100, PPID: 1, HasCPU: true, CPUPercent: 0.5}
+	proc3 := Process{PID: 200, PPID: 1, HasCPU: true, CPUPercent: 3.0}
+
+	// PID 1 stays first in ascending order
+	processes := []Process{proc1, proc2, proc3}
+	assert.NoError(t, SortProcsBy(&processes, "cpu", false))
+	assert.Equal(t, []int32{1, 100, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+	// PID 1 stays first in descending order
+	processes = []Process{proc2, proc3, proc1}
+	assert.NoError(t, SortProcsBy(&processes, "cpu", true))
+	assert.Equal(t, []int32{1, 200, 100}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+	// Without PID 1 the processes whose parent is missing stay first
+	processes = []Process{
+		{PID: 4242, PPID: 4000, HasCPU: true, CPUPercent: 2.0},
+		{PID: 4300, PPID: 4242, HasCPU: true, CPUPercent: 0.5},
+		{PID: 4301, PPID: 4242, HasCPU: true, CPUPercent: 1.0},
+		{PID: 5000, PPID: 4999, HasCPU: true, CPUPercent: 5.0},
+	}
+	assert.NoError(t, SortProcsBy(&processes, "cpu", false))
+	assert.Equal(t, []int32{4242, 5000, 4300, 4301}, []int32{processes[0].PID, processes[1].PID, processes[2].PID, processes[3].PID})
+
+	// Unknown attributes are rejected
+	assert.Error(t, SortProcsBy(&processes, "unknown", false))
+}
+
+func TestSortProcsByNumFDs(t *testing.T) {
+	// Create test processes where the file descriptors of one could not be read
+	proc1 := Process{PID: 100, NumFDs: 30}
+	proc2 := Process{PID: 200, NumFDs: -1}
+	proc3 := Process{PID: 300, NumFDs: 10}
+
+	// Unknown counts sort last in ascending order
+	processes := []Process{proc1, proc2, proc3}
+	SortProcsByNumFDs(&processes, false)
+	assert.Equal(t, []int32{300, 100, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+	// And in descending order
+	processes = []Process{proc1, proc2, proc3}
+	SortProcsByNumFDs(&processes, true)
+	assert.Equal(t, []int32{100, 300, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+}
+
+func TestSortProcsByAgeUnknown(t *testing.T) {
+	// Create test processes where the create time of one could not be read
+	proc1 := Process{PID: 100, Age: 30}
+	proc2 := Process{PID: 200, Age: -1}
+	proc3 := Process{PID: 300, Age: 10}
+
+	// Unknown ages sort last in ascending order
+	processes := []Process{proc1, proc2, proc3}
+	SortProcsByAge(&processes, false)
+	assert.Equal(t, []int32{300, 100, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+	// And in descending order
+	processes = []Process{proc1, proc2, proc3}
+	SortProcsByAge(&processes, true)
+	assert.Equal(t, []int32{100, 300, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+}
+
+func TestSortProcsUncollected(t *testing.T) {
+	// Create test processes where the usage of one could not be collected
+	proc1 := Process{PID: 100, HasCPU: true, CPUPercent: 5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4096}}
+	proc2 := Process{PID: 200, MemoryInfo: &process.MemoryInfoStat{}}
+	proc3 := Process{PID: 300, HasCPU: true, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{}}
+
+	// Uncollected values sort last in ascending order, after the real zeros
+	processes := []Process{proc1, proc2, proc3}
+	SortProcsByCpu(&processes, false)
+	assert.Equal(t, []int32{300, 100, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+	processes = []Process{proc1, proc2, proc3}
+	SortProcsByMemory(&processes, false)
+	assert.Equal(t, []int32{300, 100, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+	// And in descending order
+	processes = []Process{proc1, proc2, proc3}
+	SortProcsByCpu(&processes, true)
+	assert.Equal(t, []int32{100, 300, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+
+	processes = []Process{proc1, proc2, proc3}
+	SortProcsByMemory(&processes, true)
+	assert.Equal(t, []int32{100, 300, 200}, []int32{processes[0].PID, processes[1].PID, processes[2].PID})
+}
+
 func TestGenerateProcess(t *testing.T) {
 	// This is a more complex test that requires mocking the process.Process type
 	// For simplicity, we'll just verify that the function doesn't panic
@@ -161,8 +305,87 @@ func TestGenerateProcess(t *testing.T) {
 	proc := &process.Process{Pid: 1}
 
 	// Call generateProcess and verify it doesn't panic
-	result := GenerateProcess(proc, DisplayOptions{})
+	result, ok := GenerateProcess(proc, DisplayOptions{})
 
 	// Basic verification that the result has the expected PID
+	assert.True(t, ok)
 	assert.Equal(t, int32(1), result.PID)
 }
+
+func TestGenerateProcessSkipArgs(t *testing.T) {
+	proc := &process.Process{Pid: int32(os.Getpid())}
+
+	// The command line is read by default, argv[0] split off the arguments
+	generated, ok := GenerateProcess(proc, DisplayOptions{})
+	require.True(t, ok)
+	assert.Equal(t, os.Args[0], generated.Argv0)
+	assert.Equal(t, os.Args[1:], generated.Args)
+
+	// Only argv[0] is read otherwise, it still names the process
+	generated, ok = GenerateProcess(proc, DisplayOptions{SkipArgs: true})
+	require.True(t, ok)
+	assert.Equal(t, os.Args[0], generated.Argv0)
+	assert.Empty(t, generated.Args)
+}
+
+func TestGenerateProcesses(t *testing.T) {
+	// Include a PID that doesn't exist to make sure it still produces a result with --keep-vanished
+	procs := []*process.Process{
+		{Pid: 1},
+		{Pid: int32(os.Getpid())},
+		{Pid: 999999999},
+	}
+
+	results, vanished := generateProcesses(procs, DisplayOptions{KeepVanished: true, Workers: 2}, nil)
+
+	// The results keep the order of the input
+	assert.Equal(t, 0, vanished)
+	assert.Equal(t, len(procs), len(results))
+	for i := range procs {
+		assert.Equal(t, procs[i].Pid, results[i].PID)
+	}
+
+	// Without it, the vanished process is left out
+	results, vanished = generateProcesses(procs, DisplayOptions{Workers: 2}, nil)
+	assert.Equal(t, 1, vanished)
+	assert.Equal(t, []int32{1, int32(os.Getpid())}, []int32{results[0].PID, results[1].PID})
+}
+
+func TestGetProcesses(t *testing.T) {
+	processes, err := GetProcesses(DisplayOptions{Workers: 2})
+	assert.NoError(t, err)
+
+	// The current process must be part of the snapshot
+	found := false
+	for _, proc := range processes {
+		if proc.PID == int32(os.Getpid()) {
+			found = true
+			break
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestAppendThreadNodes(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "/sbin/init", Username: "root"},
+		{PID: 100, PPID: 1, Command: "/usr/bin/worker", Username: "alice", Threads: map[int32]*cpu.TimesStat{
+			100: {}, // main thread
+			103: {},
+			101: {},
+		}},
+	}
+
+	results := appendThreadNodes(processes)
+
+	assert.Len(t, results, 4)
+	for i, tid := range []int32{101, 103} {
+		thread := results[2+i]
+		assert.Equal(t, tid, thread.PID)
+		assert.Equal(t, int32(100), thread.PPID)
+		assert.Equal(t, "{worker}", thread.Command)
+		assert.Equal(t, "alice", thread.Username)
+		assert.True(t, thread.IsThread)
+		assert.Equal(t, uint64(0), thread.MemoryInfo.RSS)
+	}
+}
@@ -0,0 +1,221 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the snapshot repair (--snapshot-repair). Processes exit and spawn between
+// listing the PIDs and inspecting each process, so a snapshot sometimes holds a process whose
+// parent is missing, and the tree shows it as a bogus root. With SnapshotRepairRefetch, each
+// missing parent is collected once more, since it may have been spawned after the PIDs were
+// listed. With SnapshotRepairReparent, the process is attached to its nearest ancestor in the
+// snapshot instead, found by reading the parent PIDs again from the running system; a process
+// whose parent exited has already been reparented by the kernel, so this also picks up its new
+// parent. A process that cannot be repaired is left as it is.
+package pstree
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/bananazon/pstree/pkg/globals"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	// SnapshotRepairRefetch collects the missing parents once more
+	SnapshotRepairRefetch = "refetch"
+	// SnapshotRepairReparent attaches the processes to their nearest ancestor in the snapshot
+	SnapshotRepairReparent = "reparent"
+)
+
+// maxRepairDepth bounds the walk up the parent PIDs, so a parent loop never hangs the repair.
+const maxRepairDepth = 64
+
+// snapshotSource looks up processes on the running system while a snapshot is repaired.
+type snapshotSource struct {
+	// Collects the process with the given PID
+	fetch func(pid int32) (Process, error)
+	// Reads the current parent PID of the process with the given PID
+	ppid func(pid int32) (int32, error)
+}
+
+// liveSnapshotSource returns the snapshotSource reading the running system.
+//
+// Parameters:
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//
+// Returns:
+//   - snapshotSource: The source collecting processes the same way GetProcesses does
+func liveSnapshotSource(miniOptions DisplayOptions) snapshotSource {
+	return snapshotSource{
+		fetch: func(pid int32) (Process, error) {
+			proc, err := process.NewProcess(pid)
+			if err != nil {
+				return Process{}, err
+			}
+			generated, ok := GenerateProcess(proc, miniOptions)
+			if !ok {
+				return Process{}, process.ErrorProcessNotRunning
+			}
+			return generated, nil
+		},
+		ppid: func(pid int32) (int32, error) {
+			proc, err := process.NewProcess(pid)
+			if err != nil {
+				return 0, err
+			}
+			return proc.Ppid()
+		},
+	}
+}
+
+// repairLogger returns the logger the repairs are logged to, which discards them when no
+// logger was set up, e.g., when the package is embedded in another program.
+func repairLogger() *slog.Logger {
+	if logger := globals.GetLogger(); logger != nil {
+		return logger
+	}
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// missingParent reports whether the parent of a process is missing from a snapshot. Processes
+// without a parent (PPID 0) and processes that are their own parent are never missing one.
+//
+// Parameters:
+//   - node: The process to check
+//   - pids: The PIDs in the snapshot
+//
+// Returns:
+//   - bool: true if the parent of the process is missing
+func missingParent(node *Process, pids map[int32]bool) bool {
+	return node.PPID != 0 && node.PPID != node.PID && !pids[node.PPID]
+}
+
+// repairSnapshot reconciles the processes whose parent is missing from a snapshot.
+//
+// Parameters:
+//   - processes: The collected processes, sorted by PID
+//   - strategy: SnapshotRepairRefetch or SnapshotRepairReparent; anything else leaves the processes as they are
+//   - source: The source looking up processes on the running system
+//   - logger: Logger the repairs are logged to at debug level
+//
+// Returns:
+//   - []Process: The repaired processes, sorted by PID
+func repairSnapshot(processes []Process, strategy string, source snapshotSource, logger *slog.Logger) []Process {
+	var pids map[int32]bool
+
+	if strategy != SnapshotRepairRefetch && strategy != SnapshotRepairReparent {
+		return processes
+	}
+
+	pids = make(map[int32]bool, len(processes))
+	for i := range processes {
+		pids[processes[i].PID] = true
+	}
+
+	switch strategy {
+	case SnapshotRepairRefetch:
+		processes = refetchParents(processes, pids, source, logger)
+	case SnapshotRepairReparent:
+		reparentProcesses(processes, pids, source, logger)
+	}
+
+	return processes
+}
+
+// refetchParents collects each missing parent once more and adds it to the processes. A parent
+// collected this way may be missing its own parent, which is collected in turn.
+//
+// Parameters:
+//   - processes: The collected processes, sorted by PID
+//   - pids: The PIDs in the snapshot, updated with the collected parents
+//   - source: The source looking up processes on the running system
+//   - logger: Logger the repairs are logged to at debug level
+//
+// Returns:
+//   - []Process: The processes with the collected parents, sorted by PID
+func refetchParents(processes []Process, pids map[int32]bool, source snapshotSource, logger *slog.Logger) []Process {
+	var (
+		added   bool
+		fetched map[int32]bool
+		parent  Process
+		err     error
+	)
+
+	fetched = make(map[int32]bool)
+	// The collected parents are appended, so the loop visits them as well
+	for i := 0; i < len(processes); i++ {
+		ppid := processes[i].PPID
+		if processes[i].IsThread || !missingParent(&processes[i], pids) || fetched[ppid] {
+			continue
+		}
+		fetched[ppid] = true
+
+		parent, err = source.fetch(ppid)
+		if err != nil {
+			logger.Debug(fmt.Sprintf("Snapshot repair: unable to re-fetch PID %d, the parent of PID %d: %v", ppid, processes[i].PID, err))
+			continue
+		}
+		logger.Debug(fmt.Sprintf("Snapshot repair: re-fetched PID %d, the parent of PID %d", ppid, processes[i].PID))
+		processes = append(processes, parent)
+		pids[parent.PID] = true
+		added = true
+	}
+
+	if added {
+		slices.SortFunc(processes, func(a, b Process) int {
+			return cmp.Compare(a.PID, b.PID)
+		})
+	}
+	return processes
+}
+
+// reparentProcesses attaches each process whose parent is missing to its nearest ancestor in
+// the snapshot.
+//
+// Parameters:
+//   - processes: The collected processes, modified in place
+//   - pids: The PIDs in the snapshot
+//   - source: The source looking up processes on the running system
+//   - logger: Logger the repairs are logged to at debug level
+func reparentProcesses(processes []Process, pids map[int32]bool, source snapshotSource, logger *slog.Logger) {
+	for i := range processes {
+		if processes[i].IsThread || !missingParent(&processes[i], pids) {
+			continue
+		}
+
+		ancestor, ok := nearestAncestor(processes[i].PID, pids, source)
+		if !ok {
+			logger.Debug(fmt.Sprintf("Snapshot repair: no ancestor of PID %d found for its missing parent %d", processes[i].PID, processes[i].PPID))
+			continue
+		}
+		logger.Debug(fmt.Sprintf("Snapshot repair: reparented PID %d from its missing parent %d to PID %d", processes[i].PID, processes[i].PPID, ancestor))
+		processes[i].PPID = ancestor
+	}
+}
+
+// nearestAncestor walks up the current parent PIDs of a process until it reaches a process in
+// the snapshot.
+//
+// Parameters:
+//   - pid: The PID of the process whose ancestor is looked up
+//   - pids: The PIDs in the snapshot
+//   - source: The source looking up processes on the running system
+//
+// Returns:
+//   - int32: The PID of the nearest ancestor in the snapshot
+//   - bool: true if an ancestor was found, false if a parent PID could not be read or the walk ended without one
+func nearestAncestor(pid int32, pids map[int32]bool, source snapshotSource) (int32, bool) {
+	current := pid
+	for range maxRepairDepth {
+		ppid, err := source.ppid(current)
+		if err != nil || ppid == 0 || ppid == current || ppid == pid {
+			return 0, false
+		}
+		if pids[ppid] {
+			return ppid, true
+		}
+		current = ppid
+	}
+	return 0, false
+}
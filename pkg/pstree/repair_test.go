@@ -0,0 +1,114 @@
+package pstree
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// repairTestSource returns a snapshotSource answering from a fabricated system, where a PID
+// without an entry no longer exists.
+func repairTestSource(live map[int32]Process) (snapshotSource, *[]int32) {
+	fetched := []int32{}
+	return snapshotSource{
+		fetch: func(pid int32) (Process, error) {
+			fetched = append(fetched, pid)
+			if proc, ok := live[pid]; ok {
+				return proc, nil
+			}
+			return Process{}, errors.New("process not found")
+		},
+		ppid: func(pid int32) (int32, error) {
+			if proc, ok := live[pid]; ok {
+				return proc.PPID, nil
+			}
+			return 0, errors.New("process not found")
+		},
+	}, &fetched
+}
+
+// inconsistentSnapshot returns a snapshot collected while processes spawned and exited:
+//
+//	init(1) --- sshd(100)
+//	worker(401), whose parent make(400) was spawned after the PIDs were listed
+//	job(501), whose parent shell(500) exited and left it to init
+func inconsistentSnapshot() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+		{PID: 401, PPID: 400, Command: "worker"},
+		{PID: 501, PPID: 500, Command: "job"},
+	}
+}
+
+func TestRepairSnapshotRefetch(t *testing.T) {
+	source, fetched := repairTestSource(map[int32]Process{
+		1:   {PID: 1, PPID: 0, Command: "init"},
+		100: {PID: 100, PPID: 1, Command: "sshd"},
+		300: {PID: 300, PPID: 100, Command: "bash"},
+		400: {PID: 400, PPID: 300, Command: "make"},
+		401: {PID: 401, PPID: 400, Command: "worker"},
+		501: {PID: 501, PPID: 1, Command: "job"},
+	})
+	processes := repairSnapshot(inconsistentSnapshot(), SnapshotRepairRefetch, source, setupTestLogger())
+
+	// make and the bash it was started from are collected, the exited shell is not
+	pids := []int32{}
+	for _, proc := range processes {
+		pids = append(pids, proc.PID)
+	}
+	assert.Equal(t, []int32{1, 100, 300, 400, 401, 501}, pids)
+	assert.Equal(t, []int32{400, 500, 300}, *fetched, "each missing parent is fetched once")
+	assert.Equal(t, int32(500), processes[5].PPID, "a process whose parent is gone is left as it is")
+}
+
+func TestRepairSnapshotReparent(t *testing.T) {
+	source, fetched := repairTestSource(map[int32]Process{
+		1:   {PID: 1, PPID: 0, Command: "init"},
+		100: {PID: 100, PPID: 1, Command: "sshd"},
+		300: {PID: 300, PPID: 100, Command: "bash"},
+		400: {PID: 400, PPID: 300, Command: "make"},
+		401: {PID: 401, PPID: 400, Command: "worker"},
+		501: {PID: 501, PPID: 1, Command: "job"},
+	})
+	processes := repairSnapshot(inconsistentSnapshot(), SnapshotRepairReparent, source, setupTestLogger())
+
+	assert.Len(t, processes, 4, "no process is added")
+	assert.Equal(t, int32(100), processes[2].PPID, "worker is attached to its nearest collected ancestor")
+	assert.Equal(t, int32(1), processes[3].PPID, "job is attached to the parent the kernel gave it")
+	assert.Empty(t, *fetched)
+}
+
+func TestRepairSnapshotUnrepairable(t *testing.T) {
+	// Nothing can be read from the system, e.g., because the processes exited meanwhile
+	source, _ := repairTestSource(map[int32]Process{})
+	for _, strategy := range []string{SnapshotRepairRefetch, SnapshotRepairReparent} {
+		processes := repairSnapshot(inconsistentSnapshot(), strategy, source, setupTestLogger())
+		assert.Equal(t, inconsistentSnapshot(), processes, strategy)
+	}
+
+	// A parent loop ends the walk without an ancestor
+	source, _ = repairTestSource(map[int32]Process{
+		401: {PID: 401, PPID: 400},
+		400: {PID: 400, PPID: 402},
+		402: {PID: 402, PPID: 400},
+	})
+	processes := repairSnapshot(inconsistentSnapshot(), SnapshotRepairReparent, source, setupTestLogger())
+	assert.Equal(t, int32(400), processes[2].PPID)
+}
+
+func TestRepairSnapshotOff(t *testing.T) {
+	source, fetched := repairTestSource(map[int32]Process{})
+	processes := repairSnapshot(inconsistentSnapshot(), "", source, setupTestLogger())
+	assert.Equal(t, inconsistentSnapshot(), processes)
+	assert.Empty(t, *fetched)
+}
+
+func TestMissingParent(t *testing.T) {
+	pids := map[int32]bool{1: true, 100: true}
+	assert.False(t, missingParent(&Process{PID: 1, PPID: 0}, pids))
+	assert.False(t, missingParent(&Process{PID: 0, PPID: 0}, pids), "a process that is its own parent")
+	assert.False(t, missingParent(&Process{PID: 100, PPID: 1}, pids))
+	assert.True(t, missingParent(&Process{PID: 401, PPID: 400}, pids))
+}
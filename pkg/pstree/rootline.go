@@ -0,0 +1,65 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the hiding of the root line (--no-root-line), which prints the children of
+// each --pid process as trees of their own, without the line of the process itself. This saves a
+// level of indentation when the root is always the same, e.g., the shim of a container runtime.
+// The root is never marked for display, and the trees start at its displayed children, which are
+// at depth 0; when none of them is displayed, nothing is printed.
+package pstree
+
+import (
+	"slices"
+)
+
+// isHiddenRoot reports whether a process is a --pid root hidden by DisplayOptions.NoRootLine.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the line of the process is not printed
+func (processTree *ProcessTree) isHiddenRoot(node *Process) bool {
+	return processTree.DisplayOptions.NoRootLine && slices.Contains(processTree.DisplayOptions.RootPIDs, node.PID)
+}
+
+// unmarkHiddenRoots unmarks the --pid roots whose line is hidden, keeping their descendants
+// marked.
+func (processTree *ProcessTree) unmarkHiddenRoots() {
+	processTree.Logger.Debug("Entering processTree.unmarkHiddenRoots()")
+	for _, node := range processTree.Nodes {
+		if processTree.isHiddenRoot(node) {
+			node.Print = false
+			node.PrintReason = ReasonNone
+		}
+	}
+}
+
+// rootChildren replaces each hidden root by its displayed children, in the order they are printed.
+//
+// Parameters:
+//   - rootIndices: Indices of the root processes in the Nodes array
+//
+// Returns:
+//   - []int: Indices of the processes to print as roots
+func (processTree *ProcessTree) rootChildren(rootIndices []int) []int {
+	var (
+		childPidIndex int
+		indices       []int
+	)
+
+	indices = make([]int, 0, len(rootIndices))
+	for _, rootIndex := range rootIndices {
+		if !processTree.isHiddenRoot(processTree.Nodes[rootIndex]) {
+			indices = append(indices, rootIndex)
+			continue
+		}
+		childPidIndex = processTree.Nodes[rootIndex].Child
+		for childPidIndex != -1 {
+			if processTree.Nodes[childPidIndex].Print {
+				indices = append(indices, childPidIndex)
+			}
+			childPidIndex = processTree.Nodes[childPidIndex].Sister
+		}
+	}
+	return indices
+}
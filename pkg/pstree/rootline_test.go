@@ -0,0 +1,85 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rootLineTestProcesses returns the processes of a container below its runtime shim:
+//
+//	init(1) --- shim(100) -+- app(101) --- worker(102)
+//	                       |- sidecar(103)
+//	                       \- sidecar(104)
+func rootLineTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "shim"},
+		{PID: 101, PPID: 100, Command: "app"},
+		{PID: 102, PPID: 101, Command: "worker"},
+		{PID: 103, PPID: 100, Command: "sidecar"},
+		{PID: 104, PPID: 100, Command: "sidecar", HasCPU: true, CPUPercent: 8},
+	}
+}
+
+func TestNoRootLine(t *testing.T) {
+	render := func(displayOptions DisplayOptions) []string {
+		displayOptions.NoRootLine = true
+		displayOptions.RootPIDs = []int32{100}
+		displayOptions.ScreenWidth = 80
+		processTree := NewProcessTree(0, setupTestLogger(), rootLineTestProcesses(), displayOptions)
+		output := renderProcessTree(t, processTree)
+		lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+		return lines
+	}
+
+	// The children of the root are printed as trees of their own, at depth 0
+	assert.Equal(t, []string{" 0 -+- app", " 1  \\--- worker", " 0 -+- sidecar", " 0 -+- sidecar"}, render(DisplayOptions{MaxDepth: 10, ShowDepth: true}))
+
+	// Identical children are still compacted
+	lines := render(DisplayOptions{CompactMode: true, MaxDepth: 10})
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[2], "2*[sidecar]")
+
+	// The root stays hidden when it is the ancestor of a match
+	assert.Equal(t, []string{"-+- sidecar"}, render(DisplayOptions{MaxDepth: 10, MinCPU: 5}))
+
+	// The depth limit counts from the children of the root
+	assert.Equal(t, []string{"-+- app", "-+- sidecar", "-+- sidecar"}, render(DisplayOptions{MaxDepth: 0}))
+}
+
+func TestNoRootLineWithoutChildren(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), rootLineTestProcesses(), DisplayOptions{NoRootLine: true, RootPIDs: []int32{103}})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	rootIndices, err := processTree.RootIndices()
+	require.NoError(t, err)
+	assert.Empty(t, rootIndices)
+	assert.False(t, processTree.HasPrintable())
+	assert.Equal(t, []int32{}, markedPIDs(processTree))
+}
+
+func TestNoRootLineTop(t *testing.T) {
+	processes := rootLineTestProcesses()
+	processes[1].CPUPercent = 90
+	processes[3].CPUPercent = 10
+	processes[4].CPUPercent = 5
+	for i := range processes {
+		processes[i].HasCPU = true
+	}
+
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{NoRootLine: true, OrderBy: "cpu", OrderDir: "desc", RootPIDs: []int32{100}, Top: 1})
+	processTree.MarkProcesses()
+
+	// The hidden root is not ranked, so it doesn't take the place of a displayed process
+	marked := markedPIDs(processTree)
+	assert.NotContains(t, marked, int32(100))
+	assert.Contains(t, marked, int32(102))
+	assert.NotContains(t, marked, int32(103))
+}
@@ -0,0 +1,170 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the handling of the roots requested with --pid. Each requested root is
+// printed first, as the top of its own tree, with the children below it sorted by --order-by
+// like everywhere else. The roots are sticky: the filters that narrow the marked processes down,
+// e.g., --min-cpu or --top, only decide which descendants are shown, so the tree of a requested
+// root is never left empty. Only the exclusions, i.e., --exclude, --exclude-root, and
+// --no-kernel-threads, can hide a requested root. A requested PID that was not collected is
+// reported as not found.
+//
+// The roots can also be requested by command with --root-cmd, matching the basename of the
+// command exactly, or with --root-cmd-regex. The processes found are added to the requested PIDs
+// when the tree is built, so they are handled like the roots given with --pid. A match below
+// another one, e.g., a worker of an nginx master, is printed in the tree of the outer one rather
+// than as a tree of its own.
+package pstree
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// markedRoots returns the processes requested with --pid that are marked, i.e., the roots
+// kept by the initial marking of MarkProcesses.
+//
+// Returns:
+//   - []int: Indices of the marked roots in the Nodes array
+func (processTree *ProcessTree) markedRoots() []int {
+	var (
+		roots []int
+	)
+
+	for _, pid := range processTree.DisplayOptions.RootPIDs {
+		if pidIndex, ok := processTree.PidToIndexMap[pid]; ok && processTree.Nodes[pidIndex].Print {
+			roots = append(roots, pidIndex)
+		}
+	}
+	return roots
+}
+
+// keepRoots marks the requested roots again after the filters narrowed the marked processes
+// down, unless they are hidden by --exclude or --no-kernel-threads.
+//
+// Parameters:
+//   - roots: Indices of the roots marked before the filters, see markedRoots
+func (processTree *ProcessTree) keepRoots(roots []int) {
+	for _, pidIndex := range roots {
+		node := processTree.Nodes[pidIndex]
+		if node.Print || processTree.isExcluded(pidIndex) || (processTree.DisplayOptions.HideKernelThreads && IsKernelThread(*node)) {
+			continue
+		}
+		processTree.Logger.Debug(fmt.Sprintf("PID %d is a requested root", node.PID))
+		node.Print = true
+	}
+}
+
+// hasRootCommand reports whether the roots are requested by command, see matchesRootCommand.
+//
+// Returns:
+//   - bool: true with --root-cmd or --root-cmd-regex
+func (processTree *ProcessTree) hasRootCommand() bool {
+	return processTree.DisplayOptions.RootCommand != "" || processTree.DisplayOptions.RootCommandPattern != nil
+}
+
+// matchesRootCommand reports whether a process is requested as a root by its command, i.e.,
+// the basename of its command is the one given with --root-cmd, or matches --root-cmd-regex.
+// The threads and the orphans and container nodes are never requested.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the process is requested as a root
+func (processTree *ProcessTree) matchesRootCommand(node *Process) bool {
+	if node.IsThread || isSyntheticNode(node) {
+		return false
+	}
+
+	name := filepath.Base(node.Command)
+	if processTree.DisplayOptions.RootCommandPattern != nil {
+		return processTree.DisplayOptions.RootCommandPattern.MatchString(name)
+	}
+	return name == processTree.DisplayOptions.RootCommand
+}
+
+// hasMatchingAncestor reports whether an ancestor of a process is also requested as a root by
+// its command, following the PPIDs since the tree is not built yet.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the process is part of the tree of another match
+func (processTree *ProcessTree) hasMatchingAncestor(node *Process) bool {
+	current := node
+	// A loop in the PPIDs, e.g., in a broken snapshot, is never followed further than the number of processes
+	for range processTree.Nodes {
+		parentIndex, ok := processTree.PidToIndexMap[current.PPID]
+		if !ok || current.PPID == current.PID {
+			return false
+		}
+		current = processTree.Nodes[parentIndex]
+		if current == node {
+			return false
+		}
+		if processTree.matchesRootCommand(current) {
+			return true
+		}
+	}
+	return false
+}
+
+// addRootCommands adds the processes requested as roots by their command to the requested PIDs,
+// leaving out the matches printed in the tree of another one. The processes are not modified, so
+// the roots are found again each time a tree is built, e.g., on each refresh of --watch.
+func (processTree *ProcessTree) addRootCommands() {
+	var (
+		pids []int32
+	)
+
+	if !processTree.hasRootCommand() {
+		return
+	}
+
+	for _, node := range processTree.Nodes {
+		if processTree.matchesRootCommand(node) && !processTree.hasMatchingAncestor(node) {
+			processTree.Logger.Debug(fmt.Sprintf("PID %d is requested by its command %s", node.PID, node.Command))
+			pids = append(pids, node.PID)
+		}
+	}
+	slices.Sort(pids)
+
+	// The requested PIDs of the caller are left as they are
+	processTree.RootPIDs = append(slices.Clone(processTree.RootPIDs), pids...)
+	processTree.DisplayOptions.RootPIDs = processTree.RootPIDs
+}
+
+// rootCommandError returns the error reported when no process was requested by its command.
+//
+// Returns:
+//   - error: An error naming the command or the regular expression
+func (processTree *ProcessTree) rootCommandError() error {
+	if processTree.DisplayOptions.RootCommandPattern != nil {
+		return fmt.Errorf("no process matching %q found", processTree.DisplayOptions.RootCommandPattern.String())
+	}
+	return fmt.Errorf("no process named %q found", processTree.DisplayOptions.RootCommand)
+}
+
+// notFoundError returns the error reported when none of the requested PIDs were collected.
+//
+// Parameters:
+//   - pids: The requested PIDs
+//
+// Returns:
+//   - error: An error naming the PIDs, e.g., PID 500 not found
+func notFoundError(pids []int32) error {
+	if len(pids) == 1 {
+		return fmt.Errorf("PID %d not found", pids[0])
+	}
+
+	names := make([]string, 0, len(pids))
+	for _, pid := range slices.Sorted(slices.Values(pids)) {
+		names = append(names, util.Int32toStr(pid))
+	}
+	return fmt.Errorf("PIDs %s not found", strings.Join(names, ", "))
+}
@@ -0,0 +1,120 @@
+package pstree
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRootsTree builds the wide fan-out with three sessions, the later ones using more CPU
+func newRootsTree(t *testing.T, displayOptions DisplayOptions) *ProcessTree {
+	source := fixtureSource(fixtures.WideFanOut(3))
+	for i := range source.Procs {
+		source.Procs[i].HasCPU = true
+		if source.Procs[i].PID >= 1000 {
+			source.Procs[i].CPUPercent = float64(source.Procs[i].PID-1000) / 2
+		}
+	}
+	processes, err := source.Processes(displayOptions, nil)
+	require.NoError(t, err)
+	return NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+}
+
+// renderRoots prints the trees of the requested roots
+func renderRoots(t *testing.T, processTree *ProcessTree) []string {
+	output := renderProcessTree(t, processTree)
+	return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+}
+
+func TestStickyRootsOrderBy(t *testing.T) {
+	processTree := newRootsTree(t, DisplayOptions{MaxDepth: 10, OrderBy: "cpu", OrderDir: "desc", RootPIDs: []int32{100}, ScreenWidth: 80, ShowPIDs: true})
+	lines := renderRoots(t, processTree)
+
+	// The requested root comes first, with its sessions sorted below it
+	require.Len(t, lines, 7)
+	assert.Contains(t, lines[0], "(100) sshd")
+	assert.Contains(t, lines[1], "(1004) sshd")
+	assert.Contains(t, lines[3], "(1002) sshd")
+	assert.Contains(t, lines[5], "(1000) sshd")
+}
+
+func TestStickyRootsFilters(t *testing.T) {
+	// Only the busiest shell makes the cut, the other root is still printed
+	processTree := newRootsTree(t, DisplayOptions{MaxDepth: 10, OrderBy: "cpu", OrderDir: "desc", RootPIDs: []int32{1000, 1002}, ScreenWidth: 80, ShowPIDs: true, Top: 1})
+	lines := renderRoots(t, processTree)
+	assert.Equal(t, []string{"-+- (1000) sshd ", "-+- (1002) sshd ", " \\--- (1003) bash "}, lines)
+
+	// Nothing below the root meets the threshold, the root is still printed
+	processTree = newRootsTree(t, DisplayOptions{MaxDepth: 10, MinCPU: 50, RootPIDs: []int32{100}, ScreenWidth: 80, ShowPIDs: true})
+	lines = renderRoots(t, processTree)
+	assert.Equal(t, []string{"-+- (100) sshd "}, lines)
+
+	// An excluded root stays hidden
+	processTree = newRootsTree(t, DisplayOptions{ExcludePatterns: []string{"sshd"}, MaxDepth: 10, MinCPU: 50, RootPIDs: []int32{100}, ScreenWidth: 80})
+	processTree.MarkProcesses()
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[100]].Print)
+}
+
+func TestRootIndicesNotFound(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.OrphanedChild(), DisplayOptions{RootPIDs: []int32{500}})
+	_, err := processTree.RootIndices()
+	assert.EqualError(t, err, "PID 500 not found")
+
+	processTree = newFixtureTree(t, fixtures.OrphanedChild(), DisplayOptions{RootPIDs: []int32{501, 500}})
+	_, err = processTree.RootIndices()
+	assert.EqualError(t, err, "PIDs 500, 501 not found")
+
+	// The PIDs that exist are still printed
+	processTree = newFixtureTree(t, fixtures.OrphanedChild(), DisplayOptions{RootPIDs: []int32{500, 300}})
+	rootIndices, err := processTree.RootIndices()
+	require.NoError(t, err)
+	assert.Equal(t, []int{processTree.PidToIndexMap[300]}, rootIndices)
+}
+
+func TestRootCommand(t *testing.T) {
+	render := func(displayOptions DisplayOptions) []string {
+		displayOptions.MaxDepth = 10
+		displayOptions.ScreenWidth = 80
+		displayOptions.ShowPIDs = true
+		return renderRoots(t, newFixtureTree(t, fixtures.TwoMasters(), displayOptions))
+	}
+
+	// Each master is a tree of its own, with its workers below it
+	lines := render(DisplayOptions{RootCommand: "nginx"})
+	assert.Equal(t, []string{
+		"-+- (100) nginx ",
+		" |--- (101) nginx ",
+		" \\--- (102) nginx ",
+		"-+- (300) nginx ",
+		" |--- (301) nginx ",
+		" \\--- (302) nginx ",
+	}, lines)
+
+	// The name must be the whole basename, unlike the regular expression
+	processTree := newFixtureTree(t, fixtures.TwoMasters(), DisplayOptions{RootCommand: "ngin"})
+	processTree.MarkProcesses()
+	_, err := processTree.RootIndices()
+	assert.EqualError(t, err, `no process named "ngin" found`)
+
+	lines = render(DisplayOptions{RootCommandPattern: regexp.MustCompile("^(cron|ngin)")})
+	assert.Equal(t, "-+- (100) nginx ", lines[0])
+	assert.Equal(t, "-+- (200) cron ", lines[3])
+	assert.Equal(t, "-+- (300) nginx ", lines[4])
+
+	processTree = newFixtureTree(t, fixtures.TwoMasters(), DisplayOptions{RootCommandPattern: regexp.MustCompile("^apache")})
+	_, err = processTree.RootIndices()
+	assert.EqualError(t, err, `no process matching "^apache" found`)
+
+	// The roots are found again on each tree built on the same processes, without changing the options of the caller
+	displayOptions := DisplayOptions{RootCommand: "nginx"}
+	processes, err := fixtureSource(fixtures.TwoMasters()).Processes(displayOptions, nil)
+	require.NoError(t, err)
+	NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	processTree = NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	assert.Equal(t, []int32{100, 300}, processTree.RootPIDs)
+	assert.Empty(t, displayOptions.RootPIDs)
+}
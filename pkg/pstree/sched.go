@@ -0,0 +1,134 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the scheduling policy shown with --sched, e.g., to spot the SCHED_FIFO and
+// SCHED_RR processes while debugging latency: a realtime process runs before every process of the
+// normal policies and can delay all of them. On Linux, the policy is read from the 41st field of
+// /proc/<pid>/stat. --only-realtime narrows the tree down to the processes with a realtime policy
+// and their ancestors. Other platforms don't report the policy, so no process has one there, see
+// SchedSupported. The policies are only collected when they are shown or filtered by.
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Scheduling policies, named like the SCHED_* constants of sched(7) without the prefix.
+const (
+	SchedBatch    = "BATCH"
+	SchedDeadline = "DEADLINE"
+	SchedFIFO     = "FIFO"
+	SchedIdle     = "IDLE"
+	SchedOther    = "OTHER"
+	SchedRR       = "RR"
+)
+
+// ErrSchedPolicyUnavailable is returned when the scheduling policy of a process can't be read
+// on this platform.
+var ErrSchedPolicyUnavailable = errors.New("the scheduling policy is not available on this platform")
+
+// schedPolicies maps the policy numbers of the Linux kernel to their names.
+var schedPolicies = map[int]string{
+	0: SchedOther,
+	1: SchedFIFO,
+	2: SchedRR,
+	3: SchedBatch,
+	5: SchedIdle,
+	6: SchedDeadline,
+}
+
+// schedPolicyField is the index of the policy among the fields of /proc/<pid>/stat that follow
+// the command, the first of which is the state (field 3), see proc(5).
+const schedPolicyField = 41 - 3
+
+// parseSchedPolicy parses the scheduling policy from the contents of a Linux /proc/<pid>/stat file.
+//
+// The command in the second field is enclosed in parentheses and may contain spaces and
+// parentheses itself, so the fields are counted from the last closing parenthesis.
+//
+// Parameters:
+//   - stat: The contents of the file
+//
+// Returns:
+//   - string: The name of the policy, e.g., FIFO
+//   - error: An error if the file is truncated or the policy is not a number
+func parseSchedPolicy(stat string) (string, error) {
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return "", errors.New("no command in the stat file")
+	}
+
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) <= schedPolicyField {
+		return "", fmt.Errorf("the stat file has %d fields after the command, the policy is field %d", len(fields), schedPolicyField+1)
+	}
+
+	policy, err := strconv.Atoi(fields[schedPolicyField])
+	if err != nil {
+		return "", fmt.Errorf("invalid scheduling policy %q: %w", fields[schedPolicyField], err)
+	}
+	if name, ok := schedPolicies[policy]; ok {
+		return name, nil
+	}
+	return strconv.Itoa(policy), nil
+}
+
+// readSchedPolicy reads the scheduling policy of a process from a proc filesystem.
+//
+// Parameters:
+//   - procPath: Mount point of the proc filesystem
+//   - pid: The process ID
+//
+// Returns:
+//   - string: The name of the policy, e.g., FIFO
+//   - error: Any error encountered while reading or parsing the file
+func readSchedPolicy(procPath string, pid int32) (string, error) {
+	stat, err := os.ReadFile(filepath.Join(procPath, strconv.Itoa(int(pid)), "stat"))
+	if err != nil {
+		return "", err
+	}
+	return parseSchedPolicy(string(stat))
+}
+
+// IsRealtime reports whether a process runs with a realtime scheduling policy, see sched(7).
+//
+// Parameters:
+//   - process: The process to check
+//
+// Returns:
+//   - bool: true for the FIFO, RR, and DEADLINE policies, false otherwise or if the policy is not known
+func IsRealtime(process *Process) bool {
+	switch process.SchedPolicy {
+	case SchedFIFO, SchedRR, SchedDeadline:
+		return true
+	}
+	return false
+}
+
+// markRealtime unmarks the processes that don't run with a realtime scheduling policy, keeping
+// the ancestors of those that do.
+func (processTree *ProcessTree) markRealtime() {
+	processTree.Logger.Debug("Entering processTree.markRealtime()")
+	processTree.narrowMarked(func(node *Process) bool {
+		return !node.IsThread && IsRealtime(node)
+	}, "runs with a realtime scheduling policy")
+}
+
+// formatSchedField formats the scheduling policy field of a line, e.g., (sched: FIFO), or the
+// policies of a group, e.g., (sched: FIFO,OTHER).
+//
+// Parameters:
+//   - policies: The policies to show, empty if they could not be read
+//
+// Returns:
+//   - string: The formatted field, (sched: ?) if the policies are not known
+func formatSchedField(policies []string) string {
+	if len(policies) == 0 {
+		return "(sched: ?)"
+	}
+	return fmt.Sprintf("(sched: %s)", strings.Join(policies, ","))
+}
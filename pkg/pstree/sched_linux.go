@@ -0,0 +1,18 @@
+//go:build linux
+
+package pstree
+
+// SchedSupported reports whether the scheduling policies can be read on this platform.
+const SchedSupported = true
+
+// ProcessSchedPolicy retrieves the scheduling policy of a process from /proc/<pid>/stat.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - string: The name of the policy, e.g., FIFO
+//   - error: Any error encountered while reading or parsing the file
+func ProcessSchedPolicy(pid int32) (string, error) {
+	return readSchedPolicy("/proc", pid)
+}
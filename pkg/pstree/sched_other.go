@@ -0,0 +1,19 @@
+//go:build !linux
+
+package pstree
+
+// SchedSupported reports whether the scheduling policies can be read on this platform.
+// Only Linux reports them, so --sched shows (sched: ?) and --only-realtime matches nothing.
+const SchedSupported = false
+
+// ProcessSchedPolicy retrieves the scheduling policy of a process.
+//
+// Parameters:
+//   - pid: The process ID
+//
+// Returns:
+//   - string: Always an empty string
+//   - error: Always ErrSchedPolicyUnavailable
+func ProcessSchedPolicy(pid int32) (string, error) {
+	return "", ErrSchedPolicyUnavailable
+}
@@ -0,0 +1,140 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schedStatFixture returns the contents of a /proc/<pid>/stat file, taken from a real process,
+// with the given command, realtime priority, and scheduling policy.
+func schedStatFixture(command string, rtPriority int, policy int) string {
+	return fmt.Sprintf("812 (%s) S 1 812 812 0 -1 4194560 1532 0 0 0 12 30 0 0 -51 0 1 0 1204 12288000 512 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 %d %d 0 0 0 0 0 0 0 0 0 0 0\n", command, rtPriority, policy)
+}
+
+func TestParseSchedPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		stat     string
+		expected string
+	}{
+		{"Other", schedStatFixture("sshd", 0, 0), SchedOther},
+		{"FIFO", schedStatFixture("irq/35-nvme0q0", 50, 1), SchedFIFO},
+		{"RR", schedStatFixture("pipewire", 20, 2), SchedRR},
+		{"Batch", schedStatFixture("make", 0, 3), SchedBatch},
+		{"Idle", schedStatFixture("tracker-miner", 0, 5), SchedIdle},
+		{"Deadline", schedStatFixture("rt-app", 0, 6), SchedDeadline},
+		// The command may contain spaces and parentheses
+		{"CommandWithParentheses", schedStatFixture("tmux: server (1)", 1, 1), SchedFIFO},
+		// A policy unknown to this version is shown as its number
+		{"Unknown", schedStatFixture("future", 0, 7), "7"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := parseSchedPolicy(test.stat)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, policy)
+		})
+	}
+
+	_, err := parseSchedPolicy("812 (sshd) S 1 812")
+	assert.Error(t, err)
+	_, err = parseSchedPolicy("812 sshd S 1 812")
+	assert.Error(t, err)
+	_, err = parseSchedPolicy(strings.Replace(schedStatFixture("sshd", 0, 0), " 3 0 0 0", " 3 0 x 0", 1))
+	assert.Error(t, err)
+}
+
+func TestReadSchedPolicy(t *testing.T) {
+	procPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procPath, "812"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(procPath, "812", "stat"), []byte(schedStatFixture("pipewire", 20, 2)), 0o644))
+
+	policy, err := readSchedPolicy(procPath, 812)
+	require.NoError(t, err)
+	assert.Equal(t, SchedRR, policy)
+
+	_, err = readSchedPolicy(procPath, 813)
+	assert.Error(t, err)
+
+	// The policy of the test binary itself is read on Linux, nothing is reported elsewhere
+	policy, err = ProcessSchedPolicy(int32(os.Getpid()))
+	if runtime.GOOS == "linux" {
+		require.NoError(t, err)
+		assert.NotEmpty(t, policy)
+	} else {
+		assert.ErrorIs(t, err, ErrSchedPolicyUnavailable)
+	}
+}
+
+func TestOnlyRealtime(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", SchedPolicy: SchedOther},
+		{PID: 100, PPID: 1, Command: "pipewire", SchedPolicy: SchedOther},
+		{PID: 101, PPID: 100, Command: "pipewire-rt", SchedPolicy: SchedRR},
+		{PID: 102, PPID: 100, Command: "pipewire-ui", SchedPolicy: SchedOther},
+		{PID: 200, PPID: 1, Command: "sshd"},
+		{PID: 300, PPID: 1, Command: "irq", SchedPolicy: SchedFIFO},
+	}
+
+	render := func(displayOptions DisplayOptions) []string {
+		displayOptions.ScreenWidth = 200
+		output := renderTree(t, processes, displayOptions)
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+		return lines
+	}
+
+	// The realtime processes are kept along with their ancestors
+	lines := render(DisplayOptions{OnlyRealtime: true, ShowSched: true})
+	require.Len(t, lines, 4)
+	assert.Equal(t, "-+- (sched: OTHER) init", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], "(sched: OTHER) pipewire"))
+	assert.True(t, strings.HasSuffix(lines[2], "(sched: RR) pipewire-rt"))
+	assert.True(t, strings.HasSuffix(lines[3], "(sched: FIFO) irq"))
+
+	// Without the filter, a policy that could not be read is shown as unknown
+	lines = render(DisplayOptions{ShowSched: true})
+	require.Len(t, lines, 6)
+	assert.True(t, strings.HasSuffix(lines[4], "(sched: ?) sshd"))
+}
+
+func TestSchedCompactMode(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", SchedPolicy: SchedOther},
+		{PID: 100, PPID: 1, Command: "worker", SchedPolicy: SchedOther},
+		{PID: 200, PPID: 1, Command: "worker", SchedPolicy: SchedFIFO},
+		{PID: 300, PPID: 1, Command: "worker"},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{CompactMode: true, MaxDepth: 10, ScreenWidth: 200, ShowSched: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	// The group lists each policy that could be read once
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	assert.Contains(t, output, "(sched: FIFO,OTHER) worker───3*[worker]")
+}
+
+func TestWriteFlatSched(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", SchedPolicy: SchedOther},
+		{PID: 100, PPID: 1, Command: "irq", SchedPolicy: SchedFIFO},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 10, ShowSched: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	var builder strings.Builder
+	require.NoError(t, processTree.WriteFlat(&builder, ',', []int{0}))
+	assert.Equal(t, "depth,command,sched\n0,init,OTHER\n1,irq,FIFO\n", builder.String())
+}
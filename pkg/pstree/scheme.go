@@ -0,0 +1,213 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the loader for custom color scheme files (--color-scheme=path/to/scheme.yaml).
+// A scheme file is a flat YAML mapping of display elements to colors, e.g.:
+//
+//	# my pstree colors
+//	command: "#5fafff"
+//	args: 245
+//	branches: 34
+//	cpu-high: "#ff0000"
+//
+// Colors are either an ANSI 256-color index (0-255) or a hex RGB value (#rrggbb). Elements that
+// are not listed keep the colors of the default scheme.
+package pstree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// colorSchemeElements maps the element names used in color scheme files to the Colorizer
+// field that colors the element.
+var colorSchemeElements = map[string]func(colorizer *Colorizer) *ColorFunc{
+	"age":              func(colorizer *Colorizer) *ColorFunc { return &colorizer.Age },
+	"age-high":         func(colorizer *Colorizer) *ColorFunc { return &colorizer.ProcessAgeHigh },
+	"age-low":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.ProcessAgeLow },
+	"age-medium":       func(colorizer *Colorizer) *ColorFunc { return &colorizer.ProcessAgeMedium },
+	"age-very-high":    func(colorizer *Colorizer) *ColorFunc { return &colorizer.ProcessAgeVeryHigh },
+	"args":             func(colorizer *Colorizer) *ColorFunc { return &colorizer.Args },
+	"branches":         func(colorizer *Colorizer) *ColorFunc { return &colorizer.Prefix },
+	"command":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.Command },
+	"compact":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.CompactStr },
+	"connector":        func(colorizer *Colorizer) *ColorFunc { return &colorizer.Connector },
+	"cpu":              func(colorizer *Colorizer) *ColorFunc { return &colorizer.CPU },
+	"cpu-high":         func(colorizer *Colorizer) *ColorFunc { return &colorizer.CPUHigh },
+	"cpu-low":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.CPULow },
+	"cpu-medium":       func(colorizer *Colorizer) *ColorFunc { return &colorizer.CPUMedium },
+	"default":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.Default },
+	"fds":              func(colorizer *Colorizer) *ColorFunc { return &colorizer.FDs },
+	"fds-high":         func(colorizer *Colorizer) *ColorFunc { return &colorizer.FDsHigh },
+	"fds-low":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.FDsLow },
+	"fds-medium":       func(colorizer *Colorizer) *ColorFunc { return &colorizer.FDsMedium },
+	"mem":              func(colorizer *Colorizer) *ColorFunc { return &colorizer.Memory },
+	"mem-high":         func(colorizer *Colorizer) *ColorFunc { return &colorizer.MemoryHigh },
+	"mem-low":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.MemoryLow },
+	"mem-medium":       func(colorizer *Colorizer) *ColorFunc { return &colorizer.MemoryMedium },
+	"owner-transition": func(colorizer *Colorizer) *ColorFunc { return &colorizer.OwnerTransition },
+	"pid":              func(colorizer *Colorizer) *ColorFunc { return &colorizer.PIDPGID },
+	"status":           func(colorizer *Colorizer) *ColorFunc { return &colorizer.Status },
+	"threads":          func(colorizer *Colorizer) *ColorFunc { return &colorizer.NumThreads },
+	"user":             func(colorizer *Colorizer) *ColorFunc { return &colorizer.Owner },
+	"zombie":           func(colorizer *Colorizer) *ColorFunc { return &colorizer.StatusZombie },
+}
+
+// ColorSchemeElements returns the element names that can be used in color scheme files.
+//
+// Returns:
+//   - []string: The element names in alphabetical order
+func ColorSchemeElements() []string {
+	var (
+		elements []string
+	)
+
+	for element := range colorSchemeElements {
+		elements = append(elements, element)
+	}
+	slices.Sort(elements)
+
+	return elements
+}
+
+// LoadColorSchemeFile reads a color scheme file.
+//
+// Parameters:
+//   - path: Path to the scheme file
+//
+// Returns:
+//   - map[string]string: The ANSI escape sequence of each element listed in the file
+//   - error: Any error encountered while reading or parsing the file
+func LoadColorSchemeFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open color scheme: %w", err)
+	}
+	defer file.Close()
+
+	return ParseColorScheme(file, path)
+}
+
+// ParseColorScheme parses a color scheme from a reader.
+//
+// Each line holds one "element: color" pair. Blank lines and lines starting with # are ignored,
+// and colors may be quoted. Unknown elements, duplicate elements and invalid colors are reported
+// with the line they appear on.
+//
+// Parameters:
+//   - reader: Source of the scheme
+//   - name: Name of the scheme used in error messages, usually the file path
+//
+// Returns:
+//   - map[string]string: The ANSI escape sequence of each element listed in the scheme
+//   - error: Any error encountered while parsing the scheme
+func ParseColorScheme(reader io.Reader, name string) (map[string]string, error) {
+	var (
+		colors     map[string]string
+		element    string
+		found      bool
+		line       string
+		lineNumber int
+		scanner    *bufio.Scanner
+		value      string
+	)
+
+	colors = make(map[string]string)
+	scanner = bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lineNumber++
+		line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		element, value, found = strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected \"element: color\", got %q", name, lineNumber, line)
+		}
+		element = strings.TrimSpace(element)
+		value = unquoteValue(strings.TrimSpace(value))
+
+		if _, ok := colorSchemeElements[element]; !ok {
+			return nil, fmt.Errorf("%s:%d: unknown element %q; valid elements are: %s", name, lineNumber, element, strings.Join(ColorSchemeElements(), ", "))
+		}
+		if _, ok := colors[element]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate element %q", name, lineNumber, element)
+		}
+
+		escape, err := parseColorValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid color for %s: %w", name, lineNumber, element, err)
+		}
+		colors[element] = escape
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read color scheme %s: %w", name, err)
+	}
+
+	return colors, nil
+}
+
+// unquoteValue removes the quotes around a value, or a trailing comment from an unquoted value.
+//
+// Parameters:
+//   - value: The value part of a scheme line
+//
+// Returns:
+//   - string: The bare value
+func unquoteValue(value string) string {
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+		return value
+	}
+	if index := strings.Index(value, " #"); index >= 0 {
+		value = value[:index]
+	}
+	return strings.TrimSpace(value)
+}
+
+// parseColorValue converts a color from a scheme file into an ANSI escape sequence.
+//
+// Parameters:
+//   - value: An ANSI 256-color index (0-255) or a hex RGB value (#rrggbb)
+//
+// Returns:
+//   - string: The ANSI escape sequence setting the foreground color
+//   - error: An error if the value is not a valid color
+func parseColorValue(value string) (string, error) {
+	if strings.HasPrefix(value, "#") {
+		rgb, err := strconv.ParseUint(value[1:], 16, 32)
+		if err != nil || len(value) != 7 {
+			return "", fmt.Errorf("%q is not a hex color of the form #rrggbb", value)
+		}
+		return fmt.Sprintf("\033[38;2;%d;%d;%dm", rgb>>16, (rgb>>8)&0xff, rgb&0xff), nil
+	}
+
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 || index > 255 {
+		return "", fmt.Errorf("%q is neither a 256-color index (0-255) nor a hex color (#rrggbb)", value)
+	}
+	return fmt.Sprintf("\033[38;5;%dm", index), nil
+}
+
+// withCustomColors returns a copy of the colorizer with the colors of a scheme file applied.
+//
+// Parameters:
+//   - colors: The ANSI escape sequence of each element, as returned by LoadColorSchemeFile
+//
+// Returns:
+//   - Colorizer: The colorizer, with the listed elements using their custom colors
+func (colorizer Colorizer) withCustomColors(colors map[string]string) Colorizer {
+	for element, escape := range colors {
+		*colorSchemeElements[element](&colorizer) = func(cs ColorScheme, text *string) {
+			*text = fmt.Sprintf("%s%s%s", escape, *text, AnsiReset)
+		}
+	}
+	return colorizer
+}
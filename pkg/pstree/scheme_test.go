@@ -0,0 +1,106 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColorScheme(t *testing.T) {
+	scheme := `# my colors
+command: "#5fafff"
+args: 245 # dimmed
+
+cpu-high: '#FF0000'
+`
+	colors, err := ParseColorScheme(strings.NewReader(scheme), "test.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"args":     "\033[38;5;245m",
+		"command":  "\033[38;2;95;175;255m",
+		"cpu-high": "\033[38;2;255;0;0m",
+	}, colors)
+
+	errorCases := []struct {
+		name     string
+		scheme   string
+		expected string
+	}{
+		{"unknown element", "command: 1\ncomand: 2\n", "test.yaml:2: unknown element \"comand\""},
+		{"duplicate element", "pid: 1\n\npid: 2\n", "test.yaml:3: duplicate element \"pid\""},
+		{"index out of range", "user: 256\n", "test.yaml:1: invalid color for user"},
+		{"short hex color", "user: \"#fff\"\n", "test.yaml:1: invalid color for user"},
+		{"named color", "# comment\nuser: red\n", "test.yaml:2: invalid color for user"},
+		{"missing separator", "user 12\n", "test.yaml:1: expected \"element: color\""},
+	}
+
+	for _, test := range errorCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseColorScheme(strings.NewReader(test.scheme), "test.yaml")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
+
+func TestLoadColorSchemeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheme.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("branches: 34\n"), 0644))
+
+	colors, err := LoadColorSchemeFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"branches": "\033[38;5;34m"}, colors)
+
+	_, err = LoadColorSchemeFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestWithCustomColors(t *testing.T) {
+	colorizer := Colorizers["256color"].withCustomColors(map[string]string{"command": "\033[38;5;34m"})
+
+	// The listed element uses the custom color
+	command := "bash"
+	colorizer.Command(ColorSchemes["xterm"], &command)
+	assert.Equal(t, "\033[38;5;34mbash"+AnsiReset, command)
+
+	// The other elements keep the default colors
+	args := "-l"
+	expected := "-l"
+	colorizer.Args(ColorSchemes["xterm"], &args)
+	Colorizers["256color"].Args(ColorSchemes["xterm"], &expected)
+	assert.Equal(t, expected, args)
+}
+
+func TestBranchColors(t *testing.T) {
+	render := func(displayOptions DisplayOptions) []string {
+		displayOptions.ColorCount = 256
+		displayOptions.ColorSupport = true
+		output := renderFixtureTree(t, fixtures.DeepChain(3), displayOptions)
+		return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	}
+
+	// The branches keep the default color of the terminal, only the entries are colored
+	lines := render(DisplayOptions{ColorizeOutput: true})
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "-+- \033["), lines[0])
+	assert.True(t, strings.HasPrefix(lines[2], "   \\--- \033["), lines[2])
+
+	// The branches element of a scheme file colors them on their own
+	branches := "\033[38;5;240m"
+	lines = render(DisplayOptions{ColorizeOutput: true, CustomColors: map[string]string{"branches": branches}})
+	assert.True(t, strings.HasPrefix(lines[0], branches+"-+-"+AnsiReset+" "), lines[0])
+	assert.NotContains(t, strings.TrimPrefix(lines[0], branches), branches)
+
+	// Coloring by an attribute doesn't color the branches
+	lines = render(DisplayOptions{ColorAttr: "cpu", CustomColors: map[string]string{"branches": branches}})
+	assert.True(t, strings.HasPrefix(lines[1], branches+" \\-+-"+AnsiReset+" "), lines[1])
+
+	// The rainbow only colors the entries
+	lines = render(DisplayOptions{RainbowOutput: true})
+	assert.True(t, strings.HasPrefix(lines[1], " \\-+-\033["), lines[1])
+}
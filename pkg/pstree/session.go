@@ -0,0 +1,71 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the sessions of the processes. A session groups the process groups started
+// from a login, e.g., an SSH connection and the jobs of its shell, and is named by the PID of its
+// leader, usually the login shell. With --sids, the session ID of each process is shown after its
+// PGID and each session leader is marked in the tree with the SL symbol of the tree style, in the
+// place of the process group leader marker of --show-pgls, since a session leader always leads a
+// group too. The --session filter narrows the tree down to the session of a given process, e.g.,
+// everything started from the login of a user that left something running.
+package pstree
+
+import (
+	"fmt"
+)
+
+// hasSID determines whether the session ID of a process can be shown, like hasPGID for the
+// process group ID.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the SID is known or the platform supports sessions, false otherwise
+func hasSID(node *Process) bool {
+	return SIDSupported || node.SID >= 0
+}
+
+// isSessionLeader reports whether a process leads its session, i.e., its PID is its SID.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the process is a session leader, false for the threads and if its SID is unknown
+func isSessionLeader(node *Process) bool {
+	return !node.IsThread && node.SID > 0 && node.PID == node.SID
+}
+
+// leaderMarker returns the character drawn in front of the command of a process at the end of
+// its tree prefix: the session leader marker with --sids, the process group leader marker with
+// --show-pgls, and the plain connector otherwise.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+//   - groupLeader: Whether the process is marked as a process group leader
+//
+// Returns:
+//   - string: The marker of the tree style
+func (processTree *ProcessTree) leaderMarker(pidIndex int, groupLeader bool) string {
+	switch {
+	case processTree.DisplayOptions.ShowSIDs && isSessionLeader(processTree.Nodes[pidIndex]):
+		return processTree.TreeChars.SL
+	case processTree.DisplayOptions.ShowPGLs && groupLeader:
+		return processTree.TreeChars.PGL
+	}
+	return processTree.TreeChars.NPGL
+}
+
+// markSession narrows the marked processes down to those in the session of the process given
+// with --session, keeping their ancestors marked so the tree remains connected. Nothing is kept
+// if that process was not collected or its session is not known.
+func (processTree *ProcessTree) markSession() {
+	processTree.Logger.Debug("Entering processTree.markSession()")
+	sid := int32(-1)
+	if pidIndex, ok := processTree.PidToIndexMap[processTree.DisplayOptions.SessionOf]; ok && processTree.Nodes[pidIndex].SID >= 0 {
+		sid = processTree.Nodes[pidIndex].SID
+	}
+	processTree.narrowMarked(func(node *Process) bool {
+		return sid >= 0 && node.SID == sid
+	}, fmt.Sprintf("is in the session of PID %d", processTree.DisplayOptions.SessionOf))
+}
@@ -0,0 +1,58 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+// renderSessions renders a canned tree with the given options, dropping the unmarked processes
+func renderSessions(t *testing.T, displayOptions DisplayOptions) string {
+	return renderFixtureTree(t, fixtures.SSHSession(), displayOptions)
+}
+
+func TestShowSIDs(t *testing.T) {
+	// The SID follows the PGID, and the session leaders are marked
+	assert.Equal(t, "-+@ (   1,   1,   1) init \n |-+@ ( 100, 100, 100) sshd \n | \\-+@ (1000,1000,1000) sshd \n |   \\-+- (1001,1000,1000) sshd \n |     \\-+@ (1002,1002,1002) bash \n |       |--- (1003,1003,1002) vim \n |       \\-+- (1004,1004,1002) make \n |         \\--- (1005,1004,1002) cc1 \n \\--@ ( 200, 200, 200) cron \n", renderSessions(t, DisplayOptions{ShowPGIDs: true, ShowPIDs: true, ShowSIDs: true}))
+
+	// The session leader marker takes the place of the process group leader marker
+	assert.Equal(t, "─┬◉ (1) init \n ├─┬◉ (100) sshd \n │ └─┬◉ (1000) sshd \n │   └─┬─ (1000) sshd \n │     └─┬◉ (1002) bash \n │       ├──● (1002) vim \n │       └─┬● (1002) make \n │         └─── (1002) cc1 \n └──◉ (200) cron \n", renderSessions(t, DisplayOptions{ShowPGLs: true, ShowSIDs: true, UTF8Graphics: true}))
+}
+
+func TestMarkSession(t *testing.T) {
+	// The session of vim is the one of the shell, the sshd processes are only its ancestors
+	assert.Equal(t, "-+@ (1) init \n \\-+@ (100) sshd \n   \\-+@ (1000) sshd \n     \\-+- (1000) sshd \n       \\-+@ (1002) bash \n         |--- (1002) vim \n         \\-+- (1002) make \n           \\--- (1002) cc1 \n", renderSessions(t, DisplayOptions{SessionOf: 1003, ShowSIDs: true}))
+
+	// Nothing is in the session of a process that was not collected
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{SessionOf: 4242})
+	processTree.MarkProcesses()
+	for _, node := range processTree.Nodes {
+		assert.False(t, node.Print, node.PID)
+	}
+}
+
+func TestLeaderMarker(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{})
+	bash := processTree.PidToIndexMap[1002]
+	vim := processTree.PidToIndexMap[1003]
+
+	assert.Equal(t, processTree.TreeChars.NPGL, processTree.leaderMarker(bash, true))
+
+	processTree.DisplayOptions.ShowPGLs = true
+	assert.Equal(t, processTree.TreeChars.PGL, processTree.leaderMarker(bash, true))
+
+	processTree.DisplayOptions.ShowSIDs = true
+	assert.Equal(t, processTree.TreeChars.SL, processTree.leaderMarker(bash, true))
+	assert.Equal(t, processTree.TreeChars.PGL, processTree.leaderMarker(vim, true))
+
+	// A process whose SID is unknown leads no session
+	processTree.Nodes[bash].SID = -1
+	assert.Equal(t, processTree.TreeChars.PGL, processTree.leaderMarker(bash, true))
+
+	// Each tree style has a session leader marker of its own
+	for name, style := range TreeStyles {
+		assert.NotEmpty(t, style.SL, name)
+		assert.NotEqual(t, style.PGL, style.SL, name)
+	}
+}
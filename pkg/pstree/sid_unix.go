@@ -0,0 +1,26 @@
+//go:build !windows
+
+package pstree
+
+import (
+	"github.com/shirou/gopsutil/v4/process"
+	"golang.org/x/sys/unix"
+)
+
+// SIDSupported reports whether the session IDs can be read on this platform.
+const SIDSupported = true
+
+// ProcessSID retrieves the session ID of a process, i.e., the PID of its session leader.
+// Like ProcessPGID, this one calls getsid(2) directly instead of a context-aware method, through
+// golang.org/x/sys/unix since the syscall package doesn't have it on Linux.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - sid: The session ID of a process
+//   - err: Any error encountered while retrieving it
+func ProcessSID(proc *process.Process) (sid int, err error) {
+	sid, err = unix.Getsid(int(proc.Pid))
+	return sid, err
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package pstree
+
+import (
+	"errors"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// SIDSupported reports whether the session IDs can be read on this platform.
+// Windows has no sessions in the Unix sense, so the SIDs and the session leader markers are hidden.
+const SIDSupported = false
+
+// ProcessSID retrieves the session ID of a process.
+//
+// Parameters:
+//   - proc: Pointer to the process to inspect
+//
+// Returns:
+//   - sid: Always -1
+//   - err: Always errors.ErrUnsupported
+func ProcessSID(proc *process.Process) (sid int, err error) {
+	return -1, errors.ErrUnsupported
+}
@@ -0,0 +1,126 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the signaling of the displayed processes (--kill). The signal targets are
+// the processes the filters selected: with --contains only the matching processes and their
+// descendants are signaled, never the ancestors shown for context. The targets are ordered
+// depth-first with children before their parents, so a parent cannot respawn a child that was
+// already signaled. The signal names are mapped to the signals of the platform in signal_unix.go
+// and signal_windows.go.
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// SignalNames lists the signals accepted by --kill, without the SIG prefix.
+var SignalNames = []string{"TERM", "KILL", "HUP", "INT", "USR1", "USR2"}
+
+// ParseSignal returns the signal with the given name.
+//
+// Parameters:
+//   - name: The signal name, with or without the SIG prefix and in any case, e.g., "TERM" or "sigterm"
+//
+// Returns:
+//   - syscall.Signal: The signal
+//   - error: An error if the name is not one of SignalNames or the signal is not supported on this platform
+func ParseSignal(name string) (syscall.Signal, error) {
+	name = strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	if !slices.Contains(SignalNames, name) {
+		return 0, fmt.Errorf("valid options for --kill are: %s", strings.Join(SignalNames, ", "))
+	}
+
+	signal, ok := platformSignals[name]
+	if !ok {
+		return 0, fmt.Errorf("SIG%s is not supported on this platform", name)
+	}
+	return signal, nil
+}
+
+// SignalTarget is a process to be signaled by --kill.
+type SignalTarget struct {
+	// The process command
+	Command string
+	// The process ID
+	PID int32
+}
+
+// SignalTargets returns the displayed processes to be signaled, children before their parents.
+//
+// Threads, the orphans node, and pstree itself are never signaled. With --contains, only the
+// matching processes and their descendants are signaled, the ancestors shown for context are
+// skipped.
+//
+// Parameters:
+//   - rootIndices: Indices of the roots of the displayed trees, see RootIndices
+//
+// Returns:
+//   - []SignalTarget: The processes to be signaled in the order they are to be signaled
+func (processTree *ProcessTree) SignalTargets(rootIndices []int) []SignalTarget {
+	var (
+		rootIndex int
+		targets   []SignalTarget
+	)
+
+	targets = []SignalTarget{}
+	for _, rootIndex = range rootIndices {
+		targets = processTree.appendSignalTargets(targets, rootIndex, int32(os.Getpid()))
+	}
+	return targets
+}
+
+// appendSignalTargets appends the signal targets of a process and its descendants, children first.
+//
+// Parameters:
+//   - targets: The targets collected so far
+//   - pidIndex: Index of the process
+//   - selfPID: The PID of pstree itself, which is never signaled
+//
+// Returns:
+//   - []SignalTarget: The targets with those of the subtree appended
+func (processTree *ProcessTree) appendSignalTargets(targets []SignalTarget, pidIndex int, selfPID int32) []SignalTarget {
+	var (
+		childPidIndex int
+		node          *Process
+	)
+
+	node = processTree.Nodes[pidIndex]
+	if !node.Print {
+		return targets
+	}
+
+	childPidIndex = node.Child
+	for childPidIndex != -1 {
+		targets = processTree.appendSignalTargets(targets, childPidIndex, selfPID)
+		childPidIndex = processTree.Nodes[childPidIndex].Sister
+	}
+
+	if node.IsThread || isSyntheticNode(node) || node.PID == selfPID {
+		return targets
+	}
+	if processTree.DisplayOptions.Contains != "" && node.PrintReason < ReasonSubtree {
+		return targets
+	}
+	return append(targets, SignalTarget{Command: node.Command, PID: node.PID})
+}
+
+// SendSignal sends a signal to a process.
+//
+// Parameters:
+//   - pid: The process ID
+//   - signal: The signal to send
+//
+// Returns:
+//   - error: Any error encountered while looking up or signaling the process
+func SendSignal(pid int32, signal syscall.Signal) error {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.SendSignal(signal)
+}
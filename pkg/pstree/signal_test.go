@@ -0,0 +1,68 @@
+package pstree
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signalTestProcesses returns a process list with a subtree to be signaled:
+//
+//	init(1) -+- sshd(10) --- bash(11) --- worker(20) -+- worker(21)
+//	         |                                         \- helper(22) --- {helper}(23)
+//	         \- cron(30)
+func signalTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 10, PPID: 1, Command: "sshd"},
+		{PID: 11, PPID: 10, Command: "bash"},
+		{PID: 20, PPID: 11, Command: "worker"},
+		{PID: 21, PPID: 20, Command: "worker"},
+		{PID: 22, PPID: 20, Command: "helper"},
+		{PID: 23, PPID: 22, Command: "{helper}", IsThread: true},
+		{PID: 30, PPID: 1, Command: "cron"},
+	}
+}
+
+// signalTargetPIDs returns the PIDs of the signal targets of a tree built with the given options.
+func signalTargetPIDs(t *testing.T, displayOptions DisplayOptions) []int32 {
+	processTree := NewProcessTree(0, setupTestLogger(), signalTestProcesses(), displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	rootIndices, err := processTree.RootIndices()
+	require.NoError(t, err)
+
+	pids := []int32{}
+	for _, target := range processTree.SignalTargets(rootIndices) {
+		pids = append(pids, target.PID)
+	}
+	return pids
+}
+
+func TestParseSignal(t *testing.T) {
+	signal, err := ParseSignal("TERM")
+	require.NoError(t, err)
+	assert.Equal(t, syscall.SIGTERM, signal)
+
+	signal, err = ParseSignal("sigkill")
+	require.NoError(t, err)
+	assert.Equal(t, syscall.SIGKILL, signal)
+
+	_, err = ParseSignal("STOP")
+	assert.EqualError(t, err, "valid options for --kill are: TERM, KILL, HUP, INT, USR1, USR2")
+	_, err = ParseSignal("")
+	assert.Error(t, err)
+}
+
+func TestSignalTargets(t *testing.T) {
+	// --pid signals the whole tree, children before parents, without the threads
+	assert.Equal(t, []int32{21, 22, 20}, signalTargetPIDs(t, DisplayOptions{RootPIDs: []int32{20}}))
+
+	// --contains signals the matches and their descendants but not the ancestors shown for context
+	assert.Equal(t, []int32{21, 22, 20}, signalTargetPIDs(t, DisplayOptions{Contains: "worker"}))
+
+	// Nothing matches
+	assert.Equal(t, []int32{}, signalTargetPIDs(t, DisplayOptions{Contains: "nginx"}))
+}
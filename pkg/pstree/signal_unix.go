@@ -0,0 +1,15 @@
+//go:build !windows
+
+package pstree
+
+import "syscall"
+
+// platformSignals maps the names in SignalNames to the signals of this platform.
+var platformSignals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
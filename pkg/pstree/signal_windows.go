@@ -0,0 +1,14 @@
+//go:build windows
+
+package pstree
+
+import "syscall"
+
+// platformSignals maps the names in SignalNames to the signals of this platform.
+// Windows has no user-defined signals, so USR1 and USR2 are rejected by ParseSignal.
+var platformSignals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
@@ -17,7 +17,7 @@ func TestSimpleSort(t *testing.T) {
 	processes := []Process{proc1, proc2, proc3}
 	
 	// Sort the processes by PID
-	SortProcsByPid(&processes)
+	SortProcsByPid(&processes, false)
 	
 	// Verify that the processes are sorted by PID in ascending order
 	assert.Equal(t, int32(100), processes[0].PID)
@@ -0,0 +1,129 @@
+package pstree
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Age: 3600, Username: "root", Child: -1, Parent: -1, Sister: -1},
+		{PID: 100, PPID: 1, Command: "bash", Args: []string{"-l"}, HasCPU: true, CPUPercent: 1.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 2048}, NumFDs: -1, Print: true},
+		{PID: 101, PPID: 100, Command: "{bash}", IsThread: true},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buffer, NewSnapshot(processes, 8192)))
+
+	// The fields derived while building the tree are not written
+	assert.NotContains(t, buffer.String(), `"Print"`)
+	assert.NotContains(t, buffer.String(), `"Sister"`)
+
+	snapshot, err := ReadSnapshot(&buffer, DisplayOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, SnapshotVersion, snapshot.Version)
+	assert.Equal(t, uint64(8192), snapshot.InstalledMemory)
+	assert.NotEmpty(t, snapshot.Hostname)
+
+	// Thread nodes are left out of the snapshot
+	require.Len(t, snapshot.Processes, 2)
+	assert.Equal(t, "bash", snapshot.Processes[1].Command)
+	assert.Equal(t, []string{"-l"}, snapshot.Processes[1].Args)
+	assert.Equal(t, 1.5, snapshot.Processes[1].CPUPercent)
+	assert.Equal(t, uint64(2048), snapshot.Processes[1].MemoryInfo.RSS)
+	assert.Equal(t, int32(-1), snapshot.Processes[1].NumFDs)
+	assert.Equal(t, int64(3600), snapshot.Processes[0].Age)
+	assert.False(t, snapshot.Processes[1].Print)
+}
+
+func TestReadSnapshotThreads(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "/usr/bin/java", Threads: map[int32]*cpu.TimesStat{100: {}, 102: {}, 101: {}}},
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buffer, NewSnapshot(processes, 0)))
+
+	// The thread nodes are recreated from the threads of each process
+	snapshot, err := ReadSnapshot(bytes.NewReader(buffer.Bytes()), DisplayOptions{ShowThreadsTree: true})
+	require.NoError(t, err)
+	require.Len(t, snapshot.Processes, 4)
+	assert.Equal(t, "{java}", snapshot.Processes[2].Command)
+	assert.Equal(t, int32(101), snapshot.Processes[2].PID)
+
+	snapshot, err = ReadSnapshot(bytes.NewReader(buffer.Bytes()), DisplayOptions{})
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Processes, 2)
+}
+
+func TestReadSnapshotVersions(t *testing.T) {
+	_, err := ReadSnapshot(strings.NewReader(`{"version": 99, "processes": "not a list"}`), DisplayOptions{})
+	assert.ErrorContains(t, err, "unsupported snapshot version 99")
+
+	_, err = ReadSnapshot(strings.NewReader(`{"processes": []}`), DisplayOptions{})
+	assert.ErrorContains(t, err, "not a pstree snapshot")
+
+	_, err = ReadSnapshot(strings.NewReader(`[1, 2, 3]`), DisplayOptions{})
+	assert.ErrorContains(t, err, "failed to parse snapshot")
+
+	// Fields unknown to this version are ignored
+	snapshot, err := ReadSnapshot(strings.NewReader(`{"version": 1, "processes": [{"PID": 1, "Command": "init", "Future": true}]}`), DisplayOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "init", snapshot.Processes[0].Command)
+}
+
+func TestReadSnapshotCollected(t *testing.T) {
+	// Snapshots written before the collected flags take the non-zero usage as collected
+	snapshot, err := ReadSnapshot(strings.NewReader(`{"version": 1, "processes": [{"PID": 1, "CPUPercent": 1.5, "MemoryInfo": {"rss": 4096}}, {"PID": 2, "MemoryInfo": {}}]}`), DisplayOptions{})
+	require.NoError(t, err)
+	assert.True(t, snapshot.Processes[0].HasCPU)
+	assert.True(t, snapshot.Processes[0].HasMemory)
+	assert.False(t, snapshot.Processes[1].HasCPU)
+	assert.False(t, snapshot.Processes[1].HasMemory)
+
+	// The flags written with the processes are kept, a collected usage of 0 included
+	var buffer bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buffer, NewSnapshot([]Process{{PID: 1, HasCPU: true}, {PID: 2, CPUPercent: -1}}, 0)))
+	snapshot, err = ReadSnapshot(&buffer, DisplayOptions{})
+	require.NoError(t, err)
+	assert.True(t, snapshot.Processes[0].HasCPU)
+	assert.False(t, snapshot.Processes[1].HasCPU)
+}
+
+func TestLoadSnapshotFile(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "bash"},
+		{PID: 200, PPID: 1, Command: "bash"},
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, WriteSnapshot(file, NewSnapshot(processes, 0)))
+	require.NoError(t, file.Close())
+
+	// The loaded processes render exactly like the collected ones, compact mode included
+	render := func(processes []Process) string {
+		return renderTree(t, processes, DisplayOptions{CompactMode: true})
+	}
+
+	snapshot, err := LoadSnapshotFile(path, DisplayOptions{})
+	require.NoError(t, err)
+	actual := render(snapshot.Processes)
+	assert.Equal(t, render(processes), actual)
+	assert.Contains(t, actual, "2*[bash]")
+
+	_, err = LoadSnapshotFile(filepath.Join(t.TempDir(), "missing.json"), DisplayOptions{})
+	assert.ErrorContains(t, err, "failed to open snapshot")
+}
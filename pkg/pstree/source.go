@@ -0,0 +1,93 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the sources the processes of a tree are collected from. ProcessSource
+// abstracts where the processes come from, so the same pipeline builds a tree from the running
+// system with SystemSource, from a snapshot written by --dump-snapshot with FileSource, or from a
+// fixed list of processes with MockSource, e.g., the canned trees of the fixtures package in the
+// tests. Every source returns the processes sorted by PID, with the thread nodes added when
+// DisplayOptions.ShowThreadsTree is set, so nothing after the collection depends on the source.
+package pstree
+
+import (
+	"slices"
+)
+
+// ProcessSource is a source of the processes of a tree.
+type ProcessSource interface {
+	// Processes returns the processes sorted by PID, with the attributes enabled by miniOptions,
+	// adding the time spent collecting them to timings unless it is nil
+	Processes(miniOptions DisplayOptions, timings *CollectionTimings) ([]Process, error)
+}
+
+// SystemSource is the ProcessSource of the processes running on the system, see GetProcesses.
+type SystemSource struct{}
+
+// FileSource is the ProcessSource of a snapshot file written by --dump-snapshot, see LoadSnapshotFile.
+type FileSource struct {
+	// Installed memory of the host the snapshot was taken on, set by Processes (0 if unknown)
+	InstalledMemory uint64
+	// Path to the snapshot file
+	Path string
+}
+
+// MockSource is a ProcessSource returning a fixed list of processes, e.g., a canned tree in a test.
+type MockSource struct {
+	// Error returned by Processes instead of the processes (nil for none)
+	Err error
+	// Processes returned by Processes, in any order
+	Procs []Process
+}
+
+// Processes collects the processes running on the system like GetProcessesWithTimings.
+//
+// Parameters:
+//   - miniOptions: DisplayOptions struct controlling which attributes are collected
+//   - timings: The timings the durations are added to, nil to not record them
+//
+// Returns:
+//   - []Process: The processes sorted by PID
+//   - error: An error if the list of processes could not be retrieved
+func (source SystemSource) Processes(miniOptions DisplayOptions, timings *CollectionTimings) ([]Process, error) {
+	return GetProcessesWithTimings(miniOptions, timings)
+}
+
+// Processes reads the processes of the snapshot file, and records the installed memory of the
+// host it was taken on. Nothing is collected, so the timings are left as they are.
+//
+// Parameters:
+//   - miniOptions: DisplayOptions struct controlling how the processes are prepared, see ReadSnapshot
+//   - timings: Unused, the processes are not collected
+//
+// Returns:
+//   - []Process: The processes sorted by PID
+//   - error: Any error encountered while reading or parsing the file
+func (source *FileSource) Processes(miniOptions DisplayOptions, timings *CollectionTimings) ([]Process, error) {
+	snapshot, err := LoadSnapshotFile(source.Path, miniOptions)
+	if err != nil {
+		return nil, err
+	}
+	source.InstalledMemory = snapshot.InstalledMemory
+	return snapshot.Processes, nil
+}
+
+// Processes returns a copy of the fixed processes, or the fixed error.
+//
+// Parameters:
+//   - miniOptions: DisplayOptions struct controlling how the processes are prepared
+//   - timings: Unused, the processes are not collected
+//
+// Returns:
+//   - []Process: The processes sorted by PID, with thread nodes added when miniOptions.ShowThreadsTree is set
+//   - error: MockSource.Err
+func (source MockSource) Processes(miniOptions DisplayOptions, timings *CollectionTimings) ([]Process, error) {
+	if source.Err != nil {
+		return nil, source.Err
+	}
+
+	processes := slices.Clone(source.Procs)
+	SortProcsByPid(&processes, false)
+	if miniOptions.ShowThreadsTree {
+		processes = appendThreadNodes(processes)
+	}
+	return processes, nil
+}
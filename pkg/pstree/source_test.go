@@ -0,0 +1,116 @@
+package pstree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureSource returns a MockSource with the processes of a canned tree
+func fixtureSource(tree []fixtures.Process) MockSource {
+	processes := make([]Process, 0, len(tree))
+	for _, fixture := range tree {
+		processes = append(processes, Process{
+			Args:     fixture.Args,
+			Command:  fixture.Command,
+			NumFDs:   -1,
+			PGID:     fixture.PGID,
+			PID:      fixture.PID,
+			PPID:     fixture.PPID,
+			SID:      fixture.SID,
+			Terminal: fixture.Terminal,
+			Username: fixture.Username,
+		})
+	}
+	return MockSource{Procs: processes}
+}
+
+// newFixtureTree builds a process tree from a canned tree, collected through its MockSource
+func newFixtureTree(t *testing.T, tree []fixtures.Process, displayOptions DisplayOptions) *ProcessTree {
+	processes, err := fixtureSource(tree).Processes(displayOptions, nil)
+	require.NoError(t, err)
+	return NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+}
+
+// renderFixtureTree renders a canned tree like renderTree, collected through its MockSource
+func renderFixtureTree(t *testing.T, tree []fixtures.Process, displayOptions DisplayOptions) string {
+	t.Helper()
+	return renderProcessTree(t, newFixtureTree(t, tree, renderDefaults(displayOptions)))
+}
+
+func TestMockSource(t *testing.T) {
+	source := MockSource{Procs: []Process{
+		{PID: 100, PPID: 1, Command: "java", Threads: map[int32]*cpu.TimesStat{100: {}, 101: {}}},
+		{PID: 1, PPID: 0, Command: "init"},
+	}}
+
+	// The processes are sorted by PID, without changing the fixed ones
+	processes, err := source.Processes(DisplayOptions{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 100}, []int32{processes[0].PID, processes[1].PID})
+	assert.Equal(t, int32(100), source.Procs[0].PID)
+
+	processes, err = source.Processes(DisplayOptions{ShowThreadsTree: true}, nil)
+	require.NoError(t, err)
+	require.Len(t, processes, 3)
+	assert.Equal(t, "{java}", processes[2].Command)
+
+	_, err = MockSource{Err: errors.New("no processes")}.Processes(DisplayOptions{}, nil)
+	assert.EqualError(t, err, "no processes")
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, WriteSnapshot(file, NewSnapshot([]Process{{PID: 1, Command: "init"}}, 8192)))
+	require.NoError(t, file.Close())
+
+	source := &FileSource{Path: path}
+	processes, err := source.Processes(DisplayOptions{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "init", processes[0].Command)
+	assert.Equal(t, uint64(8192), source.InstalledMemory)
+
+	_, err = (&FileSource{Path: filepath.Join(t.TempDir(), "missing.json")}).Processes(DisplayOptions{}, nil)
+	assert.Error(t, err)
+}
+
+func TestSystemSource(t *testing.T) {
+	var source ProcessSource = SystemSource{}
+	processes, err := source.Processes(DisplayOptions{Workers: 2}, nil)
+	require.NoError(t, err)
+
+	_, err = GetProcessByPid(&processes, int32(os.Getpid()))
+	assert.NoError(t, err)
+}
+
+func TestFixtures(t *testing.T) {
+	// A deep chain is printed one level deeper on each line
+	processTree := newFixtureTree(t, fixtures.DeepChain(5), DisplayOptions{MaxDepth: 10, ScreenWidth: 80})
+	processTree.MarkProcesses()
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	assert.Equal(t, "-+- init \n \\-+- bash \n   \\-+- bash \n     \\-+- bash \n       \\--- bash \n", output)
+
+	// The sessions of a wide fan-out are all children of the daemon
+	processTree = newFixtureTree(t, fixtures.WideFanOut(50), DisplayOptions{})
+	assert.Len(t, processTree.Nodes, 102)
+	children := 0
+	for child := processTree.Nodes[processTree.PidToIndexMap[100]].Child; child != -1; child = processTree.Nodes[child].Sister {
+		children++
+	}
+	assert.Equal(t, 50, children)
+
+	// The orphaned worker is a root of its own
+	processTree = newFixtureTree(t, fixtures.OrphanedChild(), DisplayOptions{})
+	rootIndices, err := processTree.RootIndices()
+	require.NoError(t, err)
+	assert.Equal(t, []int{processTree.PidToIndexMap[1], processTree.PidToIndexMap[300]}, rootIndices)
+}
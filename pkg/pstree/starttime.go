@@ -0,0 +1,39 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the start time of each process shown with --start-time, which is easier
+// to correlate with logs than its age. It is read from the create time collected for the age,
+// and shown the way ps shows it: the time of the day for a process started within the last day,
+// e.g., (start: 15:04:05), and the date and time for an older one, e.g., (start: Jan 02 15:04).
+// The time is local, or UTC with --utc. In compacted view, the group shows the start time of
+// its oldest member, like its age.
+package pstree
+
+import (
+	"fmt"
+	"time"
+)
+
+// formatStartTime formats the start time of a process or compact group.
+//
+// Parameters:
+//   - createTime: The create time as a Unix timestamp, 0 or negative if it is unknown
+//   - age: The age in seconds, which decides whether the date is shown
+//
+// Returns:
+//   - string: The formatted start time, e.g., (start: 15:04:05), or (start: ?) if it is unknown
+func (processTree *ProcessTree) formatStartTime(createTime int64, age int64) string {
+	if createTime <= 0 {
+		return "(start: ?)"
+	}
+
+	start := time.Unix(createTime, 0).Local()
+	if processTree.DisplayOptions.UTC {
+		start = start.UTC()
+	}
+
+	layout := "15:04:05"
+	if age >= 86400 {
+		layout = "Jan 02 15:04"
+	}
+	return fmt.Sprintf("(start: %s)", start.Format(layout))
+}
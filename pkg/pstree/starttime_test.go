@@ -0,0 +1,62 @@
+package pstree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatStartTime(t *testing.T) {
+	createTime := time.Date(2024, time.March, 5, 9, 7, 3, 0, time.UTC).Unix()
+	processTree := &ProcessTree{DisplayOptions: DisplayOptions{UTC: true}}
+
+	assert.Equal(t, "(start: 09:07:03)", processTree.formatStartTime(createTime, 3600))
+	assert.Equal(t, "(start: Mar 05 09:07)", processTree.formatStartTime(createTime, 86400))
+	assert.Equal(t, "(start: ?)", processTree.formatStartTime(0, 0))
+	assert.Equal(t, "(start: ?)", processTree.formatStartTime(-1, 0))
+
+	// Without --utc, the time is local
+	processTree.DisplayOptions.UTC = false
+	expected := "(start: " + time.Unix(createTime, 0).Local().Format("15:04:05") + ")"
+	assert.Equal(t, expected, processTree.formatStartTime(createTime, 3600))
+}
+
+func TestStartTimeOutput(t *testing.T) {
+	now := time.Now().Unix()
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Age: 2 * 86400, CreateTime: now - 2*86400},
+		{PID: 100, PPID: 1, Command: "worker", Age: 60, CreateTime: now - 60},
+		{PID: 101, PPID: 1, Command: "worker", Age: 120, CreateTime: now - 120},
+		{PID: 102, PPID: 1, Command: "cron", CreateTime: -1},
+	}
+	start := func(createTime int64, layout string) string {
+		return "(start: " + time.Unix(createTime, 0).UTC().Format(layout) + ")"
+	}
+
+	// --age and --start-time show both fields, the age first
+	displayOptions := DisplayOptions{MaxDepth: 10, ScreenWidth: 120, ShowProcessAge: true, ShowStartTime: true, UTC: true}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	processTree.MarkProcesses()
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	assert.Contains(t, output, start(now-2*86400, "Jan 02 15:04")+" init")
+	assert.Contains(t, output, start(now-60, "15:04:05")+" worker")
+	assert.Contains(t, output, "(start: ?) cron")
+	assert.Contains(t, output, "(00:00:01:00) "+start(now-60, "15:04:05"))
+
+	// The compact group shows the start time of its oldest member
+	processTree = NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{CompactMode: true, MaxDepth: 10, ScreenWidth: 120, ShowStartTime: true, UTC: true})
+	processTree.MarkProcesses()
+	processTree.InitCompactMode()
+	count, createTimes := 0, []int64{}
+	for _, group := range processTree.ProcessGroups {
+		if group.Count == 2 {
+			count++
+			createTimes = append(createTimes, group.CreateTime)
+		}
+	}
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []int64{now - 120}, createTimes)
+}
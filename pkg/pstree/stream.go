@@ -0,0 +1,43 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the streaming print of --stream. PrintTree writes each line to the output
+// as soon as it is built, but a few features make a pass over the whole tree before the first
+// line: the identity fields are padded to the widest value among the processes printed, the
+// processes of compact mode are grouped, which is also done when it is disabled, and --align
+// buffers every line until the tree is complete. On a host with tens of thousands of processes,
+// these passes delay the first line and hold the formatted fields of every process in memory.
+// With --stream, the identity fields are printed unpadded, the processes are only grouped in
+// compact mode, and the lines are never buffered, so the first line is written right away and
+// a reader going away, e.g., pstree --stream | head, stops the walk at the next line.
+package pstree
+
+// initStream prepares the printing of a tree with --stream, in place of the passes over the
+// whole tree made by PrintTree before the first line.
+func (processTree *ProcessTree) initStream() {
+	processTree.Logger.Debug("Entering processTree.initStream()")
+	if processTree.DisplayOptions.CompactMode {
+		processTree.InitCompactMode()
+	}
+	processTree.identityWidths = processTree.streamIdentityWidths()
+}
+
+// streamIdentityWidths returns the widths of the identity fields with --stream: 1 for each
+// field enabled by the display options, whether or not any process has a known value, so the
+// fields are shown unpadded, e.g., (1234,1), without measuring the processes first.
+//
+// Returns:
+//   - map[string]int: The width of each enabled field, see identityFieldKeys
+func (processTree *ProcessTree) streamIdentityWidths() map[string]int {
+	widths := make(map[string]int, len(identityFieldKeys))
+	for key, enabled := range map[string]bool{
+		"pgid": processTree.DisplayOptions.ShowPGIDs,
+		"pid":  processTree.DisplayOptions.ShowPIDs,
+		"ppid": processTree.DisplayOptions.ShowPPIDs,
+		"sid":  processTree.DisplayOptions.ShowSIDs,
+	} {
+		if enabled {
+			widths[key] = 1
+		}
+	}
+	return widths
+}
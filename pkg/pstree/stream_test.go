@@ -0,0 +1,71 @@
+package pstree
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renderStream renders a canned tree with the PIDs and PPIDs shown and the given options
+func renderStream(t *testing.T, tree []fixtures.Process, displayOptions DisplayOptions) string {
+	displayOptions.ShowPIDs = true
+	displayOptions.ShowPPIDs = true
+	return renderFixtureTree(t, tree, displayOptions)
+}
+
+func TestPrintTreeStream(t *testing.T) {
+	// The identity fields are padded to a common width measured beforehand
+	assert.Contains(t, renderStream(t, fixtures.SSHSession(), DisplayOptions{}), "-+- (   1,   0) init \n |-+- ( 100,   1) sshd \n")
+
+	// With --stream, they are printed unpadded
+	output := renderStream(t, fixtures.SSHSession(), DisplayOptions{Stream: true})
+	assert.Contains(t, output, "-+- (1,0) init \n |-+- (100,1) sshd \n")
+	assert.Contains(t, output, "(1004,1002) make")
+
+	// The lines are never buffered, so --align is ignored
+	output = renderStream(t, fixtures.SSHSession(), DisplayOptions{ShowCpuPercent: true, Stream: true})
+	assert.Equal(t, output, renderStream(t, fixtures.SSHSession(), DisplayOptions{AlignColumns: true, ShowCpuPercent: true, Stream: true}))
+}
+
+func TestStreamIdentityWidths(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{ShowPGIDs: true, ShowPIDs: true})
+	assert.Equal(t, map[string]int{"pgid": 1, "pid": 1}, processTree.streamIdentityWidths())
+
+	processTree.DisplayOptions = DisplayOptions{}
+	assert.Empty(t, processTree.streamIdentityWidths())
+}
+
+// TestPrintTreeStreamBrokenPipe tests that a streamed tree stops once the read end of the output is closed
+func TestPrintTreeStreamBrokenPipe(t *testing.T) {
+	processes := []Process{{PID: 1, PPID: 0, Command: "init"}}
+	for pid := int32(2); pid <= 10000; pid++ {
+		processes = append(processes, Process{PID: pid, PPID: 1, Command: fmt.Sprintf("worker-%d", pid)})
+	}
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	defer writer.Close()
+
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for range 3 {
+			scanner.Scan()
+		}
+		reader.Close()
+	}()
+
+	// --align is ignored, so the first lines are written before the rest of the tree is formatted
+	output := &countingWriter{writer: writer}
+	processTree := NewProcessTreeWithOutput(0, setupTestLogger(), processes, DisplayOptions{AlignColumns: true, MaxDepth: 999, ShowPIDs: true, ShowPPIDs: true, Stream: true, WideDisplay: true}, output)
+	processTree.MarkProcesses()
+
+	err = processTree.PrintTree(0, "")
+	assert.ErrorIs(t, err, ErrBrokenPipe)
+	assert.Less(t, output.writes, len(processes), "printing should stop at the first failed write")
+	assert.Nil(t, processTree.alignedLines)
+}
@@ -0,0 +1,143 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the summary footer (--summary). The totals are computed from the processes
+// marked for display, so filters such as --contains and --user are reflected in the summary,
+// and every process is counted even when compact mode collapses it into a group.
+package pstree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// Summary holds the totals of the processes marked for display.
+type Summary struct {
+	// Total CPU usage percentage
+	CPUPercent float64
+	// Whether the CPU usage was collected
+	HasCPUPercent bool
+	// Whether the memory usage was collected
+	HasMemory bool
+	// Whether the number of threads was collected
+	HasThreads bool
+	// Whether the zombies were counted
+	HasZombies bool
+	// Total resident memory in bytes
+	MemoryUsage uint64
+	// Number of processes, not counting the thread nodes of --show-threads-tree or the orphans node
+	Processes int
+	// Total number of threads
+	Threads int64
+	// Number of processes selected with --top, 0 without it
+	Top int
+	// Number of processes --top selected from
+	TopCandidates int
+	// Number of distinct process owners
+	Users int
+	// Number of zombie processes
+	Zombies int
+}
+
+// Summarize computes the totals of the processes marked for display.
+//
+// Only the values collected with the current display options are totaled; the Has* fields
+// tell which ones are available. It should be called after MarkProcesses and DropUnmarked.
+//
+// Returns:
+//   - Summary: The totals of the displayed processes
+func (processTree *ProcessTree) Summarize() Summary {
+	var (
+		node    *Process
+		summary Summary
+		users   map[string]bool
+	)
+
+	summary = Summary{
+		HasCPUPercent: processTree.DisplayOptions.ShowCpuPercent,
+		HasMemory:     processTree.DisplayOptions.ShowMemoryUsage,
+		HasThreads:    processTree.DisplayOptions.ShowNumThreads,
+		HasZombies:    processTree.DisplayOptions.ShowZombies,
+	}
+	users = make(map[string]bool)
+
+	for _, node = range processTree.Nodes {
+		if !node.Print || node.IsThread || isSyntheticNode(node) {
+			continue
+		}
+		summary.Processes++
+		users[node.Username] = true
+		summary.Threads += int64(node.NumThreads)
+		summary.CPUPercent += max(processTree.cpuPercentOf(node), 0)
+		summary.MemoryUsage += MemoryValue(node, "rss")
+		if IsZombie(*node) {
+			summary.Zombies++
+		}
+	}
+	summary.Users = len(users)
+
+	if processTree.DisplayOptions.Top > 0 {
+		summary.Top = min(processTree.DisplayOptions.Top, processTree.TopCandidates)
+		summary.TopCandidates = processTree.TopCandidates
+	}
+
+	return summary
+}
+
+// String formats the summary as a single line, e.g.,
+// "87 processes, 3 users, 412 threads, total RSS 6.20 GiB, total CPU 113.00%".
+// The thread, memory, CPU, and zombie totals are left out when they were not collected. With
+// --top, the number of processes selected is appended, e.g., "showing top 20 of 873".
+//
+// Returns:
+//   - string: The formatted summary
+func (summary Summary) String() string {
+	var (
+		parts []string
+	)
+
+	parts = append(parts, pluralize(summary.Processes, "process", "processes"))
+	parts = append(parts, pluralize(summary.Users, "user", "users"))
+	if summary.HasThreads {
+		parts = append(parts, pluralize(int(summary.Threads), "thread", "threads"))
+	}
+	if summary.HasMemory {
+		parts = append(parts, fmt.Sprintf("total RSS %s", util.ByteConverter(summary.MemoryUsage)))
+	}
+	if summary.HasCPUPercent {
+		parts = append(parts, fmt.Sprintf("total CPU %.2f%%", summary.CPUPercent))
+	}
+	if summary.HasZombies {
+		parts = append(parts, pluralize(summary.Zombies, "zombie", "zombies"))
+	}
+	if summary.TopCandidates > 0 {
+		parts = append(parts, fmt.Sprintf("showing top %d of %d", summary.Top, summary.TopCandidates))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// PrintSummary writes the summary of the displayed processes to the output of the tree.
+//
+// Returns:
+//   - error: ErrBrokenPipe if the reader of the output went away, or any other error encountered while writing the line
+func (processTree *ProcessTree) PrintSummary() error {
+	return processTree.writeLine(processTree.Summarize().String())
+}
+
+// pluralize formats a count followed by the singular or plural form of a noun.
+//
+// Parameters:
+//   - count: The count
+//   - singular: The noun used for a count of one
+//   - plural: The noun used for any other count
+//
+// Returns:
+//   - string: The count and noun, e.g., "1 process" or "3 processes"
+func pluralize(count int, singular string, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
@@ -0,0 +1,56 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	rss := func(mib uint64) *process.MemoryInfoStat {
+		return &process.MemoryInfoStat{RSS: mib * 1024 * 1024}
+	}
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root", NumThreads: 1, HasCPU: true, CPUPercent: 0.5, HasMemory: true, MemoryInfo: rss(8)},
+		{PID: 100, PPID: 1, Command: "nginx", Username: "www", NumThreads: 4, HasCPU: true, CPUPercent: 10, HasMemory: true, MemoryInfo: rss(100)},
+		{PID: 101, PPID: 1, Command: "nginx", Username: "www", NumThreads: 4, HasCPU: true, CPUPercent: 20, HasMemory: true, MemoryInfo: rss(100)},
+		{PID: 102, PPID: 101, Command: "{nginx}", Username: "www", IsThread: true},
+		{PID: 200, PPID: 1, Command: "cron", Username: "root", NumThreads: 1, CPUPercent: -1},
+	}
+
+	summarize := func(displayOptions DisplayOptions) Summary {
+		processTree := NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+		processTree.MarkProcesses()
+		processTree.DropUnmarked()
+		return processTree.Summarize()
+	}
+
+	// Compacted processes still count, thread nodes and unreadable CPU usage don't
+	summary := summarize(DisplayOptions{CompactMode: true, ShowCpuPercent: true, ShowMemoryUsage: true, ShowNumThreads: true})
+	assert.Equal(t, 4, summary.Processes)
+	assert.Equal(t, 2, summary.Users)
+	assert.Equal(t, int64(10), summary.Threads)
+	assert.Equal(t, uint64(208*1024*1024), summary.MemoryUsage)
+	assert.InDelta(t, 30.5, summary.CPUPercent, 0.001)
+	assert.Equal(t, "4 processes, 2 users, 10 threads, total RSS 208.00 MiB, total CPU 30.50%", summary.String())
+
+	// Filters are reflected in the totals, and values that weren't collected are left out
+	summary = summarize(DisplayOptions{Contains: "cron"})
+	assert.Equal(t, "2 processes, 1 user", summary.String())
+}
+
+func TestRenderStringSummary(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, Command: "bash", Username: "root"},
+	}
+	output := renderTree(t, processes, DisplayOptions{ShowSummary: true})
+	assert.Equal(t, "-+- init \n \\--- bash \n2 processes, 1 user\n", output)
+}
+
+func TestPluralize(t *testing.T) {
+	assert.Equal(t, "0 processes", pluralize(0, "process", "processes"))
+	assert.Equal(t, "1 process", pluralize(1, "process", "processes"))
+	assert.Equal(t, "2 processes", pluralize(2, "process", "processes"))
+}
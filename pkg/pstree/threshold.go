@@ -0,0 +1,72 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the resource usage filters (--min-cpu and --min-mem). They are applied
+// after the other filters have marked the processes to display, and narrow the selection down
+// to the processes using at least the given CPU percentage and resident memory. The ancestors
+// of those processes stay marked so the tree remains connected.
+package pstree
+
+import (
+	"fmt"
+)
+
+// markThresholds unmarks the processes below the --min-cpu or --min-mem thresholds.
+//
+// Only the processes marked by the other filters are considered. A process meeting the
+// thresholds is marked along with its ancestors, and only with MatchSubtree along with all of
+// its descendants, see narrowMarked.
+func (processTree *ProcessTree) markThresholds() {
+	processTree.Logger.Debug("Entering processTree.markThresholds()")
+	processTree.narrowMarked(processTree.meetsThresholds, "meets the resource thresholds")
+}
+
+// narrowMarked narrows the marked processes down to those for which keep returns true.
+//
+// Only the processes marked by the other filters are considered. A kept process is marked along
+// with its ancestors, and only with MatchSubtree along with all of its descendants, see markMatch.
+//
+// Parameters:
+//   - keep: Determines whether a marked process stays marked
+//   - reason: Describes a kept process in the debug log, e.g., "meets the resource thresholds"
+func (processTree *ProcessTree) narrowMarked(keep func(node *Process) bool, reason string) {
+	var (
+		pidIndex int
+		selected []bool
+	)
+
+	selected = make([]bool, len(processTree.Nodes))
+	for pidIndex = range processTree.Nodes {
+		selected[pidIndex] = processTree.Nodes[pidIndex].Print
+		processTree.Nodes[pidIndex].Print = false
+		processTree.Nodes[pidIndex].PrintReason = ReasonNone
+	}
+
+	for pidIndex = range processTree.Nodes {
+		if selected[pidIndex] && keep(processTree.Nodes[pidIndex]) {
+			processTree.Logger.Debug(fmt.Sprintf("PID %d %s", processTree.Nodes[pidIndex].PID, reason))
+			processTree.markMatch(pidIndex, processTree.DisplayOptions.MatchSubtree)
+		}
+	}
+}
+
+// meetsThresholds determines whether a process uses at least the --min-cpu and --min-mem thresholds.
+// When both thresholds are given, the process has to meet both of them.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the process meets all thresholds that are set, false otherwise
+func (processTree *ProcessTree) meetsThresholds(node *Process) bool {
+	if node.IsThread {
+		// Thread nodes share the usage of their process, which is shown on the process itself
+		return false
+	}
+	if processTree.DisplayOptions.MinCPU > 0 && processTree.cpuPercentOf(node) < processTree.DisplayOptions.MinCPU {
+		return false
+	}
+	if processTree.DisplayOptions.MinMemory > 0 && MemoryValue(node, "rss") < processTree.DisplayOptions.MinMemory {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+// thresholdTestProcesses returns a small tree where only some processes use a lot of resources:
+//
+//	init(1) -+- postgres(100, 40% cpu, 512 MiB) -+- worker(101, 1% cpu, 8 MiB)
+//	         |                                   \- worker(102, 30% cpu, 64 MiB)
+//	         \- cron(200, 0% cpu, 4 MiB)
+func thresholdTestProcesses() []Process {
+	rss := func(mib uint64) *process.MemoryInfoStat {
+		return &process.MemoryInfoStat{RSS: mib * 1024 * 1024}
+	}
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 0.1, HasMemory: true, MemoryInfo: rss(12)},
+		{PID: 100, PPID: 1, Command: "postgres", HasCPU: true, CPUPercent: 40, HasMemory: true, MemoryInfo: rss(512)},
+		{PID: 101, PPID: 100, Command: "worker", HasCPU: true, CPUPercent: 1, HasMemory: true, MemoryInfo: rss(8)},
+		{PID: 102, PPID: 100, Command: "worker", HasCPU: true, CPUPercent: 30, HasMemory: true, MemoryInfo: rss(64)},
+		{PID: 200, PPID: 1, Command: "cron", HasCPU: true, CPUPercent: 0, HasMemory: true, MemoryInfo: rss(4)},
+	}
+}
+
+// markedPIDs returns the PIDs of the processes marked for display in PID order.
+func markedPIDs(processTree *ProcessTree) []int32 {
+	pids := []int32{}
+	for _, node := range processTree.Nodes {
+		if node.Print {
+			pids = append(pids, node.PID)
+		}
+	}
+	return pids
+}
+
+func TestMarkThresholds(t *testing.T) {
+	mark := func(displayOptions DisplayOptions) []int32 {
+		processTree := NewProcessTree(0, setupTestLogger(), thresholdTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		return markedPIDs(processTree)
+	}
+
+	// Processes at or above the threshold are marked along with their ancestors
+	assert.Equal(t, []int32{1, 100, 102}, mark(DisplayOptions{MinCPU: 30}))
+	assert.Equal(t, []int32{1, 100}, mark(DisplayOptions{MinMemory: 100 * 1024 * 1024}))
+
+	// Both thresholds have to be met
+	assert.Equal(t, []int32{1, 100}, mark(DisplayOptions{MinCPU: 20, MinMemory: 100 * 1024 * 1024}))
+
+	// With MatchSubtree, the descendants below the threshold are shown as well
+	assert.Equal(t, []int32{1, 100, 101, 102}, mark(DisplayOptions{MinCPU: 35, MatchSubtree: true}))
+
+	// The thresholds narrow down the other filters
+	assert.Equal(t, []int32{1, 100, 102}, mark(DisplayOptions{Contains: "worker", MinCPU: 20}))
+	assert.Equal(t, []int32{}, mark(DisplayOptions{Contains: "cron", MinCPU: 20}))
+}
+
+func TestMeetsThresholds(t *testing.T) {
+	processTree := &ProcessTree{DisplayOptions: DisplayOptions{MinMemory: 1024}}
+
+	assert.True(t, processTree.meetsThresholds(&Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1024}}))
+	assert.False(t, processTree.meetsThresholds(&Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 1023}}))
+
+	// Processes whose memory could not be read don't meet a memory threshold
+	assert.False(t, processTree.meetsThresholds(&Process{}))
+
+	// Thread nodes never match on their own
+	assert.False(t, processTree.meetsThresholds(&Process{IsThread: true, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 4096}}))
+}
@@ -0,0 +1,169 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the timings of the pipeline stages, so a slow run can be reported with
+// actionable data. CollectionTimings records how long the processes took to enumerate and
+// collect, how long their deferred usage took, and the time spent reading each attribute, and
+// Timings records the stages of the tree itself: building, marking, compacting, and printing.
+// With --debug, LogTimings logs a summary like collect=412ms build=9ms mark=2ms print=13ms,
+// 1843 procs. The attribute totals are summed over the workers, so together they exceed the
+// collection time.
+package pstree
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CollectionTimings records the durations of collecting the processes with GetProcessesWithTimings,
+// and how the reads of each attribute failed, see addResult. Its methods do nothing on a nil
+// receiver, so the collection is only instrumented when asked.
+type CollectionTimings struct {
+	// Time spent reading each attribute, summed over the processes, keyed by attribute name
+	Attributes map[string]time.Duration
+	// Time spent collecting the processes, including Enumerate
+	Collect time.Duration
+	// Time spent collecting the usage of the displayed processes in CollectDeferredUsage
+	Deferred time.Duration
+	// Time spent listing and sorting the PIDs
+	Enumerate time.Duration
+	// Reads of each attribute and how they failed, keyed by attribute name, see DeniedAttributes
+	Failures map[string]AttributeFailures
+	// Guards Attributes and Failures, the workers of generateProcesses record their attributes concurrently
+	mutex sync.Mutex
+}
+
+// Timings records the durations of the pipeline stages of a process tree.
+type Timings struct {
+	// Time spent building the tree in NewProcessTreeWithOutput
+	Build time.Duration
+	// The timings of collecting the processes, nil if they were not collected here, e.g., with --from-file
+	Collection *CollectionTimings
+	// Time spent grouping identical processes in InitCompactMode, part of Print
+	Compact time.Duration
+	// Time spent marking the processes to display in MarkProcesses
+	Mark time.Duration
+	// Time spent writing the output, set by the caller
+	Print time.Duration
+}
+
+// addAttribute adds the time elapsed since start to the total of an attribute.
+//
+// Parameters:
+//   - name: Name of the attribute, e.g., cpu
+//   - start: Time the attribute started to be read
+func (timings *CollectionTimings) addAttribute(name string, start time.Time) {
+	if timings == nil {
+		return
+	}
+	elapsed := time.Since(start)
+
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+	if timings.Attributes == nil {
+		timings.Attributes = make(map[string]time.Duration)
+	}
+	timings.Attributes[name] += elapsed
+}
+
+// addSince adds the time elapsed since start to a stage, meant to be deferred.
+//
+// Parameters:
+//   - total: The stage to add to
+//   - start: Time the stage started
+func addSince(total *time.Duration, start time.Time) {
+	*total += time.Since(start)
+}
+
+// formatTiming formats a duration for the timings summary, in milliseconds or, below a
+// millisecond, in microseconds.
+//
+// Parameters:
+//   - duration: The duration to format
+//
+// Returns:
+//   - string: The rounded duration, e.g., 412ms or 850µs
+func formatTiming(duration time.Duration) string {
+	if duration < time.Millisecond {
+		return duration.Round(time.Microsecond).String()
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+// Summary returns the durations of the stages on a single line, e.g.,
+// collect=412ms build=9ms mark=2ms print=13ms, 1843 procs. The collection is left out when the
+// processes were not collected here, deferred when no usage was deferred by SplitCollection,
+// and compact when the tree was not compacted.
+//
+// Parameters:
+//   - processes: Number of processes in the tree
+//
+// Returns:
+//   - string: The summary of the stages
+func (timings Timings) Summary(processes int) string {
+	var stages []string
+
+	if timings.Collection != nil {
+		stages = append(stages, "collect="+formatTiming(timings.Collection.Collect), "enumerate="+formatTiming(timings.Collection.Enumerate))
+	}
+	stages = append(stages, "build="+formatTiming(timings.Build), "mark="+formatTiming(timings.Mark))
+	if timings.Collection != nil && timings.Collection.Deferred > 0 {
+		stages = append(stages, "deferred="+formatTiming(timings.Collection.Deferred))
+	}
+	if timings.Compact > 0 {
+		stages = append(stages, "compact="+formatTiming(timings.Compact))
+	}
+	stages = append(stages, "print="+formatTiming(timings.Print))
+
+	return fmt.Sprintf("%s, %d procs", strings.Join(stages, " "), processes)
+}
+
+// AttributeSummary returns the time spent reading each attribute on a single line, the
+// slowest first, e.g., args=210ms cpu=95ms username=40ms.
+//
+// Returns:
+//   - string: The summary of the attributes, or an empty string if none were recorded
+func (timings *CollectionTimings) AttributeSummary() string {
+	if timings == nil {
+		return ""
+	}
+
+	names := slices.Collect(maps.Keys(timings.Attributes))
+	slices.SortFunc(names, func(a, b string) int {
+		if result := cmp.Compare(timings.Attributes[b], timings.Attributes[a]); result != 0 {
+			return result
+		}
+		return cmp.Compare(a, b)
+	})
+
+	attributes := make([]string, 0, len(names))
+	for _, name := range names {
+		attributes = append(attributes, name+"="+formatTiming(timings.Attributes[name]))
+	}
+	return strings.Join(attributes, " ")
+}
+
+// LogTimings logs the summary of the stages and, when the processes were collected here, the
+// time spent reading each attribute, at debug level 1 and above.
+func (processTree *ProcessTree) LogTimings() {
+	var processes int
+
+	if processTree.DebugLevel < 1 {
+		return
+	}
+
+	// The orphans and container nodes are not processes
+	for _, node := range processTree.Nodes {
+		if !isSyntheticNode(node) {
+			processes++
+		}
+	}
+	processTree.Logger.Debug("Timings: " + processTree.Timings.Summary(processes))
+	if attributes := processTree.Timings.Collection.AttributeSummary(); attributes != "" {
+		processTree.Logger.Debug("Attribute timings, summed over the workers: " + attributes)
+	}
+}
@@ -0,0 +1,81 @@
+package pstree
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTimingsSummary tests that the stages are listed in pipeline order, leaving out those that did not run
+func TestTimingsSummary(t *testing.T) {
+	timings := Timings{Build: 9 * time.Millisecond, Mark: 1500 * time.Microsecond, Print: 850 * time.Microsecond}
+	assert.Equal(t, "build=9ms mark=2ms print=850µs, 12 procs", timings.Summary(12))
+
+	timings.Collection = &CollectionTimings{Collect: 412 * time.Millisecond, Enumerate: 20 * time.Millisecond}
+	timings.Compact = 3 * time.Millisecond
+	assert.Equal(t, "collect=412ms enumerate=20ms build=9ms mark=2ms compact=3ms print=850µs, 1843 procs", timings.Summary(1843))
+}
+
+// TestAttributeSummary tests that the slowest attributes are listed first
+func TestAttributeSummary(t *testing.T) {
+	var timings *CollectionTimings
+	assert.Empty(t, timings.AttributeSummary())
+
+	timings = &CollectionTimings{}
+	start := time.Now().Add(-5 * time.Millisecond)
+	timings.addAttribute("cpu", start)
+	timings.addAttribute("args", time.Now().Add(-50*time.Millisecond))
+	timings.addAttribute("cpu", start)
+
+	assert.Greater(t, timings.Attributes["cpu"], 10*time.Millisecond)
+	assert.Regexp(t, `^args=\d+ms cpu=\d+ms$`, timings.AttributeSummary())
+}
+
+// TestGetProcessesWithTimings tests that collecting the processes records the stages and the attributes read
+func TestGetProcessesWithTimings(t *testing.T) {
+	timings := &CollectionTimings{}
+	processes, err := GetProcessesWithTimings(DisplayOptions{ShowCpuPercent: true, Workers: 2}, timings)
+	require.NoError(t, err)
+	require.NotEmpty(t, processes)
+
+	assert.Positive(t, timings.Enumerate)
+	assert.GreaterOrEqual(t, timings.Collect, timings.Enumerate)
+	assert.Contains(t, timings.Attributes, "args")
+	assert.Contains(t, timings.Attributes, "cpu")
+	// Attributes that are not requested are not read
+	assert.NotContains(t, timings.Attributes, "cwd")
+}
+
+// TestLogTimings tests that the summary is only logged with debugging enabled
+func TestLogTimings(t *testing.T) {
+	var output bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+	}
+
+	processTree := NewProcessTree(0, logger, processes, DisplayOptions{})
+	processTree.MarkProcesses()
+	output.Reset()
+	processTree.LogTimings()
+	assert.Empty(t, output.String())
+
+	processTree = NewProcessTree(1, logger, processes, DisplayOptions{CompactMode: true})
+	processTree.Timings.Collection = &CollectionTimings{Attributes: map[string]time.Duration{"args": time.Millisecond}}
+	processTree.MarkProcesses()
+	processTree.InitCompactMode()
+	assert.Positive(t, processTree.Timings.Build)
+	assert.Positive(t, processTree.Timings.Mark)
+	assert.Positive(t, processTree.Timings.Compact)
+
+	output.Reset()
+	processTree.LogTimings()
+	assert.Contains(t, output.String(), "Timings: collect=")
+	assert.Contains(t, output.String(), ", 2 procs")
+	assert.Contains(t, output.String(), "Attribute timings, summed over the workers: args=1ms")
+}
@@ -0,0 +1,53 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the top N filter (--top), which narrows the tree down to the processes that
+// sort first by --order-by, e.g., the 20 processes using the most CPU, along with their ancestors
+// for context. It is applied after all the other filters, so only the processes they leave are
+// ranked. Every process counts toward N; in compact mode, identical processes that made the cut
+// are still merged into a single line, which then only counts those processes.
+package pstree
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// markTop narrows the marked processes down to the DisplayOptions.Top processes that sort first
+// by DisplayOptions.OrderBy in the DisplayOptions.OrderDir direction, keeping their ancestors
+// marked so the tree remains connected. The number of ranked processes is recorded in
+// TopCandidates for the summary.
+func (processTree *ProcessTree) markTop() {
+	processTree.Logger.Debug("Entering processTree.markTop()")
+	var (
+		candidates []int
+		descending bool
+		selected   map[*Process]bool
+	)
+
+	// Processes only shown as the ancestors of a match are context, not candidates
+	for pidIndex, node := range processTree.Nodes {
+		if node.Print && node.PrintReason != ReasonAncestor && !node.IsThread && !isSyntheticNode(node) && !processTree.isHiddenRoot(node) {
+			candidates = append(candidates, pidIndex)
+		}
+	}
+	processTree.TopCandidates = len(candidates)
+
+	descending = processTree.DisplayOptions.OrderDir == "desc"
+	slices.SortStableFunc(candidates, func(i, j int) int {
+		result := compareOrdered(processTree.Nodes[i], processTree.Nodes[j], processTree.orderBy(), processTree.memoryField(), descending)
+		if result == 0 {
+			result = cmp.Compare(processTree.Nodes[i].PID, processTree.Nodes[j].PID)
+		}
+		return result
+	})
+
+	selected = make(map[*Process]bool, processTree.DisplayOptions.Top)
+	for _, pidIndex := range candidates[:min(processTree.DisplayOptions.Top, len(candidates))] {
+		selected[processTree.Nodes[pidIndex]] = true
+	}
+
+	processTree.narrowMarked(func(node *Process) bool {
+		return selected[node]
+	}, fmt.Sprintf("is in the top %d by %s", processTree.DisplayOptions.Top, processTree.DisplayOptions.OrderBy))
+}
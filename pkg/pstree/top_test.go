@@ -0,0 +1,78 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// topTestProcesses returns a tree with a pool of identical workers busy to different degrees:
+//
+//	init(1) -+- nginx(100) -+- worker(101, 40%)
+//	         |              |- worker(102, 30%)
+//	         |              \- worker(103, 1%)
+//	         |- postgres(200, 25%)
+//	         \- cron(300, 0%)
+func topTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 0.5},
+		{PID: 100, PPID: 1, Command: "nginx", HasCPU: true, CPUPercent: 2},
+		{PID: 101, PPID: 100, Command: "worker", HasCPU: true, CPUPercent: 40},
+		{PID: 102, PPID: 100, Command: "worker", HasCPU: true, CPUPercent: 30},
+		{PID: 103, PPID: 100, Command: "worker", HasCPU: true, CPUPercent: 1},
+		{PID: 200, PPID: 1, Command: "postgres", HasCPU: true, CPUPercent: 25},
+		{PID: 300, PPID: 1, Command: "cron", HasCPU: true},
+	}
+}
+
+func TestMarkTop(t *testing.T) {
+	mark := func(displayOptions DisplayOptions) *ProcessTree {
+		displayOptions.OrderBy = "cpu"
+		processTree := NewProcessTree(0, setupTestLogger(), topTestProcesses(), displayOptions)
+		processTree.MarkProcesses()
+		return processTree
+	}
+
+	// The top processes are shown with their ancestors
+	processTree := mark(DisplayOptions{OrderDir: "desc", Top: 2})
+	assert.Equal(t, []int32{1, 100, 101, 102}, markedPIDs(processTree))
+	assert.Equal(t, 7, processTree.TopCandidates)
+
+	assert.Equal(t, []int32{1, 100, 101, 102, 200}, markedPIDs(mark(DisplayOptions{OrderDir: "desc", Top: 3})))
+
+	// In ascending order, the idle processes come first
+	assert.Equal(t, []int32{1, 300}, markedPIDs(mark(DisplayOptions{OrderDir: "asc", Top: 1})))
+
+	// Only the processes left by the other filters are ranked, their ancestors are not candidates
+	processTree = mark(DisplayOptions{Contains: "worker", OrderDir: "desc", Top: 1})
+	assert.Equal(t, []int32{1, 100, 101}, markedPIDs(processTree))
+	assert.Equal(t, 3, processTree.TopCandidates)
+
+	// A limit above the number of processes shows them all
+	assert.Len(t, markedPIDs(mark(DisplayOptions{OrderDir: "desc", Top: 50})), 7)
+}
+
+func TestTopCompactMode(t *testing.T) {
+	render := func(top int) string {
+		return renderTree(t, topTestProcesses(), DisplayOptions{CompactMode: true, OrderBy: "cpu", OrderDir: "desc", Top: top})
+	}
+
+	// Each worker counts toward the limit, and the group only counts the workers that made the cut
+	output := render(2)
+	assert.Contains(t, output, "2*[worker]")
+	assert.NotContains(t, output, "postgres")
+
+	output = render(3)
+	assert.Contains(t, output, "2*[worker]")
+	assert.Contains(t, output, "postgres")
+}
+
+func TestTopSummary(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), topTestProcesses(), DisplayOptions{OrderBy: "cpu", OrderDir: "desc", Top: 2})
+	processTree.MarkProcesses()
+	assert.Equal(t, "4 processes, 1 user, showing top 2 of 7", processTree.Summarize().String())
+
+	processTree = NewProcessTree(0, setupTestLogger(), topTestProcesses(), DisplayOptions{OrderBy: "cpu", Top: 20})
+	processTree.MarkProcesses()
+	assert.Equal(t, "7 processes, 1 user, showing top 7 of 7", processTree.Summarize().String())
+}
@@ -0,0 +1,181 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the traversal helpers for the programs using pstree as a library. The tree
+// is stored as indices into the Nodes array, each process linking its parent, its first child,
+// and its next sibling, the way the original pstree.c does. These helpers walk the links instead,
+// so a caller doesn't need to know that encoding. They follow the structure as it is after the
+// tree was built and transformed, e.g., by SortChildren, AttachOrphans, or DropUnmarked, so the
+// children come in display order and the orphans and container nodes, whose PIDs are negative,
+// are visited like the processes.
+//
+// For example, to list the processes indented by their depth:
+//
+//	processTree.Walk(func(node *Process, depth int) bool {
+//		fmt.Println(strings.Repeat("  ", depth), node.PID, node.Command)
+//		return true
+//	})
+//
+// and to print the tree of a single process on its own:
+//
+//	subtree, err := processTree.Subtree(1234)
+//	if err == nil {
+//		err = subtree.PrintTree(0, "")
+//	}
+package pstree
+
+// Walk visits every process of the tree depth first, each process before its children, and the
+// children in the order they are displayed. The roots, i.e., the processes without a parent, are
+// visited in the order of the Nodes array. The processes dropped by DropUnmarked are no longer
+// linked to their parent, so they are only visited if they are roots; their Print field tells
+// them apart.
+//
+// Parameters:
+//   - fn: Called for each process with its depth below its root, 0 for the roots; returning
+//     false stops the walk
+func (processTree *ProcessTree) Walk(fn func(node *Process, depth int) bool) {
+	visit := func(pidIndex int, depth int) bool {
+		return fn(processTree.Nodes[pidIndex], depth)
+	}
+	for pidIndex, node := range processTree.Nodes {
+		if node.Parent != -1 {
+			continue
+		}
+		if !processTree.walkFrom(pidIndex, 0, visit) {
+			return
+		}
+	}
+}
+
+// walkFrom visits a process and its descendants depth first, see Walk.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+//   - depth: Depth of the process below the root of the walk
+//   - visit: Called with the index of each process and its depth; returning false stops the walk
+//
+// Returns:
+//   - bool: false if visit stopped the walk
+func (processTree *ProcessTree) walkFrom(pidIndex int, depth int, visit func(pidIndex int, depth int) bool) bool {
+	if !visit(pidIndex, depth) {
+		return false
+	}
+	for child := processTree.Nodes[pidIndex].Child; child != -1; child = processTree.Nodes[child].Sister {
+		if !processTree.walkFrom(child, depth+1, visit) {
+			return false
+		}
+	}
+	return true
+}
+
+// ChildrenOf returns the children of a process in the order they are displayed.
+//
+// Parameters:
+//   - pid: PID of the process
+//
+// Returns:
+//   - []*Process: The children of the process, nil if it has none or was not collected
+func (processTree *ProcessTree) ChildrenOf(pid int32) []*Process {
+	var (
+		children []*Process
+	)
+
+	pidIndex, ok := processTree.PidToIndexMap[pid]
+	if !ok {
+		return nil
+	}
+	for child := processTree.Nodes[pidIndex].Child; child != -1; child = processTree.Nodes[child].Sister {
+		children = append(children, processTree.Nodes[child])
+	}
+	return children
+}
+
+// ParentOf returns the parent of a process in the tree, which is the orphans or container node
+// for the processes attached to one, and the process group leader with --by-pgroup.
+//
+// Parameters:
+//   - pid: PID of the process
+//
+// Returns:
+//   - *Process: The parent of the process, nil if it was not found
+//   - bool: false if the process is a root or was not collected
+func (processTree *ProcessTree) ParentOf(pid int32) (*Process, bool) {
+	pidIndex, ok := processTree.PidToIndexMap[pid]
+	if !ok || processTree.Nodes[pidIndex].Parent == -1 {
+		return nil, false
+	}
+	return processTree.Nodes[processTree.Nodes[pidIndex].Parent], true
+}
+
+// Subtree returns a copy of the tree rooted at a process, detached from this one: the processes
+// are copied and linked to each other the same way, so the copy can be walked, marked, or printed
+// without changing this tree, and its root has no parent nor siblings. The attributes the
+// processes point to, e.g., their memory info, are shared between both trees. The copy uses the
+// same display options, colors, and output, except for --pid and --parents-of, its root being the
+// given process.
+//
+// Parameters:
+//   - pid: PID of the root of the subtree
+//
+// Returns:
+//   - *ProcessTree: The subtree, its root at index 0 of its Nodes array
+//   - error: An error if the process was not collected
+func (processTree *ProcessTree) Subtree(pid int32) (*ProcessTree, error) {
+	var (
+		indices  []int
+		newIndex = make(map[int]int)
+	)
+
+	rootIndex, ok := processTree.PidToIndexMap[pid]
+	if !ok {
+		return nil, notFoundError([]int32{pid})
+	}
+	processTree.walkFrom(rootIndex, 0, func(pidIndex int, depth int) bool {
+		newIndex[pidIndex] = len(indices)
+		indices = append(indices, pidIndex)
+		return true
+	})
+
+	// The links leaving the subtree, i.e., the parent and the siblings of its root, are dropped
+	remap := func(pidIndex int) int {
+		if index, ok := newIndex[pidIndex]; ok {
+			return index
+		}
+		return -1
+	}
+
+	subtree := &ProcessTree{
+		ColorScheme:    processTree.ColorScheme,
+		Colorizer:      processTree.Colorizer,
+		DebugLevel:     processTree.DebugLevel,
+		DisplayOptions: processTree.DisplayOptions,
+		HostNamespaces: processTree.HostNamespaces,
+		IndexToPidMap:  make(map[int]int32, len(indices)),
+		Logger:         processTree.Logger,
+		Nodes:          make([]*Process, 0, len(indices)),
+		Output:         processTree.Output,
+		PidToIndexMap:  make(map[int32]int, len(indices)),
+		ProcessGroups:  make(map[ProcessGroupKey]ProcessGroup),
+		RootPIDs:       []int32{pid},
+		TreeChars:      processTree.TreeChars,
+		UserColors:     processTree.UserColors,
+	}
+	subtree.DisplayOptions.ParentsOf = 0
+	subtree.DisplayOptions.RootPIDs = subtree.RootPIDs
+	for index, pidIndex := range indices {
+		node := *processTree.Nodes[pidIndex]
+		node.Child = remap(node.Child)
+		node.Parent = remap(node.Parent)
+		node.Sister = remap(node.Sister)
+		node.Children = []*Process{}
+		subtree.Nodes = append(subtree.Nodes, &node)
+		subtree.PidToIndexMap[node.PID] = index
+		subtree.IndexToPidMap[index] = node.PID
+	}
+	for _, node := range subtree.Nodes {
+		if node.Parent != -1 {
+			parent := subtree.Nodes[node.Parent]
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return subtree, nil
+}
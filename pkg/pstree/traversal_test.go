@@ -0,0 +1,164 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// walkPIDs walks a tree, returning the PIDs visited and their depths, stopping after limit processes if it is positive
+func walkPIDs(processTree *ProcessTree, limit int) ([]int32, []int) {
+	var (
+		depths []int
+		pids   []int32
+	)
+
+	processTree.Walk(func(node *Process, depth int) bool {
+		pids = append(pids, node.PID)
+		depths = append(depths, depth)
+		return limit <= 0 || len(pids) < limit
+	})
+	return pids, depths
+}
+
+func TestWalk(t *testing.T) {
+	// Each process is visited before its children
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{})
+	pids, depths := walkPIDs(processTree, 0)
+	assert.Equal(t, []int32{1, 100, 1000, 1001, 1002, 1003, 1004, 1005, 200}, pids)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 5, 6, 1}, depths)
+
+	// Returning false stops the walk
+	pids, _ = walkPIDs(processTree, 3)
+	assert.Equal(t, []int32{1, 100, 1000}, pids)
+
+	// The children are visited in display order
+	processTree = newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{OrderBy: "pid", OrderDir: "desc"})
+	processTree.SortChildren()
+	pids, _ = walkPIDs(processTree, 0)
+	assert.Equal(t, []int32{1, 200, 100, 1000, 1001, 1002, 1004, 1005, 1003}, pids)
+
+	// Each root is walked in turn, and so is the orphans node with --show-orphans
+	processTree = newFixtureTree(t, fixtures.OrphanedChild(), DisplayOptions{})
+	pids, depths = walkPIDs(processTree, 0)
+	assert.Equal(t, []int32{1, 200, 300, 301}, pids)
+	assert.Equal(t, []int{0, 1, 0, 1}, depths)
+
+	processTree = newFixtureTree(t, fixtures.OrphanedChild(), DisplayOptions{ShowOrphans: true})
+	pids, depths = walkPIDs(processTree, 0)
+	assert.Equal(t, []int32{1, 200, OrphansPID, 300, 301}, pids)
+	assert.Equal(t, []int{0, 1, 0, 1, 2}, depths)
+}
+
+func TestChildrenOf(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{})
+
+	var commands []string
+	for _, child := range processTree.ChildrenOf(1002) {
+		commands = append(commands, child.Command)
+	}
+	assert.Equal(t, []string{"vim", "make"}, commands)
+
+	assert.Nil(t, processTree.ChildrenOf(1005))
+	assert.Nil(t, processTree.ChildrenOf(500))
+}
+
+func TestParentOf(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{})
+
+	parent, ok := processTree.ParentOf(1005)
+	require.True(t, ok)
+	assert.Equal(t, "make", parent.Command)
+
+	// The roots have no parent, and neither have the processes that were not collected
+	parent, ok = processTree.ParentOf(1)
+	assert.False(t, ok)
+	assert.Nil(t, parent)
+	_, ok = processTree.ParentOf(500)
+	assert.False(t, ok)
+
+	// With --by-pgroup, the parent is the leader of the process group
+	processTree = newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{ByProcessGroup: true})
+	parent, ok = processTree.ParentOf(1005)
+	require.True(t, ok)
+	assert.Equal(t, int32(1004), parent.PID)
+}
+
+func TestSubtree(t *testing.T) {
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{MaxDepth: 10, ScreenWidth: 80})
+	processTree.MarkProcesses()
+
+	subtree, err := processTree.Subtree(1002)
+	require.NoError(t, err)
+	pids, depths := walkPIDs(subtree, 0)
+	assert.Equal(t, []int32{1002, 1003, 1004, 1005}, pids)
+	assert.Equal(t, []int{0, 1, 1, 2}, depths)
+	assert.Len(t, subtree.Nodes[0].Children, 2)
+
+	// The root of the copy has no parent nor siblings, and it renders on its own
+	_, ok := subtree.ParentOf(1002)
+	assert.False(t, ok)
+	output, err := subtree.RenderString()
+	require.NoError(t, err)
+	assert.Equal(t, "-+- bash \n |--- vim \n \\-+- make \n   \\--- cc1 \n", output)
+
+	// Changing the copy leaves the tree alone
+	subtree.Nodes[0].Command = "zsh"
+	subtree.Nodes[0].Child = -1
+	assert.Equal(t, "bash", processTree.Nodes[processTree.PidToIndexMap[1002]].Command)
+	assert.Len(t, processTree.ChildrenOf(1002), 2)
+
+	_, err = processTree.Subtree(500)
+	assert.EqualError(t, err, "PID 500 not found")
+}
+
+func ExampleProcessTree_Walk() {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+		{PID: 1000, PPID: 100, Command: "bash"},
+		{PID: 200, PPID: 1, Command: "cron"},
+	}
+	processTree := NewProcessTreeWithOutput(0, setupTestLogger(), processes, DisplayOptions{}, os.Stdout)
+
+	processTree.Walk(func(node *Process, depth int) bool {
+		fmt.Printf("%s%d %s\n", strings.Repeat("  ", depth), node.PID, node.Command)
+		return true
+	})
+	// Output:
+	// 1 init
+	//   100 sshd
+	//     1000 bash
+	//   200 cron
+}
+
+func ExampleProcessTree_Subtree() {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+		{PID: 1000, PPID: 100, Command: "bash"},
+		{PID: 1001, PPID: 1000, Command: "vim"},
+	}
+	processTree := NewProcessTreeWithOutput(0, setupTestLogger(), processes, DisplayOptions{}, os.Stdout)
+
+	subtree, err := processTree.Subtree(1000)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, node := range subtree.Nodes {
+		parent, _ := subtree.ParentOf(node.PID)
+		fmt.Printf("%d %s, parent %v\n", node.PID, node.Command, parent != nil)
+	}
+	_, err = processTree.Subtree(500)
+	fmt.Println(err)
+	// Output:
+	// 1000 bash, parent false
+	// 1001 vim, parent true
+	// PID 500 not found
+}
@@ -12,42 +12,59 @@
 package pstree
 
 import (
+	"cmp"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
-	"unicode/utf8"
+	"time"
 
 	"github.com/bananazon/pstree/util"
 	"github.com/giancarlosio/gorainbow"
-	"github.com/mattn/go-runewidth"
 )
 
-var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
-
 //------------------------------------------------------------------------------
 // INITIALIZATION AND TREE CONSTRUCTION
 //------------------------------------------------------------------------------
 // Functions in this section handle the creation of the process tree structure
 // and establishing the hierarchical relationships between processes.
 
-// NewProcessTree creates a new process tree from a slice of processes.
+// NewProcessTree creates a new process tree from a slice of processes that prints to stdout.
+//
+// This is a convenience wrapper around NewProcessTreeWithOutput using os.Stdout as the output.
+//
+// Parameters:
+//   - logger: Logger instance for debug and informational messages
+//   - processes: Slice of Process objects containing the process information
+//   - displayOptions: Configuration options controlling how the tree will be displayed
+//
+// Returns:
+//   - A pointer to the newly created ProcessTree
+func NewProcessTree(debugLevel int, logger *slog.Logger, processes []Process, displayOptions DisplayOptions) (processTree *ProcessTree) {
+	return NewProcessTreeWithOutput(debugLevel, logger, processes, displayOptions, os.Stdout)
+}
+
+// NewProcessTreeWithOutput creates a new process tree from a slice of processes.
 //
 // This function initializes a ProcessTree structure, populates it with ProcessNode objects
 // created from the provided processes, and builds the hierarchical relationships between them.
 // The resulting tree can be used for traversal, filtering, and visualization of the process hierarchy.
+// PrintTree and ShowPrintable write to the given output, so callers can capture the tree.
 //
 // Parameters:
 //   - logger: Logger instance for debug and informational messages
 //   - processes: Slice of Process objects containing the process information
 //   - displayOptions: Configuration options controlling how the tree will be displayed
+//   - output: Destination of the rendered tree
 //
 // Returns:
 //   - A pointer to the newly created ProcessTree
-func NewProcessTree(debugLevel int, logger *slog.Logger, processes []Process, displayOptions DisplayOptions) (processTree *ProcessTree) {
+func NewProcessTreeWithOutput(debugLevel int, logger *slog.Logger, processes []Process, displayOptions DisplayOptions, output io.Writer) (processTree *ProcessTree) {
+	start := time.Now()
 	processTree = &ProcessTree{
 		AtDepth:        0,
 		DebugLevel:     debugLevel,
@@ -55,16 +72,16 @@ func NewProcessTree(debugLevel int, logger *slog.Logger, processes []Process, di
 		IndexToPidMap:  make(map[int]int32, len(processes)),
 		Logger:         logger,
 		Nodes:          make([]*Process, 0, len(processes)),
+		Output:         output,
 		PidToIndexMap:  make(map[int32]int, len(processes)),
-		ProcessGroups:  make(map[int32]map[string]map[string]ProcessGroup),
-		RootPID:        displayOptions.RootPID,
+		ProcessGroups:  make(map[ProcessGroupKey]ProcessGroup),
+		RootPIDs:       displayOptions.RootPIDs,
 	}
 
 	// Create nodes
 	for idx := range processes {
 		proc := &processes[idx]
 		proc.Children = []*Process{} // initialize
-		proc.Signature = ""
 
 		nodeIdx := len(processTree.Nodes)
 		processTree.Nodes = append(processTree.Nodes, proc)
@@ -80,31 +97,17 @@ func NewProcessTree(debugLevel int, logger *slog.Logger, processes []Process, di
 			parent.Children = append(parent.Children, child)
 		}
 	}
-	// IMPORTANT: clear cached signatures first
-	for _, node := range processTree.Nodes {
-		node.Signature = ""
-	}
+	// The roots requested by command are known once the processes are
+	processTree.addRootCommands()
 
-	// Compute subtree signatures for all root processes
-	for _, node := range processTree.Nodes {
-		if node.PPID == 1 || node.PID == displayOptions.RootPID {
-			computeSignature(node, displayOptions.ShowArguments)
-		}
-	}
+	// Flag the privileged processes first, their signatures keep them apart from the others
+	processTree.markPrivileged()
 
-	// If PID is not set via --pid, we want to look for PID 1...
-	// https://github.com/FredHucht/pstree/blob/main/pstree.c#L558-L587
+	// Compute the subtree signatures
+	processTree.computeSignatures()
 
-	// Define the tree characters
-	if processTree.DisplayOptions.IBM850Graphics {
-		processTree.TreeChars = TreeStyles["pc850"]
-	} else if processTree.DisplayOptions.UTF8Graphics {
-		processTree.TreeChars = TreeStyles["utf8"]
-	} else if processTree.DisplayOptions.VT100Graphics {
-		processTree.TreeChars = TreeStyles["vt100"]
-	} else {
-		processTree.TreeChars = TreeStyles["ascii"]
-	}
+	// Define the tree characters, the locale is left to the caller so the tree is drawn the same everywhere
+	processTree.TreeChars = TreeStyles[ResolveTreeStyle(processTree.DisplayOptions, nil)]
 
 	// Initialize the color scheme
 	// if 8 bit color (8-16) is detected, we will use the ansi8 color scheme
@@ -138,14 +141,62 @@ func NewProcessTree(debugLevel int, logger *slog.Logger, processes []Process, di
 		} else if processTree.DisplayOptions.ColorCount >= 256 {
 			processTree.Colorizer = Colorizers["256color"]
 		}
+		if len(processTree.DisplayOptions.CustomColors) > 0 {
+			processTree.Colorizer = processTree.Colorizer.withCustomColors(processTree.DisplayOptions.CustomColors)
+		}
+	}
+
+	// Show the attribute colored by before the groups of compact mode sum it
+	processTree.showColorAttribute()
+
+	// Give each user a stable color for --color-attr=user
+	if processTree.DisplayOptions.ColorAttr == "user" {
+		usernames := make([]string, 0, len(processTree.Nodes))
+		for _, node := range processTree.Nodes {
+			usernames = append(usernames, node.Username)
+		}
+		processTree.UserColors = assignUserColors(usernames, len(processTree.userColorFuncs())-1)
+	}
+
+	// Build the tree, by process group with --by-pgroup
+	if processTree.DisplayOptions.ByProcessGroup {
+		processTree.BuildGroupTree()
+	} else {
+		processTree.BuildTree()
+	}
+
+	// Gather the processes whose parent is missing under a single node
+	if processTree.DisplayOptions.ShowOrphans {
+		processTree.AttachOrphans()
+	}
+
+	// Find the namespaces of the host once, the processes are compared with them
+	if processTree.DisplayOptions.ShowNamespaces || processTree.DisplayOptions.OnlyForeignNS {
+		processTree.HostNamespaces = processTree.hostNamespaces()
+	}
+
+	// Move the processes of each container under a node of their own
+	if processTree.DisplayOptions.GroupByContainer {
+		processTree.AttachContainers()
+	}
+
+	// Record the depth of each process below the root it is printed under
+	processTree.AssignDepths()
+
+	// Sort the children of each process
+	if processTree.DisplayOptions.OrderBy != "" {
+		processTree.SortChildren()
 	}
 
-	// Build the tree
-	processTree.BuildTree()
+	// Sum the usage of each subtree while all of the processes are still linked
+	if processTree.DisplayOptions.ShowCumulative {
+		processTree.ComputeCumulative()
+	}
 
 	// Mark UID transitions
 	processTree.MarkUIDTransitions()
 
+	processTree.Timings.Build = time.Since(start)
 	return processTree
 }
 
@@ -173,6 +224,7 @@ func (processTree *ProcessTree) BuildTree() {
 		processTree.Nodes[i].Parent = -1
 		processTree.Nodes[i].Sister = -1
 		processTree.Nodes[i].Print = false
+		processTree.Nodes[i].ParentCommand = ""
 	}
 
 	// Build the tree using the PidToIndexMap for O(1) lookups
@@ -189,6 +241,7 @@ func (processTree *ProcessTree) BuildTree() {
 
 		// Set parent relationship
 		processTree.Nodes[pidIndex].Parent = ppidIndex
+		processTree.Nodes[pidIndex].ParentCommand = processTree.commandName(processTree.Nodes[ppidIndex])
 
 		// Add as child
 		if processTree.Nodes[ppidIndex].Child == -1 {
@@ -206,6 +259,53 @@ func (processTree *ProcessTree) BuildTree() {
 	}
 }
 
+// SortChildren sorts the children of every process by the --order-by attribute.
+//
+// Sorting is applied per parent, so the tree structure stays intact and only the order of
+// siblings changes. The Child and Sister links of each process are re-linked to follow the
+// sorted order. Siblings with equal values keep ascending PID order, regardless of --order-dir.
+func (processTree *ProcessTree) SortChildren() {
+	processTree.Logger.Debug("Entering processTree.SortChildren()")
+	var (
+		childPidIndex int
+		children      []int
+		descending    bool
+		pidIndex      int
+	)
+
+	descending = processTree.DisplayOptions.OrderDir == "desc"
+
+	for pidIndex = range processTree.Nodes {
+		children = children[:0]
+		childPidIndex = processTree.Nodes[pidIndex].Child
+		for childPidIndex != -1 {
+			children = append(children, childPidIndex)
+			childPidIndex = processTree.Nodes[childPidIndex].Sister
+		}
+		if len(children) < 2 {
+			continue
+		}
+
+		slices.SortStableFunc(children, func(i, j int) int {
+			result := compareOrdered(processTree.Nodes[i], processTree.Nodes[j], processTree.orderBy(), processTree.memoryField(), descending)
+			if result == 0 {
+				result = cmp.Compare(processTree.Nodes[i].PID, processTree.Nodes[j].PID)
+			}
+			return result
+		})
+
+		// Re-link the siblings in sorted order
+		processTree.Nodes[pidIndex].Child = children[0]
+		for i := range children {
+			if i < len(children)-1 {
+				processTree.Nodes[children[i]].Sister = children[i+1]
+			} else {
+				processTree.Nodes[children[i]].Sister = -1
+			}
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
 // PROCESS MARKING AND FILTERING
 //------------------------------------------------------------------------------
@@ -214,7 +314,13 @@ func (processTree *ProcessTree) BuildTree() {
 
 // MarkProcesses marks processes that should be displayed based on filtering criteria.
 // It applies various filters such as process name pattern matching, username filtering,
-// root process exclusion, and PID filtering to determine which processes should be displayed.
+// root process exclusion, terminal, and PID filtering to determine which processes should be displayed.
+// With --tty, only the processes attached to the terminal are matched, also by --contains and --user.
+// Likewise with --group, only the processes in one of the groups are matched, see inGroups.
+// With --parents-of, only the process and its ancestors are marked, see markAncestry.
+// Processes below the --min-cpu or --min-mem thresholds are unmarked afterwards, see markThresholds,
+// then with --only-zombies the processes that are not zombies, see markZombies, followed by the
+// processes matching one of the --exclude patterns, see markExcluded.
 //
 // Refactoring opportunity: This function could be broken down into smaller functions:
 // - applyUsernameFilter: Mark processes matching username criteria
@@ -228,42 +334,58 @@ func (processTree *ProcessTree) MarkProcesses() {
 		myPid    int32
 		process  Process
 		pidIndex int
+		roots    []int
 		showAll  bool
 		username string
 	)
+	defer addSince(&processTree.Timings.Mark, time.Now())
 
-	if processTree.DisplayOptions.Contains == "" && len(processTree.DisplayOptions.Usernames) == 0 && !processTree.DisplayOptions.ExcludeRoot && processTree.DisplayOptions.RootPID < 1 {
+	if processTree.DisplayOptions.Contains == "" && len(processTree.DisplayOptions.Usernames) == 0 && !processTree.DisplayOptions.ExcludeRoot && len(processTree.DisplayOptions.RootPIDs) == 0 && processTree.DisplayOptions.Terminal == "" && len(processTree.DisplayOptions.Groups) == 0 && processTree.DisplayOptions.ParentsOf == 0 {
 		showAll = true
 	}
 
+	if processTree.DisplayOptions.ParentsOf != 0 {
+		processTree.markAncestry()
+	}
+
 	for pidIndex = range processTree.Nodes {
 		if showAll {
 			processTree.Nodes[pidIndex].Print = true
+		} else if isSyntheticNode(processTree.Nodes[pidIndex]) {
+			// The orphans and container nodes are only shown as the parent of matching processes
+			continue
 		} else {
 			process = *processTree.Nodes[pidIndex]
 			if len(processTree.DisplayOptions.Usernames) > 0 {
 				for _, username = range processTree.DisplayOptions.Usernames {
-					if process.Username == username {
+					if process.Username == username && processTree.onTerminal(&process) && processTree.inGroups(&process) {
 						processTree.markParents(pidIndex)
 						processTree.markChildren(pidIndex)
 					}
 				}
-			} else if processTree.Nodes[pidIndex].PID == processTree.DisplayOptions.RootPID {
-				// processTree.Logger.Debug("--pid == processTree.DisplayOptions.RootPID")
+			} else if slices.Contains(processTree.DisplayOptions.RootPIDs, processTree.Nodes[pidIndex].PID) {
+				// processTree.Logger.Debug("--pid is one of processTree.DisplayOptions.RootPIDs")
 				if (processTree.DisplayOptions.ExcludeRoot && processTree.Nodes[pidIndex].Username != "root") || (!processTree.DisplayOptions.ExcludeRoot) {
 					// processTree.Logger.Debug("(processTree.DisplayOptions.ExcludeRoot && processTree.Nodes[pidIndex].Username != root) || !processTree.DisplayOptions.ExcludeRoot")
-					processTree.markParents(pidIndex)
+					// Each requested PID is printed as its own tree, so only the subtree is marked
 					processTree.markChildren(pidIndex)
 				}
-			} else if processTree.DisplayOptions.Contains != "" && strings.Contains(process.Command, processTree.DisplayOptions.Contains) && (process.PID != myPid) {
+			} else if processTree.DisplayOptions.Contains != "" && strings.Contains(process.Command, processTree.DisplayOptions.Contains) && (process.PID != myPid) && processTree.onTerminal(&process) && processTree.inGroups(&process) {
 				// processTree.Logger.Debug("processTree.DisplayOptions.Contains is set && process.Command contains processTree.DisplayOptions.Contains && process.PID != myPid")
 				if (processTree.DisplayOptions.ExcludeRoot && process.Username != "root") || (!processTree.DisplayOptions.ExcludeRoot) {
 					// processTree.Logger.Debug("(processTree.DisplayOptions.ExcludeRoot && process.Username != root) || !processTree.DisplayOptions.ExcludeRoot")
-					processTree.markParents(pidIndex)
-					processTree.markChildren(pidIndex)
+					processTree.markMatch(pidIndex, true)
 				}
 			} else if processTree.DisplayOptions.Contains != "" && !strings.Contains(process.Command, processTree.DisplayOptions.Contains) && (process.PID != myPid) {
 				// processTree.Logger.Debug("processTree.DisplayOptions.Contains is set && process.Command does not contain processTree.DisplayOptions.Contains && process.PID != myPid")
+			} else if processTree.DisplayOptions.Terminal != "" {
+				if processTree.onTerminal(&process) && processTree.inGroups(&process) && ((processTree.DisplayOptions.ExcludeRoot && process.Username != "root") || !processTree.DisplayOptions.ExcludeRoot) {
+					processTree.markMatch(pidIndex, processTree.DisplayOptions.MatchSubtree)
+				}
+			} else if len(processTree.DisplayOptions.Groups) > 0 {
+				if processTree.inGroups(&process) && ((processTree.DisplayOptions.ExcludeRoot && process.Username != "root") || !processTree.DisplayOptions.ExcludeRoot) {
+					processTree.markMatch(pidIndex, processTree.DisplayOptions.MatchSubtree)
+				}
 			} else if processTree.DisplayOptions.ExcludeRoot && process.Username != "root" {
 				// processTree.Logger.Debug("processTree.DisplayOptions.ExcludeRoot && process.Username != root")
 				processTree.markParents(pidIndex)
@@ -271,6 +393,62 @@ func (processTree *ProcessTree) MarkProcesses() {
 			}
 		}
 	}
+
+	// The requested roots are kept through the filters below, see keepRoots
+	roots = processTree.markedRoots()
+
+	if processTree.DisplayOptions.MinCPU > 0 || processTree.DisplayOptions.MinMemory > 0 {
+		processTree.markThresholds()
+	}
+	if processTree.hasAgeWindow() {
+		processTree.markAgeWindow()
+	}
+	if processTree.DisplayOptions.OnlyZombies {
+		processTree.markZombies()
+	}
+	if processTree.DisplayOptions.OnlyDeleted {
+		processTree.markDeleted()
+	}
+	if processTree.DisplayOptions.OnlyRealtime {
+		processTree.markRealtime()
+	}
+	if processTree.DisplayOptions.OnlyPrivileged {
+		processTree.markOnlyPrivileged()
+	}
+	if processTree.DisplayOptions.OnlyForeignNS {
+		processTree.markForeignNamespaces()
+	}
+	if processTree.DisplayOptions.CwdUnder != "" {
+		processTree.markCwdUnder()
+	}
+	if processTree.DisplayOptions.SessionOf != 0 {
+		processTree.markSession()
+	}
+	if processTree.DisplayOptions.EnvContains != "" {
+		processTree.markEnvironment()
+	}
+
+	// Exclusions are applied last so they win over the filters above
+	if len(processTree.DisplayOptions.ExcludeUsernames) > 0 {
+		processTree.markExcludedUsers()
+	}
+	if len(processTree.DisplayOptions.ExcludePatterns) > 0 {
+		processTree.markExcluded()
+	}
+	if processTree.DisplayOptions.HideKernelThreads {
+		processTree.markKernelThreads()
+	}
+
+	// The top processes are picked from those left by all the other filters
+	if processTree.DisplayOptions.Top > 0 {
+		processTree.markTop()
+	}
+	processTree.keepRoots(roots)
+
+	// The hidden roots are unmarked after the filters, which mark them as the ancestors of matches
+	if processTree.DisplayOptions.NoRootLine {
+		processTree.unmarkHiddenRoots()
+	}
 }
 
 // DropUnmarked removes processes that are not marked for display from the process tree.
@@ -330,8 +508,8 @@ func (processTree *ProcessTree) MarkUIDTransitions() {
 	processTree.Logger.Debug("Marking UID transitions between processes - START")
 
 	for pidIndex = range processTree.Nodes {
-		// Skip the root process (which has no parent)
-		if processTree.Nodes[pidIndex].Parent == -1 {
+		// Skip the root process (which has no parent) and the orphans, whose parent is the synthetic orphans node
+		if processTree.Nodes[pidIndex].Parent == -1 || processTree.Nodes[pidIndex].IsOrphan {
 			continue
 		}
 
@@ -367,11 +545,100 @@ func (processTree *ProcessTree) MarkUIDTransitions() {
 	}
 }
 
+// MarkCurrentAndAncestors marks the highlighted process and all of its ancestors.
+// This function looks up the process given by --highlight-pid (or --highlight-self) and sets
+// IsCurrentOrAncestor=true on it and on every parent up to the root, so PrintTree can render
+// the chain highlighted. It should be called after DropUnmarked; nothing is marked if the
+// process is not part of the tree.
+func (processTree *ProcessTree) MarkCurrentAndAncestors() {
+	var (
+		exists   bool
+		pidIndex int
+	)
+
+	if processTree.DisplayOptions.HighlightPID < 1 {
+		return
+	}
+
+	pidIndex, exists = processTree.PidToIndexMap[processTree.DisplayOptions.HighlightPID]
+	if !exists {
+		processTree.Logger.Debug(fmt.Sprintf("PID %d to highlight is not part of the tree", processTree.DisplayOptions.HighlightPID))
+		return
+	}
+
+	for pidIndex != -1 {
+		processTree.Nodes[pidIndex].IsCurrentOrAncestor = true
+		pidIndex = processTree.Nodes[pidIndex].Parent
+	}
+}
+
+// ComputeCumulative sums the CPU and memory usage of every subtree into its root process.
+// The tree is walked bottom-up from each top-level process, setting CumulativeCPU and
+// CumulativeRSS on each node to its own usage plus that of all of its descendants. It must
+// be called before DropUnmarked so that hidden descendants still contribute to the totals.
+func (processTree *ProcessTree) ComputeCumulative() {
+	processTree.Logger.Debug("Entering processTree.ComputeCumulative()")
+	for pidIndex := range processTree.Nodes {
+		if processTree.Nodes[pidIndex].Parent == -1 {
+			processTree.sumSubtree(pidIndex)
+		}
+	}
+}
+
+// sumSubtree recursively computes the cumulative CPU and memory usage of a process.
+//
+// Parameters:
+//   - pidIndex: Index of the process at the top of the subtree
+//
+// Returns:
+//   - float64: CPU usage percentage of the subtree
+//   - uint64: RSS memory usage of the subtree
+func (processTree *ProcessTree) sumSubtree(pidIndex int) (float64, uint64) {
+	var (
+		childCPU      float64
+		childPidIndex int
+		childRSS      uint64
+		node          *Process
+	)
+
+	node = processTree.Nodes[pidIndex]
+	node.CumulativeCPU = 0
+	node.CumulativeRSS = 0
+	// Thread nodes would count the usage of their process twice
+	if !node.IsThread {
+		node.CumulativeCPU = max(processTree.cpuPercentOf(node), 0)
+		node.CumulativeRSS = processTree.memoryValue(node)
+	}
+
+	childPidIndex = node.Child
+	for childPidIndex != -1 {
+		childCPU, childRSS = processTree.sumSubtree(childPidIndex)
+		node.CumulativeCPU += childCPU
+		node.CumulativeRSS += childRSS
+		childPidIndex = processTree.Nodes[childPidIndex].Sister
+	}
+
+	return node.CumulativeCPU, node.CumulativeRSS
+}
+
 //------------------------------------------------------------------------------
 // DEBUGGING UTILITIES
 //------------------------------------------------------------------------------
 // Functions in this section provide debugging capabilities for the process tree.
 
+// HasPrintable determines whether any process is marked for display.
+//
+// Returns:
+//   - bool: true if at least one process is marked for display, false if the filters matched nothing
+func (processTree *ProcessTree) HasPrintable() bool {
+	for _, node := range processTree.Nodes {
+		if node.Print {
+			return true
+		}
+	}
+	return false
+}
+
 // ShowPrintable logs all processes that are marked for display.
 //
 // This method is primarily used for debugging purposes. It iterates through all nodes
@@ -382,7 +649,7 @@ func (processTree *ProcessTree) MarkUIDTransitions() {
 func (processTree *ProcessTree) ShowPrintable() {
 	for i := range processTree.Nodes {
 		if processTree.Nodes[i].Print {
-			fmt.Printf("PID %d is printable\n", processTree.IndexToPidMap[i])
+			fmt.Fprintf(processTree.Output, "PID %d is printable\n", processTree.IndexToPidMap[i])
 		}
 	}
 }
@@ -424,61 +691,47 @@ func (processTree *ProcessTree) buildLinePrefix(head string, pidIndex int) strin
 	builder.WriteString(processTree.TreeChars.SG)
 	builder.WriteString(head)
 
-	if processTree.Nodes[pidIndex].PID == 1 {
-		// This is a worakround
+	if head == "" {
+		// Top-level roots (each process without a collected parent, or each --pid root) are drawn with a leading branch
 		builder.WriteString(processTree.TreeChars.P)
-		if processTree.DisplayOptions.ShowPGLs {
-			builder.WriteString(processTree.TreeChars.PGL)
-		} else {
-			builder.WriteString(processTree.TreeChars.NPGL)
-		}
+		builder.WriteString(processTree.leaderMarker(pidIndex, hasPGID(processTree.Nodes[pidIndex])))
 		builder.WriteString(processTree.TreeChars.EG)
 		return builder.String()
 	}
 
-	if head == "" {
-		return ""
-	} else {
-		// Check if this process has a visible sibling
-		hasVisibleSibling := false
-		sibling := processTree.Nodes[pidIndex].Sister
-
-		// In compact mode, we need to check if all siblings are going to be skipped
-		if processTree.DisplayOptions.CompactMode {
-			for sibling != -1 {
-				if !ShouldSkipProcess(sibling) {
-					hasVisibleSibling = true
-					break
-				}
-				sibling = processTree.Nodes[sibling].Sister
+	// Check if this process has a visible sibling
+	hasVisibleSibling := false
+	sibling := processTree.Nodes[pidIndex].Sister
+
+	// In compact mode, we need to check if all siblings are going to be skipped
+	if processTree.DisplayOptions.CompactMode {
+		for sibling != -1 {
+			if !ShouldSkipProcess(sibling) {
+				hasVisibleSibling = true
+				break
 			}
-		} else {
-			// In normal mode, just check if there's a sibling
-			hasVisibleSibling = (sibling != -1)
+			sibling = processTree.Nodes[sibling].Sister
 		}
+	} else {
+		// In normal mode, just check if there's a sibling
+		hasVisibleSibling = (sibling != -1)
+	}
 
-		if hasVisibleSibling {
-			builder.WriteString(processTree.TreeChars.BarC) // T-connector for processes with visible siblings
-		} else {
-			builder.WriteString(processTree.TreeChars.BarL) // L-connector for processes without visible siblings (last child)
-		}
+	if hasVisibleSibling {
+		builder.WriteString(processTree.TreeChars.BarC) // T-connector for processes with visible siblings
+	} else {
+		builder.WriteString(processTree.TreeChars.BarL) // L-connector for processes without visible siblings (last child)
 	}
 
-	if processTree.Nodes[pidIndex].Child != -1 && processTree.AtDepth < processTree.DisplayOptions.MaxDepth {
+	// A collapsed chain branches off to the children of its deepest process, see chainEnd
+	last, length := processTree.chainEnd(pidIndex)
+	if processTree.Nodes[last].Child != -1 && processTree.AtDepth+length < processTree.DisplayOptions.MaxDepth {
 		builder.WriteString(processTree.TreeChars.P)
 	} else {
 		builder.WriteString(processTree.TreeChars.S2)
 	}
 
-	if processTree.Nodes[pidIndex].PID == processTree.Nodes[pidIndex].PGID {
-		if !processTree.DisplayOptions.ShowPGLs {
-			builder.WriteString(processTree.TreeChars.NPGL)
-		} else {
-			builder.WriteString(processTree.TreeChars.PGL)
-		}
-	} else {
-		builder.WriteString(processTree.TreeChars.NPGL)
-	}
+	builder.WriteString(processTree.leaderMarker(pidIndex, processTree.Nodes[pidIndex].PID == processTree.Nodes[pidIndex].PGID))
 	builder.WriteString(processTree.TreeChars.EG)
 
 	// Return the completed string
@@ -486,14 +739,7 @@ func (processTree *ProcessTree) buildLinePrefix(head string, pidIndex int) strin
 }
 
 func (processTree *ProcessTree) getProcessGroup(pidIndex int) (*ProcessGroup, bool) {
-	p := processTree.Nodes[pidIndex]
-
-	parentGroups, ok := processTree.ProcessGroups[p.PPID]
-	if !ok {
-		return nil, false
-	}
-
-	group, ok := parentGroups[p.Signature][p.Username]
+	group, ok := processTree.ProcessGroups[processGroupKey(processTree.Nodes[pidIndex])]
 	if !ok {
 		return nil, false
 	}
@@ -519,6 +765,21 @@ func (processTree *ProcessTree) getProcessGroup(pidIndex int) (*ProcessGroup, bo
 // - formatCommandInfo: Format command and arguments
 // - formatOwnerInfo: Format username and UID transition information
 func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string {
+	lineStart, lineItemMap := processTree.buildChainItems(head, pidIndex)
+	return lineStart + joinLineItems(lineItemMap, processTree.lineItemOrder())
+}
+
+// buildLineItems builds the parts of the line of a process like buildLineItem, without joining
+// them, so --align can pad them to common widths.
+//
+// Parameters:
+//   - head: The accumulated prefix string from parent levels
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - string: The tree prefix followed by a space, or the whole line for the orphans and container nodes
+//   - map[string]string: The formatted items of the line by name, see joinLineItems, nil for the orphans and container nodes
+func (processTree *ProcessTree) buildLineItems(head string, pidIndex int) (string, map[string]string) {
 
 	processTree.Logger.Debug(fmt.Sprintf("processTree.buildLineItem(head=\"%s\", pidIndex=%d, atDepth=%d)", head, pidIndex, processTree.AtDepth))
 	var (
@@ -526,18 +787,23 @@ func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string
 		args            string
 		commandStr      string
 		compactStr      string
+		connections     string
 		connector       string
 		cpuPercent      string
+		cpuTime         string
+		cwd             string
+		env             string
+		fds             string
+		ioCounters      string
+		isThread        bool
+		pageFaults      string
 		lineItemMap     map[string]string
 		linePrefix      string
 		memoryUsage     string
 		owner           string
 		ownerTransition string
-		pgidString      string
-		pidPgidSlice    []string
 		pidPgidString   string
-		pidString       string
-		ppidString      string
+		status          string
 		threads         string
 	)
 
@@ -545,6 +811,9 @@ func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string
 	// put into the builder later
 	lineItemMap = make(map[string]string)
 
+	// Threads have no usage values of their own, they are accounted for by their process
+	isThread = processTree.Nodes[pidIndex].IsThread
+
 	// Create a strings.Builder with an estimated capacity
 	// This helps avoid reallocations as the builder grows
 	var builder strings.Builder
@@ -560,65 +829,162 @@ func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string
 	builder.WriteString(linePrefix)
 	builder.WriteString(" ")
 
-	if processTree.DisplayOptions.ShowPIDs {
-		pidString = util.Int32toStr(processTree.Nodes[pidIndex].PID)
-		pidPgidSlice = append(pidPgidSlice, pidString)
-	}
-
-	if processTree.DisplayOptions.ShowPPIDs {
-		ppidString = util.Int32toStr(processTree.Nodes[pidIndex].PPID)
-		pidPgidSlice = append(pidPgidSlice, ppidString)
-	}
-
-	if processTree.DisplayOptions.ShowPGIDs {
-		pgidString = util.Int32toStr(processTree.Nodes[pidIndex].PGID)
-		pidPgidSlice = append(pidPgidSlice, pgidString)
+	// The orphans and container nodes are not processes, so there is nothing to show but their name
+	if isSyntheticNode(processTree.Nodes[pidIndex]) {
+		commandStr = processTree.Nodes[pidIndex].Command
+		processTree.colorizeField("command", &commandStr, pidIndex)
+		builder.WriteString(commandStr)
+		return builder.String(), nil
 	}
 
-	if len(pidPgidSlice) > 0 {
-		pidPgidString = fmt.Sprintf("(%s)", strings.Join(pidPgidSlice, ","))
+	pidPgidString = processTree.formatIdentity(processTree.Nodes[pidIndex])
+	if pidPgidString != "" {
 		processTree.colorizeField("pidPgid", &pidPgidString, pidIndex)
 		lineItemMap["pidPgid"] = pidPgidString
 	}
 
 	if processTree.DisplayOptions.ShowOwner {
-		owner = processTree.Nodes[pidIndex].Username
+		owner = processTree.ownerName(processTree.Nodes[pidIndex])
 		processTree.colorizeField("owner", &owner, pidIndex)
 		lineItemMap["owner"] = owner
 	}
 
+	if processTree.DisplayOptions.ShowParent && !isThread {
+		if parent := processTree.formatParentField(processTree.Nodes[pidIndex]); parent != "" {
+			processTree.colorizeField("parent", &parent, pidIndex)
+			lineItemMap["parent"] = parent
+		}
+	}
+
 	if processTree.DisplayOptions.ShowProcessAge {
 		ageString = processTree.durationFromProcessAge(processTree.Nodes[pidIndex].Age)
 		processTree.colorizeField("age", &ageString, pidIndex)
 		lineItemMap["age"] = ageString
 	}
 
-	if processTree.DisplayOptions.ShowCpuPercent {
-		cpuPercent = fmt.Sprintf("(c:%.2f%%)", processTree.Nodes[pidIndex].CPUPercent)
+	if processTree.DisplayOptions.ShowStartTime {
+		startTime := processTree.formatStartTime(processTree.Nodes[pidIndex].CreateTime, processTree.Nodes[pidIndex].Age)
+		processTree.colorizeField("age", &startTime, pidIndex)
+		lineItemMap["start"] = startTime
+	}
+
+	if processTree.DisplayOptions.ShowCpuPercent && !isThread {
+		cpuPercent = processTree.formatCPUPercent(processTree.cpuPercentOf(processTree.Nodes[pidIndex]), processTree.Nodes[pidIndex].CumulativeCPU)
 		processTree.colorizeField("cpu", &cpuPercent, pidIndex)
 		lineItemMap["cpu"] = cpuPercent
 	}
 
-	if processTree.DisplayOptions.ShowMemoryUsage {
-		memoryUsage = fmt.Sprintf("(m:%s)", util.ByteConverter(processTree.Nodes[pidIndex].MemoryInfo.RSS))
+	if processTree.DisplayOptions.ShowCpuTime && !isThread {
+		cpuTime = formatCPUTimeField(CPUTime(processTree.Nodes[pidIndex]))
+		processTree.colorizeField("cputime", &cpuTime, pidIndex)
+		lineItemMap["cputime"] = cpuTime
+	}
+
+	if processTree.DisplayOptions.ShowMemoryUsage && !isThread {
+		memoryUsage = processTree.formatMemory(processTree.memoryValue(processTree.Nodes[pidIndex]), processTree.Nodes[pidIndex].HasMemory, processTree.memoryPercent(processTree.Nodes[pidIndex]), processTree.Nodes[pidIndex].CumulativeRSS)
 		processTree.colorizeField("memory", &memoryUsage, pidIndex)
 		lineItemMap["memory"] = memoryUsage
 	}
 
-	if processTree.DisplayOptions.ShowNumThreads {
+	if processTree.DisplayOptions.ShowDiff && !isThread {
+		if diff := processTree.formatDiffField(processTree.Nodes[pidIndex]); diff != "" {
+			processTree.colorizeField("diff", &diff, pidIndex)
+			lineItemMap["diff"] = diff
+		}
+	}
+
+	if processTree.DisplayOptions.ShowNumThreads && !isThread {
 		threads = fmt.Sprintf("(t:%d)", processTree.Nodes[pidIndex].NumThreads)
 		processTree.colorizeField("threads", &threads, pidIndex)
 		lineItemMap["threads"] = threads
 	}
 
+	if processTree.DisplayOptions.ShowNice && !isThread {
+		lineItemMap["nice"] = processTree.niceField(addNice(nil, processTree.Nodes[pidIndex].Nice), pidIndex)
+	}
+
+	if processTree.DisplayOptions.ShowNumFDs && !isThread {
+		fds = formatNumFDs(processTree.Nodes[pidIndex].NumFDs)
+		processTree.colorizeField("fds", &fds, pidIndex)
+		lineItemMap["fds"] = fds
+	}
+
+	if processTree.DisplayOptions.ShowIO && !isThread {
+		ioCounters = formatIOField(processTree.Nodes[pidIndex].IOCounters)
+		processTree.colorizeField("io", &ioCounters, pidIndex)
+		lineItemMap["io"] = ioCounters
+	}
+
+	if processTree.DisplayOptions.PageFaults != "" && !isThread {
+		pageFaults = processTree.formatPageFaultsField(processTree.Nodes[pidIndex].PageFaults)
+		processTree.colorizeField("faults", &pageFaults, pidIndex)
+		lineItemMap["faults"] = pageFaults
+	}
+
+	if processTree.DisplayOptions.ShowStatus && !isThread {
+		status = fmt.Sprintf("(s:%s)", StatusLetter(processTree.Nodes[pidIndex].Status))
+		processTree.colorizeField("status", &status, pidIndex)
+		lineItemMap["status"] = status
+	}
+
+	if processTree.DisplayOptions.ShowSched && !isThread {
+		sched := formatSchedField(nil)
+		if processTree.Nodes[pidIndex].SchedPolicy != "" {
+			sched = formatSchedField([]string{processTree.Nodes[pidIndex].SchedPolicy})
+		}
+		processTree.colorizeField("sched", &sched, pidIndex)
+		lineItemMap["sched"] = sched
+	}
+
+	if processTree.DisplayOptions.ShowConnections && !isThread {
+		if processTree.Nodes[pidIndex].ConnectionsUnavailable {
+			connections = "(conn: ?)"
+		} else if summary := FormatConnections(processTree.Nodes[pidIndex].Connections); summary != "" {
+			connections = fmt.Sprintf("(%s)", summary)
+		}
+		if connections != "" {
+			processTree.colorizeField("connections", &connections, pidIndex)
+			lineItemMap["connections"] = connections
+		}
+	}
+
+	if processTree.DisplayOptions.ShowEnv && !isThread {
+		if variable, ok := processTree.envMatch(processTree.Nodes[pidIndex]); ok {
+			env = fmt.Sprintf("(env: %s)", variable)
+			processTree.colorizeField("env", &env, pidIndex)
+			lineItemMap["env"] = env
+		}
+	}
+
+	if processTree.DisplayOptions.ShowCwd && !isThread {
+		cwd = formatCwdField(processTree.Nodes[pidIndex].Cwd, 0)
+		processTree.colorizeField("cwd", &cwd, pidIndex)
+		lineItemMap["cwd"] = cwd
+	}
+
+	if processTree.DisplayOptions.ShowContainers && !isThread && processTree.Nodes[pidIndex].ContainerID != "" {
+		container := formatContainerField(processTree.Nodes[pidIndex].ContainerID)
+		processTree.colorizeField("container", &container, pidIndex)
+		lineItemMap["container"] = container
+	}
+
+	if processTree.DisplayOptions.ShowNamespaces && !isThread {
+		if namespaces := processTree.formatNamespaceField(processTree.Nodes[pidIndex]); namespaces != "" {
+			processTree.colorizeField("ns", &namespaces, pidIndex)
+			lineItemMap["ns"] = namespaces
+		}
+	}
+
 	if processTree.DisplayOptions.ShowUIDTransitions && processTree.Nodes[pidIndex].HasUIDTransition {
 		// Add UID transition notation {parentUID→currentUID}
 		if len(processTree.Nodes[pidIndex].UIDs) > 0 {
 			ownerTransition = fmt.Sprintf("(%d→%d)", processTree.Nodes[pidIndex].ParentUID, processTree.Nodes[pidIndex].UIDs[0])
 		}
 	} else if processTree.DisplayOptions.ShowUserTransitions && processTree.Nodes[pidIndex].HasUIDTransition {
-		// Add user transition notation {parentUser→currentUser}
-		if processTree.Nodes[pidIndex].ParentUsername != "" {
+		// Add user transition notation {parentUser→currentUser}, or {parentUID→currentUID} with --numeric
+		if processTree.DisplayOptions.Numeric && len(processTree.Nodes[pidIndex].UIDs) > 0 {
+			ownerTransition = fmt.Sprintf("(%d→%d)", processTree.Nodes[pidIndex].ParentUID, processTree.Nodes[pidIndex].UIDs[0])
+		} else if processTree.Nodes[pidIndex].ParentUsername != "" {
 			ownerTransition = fmt.Sprintf("(%s→%s)", processTree.Nodes[pidIndex].ParentUsername, processTree.Nodes[pidIndex].Username)
 		}
 	}
@@ -628,8 +994,11 @@ func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string
 		lineItemMap["ownerTransition"] = ownerTransition
 	}
 
-	// Get the command - use full path when compact mode is disabled
-	commandStr = processTree.Nodes[pidIndex].Command
+	// Get the command, shortened to its basename unless --command-format=full is used
+	commandStr = FormatCommand(processTree.commandName(processTree.Nodes[pidIndex]), processTree.DisplayOptions.CommandFormat)
+	if processTree.Nodes[pidIndex].IsCurrentOrAncestor {
+		processTree.highlightField(&commandStr)
+	}
 
 	// In compact mode, format the command with count for the first process in a group
 	if processTree.DisplayOptions.CompactMode {
@@ -639,7 +1008,7 @@ func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string
 		// If there are multiple identical processes, format with count
 		if count > 1 {
 			// Format in Linux pstree style
-			compactStr = FormatCompactOutput(commandStr, count, groupPIDs, processTree.DisplayOptions.ShowPIDs)
+			compactStr = FormatCompactOutput(commandStr, count, groupPIDs, processTree.DisplayOptions.ShowPIDs, processTree.DisplayOptions.CommandFormat)
 
 			if compactStr != "" {
 				if processTree.DisplayOptions.ShowProcessAge {
@@ -648,24 +1017,109 @@ func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string
 					lineItemMap["age"] = fmt.Sprintf("%s", ageString)
 				}
 
-				if processTree.DisplayOptions.ShowCpuPercent {
-					cpuPercentStr := fmt.Sprintf("(c:%.2f%%)", cpuPercent)
+				if group, ok := processTree.getProcessGroup(pidIndex); ok && processTree.DisplayOptions.ShowStartTime {
+					startTime := processTree.formatStartTime(group.CreateTime, processAge)
+					processTree.colorizeField("age", &startTime, pidIndex)
+					lineItemMap["start"] = startTime
+				}
+
+				if processTree.DisplayOptions.ShowCpuPercent && !isThread {
+					var cumulativeCPU float64
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						cumulativeCPU = group.CumulativeCPU
+					}
+					cpuPercentStr := processTree.formatCPUPercent(cpuPercent, cumulativeCPU)
 					processTree.colorizeField("cpu", &cpuPercentStr, pidIndex)
 					lineItemMap["cpu"] = cpuPercentStr
 				}
 
-				if processTree.DisplayOptions.ShowMemoryUsage {
-					memoryUsageStr := fmt.Sprintf("(m:%s)", util.ByteConverter(memoryUsage))
+				if processTree.DisplayOptions.ShowCpuTime && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						cpuTimeStr := formatCPUTimeField(group.CPUTime)
+						processTree.colorizeField("cputime", &cpuTimeStr, pidIndex)
+						lineItemMap["cputime"] = cpuTimeStr
+					}
+				}
+
+				if processTree.DisplayOptions.ShowMemoryUsage && !isThread {
+					var (
+						cumulativeRSS uint64
+						hasMemory     bool
+					)
+					memoryPercent := processTree.bytesPercent(memoryUsage)
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						cumulativeRSS = group.CumulativeRSS
+						hasMemory = group.HasMemory
+						memoryPercent = group.MemoryPercent
+					}
+					memoryUsageStr := processTree.formatMemory(memoryUsage, hasMemory, memoryPercent, cumulativeRSS)
 					processTree.colorizeField("memory", &memoryUsageStr, pidIndex)
 					lineItemMap["memory"] = memoryUsageStr
 				}
 
-				if processTree.DisplayOptions.ShowNumThreads {
+				if processTree.DisplayOptions.ShowDiff && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						// The changes of the members may cancel each other out
+						delete(lineItemMap, "diff")
+						if diff := processTree.formatDiffChange(processTree.Nodes[pidIndex].DiffState, group.DiffCPUPercent, group.DiffRSS); diff != "" {
+							processTree.colorizeField("diff", &diff, pidIndex)
+							lineItemMap["diff"] = diff
+						}
+					}
+				}
+
+				if processTree.DisplayOptions.ShowNumThreads && !isThread {
 					numThreadsStr := fmt.Sprintf("(t:%d)", numThreads)
 					processTree.colorizeField("threads", &numThreadsStr, pidIndex)
 					lineItemMap["threads"] = numThreadsStr
 				}
 
+				if processTree.DisplayOptions.ShowNice && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						lineItemMap["nice"] = processTree.niceField(group.Nice, pidIndex)
+					}
+				}
+
+				if processTree.DisplayOptions.ShowNumFDs && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						numFDsStr := formatNumFDs(group.NumFDs)
+						processTree.colorizeField("fds", &numFDsStr, pidIndex)
+						lineItemMap["fds"] = numFDsStr
+					}
+				}
+
+				if processTree.DisplayOptions.ShowIO && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						ioCountersStr := formatIOField(group.IOCounters)
+						processTree.colorizeField("io", &ioCountersStr, pidIndex)
+						lineItemMap["io"] = ioCountersStr
+					}
+				}
+
+				if processTree.DisplayOptions.PageFaults != "" && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						pageFaultsStr := processTree.formatPageFaultsField(group.PageFaults)
+						processTree.colorizeField("faults", &pageFaultsStr, pidIndex)
+						lineItemMap["faults"] = pageFaultsStr
+					}
+				}
+
+				if processTree.DisplayOptions.ShowStatus && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						statesStr := fmt.Sprintf("(s:%s)", strings.Join(group.States, ","))
+						processTree.colorizeField("status", &statesStr, pidIndex)
+						lineItemMap["status"] = statesStr
+					}
+				}
+
+				if processTree.DisplayOptions.ShowSched && !isThread {
+					if group, ok := processTree.getProcessGroup(pidIndex); ok {
+						schedStr := formatSchedField(group.SchedPolicies)
+						processTree.colorizeField("sched", &schedStr, pidIndex)
+						lineItemMap["sched"] = schedStr
+					}
+				}
+
 				// Create the connector string
 				connector = "───"
 
@@ -684,19 +1138,62 @@ func (processTree *ProcessTree) buildLineItem(head string, pidIndex int) string
 		}
 	}
 
+	if processTree.Nodes[pidIndex].IsOrphan {
+		lineItemMap["orphan"] = processTree.DisplayOptions.OrphanSymbol
+	}
+
+	if processTree.Nodes[pidIndex].IsPrivileged {
+		lineItemMap["privileged"] = PrivilegedMarker
+	}
+
+	processTree.flagZombie(&commandStr, pidIndex)
+	processTree.flagDeleted(&commandStr, pidIndex)
+	processTree.flagDiff(&commandStr, pidIndex)
+	processTree.highlightMatch(&commandStr, pidIndex)
 	processTree.colorizeField("command", &commandStr, pidIndex)
 	lineItemMap["command"] = commandStr
 
 	// Now convert the map to a builder
 	if processTree.DisplayOptions.ShowArguments {
-		if len(processTree.Nodes[pidIndex].Args) > 0 {
-			args = strings.Join(processTree.Nodes[pidIndex].Args, " ")
+		// The arguments are trimmed here, before the line is truncated to the screen width
+		args = processTree.formatArgs(processTree.Nodes[pidIndex])
+		if args != "" {
 			processTree.colorizeField("args", &args, pidIndex)
 			lineItemMap["args"] = args
 		}
 	}
 
-	keys := []string{"pidPgid", "owner", "age", "cpu", "memory", "threads", "ownerTransition", "command", "args"}
+	lineStart := builder.String()
+
+	// A working directory too long for the line is shortened instead of cutting off the command
+	if cwd != "" && processTree.Nodes[pidIndex].Cwd != "" && !processTree.DisplayOptions.WideDisplay && !processTree.DisplayOptions.WrapLines {
+		overflow := util.VisibleWidth(processTree.depthLabel(pidIndex)+lineStart+joinLineItems(lineItemMap, processTree.lineItemOrder())) - processTree.DisplayOptions.ScreenWidth
+		if overflow > 0 {
+			cwd = formatCwdField(processTree.Nodes[pidIndex].Cwd, util.VisibleWidth(processTree.Nodes[pidIndex].Cwd)-overflow)
+			processTree.colorizeField("cwd", &cwd, pidIndex)
+			lineItemMap["cwd"] = cwd
+		}
+	}
+
+	return lineStart, lineItemMap
+}
+
+// lineItemKeys are the names of the items of a line, in display order, see joinLineItems.
+var lineItemKeys = []string{"pidPgid", "owner", "age", "start", "cpu", "cputime", "memory", "diff", "threads", "nice", "fds", "io", "faults", "status", "sched", "connections", "env", "cwd", "container", "ns", "parent", "ownerTransition", "orphan", "privileged", "command", "args"}
+
+// joinLineItems joins the items of a line in display order, separated by spaces.
+//
+// Parameters:
+//   - lineItemMap: The formatted items of the line by name, e.g., "cpu" or "command"
+//   - keys: The names of the items in display order, see lineItemOrder
+//
+// Returns:
+//   - string: The items that are present, joined in display order
+func joinLineItems(lineItemMap map[string]string, keys []string) string {
+	var (
+		builder strings.Builder
+	)
+
 	for idx, key := range keys {
 		value, ok := lineItemMap[key]
 		if ok {
@@ -753,42 +1250,129 @@ func (processTree *ProcessTree) buildNewHead(head string, pidIndex int) string {
 // Functions in this section handle the recursive traversal of the process tree
 // and the display of processes with their relationships.
 
+// RootIndices returns the indices of the processes the tree should be printed from.
+//
+// Without --pid every process whose parent was not collected becomes a top-level tree, so
+// nothing is assumed about PID 1 being present, as is the case in containers and restricted
+// environments. Otherwise each requested PID becomes its own top-level tree, printed first with
+// its descendants below it, see keepRoots. In both cases the roots are ordered by PID. Requested
+// PIDs that don't exist are reported via the logger and skipped, so the remaining trees are
+// still printed. With --no-root-line,
+// each requested PID is replaced by its displayed children, which may leave no roots at all.
+//
+// Returns:
+//   - []int: Indices of the root processes in the Nodes array
+//   - error: An error if none of the requested PIDs exist, e.g., PID 500 not found, or the --parents-of PID doesn't exist
+func (processTree *ProcessTree) RootIndices() ([]int, error) {
+	var (
+		ok        bool
+		pid       int32
+		pidIndex  int
+		rootIndex []int
+		rootPIDs  []int32
+	)
+
+	if processTree.DisplayOptions.ParentsOf != 0 {
+		if _, ok = processTree.PidToIndexMap[processTree.DisplayOptions.ParentsOf]; !ok {
+			return nil, fmt.Errorf("the PID given with --parents-of does not exist: %d", processTree.DisplayOptions.ParentsOf)
+		}
+	}
+
+	if len(processTree.RootPIDs) == 0 && processTree.hasRootCommand() {
+		return nil, processTree.rootCommandError()
+	}
+
+	if len(processTree.RootPIDs) == 0 {
+		for pidIndex = range processTree.Nodes {
+			if processTree.Nodes[pidIndex].Parent == -1 {
+				rootIndex = append(rootIndex, pidIndex)
+			}
+		}
+		// The container nodes sort after the trees of the host, in the order of their IDs, and the
+		// orphans node sorts last, after the trees it doesn't belong to
+		slices.SortFunc(rootIndex, func(i, j int) int {
+			if result := cmp.Compare(rootRank(processTree.Nodes[i]), rootRank(processTree.Nodes[j])); result != 0 {
+				return result
+			}
+			if isSyntheticNode(processTree.Nodes[i]) {
+				return cmp.Compare(processTree.Nodes[i].ContainerID, processTree.Nodes[j].ContainerID)
+			}
+			return cmp.Compare(processTree.Nodes[i].PID, processTree.Nodes[j].PID)
+		})
+		return rootIndex, nil
+	}
+
+	rootPIDs = slices.Clone(processTree.RootPIDs)
+	slices.Sort(rootPIDs)
+	rootPIDs = slices.Compact(rootPIDs)
+
+	for _, pid = range rootPIDs {
+		pidIndex, ok = processTree.PidToIndexMap[pid]
+		if !ok {
+			processTree.Logger.Warn(fmt.Sprintf("PID %d not found, skipping", pid))
+			continue
+		}
+		rootIndex = append(rootIndex, pidIndex)
+	}
+
+	if len(rootIndex) == 0 {
+		return nil, notFoundError(rootPIDs)
+	}
+
+	if processTree.DisplayOptions.NoRootLine {
+		return processTree.rootChildren(rootIndex), nil
+	}
+	return rootIndex, nil
+}
+
 // PrintTree recursively prints a process tree with customizable formatting options.
 //
 // This function displays a process and all its children in a tree-like structure,
 // with various display options such as process age, CPU usage, memory usage, etc.
 // The tree is formatted using different graphical styles based on the display options.
 //
+// Each line is written as soon as it is built, except with --align, see printAligned, and the
+// identity fields are padded to a common width computed beforehand, unless --stream is used, see
+// initStream. Printing stops at the first line that cannot be written, and the error is returned
+// up the recursion; when the reader of the output went away, e.g., pstree | head, it is
+// ErrBrokenPipe.
+//
 // Parameters:
 //   - pidIndex: Index of the current process to print
 //   - head: String representing the indentation and tree structure for the current line
 //
+// Returns:
+//   - error: ErrBrokenPipe if the reader of the output went away, or any other error encountered while writing a line
+//
 // Refactoring opportunity: This function could be split into:
 // - printCurrentNode: Print just the current node
 // - printChildNodes: Handle the recursive printing of child nodes
-func (processTree *ProcessTree) PrintTree(pidIndex int, head string) {
+func (processTree *ProcessTree) PrintTree(pidIndex int, head string) error {
 	processTree.Logger.Debug(fmt.Sprintf("Entering processTree.PrintTree() with %d nodes", len(processTree.Nodes)))
 	processTree.Logger.Debug(fmt.Sprintf("processTree.PrintTree(pidIndex=%d, head=\"%s\", atDepth=%d)", pidIndex, head, processTree.AtDepth))
 	// https://github.com/FredHucht/pstree/blob/main/pstree.c#L721-L777
 	// Skip if we've reached the maximum depth
 	if processTree.DisplayOptions.MaxDepth > 0 && processTree.AtDepth > processTree.DisplayOptions.MaxDepth {
 		processTree.Logger.Debug(fmt.Sprintf("Skipping process %d at depth %d (max depth %d)", processTree.Nodes[pidIndex].PID, processTree.AtDepth, processTree.DisplayOptions.MaxDepth))
-		return
+		return nil
 	}
 
-	// Initialize compact mode if enabled and at the root level
-	if processTree.AtDepth == 0 {
+	// Initialize compact mode if enabled and at the root level, see initStream for --stream
+	if processTree.AtDepth == 0 && processTree.DisplayOptions.Stream {
+		processTree.initStream()
+	} else if processTree.AtDepth == 0 {
 		// Always initialize compact mode to identify duplicates
 		// But we'll respect the CompactMode flag when displaying
 		processTree.Logger.Debug("Initializing compact mode")
 		processTree.InitCompactMode()
+		processTree.computeIdentityWidths()
 	}
 
 	// Skip this process if it's been marked as a duplicate in compact mode
 	// Only skip if compact mode is actually enabled
 	if processTree.DisplayOptions.CompactMode && ShouldSkipProcess(pidIndex) {
 		processTree.Logger.Debug(fmt.Sprintf("Skipping process %d in compact mode", processTree.Nodes[pidIndex].PID))
-		return
+		return nil
 	}
 
 	var (
@@ -798,48 +1382,161 @@ func (processTree *ProcessTree) PrintTree(pidIndex int, head string) {
 
 	if processTree.AtDepth > processTree.DisplayOptions.MaxDepth {
 		processTree.Logger.Debug(fmt.Sprintf("Skipping process %d at depth %d (max depth %d)", processTree.Nodes[pidIndex].PID, processTree.AtDepth, processTree.DisplayOptions.MaxDepth))
-		return
+		return nil
 	}
 
 	if head == "" && !processTree.Nodes[pidIndex].Print {
 		processTree.Logger.Debug(fmt.Sprintf("Skipping process %d because head is empty and Print is false", processTree.Nodes[pidIndex].PID))
-		return
+		return nil
 	}
 
-	line = processTree.buildLineItem(head, pidIndex)
+	newHead = processTree.buildNewHead(head, pidIndex)
 
-	if !processTree.DisplayOptions.WideDisplay {
-		if len(line) > processTree.DisplayOptions.ScreenWidth {
-			if processTree.DisplayOptions.RainbowOutput {
-				line = processTree.truncateANSI(gorainbow.Rainbow(line))
-			} else {
-				line = processTree.truncateANSI(line)
-			}
-		} else {
-			if processTree.DisplayOptions.RainbowOutput {
-				line = gorainbow.Rainbow(line)
-			}
+	// A prefix too deep for the screen is only folded on the line of the process, see foldHead
+	lineHead, lineNewHead := head, newHead
+	if folded := processTree.foldHead(head, pidIndex); folded != head {
+		lineHead, lineNewHead = folded, processTree.buildNewHead(folded, pidIndex)
+	}
+
+	// With --align, the lines of the tree are only printed once all of them are known, see printAligned
+	if processTree.DisplayOptions.AlignColumns && !processTree.DisplayOptions.Stream {
+		if processTree.AtDepth == 0 {
+			processTree.alignedLines = nil
+			defer func() { processTree.alignedLines = nil }()
 		}
+		lineStart, lineItemMap := processTree.buildChainItems(lineHead, pidIndex)
+		processTree.alignedLines = append(processTree.alignedLines, alignedLine{head: lineHead, items: lineItemMap, newHead: lineNewHead, pidIndex: pidIndex, start: lineStart})
 	} else {
-		if processTree.DisplayOptions.RainbowOutput {
-			line = gorainbow.Rainbow(line)
+		line = processTree.buildLineItem(lineHead, pidIndex)
+		if err := processTree.printLine(line, lineHead, lineNewHead, pidIndex); err != nil {
+			return err
 		}
 	}
 
-	newHead = processTree.buildNewHead(head, pidIndex)
-
-	processTree.Logger.Debug(fmt.Sprintf("processTree.PrintTree(): printing line for node.PID=%d, head=\"%s\"", processTree.Nodes[pidIndex].PID, head))
-	fmt.Fprintln(os.Stdout, line)
-
-	// Iterate over children and determine sibling status
-	childme := processTree.Nodes[pidIndex].Child
+	// Iterate over children and determine sibling status, below the deepest process of a collapsed chain
+	last, length := processTree.chainEnd(pidIndex)
+	childme := processTree.Nodes[last].Child
 	for childme != -1 {
 		nextChild := processTree.Nodes[childme].Sister
-		processTree.AtDepth++
-		processTree.PrintTree(childme, newHead)
-		processTree.AtDepth--
+		processTree.AtDepth += length + 1
+		err := processTree.PrintTree(childme, newHead)
+		processTree.AtDepth -= length + 1
+		if err != nil {
+			return err
+		}
 		childme = nextChild
 	}
+
+	if processTree.DisplayOptions.AlignColumns && !processTree.DisplayOptions.Stream && processTree.AtDepth == 0 {
+		return processTree.printAligned()
+	}
+	return nil
+}
+
+// printLine prints a line of the tree built by buildLineItem, with the depth label of
+// --show-depth, colored with --rainbow, and truncated to the screen width or wrapped with --wrap.
+// The branch characters and the process entry are separate segments, each colored on its own, so
+// --rainbow only colors the entry and the branches keep their color, see splitBranches.
+//
+// Parameters:
+//   - line: The line of the process
+//   - head: The accumulated prefix string from parent levels
+//   - newHead: The prefix of the children of the process, see buildNewHead
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - error: ErrBrokenPipe if the reader of the output went away, or any other error encountered while writing
+func (processTree *ProcessTree) printLine(line string, head string, newHead string, pidIndex int) error {
+	var lines []string
+
+	branches, entry := processTree.splitBranches(line, head, pidIndex)
+	if processTree.DisplayOptions.RainbowOutput {
+		entry = gorainbow.Rainbow(entry)
+	}
+	line = processTree.depthLabel(pidIndex) + branches + entry
+
+	// Lines wider than the screen are truncated, or wrapped with --wrap, measuring only the visible characters
+	lines = []string{line}
+	if !processTree.DisplayOptions.WideDisplay && util.VisibleWidth(line) > processTree.DisplayOptions.ScreenWidth {
+		if processTree.DisplayOptions.WrapLines {
+			lines = processTree.wrapLine(line, head, newHead, pidIndex)
+		} else {
+			lines = []string{processTree.truncateANSI(line)}
+		}
+	}
+
+	processTree.Logger.Debug(fmt.Sprintf("processTree.printLine(): printing line for node.PID=%d, head=\"%s\"", processTree.Nodes[pidIndex].PID, head))
+	for _, line = range lines {
+		if err := processTree.writeLine(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitBranches splits a line of the tree into the branch characters in front of the process and
+// the process entry. The color of the branches, if any, ends with its own reset, so it is part of
+// the first segment.
+//
+// Parameters:
+//   - line: The line of the process, built by buildLineItem
+//   - head: The accumulated prefix string from parent levels
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - string: The branch characters of the line
+//   - string: The process entry, starting with the space after the branches
+func (processTree *ProcessTree) splitBranches(line string, head string, pidIndex int) (string, string) {
+	width := util.VisibleWidth(processTree.buildLinePrefix(head, pidIndex))
+	if width == 0 {
+		return "", line
+	}
+	return splitANSI(line, width)
+}
+
+// RenderString renders the complete tree, once for each root, followed by the depth statistics
+// with --depth-stats and the summary with --summary, and returns it as a string instead of writing it to the output of the tree. Lines are truncated to
+// DisplayOptions.ScreenWidth unless WideDisplay is set, so the result doesn't depend on the
+// terminal the caller runs in.
+//
+// Returns:
+//   - string: The rendered tree
+//   - error: Any error encountered while determining the roots of the tree
+func (processTree *ProcessTree) RenderString() (string, error) {
+	var (
+		builder     strings.Builder
+		err         error
+		output      io.Writer
+		rootIndex   int
+		rootIndices []int
+	)
+
+	rootIndices, err = processTree.RootIndices()
+	if err != nil {
+		return "", err
+	}
+
+	output = processTree.Output
+	processTree.Output = &builder
+	defer func() { processTree.Output = output }()
+
+	for _, rootIndex = range rootIndices {
+		if err = processTree.PrintTree(rootIndex, ""); err != nil {
+			return "", err
+		}
+	}
+	if processTree.DisplayOptions.ShowDepthStats {
+		if err = processTree.PrintDepthStats(); err != nil {
+			return "", err
+		}
+	}
+	if processTree.DisplayOptions.ShowSummary {
+		if err = processTree.PrintSummary(); err != nil {
+			return "", err
+		}
+	}
+
+	return builder.String(), nil
 }
 
 //------------------------------------------------------------------------------
@@ -868,6 +1565,37 @@ func (processTree *ProcessTree) markParents(pidIndex int) {
 	}
 }
 
+// markMatch marks a process matching a filter and its ancestors as printable. When subtree is
+// set, all descendants of the matching process are marked as well, so the full subtree rooted at
+// the match is displayed, the way --contains always does; the other filters only do so with
+// MatchSubtree. Marking is idempotent, so a descendant that matches on its own is simply marked
+// again. The reasons for displaying each process are recorded for highlightMatch.
+//
+// Parameters:
+//   - pidIndex: Index of the matching process
+//   - subtree: Whether to mark the descendants of the matching process
+func (processTree *ProcessTree) markMatch(pidIndex int, subtree bool) {
+	processTree.markParents(pidIndex)
+	if subtree {
+		processTree.markChildren(pidIndex)
+	} else {
+		processTree.Nodes[pidIndex].Print = true
+	}
+	processTree.recordMatch(pidIndex, subtree)
+}
+
+// onTerminal determines whether a process is attached to the terminal requested with --tty.
+// Processes without a controlling terminal, such as daemons, never match a requested terminal.
+//
+// Parameters:
+//   - process: The process to check
+//
+// Returns:
+//   - bool: true if no terminal was requested or the process is attached to it, false otherwise
+func (processTree *ProcessTree) onTerminal(process *Process) bool {
+	return processTree.DisplayOptions.Terminal == "" || (process.Terminal != "" && process.Terminal == processTree.DisplayOptions.Terminal)
+}
+
 // markChildren marks a process and all its child processes as printable.
 // This function recursively traverses down the process tree, marking each child
 // process with Print=true, and continues with any sibling processes.
@@ -950,11 +1678,17 @@ func (processTree *ProcessTree) colorizeField(fieldName string, value *string, p
 			case "connector":
 				processTree.Colorizer.Connector(processTree.ColorScheme, value)
 			case "command":
-				processTree.Colorizer.Command(processTree.ColorScheme, value)
+				if StatusLetter(processTree.Nodes[pidIndex].Status) == "Z" {
+					processTree.Colorizer.StatusZombie(processTree.ColorScheme, value)
+				} else {
+					processTree.Colorizer.Command(processTree.ColorScheme, value)
+				}
 			case "compactStr":
 				processTree.Colorizer.CompactStr(processTree.ColorScheme, value)
-			case "cpu":
+			case "cpu", "cputime":
 				processTree.Colorizer.CPU(processTree.ColorScheme, value)
+			case "fds":
+				processTree.Colorizer.FDs(processTree.ColorScheme, value)
 			case "memory":
 				processTree.Colorizer.Memory(processTree.ColorScheme, value)
 			case "owner":
@@ -965,69 +1699,40 @@ func (processTree *ProcessTree) colorizeField(fieldName string, value *string, p
 				processTree.Colorizer.PIDPGID(processTree.ColorScheme, value)
 			case "prefix":
 				processTree.Colorizer.Prefix(processTree.ColorScheme, value)
+			case "status":
+				if StatusLetter(processTree.Nodes[pidIndex].Status) == "Z" {
+					processTree.Colorizer.StatusZombie(processTree.ColorScheme, value)
+				} else {
+					processTree.Colorizer.Status(processTree.ColorScheme, value)
+				}
 			case "threads":
 				processTree.Colorizer.NumThreads(processTree.ColorScheme, value)
 			}
 		} else if processTree.DisplayOptions.ColorAttr != "" {
 			// Attribute-based colorization mode (--color flag)
-			// Don't apply attribute-based coloring to the tree prefix
+			// Don't apply attribute-based coloring to the tree prefix, it keeps the color of the branches
 			if fieldName != "prefix" {
 				process = processTree.Nodes[pidIndex]
-				switch processTree.DisplayOptions.ColorAttr {
-				case "age":
-					// Ensure process age is shown when coloring by age
-					processTree.DisplayOptions.ShowProcessAge = true
-
-					// Apply color based on process age thresholds in seconds
-					if process.Age < 60 {
-						// Low age (< 1 minute)
-						processTree.Colorizer.ProcessAgeLow(processTree.ColorScheme, value)
-					} else if process.Age >= 60 && process.Age < 3600 {
-						// Medium age (< 1 hour)
-						processTree.Colorizer.ProcessAgeMedium(processTree.ColorScheme, value)
-					} else if process.Age >= 3600 && process.Age < 86400 {
-						// High age (> 1 hour and < 1 day)
-						processTree.Colorizer.ProcessAgeHigh(processTree.ColorScheme, value)
-					} else if process.Age >= 86400 {
-						// Very high age (> 1 day)
-						processTree.Colorizer.ProcessAgeVeryHigh(processTree.ColorScheme, value)
-					}
-				case "cpu":
-					// Ensure CPU percentage is shown when coloring by CPU
-					processTree.DisplayOptions.ShowCpuPercent = true
-
-					// Apply color based on CPU usage thresholds in percentage
-					if process.CPUPercent < 5 {
-						// Low CPU usage (< 5%)
-						processTree.Colorizer.CPULow(processTree.ColorScheme, value)
-					} else if process.CPUPercent >= 5 && process.CPUPercent < 15 {
-						// Medium CPU usage (5-15%)
-						processTree.Colorizer.CPUMedium(processTree.ColorScheme, value)
-					} else if process.CPUPercent >= 15 {
-						// High CPU usage (> 15%)
-						processTree.Colorizer.CPUHigh(processTree.ColorScheme, value)
-					}
-				case "mem":
-					// Ensure memory usage is shown when coloring by memory
-					processTree.DisplayOptions.ShowMemoryUsage = true
-
-					// Calculate memory usage as percentage of total system memory
-					percent := (process.MemoryInfo.RSS / processTree.DisplayOptions.InstalledMemory) * 100
-
-					// Apply color based on memory usage thresholds in percentage
-					if percent < 10 {
-						// Low memory usage (< 10%)
-						processTree.Colorizer.MemoryLow(processTree.ColorScheme, value)
-					} else if percent >= 10 && percent < 20 {
-						// Medium memory usage (10-20%)
-						processTree.Colorizer.MemoryMedium(processTree.ColorScheme, value)
-					} else if percent >= 20 {
-						// High memory usage (> 20%)
-						processTree.Colorizer.MemoryHigh(processTree.ColorScheme, value)
-					}
+
+				// Ensure the attribute we color by is also displayed
+				processTree.showColorAttribute()
+
+				colorFuncs := processTree.attributeColorFuncs()
+				level := processTree.attributeLevel(process)
+				if group, ok := processTree.getProcessGroup(pidIndex); ok && processTree.DisplayOptions.CompactMode && group.Count > 1 {
+					// A line standing for several processes is colored by their aggregated value
+					level = processTree.groupAttributeLevel(group)
+				}
+				if fieldName == "command" && processTree.DisplayOptions.ShowZombies && IsZombie(*process) {
+					// Zombies stand out in red whatever the attribute
+					processTree.Colorizer.StatusZombie(processTree.ColorScheme, value)
+				} else if level >= 0 && level < len(colorFuncs) {
+					colorFuncs[level](processTree.ColorScheme, value)
+				} else {
+					processTree.Colorizer.Default(processTree.ColorScheme, value)
 				}
 			} else {
-				processTree.Colorizer.Default(processTree.ColorScheme, value)
+				processTree.Colorizer.Prefix(processTree.ColorScheme, value)
 			}
 		}
 	}
@@ -1038,29 +1743,269 @@ func (processTree *ProcessTree) colorizeField(fieldName string, value *string, p
 //------------------------------------------------------------------------------
 // General utility functions used throughout the process tree implementation.
 
-// visibleWidth calculates the display width of a string containing ANSI escape sequences.
-// It ignores ANSI escape sequences and counts only the visible characters' width.
-// The function properly handles multi-byte Unicode characters and characters with
-// different display widths (like CJK characters that take up 2 columns).
+// attributeLevel classifies a process by the --color-attr attribute.
+//
+// The value of the attribute is compared with DisplayOptions.AttrThresholds, or with
+// DefaultAttributeThresholds when none are set. A value at or above a threshold belongs to the
+// next level, e.g., with the default cpu thresholds of 5 and 15, 4.99% is level 0, 5% is level 1
+// and 15% is level 2.
+//
+// Parameters:
+//   - process: The process to classify
+//
+// Returns:
+//   - int: The level, starting at 0 for the lowest values, or -1 if the value is unknown
+func (processTree *ProcessTree) attributeLevel(process *Process) int {
+	if processTree.DisplayOptions.ColorAttr == "user" {
+		// Users are not ranked, each one has its own color
+		return processTree.userLevel(process)
+	}
+
+	value, ok := processTree.attributeValue(process)
+	if !ok {
+		return -1
+	}
+	return processTree.thresholdLevel(value)
+}
+
+// groupAttributeLevel classifies a group of identical processes by the --color-attr attribute,
+// using the value shown on its compacted line: the oldest age, or the sum of the CPU usage, CPU
+// time, file descriptors, or memory usage of the members whose value is known.
 //
 // Parameters:
-//   - input: The string to calculate the width for, which may contain ANSI escape sequences
+//   - group: The group to classify
 //
 // Returns:
-//   - The display width of the string, excluding ANSI escape sequences
-func (processTree *ProcessTree) visibleWidth(input string) int {
-	width := 0
-	for len(input) > 0 {
-		if loc := ansiEscape.FindStringIndex(input); loc != nil && loc[0] == 0 {
-			// Skip ANSI
-			input = input[loc[1]:]
+//   - int: The level, starting at 0 for the lowest values, or -1 if the value of every member is unknown
+func (processTree *ProcessTree) groupAttributeLevel(group *ProcessGroup) int {
+	var (
+		known bool
+		total float64
+	)
+
+	if processTree.DisplayOptions.ColorAttr == "user" {
+		// The members of a group have the same owner
+		return processTree.userLevel(processTree.Nodes[group.FirstIndex])
+	}
+
+	for _, pidIndex := range group.Indices {
+		value, ok := processTree.attributeValue(processTree.Nodes[pidIndex])
+		if !ok {
 			continue
 		}
-		r, size := utf8.DecodeRuneInString(input)
-		width += runewidth.RuneWidth(r)
-		input = input[size:]
+		switch {
+		case !known:
+			total = value
+		case processTree.DisplayOptions.ColorAttr == "age":
+			total = max(total, value)
+		default:
+			total += value
+		}
+		known = true
+	}
+
+	if !known {
+		return -1
+	}
+	return processTree.thresholdLevel(total)
+}
+
+// attributeValue returns the value of the --color-attr attribute of a process.
+//
+// Parameters:
+//   - process: The process
+//
+// Returns:
+//   - float64: The value, e.g., the CPU percent, or the memory usage as a percentage of the installed memory
+//   - bool: false if the value is unknown or the attribute is not ranked
+func (processTree *ProcessTree) attributeValue(process *Process) (float64, bool) {
+	switch processTree.DisplayOptions.ColorAttr {
+	case "age":
+		if process.Age < 0 {
+			// The create time could not be read
+			return 0, false
+		}
+		return float64(process.Age), true
+	case "cpu":
+		if !process.HasCPU {
+			// The CPU usage could not be read
+			return 0, false
+		}
+		return processTree.cpuPercentOf(process), true
+	case "cputime":
+		if process.CPUTimes == nil {
+			// The CPU times could not be read
+			return 0, false
+		}
+		return CPUTime(process), true
+	case "fds":
+		if process.NumFDs < 0 {
+			// The file descriptors could not be read
+			return 0, false
+		}
+		return float64(process.NumFDs), true
+	case "mem":
+		if !process.HasMemory || processTree.DisplayOptions.InstalledMemory == 0 {
+			return 0, false
+		}
+		// Calculate memory usage as percentage of total system memory, using the --mem-mode
+		return float64(MemoryValue(process, processTree.DisplayOptions.MemoryMode)) / float64(processTree.DisplayOptions.InstalledMemory) * 100, true
+	}
+	return 0, false
+}
+
+// thresholdLevel returns the level of a value of the --color-attr attribute.
+//
+// Parameters:
+//   - value: The value to classify
+//
+// Returns:
+//   - int: The number of thresholds at or below the value
+func (processTree *ProcessTree) thresholdLevel(value float64) int {
+	var (
+		level      int
+		thresholds []float64
+	)
+
+	thresholds = processTree.DisplayOptions.AttrThresholds
+	if len(thresholds) == 0 {
+		thresholds = DefaultAttributeThresholds[processTree.DisplayOptions.ColorAttr]
+	}
+
+	for _, threshold := range thresholds {
+		if value >= threshold {
+			level++
+		}
+	}
+	return level
+}
+
+// showColorAttribute enables the display of the --color-attr attribute, so the value a process
+// is colored by is also shown.
+func (processTree *ProcessTree) showColorAttribute() {
+	switch processTree.DisplayOptions.ColorAttr {
+	case "age":
+		processTree.DisplayOptions.ShowProcessAge = true
+	case "cpu":
+		processTree.DisplayOptions.ShowCpuPercent = true
+	case "cputime":
+		processTree.DisplayOptions.ShowCpuTime = true
+	case "fds":
+		processTree.DisplayOptions.ShowNumFDs = true
+	case "mem":
+		processTree.DisplayOptions.ShowMemoryUsage = true
+	case "user":
+		processTree.DisplayOptions.ShowOwner = true
+	}
+}
+
+// attributeColorFuncs returns the color functions for each level of the --color-attr attribute.
+//
+// Returns:
+//   - []ColorFunc: The color functions indexed by the level returned by attributeLevel
+func (processTree *ProcessTree) attributeColorFuncs() []ColorFunc {
+	switch processTree.DisplayOptions.ColorAttr {
+	case "age":
+		return []ColorFunc{processTree.Colorizer.ProcessAgeLow, processTree.Colorizer.ProcessAgeMedium, processTree.Colorizer.ProcessAgeHigh, processTree.Colorizer.ProcessAgeVeryHigh}
+	case "cpu", "cputime":
+		return []ColorFunc{processTree.Colorizer.CPULow, processTree.Colorizer.CPUMedium, processTree.Colorizer.CPUHigh}
+	case "fds":
+		return []ColorFunc{processTree.Colorizer.FDsLow, processTree.Colorizer.FDsMedium, processTree.Colorizer.FDsHigh}
+	case "mem":
+		return []ColorFunc{processTree.Colorizer.MemoryLow, processTree.Colorizer.MemoryMedium, processTree.Colorizer.MemoryHigh}
+	case "user":
+		return processTree.userColorFuncs()
 	}
-	return width
+	return nil
+}
+
+// highlightField marks a field of a process in the --highlight-pid ancestry.
+//
+// When colorization is enabled the field is rendered in bold inverse video, otherwise it is
+// prefixed with an asterisk so the highlighted chain is still visible in plain output.
+//
+// Parameters:
+//   - value: Pointer to the string to be highlighted (modified in place)
+func (processTree *ProcessTree) highlightField(value *string) {
+	if processTree.colorEnabled() {
+		*value = AnsiBoldInverse + *value + AnsiReset
+	} else {
+		*value = "*" + *value
+	}
+}
+
+// colorEnabled determines if ANSI colors are being added to the output.
+//
+// Returns:
+//   - true if the terminal supports colors and --color or --color-attr is used, false otherwise
+func (processTree *ProcessTree) colorEnabled() bool {
+	return processTree.DisplayOptions.ColorSupport && (processTree.DisplayOptions.ColorizeOutput || processTree.DisplayOptions.ColorAttr != "")
+}
+
+// hasPGID determines whether the process group ID of a process can be shown.
+//
+// On platforms without process groups, see PGIDSupported, the unknown PGID of -1 is hidden along
+// with the process group leader marker instead of being shown for every process. PGIDs loaded
+// from a snapshot of another platform are still shown.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the PGID is known or the platform supports process groups, false otherwise
+func hasPGID(node *Process) bool {
+	return PGIDSupported || node.PGID >= 0
+}
+
+// formatNumFDs formats a file descriptor count for display, e.g., (fds: 12).
+//
+// Parameters:
+//   - numFDs: The number of open file descriptors, negative if it could not be read
+//
+// Returns:
+//   - string: The formatted count, with "-" in place of an unknown count
+func formatNumFDs(numFDs int32) string {
+	if numFDs < 0 {
+		return "(fds: -)"
+	}
+	return fmt.Sprintf("(fds: %d)", numFDs)
+}
+
+// formatCPUPercent formats the CPU usage percentage of a process or compact group, e.g.,
+// (c:1.50%), followed with --cumulative by the usage of the subtree, e.g., (c:1.50% (4.25%)).
+//
+// Parameters:
+//   - percent: The CPU usage percentage, negative if it was not collected
+//   - cumulative: The CPU usage percentage of the subtree, shown when --cumulative is used
+//
+// Returns:
+//   - string: The formatted CPU usage, with - for a percentage that was not collected
+func (processTree *ProcessTree) formatCPUPercent(percent float64, cumulative float64) string {
+	value := "-"
+	if percent >= 0 {
+		value = fmt.Sprintf("%.2f%%", percent)
+	}
+	if processTree.DisplayOptions.ShowCumulative {
+		return fmt.Sprintf("(c:%s (%.2f%%))", value, cumulative)
+	}
+	return fmt.Sprintf("(c:%s)", value)
+}
+
+// StatusLetter converts the process status reported by gopsutil to the single-letter state shown by ps.
+//
+// Parameters:
+//   - status: Status of the process as returned by gopsutil
+//
+// Returns:
+//   - The single-letter state, or "?" if the status is unknown or wasn't collected
+func StatusLetter(status []string) string {
+	if len(status) == 0 {
+		return "?"
+	}
+	if letter, ok := StatusLetters[status[0]]; ok {
+		return letter
+	}
+	return "?"
 }
 
 // truncateANSI truncates a string containing ANSI escape sequences to fit within a specified screen width.
@@ -1069,82 +2014,169 @@ func (processTree *ProcessTree) visibleWidth(input string) int {
 // Parameters:
 //   - input: The string to truncate, which may contain ANSI escape sequences
 //
-// The function handles multi-byte Unicode characters correctly by using utf8.DecodeRuneInString
-// and accounts for characters with different display widths using the runewidth package.
-// If truncation occurs, "..." is appended to the result.
+// The string is cut between grapheme clusters with util.TruncateWidth, so a multi-byte or wide
+// character straddling the edge of the screen is left out entirely instead of being cut in half.
+// If truncation occurs, the single-column TreeChars.Ellipsis is appended to the result, … with
+// the UTF-8 line drawing characters and + otherwise, followed by a reset when the kept part
+// contains escape sequences, so the colors don't bleed into the next line.
 //
 // Returns:
 //   - A string that fits within screenWidth, with ANSI sequences preserved.
 func (processTree *ProcessTree) truncateANSI(input string) string {
-	dots := "..."
+	ellipsis := processTree.TreeChars.Ellipsis
 
-	if processTree.DisplayOptions.ScreenWidth <= 3 {
-		return dots
+	if processTree.DisplayOptions.ScreenWidth <= util.VisibleWidth(ellipsis) {
+		return ellipsis
 	}
 
-	// First, check actual display width
-	if processTree.visibleWidth(input) <= processTree.DisplayOptions.ScreenWidth {
-		return input // No truncation needed
+	output, cut := util.TruncateWidth(input, processTree.DisplayOptions.ScreenWidth)
+	if !cut {
+		return output // No truncation needed
 	}
 
-	targetWidth := processTree.DisplayOptions.ScreenWidth - len(dots)
-	var output strings.Builder
-	width := 0
-
-	for len(input) > 0 {
-		if loc := ansiEscape.FindStringIndex(input); loc != nil && loc[0] == 0 {
-			esc := input[loc[0]:loc[1]]
-			output.WriteString(esc)
-			input = input[loc[1]:]
-			continue
-		}
-
-		r, size := utf8.DecodeRuneInString(input)
-		rw := runewidth.RuneWidth(r)
+	output, _ = util.TruncateWidth(input, processTree.DisplayOptions.ScreenWidth-util.VisibleWidth(ellipsis))
+	if !strings.Contains(output, "\x1b") {
+		return output + ellipsis
+	}
+	return output + ellipsis + "\x1b[0m" // Prevent ANSI bleed
+}
 
-		if width+rw > targetWidth {
-			break
+// computeSignatures computes the subtree signature of every node, see computeSignature.
+//
+// This runs once while the tree is built, after the children are linked, so compact mode and the
+// renderers only read Process.Signature. Signatures left over from a previous tree built on the
+// same processes are cleared first, since they may have been computed with other display options.
+func (processTree *ProcessTree) computeSignatures() {
+	for _, node := range processTree.Nodes {
+		node.Signature = ""
+	}
+	// Start from the roots, the signatures are cached so each subtree is only visited once
+	for _, node := range processTree.Nodes {
+		if isRootProcess(node, processTree.PidToIndexMap) || slices.Contains(processTree.RootPIDs, node.PID) {
+			computeSignature(node, processTree.DisplayOptions.ShowArguments, processTree.DisplayOptions.ShowZombies)
 		}
-
-		output.WriteRune(r)
-		width += rw
-		input = input[size:]
 	}
-
-	output.WriteString(dots)
-	return output.String() + "\x1b[0m" // Prevent ANSI bleed
 }
 
 // computeSignature recursively generates a unique signature for a process subtree.
 // This includes the command, args, and all child subtrees.
 // Signatures are cached in Process.Signature.
-func computeSignature(p *Process, showArguments bool) string {
+// With --zombies, zombies get a signature of their own so they are not grouped with live processes.
+func computeSignature(p *Process, showArguments bool, showZombies bool) string {
 	if p.Signature != "" {
 		return p.Signature
 	}
 
 	childSigs := make([]string, 0, len(p.Children))
+	size := 2
 	for _, c := range p.Children {
-		childSigs = append(childSigs, computeSignature(c, showArguments))
+		childSigs = append(childSigs, computeSignature(c, showArguments, showZombies))
+		size += len(childSigs[len(childSigs)-1]) + 1
 	}
 
 	// Ignore child order (matches pstree)
 	sort.Strings(childSigs)
 
-	self := p.Command
-	if showArguments && len(p.Args) > 0 {
-		self += " " + strings.Join(p.Args, " ")
+	var builder strings.Builder
+	builder.Grow(len(p.Command) + len(p.Username) + size + 64)
+	builder.WriteString(p.Command)
+	if showArguments {
+		for _, arg := range p.Args {
+			builder.WriteByte(' ')
+			builder.WriteString(arg)
+		}
+	}
+
+	// A process started with another name is not grouped with the ones started as its executable
+	if hasOwnArgv0(p) {
+		builder.WriteByte('|')
+		builder.WriteString(p.Argv0)
 	}
 
 	// Include owner (matches Linux pstree)
-	self += "|" + p.Username
+	builder.WriteByte('|')
+	builder.WriteString(p.Username)
+
+	if showZombies && IsZombie(*p) {
+		builder.WriteByte('|')
+		builder.WriteString(DefunctSuffix)
+	}
+
+	// A privileged process is not grouped with the ones that didn't gain privileges
+	if p.IsPrivileged {
+		builder.WriteByte('|')
+		builder.WriteString(PrivilegedMarker)
+	}
+
+	// A process still running a deleted executable is not grouped with the upgraded ones
+	if p.ExeDeleted {
+		builder.WriteByte('|')
+		builder.WriteString(DeletedExeSuffix)
+	}
+
+	// New and gone processes are not grouped with the ones in both snapshots
+	switch p.DiffState {
+	case DiffNew:
+		builder.WriteByte('|')
+		builder.WriteString(DiffNewMarker)
+	case DiffGone:
+		builder.WriteByte('|')
+		builder.WriteString(DiffGoneMarker)
+	}
 
-	p.Signature = self + "(" + strings.Join(childSigs, ",") + ")"
+	builder.WriteByte('(')
+	for i, childSig := range childSigs {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(childSig)
+	}
+	builder.WriteByte(')')
+
+	p.Signature = builder.String()
 	return p.Signature
 }
 
+// durationFromProcessAge formats the age of a process for display using DisplayOptions.AgeFormat.
+//
+// The formats are:
+//   - dhms: days, hours, minutes and seconds, e.g., (02:04:13:07); this is the default
+//   - hms: hours, minutes and seconds, e.g., (3:42:17)
+//   - human: the two largest non-zero units, e.g., (2d4h) or (5m7s)
+//   - seconds: the number of seconds, e.g., (13387)
+//
+// Parameters:
+//   - processAge: Age of the process in seconds, or a negative number if it is unknown
+//
+// Returns:
+//   - string: The formatted age in parentheses, or (?) if the age is unknown
 func (processTree *ProcessTree) durationFromProcessAge(processAge int64) string {
+	if processAge < 0 {
+		return "(?)"
+	}
+
 	duration := util.FindDuration(processAge)
+	switch processTree.DisplayOptions.AgeFormat {
+	case "hms":
+		return fmt.Sprintf("(%d:%02d:%02d)", processAge/3600, duration.Minutes, duration.Seconds)
+	case "human":
+		units := []string{}
+		for _, unit := range []struct {
+			value  int64
+			suffix string
+		}{{duration.Days, "d"}, {duration.Hours, "h"}, {duration.Minutes, "m"}, {duration.Seconds, "s"}} {
+			if unit.value > 0 || len(units) > 0 {
+				units = append(units, fmt.Sprintf("%d%s", unit.value, unit.suffix))
+			}
+		}
+		if len(units) == 0 {
+			return "(0s)"
+		}
+		return fmt.Sprintf("(%s)", strings.Join(units[:min(len(units), 2)], ""))
+	case "seconds":
+		return fmt.Sprintf("(%d)", processAge)
+	}
+
 	ageSlice := []string{}
 	ageSlice = append(ageSlice, fmt.Sprintf("%02d", duration.Days))
 	ageSlice = append(ageSlice, fmt.Sprintf("%02d", duration.Hours))
@@ -1154,7 +2186,6 @@ func (processTree *ProcessTree) durationFromProcessAge(processAge int64) string
 		"(%s)",
 		strings.Join(ageSlice, ":"),
 	)
-	// processTree.colorizeField("age", &ageString, pidIndex)
 
 	return ageString
 }
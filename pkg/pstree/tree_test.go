@@ -1,11 +1,18 @@
 package pstree
 
 import (
+	"bytes"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/bananazon/pstree/util"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/process"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // setupTestLogger creates a logger for testing
@@ -13,6 +20,34 @@ func setupTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
 }
 
+// renderTree builds the tree of the processes and renders it the way the command does, 10 levels
+// deep and 80 columns wide unless the display options say otherwise
+func renderTree(t *testing.T, processes []Process, displayOptions DisplayOptions) string {
+	t.Helper()
+	return renderProcessTree(t, NewProcessTree(0, setupTestLogger(), processes, renderDefaults(displayOptions)))
+}
+
+// renderDefaults returns the display options with the depth and width of renderTree filled in
+func renderDefaults(displayOptions DisplayOptions) DisplayOptions {
+	if displayOptions.MaxDepth == 0 {
+		displayOptions.MaxDepth = 10
+	}
+	if displayOptions.ScreenWidth == 0 {
+		displayOptions.ScreenWidth = 80
+	}
+	return displayOptions
+}
+
+// renderProcessTree marks the processes to display, drops the others and renders the tree
+func renderProcessTree(t *testing.T, processTree *ProcessTree) string {
+	t.Helper()
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	output, err := processTree.RenderString()
+	require.NoError(t, err)
+	return output
+}
+
 // TestNewProcessTree tests the creation of a new process tree
 func TestNewProcessTree(t *testing.T) {
 	// Create test processes
@@ -112,6 +147,520 @@ func TestMarkProcesses(t *testing.T) {
 	assert.True(t, processTree2.Nodes[idx4].Print)  // proc4 belongs to user1
 }
 
+// TestMarkProcessesMatchSubtree tests that all descendants of a --contains match are marked,
+// with or without --match-subtree
+func TestMarkProcessesMatchSubtree(t *testing.T) {
+	// Only the orphaned worker matches the pattern, its child doesn't
+	tree := fixtures.OrphanedChild()
+
+	// Test case 1: The whole subtree of a --contains match is marked
+	processTree1 := newFixtureTree(t, tree, DisplayOptions{Contains: "worker"})
+	processTree1.MarkProcesses()
+
+	assert.Equal(t, []int32{300, 301}, markedPIDs(processTree1)) // the parent of the worker is missing, init is not its ancestor
+
+	// Test case 2: MatchSubtree doesn't change a --contains match
+	processTree2 := newFixtureTree(t, tree, DisplayOptions{Contains: "worker", MatchSubtree: true})
+	processTree2.MarkProcesses()
+
+	assert.Equal(t, []int32{300, 301}, markedPIDs(processTree2))
+	assert.False(t, processTree2.Nodes[processTree2.PidToIndexMap[200]].Print) // cron is outside the subtree
+}
+
+// TestMarkProcessesTerminal tests that --tty marks the processes attached to the terminal and their ancestors
+func TestMarkProcessesTerminal(t *testing.T) {
+	// Three sessions of sshd, each with a shell attached to its own terminal
+	marked := func(displayOptions DisplayOptions) []int32 {
+		processTree := newFixtureTree(t, fixtures.WideFanOut(3), displayOptions)
+		processTree.MarkProcesses()
+		return markedPIDs(processTree)
+	}
+
+	// The sessions without a terminal don't match, but ancestors are kept
+	assert.Equal(t, []int32{1, 100, 1000, 1001}, marked(DisplayOptions{Terminal: "pts/0"}))
+	assert.Equal(t, []int32{1, 100, 1002, 1003}, marked(DisplayOptions{Terminal: "pts/1"}))
+	assert.Equal(t, []int32{}, marked(DisplayOptions{Terminal: "pts/9"}))
+
+	// The terminal narrows down --contains and --user
+	assert.Equal(t, []int32{1, 100, 1004, 1005}, marked(DisplayOptions{Terminal: "pts/2", Contains: "bash"}))
+	assert.Equal(t, []int32{}, marked(DisplayOptions{Terminal: "pts/1", Usernames: []string{"user0"}}))
+}
+
+// TestMarkProcessesRootPIDs tests that each requested root PID marks its own subtree
+func TestMarkProcessesRootPIDs(t *testing.T) {
+	logger := setupTestLogger()
+
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "nginx"},
+		{PID: 101, PPID: 100, Command: "worker"},
+		{PID: 200, PPID: 1, Command: "sshd"},
+		{PID: 201, PPID: 200, Command: "bash"},
+		{PID: 300, PPID: 1, Command: "cron"},
+	}
+
+	processTree := NewProcessTree(0, logger, processes, DisplayOptions{RootPIDs: []int32{200, 100}})
+	processTree.MarkProcesses()
+
+	for _, pid := range []int32{100, 101, 200, 201} {
+		assert.True(t, processTree.Nodes[processTree.PidToIndexMap[pid]].Print, "PID %d should be marked", pid)
+	}
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[300]].Print) // cron is not in a requested subtree
+
+	// Roots are returned in PID order
+	rootIndices, err := processTree.RootIndices()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{processTree.PidToIndexMap[100], processTree.PidToIndexMap[200]}, rootIndices)
+}
+
+// TestRootIndices tests the selection of the root processes to print from
+func TestRootIndices(t *testing.T) {
+	logger := setupTestLogger()
+
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "nginx"},
+	}
+
+	// Without root PIDs the tree is printed from the first node
+	processTree1 := NewProcessTree(0, logger, processes, DisplayOptions{})
+	rootIndices, err := processTree1.RootIndices()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, rootIndices)
+
+	// Missing PIDs are skipped as long as one of them exists
+	processTree2 := NewProcessTree(0, logger, processes, DisplayOptions{RootPIDs: []int32{99999, 100}})
+	rootIndices, err = processTree2.RootIndices()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{processTree2.PidToIndexMap[100]}, rootIndices)
+
+	// It is an error if none of them exist
+	processTree3 := NewProcessTree(0, logger, processes, DisplayOptions{RootPIDs: []int32{99998, 99999}})
+	_, err = processTree3.RootIndices()
+	assert.Error(t, err)
+}
+
+// TestRootIndicesWithoutInit tests that the roots are found when PID 1 was not collected
+func TestRootIndicesWithoutInit(t *testing.T) {
+	// The lowest PID is 4242, as in a container, and the parent of 5000 is not visible either
+	processes := []Process{
+		{PID: 4242, PPID: 4000, Command: "sh", HasCPU: true, CPUPercent: 0.1},
+		{PID: 4300, PPID: 4242, Command: "worker", HasCPU: true, CPUPercent: 2.0},
+		{PID: 4301, PPID: 4242, Command: "worker", HasCPU: true, CPUPercent: 1.0},
+		{PID: 5000, PPID: 4999, Command: "agent", HasCPU: true, CPUPercent: 3.0},
+		{PID: 5001, PPID: 5000, Command: "collector"},
+	}
+
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{
+		CompactMode: true,
+		MaxDepth:    10,
+		OrderBy:     "cpu",
+		OrderDir:    "desc",
+		ScreenWidth: 80,
+	})
+	rootIndices, err := processTree.RootIndices()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{processTree.PidToIndexMap[4242], processTree.PidToIndexMap[5000]}, rootIndices)
+
+	// Both trees are printed, with the identical workers grouped
+	output := renderProcessTree(t, processTree)
+	assert.Regexp(t, `(?s)sh.*2\*\[worker\].*agent.*collector`, output)
+}
+
+// TestMarkCurrentAndAncestors tests that the highlighted process and its ancestors are marked
+func TestMarkCurrentAndAncestors(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+		{PID: 101, PPID: 100, Command: "bash"},
+		{PID: 200, PPID: 1, Command: "cron"},
+	}
+
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{HighlightPID: 101})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	processTree.MarkCurrentAndAncestors()
+
+	for _, pid := range []int32{1, 100, 101} {
+		assert.True(t, processTree.Nodes[processTree.PidToIndexMap[pid]].IsCurrentOrAncestor, "PID %d should be highlighted", pid)
+	}
+	assert.False(t, processTree.Nodes[processTree.PidToIndexMap[200]].IsCurrentOrAncestor) // cron is not an ancestor
+
+	// Without color the highlighted lines get an asterisk marker
+	command := "bash"
+	processTree.highlightField(&command)
+	assert.Equal(t, "*bash", command)
+
+	// With color the highlighted lines are shown in inverse video
+	processTree.DisplayOptions.ColorSupport = true
+	processTree.DisplayOptions.ColorizeOutput = true
+	command = "bash"
+	processTree.highlightField(&command)
+	assert.Equal(t, AnsiBoldInverse+"bash"+AnsiReset, command)
+}
+
+// TestStatusLetter tests the conversion of the gopsutil status to a ps state letter
+func TestStatusLetter(t *testing.T) {
+	assert.Equal(t, "R", StatusLetter([]string{"running"}))
+	assert.Equal(t, "S", StatusLetter([]string{"sleep"}))
+	assert.Equal(t, "D", StatusLetter([]string{"blocked"}))
+	assert.Equal(t, "T", StatusLetter([]string{"stop"}))
+	assert.Equal(t, "Z", StatusLetter([]string{"zombie"}))
+	assert.Equal(t, "?", StatusLetter([]string{"unknown"}))
+	assert.Equal(t, "?", StatusLetter([]string{}))
+}
+
+// TestSortChildren tests that sorting reorders siblings without changing the tree structure
+func TestSortChildren(t *testing.T) {
+	logger := setupTestLogger()
+
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 0.1},
+		{PID: 100, PPID: 1, Command: "sshd", HasCPU: true, CPUPercent: 5.0},
+		{PID: 101, PPID: 100, Command: "bash", HasCPU: true, CPUPercent: 9.0},
+		{PID: 102, PPID: 100, Command: "vim", HasCPU: true, CPUPercent: 1.0},
+		{PID: 200, PPID: 1, Command: "cron", HasCPU: true, CPUPercent: 0.5},
+		{PID: 300, PPID: 1, Command: "nginx", HasCPU: true, CPUPercent: 5.0},
+	}
+
+	// childPIDs returns the PIDs of the children of a process in display order
+	childPIDs := func(processTree *ProcessTree, pid int32) []int32 {
+		pids := []int32{}
+		for child := processTree.Nodes[processTree.PidToIndexMap[pid]].Child; child != -1; child = processTree.Nodes[child].Sister {
+			pids = append(pids, processTree.Nodes[child].PID)
+		}
+		return pids
+	}
+
+	// Ascending, equal values keep PID order
+	processTree1 := NewProcessTree(0, logger, processes, DisplayOptions{OrderBy: "cpu", OrderDir: "asc"})
+	assert.Equal(t, []int32{200, 100, 300}, childPIDs(processTree1, 1))
+	assert.Equal(t, []int32{102, 101}, childPIDs(processTree1, 100))
+
+	// Descending, equal values still keep PID order
+	processTree2 := NewProcessTree(0, logger, processes, DisplayOptions{OrderBy: "cpu", OrderDir: "desc"})
+	assert.Equal(t, []int32{100, 300, 200}, childPIDs(processTree2, 1))
+	assert.Equal(t, []int32{101, 102}, childPIDs(processTree2, 100))
+
+	// The tree structure is unchanged
+	for _, pid := range []int32{101, 102} {
+		parentIndex := processTree2.Nodes[processTree2.PidToIndexMap[pid]].Parent
+		assert.Equal(t, int32(100), processTree2.Nodes[parentIndex].PID)
+	}
+}
+
+// TestComputeCumulative tests that subtree usage is summed bottom-up, including hidden processes
+func TestComputeCumulative(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 0.5, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 100}},
+		{PID: 100, PPID: 1, Command: "sshd", HasCPU: true, CPUPercent: 1.0, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 200}},
+		{PID: 101, PPID: 100, Command: "bash", HasCPU: true, CPUPercent: 2.0, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 300}},
+		{PID: 102, PPID: 101, Command: "vim", HasCPU: true, CPUPercent: 4.0},
+		{PID: 200, PPID: 1, Command: "cron", HasCPU: true, CPUPercent: 8.0, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 400}},
+	}
+
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{Contains: "cron", ShowCumulative: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	node := func(pid int32) *Process {
+		return processTree.Nodes[processTree.PidToIndexMap[pid]]
+	}
+
+	// sshd, bash, and vim are not displayed, but bash and vim still count towards sshd
+	assert.False(t, node(100).Print)
+	assert.False(t, node(101).Print)
+	assert.Equal(t, 7.0, node(100).CumulativeCPU)
+	assert.Equal(t, uint64(500), node(100).CumulativeRSS)
+
+	// A process without memory information contributes nothing to the RSS total
+	assert.Equal(t, 4.0, node(102).CumulativeCPU)
+	assert.Equal(t, uint64(0), node(102).CumulativeRSS)
+
+	assert.Equal(t, 15.5, node(1).CumulativeCPU)
+	assert.Equal(t, uint64(1000), node(1).CumulativeRSS)
+}
+
+// TestNewProcessTreeWithOutput tests that the tree is printed to the configured writer
+func TestNewProcessTreeWithOutput(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd"},
+	}
+
+	var buffer bytes.Buffer
+	processTree := NewProcessTreeWithOutput(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 10, ScreenWidth: 80}, &buffer)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	processTree.PrintTree(0, "")
+
+	assert.Contains(t, buffer.String(), "init")
+	assert.Contains(t, buffer.String(), "sshd")
+
+	// The default constructor keeps writing to stdout
+	assert.Equal(t, os.Stdout, NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{}).Output)
+}
+
+// TestRenderString tests rendering the tree into a string with the different drawing styles
+func TestRenderString(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd", Args: []string{"-D"}},
+		{PID: 101, PPID: 100, Command: "bash"},
+		{PID: 200, PPID: 1, Command: "cron"},
+	}
+
+	tests := []struct {
+		name           string
+		displayOptions DisplayOptions
+		expected       string
+	}{
+		{
+			name:           "ASCII with PIDs and arguments",
+			displayOptions: DisplayOptions{MaxDepth: 10, ScreenWidth: 80, ShowArguments: true, ShowPIDs: true},
+			expected:       "-+- (1) init \n |-+- (100) sshd -D\n | \\--- (101) bash \n \\--- (200) cron \n",
+		},
+		{
+			name:           "UTF-8 graphics",
+			displayOptions: DisplayOptions{MaxDepth: 10, ScreenWidth: 80, UTF8Graphics: true},
+			expected:       "─┬─ init \n ├─┬─ sshd \n │ └─── bash \n └─── cron \n",
+		},
+		{
+			name:           "Truncated to the screen width",
+			displayOptions: DisplayOptions{MaxDepth: 10, ScreenWidth: 12, ShowArguments: true},
+			expected:       "-+- init \n |-+- sshd +\n | \\--- bas+\n \\--- cron \n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			processTree := NewProcessTreeWithOutput(0, setupTestLogger(), processes, test.displayOptions, &buffer)
+			processTree.MarkProcesses()
+			processTree.DropUnmarked()
+
+			output, err := processTree.RenderString()
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, output)
+
+			// The configured output is restored and left untouched
+			assert.Equal(t, &buffer, processTree.Output)
+			assert.Empty(t, buffer.String())
+		})
+	}
+
+	// Unknown root PIDs are reported instead of rendering an empty tree
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{MaxDepth: 10, RootPIDs: []int32{999}, ScreenWidth: 80})
+	_, err := processTree.RenderString()
+	assert.Error(t, err)
+}
+
+// TestRenderStringUncollected tests that usage that was not collected is shown as - rather than 0
+func TestRenderStringUncollected(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{}},
+		{PID: 100, PPID: 1, Command: "gone", CPUPercent: -1, MemoryInfo: &process.MemoryInfoStat{}},
+	}
+	output := renderTree(t, processes, DisplayOptions{ShowCpuPercent: true, ShowMemoryUsage: true, WideDisplay: true})
+	assert.Equal(t, "-+- (c:0.00%) (m:0.0 B) init \n \\--- (c:-) (m:-) gone \n", output)
+}
+
+// TestRenderStringColoredWidth tests that colored lines are measured by their visible width
+func TestRenderStringColoredWidth(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "sshd", Args: []string{"-D"}},
+	}
+
+	render := func(screenWidth int) []string {
+		processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{
+			ColorCount:     256,
+			ColorizeOutput: true,
+			MaxDepth:       10,
+			ScreenWidth:    screenWidth,
+			ShowArguments:  true,
+			UTF8Graphics:   true,
+		})
+		output := renderProcessTree(t, processTree)
+		return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	}
+
+	// " └─── sshd -D" is 13 columns wide, the escape sequences and multi-byte branch characters don't count
+	lines := render(13)
+	assert.Greater(t, len(lines[1]), 13)
+	assert.Equal(t, 13, util.VisibleWidth(lines[1]))
+	assert.NotContains(t, lines[1], "…")
+
+	// One column less truncates the line before the colored arguments, so it needs no color reset
+	lines = render(12)
+	assert.Equal(t, 12, util.VisibleWidth(lines[1]))
+	assert.Equal(t, " └─── sshd …", lines[1])
+}
+
+// TestTruncateANSI tests that lines are truncated between characters and marked with the ellipsis of the tree style
+func TestTruncateANSI(t *testing.T) {
+	tests := []struct {
+		name        string
+		style       string
+		screenWidth int
+		input       string
+		expected    string
+	}{
+		{"fits", "utf8", 9, "bash 日本", "bash 日本"},
+		{"UTF-8 ellipsis", "utf8", 10, "bash 日本語", "bash 日本…"},
+		{"wide character straddling the ellipsis", "utf8", 9, "bash 日本語", "bash 日…"},
+		{"ASCII ellipsis", "ascii", 9, "bash 日本語", "bash 日+"},
+		{"wide character straddling the edge", "ascii", 8, "bash 日本語", "bash 日+"},
+		{"colored", "utf8", 4, "\x1b[31m日本語\x1b[0m", "\x1b[31m日…\x1b[0m"},
+		{"joined emoji", "utf8", 5, "bash 👩‍💻 -i", "bash…"},
+		{"joined emoji kept whole", "utf8", 8, "bash 👩‍💻 -i", "bash 👩‍💻…"},
+		{"IBM850 line drawing characters", "pc850", 6, "\xda\xc2 bash -l", "\xda\xc2 ba+"},
+		{"colors reset before the cut", "utf8", 6, "\x1b[31mbash\x1b[0m -l -i", "\x1b[31mbash\x1b[0m …\x1b[0m"},
+		{"no room for anything but the ellipsis", "utf8", 1, "bash", "…"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processTree := &ProcessTree{DisplayOptions: DisplayOptions{ScreenWidth: test.screenWidth}, TreeChars: TreeStyles[test.style]}
+			output := processTree.truncateANSI(test.input)
+			assert.Equal(t, test.expected, output)
+			assert.LessOrEqual(t, util.VisibleWidth(output), test.screenWidth)
+		})
+	}
+}
+
+// TestThreadNodes tests that thread nodes are compacted and don't count towards usage totals
+func TestThreadNodes(t *testing.T) {
+	processes := appendThreadNodes([]Process{
+		{PID: 1, PPID: 0, Command: "init", HasCPU: true, CPUPercent: 1.0, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 100}},
+		{PID: 100, PPID: 1, Command: "worker", HasCPU: true, CPUPercent: 4.0, HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 400}, NumThreads: 3, Threads: map[int32]*cpu.TimesStat{
+			100: {},
+			101: {},
+			102: {},
+		}},
+	})
+
+	displayOptions := DisplayOptions{CompactMode: true, MaxDepth: 10, ScreenWidth: 80, ShowCpuPercent: true, ShowCumulative: true, ShowNumThreads: true}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+
+	assert.Equal(t, 5.0, processTree.Nodes[0].CumulativeCPU)
+	assert.Equal(t, uint64(500), processTree.Nodes[0].CumulativeRSS)
+
+	output, err := processTree.RenderString()
+	assert.NoError(t, err)
+	assert.Equal(t, "-+- (c:1.00% (5.00%)) (t:0) init \n \\-+- (c:4.00% (4.00%)) (t:3) worker \n   \\--- {worker}───2*[{worker}] \n", output)
+}
+
+// TestAttributeLevel tests that values at exactly a threshold land in the higher level
+func TestAttributeLevel(t *testing.T) {
+	processTree := &ProcessTree{}
+
+	level := func(attr string, thresholds []float64, process *Process) int {
+		processTree.DisplayOptions = DisplayOptions{AttrThresholds: thresholds, ColorAttr: attr, InstalledMemory: 1000}
+		return processTree.attributeLevel(process)
+	}
+
+	// Default thresholds
+	assert.Equal(t, 0, level("cpu", nil, &Process{HasCPU: true, CPUPercent: 4.99}))
+	assert.Equal(t, 1, level("cpu", nil, &Process{HasCPU: true, CPUPercent: 5}))
+	assert.Equal(t, 2, level("cpu", nil, &Process{HasCPU: true, CPUPercent: 15}))
+	assert.Equal(t, 0, level("age", nil, &Process{Age: 59}))
+	assert.Equal(t, 1, level("age", nil, &Process{Age: 60}))
+	assert.Equal(t, 2, level("age", nil, &Process{Age: 3600}))
+	assert.Equal(t, 3, level("age", nil, &Process{Age: 86400}))
+	assert.Equal(t, 1, level("fds", nil, &Process{NumFDs: 100}))
+	assert.Equal(t, -1, level("fds", nil, &Process{NumFDs: -1}))
+	assert.Equal(t, 0, level("mem", nil, &Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 99}}))
+	assert.Equal(t, 1, level("mem", nil, &Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 100}}))
+	assert.Equal(t, 2, level("mem", nil, &Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 200}}))
+	assert.Equal(t, -1, level("mem", nil, &Process{}))
+
+	// Configured thresholds
+	thresholds := []float64{50, 80}
+	assert.Equal(t, 0, level("cpu", thresholds, &Process{HasCPU: true, CPUPercent: 49.9}))
+	assert.Equal(t, 1, level("cpu", thresholds, &Process{HasCPU: true, CPUPercent: 50}))
+	assert.Equal(t, 1, level("cpu", thresholds, &Process{HasCPU: true, CPUPercent: 79.9}))
+	assert.Equal(t, 2, level("cpu", thresholds, &Process{HasCPU: true, CPUPercent: 80}))
+	assert.Equal(t, 1, level("mem", thresholds, &Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 500}}))
+	assert.Equal(t, 2, level("mem", thresholds, &Process{HasMemory: true, MemoryInfo: &process.MemoryInfoStat{RSS: 800}}))
+	assert.Equal(t, 3, level("age", []float64{10, 20, 30}, &Process{Age: 30}))
+}
+
+// TestGroupAttributeLevel tests that groups are classified by their aggregated value
+func TestGroupAttributeLevel(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Age: 10},
+		{PID: 100, PPID: 1, Command: "worker", Age: 30, HasCPU: true, CPUPercent: 3, NumFDs: 40},
+		{PID: 200, PPID: 1, Command: "worker", Age: 90, HasCPU: true, CPUPercent: 3, NumFDs: -1},
+		{PID: 300, PPID: 1, Command: "worker", Age: 20, HasCPU: true, CPUPercent: 3, NumFDs: 70},
+	}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{CompactMode: true})
+	group := &ProcessGroup{FirstIndex: 1, Indices: []int{1, 2, 3}}
+
+	level := func(attr string) int {
+		processTree.DisplayOptions.ColorAttr = attr
+		return processTree.groupAttributeLevel(group)
+	}
+
+	// Each member is below the first cpu threshold, their sum is not
+	assert.Equal(t, 1, level("cpu"))
+	assert.Equal(t, 0, processTree.attributeLevel(processTree.Nodes[1]))
+	// The oldest member counts for the age
+	assert.Equal(t, 1, level("age"))
+	// Members whose value is unknown are left out
+	assert.Equal(t, 1, level("fds"))
+	assert.Equal(t, -1, level("mem"))
+}
+
+// TestCompactColorAttr tests that compacted lines are colored by the value of the whole group
+func TestCompactColorAttr(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "worker", HasCPU: true, CPUPercent: 3},
+		{PID: 200, PPID: 1, Command: "worker", HasCPU: true, CPUPercent: 3},
+	}
+	displayOptions := DisplayOptions{ColorAttr: "cpu", ColorCount: 8, ColorSupport: true, CompactMode: true, MaxDepth: 10, ScreenWidth: 200}
+	processTree := NewProcessTree(0, setupTestLogger(), processes, displayOptions)
+	output := renderProcessTree(t, processTree)
+
+	// The members are still compacted, and the line uses the color of the 6% they sum to
+	medium, low := "(c:6.00%)", "(c:6.00%)"
+	processTree.Colorizer.CPUMedium(processTree.ColorScheme, &medium)
+	processTree.Colorizer.CPULow(processTree.ColorScheme, &low)
+	require.NotEqual(t, medium, low)
+	assert.Contains(t, output, "2*[")
+	assert.Contains(t, output, medium)
+}
+
+func TestDurationFromProcessAge(t *testing.T) {
+	processTree := &ProcessTree{}
+
+	format := func(ageFormat string, age int64) string {
+		processTree.DisplayOptions = DisplayOptions{AgeFormat: ageFormat}
+		return processTree.durationFromProcessAge(age)
+	}
+
+	// 2 days, 4 hours, 13 minutes and 7 seconds
+	assert.Equal(t, "(02:04:13:07)", format("", 187987))
+	assert.Equal(t, "(02:04:13:07)", format("dhms", 187987))
+	assert.Equal(t, "(52:13:07)", format("hms", 187987))
+	assert.Equal(t, "(2d4h)", format("human", 187987))
+	assert.Equal(t, "(187987)", format("seconds", 187987))
+
+	// The human format starts at the largest non-zero unit
+	assert.Equal(t, "(5m7s)", format("human", 307))
+	assert.Equal(t, "(1d0h)", format("human", 86405))
+	assert.Equal(t, "(0s)", format("human", 0))
+	assert.Equal(t, "(0:00:00)", format("hms", 0))
+
+	// Unknown ages
+	for _, ageFormat := range []string{"dhms", "hms", "human", "seconds"} {
+		assert.Equal(t, "(?)", format(ageFormat, -1))
+	}
+}
+
 // TestMarkUIDTransitions tests the MarkUIDTransitions method
 func TestMarkUIDTransitions(t *testing.T) {
 	// Skip this test since the Process struct doesn't have a UIDTransition field
@@ -0,0 +1,66 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the selection of the line drawing characters. An explicit graphics flag
+// always wins; without one, the locale decides, so terminals using UTF-8 get the Unicode lines
+// while other locales fall back to plain ASCII instead of printing mojibake.
+package pstree
+
+import (
+	"strings"
+)
+
+// localeVariables lists the environment variables that set the character encoding, in order of precedence.
+var localeVariables = []string{"LC_ALL", "LC_CTYPE", "LANG"}
+
+// ResolveTreeStyle returns the name of the TreeStyles entry used to draw the tree.
+//
+// The graphics options are checked first. When none of them is set, the first non-empty
+// locale variable (LC_ALL, LC_CTYPE, then LANG) decides: a UTF-8 codeset, e.g., en_US.UTF-8,
+// selects utf8 and anything else selects ascii.
+//
+// Parameters:
+//   - opts: DisplayOptions struct holding the graphics options
+//   - env: The locale environment variables, a nil map always falls back to ascii
+//
+// Returns:
+//   - string: The name of the tree style, one of ascii, pc850, utf8, vt100
+func ResolveTreeStyle(opts DisplayOptions, env map[string]string) string {
+	switch {
+	case opts.ASCIIGraphics:
+		return "ascii"
+	case opts.IBM850Graphics:
+		return "pc850"
+	case opts.UTF8Graphics:
+		return "utf8"
+	case opts.VT100Graphics:
+		return "vt100"
+	}
+
+	for _, name := range localeVariables {
+		if value := env[name]; value != "" {
+			if isUTF8Locale(value) {
+				return "utf8"
+			}
+			break
+		}
+	}
+
+	return "ascii"
+}
+
+// isUTF8Locale reports whether a locale, e.g., en_US.UTF-8 or C.utf8, uses the UTF-8 codeset.
+//
+// Parameters:
+//   - locale: The value of a locale variable
+//
+// Returns:
+//   - bool: true if the codeset of the locale is UTF-8
+func isUTF8Locale(locale string) bool {
+	_, codeset, ok := strings.Cut(locale, ".")
+	if !ok {
+		return false
+	}
+	codeset, _, _ = strings.Cut(codeset, "@")
+	codeset = strings.ToLower(codeset)
+	return codeset == "utf-8" || codeset == "utf8"
+}
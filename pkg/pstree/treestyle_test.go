@@ -0,0 +1,38 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTreeStyle(t *testing.T) {
+	utf8Env := map[string]string{"LANG": "en_US.UTF-8"}
+
+	testCases := []struct {
+		name     string
+		opts     DisplayOptions
+		env      map[string]string
+		expected string
+	}{
+		{"no environment", DisplayOptions{}, nil, "ascii"},
+		{"utf-8 lang", DisplayOptions{}, utf8Env, "utf8"},
+		{"lowercase codeset", DisplayOptions{}, map[string]string{"LANG": "C.utf8"}, "utf8"},
+		{"codeset with modifier", DisplayOptions{}, map[string]string{"LANG": "de_DE.UTF-8@euro"}, "utf8"},
+		{"latin-1 lang", DisplayOptions{}, map[string]string{"LANG": "de_DE.ISO-8859-1"}, "ascii"},
+		{"posix locale", DisplayOptions{}, map[string]string{"LANG": "C"}, "ascii"},
+		{"lc_all overrides lang", DisplayOptions{}, map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "C"}, "ascii"},
+		{"lc_ctype overrides lang", DisplayOptions{}, map[string]string{"LANG": "C", "LC_CTYPE": "en_US.UTF-8"}, "utf8"},
+		{"empty lc_all is ignored", DisplayOptions{}, map[string]string{"LC_ALL": "", "LANG": "en_US.UTF-8"}, "utf8"},
+		{"ascii flag", DisplayOptions{ASCIIGraphics: true}, utf8Env, "ascii"},
+		{"ibm-850 flag", DisplayOptions{IBM850Graphics: true}, utf8Env, "pc850"},
+		{"utf-8 flag", DisplayOptions{UTF8Graphics: true}, nil, "utf8"},
+		{"vt-100 flag", DisplayOptions{VT100Graphics: true}, utf8Env, "vt100"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ResolveTreeStyle(tc.opts, tc.env))
+		})
+	}
+}
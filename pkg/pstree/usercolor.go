@@ -0,0 +1,142 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the coloring of processes by their owner (--color-attr=user). Each
+// username is hashed into a palette of colors, so a user keeps the same color from one run to
+// the next, while root is always shown in red. Red is left out of the palette so no other user
+// can be mistaken for root.
+package pstree
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// userColors256 is the palette used for --color-attr=user on terminals with 256 or more colors.
+var userColors256 = []ColorMap{
+	{R: 0x1f, G: 0x77, B: 0xb4},
+	{R: 0xff, G: 0x7f, B: 0x0e},
+	{R: 0x2c, G: 0xa0, B: 0x2c},
+	{R: 0x94, G: 0x67, B: 0xbd},
+	{R: 0x8c, G: 0x56, B: 0x4b},
+	{R: 0xe3, G: 0x77, B: 0xc2},
+	{R: 0xbc, G: 0xbd, B: 0x22},
+	{R: 0x17, G: 0xbe, B: 0xcf},
+	{R: 0xae, G: 0xc7, B: 0xe8},
+	{R: 0xff, G: 0xbb, B: 0x78},
+	{R: 0x98, G: 0xdf, B: 0x8a},
+	{R: 0xc5, G: 0xb0, B: 0xd5},
+	{R: 0xc4, G: 0x9c, B: 0x94},
+	{R: 0xf7, G: 0xb6, B: 0xd2},
+	{R: 0xdb, G: 0xdb, B: 0x8d},
+	{R: 0x9e, G: 0xda, B: 0xe5},
+}
+
+// userColors8 is the palette used for --color-attr=user on terminals with 8 or 16 colors.
+var userColors8 = []ColorFunc{
+	Color8Blue,
+	Color8Cyan,
+	Color8Green,
+	Color8Magenta,
+	Color8Yellow,
+	Color8BlueBold,
+	Color8CyanBold,
+	Color8GreenBold,
+	Color8MagentaBold,
+	Color8YellowBold,
+}
+
+// userColorFuncs returns the color functions for --color-attr=user.
+//
+// The first function colors root red, the others form the palette the remaining users are
+// assigned to by assignUserColors. The palette depends on DisplayOptions.ColorCount.
+//
+// Returns:
+//   - []ColorFunc: The color functions indexed by the level returned by attributeLevel
+func (processTree *ProcessTree) userColorFuncs() []ColorFunc {
+	var (
+		colorFuncs []ColorFunc
+	)
+
+	if processTree.DisplayOptions.ColorCount < 256 {
+		return append([]ColorFunc{Color8Red}, userColors8...)
+	}
+
+	colorFuncs = []ColorFunc{Color256Red}
+	for _, colorMap := range userColors256 {
+		colorFuncs = append(colorFuncs, func(cs ColorScheme, text *string) {
+			color256(colorMap, text)
+		})
+	}
+	return colorFuncs
+}
+
+// assignUserColors assigns a palette slot to each user other than root.
+//
+// The slot of a user is the FNV-1a hash of the username modulo the palette size. When that
+// slot is already taken, the next free slot is used, so no two users share a color as long as
+// there are no more users than slots. The usernames are assigned in sorted order, which makes
+// the result independent of the order of the processes.
+//
+// Parameters:
+//   - usernames: The usernames to assign, duplicates and root are ignored
+//   - slots: The number of colors in the palette
+//
+// Returns:
+//   - map[string]int: The palette slot of each username, from 0 to slots-1
+func assignUserColors(usernames []string, slots int) map[string]int {
+	var (
+		assigned map[string]int
+		sorted   []string
+		taken    map[int]bool
+	)
+
+	assigned = make(map[string]int)
+	if slots < 1 {
+		return assigned
+	}
+
+	for _, username := range usernames {
+		if username != "" && username != "root" {
+			assigned[username] = -1
+		}
+	}
+	for username := range assigned {
+		sorted = append(sorted, username)
+	}
+	sort.Strings(sorted)
+
+	taken = make(map[int]bool)
+	for _, username := range sorted {
+		hash := fnv.New32a()
+		hash.Write([]byte(username))
+		slot := int(hash.Sum32() % uint32(slots))
+
+		// Probe for a free slot, once the palette is exhausted the hashed slot is shared
+		if len(taken) < slots {
+			for taken[slot] {
+				slot = (slot + 1) % slots
+			}
+		}
+		taken[slot] = true
+		assigned[username] = slot
+	}
+
+	return assigned
+}
+
+// userLevel returns the level of a process for --color-attr=user.
+//
+// Parameters:
+//   - process: The process to classify
+//
+// Returns:
+//   - int: 0 for root, the palette slot of the owner plus one for other users, or -1 if the owner is unknown
+func (processTree *ProcessTree) userLevel(process *Process) int {
+	if process.Username == "root" {
+		return 0
+	}
+	if slot, ok := processTree.UserColors[process.Username]; ok {
+		return slot + 1
+	}
+	return -1
+}
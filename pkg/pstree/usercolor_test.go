@@ -0,0 +1,79 @@
+package pstree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignUserColors(t *testing.T) {
+	usernames := []string{"root", "alice", "bob", "postgres", "www-data", "alice", ""}
+
+	assigned := assignUserColors(usernames, 10)
+
+	// root and unknown owners are not assigned a palette slot
+	assert.Len(t, assigned, 4)
+	assert.NotContains(t, assigned, "root")
+	assert.NotContains(t, assigned, "")
+
+	// The assignment does not depend on the order of the processes
+	assert.Equal(t, assigned, assignUserColors([]string{"www-data", "postgres", "bob", "alice"}, 10))
+
+	// A palette without slots assigns nothing
+	assert.Empty(t, assignUserColors(usernames, 0))
+}
+
+func TestAssignUserColorsNoCollisions(t *testing.T) {
+	for _, slots := range []int{len(userColors8), len(userColors256)} {
+		for count := 1; count <= slots; count++ {
+			usernames := []string{}
+			for i := range count {
+				usernames = append(usernames, fmt.Sprintf("user%d", i))
+			}
+
+			taken := map[int]string{}
+			for username, slot := range assignUserColors(usernames, slots) {
+				require.GreaterOrEqual(t, slot, 0)
+				require.Less(t, slot, slots)
+				require.NotContains(t, taken, slot, "%s and %s share a color with %d slots", username, taken[slot], slots)
+				taken[slot] = username
+			}
+		}
+	}
+}
+
+func TestColorAttrUser(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Username: "root"},
+		{PID: 100, PPID: 1, Command: "bash", Username: "alice"},
+		{PID: 200, PPID: 1, Command: "postgres", Username: "postgres"},
+		{PID: 300, PPID: 1, Command: "sshd", Username: "root"},
+	}
+
+	for _, colorCount := range []int{8, 256} {
+		processTree := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{ColorAttr: "user", ColorCount: colorCount, ColorSupport: true, MaxDepth: 10, ScreenWidth: 80})
+
+		// root is always red, the other users each get their own color
+		assert.Equal(t, 0, processTree.attributeLevel(processTree.Nodes[0]))
+		assert.Equal(t, 0, processTree.attributeLevel(processTree.Nodes[3]))
+		assert.Positive(t, processTree.attributeLevel(processTree.Nodes[1]))
+		assert.NotEqual(t, processTree.attributeLevel(processTree.Nodes[1]), processTree.attributeLevel(processTree.Nodes[2]))
+
+		red := "init"
+		colorFuncs := processTree.attributeColorFuncs()
+		colorFuncs[0](processTree.ColorScheme, &red)
+		if colorCount == 8 {
+			assert.Equal(t, ColorSchemes["ansi8"].Red.Ansi+"init"+AnsiReset, red)
+		}
+
+		output := renderProcessTree(t, processTree)
+		assert.Contains(t, output, red)
+		assert.True(t, processTree.DisplayOptions.ShowOwner, "the owner is shown along with its color")
+
+		// The colors are the same on every run
+		again := NewProcessTree(0, setupTestLogger(), processes, DisplayOptions{ColorAttr: "user", ColorCount: colorCount, ColorSupport: true, MaxDepth: 10, ScreenWidth: 80})
+		assert.Equal(t, processTree.UserColors, again.UserColors)
+	}
+}
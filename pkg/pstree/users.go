@@ -0,0 +1,35 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the exclusion of users with --not-user or --user=!name, e.g., to show
+// everything except the processes of root and messagebus. The processes of an excluded user are
+// hidden, unless they are the ancestors of a displayed process, which are kept to show where it
+// runs, e.g., the sshd of root serving the session of a user. The users shown with --user take
+// precedence: their processes are marked first, then the processes of the excluded users are
+// hidden from their subtrees, like a process spawned with sudo within a session.
+package pstree
+
+import (
+	"slices"
+)
+
+// markExcludedUsers narrows the marked processes down to those not owned by one of the excluded
+// users, keeping their ancestors, see narrowMarked.
+func (processTree *ProcessTree) markExcludedUsers() {
+	processTree.Logger.Debug("Entering processTree.markExcludedUsers()")
+	processTree.narrowMarked(func(node *Process) bool {
+		// The orphans and container nodes are only shown as the parent of displayed processes
+		return !isSyntheticNode(node) && !processTree.isExcludedUser(node)
+	}, "is not owned by an excluded user")
+}
+
+// isExcludedUser reports whether a process is owned by one of the users excluded with
+// --not-user or --user=!name.
+//
+// Parameters:
+//   - node: The process to check
+//
+// Returns:
+//   - bool: true if the process is owned by an excluded user
+func (processTree *ProcessTree) isExcludedUser(node *Process) bool {
+	return slices.Contains(processTree.DisplayOptions.ExcludeUsernames, node.Username)
+}
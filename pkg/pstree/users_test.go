@@ -0,0 +1,27 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/bananazon/pstree/pkg/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkExcludedUsers(t *testing.T) {
+	// The processes of root are hidden, unless they are the ancestors of the session of alice
+	processTree := newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{ExcludeUsernames: []string{"root"}})
+	processTree.MarkProcesses()
+	assert.Equal(t, []int32{1, 100, 1000, 1001, 1002, 1003, 1004, 1005}, markedPIDs(processTree))
+
+	// The users shown with --user are marked first, then the excluded users are hidden from their subtrees
+	session := fixtures.SSHSession()
+	session[8].Username = "root"
+	processTree = newFixtureTree(t, session, DisplayOptions{ExcludeUsernames: []string{"root"}, Usernames: []string{"alice"}})
+	processTree.MarkProcesses()
+	assert.Equal(t, []int32{1, 100, 1000, 1001, 1002, 1003, 1004}, markedPIDs(processTree))
+
+	// Excluding every user leaves nothing
+	processTree = newFixtureTree(t, fixtures.SSHSession(), DisplayOptions{ExcludeUsernames: []string{"alice", "root"}})
+	processTree.MarkProcesses()
+	assert.Empty(t, markedPIDs(processTree))
+}
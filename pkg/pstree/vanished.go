@@ -0,0 +1,75 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the handling of processes that exit between the moment they are listed and
+// the moment their attributes are read. On a busy host, many short-lived processes vanish in
+// between, and every attribute of such a process falls back to its default value, so it used to
+// show up as a "[PID n]" node without a parent. generateProcess reads the PPID of each process
+// first, and when the read fails because the process no longer exists, the process is skipped
+// instead. With DisplayOptions.KeepVanished, i.e., --keep-vanished, they are kept the way they
+// were collected, e.g., to investigate what spawned them.
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// processProbe is the part of a process.Process read by readParent, an interface so processes that
+// vanished can be simulated.
+type processProbe interface {
+	// IsRunning reports whether the process is still running
+	IsRunning() (bool, error)
+	// Ppid returns the PID of the parent of the process
+	Ppid() (int32, error)
+}
+
+// isNotFound reports whether an error means that a process no longer exists.
+//
+// Parameters:
+//   - err: The error returned while reading an attribute of the process
+//
+// Returns:
+//   - bool: true if err is process.ErrorProcessNotRunning, ESRCH, or a missing /proc entry
+func isNotFound(err error) bool {
+	return errors.Is(err, process.ErrorProcessNotRunning) || errors.Is(err, syscall.ESRCH) || errors.Is(err, fs.ErrNotExist)
+}
+
+// readParent reads the PPID of a process, and whether the process vanished since it was listed.
+// A failure that doesn't tell, e.g., a permission error, is followed by one existence check, so a
+// process that can't be read is not mistaken for one that exited.
+//
+// Parameters:
+//   - proc: The process to read the parent of
+//
+// Returns:
+//   - ppid: The PID of the parent, or -1 if it could not be read
+//   - vanished: true if the process no longer exists
+//   - err: Any error encountered while reading the PPID
+func readParent(proc processProbe) (ppid int32, vanished bool, err error) {
+	ppid, err = proc.Ppid()
+	if err == nil {
+		return ppid, false, nil
+	}
+	if isNotFound(err) {
+		return -1, true, err
+	}
+
+	running, runErr := proc.IsRunning()
+	return -1, isNotFound(runErr) || (runErr == nil && !running), err
+}
+
+// logVanished logs the number of processes skipped because they vanished while they were
+// collected, at debug level.
+//
+// Parameters:
+//   - count: Number of processes skipped
+func logVanished(count int) {
+	if count == 0 {
+		return
+	}
+	repairLogger().Debug(fmt.Sprintf("Skipped %d processes that exited while they were collected", count))
+}
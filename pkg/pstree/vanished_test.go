@@ -0,0 +1,72 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProbe is a processProbe returning fixed results.
+type fakeProbe struct {
+	ppid       int32
+	ppidErr    error
+	running    bool
+	runningErr error
+}
+
+func (probe fakeProbe) IsRunning() (bool, error) {
+	return probe.running, probe.runningErr
+}
+
+func (probe fakeProbe) Ppid() (int32, error) {
+	return probe.ppid, probe.ppidErr
+}
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(process.ErrorProcessNotRunning))
+	assert.True(t, isNotFound(fmt.Errorf("kill: %w", syscall.ESRCH)))
+	assert.True(t, isNotFound(&os.PathError{Op: "open", Path: "/proc/1234/stat", Err: syscall.ENOENT}))
+	assert.False(t, isNotFound(&os.PathError{Op: "open", Path: "/proc/1234/stat", Err: syscall.EACCES}))
+	assert.False(t, isNotFound(nil))
+}
+
+func TestReadParent(t *testing.T) {
+	ppid, vanished, err := readParent(fakeProbe{ppid: 42, running: true})
+	assert.NoError(t, err)
+	assert.False(t, vanished)
+	assert.Equal(t, int32(42), ppid)
+
+	// Every method of a process that exited fails with not found
+	ppid, vanished, err = readParent(fakeProbe{ppidErr: process.ErrorProcessNotRunning, runningErr: process.ErrorProcessNotRunning})
+	assert.Error(t, err)
+	assert.True(t, vanished)
+	assert.Equal(t, int32(-1), ppid)
+
+	// Other errors are followed by an existence check
+	_, vanished, err = readParent(fakeProbe{ppidErr: syscall.EACCES, running: true})
+	assert.Error(t, err)
+	assert.False(t, vanished)
+
+	_, vanished, _ = readParent(fakeProbe{ppidErr: syscall.EACCES, running: false})
+	assert.True(t, vanished)
+
+	_, vanished, _ = readParent(fakeProbe{ppidErr: syscall.EACCES, runningErr: syscall.ESRCH})
+	assert.True(t, vanished)
+}
+
+func TestGenerateProcessVanished(t *testing.T) {
+	// No system uses PIDs this high, so every method fails the way it does for a process that exited
+	proc := &process.Process{Pid: 2147483646}
+
+	_, ok := GenerateProcess(proc, DisplayOptions{})
+	assert.False(t, ok)
+
+	generated, ok := GenerateProcess(proc, DisplayOptions{KeepVanished: true})
+	assert.True(t, ok)
+	assert.Equal(t, int32(2147483646), generated.PID)
+	assert.Equal(t, int32(-1), generated.PPID)
+}
@@ -0,0 +1,67 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains helpers for watch mode, where the process tree is collected and
+// redrawn at a fixed interval. Successive snapshots are compared so that the CPU
+// percentage reflects the usage during the last interval instead of the lifetime
+// average reported by gopsutil.
+package pstree
+
+import (
+	"time"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// CPUTimesByPID returns the total CPU time (user + system) in seconds consumed by each process.
+//
+// Processes without collected CPU times are omitted from the result. The returned map is
+// meant to be kept between two watch mode refreshes and passed to ApplyIntervalCPUPercent.
+//
+// Parameters:
+//   - processes: Slice of Process structs to read the CPU times from
+//
+// Returns:
+//   - map[int32]float64: Map of PID to consumed CPU seconds
+func CPUTimesByPID(processes []Process) map[int32]float64 {
+	cpuTimes := make(map[int32]float64, len(processes))
+	for i := range processes {
+		if processes[i].CPUTimes == nil {
+			continue
+		}
+		cpuTimes[processes[i].PID] = processes[i].CPUTimes.User + processes[i].CPUTimes.System
+	}
+	return cpuTimes
+}
+
+// ApplyIntervalCPUPercent replaces the CPU percentage of each process with the usage measured
+// between the previous snapshot and this one.
+//
+// Processes that were not present in the previous snapshot, or whose CPU times could not be
+// collected, keep the percentage reported by gopsutil. A process whose consumed CPU time went
+// backwards (PID reuse) is treated the same way.
+//
+// Parameters:
+//   - processes: Slice of Process structs from the current snapshot (modified in place)
+//   - previous: Map of PID to consumed CPU seconds from the previous snapshot, see CPUTimesByPID
+//   - elapsed: Wall clock time between the two snapshots
+func ApplyIntervalCPUPercent(processes []Process, previous map[int32]float64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+
+	for i := range processes {
+		if processes[i].CPUTimes == nil {
+			continue
+		}
+		before, ok := previous[processes[i].PID]
+		if !ok {
+			continue
+		}
+		delta := processes[i].CPUTimes.User + processes[i].CPUTimes.System - before
+		if delta < 0 {
+			continue
+		}
+		processes[i].CPUPercent = util.RoundFloat(delta/elapsed.Seconds()*100, 2)
+		processes[i].HasCPU = true
+	}
+}
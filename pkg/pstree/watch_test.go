@@ -0,0 +1,51 @@
+package pstree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPUTimesByPID(t *testing.T) {
+	processes := []Process{
+		{PID: 100, CPUTimes: &cpu.TimesStat{User: 1.5, System: 0.5}},
+		{PID: 200},
+	}
+
+	cpuTimes := CPUTimesByPID(processes)
+
+	// Only processes with collected CPU times are included
+	assert.Equal(t, map[int32]float64{100: 2.0}, cpuTimes)
+}
+
+func TestApplyIntervalCPUPercent(t *testing.T) {
+	processes := []Process{
+		{PID: 100, HasCPU: true, CPUPercent: 50.0, CPUTimes: &cpu.TimesStat{User: 3.0, System: 1.0}},
+		{PID: 200, HasCPU: true, CPUPercent: 7.0, CPUTimes: &cpu.TimesStat{User: 1.0}},
+		{PID: 300, HasCPU: true, CPUPercent: 9.0, CPUTimes: &cpu.TimesStat{User: 1.0}},
+		{PID: 400, HasCPU: true, CPUPercent: 3.0},
+	}
+	previous := map[int32]float64{
+		100: 3.0, // used 1 second of CPU time in 2 seconds
+		300: 5.0, // went backwards, the PID was reused
+		400: 1.0,
+	}
+
+	ApplyIntervalCPUPercent(processes, previous, 2*time.Second)
+
+	assert.Equal(t, 50.0, processes[0].CPUPercent) // 1 second over 2 seconds
+	assert.Equal(t, 7.0, processes[1].CPUPercent)  // new process keeps the lifetime value
+	assert.Equal(t, 9.0, processes[2].CPUPercent)  // reused PID keeps the lifetime value
+	assert.Equal(t, 3.0, processes[3].CPUPercent)  // no CPU times collected
+
+	processes[0].CPUTimes = &cpu.TimesStat{User: 4.0, System: 1.0}
+	ApplyIntervalCPUPercent(processes, previous, 4*time.Second)
+	assert.Equal(t, 50.0, processes[0].CPUPercent) // 2 seconds over 4 seconds
+
+	// A zero interval leaves the values untouched
+	processes[0].CPUPercent = 1.0
+	ApplyIntervalCPUPercent(processes, previous, 0)
+	assert.Equal(t, 1.0, processes[0].CPUPercent)
+}
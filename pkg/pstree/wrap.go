@@ -0,0 +1,153 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the wrapping of long lines (--wrap). Instead of truncating a line at the
+// screen width, the overflow is continued on the following lines, indented to line up under the
+// process entry. The continuation lines repeat the vertical branch characters of the ancestors
+// and of the process itself, so the tree stays intact around the wrapped entry.
+package pstree
+
+import (
+	"strings"
+
+	"github.com/bananazon/pstree/util"
+)
+
+// minWrapWidth is the narrowest continuation worth wrapping onto; narrower lines are truncated instead.
+const minWrapWidth = 10
+
+// wrapLine wraps a line that is wider than the screen onto continuation lines.
+//
+// The first line is filled up to DisplayOptions.ScreenWidth. Each continuation line starts with
+// the branch characters of newHead, followed by a vertical bar when the process has children
+// below it, and is padded to the column of the process entry. Colors active at a break are reset
+// at the end of the line and restored after the continuation prefix. When the entry starts too
+// close to the right edge to leave minWrapWidth columns, the line is truncated as usual.
+//
+// Parameters:
+//   - line: The formatted line, which may contain ANSI escape sequences
+//   - head: The head the line was built with
+//   - newHead: The head of the children of the process, as returned by buildNewHead
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - []string: The line followed by its continuation lines
+func (processTree *ProcessTree) wrapLine(line string, head string, newHead string, pidIndex int) []string {
+	var (
+		bar         string
+		depthWidth  int
+		indent      int
+		lines       []string
+		prefix      string
+		rest        string
+		screenWidth int
+		segment     string
+		treeWidth   int
+		wrapWidth   int
+	)
+
+	screenWidth = processTree.DisplayOptions.ScreenWidth
+	if util.VisibleWidth(line) <= screenWidth {
+		return []string{line}
+	}
+
+	// The process entry starts one column after the depth and the branch characters of its own line
+	depthWidth = util.VisibleWidth(processTree.depthLabel(pidIndex))
+	indent = depthWidth + util.VisibleWidth(processTree.buildLinePrefix(head, pidIndex)) + 1
+	wrapWidth = screenWidth - indent
+	if wrapWidth < minWrapWidth {
+		return []string{processTree.truncateANSI(line)}
+	}
+
+	bar = " "
+	if processTree.hasVisibleChild(pidIndex) {
+		bar = processTree.TreeChars.Bar
+	}
+	prefix = processTree.TreeChars.SG + newHead + bar + processTree.TreeChars.EG
+	treeWidth = depthWidth + util.VisibleWidth(prefix)
+	processTree.colorizeField("prefix", &prefix, pidIndex)
+	prefix = strings.Repeat(" ", depthWidth) + prefix + strings.Repeat(" ", max(indent-treeWidth, 0))
+
+	segment, rest = splitANSI(line, screenWidth)
+	lines = append(lines, segment)
+	for rest != "" {
+		if util.VisibleWidth(rest) == 0 {
+			// Only escape sequences are left, e.g., the final color reset
+			lines[len(lines)-1] += rest
+			break
+		}
+		segment, rest = splitANSI(rest, wrapWidth)
+		lines = append(lines, prefix+segment)
+	}
+
+	return lines
+}
+
+// hasVisibleChild reports whether any child of a process is printed below it, taking the
+// maximum depth and the children hidden by compact mode into account.
+//
+// Parameters:
+//   - pidIndex: Index of the process in the Nodes array
+//
+// Returns:
+//   - bool: true if at least one child of the process is printed
+func (processTree *ProcessTree) hasVisibleChild(pidIndex int) bool {
+	// The children of a collapsed chain are the ones of its deepest process, see chainEnd
+	last, length := processTree.chainEnd(pidIndex)
+	if processTree.AtDepth+length+1 > processTree.DisplayOptions.MaxDepth {
+		return false
+	}
+
+	child := processTree.Nodes[last].Child
+	for child != -1 {
+		if !processTree.DisplayOptions.CompactMode || !ShouldSkipProcess(child) {
+			return true
+		}
+		child = processTree.Nodes[child].Sister
+	}
+	return false
+}
+
+// splitANSI splits a string containing ANSI escape sequences after the given visible width.
+//
+// The escape sequences are never split and are not counted toward the width. When colors are
+// active at the split, the first part ends with a reset and the second part starts with the
+// sequences needed to restore them, so each part can be printed on its own line.
+//
+// Parameters:
+//   - input: The string to split
+//   - width: The visible width of the first part
+//
+// Returns:
+//   - string: The first part, at most width columns wide
+//   - string: The remainder, empty if the whole string fits
+func splitANSI(input string, width int) (string, string) {
+	var (
+		active   strings.Builder
+		consumed int
+		output   strings.Builder
+		visible  int
+	)
+
+	util.WalkVisible(input, func(segment string, segmentWidth int, escape bool) bool {
+		if escape {
+			if segment == "\x1b[0m" || segment == "\x1b[m" {
+				active.Reset()
+			} else {
+				active.WriteString(segment)
+			}
+		} else if visible+segmentWidth > width && visible > 0 {
+			return false
+		}
+
+		output.WriteString(segment)
+		visible += segmentWidth
+		consumed += len(segment)
+		return true
+	})
+	input = input[consumed:]
+
+	if input == "" || active.Len() == 0 {
+		return output.String(), input
+	}
+	return output.String() + "\x1b[0m", active.String() + input
+}
@@ -0,0 +1,69 @@
+package pstree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bananazon/pstree/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapLines(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init"},
+		{PID: 100, PPID: 1, Command: "java", Args: []string{"-Xmx4g", "-cp", "/opt/app/lib/*", "com.example.Main"}},
+		{PID: 101, PPID: 100, Command: "bash"},
+		{PID: 200, PPID: 1, Command: "cron", Args: []string{"-f", "-l", "-L", "15", "-s", "/etc/crontab"}},
+	}
+
+	output := renderTree(t, processes, DisplayOptions{ScreenWidth: 24, ShowArguments: true, WrapLines: true})
+
+	// The continuation lines keep the bar of the sibling below and of the children of java
+	assert.Equal(t, "-+- init \n"+
+		" |-+- java -Xmx4g -cp /o\n"+
+		" | |  pt/app/lib/* com.e\n"+
+		" | |  xample.Main\n"+
+		" | \\--- bash \n"+
+		" \\--- cron -f -l -L 15 -\n"+
+		"      s /etc/crontab\n", output)
+
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		assert.LessOrEqual(t, util.VisibleWidth(line), 24, "line %q is wider than the screen", line)
+	}
+}
+
+func TestWrapLinesTooNarrow(t *testing.T) {
+	processes := []Process{
+		{PID: 1, PPID: 0, Command: "init", Args: []string{"--system", "--deserialize", "42"}},
+	}
+
+	// Without room for a continuation the line is truncated as before
+	output := renderTree(t, processes, DisplayOptions{ScreenWidth: 12, ShowArguments: true, WrapLines: true})
+	assert.Equal(t, "-+- init --+\n", output)
+}
+
+func TestSplitANSI(t *testing.T) {
+	// Escape sequences are not counted and the active color is carried over
+	first, rest := splitANSI("\x1b[31mabcdef\x1b[0m", 4)
+	assert.Equal(t, "\x1b[31mabcd\x1b[0m", first)
+	assert.Equal(t, "\x1b[31mef\x1b[0m", rest)
+
+	// A reset before the split leaves nothing to restore
+	first, rest = splitANSI("\x1b[31mab\x1b[0mcdef", 4)
+	assert.Equal(t, "\x1b[31mab\x1b[0mcd", first)
+	assert.Equal(t, "ef", rest)
+
+	// Wide characters are not split
+	first, rest = splitANSI("日本語", 5)
+	assert.Equal(t, "日本", first)
+	assert.Equal(t, "語", rest)
+
+	// Neither are characters joined from several code points
+	first, rest = splitANSI("ab👩‍💻cd", 3)
+	assert.Equal(t, "ab", first)
+	assert.Equal(t, "👩‍💻cd", rest)
+
+	first, rest = splitANSI("short", 10)
+	assert.Equal(t, "short", first)
+	assert.Empty(t, rest)
+}
@@ -0,0 +1,48 @@
+// Package pstree provides functionality for building and displaying process trees.
+//
+// This file contains the zombie detection (--zombies and --only-zombies). A zombie has exited
+// but was not reaped by its parent yet, so it keeps its PID until the parent waits for it.
+// With --zombies, zombies are marked with a <defunct> suffix like ps does, and shown in red when
+// colors are enabled. --only-zombies narrows the tree down to the zombies and their ancestors,
+// which shows the parents that fail to reap their children.
+package pstree
+
+import (
+	"fmt"
+)
+
+// DefunctSuffix is appended to the command of a zombie process with --zombies.
+const DefunctSuffix = "<defunct>"
+
+// IsZombie determines whether a process is a zombie.
+//
+// The status of the process is only collected with --status, --zombies, or --only-zombies, so
+// without them no process is a zombie.
+//
+// Parameters:
+//   - p: The process to check
+//
+// Returns:
+//   - bool: true if the process is a zombie, false otherwise
+func IsZombie(p Process) bool {
+	return !p.IsThread && StatusLetter(p.Status) == "Z"
+}
+
+// markZombies unmarks the processes that are not zombies, keeping the ancestors of the zombies.
+func (processTree *ProcessTree) markZombies() {
+	processTree.Logger.Debug("Entering processTree.markZombies()")
+	processTree.narrowMarked(func(node *Process) bool {
+		return IsZombie(*node)
+	}, "is a zombie")
+}
+
+// flagZombie appends DefunctSuffix to the command of a zombie process when --zombies is used.
+//
+// Parameters:
+//   - value: Pointer to the command to be flagged (modified in place)
+//   - pidIndex: Index of the process in the Nodes array
+func (processTree *ProcessTree) flagZombie(value *string, pidIndex int) {
+	if processTree.DisplayOptions.ShowZombies && IsZombie(*processTree.Nodes[pidIndex]) {
+		*value = fmt.Sprintf("%s %s", *value, DefunctSuffix)
+	}
+}
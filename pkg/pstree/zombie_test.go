@@ -0,0 +1,71 @@
+package pstree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// zombieTestProcesses returns a process list where a worker fails to reap its children:
+//
+//	init(1) -+- worker(10) -+- job(11) <defunct>
+//	         |              |- job(12) <defunct>
+//	         |              \- job(13)
+//	         \- cron(20)
+func zombieTestProcesses() []Process {
+	return []Process{
+		{PID: 1, PPID: 0, Command: "init", Status: []string{"sleep"}},
+		{PID: 10, PPID: 1, Command: "worker", Status: []string{"sleep"}},
+		{PID: 11, PPID: 10, Command: "job", Status: []string{"zombie"}},
+		{PID: 12, PPID: 10, Command: "job", Status: []string{"zombie"}},
+		{PID: 13, PPID: 10, Command: "job", Status: []string{"running"}},
+		{PID: 20, PPID: 1, Command: "cron", Status: []string{"sleep"}},
+	}
+}
+
+func TestIsZombie(t *testing.T) {
+	assert.True(t, IsZombie(Process{Status: []string{"zombie"}}))
+	assert.False(t, IsZombie(Process{Status: []string{"sleep"}}))
+	assert.False(t, IsZombie(Process{}), "the status was not collected")
+	assert.False(t, IsZombie(Process{Status: []string{"zombie"}, IsThread: true}))
+}
+
+func TestMarkZombies(t *testing.T) {
+	processTree := NewProcessTree(0, setupTestLogger(), zombieTestProcesses(), DisplayOptions{OnlyZombies: true, ShowZombies: true})
+	processTree.MarkProcesses()
+	assert.Equal(t, []int32{1, 10, 11, 12}, markedPIDs(processTree))
+
+	// The other filters still apply
+	processTree = NewProcessTree(0, setupTestLogger(), zombieTestProcesses(), DisplayOptions{Contains: "cron", OnlyZombies: true, ShowZombies: true})
+	processTree.MarkProcesses()
+	assert.Equal(t, []int32{}, markedPIDs(processTree))
+}
+
+func TestRenderZombies(t *testing.T) {
+	render := func(displayOptions DisplayOptions) string {
+		return renderTree(t, zombieTestProcesses(), displayOptions)
+	}
+
+	// Zombies are flagged and not grouped with the live process of the same name
+	output := render(DisplayOptions{CompactMode: true, ShowZombies: true, ShowPIDs: true})
+	assert.Regexp(t, `2\*\[job\] \(11,12\) <defunct>`, output)
+	assert.Regexp(t, `\(13\) job\s*\n`, output)
+
+	// Without --zombies, nothing is flagged
+	output = render(DisplayOptions{})
+	assert.NotContains(t, output, DefunctSuffix)
+
+	// Zombies are shown in red with --color-attr as well
+	colorOptions := DisplayOptions{ColorAttr: "cpu", ColorSupport: true, ColorCount: 256, ShowZombies: true}
+	output = render(colorOptions)
+	red := "job <defunct>"
+	colorTree := NewProcessTree(0, setupTestLogger(), zombieTestProcesses(), colorOptions)
+	colorTree.Colorizer.StatusZombie(colorTree.ColorScheme, &red)
+	assert.Contains(t, output, red)
+
+	// The summary counts the zombies
+	processTree := NewProcessTree(0, setupTestLogger(), zombieTestProcesses(), DisplayOptions{ShowZombies: true})
+	processTree.MarkProcesses()
+	processTree.DropUnmarked()
+	assert.Equal(t, "6 processes, 1 user, 2 zombies", processTree.Summarize().String())
+}
@@ -8,10 +8,11 @@ import (
 
 // main is the entry point for the pstree application.
 // It executes the root command and handles any errors that occur.
-// If an error is encountered, the program will exit with a non-zero status code.
+// If an error is encountered, the program will exit with a non-zero status code:
+// 1 when no processes match the filters or pstree fails, and 2 for an invalid command line.
 func main() {
 	err := cmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
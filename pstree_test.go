@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var binaryPath string
@@ -92,6 +94,9 @@ func TestVersionFlag(t *testing.T) {
 		shouldFail bool
 	}{
 		{"Version", []string{"--version"}, false},
+		{"VersionCommand", []string{"version"}, false},
+		{"VersionJSON", []string{"--version", "--output=json"}, false},
+		{"VersionCommandInvalidOutput", []string{"version", "--output=csv"}, true},
 	}
 
 	for _, tc := range testCases {
@@ -119,6 +124,8 @@ func TestCommandLineArgs(t *testing.T) {
 		{"Version", []string{"pstree", "--version"}, false},
 		{"ShowPIDs", []string{"pstree", "--show-pids"}, false},
 		{"ShowPPIDs", []string{"pstree", "--show-ppids"}, false},
+		{"ShowParent", []string{"pstree", "--show-parent"}, false},
+		{"ShowParentCSV", []string{"pstree", "--show-parent", "--output", "csv", "--fields", "parent,pid"}, false},
 		{"ShowPGIDs", []string{"pstree", "--show-pgids"}, false},
 		{"ShowAllPidStuff", []string{"pstree", "--show-pids", "--show-ppids", "--show-pgids"}, false},
 		{"ShowOwner", []string{"pstree", "--show-owner"}, false},
@@ -134,6 +141,126 @@ func TestCommandLineArgs(t *testing.T) {
 		{"ValidOrderBy", []string{"pstree", "--order-by", "cpu"}, false},
 		{"InvalidOrderBy", []string{"pstree", "--order-by", "invalid"}, true},
 		{"SetUTF8andVT100", []string{"pstree", "--utf-8", "--vt-100"}, true},
+		{"SetASCIIandUTF8", []string{"pstree", "--ascii", "--utf-8"}, true},
+		{"SetWrapAndWide", []string{"pstree", "--wrap", "--wide"}, true},
+		{"CommandFormatFull", []string{"pstree", "--command-format", "full"}, false},
+		{"InvalidCommandFormat", []string{"pstree", "--command-format", "short"}, true},
+		{"NoKernelThreads", []string{"pstree", "--no-kernel-threads"}, false},
+		{"MemFieldVMS", []string{"pstree", "--mem-field", "vms", "--mem-unit", "G"}, false},
+		{"InvalidMemField", []string{"pstree", "--mem-field", "pss"}, true},
+		{"InvalidMemUnit", []string{"pstree", "--mem-unit", "T"}, true},
+		{"MemPercent", []string{"pstree", "--mem-percent"}, false},
+		{"MemFormatBoth", []string{"pstree", "--mem-percent", "--mem-format", "both"}, false},
+		{"MemPercentFormatAbs", []string{"pstree", "--mem-percent", "--mem-format", "abs"}, true},
+		{"InvalidMemFormat", []string{"pstree", "--mem-format", "ratio"}, true},
+		{"KillDryRun", []string{"pstree", "--pid", "1", "--kill", "TERM", "--dry-run"}, false},
+		{"KillWithoutFilter", []string{"pstree", "--kill", "TERM"}, true},
+		{"InvalidKillSignal", []string{"pstree", "--pid", "1", "--kill", "STOP"}, true},
+		{"YesWithoutKill", []string{"pstree", "--yes"}, true},
+		{"KillWatch", []string{"pstree", "--pid", "1", "--kill", "TERM", "--watch"}, true},
+		{"Zombies", []string{"pstree", "--zombies", "--summary"}, false},
+		{"Quiet", []string{"pstree", "--io", "--fds", "--quiet"}, false},
+		{"ByPgroup", []string{"pstree", "--by-pgroup"}, false},
+		{"ByPgroupWithPGIDs", []string{"pstree", "--by-pgroup", "--show-pids", "--show-pgids"}, false},
+		{"ByPgroupWithShowOrphans", []string{"pstree", "--by-pgroup", "--show-orphans"}, true},
+		{"ShowSIDs", []string{"pstree", "--sids", "--show-pids"}, false},
+		{"Session", []string{"pstree", "--session", "1"}, false},
+		{"SessionZero", []string{"pstree", "--session", "0"}, true},
+		{"Stream", []string{"pstree", "--stream", "--show-pids", "--show-ppids"}, false},
+		{"StreamWithAlign", []string{"pstree", "--stream", "--align"}, true},
+		{"StreamWithJSON", []string{"pstree", "--stream", "--output", "json"}, true},
+		{"Privileged", []string{"pstree", "--privileged", "--compact-not"}, false},
+		{"OnlyPrivileged", []string{"pstree", "--pid", "1", "--only-privileged", "--show-owner"}, false},
+		{"MemModePSS", []string{"pstree", "--mem-mode", "pss", "--order-by", "mem"}, false},
+		{"InvalidMemMode", []string{"pstree", "--mem-mode", "wss"}, true},
+		{"MemModeWithVMS", []string{"pstree", "--mem-mode", "uss", "--mem-field", "vms"}, true},
+		{"Group", []string{"pstree", "--group", "0", "--group", "nonexistentgroup123456789"}, false},
+		{"ShowDepth", []string{"pstree", "--show-depth", "--depth-stats", "--level", "2"}, false},
+		{"OutputMarkdown", []string{"pstree", "--output", "markdown", "--cpu"}, false},
+		{"ShowDepthWithCSV", []string{"pstree", "--show-depth", "--output", "csv"}, true},
+		{"ParentsOf", []string{"pstree", "--parents-of", "1", "--with-children"}, false},
+		{"ParentsOfWithPID", []string{"pstree", "--parents-of", "1", "--pid", "1"}, true},
+		{"ParentsOfInvalid", []string{"pstree", "--parents-of", "0"}, true},
+		{"WithChildrenWithoutParentsOf", []string{"pstree", "--with-children"}, true},
+		{"CpuTime", []string{"pstree", "--cpu-time", "--order-by", "cputime"}, false},
+		{"ColorAttrCpuTime", []string{"pstree", "--color-attr", "cputime"}, false},
+		{"IO", []string{"pstree", "--io", "--order-by", "io"}, false},
+		{"PageFaults", []string{"pstree", "--page-faults", "--order-by", "faults"}, false},
+		{"PageFaultsAll", []string{"pstree", "--page-faults=all"}, false},
+		{"InvalidPageFaults", []string{"pstree", "--page-faults=minor"}, true},
+		{"EnvContainsNoMatch", []string{"pstree", "--env-contains", "PSTREE_TEST_MISSING=1", "--env-show"}, true},
+		{"EnvContainsNoName", []string{"pstree", "--env-contains", "=on"}, true},
+		{"EnvShowWithoutEnvContains", []string{"pstree", "--env-show"}, true},
+		{"Cwd", []string{"pstree", "--cwd", "--cwd-under", "/"}, false},
+		{"CwdUnderEmpty", []string{"pstree", "--cwd-under="}, true},
+		{"DeletedMarker", []string{"pstree", "--deleted-marker", "(!)"}, false},
+		{"SnapshotRepairRefetch", []string{"pstree", "--snapshot-repair", "refetch"}, false},
+		{"SnapshotRepairReparent", []string{"pstree", "--snapshot-repair=reparent", "--show-orphans"}, false},
+		{"InvalidSnapshotRepair", []string{"pstree", "--snapshot-repair=retry"}, true},
+		{"Diff", []string{"pstree", "--diff", "1", "--show-pids"}, false},
+		{"DiffInvalid", []string{"pstree", "--diff", "0"}, true},
+		{"DiffMissingFile", []string{"pstree", "--diff", "/nonexistent/before.json"}, true},
+		{"DiffWithWatch", []string{"pstree", "--diff", "1", "--watch"}, true},
+		{"Top", []string{"pstree", "--top", "5", "--order-by", "cpu"}, false},
+		{"TopWithoutOrderBy", []string{"pstree", "--top", "5"}, true},
+		{"TopZero", []string{"pstree", "--top", "0", "--order-by", "cpu"}, true},
+		{"MaxArgs", []string{"pstree", "--max-args", "2"}, false},
+		{"MaxArgsZero", []string{"pstree", "--max-args", "0"}, true},
+		{"RawArgs", []string{"pstree", "--raw-args", "--output", "csv"}, false},
+		{"LookupTimeout", []string{"pstree", "--lookup-timeout", "1s", "--show-owner"}, false},
+		{"RootCmdRegex", []string{"pstree", "--root-cmd-regex", "."}, false},
+		{"NotUser", []string{"pstree", "--not-user", "nobody"}, false},
+		{"UserExclusion", []string{"pstree", "--user", "!nobody"}, false},
+		{"UnknownAndKnownUsers", []string{"pstree", "--user", "nosuchuser1,root,nosuchuser2"}, false},
+		{"CollapseChains", []string{"pstree", "--collapse-chains"}, false},
+		{"CollapseChainsShowOwner", []string{"pstree", "--collapse-chains", "--show-owner", "--level", "3"}, false},
+		{"YoungerThan", []string{"pstree", "--younger-than", "10000d", "--contains", "pstree"}, false},
+		{"AgeWindow", []string{"pstree", "--older-than", "1s", "--younger-than", "10000d", "--pid", "1"}, false},
+		{"ArgsFilter", []string{"pstree", "--args-filter", "^-", "--max-args", "1"}, false},
+		{"ArgsFilterInvalid", []string{"pstree", "--args-filter", "("}, true},
+		{"NoRootLine", []string{"pstree", "--pid", "1", "--no-root-line"}, false},
+		{"NoRootLineWithoutPID", []string{"pstree", "--no-root-line"}, true},
+		{"NoCompact", []string{"pstree", "--no-compact"}, false},
+		{"ColorAttrCompact", []string{"pstree", "--color-attr", "cpu", "--contains", "pstree"}, false},
+		{"Nice", []string{"pstree", "--nice"}, false},
+		{"HighlightNice", []string{"pstree", "--highlight-nice"}, false},
+		{"OrderByNice", []string{"pstree", "--order-by", "nice"}, false},
+		{"Sched", []string{"pstree", "--sched"}, false},
+		{"OnlyRealtime", []string{"pstree", "--only-realtime", "--sched"}, false},
+		{"Containers", []string{"pstree", "--containers"}, false},
+		{"GroupByContainer", []string{"pstree", "--group-by-container"}, false},
+		{"GroupByContainerWithPID", []string{"pstree", "--group-by-container", "--pid", "1"}, true},
+		{"Namespaces", []string{"pstree", "--namespaces"}, false},
+		{"OnlyForeignNS", []string{"pstree", "--only-foreign-ns"}, false},
+		{"ServeWithWatch", []string{"pstree", "--serve", ":0", "--watch"}, true},
+		{"ProfileWithWatch", []string{"pstree", "--profile", "/tmp/pstree", "--watch"}, true},
+		{"AlignWithOutput", []string{"pstree", "--align", "--output=csv"}, true},
+		{"KeepVanished", []string{"pstree", "--keep-vanished"}, false},
+		{"KeepVanishedWithFromFile", []string{"pstree", "--keep-vanished", "--from-file=snapshot.json"}, true},
+		{"Fields", []string{"pstree", "--fields", "mem,cpu,user", "--threads"}, false},
+		{"FieldsUnknown", []string{"pstree", "--fields", "cpu,rss"}, true},
+		{"PidOrderBy", []string{"pstree", "--pid", "1", "--order-by", "cpu"}, false},
+		{"CpuModeSolaris", []string{"pstree", "--cpu-mode", "solaris", "--color-attr", "cpu"}, false},
+		{"CpuModeInvalid", []string{"pstree", "--cpu-mode", "linux"}, true},
+		{"StartTime", []string{"pstree", "--start-time", "--age"}, false},
+		{"StartTimeUTC", []string{"pstree", "--start-time", "--utc", "--output", "csv"}, false},
+		{"OrderByStart", []string{"pstree", "--order-by", "start", "--order-dir", "desc"}, false},
+		{"UTCWithoutStartTime", []string{"pstree", "--utc"}, true},
+		{"ZeroLookupTimeout", []string{"pstree", "--lookup-timeout", "0s"}, true},
+		{"RootCmdNotFound", []string{"pstree", "--root-cmd", "no-such-command"}, true},
+		{"RootCmdWithPid", []string{"pstree", "--root-cmd", "init", "--pid", "1"}, true},
+		{"RootCmdAndRegex", []string{"pstree", "--root-cmd", "init", "--root-cmd-regex", "init"}, true},
+		{"InvalidRootCmdRegex", []string{"pstree", "--root-cmd-regex", "("}, true},
+		{"UserShownAndHidden", []string{"pstree", "--user", "root", "--not-user", "root"}, true},
+		{"UnknownUsers", []string{"pstree", "--user", "nosuchuser1,nosuchuser2"}, true},
+		{"CollapseChainsCSV", []string{"pstree", "--collapse-chains", "--output", "csv"}, true},
+		{"InvalidYoungerThan", []string{"pstree", "--younger-than", "5"}, true},
+		{"EmptyAgeWindow", []string{"pstree", "--younger-than", "1h", "--older-than", "1d"}, true},
+		{"PidNotFound", []string{"pstree", "--pid", "99999999", "--order-by", "cpu"}, true},
+		{"Numeric", []string{"pstree", "--numeric", "--order-by", "user"}, false},
+		{"NumericUserTransitions", []string{"pstree", "--numeric", "--user-transitions"}, false},
+		{"ColorAttrUser", []string{"pstree", "--color-attr", "user"}, false},
+		{"ColorAttrUserThresholds", []string{"pstree", "--color-attr", "user", "--attr-thresholds", "1,2"}, true},
 		{"ShowLotsOfStuff", []string{"pstree", "--show-owner", "--show-pids", "--show-ppids", "--show-pgids",
 			"--age", "--cpu", "--memory", "--threads", "--user-transitions"}, false},
 	}
@@ -151,3 +278,80 @@ func TestCommandLineArgs(t *testing.T) {
 		})
 	}
 }
+
+// TestTruncationWithoutColors tests that the lines truncated to the screen width get no escape
+// sequences when colors are disabled
+func TestTruncationWithoutColors(t *testing.T) {
+	// GNU sleep adds up its arguments, the zeros only make the command line wider than the screen
+	sleepArgs := []string{"30"}
+	for range 100 {
+		sleepArgs = append(sleepArgs, "0")
+	}
+	sleep := exec.Command("sleep", sleepArgs...)
+	require.NoError(t, sleep.Start())
+	defer func() {
+		_ = sleep.Process.Kill()
+		_ = sleep.Wait()
+	}()
+
+	for _, test := range []struct {
+		name string
+		args []string
+		env  []string
+	}{
+		{"Never", []string{"--color=never"}, nil},
+		{"NoColor", []string{}, []string{"NO_COLOR=1"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, append([]string{"--pid", fmt.Sprintf("%d", sleep.Process.Pid), "--arguments", "--utf-8"}, test.args...)...)
+			cmd.Env = append(os.Environ(), test.env...)
+			output, err := cmd.Output()
+			require.NoError(t, err)
+			assert.Contains(t, string(output), "…")
+			assert.NotContains(t, string(output), "\x1b")
+		})
+	}
+}
+
+// TestExitCodes tests that scripts can tell failures, empty results, and usage errors apart
+func TestExitCodes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		exitCode int
+	}{
+		{"Success", []string{}, 0},
+		{"NoMatch", []string{"--contains", "no-such-process-name"}, 1},
+		{"MissingPID", []string{"--pid", "2147483647"}, 1},
+		{"FlagConflict", []string{"--utf-8", "--vt-100"}, 2},
+		{"InvalidValue", []string{"--order-by", "invalid"}, 2},
+		{"UnknownFlag", []string{"--no-such-flag"}, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tc.args...)
+			output, _ := cmd.CombinedOutput()
+
+			assert.Equal(t, tc.exitCode, cmd.ProcessState.ExitCode(), string(output))
+		})
+	}
+}
+
+// TestNoRootLineWithoutChildren tests that --no-root-line prints nothing and exits with status 1 when the root has no children
+func TestNoRootLineWithoutChildren(t *testing.T) {
+	sleep := exec.Command("sleep", "30")
+	require.NoError(t, sleep.Start())
+	defer func() {
+		_ = sleep.Process.Kill()
+		_ = sleep.Wait()
+	}()
+
+	cmd := exec.Command(binaryPath, "--pid", fmt.Sprintf("%d", sleep.Process.Pid), "--no-root-line")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	_ = cmd.Run()
+
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Empty(t, stdout.String())
+}
@@ -7,16 +7,26 @@ import (
 	"slices"
 
 	"math"
+	"os"
 	"os/exec"
 	"os/user"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
 	terminal "github.com/wayneashleyberry/terminal-dimensions"
 )
 
+// ANSIEscape matches the terminal escape sequences written by pstree: the CSI sequences used for
+// colors, e.g., \x1b[1;31m, and the character set designations used by the VT-100 line drawing,
+// e.g., \x1b(B. They take up no space on the screen.
+var ANSIEscape = regexp.MustCompile(`\x1b(\[[0-9;?]*[a-zA-Z]|[()][0-9A-Za-z])`)
+
 type Duration struct {
 	Days    int64
 	Hours   int64
@@ -116,6 +126,18 @@ func GetTotalMemory() (*mem.VirtualMemoryStat, error) {
 	return v, nil
 }
 
+// GetCPUCount retrieves the number of logical CPUs of the system.
+//
+// Returns:
+//   - int: The number of logical CPUs, or 0 if it cannot be read
+func GetCPUCount() int {
+	count, err := cpu.Counts(true)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
 // StrToInt32 converts a string to an int32 value.
 //
 // This function parses a string representation of an integer and returns it as an int32.
@@ -205,6 +227,102 @@ func TruncateString(s string, length int) string {
 	return s
 }
 
+// VisibleWidth calculates the number of columns a string takes up on the screen.
+//
+// ANSI escape sequences matched by ANSIEscape and other control characters are not counted,
+// and each rune counts with its display width, so wide characters such as CJK take up two
+// columns while the UTF-8 line drawing characters take up one.
+//
+// Parameters:
+//   - input: The string to measure, which may contain ANSI escape sequences
+//
+// Returns:
+//   - int: The display width of the string
+func VisibleWidth(input string) int {
+	width := 0
+	WalkVisible(input, func(segment string, segmentWidth int, escape bool) bool {
+		width += segmentWidth
+		return true
+	})
+	return width
+}
+
+// WalkVisible calls visit for each ANSI escape sequence and each grapheme cluster of a string, in
+// order, until visit returns false.
+//
+// A grapheme cluster is what the terminal shows as a single character, e.g., a letter followed by
+// combining accents or an emoji joined from several code points, so cutting a string between the
+// clusters never splits a character. The segments are slices of the input, so bytes that are not
+// valid UTF-8, such as the IBM850 line drawing characters, are passed on unchanged.
+//
+// Parameters:
+//   - input: The string to walk, which may contain ANSI escape sequences
+//   - visit: Called with each segment, its display width (0 for an escape sequence), and whether it is an escape sequence
+func WalkVisible(input string, visit func(segment string, width int, escape bool) bool) {
+	offset := 0
+	for _, loc := range ANSIEscape.FindAllStringIndex(input, -1) {
+		if !walkClusters(input[offset:loc[0]], visit) || !visit(input[loc[0]:loc[1]], 0, true) {
+			return
+		}
+		offset = loc[1]
+	}
+	walkClusters(input[offset:], visit)
+}
+
+// walkClusters calls visit for each grapheme cluster of a string without escape sequences.
+//
+// Parameters:
+//   - text: The string to walk
+//   - visit: Called with each cluster, its display width, and false
+//
+// Returns:
+//   - bool: false if visit stopped the walk, true otherwise
+func walkClusters(text string, visit func(segment string, width int, escape bool) bool) bool {
+	if text == "" {
+		return true
+	}
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		from, to := graphemes.Positions()
+		if !visit(text[from:to], runewidth.StringWidth(text[from:to]), false) {
+			return false
+		}
+	}
+	return true
+}
+
+// TruncateWidth cuts a string down to a display width without splitting a character.
+//
+// Escape sequences don't count toward the width, and those in front of the first character that
+// doesn't fit are kept, so the colors of the kept part are intact. A character wider than the
+// remaining space is left out entirely instead of being cut in half.
+//
+// Parameters:
+//   - input: The string to truncate, which may contain ANSI escape sequences
+//   - width: The maximum display width of the result
+//
+// Returns:
+//   - string: The longest prefix of the string at most width columns wide
+//   - bool: true if anything was cut off, false if the whole string fits
+func TruncateWidth(input string, width int) (string, bool) {
+	var (
+		cut    bool
+		length int
+		used   int
+	)
+
+	WalkVisible(input, func(segment string, segmentWidth int, escape bool) bool {
+		if used+segmentWidth > width {
+			cut = true
+			return false
+		}
+		used += segmentWidth
+		length += len(segment)
+		return true
+	})
+	return input[:length], cut
+}
+
 // HasColorSupport determines if the terminal supports color output and how many colors.
 //
 // This function uses the 'tput colors' command to determine the number of colors
@@ -236,6 +354,49 @@ func HasColorSupport() (bool, int) {
 	}
 }
 
+// ShouldColorize determines if ANSI colors should be written to the output.
+//
+// An explicit mode always takes precedence: "always" enables colors and "never" disables
+// them. In "auto" mode, colors are disabled when the NO_COLOR environment variable is set
+// to a non-empty value (see https://no-color.org), and otherwise enabled only when the
+// output is a terminal that supports colors.
+//
+// Parameters:
+//   - mode: The color mode, one of "always", "auto", or "never"
+//   - noColor: Value of the NO_COLOR environment variable
+//   - isTerminal: Whether the output is a terminal
+//   - colorSupport: Whether the terminal supports color output
+//
+// Returns:
+//   - bool: true if colors should be written, false otherwise
+func ShouldColorize(mode string, noColor string, isTerminal bool, colorSupport bool) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if noColor != "" {
+		return false
+	}
+	return isTerminal && colorSupport
+}
+
+// IsTerminal determines if a file is connected to a terminal.
+//
+// Parameters:
+//   - file: The file to check, e.g., os.Stdout
+//
+// Returns:
+//   - bool: true if the file is a character device such as a terminal, false otherwise
+func IsTerminal(file *os.File) bool {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
 // UserExists checks if a user with the specified username exists on the system.
 //
 // Parameters:
@@ -248,6 +409,115 @@ func UserExists(username string) bool {
 	return err == nil
 }
 
+// ReadUsernames reads the names of the users listed in a passwd(5) file, e.g., /etc/passwd.
+// Blank lines and comments are skipped.
+//
+// Parameters:
+//   - path: Path of the passwd file
+//
+// Returns:
+//   - []string: The usernames, sorted
+//   - error: Any error encountered while reading the file
+func ReadUsernames(path string) ([]string, error) {
+	var (
+		usernames []string
+	)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, _, _ := strings.Cut(line, ":"); name != "" {
+			usernames = append(usernames, name)
+		}
+	}
+	slices.Sort(usernames)
+	return slices.Compact(usernames), nil
+}
+
+// SplitUsernames splits the users given with --user into the users to show and the users to
+// hide, which are prefixed with an exclamation mark, e.g., !root.
+//
+// Parameters:
+//   - usernames: The users given with --user
+//
+// Returns:
+//   - []string: The users to show, in the order given
+//   - []string: The users to hide, without the exclamation mark, in the order given
+func SplitUsernames(usernames []string) ([]string, []string) {
+	var (
+		excluded []string
+		included []string
+	)
+
+	for _, username := range usernames {
+		if name, ok := strings.CutPrefix(username, "!"); ok {
+			excluded = append(excluded, name)
+		} else {
+			included = append(included, username)
+		}
+	}
+	return included, excluded
+}
+
+// KnownUsernames separates the users that exist from the others.
+//
+// Parameters:
+//   - usernames: The users to check
+//   - exists: Function reporting whether a user exists, e.g., UserExists
+//
+// Returns:
+//   - []string: The users that exist, in the order given
+//   - []string: The users that don't exist, in the order given
+func KnownUsernames(usernames []string, exists func(username string) bool) ([]string, []string) {
+	var (
+		known   []string
+		unknown []string
+	)
+
+	for _, username := range usernames {
+		if exists(username) {
+			known = append(known, username)
+		} else {
+			unknown = append(unknown, username)
+		}
+	}
+	return known, unknown
+}
+
+// LookupGroupID resolves a group name or numeric group ID to a group ID.
+//
+// A numeric group ID is returned as is, even when the system has no group with that ID, so groups
+// that only exist inside a container can still be given.
+//
+// Parameters:
+//   - group: Name or ID of the group
+//
+// Returns:
+//   - uint32: The group ID
+//   - error: An error if the name is not a known group
+func LookupGroupID(group string) (uint32, error) {
+	if gid, err := strconv.ParseUint(group, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+
+	userGroup, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	gid, err := strconv.ParseUint(userGroup.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("group %q has a non-numeric ID %q", group, userGroup.Gid)
+	}
+	return uint32(gid), nil
+}
+
 // RoundFloat rounds a floating-point number to the specified precision.
 //
 // Parameters:
@@ -289,6 +559,134 @@ func ByteConverter(num uint64) string {
 	return fmt.Sprintf("%.2f Yi%s", RoundFloat(absolute, 2), suffix)
 }
 
+// FormatByteSize formats a byte count with one decimal place in the given unit.
+//
+// With the "auto" unit, the largest binary unit that keeps the number below 1024 after
+// rounding is used, e.g., 1023 bytes are "1023.0 B" and 1024 bytes are "1.0 KiB". The K, M,
+// and G units always format the count in KiB, MiB, or GiB, e.g., "0.5 MiB".
+//
+// Parameters:
+//   - num: Number of bytes to format
+//   - unit: One of "auto", "K", "M", or "G"; an empty or unknown unit means "auto"
+//
+// Returns:
+//   - string: Formatted string with the binary unit prefix
+func FormatByteSize(num uint64, unit string) string {
+	var (
+		absolute float64
+		prefixes []string = []string{"", "Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+	)
+	absolute = float64(num)
+
+	switch unit {
+	case "K":
+		return fmt.Sprintf("%.1f KiB", absolute/1024)
+	case "M":
+		return fmt.Sprintf("%.1f MiB", absolute/(1024*1024))
+	case "G":
+		return fmt.Sprintf("%.1f GiB", absolute/(1024*1024*1024))
+	}
+
+	for i, prefix := range prefixes {
+		// Compare the rounded value, so 1048575 bytes are 1.0 MiB rather than 1024.0 KiB
+		if math.Round(absolute*10)/10 < 1024 || i == len(prefixes)-1 {
+			return fmt.Sprintf("%.1f %sB", absolute, prefix)
+		}
+		absolute = absolute / 1024
+	}
+	return ""
+}
+
+// ParseByteSize parses a human-readable size into a byte count.
+//
+// The size is a number optionally followed by a binary unit, e.g., 512M, 1.5GiB, or 2048.
+// The units K, M, G, T, P, and E are powers of 1024, matching ByteConverter, and may be
+// written as K, KB, or KiB in any case.
+//
+// Parameters:
+//   - size: The size to parse
+//
+// Returns:
+//   - uint64: The number of bytes
+//   - error: An error if the size is not a non-negative number with a valid unit
+func ParseByteSize(size string) (uint64, error) {
+	var (
+		exponent int
+		number   string
+		unit     string
+		value    float64
+	)
+
+	size = strings.TrimSpace(size)
+	number = strings.TrimRightFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	unit = strings.ToUpper(strings.TrimSpace(size[len(number):]))
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+
+	if unit != "" {
+		exponent = strings.Index("KMGTPE", unit) + 1
+		if exponent == 0 || len(unit) != 1 {
+			return 0, fmt.Errorf("invalid size %q: unknown unit", size)
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q: expected a number followed by an optional unit, e.g., 512M", size)
+	}
+
+	value *= math.Pow(1024, float64(exponent))
+	if value >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid size %q: too large", size)
+	}
+	return uint64(value), nil
+}
+
+// ParseDuration parses a duration like time.ParseDuration, also accepting a number of days in
+// front of the other units, e.g., 1d, 1.5d, or 1d12h. A day is always 24 hours.
+//
+// Parameters:
+//   - duration: The duration to parse, e.g., 5m, 2h, or 7d
+//
+// Returns:
+//   - time.Duration: The duration
+//   - error: An error if the duration is not a non-negative number of days followed by an optional time.ParseDuration duration
+func ParseDuration(duration string) (time.Duration, error) {
+	var (
+		days  float64
+		err   error
+		total time.Duration
+	)
+
+	duration = strings.TrimSpace(duration)
+	before, after, found := strings.Cut(duration, "d")
+	if !found {
+		return time.ParseDuration(duration)
+	}
+
+	days, err = strconv.ParseFloat(before, 64)
+	if err != nil || days < 0 || strings.HasPrefix(before, "+") {
+		return 0, fmt.Errorf("invalid duration %q: expected a number of days, e.g., 7d", duration)
+	}
+	if days*24 >= float64(math.MaxInt64/time.Hour) {
+		return 0, fmt.Errorf("invalid duration %q: too large", duration)
+	}
+	total = time.Duration(days * 24 * float64(time.Hour))
+
+	if after != "" {
+		if strings.HasPrefix(after, "-") || strings.HasPrefix(after, "+") {
+			return 0, fmt.Errorf("invalid duration %q: unexpected sign after the days", duration)
+		}
+		rest, err := time.ParseDuration(after)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", duration, err)
+		}
+		total += rest
+	}
+	return total, nil
+}
+
 // BtoI converts a boolean value to an integer (1 for true, 0 for false).
 //
 // Parameters:
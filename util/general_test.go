@@ -1,9 +1,13 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRoundFloat(t *testing.T) {
@@ -69,6 +73,53 @@ func TestTruncateString(t *testing.T) {
 	assert.Equal(t, "123456789", TruncateString("123456789", 10))
 }
 
+func TestVisibleWidth(t *testing.T) {
+	assert.Equal(t, 0, VisibleWidth(""))
+	assert.Equal(t, 4, VisibleWidth("bash"))
+	// Colors are not counted
+	assert.Equal(t, 4, VisibleWidth("\x1b[1;31mbash\x1b[0m"))
+	// The UTF-8 line drawing characters take up one column each, even when colored
+	assert.Equal(t, 10, VisibleWidth("\x1b[38;5;244m ├─┬─\x1b[0m init"))
+	// Wide characters take up two columns
+	assert.Equal(t, 6, VisibleWidth("日本語"))
+	// Neither are the VT-100 character set designations and shifts
+	assert.Equal(t, 8, VisibleWidth("\x1b(B\x1b)0\x0eqwq\x0f bash"))
+	// An emoji joined from several code points takes up the columns of one character
+	assert.Equal(t, 4, VisibleWidth("x👩‍💻y"))
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		expected string
+		cut      bool
+	}{
+		{"fits", "bash", 10, "bash", false},
+		{"fits exactly", "bash", 4, "bash", false},
+		{"ascii", "bash", 3, "bas", true},
+		{"wide character straddling the edge", "日本語", 3, "日", true},
+		{"wide characters up to the edge", "日本語", 4, "日本", true},
+		{"wide character after ascii", "ab日", 3, "ab", true},
+		{"colors are kept", "\x1b[31m日本\x1b[0m", 3, "\x1b[31m日", true},
+		{"combining accent stays with its letter", "cafe\u0301s", 4, "cafe\u0301", true},
+		{"joined emoji is not split", "x👩‍💻y", 3, "x👩‍💻", true},
+		{"joined emoji straddling the edge", "x👩‍💻y", 2, "x", true},
+		{"invalid UTF-8 is passed on", "\xb3\xc3 bash", 2, "\xb3\xc3", true},
+		{"zero width", "bash", 0, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, cut := TruncateWidth(test.input, test.width)
+			assert.Equal(t, test.expected, output)
+			assert.Equal(t, test.cut, cut)
+			assert.LessOrEqual(t, VisibleWidth(output), test.width)
+		})
+	}
+}
+
 func TestUserExists(t *testing.T) {
 	// Test with a user that should exist on most systems
 	assert.True(t, UserExists("root"))
@@ -77,6 +128,58 @@ func TestUserExists(t *testing.T) {
 	assert.False(t, UserExists("nonexistentuser123456789"))
 }
 
+func TestReadUsernames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwd")
+	require.NoError(t, os.WriteFile(path, []byte("root:x:0:0:root:/root:/bin/bash\n# comment\n\nmessagebus:x:100:101::/nonexistent:/usr/sbin/nologin\nalice:x:1000:1000::/home/alice:/bin/zsh\n"), 0o600))
+
+	usernames, err := ReadUsernames(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "messagebus", "root"}, usernames)
+
+	_, err = ReadUsernames(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestSplitUsernames(t *testing.T) {
+	included, excluded := SplitUsernames([]string{"alice", "!root", "bob", "!messagebus"})
+	assert.Equal(t, []string{"alice", "bob"}, included)
+	assert.Equal(t, []string{"root", "messagebus"}, excluded)
+
+	included, excluded = SplitUsernames(nil)
+	assert.Empty(t, included)
+	assert.Empty(t, excluded)
+}
+
+func TestKnownUsernames(t *testing.T) {
+	exists := func(username string) bool { return username == "root" || username == "alice" }
+
+	// Several users that don't exist are all left out, wherever they are given
+	known, unknown := KnownUsernames([]string{"nobody1", "root", "nobody2", "nobody3", "alice"}, exists)
+	assert.Equal(t, []string{"root", "alice"}, known)
+	assert.Equal(t, []string{"nobody1", "nobody2", "nobody3"}, unknown)
+
+	known, unknown = KnownUsernames([]string{"nobody1", "nobody2"}, exists)
+	assert.Empty(t, known)
+	assert.Equal(t, []string{"nobody1", "nobody2"}, unknown)
+}
+
+func TestLookupGroupID(t *testing.T) {
+	// Numeric IDs are used as is
+	gid, err := LookupGroupID("4242")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4242), gid)
+
+	// Test with a group that should exist on most systems
+	gid, err = LookupGroupID("root")
+	if err == nil {
+		assert.Equal(t, uint32(0), gid)
+	}
+
+	// Test with a group that should not exist
+	_, err = LookupGroupID("nonexistentgroup123456789")
+	assert.Error(t, err)
+}
+
 func TestByteConverter(t *testing.T) {
 	// Test with valid input
 	assert.Equal(t, "1.00 KiB", ByteConverter(1024))
@@ -87,6 +190,80 @@ func TestByteConverter(t *testing.T) {
 	assert.Equal(t, "1.00 EiB", ByteConverter(1152921504606847000))
 }
 
+func TestFormatByteSize(t *testing.T) {
+	testCases := []struct {
+		num      uint64
+		unit     string
+		expected string
+	}{
+		{0, "auto", "0.0 B"},
+		{1023, "auto", "1023.0 B"},
+		{1024, "auto", "1.0 KiB"},
+		{1536, "auto", "1.5 KiB"},
+		{1048575, "auto", "1.0 MiB"},
+		{1048576, "auto", "1.0 MiB"},
+		{1073741824, "", "1.0 GiB"},
+		{1152921504606846976, "auto", "1.0 EiB"},
+		{1024, "K", "1.0 KiB"},
+		{524288, "M", "0.5 MiB"},
+		{1073741824, "M", "1024.0 MiB"},
+		{1, "G", "0.0 GiB"},
+		{3221225472, "G", "3.0 GiB"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, FormatByteSize(tc.num, tc.unit), "%d %s", tc.num, tc.unit)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	sizes := map[string]uint64{
+		"0":      0,
+		"2048":   2048,
+		"512K":   524288,
+		"512M":   536870912,
+		"512mb":  536870912,
+		"512MiB": 536870912,
+		"1.5G":   1610612736,
+		"1 T":    1099511627776,
+		"100B":   100,
+	}
+	for size, expected := range sizes {
+		bytes, err := ParseByteSize(size)
+		assert.NoError(t, err, size)
+		assert.Equal(t, expected, bytes, size)
+	}
+
+	for _, size := range []string{"", "M", "-1M", "12X", "1MM", "1.2.3G", "99999999E"} {
+		_, err := ParseByteSize(size)
+		assert.Error(t, err, size)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	durations := map[string]time.Duration{
+		"5m":     5 * time.Minute,
+		"2h30m":  150 * time.Minute,
+		"1d":     24 * time.Hour,
+		"1.5d":   36 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"7d":     7 * 24 * time.Hour,
+		" 30s ":  30 * time.Second,
+		"0d":     0,
+		"2d1h1m": 49*time.Hour + time.Minute,
+	}
+	for duration, expected := range durations {
+		parsed, err := ParseDuration(duration)
+		assert.NoError(t, err, duration)
+		assert.Equal(t, expected, parsed, duration)
+	}
+
+	for _, duration := range []string{"", "d", "5", "-1d", "+1d", "1d-2h", "1dd", "5x", "1d5", "99999999999d"} {
+		_, err := ParseDuration(duration)
+		assert.Error(t, err, duration)
+	}
+}
+
 func TestBtoI(t *testing.T) {
 	assert.Equal(t, 1, BtoI(true))
 	assert.Equal(t, 0, BtoI(false))
@@ -152,3 +329,25 @@ func TestDeleteSliceElement(t *testing.T) {
 	result = DeleteSliceElement(slice, 0)
 	assert.Equal(t, []string{}, result)
 }
+
+func TestShouldColorize(t *testing.T) {
+	// An explicit mode wins over NO_COLOR and the terminal
+	assert.True(t, ShouldColorize("always", "1", false, false))
+	assert.False(t, ShouldColorize("never", "", true, true))
+
+	// NO_COLOR wins over the terminal in auto mode
+	assert.False(t, ShouldColorize("auto", "1", true, true))
+
+	// Otherwise colors are used only on a terminal that supports them
+	assert.True(t, ShouldColorize("auto", "", true, true))
+	assert.False(t, ShouldColorize("auto", "", false, true))
+	assert.False(t, ShouldColorize("auto", "", true, false))
+}
+
+func TestIsTerminal(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "pstree")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	assert.False(t, IsTerminal(file))
+}